@@ -4,18 +4,27 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"text/template"
 	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/improbable-eng/thanos/pkg/block"
 	"github.com/improbable-eng/thanos/pkg/block/metadata"
+	"github.com/improbable-eng/thanos/pkg/compact"
+	"github.com/improbable-eng/thanos/pkg/compact/rewrite"
+	"github.com/improbable-eng/thanos/pkg/model"
 	"github.com/improbable-eng/thanos/pkg/objstore"
 	"github.com/improbable-eng/thanos/pkg/objstore/client"
+	"github.com/improbable-eng/thanos/pkg/promclient"
+	thanosrule "github.com/improbable-eng/thanos/pkg/rule"
 	"github.com/improbable-eng/thanos/pkg/runutil"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
 	"github.com/improbable-eng/thanos/pkg/verifier"
 	"github.com/oklog/run"
 	"github.com/oklog/ulid"
@@ -23,6 +32,8 @@ import (
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/tsdb/labels"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
@@ -53,6 +64,9 @@ func registerBucket(m map[string]setupFunc, app *kingpin.Application, name strin
 	registerBucketVerify(m, cmd, name, objStoreConfig)
 	registerBucketLs(m, cmd, name, objStoreConfig)
 	registerBucketInspect(m, cmd, name, objStoreConfig)
+	registerBucketRewrite(m, cmd, name, objStoreConfig)
+	registerBucketRetention(m, cmd, name, objStoreConfig)
+	registerBucketBackfill(m, cmd, name, objStoreConfig)
 	return
 }
 
@@ -291,6 +305,272 @@ func registerBucketInspect(m map[string]setupFunc, root *kingpin.CmdClause, name
 	}
 }
 
+func registerBucketRewrite(m map[string]setupFunc, root *kingpin.CmdClause, name string, objStoreConfig *pathOrContent) {
+	cmd := root.Command("rewrite", "Rewrite external labels of chosen blocks into new blocks. Remember to rewrite all downsampled versions of a block as well.")
+	blockIDs := cmd.Flag("id", "ID (ULID) of the blocks to rewrite (repeated flag).").Required().Strings()
+	tmpDir := cmd.Flag("tmp.dir", "Working directory for temporary files.").
+		Default(filepath.Join(os.TempDir(), "thanos-rewrite")).String()
+	dryRun := cmd.Flag("dry-run", "Prints the external labels blocks would be rewritten to, without touching the bucket. Use --no-dry-run to rewrite.").
+		Default("true").Bool()
+	deleteBlocks := cmd.Flag("delete-blocks", "Mark the original blocks for deletion after they were rewritten successfully.").
+		Default("false").Bool()
+	relabelConfig := regRelabelConfigFlags(cmd, true)
+
+	m[name+" rewrite"] = func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ bool) error {
+		relabelContentYaml, err := relabelConfig.Content()
+		if err != nil {
+			return err
+		}
+		relabelConfigs, err := rewrite.ParseRelabelConfig(relabelContentYaml)
+		if err != nil {
+			return err
+		}
+
+		var ids []ulid.ULID
+		for _, bid := range *blockIDs {
+			id, err := ulid.Parse(bid)
+			if err != nil {
+				return errors.Wrap(err, "invalid ULID found in --id flag")
+			}
+			ids = append(ids, id)
+		}
+
+		confContentYaml, err := objStoreConfig.Content()
+		if err != nil {
+			return err
+		}
+
+		bkt, err := client.NewBucket(logger, confContentYaml, reg, name)
+		if err != nil {
+			return err
+		}
+
+		// Dummy actor to immediately kill the group after the run function returns.
+		g.Add(func() error { return nil }, func(error) {})
+
+		defer runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
+
+		ctx := context.Background()
+		for _, id := range ids {
+			meta, err := block.DownloadMeta(ctx, logger, bkt, id)
+			if err != nil {
+				return err
+			}
+
+			if *dryRun {
+				newLabels, changed, err := rewrite.RelabelExternalLabels(&meta, relabelConfigs)
+				if err != nil {
+					return err
+				}
+				if !changed {
+					level.Info(logger).Log("msg", "relabeling does not change block external labels; skipping", "block", id)
+					continue
+				}
+				level.Info(logger).Log("msg", "dry run: block would be rewritten", "block", id,
+					"from", labels.FromMap(meta.Thanos.Labels), "to", labels.FromMap(newLabels))
+				continue
+			}
+
+			newID, changed, err := rewrite.Block(ctx, logger, bkt, *tmpDir, &meta, relabelConfigs)
+			if err != nil {
+				return errors.Wrapf(err, "rewrite block %s", id)
+			}
+			if !changed {
+				level.Info(logger).Log("msg", "relabeling does not change block external labels; skipping", "block", id)
+				continue
+			}
+			if *deleteBlocks {
+				if err := block.MarkForDeletion(ctx, logger, bkt, id); err != nil {
+					return errors.Wrapf(err, "mark block %s rewritten to %s for deletion", id, newID)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func registerBucketRetention(m map[string]setupFunc, root *kingpin.CmdClause, name string, objStoreConfig *pathOrContent) {
+	cmd := root.Command("retention", "Simulate retention policy against blocks in the bucket and report which blocks and how many bytes it would delete, without deleting anything")
+	retentionRaw := modelDuration(cmd.Flag("retention.resolution-raw", "How long to retain raw samples in bucket. 0d - disables this retention").Default("0d"))
+	retention5m := modelDuration(cmd.Flag("retention.resolution-5m", "How long to retain samples of resolution 1 (5 minutes) in bucket. 0d - disables this retention").Default("0d"))
+	retention1h := modelDuration(cmd.Flag("retention.resolution-1h", "How long to retain samples of resolution 2 (1 hour) in bucket. 0d - disables this retention").Default("0d"))
+	horizon := modelDuration(cmd.Flag("horizon", "Report blocks deleted within this period from now. 0d reports only blocks that the next retention run would delete.").Default("0d"))
+	selector := cmd.Flag("selector", "Selects blocks based on label, e.g. '-l key1=\"value1\" -l key2=\"value2\"'. All key value pairs must match. It only limits the report, the retention applies to all blocks regardless of their labels.").Short('l').
+		PlaceHolder("<name>=\"<value>\"").Strings()
+	output := cmd.Flag("output", "Format of the report. Options are 'table' or 'json'.").Short('o').Default("table").Enum("table", "json")
+
+	m[name+" retention"] = func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ bool) error {
+		selectorLabels, err := parseFlagLabels(*selector)
+		if err != nil {
+			return fmt.Errorf("error parsing selector flag: %v", err)
+		}
+
+		retentionByResolution := map[compact.ResolutionLevel]time.Duration{
+			compact.ResolutionLevelRaw: time.Duration(*retentionRaw),
+			compact.ResolutionLevel5m:  time.Duration(*retention5m),
+			compact.ResolutionLevel1h:  time.Duration(*retention1h),
+		}
+
+		confContentYaml, err := objStoreConfig.Content()
+		if err != nil {
+			return err
+		}
+
+		bkt, err := client.NewBucket(logger, confContentYaml, reg, name)
+		if err != nil {
+			return err
+		}
+
+		// Dummy actor to immediately kill the group after the run function returns.
+		g.Add(func() error { return nil }, func(error) {})
+
+		defer runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		var blockMetas []*metadata.Meta
+		if err = bkt.Iter(ctx, "", func(name string) error {
+			id, ok := block.IsBlockDir(name)
+			if !ok {
+				return nil
+			}
+
+			m, err := block.DownloadMeta(ctx, logger, bkt, id)
+			if err != nil {
+				return err
+			}
+			if matchesSelector(&m, selectorLabels) {
+				blockMetas = append(blockMetas, &m)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		candidates := compact.PlanRetentionByResolution(blockMetas, retentionByResolution, time.Now().Add(time.Duration(*horizon)))
+
+		report := retentionReport{Blocks: make([]retentionReportBlock, 0, len(candidates))}
+		for _, c := range candidates {
+			size, err := block.Size(ctx, bkt, c.Meta.ULID)
+			if err != nil {
+				return errors.Wrapf(err, "get size of block %s", c.Meta.ULID)
+			}
+			report.Blocks = append(report.Blocks, retentionReportBlock{
+				ID:           c.Meta.ULID,
+				Labels:       c.Meta.Thanos.Labels,
+				Resolution:   c.Meta.Thanos.Downsample.Resolution,
+				MinTime:      c.Meta.MinTime,
+				MaxTime:      c.Meta.MaxTime,
+				DeletionTime: c.DeletionTime,
+				Bytes:        size,
+			})
+			report.TotalBytes += size
+		}
+
+		if *output == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "\t")
+			return enc.Encode(&report)
+		}
+		return printRetentionReport(report)
+	}
+}
+
+type retentionReport struct {
+	Blocks     []retentionReportBlock `json:"blocks"`
+	TotalBytes uint64                 `json:"total_bytes"`
+}
+
+type retentionReportBlock struct {
+	ID           ulid.ULID         `json:"id"`
+	Labels       map[string]string `json:"labels"`
+	Resolution   int64             `json:"resolution"`
+	MinTime      int64             `json:"min_time"`
+	MaxTime      int64             `json:"max_time"`
+	DeletionTime time.Time         `json:"deletion_time"`
+	Bytes        uint64            `json:"bytes"`
+}
+
+// printRetentionReport prints blocks to be deleted followed by a summary per external labels and resolution.
+func printRetentionReport(report retentionReport) error {
+	p := message.NewPrinter(language.English)
+
+	type groupKey struct {
+		labels     string
+		resolution int64
+	}
+	type groupSummary struct {
+		blocks int
+		bytes  uint64
+	}
+	var (
+		lines   [][]string
+		keys    []groupKey
+		summary = map[groupKey]*groupSummary{}
+	)
+	for _, b := range report.Blocks {
+		lset := labels.FromMap(b.Labels).String()
+		resolution := time.Duration(b.Resolution * int64(time.Millisecond)).String()
+		lines = append(lines, []string{
+			b.ID.String(),
+			lset,
+			resolution,
+			time.Unix(b.MaxTime/1000, 0).Format("02-01-2006 15:04:05"),
+			b.DeletionTime.Format("02-01-2006 15:04:05"),
+			p.Sprintf("%d", b.Bytes),
+		})
+
+		k := groupKey{labels: lset, resolution: b.Resolution}
+		s, ok := summary[k]
+		if !ok {
+			s = &groupSummary{}
+			summary[k] = s
+			keys = append(keys, k)
+		}
+		s.blocks++
+		s.bytes += b.Bytes
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ULID", "LABELS", "RESOLUTION", "UNTIL", "DELETION TIME", "BYTES"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetAutoWrapText(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.AppendBulk(lines)
+	table.Render()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].labels == keys[j].labels {
+			return keys[i].resolution < keys[j].resolution
+		}
+		return keys[i].labels < keys[j].labels
+	})
+	var summaryLines [][]string
+	for _, k := range keys {
+		summaryLines = append(summaryLines, []string{
+			k.labels,
+			time.Duration(k.resolution * int64(time.Millisecond)).String(),
+			p.Sprintf("%d", summary[k].blocks),
+			p.Sprintf("%d", summary[k].bytes),
+		})
+	}
+
+	fmt.Fprintln(os.Stdout, "")
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"LABELS", "RESOLUTION", "BLOCKS", "BYTES"})
+	table.SetFooter([]string{"", "TOTAL", p.Sprintf("%d", len(report.Blocks)), p.Sprintf("%d", report.TotalBytes)})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetAutoWrapText(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.AppendBulk(summaryLines)
+	table.Render()
+
+	return nil
+}
+
 func printTable(blockMetas []*metadata.Meta, selectorLabels labels.Labels, sortBy []string) error {
 	header := inspectColumns
 
@@ -416,3 +696,96 @@ func compare(s1, s2 string) bool {
 	}
 	return s1Time.Before(s2Time)
 }
+
+func registerBucketBackfill(m map[string]setupFunc, root *kingpin.CmdClause, name string, objStoreConfig *pathOrContent) {
+	cmd := root.Command("backfill", "Evaluate recording rules over a past time range via the Query API and upload the results as blocks, so new rules have history")
+	queryURL := cmd.Flag("query", "URL of the Query API the rules are evaluated against, e.g. http://thanos-query:10902.").Required().String()
+	ruleFiles := cmd.Flag("rule-file", "Rule files with the recording rules to backfill. Can be in glob format (repeated). Alerting rules are skipped.").
+		Required().Strings()
+	start := model.TimeOrDuration(cmd.Flag("start", "Start of the time range to backfill. Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or -2w.").
+		Required())
+	end := model.TimeOrDuration(cmd.Flag("end", "End of the time range to backfill (exclusive). Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or -2w.").
+		Default("0s"))
+	evalInterval := modelDuration(cmd.Flag("eval-interval", "The default evaluation interval of rule groups without an interval.").
+		Default("30s"))
+	blockDuration := modelDuration(cmd.Flag("block-duration", "Time range of the uploaded blocks.").
+		Default("2h"))
+	labelStrs := cmd.Flag("label", "External labels of the uploaded blocks (repeated). They must differ from the labels of rulers evaluating the same rules, otherwise the compactor finds overlapping blocks.").
+		Required().PlaceHolder("<name>=\"<value>\"").Strings()
+	tenantLabel := cmd.Flag("tenant-label-name", "Name of the label identifying the tenant of a series for rule groups with a 'tenant' field, as for the ruler.").
+		Default("tenant").String()
+	tmpDir := cmd.Flag("tmp.dir", "Working directory for temporary files.").
+		Default(filepath.Join(os.TempDir(), "thanos-backfill")).String()
+
+	m[name+" backfill"] = func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ bool) error {
+		lset, err := parseFlagLabels(*labelStrs)
+		if err != nil {
+			return errors.Wrap(err, "parse labels")
+		}
+		u, err := url.Parse(*queryURL)
+		if err != nil {
+			return errors.Wrap(err, "parse query URL")
+		}
+
+		var files []string
+		for _, pat := range *ruleFiles {
+			fs, err := filepath.Glob(pat)
+			if err != nil {
+				return errors.Wrapf(err, "glob rule file pattern %s", pat)
+			}
+			files = append(files, fs...)
+		}
+		groups, err := thanosrule.LoadRuleGroups(files)
+		if err != nil {
+			return err
+		}
+
+		confContentYaml, err := objStoreConfig.Content()
+		if err != nil {
+			return err
+		}
+
+		bkt, err := client.NewBucket(logger, confContentYaml, reg, name)
+		if err != nil {
+			return err
+		}
+
+		// Dummy actor to immediately kill the group after the run function returns.
+		g.Add(func() error { return nil }, func(error) {})
+
+		defer runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
+
+		if err := os.MkdirAll(*tmpDir, os.ModePerm); err != nil {
+			return errors.Wrapf(err, "create tmp dir %s", *tmpDir)
+		}
+
+		query := func(ctx context.Context, expr string, start, end time.Time, step time.Duration, strategy storepb.PartialResponseStrategy) (promql.Matrix, error) {
+			matrix, warns, err := promclient.PromqlQueryRange(ctx, logger, u, expr, start, end, step, promclient.QueryOptions{
+				Deduplicate:             true,
+				PartialResponseStrategy: strategy,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if len(warns) > 0 {
+				level.Warn(logger).Log("warnings", strings.Join(warns, ", "), "query", expr)
+			}
+			return matrix, nil
+		}
+
+		ids, err := thanosrule.Backfill(context.Background(), logger, bkt, query, groups, thanosrule.BackfillOptions{
+			Start:           timestamp.Time(start.PrometheusTimestamp()),
+			End:             timestamp.Time(end.PrometheusTimestamp()),
+			DefaultInterval: time.Duration(*evalInterval),
+			BlockDuration:   time.Duration(*blockDuration),
+			ExternalLabels:  labelsTSDBToProm(lset),
+			TenantLabel:     *tenantLabel,
+			Dir:             *tmpDir,
+		})
+		if err != nil {
+			return err
+		}
+		level.Info(logger).Log("msg", "backfill done", "groups", len(groups), "blocks", len(ids))
+		return nil
+	}
+}
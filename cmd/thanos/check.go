@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-kit/kit/log"
+	thanosrule "github.com/improbable-eng/thanos/pkg/rule"
+	"github.com/oklog/run"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+func registerCheck(m map[string]setupFunc, app *kingpin.Application, name string) {
+	cmd := app.Command(name, "Linting utility commands")
+
+	registerCheckRules(m, cmd, name)
+}
+
+func registerCheckRules(m map[string]setupFunc, root *kingpin.CmdClause, name string) {
+	cmd := root.Command("rules", "Check if the rule files are valid for the ruler, including the Thanos specific fields like partial_response_strategy and tenant")
+	ruleFiles := cmd.Flag("rule-file", "Rule files to check. Can be in glob format (repeated).").
+		Required().Strings()
+	tenantLabel := cmd.Flag("tenant-label-name", "Name of the label identifying the tenant of a series for rule groups with a 'tenant' field, as for the ruler.").
+		Default("tenant").String()
+
+	m[name+" rules"] = func(g *run.Group, _ log.Logger, _ *prometheus.Registry, _ opentracing.Tracer, _ bool) error {
+		// Dummy actor to immediately kill the group after the run function returns.
+		g.Add(func() error { return nil }, func(error) {})
+
+		var files []string
+		for _, pat := range *ruleFiles {
+			fs, err := filepath.Glob(pat)
+			if err != nil {
+				return errors.Wrapf(err, "glob rule file pattern %s", pat)
+			}
+			if len(fs) == 0 {
+				return errors.Errorf("no rule files found for pattern %s", pat)
+			}
+			files = append(files, fs...)
+		}
+		return checkRules(files, *tenantLabel)
+	}
+}
+
+func checkRules(files []string, tenantLabel string) error {
+	var (
+		total  thanosrule.RuleCounts
+		failed int
+	)
+	for _, fn := range files {
+		fmt.Fprintln(os.Stdout, "Checking", fn)
+
+		counts, err := thanosrule.CheckRuleFile(fn, tenantLabel)
+		if err != nil {
+			fmt.Fprintln(os.Stdout, "  FAILED:", err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "  SUCCESS: %d groups, %d recording rules, %d alerting rules found\n", counts.Groups, counts.Recording, counts.Alerting)
+		total.Add(counts)
+	}
+	fmt.Fprintf(os.Stdout, "%d rule files checked: %d groups, %d recording rules, %d alerting rules\n", len(files), total.Groups, total.Recording, total.Alerting)
+
+	if failed > 0 {
+		return errors.Errorf("%d of %d rule files are invalid", failed, len(files))
+	}
+	return nil
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/tsdb"
+	"golang.org/x/sync/errgroup"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -86,6 +87,11 @@ func registerCompact(m map[string]setupFunc, app *kingpin.Application, name stri
 	consistencyDelay := modelDuration(cmd.Flag("consistency-delay", fmt.Sprintf("Minimum age of fresh (non-compacted) blocks before they are being processed. Malformed blocks older than the maximum of consistency-delay and %s will be removed.", compact.MinimumAgeForRemoval)).
 		Default("30m"))
 
+	deleteDelay := modelDuration(cmd.Flag("delete-delay", "Time before blocks marked for deletion, e.g. by 'bucket rewrite', are deleted from the bucket. "+
+		"Marked blocks are never compacted or downsampled again. It should be higher than --ignore-deletion-marks-delay of the store gateways, so that they stop serving "+
+		"the blocks before their files disappear.").
+		Default("48h"))
+
 	retentionRaw := modelDuration(cmd.Flag("retention.resolution-raw", "How long to retain raw samples in bucket. 0d - disables this retention").Default("0d"))
 	retention5m := modelDuration(cmd.Flag("retention.resolution-5m", "How long to retain samples of resolution 1 (5 minutes) in bucket. 0d - disables this retention").Default("0d"))
 	retention1h := modelDuration(cmd.Flag("retention.resolution-1h", "How long to retain samples of resolution 2 (1 hour) in bucket. 0d - disables this retention").Default("0d"))
@@ -110,12 +116,27 @@ func registerCompact(m map[string]setupFunc, app *kingpin.Application, name stri
 	compactionConcurrency := cmd.Flag("compact.concurrency", "Number of goroutines to use when compacting groups.").
 		Default("1").Int()
 
+	downsampleConcurrency := cmd.Flag("downsample.concurrency", "Number of blocks downsampled in parallel. Downsampling runs alongside "+
+		"the compaction of the same iteration, so this adds to the --compact.concurrency goroutines.").
+		Default("1").Int()
+
+	downsampleMemoryLimit := cmd.Flag("downsample.memory-limit", "Upper bound of estimated memory used by blocks being downsampled. "+
+		"Memory used by the compaction running at the same time is not included, so leave room for it when sizing the compactor. 0 means no limit.").
+		Default("0B").Bytes()
+
+	healthReport := cmd.Flag("health-report", fmt.Sprintf("Write a machine-readable bucket health report (overlaps, gaps, partial blocks, sizes) to %s in the bucket after each iteration.", compact.HealthReportPath)).
+		Default("false").Bool()
+
+	bucketIndex := cmd.Flag("bucket-index", fmt.Sprintf("Maintain %s, listing the metas and deletion marks of all blocks, after each iteration. Store gateways started with --store.bucket-index sync from it.", block.BucketIndexFilename)).
+		Default("false").Bool()
+
 	m[name] = func(g *run.Group, logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, _ bool) error {
 		return runCompact(g, logger, reg,
 			*httpAddr,
 			*dataDir,
 			objStoreConfig,
 			time.Duration(*consistencyDelay),
+			time.Duration(*deleteDelay),
 			*haltOnError,
 			*acceptMalformedIndex,
 			*wait,
@@ -130,6 +151,10 @@ func registerCompact(m map[string]setupFunc, app *kingpin.Application, name stri
 			*maxCompactionLevel,
 			*blockSyncConcurrency,
 			*compactionConcurrency,
+			*downsampleConcurrency,
+			uint64(*downsampleMemoryLimit),
+			*healthReport,
+			*bucketIndex,
 		)
 	}
 }
@@ -142,6 +167,7 @@ func runCompact(
 	dataDir string,
 	objStoreConfig *pathOrContent,
 	consistencyDelay time.Duration,
+	deleteDelay time.Duration,
 	haltOnError bool,
 	acceptMalformedIndex bool,
 	wait bool,
@@ -152,7 +178,15 @@ func runCompact(
 	maxCompactionLevel int,
 	blockSyncConcurrency int,
 	concurrency int,
+	downsampleConcurrency int,
+	downsampleMemoryLimit uint64,
+	healthReport bool,
+	bucketIndex bool,
 ) error {
+	if downsampleConcurrency <= 0 {
+		return errors.Errorf("invalid downsample concurrency %d, must be > 0", downsampleConcurrency)
+	}
+
 	halted := prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "thanos_compactor_halted",
 		Help: "Set to 1 if the compactor halted due to an unexpected error",
@@ -161,10 +195,15 @@ func runCompact(
 		Name: "thanos_compactor_retries_total",
 		Help: "Total number of retries after retriable compactor error",
 	})
+	bucketIndexUpdated := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "thanos_compactor_bucket_index_last_successful_update_timestamp_seconds",
+		Help: "Unix timestamp of the last successful update of the bucket index",
+	})
 	halted.Set(0)
 
 	reg.MustRegister(halted)
 	reg.MustRegister(retried)
+	reg.MustRegister(bucketIndexUpdated)
 
 	confContentYaml, err := objStoreConfig.Content()
 	if err != nil {
@@ -183,7 +222,7 @@ func runCompact(
 		}
 	}()
 
-	sy, err := compact.NewSyncer(logger, reg, bkt, consistencyDelay,
+	sy, err := compact.NewSyncer(logger, reg, bkt, consistencyDelay, deleteDelay,
 		blockSyncConcurrency, acceptMalformedIndex)
 	if err != nil {
 		return errors.Wrap(err, "create syncer")
@@ -213,6 +252,8 @@ func runCompact(
 		indexCacheDir   = path.Join(dataDir, "index_cache")
 	)
 
+	dsMetrics := newDownsampleMetrics(reg)
+
 	if err := os.RemoveAll(downsamplingDir); err != nil {
 		return errors.Wrap(err, "clean working downsample directory")
 	}
@@ -232,36 +273,77 @@ func runCompact(
 		level.Info(logger).Log("msg", "retention policy of 1 hour aggregated samples is enabled", "duration", retentionByResolution[compact.ResolutionLevel1h])
 	}
 
+	var healthReporter *compact.HealthReporter
+	if healthReport {
+		healthReporter = compact.NewHealthReporter(logger, bkt, consistencyDelay)
+	}
+	var bucketIndexUpdater *block.BucketIndexUpdater
+	if bucketIndex {
+		bucketIndexUpdater = block.NewBucketIndexUpdater(logger, bkt)
+	}
+
 	f := func() error {
-		if err := compactor.Compact(ctx); err != nil {
-			return errors.Wrap(err, "compaction failed")
-		}
-		level.Info(logger).Log("msg", "compaction iterations done")
+		// Compaction and downsampling work on different blocks most of the time, so work down the downsampling
+		// backlog while compacting instead of waiting for all compactions to be done. Blocks compacted or
+		// downsampled in this iteration are picked up by the other one in the next iteration.
+		eg, egCtx := errgroup.WithContext(ctx)
+		eg.Go(func() error {
+			if err := compactor.Compact(egCtx); err != nil {
+				return errors.Wrap(err, "compaction failed")
+			}
+			level.Info(logger).Log("msg", "compaction iterations done")
+			return nil
+		})
 
 		// TODO(bplotka): Remove "disableDownsampling" once https://github.com/improbable-eng/thanos/issues/297 is fixed.
 		if !disableDownsampling {
-			// After all compactions are done, work down the downsampling backlog.
-			// We run two passes of this to ensure that the 1h downsampling is generated
-			// for 5m downsamplings created in the first run.
-			level.Info(logger).Log("msg", "start first pass of downsampling")
+			eg.Go(func() error {
+				// We run two passes of this to ensure that the 1h downsampling is generated
+				// for 5m downsamplings created in the first run.
+				level.Info(logger).Log("msg", "start first pass of downsampling")
 
-			if err := downsampleBucket(ctx, logger, bkt, downsamplingDir); err != nil {
-				return errors.Wrap(err, "first pass of downsampling failed")
-			}
+				if err := downsampleBucket(egCtx, logger, dsMetrics, bkt, downsamplingDir, consistencyDelay, downsampleConcurrency, downsampleMemoryLimit); err != nil {
+					return errors.Wrap(err, "first pass of downsampling failed")
+				}
 
-			level.Info(logger).Log("msg", "start second pass of downsampling")
+				level.Info(logger).Log("msg", "start second pass of downsampling")
 
-			if err := downsampleBucket(ctx, logger, bkt, downsamplingDir); err != nil {
-				return errors.Wrap(err, "second pass of downsampling failed")
-			}
-			level.Info(logger).Log("msg", "downsampling iterations done")
+				if err := downsampleBucket(egCtx, logger, dsMetrics, bkt, downsamplingDir, consistencyDelay, downsampleConcurrency, downsampleMemoryLimit); err != nil {
+					return errors.Wrap(err, "second pass of downsampling failed")
+				}
+				level.Info(logger).Log("msg", "downsampling iterations done")
+				return nil
+			})
 		} else {
 			level.Warn(logger).Log("msg", "downsampling was explicitly disabled")
 		}
 
+		if err := eg.Wait(); err != nil {
+			return err
+		}
+
+		if err := sy.CleanMarkedBlocks(ctx); err != nil {
+			return errors.Wrap(err, "clean blocks marked for deletion")
+		}
+
 		if err := compact.ApplyRetentionPolicyByResolution(ctx, logger, bkt, retentionByResolution); err != nil {
 			return errors.Wrap(err, fmt.Sprintf("retention failed"))
 		}
+
+		if healthReporter != nil {
+			// The report is for debugging only, so do not fail the iteration on error.
+			if err := healthReporter.WriteReport(ctx); err != nil {
+				level.Warn(logger).Log("msg", "failed to write bucket health report", "err", err)
+			}
+		}
+		if bucketIndexUpdater != nil {
+			// Store gateways fall back to iterating the bucket if the index gets stale, so do not fail the iteration on error.
+			if _, err := bucketIndexUpdater.Update(ctx); err != nil {
+				level.Warn(logger).Log("msg", "failed to update bucket index", "err", err)
+			} else {
+				bucketIndexUpdated.SetToCurrentTime()
+			}
+		}
 		return nil
 	}
 
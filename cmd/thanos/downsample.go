@@ -2,11 +2,11 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
@@ -25,19 +25,91 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/tsdb"
 	"github.com/prometheus/tsdb/chunkenc"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
 
+// downsampleSeriesBytes is a rough estimate of how many bytes the streamed block writer keeps in memory
+// per series (labels, postings and chunk metas) until the index of the downsampled block is flushed.
+const downsampleSeriesBytes = 512
+
+type downsampleMetrics struct {
+	downsamples        *prometheus.CounterVec
+	downsampleFailures *prometheus.CounterVec
+	downsampleSkipped  *prometheus.CounterVec
+	downsampleDuration *prometheus.HistogramVec
+	pendingBlocks      *prometheus.GaugeVec
+	reservedMemory     prometheus.Gauge
+}
+
+func newDownsampleMetrics(reg prometheus.Registerer) *downsampleMetrics {
+	var m downsampleMetrics
+
+	m.downsamples = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_downsample_total",
+		Help: "Total number of blocks downsampled to the given resolution.",
+	}, []string{"resolution"})
+	m.downsampleFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_downsample_failures_total",
+		Help: "Total number of failed block downsamplings to the given resolution.",
+	}, []string{"resolution"})
+	m.downsampleSkipped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_downsample_skipped_unsupported_chunks_total",
+		Help: "Total number of block downsamplings to the given resolution skipped because the block contains chunks that cannot be downsampled, e.g. native histograms.",
+	}, []string{"resolution"})
+	m.downsampleDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "thanos_downsample_duration_seconds",
+		Help: "Time it took to download, downsample and upload a single block.",
+		Buckets: []float64{
+			1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600, 7200,
+		},
+	}, []string{"resolution"})
+	m.pendingBlocks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "thanos_downsample_pending_blocks",
+		Help: "Number of blocks still waiting to be downsampled to the given resolution in the current pass.",
+	}, []string{"resolution"})
+	m.reservedMemory = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "thanos_downsample_reserved_memory_bytes",
+		Help: "Estimated number of bytes reserved by blocks being currently downsampled.",
+	})
+
+	if reg != nil {
+		reg.MustRegister(
+			m.downsamples,
+			m.downsampleFailures,
+			m.downsampleSkipped,
+			m.downsampleDuration,
+			m.pendingBlocks,
+			m.reservedMemory,
+		)
+	}
+	return &m
+}
+
 func registerDownsample(m map[string]setupFunc, app *kingpin.Application, name string) {
 	cmd := app.Command(name, "continuously downsamples blocks in an object store bucket")
 
+	httpAddr := regHTTPAddrFlag(cmd)
+
 	dataDir := cmd.Flag("data-dir", "Data directory in which to cache blocks and process downsamplings.").
 		Default("./data").String()
 
 	objStoreConfig := regCommonObjStoreFlags(cmd, "", true)
 
+	consistencyDelay := modelDuration(cmd.Flag("consistency-delay", "Minimum age of fresh (non-compacted) blocks before they are being downsampled. "+
+		"Younger blocks may still be uploading or not be listable yet due to eventual consistency of the object storage.").
+		Default("30m"))
+
+	concurrency := cmd.Flag("downsample.concurrency", "Number of blocks that are downsampled in parallel.").
+		Default("1").Int()
+
+	memoryLimit := cmd.Flag("downsample.memory-limit", "Upper bound of estimated memory used by blocks downsampled in parallel. "+
+		"Blocks that would exceed it wait until enough memory is released. 0 means no limit.").
+		Default("0B").Bytes()
+
 	m[name] = func(g *run.Group, logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, _ bool) error {
-		return runDownsample(g, logger, reg, *dataDir, objStoreConfig)
+		return runDownsample(g, logger, reg, *httpAddr, *dataDir, objStoreConfig, time.Duration(*consistencyDelay), *concurrency, uint64(*memoryLimit))
 	}
 }
 
@@ -45,9 +117,19 @@ func runDownsample(
 	g *run.Group,
 	logger log.Logger,
 	reg *prometheus.Registry,
+	httpBindAddr string,
 	dataDir string,
 	objStoreConfig *pathOrContent,
+	consistencyDelay time.Duration,
+	concurrency int,
+	memoryLimit uint64,
 ) error {
+	if concurrency <= 0 {
+		return errors.Errorf("invalid downsample concurrency %d, must be > 0", concurrency)
+	}
+
+	metrics := newDownsampleMetrics(reg)
+
 	confContentYaml, err := objStoreConfig.Content()
 	if err != nil {
 		return err
@@ -74,13 +156,13 @@ func runDownsample(
 
 			level.Info(logger).Log("msg", "start first pass of downsampling")
 
-			if err := downsampleBucket(ctx, logger, bkt, dataDir); err != nil {
+			if err := downsampleBucket(ctx, logger, metrics, bkt, dataDir, consistencyDelay, concurrency, memoryLimit); err != nil {
 				return errors.Wrap(err, "downsampling failed")
 			}
 
 			level.Info(logger).Log("msg", "start second pass of downsampling")
 
-			if err := downsampleBucket(ctx, logger, bkt, dataDir); err != nil {
+			if err := downsampleBucket(ctx, logger, metrics, bkt, dataDir, consistencyDelay, concurrency, memoryLimit); err != nil {
 				return errors.Wrap(err, "downsampling failed")
 			}
 
@@ -90,15 +172,29 @@ func runDownsample(
 		})
 	}
 
+	if err := metricHTTPListenGroup(g, logger, reg, httpBindAddr); err != nil {
+		return err
+	}
+
 	level.Info(logger).Log("msg", "starting downsample node")
 	return nil
 }
 
+// downsampleTask describes a single block to be downsampled to the given resolution.
+type downsampleTask struct {
+	meta       *metadata.Meta
+	resolution int64
+}
+
 func downsampleBucket(
 	ctx context.Context,
 	logger log.Logger,
+	metrics *downsampleMetrics,
 	bkt objstore.Bucket,
 	dir string,
+	consistencyDelay time.Duration,
+	concurrency int,
+	memoryLimit uint64,
 ) error {
 	if err := os.RemoveAll(dir); err != nil {
 		return errors.Wrap(err, "clean working directory")
@@ -114,21 +210,33 @@ func downsampleBucket(
 			return nil
 		}
 
-		rc, err := bkt.Get(ctx, path.Join(id.String(), block.MetaFilename))
+		m, err := block.DownloadMeta(ctx, logger, bkt, id)
 		if err != nil {
+			// The block may still be uploading or its meta.json may not be visible yet.
+			if bkt.IsObjNotFoundErr(errors.Cause(err)) && isBlockTooFresh(id, consistencyDelay) {
+				level.Debug(logger).Log("msg", "block is too fresh for now", "block", id)
+				return nil
+			}
 			return errors.Wrapf(err, "get meta for block %s", id)
 		}
-		defer runutil.CloseWithLogOnErr(logger, rc, "block reader")
 
-		var m metadata.Meta
+		// Blocks produced by the compactor or a repair are complete once their meta.json is uploaded.
+		if isBlockTooFresh(id, consistencyDelay) &&
+			m.Thanos.Source != metadata.BucketRepairSource &&
+			m.Thanos.Source != metadata.CompactorSource &&
+			m.Thanos.Source != metadata.CompactorRepairSource {
+			level.Debug(logger).Log("msg", "block is too fresh for now", "block", id)
+			return nil
+		}
 
-		obj, err := ioutil.ReadAll(rc)
+		// Blocks marked for deletion are replaced or going away, downsampling them would only recreate their data.
+		marked, err := bkt.Exists(ctx, path.Join(id.String(), metadata.DeletionMarkFilename))
 		if err != nil {
-			return errors.Wrap(err, "read meta")
+			return errors.Wrapf(err, "check deletion mark of block %s", id)
 		}
-
-		if err = json.Unmarshal(obj, &m); err != nil {
-			return errors.Wrap(err, "unmarshal meta")
+		if marked {
+			level.Debug(logger).Log("msg", "block is marked for deletion", "block", id)
+			return nil
 		}
 
 		metas = append(metas, &m)
@@ -139,6 +247,21 @@ func downsampleBucket(
 		return errors.Wrap(err, "retrieve bucket block metas")
 	}
 
+	tasks, err := planDownsampling(metas)
+	if err != nil {
+		return err
+	}
+	return runDownsampleTasks(ctx, logger, metrics, bkt, dir, tasks, concurrency, memoryLimit)
+}
+
+// isBlockTooFresh returns true if the block was created less than consistencyDelay ago.
+func isBlockTooFresh(id ulid.ULID, consistencyDelay time.Duration) bool {
+	return ulid.Now()-id.Time() < uint64(consistencyDelay/time.Millisecond)
+}
+
+// planDownsampling returns all blocks that are not downsampled yet, together with the resolution
+// they should be downsampled to.
+func planDownsampling(metas []*metadata.Meta) ([]downsampleTask, error) {
 	// mapping from a hash over all source IDs to blocks. We don't need to downsample a block
 	// if a downsampled version with the same hash already exists.
 	sources5m := map[ulid.ULID]struct{}{}
@@ -157,10 +280,11 @@ func downsampleBucket(
 				sources1h[id] = struct{}{}
 			}
 		default:
-			return errors.Errorf("unexpected downsampling resolution %d", m.Thanos.Downsample.Resolution)
+			return nil, errors.Errorf("unexpected downsampling resolution %d", m.Thanos.Downsample.Resolution)
 		}
 	}
 
+	var tasks []downsampleTask
 	for _, m := range metas {
 		switch m.Thanos.Downsample.Resolution {
 		case 0:
@@ -180,9 +304,7 @@ func downsampleBucket(
 			if m.MaxTime-m.MinTime < 40*60*60*1000 {
 				continue
 			}
-			if err := processDownsampling(ctx, logger, bkt, m, dir, 5*60*1000); err != nil {
-				return errors.Wrap(err, "downsampling to 5 min")
-			}
+			tasks = append(tasks, downsampleTask{meta: m, resolution: 5 * 60 * 1000})
 
 		case 5 * 60 * 1000:
 			missing := false
@@ -201,14 +323,136 @@ func downsampleBucket(
 			if m.MaxTime-m.MinTime < 10*24*60*60*1000 {
 				continue
 			}
-			if err := processDownsampling(ctx, logger, bkt, m, dir, 60*60*1000); err != nil {
-				return errors.Wrap(err, "downsampling to 60 min")
+			tasks = append(tasks, downsampleTask{meta: m, resolution: 60 * 60 * 1000})
+		}
+	}
+	return tasks, nil
+}
+
+// runDownsampleTasks downsamples the given blocks using the given number of workers. If memoryLimit is non-zero,
+// a block is only processed once its estimated memory usage fits into the budget left by the blocks in progress.
+func runDownsampleTasks(
+	ctx context.Context,
+	logger log.Logger,
+	metrics *downsampleMetrics,
+	bkt objstore.Bucket,
+	dir string,
+	tasks []downsampleTask,
+	concurrency int,
+	memoryLimit uint64,
+) error {
+	for _, t := range tasks {
+		metrics.pendingBlocks.WithLabelValues(resolutionLabel(t.resolution)).Inc()
+	}
+	defer func() {
+		for _, res := range []int64{downsample.ResLevel1, downsample.ResLevel2} {
+			metrics.pendingBlocks.WithLabelValues(resolutionLabel(res)).Set(0)
+		}
+	}()
+
+	var (
+		taskCh   = make(chan downsampleTask)
+		mem      *semaphore.Weighted
+		memMtx   sync.Mutex
+		reserved uint64
+	)
+	if memoryLimit > 0 {
+		mem = semaphore.NewWeighted(int64(memoryLimit))
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	for i := 0; i < concurrency; i++ {
+		g.Go(func() error {
+			for t := range taskCh {
+				cost := estimateDownsampleMemory(t.meta)
+				if mem != nil {
+					// Blocks bigger than the whole budget are processed exclusively.
+					if cost > memoryLimit {
+						cost = memoryLimit
+					}
+					if err := mem.Acquire(gctx, int64(cost)); err != nil {
+						return err
+					}
+				}
+				memMtx.Lock()
+				reserved += cost
+				metrics.reservedMemory.Set(float64(reserved))
+				memMtx.Unlock()
+
+				err := processDownsamplingTask(gctx, logger, metrics, bkt, dir, t)
+
+				memMtx.Lock()
+				reserved -= cost
+				metrics.reservedMemory.Set(float64(reserved))
+				memMtx.Unlock()
+				if mem != nil {
+					mem.Release(int64(cost))
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		defer close(taskCh)
+		for _, t := range tasks {
+			select {
+			case <-gctx.Done():
+				return gctx.Err()
+			case taskCh <- t:
 			}
 		}
+		return nil
+	})
+	return g.Wait()
+}
+
+func processDownsamplingTask(ctx context.Context, logger log.Logger, metrics *downsampleMetrics, bkt objstore.Bucket, dir string, t downsampleTask) error {
+	res := resolutionLabel(t.resolution)
+	defer metrics.pendingBlocks.WithLabelValues(res).Dec()
+
+	begin := time.Now()
+	if err := processDownsampling(ctx, logger, bkt, t.meta, dir, t.resolution); err != nil {
+		if downsample.IsUnsupportedEncodingError(err) {
+			// Failing the whole pass would stop downsampling of every other block in the bucket.
+			level.Warn(logger).Log("msg", "skipping downsampling of block with chunks that cannot be downsampled",
+				"block", t.meta.ULID, "resolution", t.resolution, "err", err)
+			metrics.downsampleSkipped.WithLabelValues(res).Inc()
+			return nil
+		}
+		// The compactor garbage collects blocks while downsampling, so the block may be gone by now. Its data is part of
+		// the compacted block then, which is downsampled in a later pass.
+		if exists, eerr := bkt.Exists(ctx, path.Join(t.meta.ULID.String(), block.MetaFilename)); eerr == nil && !exists {
+			level.Info(logger).Log("msg", "block was deleted while downsampling, skipping", "block", t.meta.ULID, "err", err)
+			return nil
+		}
+		metrics.downsampleFailures.WithLabelValues(res).Inc()
+		return errors.Wrapf(err, "downsampling to %d", t.resolution)
 	}
+	metrics.downsamples.WithLabelValues(res).Inc()
+	metrics.downsampleDuration.WithLabelValues(res).Observe(time.Since(begin).Seconds())
 	return nil
 }
 
+// estimateDownsampleMemory returns a rough estimate of how many bytes downsampling the given block requires.
+// The series of the block are processed one by one, but the streamed block writer has to keep the index
+// data for all of them in memory.
+func estimateDownsampleMemory(m *metadata.Meta) uint64 {
+	est := m.Stats.NumSeries * downsampleSeriesBytes
+	if m.Stats.NumSeries > 0 {
+		// All samples of a single series are decoded at once. Each sample takes 16 bytes.
+		est += (m.Stats.NumSamples / m.Stats.NumSeries) * 16
+	}
+	return est
+}
+
+func resolutionLabel(res int64) string {
+	return strconv.FormatInt(res, 10)
+}
+
 func processDownsampling(ctx context.Context, logger log.Logger, bkt objstore.Bucket, m *metadata.Meta, dir string, resolution int64) error {
 	begin := time.Now()
 	bdir := filepath.Join(dir, m.ULID.String())
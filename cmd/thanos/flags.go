@@ -113,3 +113,37 @@ func regCommonObjStoreFlags(cmd *kingpin.CmdClause, suffix string, required bool
 		content: bucketConf,
 	}
 }
+
+func regRelabelConfigFlags(cmd *kingpin.CmdClause, required bool) *pathOrContent {
+	relabelConfigFile := cmd.Flag("relabel-config-file", "Path to YAML file that contains relabeling configuration in the Prometheus relabel_configs format.").
+		PlaceHolder("<relabel.config-yaml-path>").String()
+
+	relabelConfig := cmd.Flag("relabel-config", "Alternative to 'relabel-config-file' flag. Relabeling configuration in YAML.").
+		PlaceHolder("<relabel.config-yaml>").String()
+
+	return &pathOrContent{
+		fileFlagName:    "relabel-config-file",
+		contentFlagName: "relabel-config",
+		required:        required,
+
+		path:    relabelConfigFile,
+		content: relabelConfig,
+	}
+}
+
+func regSelectorRelabelFlags(cmd *kingpin.CmdClause) *pathOrContent {
+	selectorRelabelConfigFile := cmd.Flag("selector.relabel-config-file", "Path to YAML file that contains relabeling configuration that allows selecting blocks. It follows native Prometheus relabel-config syntax. See format details: https://prometheus.io/docs/prometheus/latest/configuration/configuration/#relabel_config ").
+		PlaceHolder("<selector.relabel-config-yaml-path>").String()
+
+	selectorRelabelConfig := cmd.Flag("selector.relabel-config", "Alternative to 'selector.relabel-config-file' flag. Relabeling configuration in YAML.").
+		PlaceHolder("<selector.relabel-config-yaml>").String()
+
+	return &pathOrContent{
+		fileFlagName:    "selector.relabel-config-file",
+		contentFlagName: "selector.relabel-config",
+		required:        false,
+
+		path:    selectorRelabelConfigFile,
+		content: selectorRelabelConfig,
+	}
+}
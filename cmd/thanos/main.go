@@ -10,11 +10,13 @@ import (
 	"net"
 	"net/http"
 	"net/http/pprof"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"strings"
 	"syscall"
 
 	gmetrics "github.com/armon/go-metrics"
@@ -26,6 +28,7 @@ import (
 	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/improbable-eng/thanos/pkg/runutil"
+	apistatus "github.com/improbable-eng/thanos/pkg/status"
 	"github.com/improbable-eng/thanos/pkg/tracing"
 	"github.com/oklog/run"
 	opentracing "github.com/opentracing/opentracing-go"
@@ -47,6 +50,9 @@ const (
 
 type setupFunc func(*run.Group, log.Logger, *prometheus.Registry, opentracing.Tracer, bool) error
 
+// appStatus serves the status endpoints of the Prometheus HTTP API with the flags the command was started with.
+var appStatus = apistatus.New(nil)
+
 func main() {
 	if os.Getenv("DEBUG") != "" {
 		runtime.SetMutexProfileFraction(10)
@@ -79,6 +85,8 @@ func main() {
 	registerBucket(cmds, app, "bucket")
 	registerDownsample(cmds, app, "downsample")
 	registerReceive(cmds, app, "receive")
+	registerQueryFrontend(cmds, app, "query-frontend")
+	registerCheck(cmds, app, "check")
 
 	cmd, err := app.Parse(os.Args[1:])
 	if err != nil {
@@ -86,6 +94,7 @@ func main() {
 		app.Usage(os.Args[1:])
 		os.Exit(2)
 	}
+	appStatus = apistatus.New(flagValues(app, cmd))
 
 	var logger log.Logger
 	{
@@ -213,6 +222,54 @@ func registerMetrics(mux *http.ServeMux, g prometheus.Gatherer) {
 	mux.Handle("/metrics", promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
 }
 
+// registerStatus registers the status endpoints of the Prometheus HTTP API under the given prefix.
+func registerStatus(mux *http.ServeMux, prefix string) {
+	appStatus.Register(mux, prefix)
+}
+
+// flagValues returns the values of the application flags and the flags of the given command by flag name. Values of
+// inline configuration flags are hidden and passwords of URLs are masked, as they may contain credentials, e.g. of
+// object storages or of Alertmanagers.
+func flagValues(app *kingpin.Application, cmd string) map[string]string {
+	model := app.Model()
+	flags := model.Flags
+	for _, c := range model.FlattenedCommands() {
+		if c.FullCommand == cmd {
+			flags = append(flags, c.Flags...)
+		}
+	}
+
+	res := make(map[string]string, len(flags))
+	for _, f := range flags {
+		if f.Name == "help" || f.Name == "version" {
+			continue
+		}
+		v := f.String()
+		if strings.HasSuffix(f.Name, "config") && v != "" {
+			v = "<hidden>"
+		}
+		res[f.Name] = maskURLPasswords(v)
+	}
+	return res
+}
+
+// maskURLPasswords masks the passwords of the URLs in the given flag value. Values of repeated flags are joined by
+// commas.
+func maskURLPasswords(v string) string {
+	vs := strings.Split(v, ",")
+	for i, s := range vs {
+		u, err := url.Parse(s)
+		if err != nil || u.User == nil {
+			continue
+		}
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+			vs[i] = u.String()
+		}
+	}
+	return strings.Join(vs, ",")
+}
+
 // defaultGRPCServerOpts returns default gRPC server opts that includes:
 // - request histogram
 // - tracing
@@ -295,10 +352,17 @@ func defaultGRPCServerOpts(logger log.Logger, reg *prometheus.Registry, tracer o
 }
 
 // metricHTTPListenGroup is a run.Group that servers HTTP endpoint with only Prometheus metrics.
-func metricHTTPListenGroup(g *run.Group, logger log.Logger, reg *prometheus.Registry, httpBindAddr string) error {
+// The register functions can add further handlers to the mux.
+func metricHTTPListenGroup(g *run.Group, logger log.Logger, reg *prometheus.Registry, httpBindAddr string, registers ...func(*http.ServeMux) error) error {
 	mux := http.NewServeMux()
 	registerMetrics(mux, reg)
 	registerProfile(mux)
+	registerStatus(mux, "")
+	for _, r := range registers {
+		if err := r(mux); err != nil {
+			return err
+		}
+	}
 
 	l, err := net.Listen("tcp", httpBindAddr)
 	if err != nil {
@@ -2,14 +2,13 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"fmt"
-	"io/ioutil"
 	"math"
 	"net"
 	"net/http"
 	"path"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-kit/kit/log"
@@ -19,18 +18,28 @@ import (
 	"github.com/improbable-eng/thanos/pkg/component"
 	"github.com/improbable-eng/thanos/pkg/discovery/cache"
 	"github.com/improbable-eng/thanos/pkg/discovery/dns"
+	"github.com/improbable-eng/thanos/pkg/exemplars"
+	"github.com/improbable-eng/thanos/pkg/exemplars/exemplarspb"
+	"github.com/improbable-eng/thanos/pkg/extgrpc"
 	"github.com/improbable-eng/thanos/pkg/extprom"
+	"github.com/improbable-eng/thanos/pkg/metadata"
+	"github.com/improbable-eng/thanos/pkg/metadata/metadatapb"
 	"github.com/improbable-eng/thanos/pkg/query"
 	v1 "github.com/improbable-eng/thanos/pkg/query/api"
+	"github.com/improbable-eng/thanos/pkg/rules"
+	"github.com/improbable-eng/thanos/pkg/rules/rulespb"
 	"github.com/improbable-eng/thanos/pkg/runutil"
 	"github.com/improbable-eng/thanos/pkg/store"
 	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/targets"
+	"github.com/improbable-eng/thanos/pkg/targets/targetspb"
 	"github.com/improbable-eng/thanos/pkg/tracing"
 	"github.com/improbable-eng/thanos/pkg/ui"
 	"github.com/oklog/run"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/route"
 	"github.com/prometheus/prometheus/discovery/file"
 	"github.com/prometheus/prometheus/discovery/targetgroup"
@@ -52,6 +61,9 @@ func registerQuery(m map[string]setupFunc, app *kingpin.Application, name string
 	key := cmd.Flag("grpc-client-tls-key", "TLS Key for the client's certificate").Default("").String()
 	caCert := cmd.Flag("grpc-client-tls-ca", "TLS CA Certificates to use to verify gRPC servers").Default("").String()
 	serverName := cmd.Flag("grpc-client-server-name", "Server name to verify the hostname on the returned gRPC certificates. See https://tools.ietf.org/html/rfc4366#section-3.1").Default("").String()
+	grpcCompression := cmd.Flag("grpc-client-compression", "Compression of the gRPC calls to the StoreAPI servers, which respond with the same compression. "+
+		"Compressing chunk-heavy Series responses reduces the network traffic at some CPU cost.").
+		Default(extgrpc.CompressionNone).Enum(extgrpc.Compressions...)
 
 	webRoutePrefix := cmd.Flag("web.route-prefix", "Prefix for API and UI endpoints. This allows thanos UI to be served on a sub-path. This option is analogous to --web.route-prefix of Promethus.").Default("").String()
 	webExternalPrefix := cmd.Flag("web.external-prefix", "Static prefix for all HTML links and redirect URLs in the UI query web interface. Actual endpoints are still served on / or the web.route-prefix. This allows thanos UI to be served behind a reverse proxy that strips a URL sub-path.").Default("").String()
@@ -60,11 +72,52 @@ func registerQuery(m map[string]setupFunc, app *kingpin.Application, name string
 	queryTimeout := modelDuration(cmd.Flag("query.timeout", "Maximum time to process query by query node.").
 		Default("2m"))
 
+	lookbackDelta := modelDuration(cmd.Flag("query.lookback-delta", "The maximum lookback duration for retrieving metrics during expression evaluations. "+
+		"Samples of downsampled data are further apart than the default, e.g. 1h for the 1h resolution, so queries of such data need a longer lookback delta to find them. "+
+		"It applies to all queries, the embedded PromQL engine does not support overriding it per query.").
+		Default("5m"))
+
 	maxConcurrentQueries := cmd.Flag("query.max-concurrent", "Maximum number of queries processed concurrently by query node.").
 		Default("20").Int()
 
-	replicaLabel := cmd.Flag("query.replica-label", "Label to treat as a replica indicator along which data is deduplicated. Still you will be able to query without deduplication using 'dedup=false' parameter.").
-		String()
+	maxSamples := cmd.Flag("query.max-samples", "Maximum number of samples a single query can load into memory. Queries exceeding it fail with 422 and the too_many_samples error type.").
+		Default("50000000").Int()
+
+	maxConcurrentSelects := cmd.Flag("query.max-concurrent-select", "Maximum number of Series requests of all queries sent to the stores concurrently. Further selects wait for a free slot. 0 means no limit.").
+		Default("0").Int()
+
+	maxQueuedQueries := cmd.Flag("query.max-queued", "Maximum number of queries waiting for one of the --query.max-concurrent slots. Further queries are rejected with 429 Too Many Requests. "+
+		"The default is 4 times the default of --query.max-concurrent, scale it together with that flag. 0 means no limit, which lets bursts of queries exhaust the memory of the querier.").
+		Default("80").Int()
+
+	activeQueryPath := cmd.Flag("query.active-query-path", "File to record the queries being evaluated in. Queries recorded there when the querier crashed are logged on the next start. Empty disables the active query tracking.").
+		Default("queries.active").String()
+
+	slowQueryLogThreshold := modelDuration(cmd.Flag("query.slow-query-log-threshold", "Minimum duration of queries to log them with the statistics of the stores they touched. 0s disables the slow query log.").
+		Default("0s"))
+
+	verticalShards := cmd.Flag("query.vertical-shards", "Number of partial queries aggregations grouping by labels are split into. Each partial query selects the series of one shard, partitioned by the grouping labels, and all of them are evaluated in parallel. 0 or 1 disables vertical sharding.").
+		Default("0").Int()
+
+	instantCacheTTL := modelDuration(cmd.Flag("query.instant-cache-ttl", "Time for which results of instant queries are cached and served to identical queries. Results may be stale for up to that long. 0s disables the cache.").
+		Default("0s"))
+
+	instantCacheStep := modelDuration(cmd.Flag("query.instant-cache-step", "Step the evaluation time of cached instant queries is rounded down to, so that queries at slightly different times share results. 0s uses the cache TTL.").
+		Default("0s"))
+
+	tenantLabel := cmd.Flag("query.tenant-label-name", "If set, queries, series and label requests are limited to the series of the tenant given in 'query.tenant-header', i.e. with this label set to the tenant. "+
+		"Requests without tenant or selecting series of other tenants are rejected.").
+		Default("").String()
+
+	tenantHeader := cmd.Flag("query.tenant-header", "HTTP header carrying the tenant of a request if 'query.tenant-label-name' is set.").
+		Default(store.DefaultTenantHeader).String()
+
+	replicaLabels := cmd.Flag("query.replica-label", "Labels to treat as a replica indicator along which data is deduplicated. Still you will be able to query without deduplication using 'dedup=false' parameter. "+
+		"Can be repeated, series equal without all of the given labels are deduplicated.").
+		Strings()
+
+	dedupAlgorithm := cmd.Flag("query.dedup-algorithm", "Algorithm merging the samples of replicas when deduplicating. 'penalty' sticks to one replica and switches only after gaps longer than twice the scrape interval, 'chain' merges the samples of all replicas ordered by timestamp.").
+		Default(query.DedupPenalty).Enum(query.DedupPenalty, query.DedupChain)
 
 	selectorLabels := cmd.Flag("selector-label", "Query selector labels that will be exposed in info endpoint (repeated).").
 		PlaceHolder("<name>=\"<value>\"").Strings()
@@ -72,6 +125,17 @@ func registerQuery(m map[string]setupFunc, app *kingpin.Application, name string
 	stores := cmd.Flag("store", "Addresses of statically configured store API servers (repeatable). The scheme may be prefixed with 'dns+' or 'dnssrv+' to detect store API servers through respective DNS lookups.").
 		PlaceHolder("<store>").Strings()
 
+	strictStores := cmd.Flag("store-strict", "Addresses of statically configured store API servers that are always kept in the store set, even if their health check fails (repeatable). "+
+		"Queries get errors or partial response warnings while such a server is unavailable, instead of silently missing its data. DNS lookups are not supported.").
+		PlaceHolder("<staticstore>").Strings()
+
+	endpointGroupsConfigFile := cmd.Flag("store.endpoint-groups-config-file", "Path to YAML file with groups of store API server addresses, each with its own TLS and bearer token client configuration. "+
+		"The addresses may be prefixed with 'dns+' or 'dnssrv+' like those of the store flag.").
+		PlaceHolder("<endpoint-groups.config-yaml-path>").String()
+
+	endpointGroupsConfig := cmd.Flag("store.endpoint-groups-config", "Alternative to 'store.endpoint-groups-config-file' flag. Endpoint groups configuration in YAML.").
+		PlaceHolder("<endpoint-groups.config-yaml>").String()
+
 	fileSDFiles := cmd.Flag("store.sd-files", "Path to files that contain addresses of store API servers. The path can be a glob pattern (repeatable).").
 		PlaceHolder("<path>").Strings()
 
@@ -87,9 +151,13 @@ func registerQuery(m map[string]setupFunc, app *kingpin.Application, name string
 
 	unhealthyStoreTimeout := modelDuration(cmd.Flag("store.unhealthy-timeout", "Timeout before an unhealthy store is cleaned from the store UI page.").Default("5m"))
 
-	enableAutodownsampling := cmd.Flag("query.auto-downsampling", "Enable automatic adjustment (step / 5) to what source of data should be used in store gateways if no max_source_resolution param is specified.").
+	enableAutodownsampling := cmd.Flag("query.auto-downsampling", "Enable automatic adjustment to what source of data should be used in store gateways if no max_source_resolution param is specified. "+
+		"The resolution is step / 5, or the resolution giving 1000 samples per series over the query range if higher. Queries can request it with 'max_source_resolution=auto' as well.").
 		Default("false").Bool()
 
+	autoDownsamplingMaxResolution := modelDuration(cmd.Flag("query.auto-downsampling.max-resolution", "Maximum resolution automatically selected for queries, e.g. 5m to never use 1h downsampled data automatically. 0 disables the limit.").
+		Default("0s"))
+
 	enablePartialResponse := cmd.Flag("query.partial-response", "Enable partial response for queries if no partial_response param is specified.").
 		Default("true").Bool()
 
@@ -97,12 +165,39 @@ func registerQuery(m map[string]setupFunc, app *kingpin.Application, name string
 
 	storeResponseTimeout := modelDuration(cmd.Flag("store.response-timeout", "If a Store doesn't send any data in this specified duration then a Store will be ignored and partial data will be returned if it's enabled. 0 disables timeout.").Default("0ms"))
 
+	storeResponseTimeoutOverrides := cmd.Flag("store.response-timeout-override", "Response timeout of the store with the given address instead of store.response-timeout (repeatable).").
+		PlaceHolder("<store>=<duration>").StringMap()
+
+	storeHedgeDelay := modelDuration(cmd.Flag("store.hedge-delay", "If a Store doesn't send any data in this duration then the Series request is sent to a replica of the Store as well, using the data of the replica responding first. "+
+		"Stores advertising the same labels and time range are replicas, only one of them is queried if set. 0 disables hedging.").Default("0s"))
+
+	storeGroupReplicas := cmd.Flag("store.group-replicas", "Treat Stores advertising the same labels and time range as replicas: Series requests are balanced across them and sent to the next replica only if one fails, instead of querying all of them.").
+		Default("false").Bool()
+
+	storeLabelValuesCacheTTL := modelDuration(cmd.Flag("store.label-values-cache-ttl", "Time for which all values of a label returned by a Store are used to skip the Store for requests with matchers none of the values match. "+
+		"Values are cached from label values requests without matchers, e.g. of dashboard variables. Series with new label values of the Store are not found for up to this time. 0 disables caching.").Default("0s"))
+
+	storeZone := cmd.Flag("store.zone", "Zone of this querier. If set, StoreAPI servers advertising another zone in the store.zone-label external label are queried only if the servers of this zone cannot cover the requested data.").
+		Default("").String()
+
+	storeZoneLabel := cmd.Flag("store.zone-label", "External label with which StoreAPI servers advertise their zone.").
+		Default("zone").String()
+
 	m[name] = func(g *run.Group, logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, _ bool) error {
 		selectorLset, err := parseFlagLabels(*selectorLabels)
 		if err != nil {
 			return errors.Wrap(err, "parse federation labels")
 		}
 
+		storeResponseTimeouts := make(map[string]time.Duration, len(*storeResponseTimeoutOverrides))
+		for addr, v := range *storeResponseTimeoutOverrides {
+			d, err := model.ParseDuration(v)
+			if err != nil {
+				return errors.Wrapf(err, "parse response timeout of store %s", addr)
+			}
+			storeResponseTimeouts[addr] = time.Duration(d)
+		}
+
 		lookupStores := map[string]struct{}{}
 		for _, s := range *stores {
 			if _, ok := lookupStores[s]; ok {
@@ -111,6 +206,30 @@ func registerQuery(m map[string]setupFunc, app *kingpin.Application, name string
 
 			lookupStores[s] = struct{}{}
 		}
+		for _, s := range *strictStores {
+			if _, ok := lookupStores[s]; ok {
+				return errors.Errorf("Address %s is duplicated for --store or --store-strict flag.", s)
+			}
+			if strings.HasPrefix(s, string(dns.A)+"+") || strings.HasPrefix(s, string(dns.SRV)+"+") || strings.HasPrefix(s, string(dns.SRVNoA)+"+") {
+				return errors.Errorf("Address %s of --store-strict flag must not use DNS lookups.", s)
+			}
+
+			lookupStores[s] = struct{}{}
+		}
+
+		endpointGroupsContentYaml, err := (&pathOrContent{
+			fileFlagName:    "store.endpoint-groups-config-file",
+			contentFlagName: "store.endpoint-groups-config",
+			path:            endpointGroupsConfigFile,
+			content:         endpointGroupsConfig,
+		}).Content()
+		if err != nil {
+			return errors.Wrap(err, "get content of endpoint groups configuration")
+		}
+		endpointGroupConfigs, err := query.LoadEndpointGroupConfigs(endpointGroupsContentYaml)
+		if err != nil {
+			return errors.Wrap(err, "parse endpoint groups configuration")
+		}
 
 		var fileSD *file.Discovery
 		if len(*fileSDFiles) > 0 {
@@ -137,17 +256,39 @@ func registerQuery(m map[string]setupFunc, app *kingpin.Application, name string
 			*key,
 			*caCert,
 			*serverName,
+			*grpcCompression,
 			*httpBindAddr,
 			*webRoutePrefix,
 			*webExternalPrefix,
 			*webPrefixHeaderName,
 			*maxConcurrentQueries,
+			*maxSamples,
+			*maxConcurrentSelects,
+			*maxQueuedQueries,
+			*activeQueryPath,
+			time.Duration(*slowQueryLogThreshold),
+			*verticalShards,
+			time.Duration(*instantCacheTTL),
+			time.Duration(*instantCacheStep),
+			*tenantLabel,
+			*tenantHeader,
 			time.Duration(*queryTimeout),
+			time.Duration(*lookbackDelta),
 			time.Duration(*storeResponseTimeout),
-			*replicaLabel,
+			storeResponseTimeouts,
+			time.Duration(*storeHedgeDelay),
+			*storeGroupReplicas,
+			time.Duration(*storeLabelValuesCacheTTL),
+			*storeZoneLabel,
+			*storeZone,
+			*replicaLabels,
+			*dedupAlgorithm,
 			selectorLset,
 			*stores,
+			*strictStores,
+			endpointGroupConfigs,
 			*enableAutodownsampling,
+			time.Duration(*autoDownsamplingMaxResolution),
 			*enablePartialResponse,
 			fileSD,
 			time.Duration(*dnsSDInterval),
@@ -157,7 +298,12 @@ func registerQuery(m map[string]setupFunc, app *kingpin.Application, name string
 	}
 }
 
-func storeClientGRPCOpts(logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, secure bool, cert, key, caCert string, serverName string) ([]grpc.DialOption, error) {
+func storeClientGRPCOpts(logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, compression string) ([]grpc.DialOption, error) {
+	compressionOpt, err := extgrpc.CallOption(compression)
+	if err != nil {
+		return nil, err
+	}
+
 	grpcMets := grpc_prometheus.NewClientMetrics()
 	grpcMets.EnableClientHandlingTimeHistogram(
 		grpc_prometheus.WithHistogramBuckets([]float64{
@@ -169,7 +315,7 @@ func storeClientGRPCOpts(logger log.Logger, reg *prometheus.Registry, tracer ope
 		// On TCP level we can be fine, but the gRPC overhead for huge messages could be significant.
 		// Current limit is ~2GB.
 		// TODO(bplotka): Split sent chunks on store node per max 4MB chunks if needed.
-		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(math.MaxInt32)),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(math.MaxInt32), compressionOpt),
 		grpc.WithUnaryInterceptor(
 			grpc_middleware.ChainUnaryClient(
 				grpcMets.UnaryClientInterceptor(),
@@ -187,55 +333,50 @@ func storeClientGRPCOpts(logger log.Logger, reg *prometheus.Registry, tracer ope
 	if reg != nil {
 		reg.MustRegister(grpcMets)
 	}
+	return dialOpts, nil
+}
 
-	if !secure {
-		return append(dialOpts, grpc.WithInsecure()), nil
+// storeClientTransportOpt returns the transport credentials of store clients, TLS if tlsConf is set.
+func storeClientTransportOpt(logger log.Logger, tlsConf *extgrpc.TLSConfig) (grpc.DialOption, error) {
+	if tlsConf == nil {
+		return grpc.WithInsecure(), nil
 	}
 
 	level.Info(logger).Log("msg", "Enabling client to server TLS")
 
-	var certPool *x509.CertPool
-
-	if caCert != "" {
-		caPEM, err := ioutil.ReadFile(caCert)
-		if err != nil {
-			return nil, errors.Wrap(err, "reading client CA")
-		}
-
-		certPool = x509.NewCertPool()
-		if !certPool.AppendCertsFromPEM(caPEM) {
-			return nil, errors.Wrap(err, "building client CA")
-		}
-		level.Info(logger).Log("msg", "TLS Client using provided certificate pool")
-	} else {
-		var err error
-		certPool, err = x509.SystemCertPool()
-		if err != nil {
-			return nil, errors.Wrap(err, "reading system certificate pool")
-		}
-		level.Info(logger).Log("msg", "TLS Client using system certificate pool")
-	}
-
-	tlsCfg := &tls.Config{
-		RootCAs: certPool,
+	tlsCfg, err := extgrpc.ClientTLSConfig(logger, *tlsConf)
+	if err != nil {
+		return nil, err
 	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)), nil
+}
 
-	if serverName != "" {
-		tlsCfg.ServerName = serverName
+// endpointGroupGRPCOpts returns the dial options of the stores of the given endpoint group.
+func endpointGroupGRPCOpts(logger log.Logger, baseOpts []grpc.DialOption, conf query.EndpointGroupConfig) ([]grpc.DialOption, error) {
+	transportOpt, err := storeClientTransportOpt(logger, conf.TLSConfig)
+	if err != nil {
+		return nil, err
 	}
+	opts := append(append([]grpc.DialOption{}, baseOpts...), transportOpt)
 
-	if cert != "" {
-		cert, err := tls.LoadX509KeyPair(cert, key)
+	switch {
+	case conf.BearerToken != "":
+		opts = append(opts, grpc.WithPerRPCCredentials(extgrpc.NewBearerTokenCredentials(conf.BearerToken)))
+	case conf.BearerTokenFile != "":
+		creds, err := extgrpc.NewBearerTokenFileCredentials(conf.BearerTokenFile)
 		if err != nil {
-			return nil, errors.Wrap(err, "client credentials")
+			return nil, err
 		}
-		tlsCfg.Certificates = []tls.Certificate{cert}
-		level.Info(logger).Log("msg", "TLS Client authentication enabled")
+		opts = append(opts, grpc.WithPerRPCCredentials(creds))
 	}
+	return opts, nil
+}
 
-	creds := credentials.NewTLS(tlsCfg)
-
-	return append(dialOpts, grpc.WithTransportCredentials(creds)), nil
+// endpointGroup is a group of store addresses dialed with their own client configuration.
+type endpointGroup struct {
+	addrs       []string
+	dialOpts    []grpc.DialOption
+	dnsProvider *dns.Provider
 }
 
 // runQuery starts a server that exposes PromQL Query API. It is responsible for querying configured
@@ -254,17 +395,39 @@ func runQuery(
 	key string,
 	caCert string,
 	serverName string,
+	grpcCompression string,
 	httpBindAddr string,
 	webRoutePrefix string,
 	webExternalPrefix string,
 	webPrefixHeaderName string,
 	maxConcurrentQueries int,
+	maxSamples int,
+	maxConcurrentSelects int,
+	maxQueuedQueries int,
+	activeQueryPath string,
+	slowQueryLogThreshold time.Duration,
+	verticalShards int,
+	instantCacheTTL time.Duration,
+	instantCacheStep time.Duration,
+	tenantLabel string,
+	tenantHeader string,
 	queryTimeout time.Duration,
+	lookbackDelta time.Duration,
 	storeResponseTimeout time.Duration,
-	replicaLabel string,
+	storeResponseTimeouts map[string]time.Duration,
+	storeHedgeDelay time.Duration,
+	storeGroupReplicas bool,
+	storeLabelValuesCacheTTL time.Duration,
+	storeZoneLabel string,
+	storeZone string,
+	replicaLabels []string,
+	dedupAlgorithm string,
 	selectorLset labels.Labels,
 	storeAddrs []string,
+	strictStoreAddrs []string,
+	endpointGroupConfigs []query.EndpointGroupConfig,
 	enableAutodownsampling bool,
+	autoDownsamplingMaxResolution time.Duration,
 	enablePartialResponse bool,
 	fileSD *file.Discovery,
 	dnsSDInterval time.Duration,
@@ -278,10 +441,44 @@ func runQuery(
 	})
 	reg.MustRegister(duplicatedStores)
 
-	dialOpts, err := storeClientGRPCOpts(logger, reg, tracer, secure, cert, key, caCert, serverName)
+	baseDialOpts, err := storeClientGRPCOpts(logger, reg, tracer, grpcCompression)
+	if err != nil {
+		return errors.Wrap(err, "building gRPC client")
+	}
+	var tlsConf *extgrpc.TLSConfig
+	if secure {
+		tlsConf = &extgrpc.TLSConfig{CAFile: caCert, CertFile: cert, KeyFile: key, ServerName: serverName}
+	}
+	transportOpt, err := storeClientTransportOpt(logger, tlsConf)
 	if err != nil {
 		return errors.Wrap(err, "building gRPC client")
 	}
+	dialOpts := append(baseDialOpts, transportOpt)
+
+	endpointGroups := make([]endpointGroup, 0, len(endpointGroupConfigs))
+	for i, conf := range endpointGroupConfigs {
+		opts, err := endpointGroupGRPCOpts(logger, baseDialOpts, conf)
+		if err != nil {
+			return errors.Wrapf(err, "building gRPC client of endpoint group %d", i)
+		}
+		endpointGroups = append(endpointGroups, endpointGroup{
+			addrs:    conf.Addresses,
+			dialOpts: opts,
+			dnsProvider: dns.NewProvider(
+				logger,
+				extprom.WrapRegistererWith(
+					prometheus.Labels{"group": strconv.Itoa(i)},
+					extprom.WrapRegistererWithPrefix("thanos_querier_endpoint_group_", reg),
+				),
+				dns.ResolverType(dnsSDResolver),
+			),
+		})
+	}
+
+	dedup, err := query.NewDeduplicator(dedupAlgorithm)
+	if err != nil {
+		return errors.Wrap(err, "deduplication algorithm")
+	}
 
 	fileSDCache := cache.New()
 	dnsProvider := dns.NewProvider(
@@ -290,6 +487,12 @@ func runQuery(
 		dns.ResolverType(dnsSDResolver),
 	)
 
+	if lookbackDelta <= 0 {
+		return errors.Errorf("lookback delta must be positive, got %s", lookbackDelta)
+	}
+	// The engine of this Prometheus version has no per engine or per query lookback delta.
+	promql.LookbackDelta = lookbackDelta
+
 	var (
 		stores = query.NewStoreSet(
 			logger,
@@ -297,7 +500,16 @@ func runQuery(
 			func() (specs []query.StoreSpec) {
 				// Add DNS resolved addresses from static flags and file SD.
 				for _, addr := range dnsProvider.Addresses() {
-					specs = append(specs, query.NewGRPCStoreSpec(addr))
+					specs = append(specs, query.NewGRPCStoreSpec(addr, false))
+				}
+				for _, eg := range endpointGroups {
+					for _, addr := range eg.dnsProvider.Addresses() {
+						specs = append(specs, query.NewGRPCStoreSpec(addr, false, eg.dialOpts...))
+					}
+				}
+				// Strict static stores are added last, so that they win over the same addresses from other sources.
+				for _, addr := range strictStoreAddrs {
+					specs = append(specs, query.NewGRPCStoreSpec(addr, true))
 				}
 
 				specs = removeDuplicateStoreSpecs(logger, duplicatedStores, specs)
@@ -307,16 +519,21 @@ func runQuery(
 			dialOpts,
 			unhealthyStoreTimeout,
 		)
-		proxy            = store.NewProxyStore(logger, stores.Get, component.Query, selectorLset, storeResponseTimeout)
-		queryableCreator = query.NewQueryableCreator(logger, proxy, replicaLabel)
+		proxy            = store.NewProxyStore(logger, stores.Get, component.Query, selectorLset, storeResponseTimeout, storeResponseTimeouts, storeHedgeDelay, storeGroupReplicas, storeLabelValuesCacheTTL, storeZoneLabel, storeZone)
+		queryableCreator = query.NewQueryableCreator(logger, selectProxy(proxy, maxConcurrentSelects, reg), replicaLabels, dedup)
+		exemplarsProxy   = exemplars.NewProxy(logger, stores.GetExemplarsClients)
+		metadataProxy    = metadata.NewProxy(logger, stores.GetMetadataClients)
+		targetsProxy     = targets.NewProxy(logger, stores.GetTargetsClients)
+		rulesProxy       = rules.NewProxy(logger, stores.GetRulesClients)
 		engine           = promql.NewEngine(
 			promql.EngineOpts{
-				Logger:        logger,
-				Reg:           reg,
-				MaxConcurrent: maxConcurrentQueries,
-				// TODO(bwplotka): Expose this as a flag: https://github.com/improbable-eng/thanos/issues/703
-				MaxSamples: math.MaxInt32,
-				Timeout:    queryTimeout,
+				Logger: logger,
+				Reg:    reg,
+				// The query scheduler of the API limits and queues concurrent queries, a second limit in the engine
+				// would only make queries that got a slot wait again.
+				MaxConcurrent: math.MaxInt32,
+				MaxSamples:    maxSamples,
+				Timeout:       queryTimeout,
 			},
 		)
 	)
@@ -357,8 +574,9 @@ func runQuery(
 						continue
 					}
 					fileSDCache.Update(update)
-					stores.Update(ctxUpdate)
+					// Resolve the new addresses first, so that the store set picks them up right away.
 					dnsProvider.Resolve(ctxUpdate, append(fileSDCache.Addresses(), storeAddrs...))
+					stores.Update(ctxUpdate)
 				case <-ctxUpdate.Done():
 					return nil
 				}
@@ -374,6 +592,9 @@ func runQuery(
 		g.Add(func() error {
 			return runutil.Repeat(dnsSDInterval, ctx.Done(), func() error {
 				dnsProvider.Resolve(ctx, append(fileSDCache.Addresses(), storeAddrs...))
+				for _, eg := range endpointGroups {
+					eg.dnsProvider.Resolve(ctx, eg.addrs)
+				}
 				return nil
 			})
 		}, func(error) {
@@ -399,7 +620,16 @@ func runQuery(
 
 		ui.NewQueryUI(logger, stores, flagsMap).Register(router.WithPrefix(webRoutePrefix))
 
-		api := v1.NewAPI(logger, reg, engine, queryableCreator, enableAutodownsampling, enablePartialResponse)
+		var tracker *v1.ActiveQueryTracker
+		if activeQueryPath != "" {
+			var err error
+			tracker, err = v1.NewActiveQueryTracker(logger, activeQueryPath, maxConcurrentQueries)
+			if err != nil {
+				level.Error(logger).Log("msg", "active query tracking disabled", "err", err)
+			}
+		}
+
+		api := v1.NewAPI(logger, reg, engine, queryableCreator, exemplars.NewGRPCClient(exemplarsProxy, replicaLabels), metadata.NewGRPCClient(metadataProxy), targets.NewGRPCClient(targetsProxy, replicaLabels), rules.NewGRPCClient(rulesProxy, replicaLabels), stores.GetStoreStatus, enableAutodownsampling, autoDownsamplingMaxResolution, enablePartialResponse, maxConcurrentQueries, maxQueuedQueries, tracker, slowQueryLogThreshold, verticalShards, instantCacheTTL, instantCacheStep, tenantLabel, tenantHeader)
 
 		api.Register(router.WithPrefix(path.Join(webRoutePrefix, "/api/v1")), tracer, logger)
 
@@ -413,6 +643,7 @@ func runQuery(
 		mux := http.NewServeMux()
 		registerMetrics(mux, reg)
 		registerProfile(mux)
+		registerStatus(mux, webRoutePrefix)
 		mux.Handle("/", router)
 
 		l, err := net.Listen("tcp", httpBindAddr)
@@ -442,6 +673,10 @@ func runQuery(
 
 		s := grpc.NewServer(opts...)
 		storepb.RegisterStoreServer(s, proxy)
+		exemplarspb.RegisterExemplarsServer(s, exemplarsProxy)
+		metadatapb.RegisterMetadataServer(s, metadataProxy)
+		targetspb.RegisterTargetsServer(s, targetsProxy)
+		rulespb.RegisterRulesServer(s, rulesProxy)
 
 		g.Add(func() error {
 			level.Info(logger).Log("msg", "Listening for StoreAPI gRPC", "address", grpcBindAddr)
@@ -472,3 +707,11 @@ func removeDuplicateStoreSpecs(logger log.Logger, duplicatedStores prometheus.Co
 	}
 	return deduplicated
 }
+
+// selectProxy returns the proxy limited to the given number of concurrent Series requests, if any.
+func selectProxy(proxy storepb.StoreServer, maxConcurrentSelects int, reg prometheus.Registerer) storepb.StoreServer {
+	if maxConcurrentSelects <= 0 {
+		return proxy
+	}
+	return query.NewGatedStore(proxy, store.NewGate(maxConcurrentSelects, 0, extprom.WrapRegistererWithPrefix("thanos_query_concurrent_selects_", reg)))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/queryfrontend"
+	"github.com/improbable-eng/thanos/pkg/runutil"
+	"github.com/improbable-eng/thanos/pkg/store"
+	"github.com/oklog/run"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+func registerQueryFrontend(m map[string]setupFunc, app *kingpin.Application, name string) {
+	cmd := app.Command(name, "query frontend splitting range queries, retrying and caching their results in front of the Query API of a querier")
+
+	httpBindAddr := regHTTPAddrFlag(cmd)
+
+	downstreamURL := cmd.Flag("query-frontend.downstream-url", "URL of the querier the Query API requests are sent to.").
+		Default("http://localhost:9090").URL()
+
+	splitInterval := modelDuration(cmd.Flag("query-range.split-interval", "Split range queries by this interval and execute them in parallel. 0 disables splitting.").
+		Default("24h"))
+
+	maxRetries := cmd.Flag("query-range.max-retries", "Maximum number of retries of a split query on server and transport errors.").
+		Default("5").Int()
+
+	alignWithStep := cmd.Flag("query-range.align-with-step", "Align start and end of range queries with their step, which makes results of refreshed dashboards cacheable.").
+		Default("true").Bool()
+
+	maxConcurrency := cmd.Flag("query-range.max-concurrency", "Maximum number of split queries of a range query executed in parallel.").
+		Default("14").Int()
+
+	maxFreshness := modelDuration(cmd.Flag("query-range.max-cache-freshness", "Results of split queries ending within this period before now are not cached, as they may still change.").
+		Default("10m"))
+
+	cacheTTL := modelDuration(cmd.Flag("query-range.cache-ttl", "TTL of cached results of split queries. 0 means no expiration.").
+		Default("0s"))
+
+	cacheConfigFile := cmd.Flag("query-range.response-cache-config-file", "Path to YAML file that contains the response cache configuration. If set, results of split queries are cached in IN-MEMORY, MEMCACHED or REDIS cache.").
+		PlaceHolder("<response-cache.config-yaml-path>").String()
+
+	cacheConfig := cmd.Flag("query-range.response-cache-config", "Alternative to 'query-range.response-cache-config-file' flag. Response cache configuration in YAML.").
+		PlaceHolder("<response-cache.config-yaml>").String()
+
+	tenantHeader := cmd.Flag("query-frontend.tenant-header", "HTTP header carrying the tenant of a request, as configured in 'query.tenant-header' of the querier. Results of split queries are cached per tenant.").
+		Default(store.DefaultTenantHeader).String()
+
+	m[name] = func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ bool) error {
+		return runQueryFrontend(
+			g,
+			logger,
+			reg,
+			*httpBindAddr,
+			*downstreamURL,
+			&pathOrContent{
+				fileFlagName:    "query-range.response-cache-config-file",
+				contentFlagName: "query-range.response-cache-config",
+				path:            cacheConfigFile,
+				content:         cacheConfig,
+			},
+			queryfrontend.Config{
+				SplitInterval:     time.Duration(*splitInterval),
+				MaxRetries:        *maxRetries,
+				AlignWithStep:     *alignWithStep,
+				MaxConcurrency:    *maxConcurrency,
+				MaxCacheFreshness: time.Duration(*maxFreshness),
+				CacheTTL:          time.Duration(*cacheTTL),
+				TenantHeader:      *tenantHeader,
+			},
+		)
+	}
+}
+
+func runQueryFrontend(
+	g *run.Group,
+	logger log.Logger,
+	reg *prometheus.Registry,
+	httpBindAddr string,
+	downstreamURL *url.URL,
+	cacheConfig *pathOrContent,
+	conf queryfrontend.Config,
+) error {
+	cacheContentYaml, err := cacheConfig.Content()
+	if err != nil {
+		return errors.Wrap(err, "get content of response cache configuration")
+	}
+	c, err := queryfrontend.NewResponseCacheFromConfig(logger, cacheContentYaml, reg)
+	if err != nil {
+		return errors.Wrap(err, "create response cache")
+	}
+
+	frontend := queryfrontend.NewFrontend(logger, reg, downstreamURL, c, conf)
+
+	mux := http.NewServeMux()
+	registerMetrics(mux, reg)
+	registerProfile(mux)
+	registerStatus(mux, "")
+	mux.Handle("/", frontend)
+
+	l, err := net.Listen("tcp", httpBindAddr)
+	if err != nil {
+		return errors.Wrapf(err, "listen HTTP on address %s", httpBindAddr)
+	}
+
+	g.Add(func() error {
+		level.Info(logger).Log("msg", "Listening for query frontend and metrics", "address", httpBindAddr, "downstream", downstreamURL.String())
+		return errors.Wrap(http.Serve(l, mux), "serve query frontend")
+	}, func(error) {
+		runutil.CloseWithLogOnErr(logger, l, "query frontend listener")
+	})
+
+	level.Info(logger).Log("msg", "starting query frontend")
+	return nil
+}
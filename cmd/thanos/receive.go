@@ -273,7 +273,7 @@ func runReceive(
 			}
 		}()
 
-		s := shipper.New(logger, reg, dataDir, bkt, func() labels.Labels { return lset }, metadata.ReceiveSource)
+		s := shipper.New(logger, reg, dataDir, bkt, func() labels.Labels { return lset }, nil, metadata.ReceiveSource, 1)
 
 		ctx, cancel := context.WithCancel(context.Background())
 		g.Add(func() error {
@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"fmt"
-	"math/rand"
 	"net"
 	"net/http"
 	"net/url"
@@ -11,9 +10,10 @@ import (
 	"os/signal"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -29,6 +29,8 @@ import (
 	"github.com/improbable-eng/thanos/pkg/promclient"
 	thanosrule "github.com/improbable-eng/thanos/pkg/rule"
 	v1 "github.com/improbable-eng/thanos/pkg/rule/api"
+	rulesapi "github.com/improbable-eng/thanos/pkg/rules"
+	"github.com/improbable-eng/thanos/pkg/rules/rulespb"
 	"github.com/improbable-eng/thanos/pkg/runutil"
 	"github.com/improbable-eng/thanos/pkg/shipper"
 	"github.com/improbable-eng/thanos/pkg/store"
@@ -46,8 +48,9 @@ import (
 	promlabels "github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/storage/tsdb"
-	"github.com/prometheus/prometheus/util/strutil"
+	promtsdb "github.com/prometheus/tsdb"
 	"github.com/prometheus/tsdb/labels"
 	"google.golang.org/grpc"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
@@ -61,6 +64,9 @@ func registerRule(m map[string]setupFunc, app *kingpin.Application, name string)
 
 	labelStrs := cmd.Flag("label", "Labels to be applied to all generated metrics (repeated). Similar to external labels for Prometheus, used to identify ruler and its blocks as unique source.").
 		PlaceHolder("<name>=\"<value>\"").Strings()
+	replicaLabels := cmd.Flag("label.replica", "Name of a --label which identifies the ruler replica of a HA pair (repeated). It is dropped from alerts before sending them to Alertmanager, so the alerts of all replicas are deduplicated, "+
+		"and recorded as replica label in the meta.json of uploaded blocks. It is kept on the blocks, as blocks of the replicas would overlap otherwise.").
+		Strings()
 
 	dataDir := cmd.Flag("data-dir", "data directory").Default("data/").String()
 
@@ -69,17 +75,37 @@ func registerRule(m map[string]setupFunc, app *kingpin.Application, name string)
 
 	evalInterval := modelDuration(cmd.Flag("eval-interval", "The default evaluation interval to use.").
 		Default("30s"))
+	evalConcurrency := cmd.Flag("eval-concurrency", "Maximum number of rule queries evaluated concurrently across all rule groups. Each group is evaluated independently of the others, its rules sequentially. 0 means no limit.").
+		Default("0").Int()
+
+	shardIndex := cmd.Flag("shard.index", "Index of this ruler among the --shard.count rulers that share the same rule files. Only rule groups whose name hashes to this index are evaluated.").
+		Default("0").Int()
+
+	shardCount := cmd.Flag("shard.count", "Number of rulers the rule groups are sharded across. Every group is evaluated by exactly one of them. 1 disables sharding.").
+		Default("1").Int()
+
+	tenantLabel := cmd.Flag("tenant-label-name", "Name of the label identifying the tenant of a series. Rules of groups with a 'tenant' field only select the series of this tenant and produce series and alerts with this label set to it.").
+		Default("tenant").String()
+
 	tsdbBlockDuration := modelDuration(cmd.Flag("tsdb.block-duration", "Block duration for TSDB block.").
 		Default("2h"))
-	tsdbRetention := modelDuration(cmd.Flag("tsdb.retention", "Block retention time on local disk.").
+	tsdbRetention := modelDuration(cmd.Flag("tsdb.retention", "Block retention time on local disk. With uploads to a bucket, blocks beyond the retention are deleted only once they are uploaded, so that they are not lost during object storage outages. 0 disables the retention.").
 		Default("48h"))
+	tsdbWALSegmentSize := cmd.Flag("tsdb.wal-segment-size", "Maximum size of a WAL segment file of the TSDB. 0B uses the TSDB default of 128MB.").
+		Default("0B").Bytes()
 
 	alertmgrs := cmd.Flag("alertmanagers.url", "Alertmanager replica URLs to push firing alerts. Ruler claims success if push to at least one alertmanager from discovered succeeds. The scheme may be prefixed with 'dns+' or 'dnssrv+' to detect Alertmanager IPs through respective DNS lookups. The port defaults to 9093 or the SRV record's value. The URL path is used as a prefix for the regular Alertmanager API path.").
 		Strings()
 
 	alertmgrsTimeout := cmd.Flag("alertmanagers.send-timeout", "Timeout for sending alerts to alertmanager").Default("10s").Duration()
 
-	alertQueryURL := cmd.Flag("alert.query-url", "The external Thanos Query URL that would be set in all alerts 'Source' field").String()
+	alertmgrsConfigFile := cmd.Flag("alertmanagers.config-file", "Path to YAML file that contains alerting configuration: HTTP client (TLS, basic auth, bearer token, proxy URL), static and file SD addresses of Alertmanager replicas, and alert relabel configs applied before alerts are sent. Alertmanagers are an alternative to --alertmanagers.url and --alertmanagers.send-timeout.").
+		PlaceHolder("<alertmanagers.config-yaml-path>").String()
+	alertmgrsConfig := cmd.Flag("alertmanagers.config", "Alternative to 'alertmanagers.config-file' flag. Alerting configuration in YAML.").
+		PlaceHolder("<alertmanagers.config-yaml>").String()
+
+	alertQueryURL := cmd.Flag("alert.query-url", "The external Thanos Query URL that would be set in all alerts 'Source' field. Alerts link to the graph of their expression over the time they were active. "+
+		"If it contains '{{', it is a Go template of the whole link executed with .Expr, .StartsAt, .EndsAt and .Labels of the alert, e.g. 'https://query.example/graph?g0.expr={{ urlquery .Expr }}&g0.tab=1'.").String()
 
 	alertExcludeLabels := cmd.Flag("alert.label-drop", "Labels by name to drop before sending to alertmanager. This allows alert to be deduplicated on replica label (repeated). Similar Prometheus alert relabelling").
 		Strings()
@@ -89,6 +115,11 @@ func registerRule(m map[string]setupFunc, app *kingpin.Application, name string)
 
 	objStoreConfig := regCommonObjStoreFlags(cmd, "", false)
 
+	remoteWriteConfigFile := cmd.Flag("remote-write.config-file", "Path to YAML file that contains remote write configuration in the format of the Prometheus remote_write section. If set, the ruler runs stateless: rule results are written to the configured endpoints (e.g. Thanos receive) instead of a local TSDB, and no StoreAPI is served.").
+		PlaceHolder("<remote-write.config-yaml-path>").String()
+	remoteWriteConfig := cmd.Flag("remote-write.config", "Alternative to 'remote-write.config-file' flag. Remote write configuration in YAML.").
+		PlaceHolder("<remote-write.config-yaml>").String()
+
 	queries := cmd.Flag("query", "Addresses of statically configured query API servers (repeatable). The scheme may be prefixed with 'dns+' or 'dnssrv+' to detect query API servers through respective DNS lookups.").
 		PlaceHolder("<query>").Strings()
 
@@ -109,17 +140,36 @@ func registerRule(m map[string]setupFunc, app *kingpin.Application, name string)
 		if err != nil {
 			return errors.Wrap(err, "parse labels")
 		}
-		alertQueryURL, err := url.Parse(*alertQueryURL)
+		for _, n := range *replicaLabels {
+			if lset.Get(n) == "" {
+				return errors.Errorf("replica label %s is not one of the --label labels", n)
+			}
+		}
+		generatorURL, err := alert.NewGeneratorURL(*alertQueryURL)
 		if err != nil {
 			return errors.Wrap(err, "parse alert query url")
 		}
+		if *alertQueryURL == "" {
+			level.Warn(logger).Log("msg", "no --alert.query-url given, alerts link to the expression relative to the Alertmanager URL")
+		}
 
+		shard := thanosrule.Shard{Index: *shardIndex, Count: *shardCount}
+		if err := shard.Validate(); err != nil {
+			return errors.Wrap(err, "invalid --shard.index or --shard.count")
+		}
+
+		// Min and max block duration are the same, so that blocks are never compacted locally and every block is
+		// uploaded by the shipper.
 		tsdbOpts := &tsdb.Options{
 			MinBlockDuration:  *tsdbBlockDuration,
 			MaxBlockDuration:  *tsdbBlockDuration,
 			RetentionDuration: *tsdbRetention,
+			WALSegmentSize:    *tsdbWALSegmentSize,
 			NoLockfile:        true,
 		}
+		if *tsdbRetention != 0 && *tsdbRetention < *tsdbBlockDuration {
+			return errors.Errorf("--tsdb.retention %s must not be shorter than --tsdb.block-duration %s", *tsdbRetention, *tsdbBlockDuration)
+		}
 
 		lookupQueries := map[string]struct{}{}
 		for _, q := range *queries {
@@ -143,13 +193,53 @@ func registerRule(m map[string]setupFunc, app *kingpin.Application, name string)
 			return errors.Errorf("No --query parameter was given.")
 		}
 
+		alertingContentYaml, err := (&pathOrContent{
+			fileFlagName:    "alertmanagers.config-file",
+			contentFlagName: "alertmanagers.config",
+			path:            alertmgrsConfigFile,
+			content:         alertmgrsConfig,
+		}).Content()
+		if err != nil {
+			return errors.Wrap(err, "get content of alerting configuration")
+		}
+		var alertingCfg alert.AlertingConfig
+		if len(alertingContentYaml) > 0 {
+			if alertingCfg, err = alert.LoadAlertingConfig(alertingContentYaml); err != nil {
+				return errors.Wrap(err, "parse alerting configuration")
+			}
+			if len(alertingCfg.Alertmanagers) > 0 && len(*alertmgrs) > 0 {
+				return errors.New("--alertmanagers.url and alertmanagers of --alertmanagers.config(-file) flags cannot be used together")
+			}
+		}
+		for _, addr := range *alertmgrs {
+			cfg, err := alert.BuildAlertmanagerConfig(addr, *alertmgrsTimeout)
+			if err != nil {
+				return errors.Wrapf(err, "parse --alertmanagers.url %s", addr)
+			}
+			alertingCfg.Alertmanagers = append(alertingCfg.Alertmanagers, cfg)
+		}
+
+		remoteWriteContentYaml, err := (&pathOrContent{
+			fileFlagName:    "remote-write.config-file",
+			contentFlagName: "remote-write.config",
+			path:            remoteWriteConfigFile,
+			content:         remoteWriteConfig,
+		}).Content()
+		if err != nil {
+			return errors.Wrap(err, "get content of remote write configuration")
+		}
+		remoteWriteConfigs, err := thanosrule.LoadRemoteWriteConfigs(remoteWriteContentYaml)
+		if err != nil {
+			return errors.Wrap(err, "parse remote write configuration")
+		}
+
 		return runRule(g,
 			logger,
 			reg,
 			tracer,
 			lset,
-			*alertmgrs,
-			*alertmgrsTimeout,
+			*replicaLabels,
+			alertingCfg,
 			*grpcBindAddr,
 			*cert,
 			*key,
@@ -159,11 +249,16 @@ func registerRule(m map[string]setupFunc, app *kingpin.Application, name string)
 			*webExternalPrefix,
 			*webPrefixHeaderName,
 			time.Duration(*evalInterval),
+			*evalConcurrency,
 			*dataDir,
 			*ruleFiles,
+			shard,
+			*tenantLabel,
 			objStoreConfig,
 			tsdbOpts,
-			alertQueryURL,
+			remoteWriteConfigs,
+			*alertQueryURL,
+			generatorURL,
 			*alertExcludeLabels,
 			*queries,
 			fileSD,
@@ -175,14 +270,15 @@ func registerRule(m map[string]setupFunc, app *kingpin.Application, name string)
 
 // runRule runs a rule evaluation component that continuously evaluates alerting and recording
 // rules. It sends alert notifications and writes TSDB data for results like a regular Prometheus server.
+// If remote write endpoints are configured, results are written to them instead and no local TSDB is kept.
 func runRule(
 	g *run.Group,
 	logger log.Logger,
 	reg *prometheus.Registry,
 	tracer opentracing.Tracer,
 	lset labels.Labels,
-	alertmgrURLs []string,
-	alertmgrsTimeout time.Duration,
+	replicaLabels []string,
+	alertingCfg alert.AlertingConfig,
 	grpcBindAddr string,
 	cert string,
 	key string,
@@ -192,11 +288,16 @@ func runRule(
 	webExternalPrefix string,
 	webPrefixHeaderName string,
 	evalInterval time.Duration,
+	evalConcurrency int,
 	dataDir string,
 	ruleFiles []string,
+	shard thanosrule.Shard,
+	tenantLabel string,
 	objStoreConfig *pathOrContent,
 	tsdbOpts *tsdb.Options,
-	alertQueryURL *url.URL,
+	remoteWriteConfigs []*thanosrule.RemoteWriteConfig,
+	alertQueryURL string,
+	generatorURL *alert.GeneratorURL,
 	alertExcludeLabels []string,
 	queryAddrs []string,
 	fileSD *file.Discovery,
@@ -234,13 +335,33 @@ func runRule(
 	)
 	ruleEvalWarnings.WithLabelValues(strings.ToLower(storepb.PartialResponseStrategy_ABORT.String()))
 	ruleEvalWarnings.WithLabelValues(strings.ToLower(storepb.PartialResponseStrategy_WARN.String()))
+	queryFailures := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "thanos_rule_evaluation_query_failures_total",
+			Help: "The total number of failed rule evaluation queries per query API endpoint. Failed queries are retried against the other endpoints.",
+		}, []string{"endpoint"},
+	)
+	limitsExceeded := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "thanos_rule_evaluation_limit_exceeded_total",
+			Help: "The total number of rule evaluations which failed because they exceeded the query_timeout or sample_limit of their rule group.",
+		}, []string{"strategy", "limit"},
+	)
+
+	tsdbOpenDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "thanos_rule_tsdb_open_duration_seconds",
+		Help: "Time it took to open the TSDB on startup, which is dominated by the replay of the WAL.",
+	})
 
 	reg.MustRegister(configSuccess)
+	reg.MustRegister(tsdbOpenDuration)
 	reg.MustRegister(configSuccessTime)
 	reg.MustRegister(duplicatedQuery)
 	reg.MustRegister(alertMngrAddrResolutionErrors)
 	reg.MustRegister(rulesLoaded)
 	reg.MustRegister(ruleEvalWarnings)
+	reg.MustRegister(queryFailures)
+	reg.MustRegister(limitsExceeded)
 
 	for _, addr := range queryAddrs {
 		if addr == "" {
@@ -248,11 +369,39 @@ func runRule(
 		}
 	}
 
-	db, err := tsdb.Open(dataDir, log.With(logger, "component", "tsdb"), reg, tsdbOpts)
+	confContentYaml, err := objStoreConfig.Content()
 	if err != nil {
-		return errors.Wrap(err, "open TSDB")
+		return err
 	}
-	{
+
+	// Rule results are either written to a local TSDB, which is served via StoreAPI and shipped to the bucket,
+	// or in stateless mode to remote write endpoints only.
+	var (
+		st storage.Storage
+		db *promtsdb.DB
+	)
+	if len(remoteWriteConfigs) > 0 {
+		rws, err := thanosrule.NewRemoteWriteStorage(log.With(logger, "component", "remote-write"), reg, remoteWriteConfigs, labelsTSDBToProm(lset))
+		if err != nil {
+			return errors.Wrap(err, "create remote write storage")
+		}
+		st = rws
+		level.Info(logger).Log("msg", "running stateless, rule results are written to remote write endpoints", "endpoints", len(remoteWriteConfigs))
+	} else {
+		opts := *tsdbOpts
+		if len(confContentYaml) > 0 {
+			// The TSDB applies the retention whenever it reloads its blocks, including when it is opened, whether the
+			// blocks were uploaded or not. With uploads, the shipper marks blocks beyond the retention as deletable
+			// once they are uploaded instead.
+			opts.RetentionDuration = 0
+		}
+		begin := time.Now()
+		db, err = tsdb.Open(dataDir, log.With(logger, "component", "tsdb"), reg, &opts)
+		if err != nil {
+			return errors.Wrap(err, "open TSDB")
+		}
+		tsdbOpenDuration.Set(time.Since(begin).Seconds())
+		level.Info(logger).Log("msg", "TSDB opened", "duration", time.Since(begin))
 		done := make(chan struct{})
 		g.Add(func() error {
 			<-done
@@ -260,6 +409,7 @@ func runRule(
 		}, func(error) {
 			close(done)
 		})
+		st = tsdb.Adapter(db, 0)
 	}
 
 	// FileSD query addresses.
@@ -272,11 +422,21 @@ func runRule(
 	)
 
 	// Run rule evaluation and alert notifications.
+	// Replica labels are dropped from alerts, so Alertmanager deduplicates the alerts of all replicas.
+	excludeLabels := append(append([]string{}, replicaLabels...), alertExcludeLabels...)
 	var (
-		alertmgrs = newAlertmanagerSet(logger, alertmgrURLs, dns.ResolverType(dnsSDResolver))
-		alertQ    = alert.NewQueue(logger, reg, 10000, 100, labelsTSDBToProm(lset), alertExcludeLabels)
-		ruleMgrs  = thanosrule.Managers{}
+		alertmgrs   []*alert.Alertmanager
+		alertQ      = alert.NewQueue(logger, reg, 10000, 100, labelsTSDBToProm(lset), excludeLabels, alertingCfg.AlertRelabelConfigs)
+		ruleMgrs    = thanosrule.Managers{}
+		groupLimits = thanosrule.NewLimits()
 	)
+	for _, cfg := range alertingCfg.Alertmanagers {
+		am, err := alert.NewAlertmanager(logger, cfg, dns.NewResolver(dns.ResolverType(dnsSDResolver).ToResolver(logger)))
+		if err != nil {
+			return errors.Wrap(err, "create alertmanager client")
+		}
+		alertmgrs = append(alertmgrs, am)
+	}
 	{
 		notify := func(ctx context.Context, expr string, alerts ...*rules.Alert) {
 			res := make([]*alert.Alert, 0, len(alerts))
@@ -285,11 +445,15 @@ func runRule(
 				if alrt.State == rules.StatePending {
 					continue
 				}
+				u, err := generatorURL.URL(expr, alrt.Labels, alrt.ActiveAt, alrt.ResolvedAt)
+				if err != nil {
+					level.Warn(logger).Log("msg", "building alert generator URL failed", "alert", alrt.Labels.Get(promlabels.AlertName), "err", err)
+				}
 				a := &alert.Alert{
 					StartsAt:     alrt.FiredAt,
 					Labels:       alrt.Labels,
 					Annotations:  alrt.Annotations,
-					GeneratorURL: alertQueryURL.String() + strutil.TableLinkForExpression(expr),
+					GeneratorURL: u,
 				}
 				if !alrt.ResolvedAt.IsZero() {
 					a.EndsAt = alrt.ResolvedAt
@@ -298,8 +462,6 @@ func runRule(
 			}
 			alertQ.Push(res)
 		}
-		st := tsdb.Adapter(db, 0)
-
 		opts := rules.ManagerOptions{
 			NotifyFunc:  notify,
 			Logger:      log.With(logger, "component", "rules"),
@@ -308,6 +470,13 @@ func runRule(
 			TSDB:        st,
 		}
 
+		// The gate is shared by the managers of all strategies, so the limit applies to all groups. Groups are
+		// sequential internally, so it bounds the number of concurrently evaluated groups.
+		var evalGate *store.Gate
+		if evalConcurrency > 0 {
+			evalGate = store.NewGate(evalConcurrency, 0, extprom.WrapRegistererWithPrefix("thanos_rule_concurrent_evaluations_", reg))
+		}
+
 		for _, strategy := range storepb.PartialResponseStrategy_value {
 			s := storepb.PartialResponseStrategy(strategy)
 
@@ -317,7 +486,11 @@ func runRule(
 			opts := opts
 			opts.Registerer = extprom.WrapRegistererWith(prometheus.Labels{"strategy": strings.ToLower(s.String())}, reg)
 			opts.Context = ctx
-			opts.QueryFunc = queryFunc(logger, dnsProvider, duplicatedQuery, ruleEvalWarnings, s)
+			opts.QueryFunc = queryFunc(logger, dnsProvider, duplicatedQuery, ruleEvalWarnings, queryFailures, s)
+			opts.QueryFunc = limitedQueryFunc(groupLimits, limitsExceeded.MustCurryWith(prometheus.Labels{"strategy": strings.ToLower(s.String())}), opts.QueryFunc)
+			if evalGate != nil {
+				opts.QueryFunc = gatedQueryFunc(evalGate, opts.QueryFunc)
+			}
 
 			ruleMgrs[s] = rules.NewManager(&opts)
 			g.Add(func() error {
@@ -333,7 +506,7 @@ func runRule(
 	}
 	{
 		// TODO(bwplotka): https://github.com/improbable-eng/thanos/issues/660
-		sdr := alert.NewSender(logger, reg, alertmgrs.get, nil, alertmgrsTimeout)
+		sdr := alert.NewSender(logger, reg, alertmgrs)
 		ctx, cancel := context.WithCancel(context.Background())
 
 		g.Add(func() error {
@@ -350,12 +523,19 @@ func runRule(
 			cancel()
 		})
 	}
-	{
+	for _, am := range alertmgrs {
+		am := am
 		ctx, cancel := context.WithCancel(context.Background())
 
+		g.Add(func() error {
+			am.Discover(ctx)
+			return nil
+		}, func(error) {
+			cancel()
+		})
 		g.Add(func() error {
 			return runutil.Repeat(30*time.Second, ctx.Done(), func() error {
-				if err := alertmgrs.update(ctx); err != nil {
+				if err := am.Resolve(ctx); err != nil {
 					level.Error(logger).Log("msg", "refreshing alertmanagers failed", "err", err)
 					alertMngrAddrResolutionErrors.Inc()
 				}
@@ -389,6 +569,8 @@ func runRule(
 						continue
 					}
 					fileSDCache.Update(update)
+					// Resolve right away, so that new query addresses are used without waiting for the next DNS SD interval.
+					dnsProvider.Resolve(ctxUpdate, append(fileSDCache.Addresses(), queryAddrs...))
 				case <-ctxUpdate.Done():
 					return nil
 				}
@@ -401,49 +583,66 @@ func runRule(
 
 	// Handle reload and termination interrupts.
 	reload := make(chan struct{}, 1)
+	// Reloads requested via the web handler report their result back.
+	reloadWebhandler := make(chan chan error)
 	{
 		cancel := make(chan struct{})
 		reload <- struct{}{} // initial reload
 
-		g.Add(func() error {
-			for {
-				select {
-				case <-cancel:
-					return errors.New("canceled")
-				case <-reload:
+		reloadRules := func() error {
+			level.Debug(logger).Log("msg", "configured rule files", "files", strings.Join(ruleFiles, ","))
+			var (
+				files []string
+				seen  = map[string]struct{}{}
+			)
+			for _, pat := range ruleFiles {
+				fs, err := filepath.Glob(pat)
+				if err != nil {
+					// The only error can be a bad pattern.
+					level.Error(logger).Log("msg", "retrieving rule files failed. Ignoring file.", "pattern", pat, "err", err)
+					continue
 				}
 
-				level.Debug(logger).Log("msg", "configured rule files", "files", strings.Join(ruleFiles, ","))
-				var files []string
-				for _, pat := range ruleFiles {
-					fs, err := filepath.Glob(pat)
-					if err != nil {
-						// The only error can be a bad pattern.
-						level.Error(logger).Log("msg", "retrieving rule files failed. Ignoring file.", "pattern", pat, "err", err)
+				for _, f := range fs {
+					// Files matched by multiple patterns are loaded once.
+					if _, ok := seen[f]; ok {
 						continue
 					}
-
-					files = append(files, fs...)
+					seen[f] = struct{}{}
+					files = append(files, f)
 				}
+			}
 
-				level.Info(logger).Log("msg", "reload rule files", "numFiles", len(files))
+			level.Info(logger).Log("msg", "reload rule files", "numFiles", len(files))
 
-				if err := ruleMgrs.Update(dataDir, evalInterval, files); err != nil {
-					configSuccess.Set(0)
-					level.Error(logger).Log("msg", "reloading rules failed", "err", err)
-					continue
-				}
+			if err := ruleMgrs.Update(dataDir, evalInterval, files, thanosrule.UpdateOptions{Shard: shard, TenantLabel: tenantLabel, Limits: groupLimits}); err != nil {
+				configSuccess.Set(0)
+				level.Error(logger).Log("msg", "reloading rules failed", "err", err)
+				return err
+			}
 
-				configSuccess.Set(1)
-				configSuccessTime.Set(float64(time.Now().UnixNano()) / 1e9)
+			configSuccess.Set(1)
+			configSuccessTime.Set(float64(time.Now().UnixNano()) / 1e9)
 
-				rulesLoaded.Reset()
-				for s, mgr := range ruleMgrs {
-					for _, group := range mgr.RuleGroups() {
-						rulesLoaded.WithLabelValues(s.String(), group.File(), group.Name()).Set(float64(len(group.Rules())))
-					}
+			rulesLoaded.Reset()
+			for s, mgr := range ruleMgrs {
+				for _, group := range mgr.RuleGroups() {
+					rulesLoaded.WithLabelValues(s.String(), group.File(), group.Name()).Set(float64(len(group.Rules())))
 				}
+			}
+			return nil
+		}
 
+		g.Add(func() error {
+			for {
+				select {
+				case <-cancel:
+					return errors.New("canceled")
+				case <-reload:
+					_ = reloadRules()
+				case errc := <-reloadWebhandler:
+					errc <- reloadRules()
+				}
 			}
 		}, func(error) {
 			close(cancel)
@@ -490,14 +689,15 @@ func runRule(
 		}
 		logger := log.With(logger, "component", component.Rule.String())
 
-		store := store.NewTSDBStore(logger, reg, db, component.Rule, lset)
-
 		opts, err := defaultGRPCServerOpts(logger, reg, tracer, cert, key, clientCA)
 		if err != nil {
 			return errors.Wrap(err, "setup gRPC options")
 		}
 		s := grpc.NewServer(opts...)
-		storepb.RegisterStoreServer(s, store)
+		if db != nil {
+			storepb.RegisterStoreServer(s, store.NewTSDBStore(logger, reg, db, component.Rule, lset))
+		}
+		rulespb.RegisterRulesServer(s, rulesapi.NewRuler(ruleMgrs, lset))
 
 		g.Add(func() error {
 			return errors.Wrap(s.Serve(l), "serve gRPC")
@@ -517,9 +717,19 @@ func runRule(
 			})
 		}
 
-		router.WithPrefix(webRoutePrefix).Post("/-/reload", func(w http.ResponseWriter, r *http.Request) {
-			reload <- struct{}{}
-		})
+		reloadHandler := func(w http.ResponseWriter, r *http.Request) {
+			errc := make(chan error)
+			select {
+			case reloadWebhandler <- errc:
+			case <-r.Context().Done():
+				return
+			}
+			if err := <-errc; err != nil {
+				http.Error(w, fmt.Sprintf("failed to reload rules: %s", err), http.StatusInternalServerError)
+			}
+		}
+		router.WithPrefix(webRoutePrefix).Post("/-/reload", reloadHandler)
+		router.WithPrefix(webRoutePrefix).Put("/-/reload", reloadHandler)
 
 		flagsMap := map[string]string{
 			// TODO(bplotka in PR #513 review): pass all flags, not only the flags needed by prefix rewriting.
@@ -527,7 +737,7 @@ func runRule(
 			"web.prefix-header":   webPrefixHeaderName,
 		}
 
-		ui.NewRuleUI(logger, ruleMgrs, alertQueryURL.String(), flagsMap).Register(router.WithPrefix(webRoutePrefix))
+		ui.NewRuleUI(logger, ruleMgrs, alertQueryURL, flagsMap).Register(router.WithPrefix(webRoutePrefix))
 
 		api := v1.NewAPI(logger, ruleMgrs)
 		api.Register(router.WithPrefix(path.Join(webRoutePrefix, "/api/v1")), tracer, logger)
@@ -535,6 +745,7 @@ func runRule(
 		mux := http.NewServeMux()
 		registerMetrics(mux, reg)
 		registerProfile(mux)
+		registerStatus(mux, webRoutePrefix)
 		mux.Handle("/", router)
 
 		l, err := net.Listen("tcp", httpBindAddr)
@@ -550,16 +761,15 @@ func runRule(
 		})
 	}
 
-	confContentYaml, err := objStoreConfig.Content()
-	if err != nil {
-		return err
-	}
-
 	uploads := true
 	if len(confContentYaml) == 0 {
 		level.Info(logger).Log("msg", "No supported bucket was configured, uploads will be disabled")
 		uploads = false
 	}
+	if uploads && db == nil {
+		level.Warn(logger).Log("msg", "bucket configuration is ignored in stateless mode, there are no blocks to upload")
+		uploads = false
+	}
 
 	if uploads {
 		// The background shipper continuously scans the data directory and uploads
@@ -576,7 +786,7 @@ func runRule(
 			}
 		}()
 
-		s := shipper.New(logger, nil, dataDir, bkt, func() labels.Labels { return lset }, metadata.RulerSource)
+		s := shipper.New(logger, reg, dataDir, bkt, func() labels.Labels { return lset }, replicaLabels, metadata.RulerSource, 1)
 
 		ctx, cancel := context.WithCancel(context.Background())
 
@@ -587,6 +797,12 @@ func runRule(
 				if _, err := s.Sync(ctx); err != nil {
 					level.Warn(logger).Log("err", err)
 				}
+				if tsdbOpts.RetentionDuration == 0 {
+					return nil
+				}
+				if _, err := s.MarkDeletable(time.Duration(tsdbOpts.RetentionDuration)); err != nil {
+					level.Warn(logger).Log("msg", "marking blocks beyond the retention as deletable failed", "err", err)
+				}
 				return nil
 			})
 		}, func(error) {
@@ -598,81 +814,6 @@ func runRule(
 	return nil
 }
 
-type alertmanagerSet struct {
-	resolver dns.Resolver
-	addrs    []string
-	mtx      sync.Mutex
-	current  []*url.URL
-}
-
-func newAlertmanagerSet(logger log.Logger, addrs []string, dnsSDResolver dns.ResolverType) *alertmanagerSet {
-	return &alertmanagerSet{
-		resolver: dns.NewResolver(dnsSDResolver.ToResolver(logger)),
-		addrs:    addrs,
-	}
-}
-
-func (s *alertmanagerSet) get() []*url.URL {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	return s.current
-}
-
-const defaultAlertmanagerPort = 9093
-
-func (s *alertmanagerSet) update(ctx context.Context) error {
-	var result []*url.URL
-	for _, addr := range s.addrs {
-		var (
-			name           = addr
-			qtype          dns.QType
-			resolvedDomain []string
-		)
-
-		if nameQtype := strings.SplitN(addr, "+", 2); len(nameQtype) == 2 {
-			name, qtype = nameQtype[1], dns.QType(nameQtype[0])
-		}
-
-		u, err := url.Parse(name)
-		if err != nil {
-			return errors.Wrapf(err, "parse URL %q", name)
-		}
-
-		// Get only the host and resolve it if needed.
-		host := u.Host
-		if qtype != "" {
-			if qtype == dns.A {
-				_, _, err = net.SplitHostPort(host)
-				if err != nil {
-					// The host could be missing a port. Append the defaultAlertmanagerPort.
-					host = host + ":" + strconv.Itoa(defaultAlertmanagerPort)
-				}
-			}
-			resolvedDomain, err = s.resolver.Resolve(ctx, host, qtype)
-			if err != nil {
-				return errors.Wrap(err, "alertmanager resolve")
-			}
-		} else {
-			resolvedDomain = []string{host}
-		}
-
-		for _, resolved := range resolvedDomain {
-			result = append(result, &url.URL{
-				Scheme: u.Scheme,
-				Host:   resolved,
-				Path:   u.Path,
-				User:   u.User,
-			})
-		}
-	}
-
-	s.mtx.Lock()
-	s.current = result
-	s.mtx.Unlock()
-
-	return nil
-}
-
 func parseFlagLabels(s []string) (labels.Labels, error) {
 	var lset labels.Labels
 	for _, l := range s {
@@ -719,13 +860,15 @@ func removeDuplicateQueryAddrs(logger log.Logger, duplicatedQueriers prometheus.
 	return deduplicated
 }
 
-// queryFunc returns query function that hits the HTTP query API of query peers in randomized order until we get a result
-// back or the context get canceled.
+// queryFunc returns query function that hits the HTTP query API of query peers until we get a result back or the
+// context get canceled. Query peers are used in round-robin order across evaluations and a failed query is retried
+// against the next peer.
 func queryFunc(
 	logger log.Logger,
 	dnsProvider *dns.Provider,
 	duplicatedQuery prometheus.Counter,
 	ruleEvalWarnings *prometheus.CounterVec,
+	queryFailures *prometheus.CounterVec,
 	partialResponseStrategy storepb.PartialResponseStrategy,
 ) rules.QueryFunc {
 	var spanID string
@@ -740,17 +883,27 @@ func queryFunc(
 		panic(errors.Errorf("unknown partial response strategy %v", partialResponseStrategy).Error())
 	}
 
+	var next uint64
 	return func(ctx context.Context, q string, t time.Time) (promql.Vector, error) {
 		// Add DNS resolved addresses from static flags and file SD.
 		// TODO(bwplotka): Consider generating addresses in *url.URL
 		addrs := dnsProvider.Addresses()
 
-		removeDuplicateQueryAddrs(logger, duplicatedQuery, addrs)
+		addrs = removeDuplicateQueryAddrs(logger, duplicatedQuery, addrs)
+		// Addresses are not sorted by the provider, sort them to keep the round-robin order stable.
+		sort.Strings(addrs)
 
-		for _, i := range rand.Perm(len(addrs)) {
-			u, err := url.Parse(fmt.Sprintf("http://%s", addrs[i]))
+		if len(addrs) == 0 {
+			return nil, errors.Errorf("no query peer reachable")
+		}
+
+		var lastErr error
+		start := int(atomic.AddUint64(&next, 1) % uint64(len(addrs)))
+		for j := range addrs {
+			addr := addrs[(start+j)%len(addrs)]
+			u, err := url.Parse(fmt.Sprintf("http://%s", addr))
 			if err != nil {
-				return nil, errors.Wrapf(err, "url parse %s", addrs[i])
+				return nil, errors.Wrapf(err, "url parse %s", addr)
 			}
 
 			span, ctx := tracing.StartSpan(ctx, spanID)
@@ -761,16 +914,61 @@ func queryFunc(
 			span.Finish()
 
 			if err != nil {
-				level.Error(logger).Log("err", err, "query", q)
+				queryFailures.WithLabelValues(addr).Inc()
+				level.Error(logger).Log("msg", "rule query failed, trying next query peer", "endpoint", addr, "err", err, "query", q)
+				lastErr = err
+				if ctx.Err() != nil {
+					break
+				}
+				continue
 			}
 
-			if err == nil && len(warns) > 0 {
+			if len(warns) > 0 {
 				ruleEvalWarnings.WithLabelValues(strings.ToLower(partialResponseStrategy.String())).Inc()
 				// TODO(bwplotka): Propagate those to UI, probably requires changing rule manager code ):
 				level.Warn(logger).Log("warnings", strings.Join(warns, ", "), "query", q)
 			}
-			return v, err
+			return v, nil
+		}
+		return nil, errors.Wrapf(lastErr, "query failed on all %d query peers", len(addrs))
+	}
+}
+
+// limitedQueryFunc returns the query function failing queries which exceed the limits of the rule group of their
+// expression.
+func limitedQueryFunc(limits *thanosrule.Limits, exceeded *prometheus.CounterVec, f rules.QueryFunc) rules.QueryFunc {
+	return func(ctx context.Context, q string, t time.Time) (promql.Vector, error) {
+		l := limits.For(q)
+		if l.QueryTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, l.QueryTimeout)
+			defer cancel()
+		}
+
+		v, err := f(ctx, q, t)
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				exceeded.WithLabelValues("query_timeout").Inc()
+				return nil, errors.Wrapf(err, "query_timeout %s of rule group exceeded", l.QueryTimeout)
+			}
+			return nil, err
+		}
+		if l.SampleLimit > 0 && len(v) > l.SampleLimit {
+			exceeded.WithLabelValues("sample_limit").Inc()
+			return nil, errors.Errorf("query returned %d samples, more than the sample_limit %d of rule group", len(v), l.SampleLimit)
 		}
-		return nil, errors.Errorf("no query peer reachable")
+		return v, nil
+	}
+}
+
+// gatedQueryFunc returns the query function with the number of concurrent queries limited by the gate.
+func gatedQueryFunc(gate *store.Gate, f rules.QueryFunc) rules.QueryFunc {
+	return func(ctx context.Context, q string, t time.Time) (promql.Vector, error) {
+		if err := gate.IsMyTurn(ctx); err != nil {
+			return nil, errors.Wrap(err, "waiting for an evaluation slot")
+		}
+		defer gate.Done()
+
+		return f(ctx, q, t)
 	}
 }
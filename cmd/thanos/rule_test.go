@@ -2,12 +2,26 @@ package main
 
 import (
 	"context"
-	"net/url"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/go-kit/kit/log"
 	"github.com/improbable-eng/thanos/pkg/discovery/dns"
+	thanosrule "github.com/improbable-eng/thanos/pkg/rule"
+	"github.com/improbable-eng/thanos/pkg/store"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
 	"github.com/improbable-eng/thanos/pkg/testutil"
-	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
 )
 
 func Test_parseFlagLabels(t *testing.T) {
@@ -50,61 +64,126 @@ func Test_parseFlagLabels(t *testing.T) {
 	}
 }
 
-func TestRule_AlertmanagerResolveWithoutPort(t *testing.T) {
-	mockResolver := mockResolver{
-		resultIPs: map[string][]string{
-			"alertmanager.com:9093": {"1.1.1.1:9300"},
-		},
-	}
-	am := alertmanagerSet{resolver: mockResolver, addrs: []string{"dns+http://alertmanager.com"}}
+func TestRule_QueryFuncFailover(t *testing.T) {
+	var okQueries int
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		okQueries++
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"a"},"value":[1,"1"]}]}}`))
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
 
-	ctx := context.TODO()
-	err := am.update(ctx)
-	testutil.Ok(t, err)
+	okAddr, failingAddr := strings.TrimPrefix(ok.URL, "http://"), strings.TrimPrefix(failing.URL, "http://")
+	dnsProvider := dns.NewProvider(log.NewNopLogger(), nil, dns.GolangResolverType)
+	dnsProvider.Resolve(context.Background(), []string{okAddr, failingAddr})
 
-	expected := []*url.URL{
-		{
-			Scheme: "http",
-			Host:   "1.1.1.1:9300",
-		},
-	}
-	gotURLs := am.get()
-	testutil.Equals(t, expected, gotURLs)
-}
+	queryFailures := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "failures"}, []string{"endpoint"})
+	f := queryFunc(
+		log.NewNopLogger(),
+		dnsProvider,
+		prometheus.NewCounter(prometheus.CounterOpts{Name: "duplicated"}),
+		prometheus.NewCounterVec(prometheus.CounterOpts{Name: "warnings"}, []string{"strategy"}),
+		queryFailures,
+		storepb.PartialResponseStrategy_WARN,
+	)
 
-func TestRule_AlertmanagerResolveWithPort(t *testing.T) {
-	mockResolver := mockResolver{
-		resultIPs: map[string][]string{
-			"alertmanager.com:19093": {"1.1.1.1:9300"},
-		},
+	// Every evaluation succeeds, the failing peer is tried on every other one due to round-robin.
+	for i := 0; i < 4; i++ {
+		v, err := f(context.Background(), "up", time.Unix(1, 0))
+		testutil.Ok(t, err)
+		testutil.Equals(t, 1, len(v))
 	}
-	am := alertmanagerSet{resolver: mockResolver, addrs: []string{"dns+http://alertmanager.com:19093"}}
+	testutil.Equals(t, 4, okQueries)
+	testutil.Equals(t, 2.0, promtest.ToFloat64(queryFailures.WithLabelValues(failingAddr)))
+	testutil.Equals(t, 0.0, promtest.ToFloat64(queryFailures.WithLabelValues(okAddr)))
+
+	dnsProvider.Resolve(context.Background(), []string{failingAddr})
+	_, err := f(context.Background(), "up", time.Unix(1, 0))
+	testutil.NotOk(t, err)
+}
 
-	ctx := context.TODO()
-	err := am.update(ctx)
+func TestRule_LimitedQueryFunc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test_rule_limited_query_func")
 	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
 
-	expected := []*url.URL{
-		{
-			Scheme: "http",
-			Host:   "1.1.1.1:9300",
-		},
-	}
-	gotURLs := am.get()
-	testutil.Equals(t, expected, gotURLs)
-}
+	testutil.Ok(t, ioutil.WriteFile(path.Join(dir, "rules.yaml"), []byte(`
+groups:
+- name: "limited"
+  query_timeout: 50ms
+  sample_limit: 1
+  rules:
+  - alert: "slow"
+    expr: "slow"
+  - alert: "many"
+    expr: "many"
+- name: "unlimited"
+  rules:
+  - alert: "other"
+    expr: "other"
+`), os.ModePerm))
+
+	limits := thanosrule.NewLimits()
+	mgrs := thanosrule.Managers{storepb.PartialResponseStrategy_ABORT: rules.NewManager(&rules.ManagerOptions{Logger: log.NewNopLogger()})}
+	testutil.Ok(t, mgrs.Update(dir, time.Minute, []string{path.Join(dir, "rules.yaml")}, thanosrule.UpdateOptions{Limits: limits}))
+
+	exceeded := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test"}, []string{"limit"})
+	f := limitedQueryFunc(limits, exceeded, func(ctx context.Context, q string, _ time.Time) (promql.Vector, error) {
+		switch q {
+		case "slow":
+			<-ctx.Done()
+			return nil, ctx.Err()
+		default:
+			return promql.Vector{{}, {}}, nil
+		}
+	})
+
+	_, err = f(context.Background(), "slow", time.Now())
+	testutil.NotOk(t, err)
+	testutil.Equals(t, 1.0, promtest.ToFloat64(exceeded.WithLabelValues("query_timeout")))
+
+	_, err = f(context.Background(), "many", time.Now())
+	testutil.NotOk(t, err)
+	testutil.Equals(t, 1.0, promtest.ToFloat64(exceeded.WithLabelValues("sample_limit")))
 
-type mockResolver struct {
-	resultIPs map[string][]string
-	err       error
+	v, err := f(context.Background(), "other", time.Now())
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(v))
 }
 
-func (m mockResolver) Resolve(ctx context.Context, name string, qtype dns.QType) ([]string, error) {
-	if m.err != nil {
-		return nil, m.err
-	}
-	if res, ok := m.resultIPs[name]; ok {
-		return res, nil
+func TestRule_GatedQueryFunc(t *testing.T) {
+	var (
+		mtx               sync.Mutex
+		inflight, maxSeen int
+	)
+	f := gatedQueryFunc(store.NewGate(2, 0, nil), func(context.Context, string, time.Time) (promql.Vector, error) {
+		mtx.Lock()
+		inflight++
+		if inflight > maxSeen {
+			maxSeen = inflight
+		}
+		mtx.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mtx.Lock()
+		inflight--
+		mtx.Unlock()
+		return nil, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := f(context.Background(), "up", time.Now())
+			testutil.Ok(t, err)
+		}()
 	}
-	return nil, errors.Errorf("mockResolver not found response for name: %s", name)
+	wg.Wait()
+	testutil.Equals(t, 2, maxSeen)
 }
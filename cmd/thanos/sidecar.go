@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"math"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,13 +15,23 @@ import (
 	"github.com/go-kit/kit/log/level"
 	"github.com/improbable-eng/thanos/pkg/block/metadata"
 	"github.com/improbable-eng/thanos/pkg/component"
+	"github.com/improbable-eng/thanos/pkg/exemplars"
+	"github.com/improbable-eng/thanos/pkg/exemplars/exemplarspb"
+	metricmetadata "github.com/improbable-eng/thanos/pkg/metadata"
+	"github.com/improbable-eng/thanos/pkg/metadata/metadatapb"
+	thanosmodel "github.com/improbable-eng/thanos/pkg/model"
+	"github.com/improbable-eng/thanos/pkg/objstore"
 	"github.com/improbable-eng/thanos/pkg/objstore/client"
 	"github.com/improbable-eng/thanos/pkg/promclient"
 	"github.com/improbable-eng/thanos/pkg/reloader"
+	"github.com/improbable-eng/thanos/pkg/rules"
+	"github.com/improbable-eng/thanos/pkg/rules/rulespb"
 	"github.com/improbable-eng/thanos/pkg/runutil"
 	"github.com/improbable-eng/thanos/pkg/shipper"
 	"github.com/improbable-eng/thanos/pkg/store"
 	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/targets"
+	"github.com/improbable-eng/thanos/pkg/targets/targetspb"
 	"github.com/oklog/run"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
@@ -38,28 +50,68 @@ func registerSidecar(m map[string]setupFunc, app *kingpin.Application, name stri
 	promURL := cmd.Flag("prometheus.url", "URL at which to reach Prometheus's API. For better performance use local network.").
 		Default("http://localhost:9090").URL()
 
+	proxyPaths := cmd.Flag("prometheus.proxy-path", "Path of the Prometheus UI or HTTP API to proxy on the HTTP address, e.g. /graph. Paths ending with / proxy all paths below them. No paths are proxied by default (repeated).").
+		Strings()
+
+	promExternalURL := cmd.Flag("prometheus.external-url", "External URL of Prometheus as set with its --web.external-url flag. With --prometheus.proxy-trust-forwarded-headers, absolute links to it in proxied redirects and pages are rewritten to the address the sidecar was requested with.").
+		URL()
+
+	proxyTrustForwarded := cmd.Flag("prometheus.proxy-trust-forwarded-headers", "Take the address the sidecar was requested with from the X-Forwarded-Host and X-Forwarded-Proto headers of proxied requests, falling back to their Host, for rewriting links to --prometheus.external-url. Security risk: enable this option only if a reverse proxy in front of the sidecar is resetting the headers.").
+		Default("false").Bool()
+
 	dataDir := cmd.Flag("tsdb.path", "Data directory of TSDB.").
 		Default("./data").String()
 
 	reloaderCfgFile := cmd.Flag("reloader.config-file", "Config file watched by the reloader.").
 		Default("").String()
 
-	reloaderCfgOutputFile := cmd.Flag("reloader.config-envsubst-file", "Output file for environment variable substituted config file.").
+	reloaderCfgOutputFile := cmd.Flag("reloader.config-envsubst-file", "Output file for environment variable substituted config file. The config file is decompressed first if it is gzip compressed.").
 		Default("").String()
 
 	reloaderRuleDirs := cmd.Flag("reloader.rule-dir", "Rule directories for the reloader to refresh (repeated field).").Strings()
 
+	reloaderWatchFiles := cmd.Flag("reloader.watch-file", "Additional config file watched by the reloader in the form <file>[:<output file>]. If the output file is given, the config file is written to it with environment variables substituted (repeated field).").
+		PlaceHolder("<file>[:<output file>]").Strings()
+
+	reloaderWatchDirs := cmd.Flag("reloader.watch-dir", "Additional directory watched by the reloader in the form <dir>[:<output dir>]. If the output dir is given, the directory tree is copied to it atomically with environment variables substituted in all files (repeated field).").
+		PlaceHolder("<dir>[:<output dir>]").Strings()
+
 	objStoreConfig := regCommonObjStoreFlags(cmd, "", false)
 
-	uploadCompacted := cmd.Flag("shipper.upload-compacted", "[Experimental] If true sidecar will try to upload compacted blocks as well. Useful for migration purposes. Works only if compaction is disabled on Prometheus.").Default("false").Hidden().Bool()
+	uploadCompacted := cmd.Flag("shipper.upload-compacted", "If true sidecar will try to upload compacted blocks as well, once each. Compacted blocks overlapping with blocks of the same external labels in the bucket are not uploaded. "+
+		"Useful to migrate the history of existing Prometheus servers. Works only if compaction is disabled on Prometheus.").Default("false").Bool()
+	uploadConcurrency := cmd.Flag("shipper.upload-concurrency", "Number of blocks uploaded at the same time. Increase it to catch up faster with blocks which were not uploaded, e.g. during an object storage outage.").
+		Default("1").Int()
+	uploadBandwidthLimit := cmd.Flag("shipper.upload-bandwidth-limit", "Maximum number of bytes per second uploaded by all concurrent block uploads together. 0B means no limit.").
+		Default("0B").Bytes()
+
+	minTime := thanosmodel.TimeOrDuration(cmd.Flag("min-time", "Start of time range limit to serve. Thanos sidecar serves and advertises only data later than this value, e.g. to not query ranges already uploaded to the bucket from it. Option can be a constant time in RFC3339 format or time duration relative to current time, such as -4h or -2d. Valid duration units are ms, s, m, h, d, w, y.").
+		Default("0000-01-01T00:00:00Z"))
 
 	m[name] = func(g *run.Group, logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, _ bool) error {
-		rl := reloader.New(
+		var (
+			cfgFiles []reloader.ConfigFile
+			dirs     []reloader.Dir
+		)
+		if *reloaderCfgFile != "" {
+			cfgFiles = append(cfgFiles, reloader.ConfigFile{Path: *reloaderCfgFile, OutputPath: *reloaderCfgOutputFile})
+		}
+		for _, f := range *reloaderWatchFiles {
+			in, out := parseReloaderPath(f)
+			cfgFiles = append(cfgFiles, reloader.ConfigFile{Path: in, OutputPath: out})
+		}
+		for _, d := range *reloaderRuleDirs {
+			dirs = append(dirs, reloader.Dir{Path: d})
+		}
+		for _, d := range *reloaderWatchDirs {
+			in, out := parseReloaderPath(d)
+			dirs = append(dirs, reloader.Dir{Path: in, OutputPath: out})
+		}
+		rl := reloader.NewWithFiles(
 			log.With(logger, "component", "reloader"),
 			reloader.ReloadURLFromBase(*promURL),
-			*reloaderCfgFile,
-			*reloaderCfgOutputFile,
-			*reloaderRuleDirs,
+			cfgFiles,
+			dirs,
 		)
 		return runSidecar(
 			g,
@@ -72,10 +124,16 @@ func registerSidecar(m map[string]setupFunc, app *kingpin.Application, name stri
 			*clientCA,
 			*httpBindAddr,
 			*promURL,
+			*promExternalURL,
+			*proxyTrustForwarded,
+			*proxyPaths,
 			*dataDir,
 			objStoreConfig,
 			rl,
 			*uploadCompacted,
+			*uploadConcurrency,
+			int64(*uploadBandwidthLimit),
+			*minTime,
 		)
 	}
 }
@@ -91,13 +149,20 @@ func runSidecar(
 	clientCA string,
 	httpBindAddr string,
 	promURL *url.URL,
+	promExternalURL *url.URL,
+	proxyTrustForwarded bool,
+	proxyPaths []string,
 	dataDir string,
 	objStoreConfig *pathOrContent,
 	reloader *reloader.Reloader,
 	uploadCompacted bool,
+	uploadConcurrency int,
+	uploadBandwidthLimit int64,
+	limitMinTime thanosmodel.TimeOrDurationValue,
 ) error {
 	var m = &promMetadata{
-		promURL: promURL,
+		promURL:      promURL,
+		limitMinTime: limitMinTime,
 
 		// Start out with the full time range. The shipper will constrain it later.
 		// TODO(fabxc): minimum timestamp is never adjusted if shipping is disabled.
@@ -116,6 +181,10 @@ func runSidecar(
 		uploads = false
 	}
 
+	// The sidecar is ready once Prometheus is ready and its external labels were fetched. Until then the StoreAPI is
+	// not served and no blocks are uploaded, as neither can be done correctly without the external labels.
+	ready := make(chan struct{})
+
 	// Setup all the concurrent groups.
 	{
 		promUp := prometheus.NewGauge(prometheus.GaugeOpts{
@@ -126,7 +195,15 @@ func runSidecar(
 			Name: "thanos_sidecar_last_heartbeat_success_time_seconds",
 			Help: "Second timestamp of the last successful heartbeat.",
 		})
-		reg.MustRegister(promUp, lastHeartbeat)
+		readyGauge := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "thanos_sidecar_ready",
+			Help: "Boolean indicator whether the sidecar is ready, i.e. Prometheus is ready and its external labels were fetched.",
+		})
+		readinessFailures := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_sidecar_readiness_check_failures_total",
+			Help: "Total number of failed readiness checks of Prometheus before the sidecar got ready, by reason.",
+		}, []string{"reason"})
+		reg.MustRegister(promUp, lastHeartbeat, readyGauge, readinessFailures)
 
 		ctx, cancel := context.WithCancel(context.Background())
 		g.Add(func() error {
@@ -138,14 +215,24 @@ func runSidecar(
 				}
 			}
 
-			// Blocking query of external labels before joining as a Source Peer into gossip.
-			// We retry infinitely until we reach and fetch labels from our Prometheus.
-			err := runutil.Retry(2*time.Second, ctx.Done(), func() error {
+			// Blocking readiness check and query of external labels before serving the StoreAPI.
+			// We retry infinitely with backoff until Prometheus is ready and we fetched labels from it.
+			err := runutil.RetryWithBackoff(time.Second, 30*time.Second, ctx.Done(), func() error {
+				if err := promclient.IsReady(ctx, logger, m.promURL); err != nil {
+					level.Warn(logger).Log(
+						"msg", "Prometheus is not ready yet. Retrying",
+						"err", err,
+					)
+					readinessFailures.WithLabelValues("prometheus_not_ready").Inc()
+					promUp.Set(0)
+					return err
+				}
 				if err := m.UpdateLabels(ctx, logger); err != nil {
 					level.Warn(logger).Log(
 						"msg", "failed to fetch initial external labels. Is Prometheus running? Retrying",
 						"err", err,
 					)
+					readinessFailures.WithLabelValues("external_labels").Inc()
 					promUp.Set(0)
 					return err
 				}
@@ -165,6 +252,9 @@ func runSidecar(
 			if len(m.Labels()) == 0 {
 				return errors.New("no external labels configured on Prometheus server, uniquely identifying external labels must be configured")
 			}
+			close(ready)
+			readyGauge.Set(1)
+			level.Info(logger).Log("msg", "sidecar is ready")
 
 			// Periodically query the Prometheus config. We use this as a heartbeat as well as for updating
 			// the external labels we apply.
@@ -194,14 +284,23 @@ func runSidecar(
 			cancel()
 		})
 	}
-	if err := metricHTTPListenGroup(g, logger, reg, httpBindAddr); err != nil {
+	if err := metricHTTPListenGroup(g, logger, reg, httpBindAddr, func(mux *http.ServeMux) error {
+		mux.HandleFunc("/-/ready", func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-ready:
+			default:
+				http.Error(w, "Thanos Sidecar is not ready, waiting for Prometheus to be ready and its external labels.", http.StatusServiceUnavailable)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "Thanos Sidecar is Ready.\n"); err != nil {
+				level.Error(logger).Log("msg", "Could not write readiness check response.")
+			}
+		})
+		return registerPrometheusProxy(mux, logger, promURL, promExternalURL, proxyTrustForwarded, proxyPaths)
+	}); err != nil {
 		return err
 	}
 	{
-		l, err := net.Listen("tcp", grpcBindAddr)
-		if err != nil {
-			return errors.Wrap(err, "listen API address")
-		}
 		logger := log.With(logger, "component", component.Sidecar.String())
 
 		var client http.Client
@@ -218,13 +317,28 @@ func runSidecar(
 		}
 		s := grpc.NewServer(opts...)
 		storepb.RegisterStoreServer(s, promStore)
+		exemplarspb.RegisterExemplarsServer(s, exemplars.NewPrometheus(logger, promURL, m.Labels))
+		metadatapb.RegisterMetadataServer(s, metricmetadata.NewPrometheus(logger, promURL))
+		targetspb.RegisterTargetsServer(s, targets.NewPrometheus(logger, promURL, m.Labels))
+		rulespb.RegisterRulesServer(s, rules.NewPrometheus(logger, promURL, m.Labels))
 
+		grpcCtx, grpcCancel := context.WithCancel(context.Background())
 		g.Add(func() error {
+			select {
+			case <-ready:
+			case <-grpcCtx.Done():
+				return nil
+			}
+
+			l, err := net.Listen("tcp", grpcBindAddr)
+			if err != nil {
+				return errors.Wrap(err, "listen API address")
+			}
 			level.Info(logger).Log("msg", "Listening for StoreAPI gRPC", "address", grpcBindAddr)
 			return errors.Wrap(s.Serve(l), "serve gRPC")
 		}, func(error) {
+			grpcCancel()
 			s.Stop()
-			runutil.CloseWithLogOnErr(logger, l, "store gRPC listener")
 		})
 	}
 
@@ -235,6 +349,7 @@ func runSidecar(
 		if err != nil {
 			return err
 		}
+		bkt = objstore.BucketWithUploadLimit(bkt, uploadBandwidthLimit)
 
 		// Ensure we close up everything properly.
 		defer func() {
@@ -251,11 +366,18 @@ func runSidecar(
 		g.Add(func() error {
 			defer runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
 
+			// Blocks are uploaded with the external labels, so wait for them.
+			select {
+			case <-ready:
+			case <-ctx.Done():
+				return nil
+			}
+
 			var s *shipper.Shipper
 			if uploadCompacted {
-				s = shipper.NewWithCompacted(logger, reg, dataDir, bkt, m.Labels, metadata.SidecarSource)
+				s = shipper.NewWithCompacted(logger, reg, dataDir, bkt, m.Labels, nil, metadata.SidecarSource, uploadConcurrency)
 			} else {
-				s = shipper.New(logger, reg, dataDir, bkt, m.Labels, metadata.SidecarSource)
+				s = shipper.New(logger, reg, dataDir, bkt, m.Labels, nil, metadata.SidecarSource, uploadConcurrency)
 			}
 
 			return runutil.Repeat(30*time.Second, ctx.Done(), func() error {
@@ -280,6 +402,34 @@ func runSidecar(
 	return nil
 }
 
+// parseReloaderPath splits a watched path of the form <path>[:<output path>].
+func parseReloaderPath(s string) (path string, outputPath string) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// registerPrometheusProxy proxies the given paths to Prometheus. Paths served by the sidecar itself cannot be proxied.
+func registerPrometheusProxy(mux *http.ServeMux, logger log.Logger, promURL *url.URL, externalURL *url.URL, trustForwarded bool, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	proxy := promclient.NewProxy(log.With(logger, "component", "prometheus-proxy"), promURL, externalURL, trustForwarded)
+	for _, p := range paths {
+		if !strings.HasPrefix(p, "/") {
+			return errors.Errorf("proxy path %s must start with /", p)
+		}
+		if _, pattern := mux.Handler(&http.Request{URL: &url.URL{Path: p}}); pattern == p {
+			return errors.Errorf("proxy path %s is served by the sidecar", p)
+		}
+		mux.Handle(p, proxy)
+	}
+	level.Info(logger).Log("msg", "proxying Prometheus paths", "paths", strings.Join(paths, ","))
+	return nil
+}
+
 func validatePrometheus(ctx context.Context, logger log.Logger, m *promMetadata) error {
 	flags := promclient.Flags{
 		TSDBMinTime: model.Duration(2 * time.Hour),
@@ -320,6 +470,8 @@ func validatePrometheus(ctx context.Context, logger log.Logger, m *promMetadata)
 
 type promMetadata struct {
 	promURL *url.URL
+	// limitMinTime limits the min time of the served data, it is evaluated on each call if relative to now.
+	limitMinTime thanosmodel.TimeOrDurationValue
 
 	mtx    sync.Mutex
 	mint   int64
@@ -373,5 +525,9 @@ func (s *promMetadata) Timestamps() (mint int64, maxt int64) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	return s.mint, s.maxt
+	mint = s.mint
+	if limit := s.limitMinTime.PrometheusTimestamp(); limit > mint {
+		mint = limit
+	}
+	return mint, s.maxt
 }
@@ -2,12 +2,20 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/block"
+	"github.com/improbable-eng/thanos/pkg/compact/rewrite"
+	"github.com/improbable-eng/thanos/pkg/extprom"
+	"github.com/improbable-eng/thanos/pkg/model"
 	"github.com/improbable-eng/thanos/pkg/objstore/client"
+	"github.com/improbable-eng/thanos/pkg/pool"
 	"github.com/improbable-eng/thanos/pkg/runutil"
 	"github.com/improbable-eng/thanos/pkg/store"
 	storecache "github.com/improbable-eng/thanos/pkg/store/cache"
@@ -29,26 +37,141 @@ func registerStore(m map[string]setupFunc, app *kingpin.Application, name string
 	dataDir := cmd.Flag("data-dir", "Data directory in which to cache remote blocks.").
 		Default("./data").String()
 
-	indexCacheSize := cmd.Flag("index-cache-size", "Maximum size of items held in the index cache.").
+	indexCacheSize := cmd.Flag("index-cache-size", "Maximum size of items held in the in-memory index cache. Ignored if an index cache configuration is given.").
 		Default("250MB").Bytes()
 
+	indexCacheConfigFile := cmd.Flag("index-cache.config-file", "Path to YAML file that contains index cache configuration. Supported types are IN-MEMORY, MEMCACHED and REDIS. If not set, an in-memory cache of index-cache-size is used.").
+		PlaceHolder("<index-cache.config-yaml-path>").String()
+
+	indexCacheConfig := cmd.Flag("index-cache.config", "Alternative to 'index-cache.config-file' flag. Index cache configuration in YAML.").
+		PlaceHolder("<index-cache.config-yaml>").String()
+
+	cachingBucketConfigFile := cmd.Flag("store.caching-bucket.config-file", "Path to YAML file that contains the caching bucket configuration. If set, ranges of chunk files fetched from the bucket are cached in IN-MEMORY, MEMCACHED, REDIS or DISK cache.").
+		PlaceHolder("<caching-bucket.config-yaml-path>").String()
+
+	cachingBucketConfig := cmd.Flag("store.caching-bucket.config", "Alternative to 'store.caching-bucket.config-file' flag. Caching bucket configuration in YAML.").
+		PlaceHolder("<caching-bucket.config-yaml>").String()
+
 	chunkPoolSize := cmd.Flag("chunk-pool-size", "Maximum size of concurrently allocatable bytes for chunks.").
 		Default("2GB").Bytes()
 
+	chunkPoolMinBucketSize := cmd.Flag("store.chunk-pool.min-bucket-size", "Size of the smallest size class of the chunk pool. Smaller allocations use slices of this size.").
+		Default("200KB").Bytes()
+
+	chunkPoolMaxBucketSize := cmd.Flag("store.chunk-pool.max-bucket-size", "Size of the largest size class of the chunk pool. Larger allocations are not pooled.").
+		Default("50MB").Bytes()
+
+	chunkPoolGrowthFactor := cmd.Flag("store.chunk-pool.growth-factor", "Factor by which each size class of the chunk pool is larger than the previous one.").
+		Default("2").Float64()
+
+	chunkPoolDisabled := cmd.Flag("store.chunk-pool.disabled", "Allocate chunk bytes for every request instead of pooling them. The chunk-pool-size limit does not apply then.").
+		Default("false").Bool()
+
 	maxSampleCount := cmd.Flag("store.grpc.series-sample-limit",
 		"Maximum amount of samples returned via a single Series call. 0 means no limit. NOTE: for efficiency we take 120 as the number of samples in chunk (it cannot be bigger than that), so the actual number of samples might be lower, even though the maximum could be hit.").
 		Default("0").Uint()
 
+	maxSeriesCount := cmd.Flag("store.grpc.series-limit",
+		"Maximum amount of series touched by a single Series call. Queries exceeding the limit fail with ResourceExhausted gRPC code. 0 means no limit.").
+		Default("0").Uint()
+
+	maxChunkCount := cmd.Flag("store.grpc.series-chunk-limit",
+		"Maximum amount of chunks touched by a single Series call. Queries exceeding the limit fail with ResourceExhausted gRPC code. 0 means no limit.").
+		Default("0").Uint()
+
+	maxFetchedBytes := cmd.Flag("store.grpc.series-fetched-bytes-limit",
+		"Maximum amount of index and chunk bytes fetched from the bucket by a single Series call. Queries exceeding the limit fail with ResourceExhausted gRPC code. 0 means no limit.").
+		Default("0").Bytes()
+
+	memoryBudget := cmd.Flag("store.grpc.series-memory-budget",
+		"Maximum estimated memory of all concurrent Series calls, estimated from the index-headers and block stats before fetching any data. "+
+			"Calls not fitting into the budget wait for other calls for at most series-max-queue-duration and fail with ResourceExhausted gRPC code afterwards. 0 means no limit.").
+		Default("0").Bytes()
+
 	maxConcurrent := cmd.Flag("store.grpc.series-max-concurrency", "Maximum number of concurrent Series calls.").Default("20").Int()
 
+	maxQueueDuration := cmd.Flag("store.grpc.series-max-queue-duration", "Maximum time a Series call waits for its turn when series-max-concurrency calls are already running. Calls waiting longer fail with ResourceExhausted gRPC code. 0 means no limit.").
+		Default("0s").Duration()
+
+	dedupInflightSeries := cmd.Flag("store.grpc.series-inflight-deduplication", "If true, identical Series calls arriving while one of them is running share its result instead of fetching the same data again. "+
+		"Responses of the running call are buffered in memory for calls joining later, up to series-inflight-deduplication-max-buffer.").
+		Default("false").Bool()
+
+	dedupInflightMaxBuffer := cmd.Flag("store.grpc.series-inflight-deduplication-max-buffer", "Maximum size of the responses of a single Series call buffered for identical calls joining it. "+
+		"Once a call exceeds it, no further calls join it and they fetch their data again instead.").
+		Default("64MB").Bytes()
+
+	tenantLabel := cmd.Flag("store.tenant-label-name", "If set, Series, LabelNames and LabelValues calls must be limited to a single tenant with an equality matcher on this label. "+
+		"Calls without such matcher are rejected unless 'store.tenant-matcher-injection' is enabled.").
+		Default("").String()
+
+	tenantHeader := cmd.Flag("store.tenant-header", "gRPC metadata key carrying the tenant of a call. A tenant given this way must match the tenant matcher of the call.").
+		Default(store.DefaultTenantHeader).String()
+
+	tenantInjection := cmd.Flag("store.tenant-matcher-injection", "If true, calls without a tenant matcher get the matcher for the tenant given in 'store.tenant-header' instead of being rejected.").
+		Default("false").Bool()
+
 	objStoreConfig := regCommonObjStoreFlags(cmd, "", true)
 
 	syncInterval := cmd.Flag("sync-block-duration", "Repeat interval for syncing the blocks between local and remote view.").
 		Default("3m").Duration()
 
-	blockSyncConcurrency := cmd.Flag("block-sync-concurrency", "Number of goroutines to use when syncing blocks from object storage.").
+	blockSyncConcurrency := cmd.Flag("block-sync-concurrency", "Number of goroutines to use when loading new blocks, i.e. downloading their index-headers, from object storage.").
 		Default("20").Int()
 
+	metaSyncConcurrency := cmd.Flag("meta-sync-concurrency", "Number of goroutines to use when checking the metadata of all blocks in object storage during a block sync.").
+		Default("32").Int()
+
+	ignoreDeletionMarksDelay := modelDuration(cmd.Flag("ignore-deletion-marks-delay", "Duration after which blocks marked for deletion are not served anymore. "+
+		"The compactor deletes marked blocks after its delete delay, so this value should be lower than it to give queries in flight time to finish.").
+		Default("24h"))
+
+	consistencyDelay := modelDuration(cmd.Flag("consistency-delay", "Minimum age of blocks before they are served. Blocks created by the compactor are served immediately, "+
+		"as the blocks they replace are deleted right after.").
+		Default("0s"))
+
+	bucketIndex := cmd.Flag("store.bucket-index", fmt.Sprintf("Sync blocks from %s maintained by the compactor with --bucket-index instead of iterating the bucket "+
+		"and fetching the meta.json of every block.", block.BucketIndexFilename)).
+		Default("false").Bool()
+
+	bucketIndexMaxStalePeriod := modelDuration(cmd.Flag("store.bucket-index.max-stale-period", "Maximum age of the bucket index. If it is older, e.g. because the compactor is down, "+
+		"blocks are synced by iterating the bucket.").
+		Default("1h"))
+
+	serveDegradedAfter := cmd.Flag("store.serve-degraded-after", "Time after which the store reports ready and serves the StoreAPI even if the initial block sync has not finished yet, "+
+		"serving only the blocks loaded so far. 0 means the store waits for the initial sync to finish.").
+		Default("0s").Duration()
+
+	lazyIndexHeader := cmd.Flag("store.enable-index-header-lazy-reader", "If true, the index-header of a block is loaded into memory only on the first query touching the block.").
+		Default("false").Bool()
+
+	indexHeaderIdleTimeout := cmd.Flag("store.index-header-lazy-idle-timeout", "Time after which a lazily loaded index-header is unloaded from memory if no query used it. 0 disables unloading. Used only if 'store.enable-index-header-lazy-reader' is set.").
+		Default("20m").Duration()
+
+	indexHeaderWarmUpPeriod := cmd.Flag("store.index-header-warm-up-period", "Index-headers of blocks with data of this period before now are loaded into memory on startup, before the store reports ready. "+
+		"0 disables the warm-up. Used only if 'store.enable-index-header-lazy-reader' is set.").
+		Default("0s").Duration()
+
+	postingsCompression := cmd.Flag("store.index-cache.postings-compression", "Encoding of the postings lists stored in the index cache. "+
+		"Delta encoded lists (diff-varint) take a fraction of the size of raw lists (none), snappy compresses them further at some CPU cost.").
+		Default(store.PostingsCompressionDiffVarint).
+		Enum(store.PostingsCompressionNone, store.PostingsCompressionDiffVarint, store.PostingsCompressionDiffVarintSnappy)
+
+	rangeMaxGapSize := cmd.Flag("store.bucket-range.max-gap-size", "Maximum gap between byte ranges of index or chunk files which are fetched from the bucket with a single request. "+
+		"Larger gaps mean fewer requests, but more bytes fetched needlessly.").
+		Default("512KiB").Bytes()
+
+	rangeMaxSize := cmd.Flag("store.bucket-range.max-size", "Maximum size of the combined byte ranges fetched from the bucket with a single request, so large fetches are split into concurrent requests. 0 means no limit.").
+		Default("0").Bytes()
+
+	selectorRelabelConf := regSelectorRelabelFlags(cmd)
+
+	minTime := model.TimeOrDuration(cmd.Flag("min-time", "Start of time range limit to serve. Thanos Store serves only blocks which have data later than this value. Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or -2w. Valid duration units are ms, s, m, h, d, w, y.").
+		Default("0000-01-01T00:00:00Z"))
+
+	maxTime := model.TimeOrDuration(cmd.Flag("max-time", "End of time range limit to serve. Thanos Store serves only blocks which have data earlier than this value. Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or -2w. Valid duration units are ms, s, m, h, d, w, y.").
+		Default("9999-12-31T23:59:59Z"))
+
 	m[name] = func(g *run.Group, logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, debugLogging bool) error {
 		return runStore(g,
 			logger,
@@ -62,13 +185,55 @@ func registerStore(m map[string]setupFunc, app *kingpin.Application, name string
 			*clientCA,
 			*httpBindAddr,
 			uint64(*indexCacheSize),
+			&pathOrContent{
+				fileFlagName:    "index-cache.config-file",
+				contentFlagName: "index-cache.config",
+				path:            indexCacheConfigFile,
+				content:         indexCacheConfig,
+			},
+			&pathOrContent{
+				fileFlagName:    "store.caching-bucket.config-file",
+				contentFlagName: "store.caching-bucket.config",
+				path:            cachingBucketConfigFile,
+				content:         cachingBucketConfig,
+			},
 			uint64(*chunkPoolSize),
+			int(*chunkPoolMinBucketSize),
+			int(*chunkPoolMaxBucketSize),
+			*chunkPoolGrowthFactor,
+			*chunkPoolDisabled,
 			uint64(*maxSampleCount),
+			uint64(*maxSeriesCount),
+			uint64(*maxChunkCount),
+			uint64(*maxFetchedBytes),
+			uint64(*memoryBudget),
 			int(*maxConcurrent),
+			*maxQueueDuration,
 			name,
 			debugLogging,
 			*syncInterval,
 			*blockSyncConcurrency,
+			*metaSyncConcurrency,
+			time.Duration(*ignoreDeletionMarksDelay),
+			time.Duration(*consistencyDelay),
+			bucketIndexPeriod(*bucketIndex, time.Duration(*bucketIndexMaxStalePeriod)),
+			*serveDegradedAfter,
+			*lazyIndexHeader,
+			*indexHeaderIdleTimeout,
+			*indexHeaderWarmUpPeriod,
+			*postingsCompression,
+			uint64(*rangeMaxGapSize),
+			uint64(*rangeMaxSize),
+			*dedupInflightSeries,
+			int(*dedupInflightMaxBuffer),
+			*tenantLabel,
+			*tenantHeader,
+			*tenantInjection,
+			selectorRelabelConf,
+			store.FilterConfig{
+				MinTime: *minTime,
+				MaxTime: *maxTime,
+			},
 		)
 	}
 }
@@ -87,14 +252,56 @@ func runStore(
 	clientCA string,
 	httpBindAddr string,
 	indexCacheSizeBytes uint64,
+	indexCacheConfig *pathOrContent,
+	cachingBucketConfig *pathOrContent,
 	chunkPoolSizeBytes uint64,
+	chunkPoolMinBucketSize int,
+	chunkPoolMaxBucketSize int,
+	chunkPoolGrowthFactor float64,
+	chunkPoolDisabled bool,
 	maxSampleCount uint64,
+	maxSeriesCount uint64,
+	maxChunkCount uint64,
+	maxFetchedBytes uint64,
+	memoryBudget uint64,
 	maxConcurrent int,
+	maxQueueDuration time.Duration,
 	component string,
 	verbose bool,
 	syncInterval time.Duration,
 	blockSyncConcurrency int,
+	metaSyncConcurrency int,
+	ignoreDeletionMarksDelay time.Duration,
+	consistencyDelay time.Duration,
+	bucketIndexMaxStalePeriod time.Duration,
+	serveDegradedAfter time.Duration,
+	lazyIndexHeader bool,
+	indexHeaderIdleTimeout time.Duration,
+	indexHeaderWarmUpPeriod time.Duration,
+	postingsCompression string,
+	rangeMaxGapSize uint64,
+	rangeMaxSize uint64,
+	dedupInflightSeries bool,
+	dedupInflightMaxBuffer int,
+	tenantLabel string,
+	tenantHeader string,
+	tenantInjection bool,
+	selectorRelabelConf *pathOrContent,
+	filterConf store.FilterConfig,
 ) error {
+	if filterConf.MinTime.PrometheusTimestamp() > filterConf.MaxTime.PrometheusTimestamp() {
+		return errors.Errorf("invalid time range: min-time %s is later than max-time %s", filterConf.MinTime.String(), filterConf.MaxTime.String())
+	}
+
+	// The store is ready once the initial sync is done or once it serves degraded.
+	var (
+		ready     = make(chan struct{})
+		readyOnce sync.Once
+	)
+	markReady := func() {
+		readyOnce.Do(func() { close(ready) })
+	}
+
 	{
 		confContentYaml, err := objStoreConfig.Content()
 		if err != nil {
@@ -113,44 +320,109 @@ func runStore(
 			}
 		}()
 
-		// TODO(bwplotka): Add as a flag?
-		maxItemSizeBytes := indexCacheSizeBytes / 2
+		cachingBucketContentYaml, err := cachingBucketConfig.Content()
+		if err != nil {
+			return errors.Wrap(err, "get content of caching bucket configuration")
+		}
+		if len(cachingBucketContentYaml) > 0 {
+			cachingBkt, err := storecache.NewCachingBucketFromConfig(logger, cachingBucketContentYaml, bkt, reg)
+			if err != nil {
+				return errors.Wrap(err, "create caching bucket")
+			}
+			bkt = cachingBkt
+		}
 
-		indexCache, err := storecache.NewIndexCache(logger, reg, storecache.Opts{
-			MaxSizeBytes:     indexCacheSizeBytes,
-			MaxItemSizeBytes: maxItemSizeBytes,
-		})
+		relabelContentYaml, err := selectorRelabelConf.Content()
 		if err != nil {
-			return errors.Wrap(err, "create index cache")
+			return errors.Wrap(err, "get content of relabel configuration")
+		}
+		relabelConfig, err := rewrite.ParseRelabelConfig(relabelContentYaml)
+		if err != nil {
+			return err
 		}
 
-		bs, err := store.NewBucketStore(
-			logger,
-			reg,
-			bkt,
-			dataDir,
-			indexCache,
-			chunkPoolSizeBytes,
-			maxSampleCount,
-			maxConcurrent,
-			verbose,
-			blockSyncConcurrency,
-		)
+		indexCacheContentYaml, err := indexCacheConfig.Content()
 		if err != nil {
-			return errors.Wrap(err, "create object storage store")
+			return errors.Wrap(err, "get content of index cache configuration")
+		}
+
+		var indexCache storecache.Cache
+		if len(indexCacheContentYaml) > 0 {
+			indexCache, err = storecache.NewIndexCacheFromConfig(logger, indexCacheContentYaml, reg)
+		} else {
+			// TODO(bwplotka): Add as a flag?
+			maxItemSizeBytes := indexCacheSizeBytes / 2
+
+			indexCache, err = storecache.NewIndexCache(logger, reg, storecache.Opts{
+				MaxSizeBytes:     indexCacheSizeBytes,
+				MaxItemSizeBytes: maxItemSizeBytes,
+			})
+		}
+		if err != nil {
+			return errors.Wrap(err, "create index cache")
+		}
+
+		var chunkPool pool.Bytes = pool.NoopBytes{}
+		if !chunkPoolDisabled {
+			chunkPool, err = pool.NewBytesPool(
+				chunkPoolMinBucketSize,
+				chunkPoolMaxBucketSize,
+				chunkPoolGrowthFactor,
+				chunkPoolSizeBytes,
+				extprom.WrapRegistererWithPrefix("thanos_bucket_store_chunk_", reg),
+			)
+			if err != nil {
+				return errors.Wrap(err, "create chunk pool")
+			}
 		}
 
-		begin := time.Now()
-		level.Debug(logger).Log("msg", "initializing bucket store")
-		if err := bs.InitialSync(context.Background()); err != nil {
-			return errors.Wrap(err, "bucket store initial sync")
+		bs, err := store.NewBucketStore(logger, reg, bkt, indexCache, store.BucketStoreConfig{
+			Dir:                       dataDir,
+			ChunkPool:                 chunkPool,
+			MaxSampleCount:            maxSampleCount,
+			MaxSeriesCount:            maxSeriesCount,
+			MaxChunkCount:             maxChunkCount,
+			MaxFetchedBytes:           maxFetchedBytes,
+			MemoryBudget:              memoryBudget,
+			MaxConcurrent:             maxConcurrent,
+			MaxQueueDuration:          maxQueueDuration,
+			DebugLogging:              verbose,
+			BlockSyncConcurrency:      blockSyncConcurrency,
+			MetaSyncConcurrency:       metaSyncConcurrency,
+			IgnoreDeletionMarksDelay:  ignoreDeletionMarksDelay,
+			ConsistencyDelay:          consistencyDelay,
+			BucketIndexMaxStalePeriod: bucketIndexMaxStalePeriod,
+			LazyIndexHeader:           lazyIndexHeader,
+			IndexHeaderIdleTimeout:    indexHeaderIdleTimeout,
+			PostingsCompression:       postingsCompression,
+			RangeMaxGapSize:           rangeMaxGapSize,
+			RangeMaxSize:              rangeMaxSize,
+			DedupInflightSeries:       dedupInflightSeries,
+			DedupInflightMaxBuffer:    dedupInflightMaxBuffer,
+			RelabelConfig:             relabelConfig,
+			FilterConfig:              &filterConf,
+		})
+		if err != nil {
+			return errors.Wrap(err, "create object storage store")
 		}
-		level.Debug(logger).Log("msg", "bucket store ready", "init_duration", time.Since(begin).String())
 
 		ctx, cancel := context.WithCancel(context.Background())
 		g.Add(func() error {
 			defer runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
 
+			begin := time.Now()
+			level.Debug(logger).Log("msg", "initializing bucket store")
+			if err := bs.InitialSync(ctx); err != nil {
+				runutil.CloseWithLogOnErr(logger, bs, "bucket store")
+				return errors.Wrap(err, "bucket store initial sync")
+			}
+			if err := bs.WarmUpIndexHeaders(ctx, indexHeaderWarmUpPeriod); err != nil {
+				runutil.CloseWithLogOnErr(logger, bs, "bucket store")
+				return errors.Wrap(err, "warm up index-headers")
+			}
+			level.Info(logger).Log("msg", "bucket store ready", "init_duration", time.Since(begin).String())
+			markReady()
+
 			err := runutil.Repeat(syncInterval, ctx.Done(), func() error {
 				if err := bs.SyncBlocks(ctx); err != nil {
 					level.Warn(logger).Log("msg", "syncing blocks failed", "err", err)
@@ -164,9 +436,34 @@ func runStore(
 			cancel()
 		})
 
-		l, err := net.Listen("tcp", grpcBindAddr)
-		if err != nil {
-			return errors.Wrap(err, "listen API address")
+		if lazyIndexHeader && indexHeaderIdleTimeout > 0 {
+			ctx, cancel := context.WithCancel(context.Background())
+			g.Add(func() error {
+				// Check often enough to unload index-headers not much later than the idle timeout.
+				return runutil.Repeat(indexHeaderIdleTimeout/4, ctx.Done(), func() error {
+					bs.UnloadIdleIndexHeaders()
+					return nil
+				})
+			}, func(error) {
+				cancel()
+			})
+		}
+
+		if serveDegradedAfter > 0 {
+			ctx, cancel := context.WithCancel(context.Background())
+			g.Add(func() error {
+				select {
+				case <-ready:
+				case <-time.After(serveDegradedAfter):
+					level.Warn(logger).Log("msg", "initial block sync not finished, serving degraded", "serve_degraded_after", serveDegradedAfter)
+					markReady()
+				case <-ctx.Done():
+				}
+				<-ctx.Done()
+				return nil
+			}, func(error) {
+				cancel()
+			})
 		}
 
 		opts, err := defaultGRPCServerOpts(logger, reg, tracer, cert, key, clientCA)
@@ -175,19 +472,70 @@ func runStore(
 		}
 
 		s := grpc.NewServer(opts...)
-		storepb.RegisterStoreServer(s, bs)
+		var storeSrv storepb.StoreServer = bs
+		if tenantLabel != "" {
+			storeSrv = store.NewTenantStore(bs, tenantLabel, tenantHeader, tenantInjection)
+		}
+		storepb.RegisterStoreServer(s, storeSrv)
 
+		// The StoreAPI is not served before the store is ready, so queriers do not query half-initialized stores.
+		grpcCtx, grpcCancel := context.WithCancel(context.Background())
 		g.Add(func() error {
+			select {
+			case <-ready:
+			case <-grpcCtx.Done():
+				return nil
+			}
+
+			l, err := net.Listen("tcp", grpcBindAddr)
+			if err != nil {
+				return errors.Wrap(err, "listen API address")
+			}
 			level.Info(logger).Log("msg", "Listening for StoreAPI gRPC", "address", grpcBindAddr)
 			return errors.Wrap(s.Serve(l), "serve gRPC")
 		}, func(error) {
-			runutil.CloseWithLogOnErr(logger, l, "store gRPC listener")
+			grpcCancel()
+			s.Stop()
 		})
 	}
-	if err := metricHTTPListenGroup(g, logger, reg, httpBindAddr); err != nil {
-		return err
+	{
+		mux := http.NewServeMux()
+		registerMetrics(mux, reg)
+		registerProfile(mux)
+		registerStatus(mux, "")
+		mux.HandleFunc("/-/ready", func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-ready:
+			default:
+				http.Error(w, "Thanos Store is not ready, initial block sync in progress.", http.StatusServiceUnavailable)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "Thanos Store is Ready.\n"); err != nil {
+				level.Error(logger).Log("msg", "Could not write readiness check response.")
+			}
+		})
+
+		l, err := net.Listen("tcp", httpBindAddr)
+		if err != nil {
+			return errors.Wrap(err, "listen metrics address")
+		}
+
+		g.Add(func() error {
+			level.Info(logger).Log("msg", "Listening for metrics", "address", httpBindAddr)
+			return errors.Wrap(http.Serve(l, mux), "serve metrics")
+		}, func(error) {
+			runutil.CloseWithLogOnErr(logger, l, "metric listener")
+		})
 	}
 
 	level.Info(logger).Log("msg", "starting store node")
 	return nil
 }
+
+// bucketIndexPeriod returns the max stale period of the bucket index passed to the bucket store, where 0 disables using it.
+func bucketIndexPeriod(enabled bool, maxStalePeriod time.Duration) time.Duration {
+	if !enabled {
+		return 0
+	}
+	return maxStalePeriod
+}
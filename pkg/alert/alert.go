@@ -20,6 +20,7 @@ import (
 
 	"github.com/improbable-eng/thanos/pkg/runutil"
 	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
 )
 
 const (
@@ -82,6 +83,7 @@ type Queue struct {
 	capacity        int
 	toAddLset       labels.Labels
 	toExcludeLabels labels.Labels
+	relabelConfigs  []*relabel.Config
 
 	mtx   sync.Mutex
 	queue []*Alert
@@ -112,7 +114,15 @@ func relabelLabels(lset labels.Labels, excludeLset []string) (toAdd labels.Label
 
 // NewQueue returns a new queue. The given label set is attached to all alerts pushed to the queue.
 // The given exclude label set tells what label names to drop including external labels.
-func NewQueue(logger log.Logger, reg prometheus.Registerer, capacity, maxBatchSize int, externalLset labels.Labels, excludeLabels []string) *Queue {
+// The relabel configs are applied afterwards, alerts they drop are not queued.
+func NewQueue(
+	logger log.Logger,
+	reg prometheus.Registerer,
+	capacity, maxBatchSize int,
+	externalLset labels.Labels,
+	excludeLabels []string,
+	relabelConfigs []*relabel.Config,
+) *Queue {
 	toAdd, toExclude := relabelLabels(externalLset, excludeLabels)
 
 	if logger == nil {
@@ -125,6 +135,7 @@ func NewQueue(logger log.Logger, reg prometheus.Registerer, capacity, maxBatchSi
 		maxBatchSize:    maxBatchSize,
 		toAddLset:       toAdd,
 		toExcludeLabels: toExclude,
+		relabelConfigs:  relabelConfigs,
 
 		dropped: prometheus.NewCounter(prometheus.CounterOpts{
 			Name: "thanos_alert_queue_alerts_dropped_total",
@@ -200,10 +211,8 @@ func (q *Queue) Push(alerts []*Alert) {
 	q.mtx.Lock()
 	defer q.mtx.Unlock()
 
-	q.pushed.Add(float64(len(alerts)))
-
-	// Attach external labels and drop excluded labels before sending.
-	// TODO(bwplotka): User proper relabelling with https://github.com/improbable-eng/thanos/issues/660
+	// Attach external labels, drop excluded labels and apply the alert relabeling before sending.
+	relabeled := make([]*Alert, 0, len(alerts))
 	for _, a := range alerts {
 		lb := labels.NewBuilder(labels.Labels{})
 		for _, l := range a.Labels {
@@ -215,8 +224,18 @@ func (q *Queue) Push(alerts []*Alert) {
 		for _, l := range q.toAddLset {
 			lb.Set(l.Name, l.Value)
 		}
-		a.Labels = lb.Labels()
+		a.Labels = relabel.Process(lb.Labels(), q.relabelConfigs...)
+		if a.Labels == nil {
+			continue
+		}
+		relabeled = append(relabeled, a)
 	}
+	alerts = relabeled
+	if len(alerts) == 0 {
+		return
+	}
+
+	q.pushed.Add(float64(len(alerts)))
 
 	// Queue capacity should be significantly larger than a single alert
 	// batch could be.
@@ -251,9 +270,7 @@ func (q *Queue) Push(alerts []*Alert) {
 // Sender sends notifications to a dynamic set of alertmanagers.
 type Sender struct {
 	logger        log.Logger
-	alertmanagers func() []*url.URL
-	doReq         func(req *http.Request) (*http.Response, error)
-	timeout       time.Duration
+	alertmanagers []*Alertmanager
 
 	sent    *prometheus.CounterVec
 	errs    *prometheus.CounterVec
@@ -262,25 +279,18 @@ type Sender struct {
 }
 
 // NewSender returns a new sender. On each call to Send the entire alert batch is sent
-// to each Alertmanager returned by the getter function.
+// to each currently resolved replica of all given Alertmanagers.
 func NewSender(
 	logger log.Logger,
 	reg prometheus.Registerer,
-	alertmanagers func() []*url.URL,
-	doReq func(req *http.Request) (*http.Response, error),
-	timeout time.Duration,
+	alertmanagers []*Alertmanager,
 ) *Sender {
-	if doReq == nil {
-		doReq = http.DefaultClient.Do
-	}
 	if logger == nil {
 		logger = log.NewNopLogger()
 	}
 	s := &Sender{
 		logger:        logger,
 		alertmanagers: alertmanagers,
-		doReq:         doReq,
-		timeout:       timeout,
 
 		sent: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "thanos_alert_sender_alerts_sent_total",
@@ -323,34 +333,38 @@ func (s *Sender) Send(ctx context.Context, alerts []*Alert) {
 	var (
 		wg         sync.WaitGroup
 		numSuccess uint64
+		amrs       []*url.URL
 	)
-	amrs := s.alertmanagers()
-	for _, u := range amrs {
-		amURL := *u
-		sendCtx, cancel := context.WithTimeout(ctx, s.timeout)
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			defer cancel()
-
-			start := time.Now()
-			amURL.Path = path.Join(amURL.Path, alertPushEndpoint)
-
-			if err := s.sendOne(sendCtx, amURL.String(), b); err != nil {
-				level.Warn(s.logger).Log(
-					"msg", "sending alerts failed",
-					"alertmanager", amURL.Host,
-					"numAlerts", len(alerts),
-					"err", err)
-				s.errs.WithLabelValues(amURL.Host).Inc()
-				return
-			}
-			s.latency.WithLabelValues(amURL.Host).Observe(time.Since(start).Seconds())
-			s.sent.WithLabelValues(amURL.Host).Add(float64(len(alerts)))
-
-			atomic.AddUint64(&numSuccess, 1)
-		}()
+	for _, am := range s.alertmanagers {
+		for _, u := range am.Endpoints() {
+			amrs = append(amrs, u)
+			amURL := *u
+			am := am
+			sendCtx, cancel := context.WithTimeout(ctx, am.timeout)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer cancel()
+
+				start := time.Now()
+				amURL.Path = path.Join(amURL.Path, alertPushEndpoint)
+
+				if err := s.sendOne(sendCtx, am.client, amURL.String(), b); err != nil {
+					level.Warn(s.logger).Log(
+						"msg", "sending alerts failed",
+						"alertmanager", amURL.Host,
+						"numAlerts", len(alerts),
+						"err", err)
+					s.errs.WithLabelValues(amURL.Host).Inc()
+					return
+				}
+				s.latency.WithLabelValues(amURL.Host).Observe(time.Since(start).Seconds())
+				s.sent.WithLabelValues(amURL.Host).Add(float64(len(alerts)))
+
+				atomic.AddUint64(&numSuccess, 1)
+			}()
+		}
 	}
 	wg.Wait()
 
@@ -363,7 +377,7 @@ func (s *Sender) Send(ctx context.Context, alerts []*Alert) {
 	return
 }
 
-func (s *Sender) sendOne(ctx context.Context, url string, b []byte) error {
+func (s *Sender) sendOne(ctx context.Context, client doer, url string, b []byte) error {
 	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
 	if err != nil {
 		return err
@@ -371,7 +385,7 @@ func (s *Sender) sendOne(ctx context.Context, url string, b []byte) error {
 	req = req.WithContext(ctx)
 	req.Header.Set("Content-Type", contentTypeJSON)
 
-	resp, err := s.doReq(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return errors.Wrapf(err, "send request to %q", url)
 	}
@@ -22,6 +22,7 @@ func TestQueue_Push_Relabelled(t *testing.T) {
 		nil, nil, 10, 10,
 		labels.FromStrings("a", "1", "replica", "A"), // Labels to be added.
 		[]string{"b", "replica"},                     // Labels to be dropped (excluding those added).
+		nil,
 	)
 
 	q.Push([]*Alert{
@@ -36,6 +37,33 @@ func TestQueue_Push_Relabelled(t *testing.T) {
 	testutil.Equals(t, labels.FromStrings("a", "1"), q.queue[2].Labels)
 }
 
+func TestQueue_Push_AlertRelabelConfigs(t *testing.T) {
+	cfg, err := LoadAlertingConfig([]byte(`
+alert_relabel_configs:
+- source_labels: [severity]
+  regex: debug
+  action: drop
+- target_label: env
+  replacement: prod
+- regex: replica
+  action: labeldrop
+`))
+	testutil.Ok(t, err)
+
+	q := NewQueue(nil, nil, 10, 10, labels.FromStrings("replica", "A"), nil, cfg.AlertRelabelConfigs)
+	q.Push([]*Alert{
+		{Labels: labels.FromStrings("alertname", "a", "severity", "critical")},
+		{Labels: labels.FromStrings("alertname", "b", "severity", "debug")},
+	})
+
+	testutil.Equals(t, 1, len(q.queue))
+	testutil.Equals(t, labels.FromStrings("alertname", "a", "env", "prod", "severity", "critical"), q.queue[0].Labels)
+
+	// Nothing is queued if all alerts are dropped.
+	q.Push([]*Alert{{Labels: labels.FromStrings("alertname", "b", "severity", "debug")}})
+	testutil.Equals(t, 1, len(q.queue))
+}
+
 func assertSameHosts(t *testing.T, expected []*url.URL, found []*url.URL) {
 	testutil.Equals(t, len(expected), len(found))
 
@@ -68,7 +96,7 @@ func TestSender_Send_OK(t *testing.T) {
 			StatusCode: http.StatusOK,
 		}, nil
 	}
-	s := NewSender(nil, nil, func() []*url.URL { return expectedHosts }, okDo, 10*time.Second)
+	s := NewSender(nil, nil, []*Alertmanager{{client: doFunc(okDo), timeout: 10 * time.Second, current: expectedHosts}})
 
 	s.Send(context.Background(), []*Alert{{}, {}})
 
@@ -103,7 +131,7 @@ func TestSender_Send_OneFails(t *testing.T) {
 			StatusCode: http.StatusOK,
 		}, nil
 	}
-	s := NewSender(nil, nil, func() []*url.URL { return expectedHosts }, do, 10*time.Second)
+	s := NewSender(nil, nil, []*Alertmanager{{client: doFunc(do), timeout: 10 * time.Second, current: expectedHosts}})
 
 	s.Send(context.Background(), []*Alert{{}, {}})
 
@@ -132,7 +160,7 @@ func TestSender_Send_AllFails(t *testing.T) {
 
 		return nil, errors.New("no such host")
 	}
-	s := NewSender(nil, nil, func() []*url.URL { return expectedHosts }, do, 10*time.Second)
+	s := NewSender(nil, nil, []*Alertmanager{{client: doFunc(do), timeout: 10 * time.Second, current: expectedHosts}})
 
 	s.Send(context.Background(), []*Alert{{}, {}})
 
@@ -145,3 +173,9 @@ func TestSender_Send_AllFails(t *testing.T) {
 	testutil.Equals(t, 1, int(promtestutil.ToFloat64(s.errs.WithLabelValues(expectedHosts[1].Host))))
 	testutil.Equals(t, 2, int(promtestutil.ToFloat64(s.dropped)))
 }
+
+type doFunc func(req *http.Request) (*http.Response, error)
+
+func (f doFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,156 @@
+package alert
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/discovery/cache"
+	"github.com/improbable-eng/thanos/pkg/discovery/dns"
+	"github.com/pkg/errors"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/prometheus/discovery/file"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+)
+
+const defaultAlertmanagerPort = 9093
+
+type doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Alertmanager is a set of Alertmanager replicas discovered from static addresses and file SD, which are
+// all sent alerts with the same HTTP client.
+type Alertmanager struct {
+	logger   log.Logger
+	client   doer
+	timeout  time.Duration
+	scheme   string
+	prefix   string
+	resolver dns.Resolver
+
+	staticAddresses []string
+	fileSDCache     *cache.Cache
+	fileSD          []*file.Discovery
+
+	mtx     sync.Mutex
+	current []*url.URL
+}
+
+// NewAlertmanager returns an Alertmanager client for the given configuration. Addresses are only available
+// after the first call to Resolve.
+func NewAlertmanager(logger log.Logger, cfg AlertmanagerConfig, resolver dns.Resolver) (*Alertmanager, error) {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	client, err := config_util.NewClientFromConfig(cfg.HTTPClientConfig, "alertmanager")
+	if err != nil {
+		return nil, errors.Wrap(err, "create HTTP client")
+	}
+	a := &Alertmanager{
+		logger:          logger,
+		client:          client,
+		timeout:         time.Duration(cfg.Timeout),
+		scheme:          cfg.Scheme,
+		prefix:          cfg.PathPrefix,
+		resolver:        resolver,
+		staticAddresses: cfg.StaticAddresses,
+		fileSDCache:     cache.New(),
+	}
+	for i := range cfg.FileSDConfigs {
+		a.fileSD = append(a.fileSD, file.NewDiscovery(&cfg.FileSDConfigs[i], logger))
+	}
+	return a, nil
+}
+
+// Discover runs the file service discoveries of the Alertmanager until the context is canceled.
+func (a *Alertmanager) Discover(ctx context.Context) {
+	if len(a.fileSD) == 0 {
+		<-ctx.Done()
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range a.fileSD {
+		updates := make(chan []*targetgroup.Group)
+		wg.Add(2)
+		go func(d *file.Discovery) {
+			defer wg.Done()
+			d.Run(ctx, updates)
+		}(d)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case tgs := <-updates:
+					// Discoverers sometimes send nil updates so need to check for it to avoid panics.
+					if tgs == nil {
+						continue
+					}
+					a.fileSDCache.Update(tgs)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Resolve updates the endpoints of the Alertmanager from the static and file SD addresses, resolving
+// them through DNS if requested.
+func (a *Alertmanager) Resolve(ctx context.Context) error {
+	var result []*url.URL
+	for _, addr := range append(a.fileSDCache.Addresses(), a.staticAddresses...) {
+		var (
+			host     = addr
+			qtype    dns.QType
+			resolved []string
+		)
+		if qtypeAndName := strings.SplitN(addr, "+", 2); len(qtypeAndName) == 2 {
+			qtype, host = dns.QType(qtypeAndName[0]), qtypeAndName[1]
+		}
+
+		if qtype != "" {
+			if qtype == dns.A {
+				if _, _, err := net.SplitHostPort(host); err != nil {
+					// The host could be missing a port. Append the defaultAlertmanagerPort.
+					host = host + ":" + strconv.Itoa(defaultAlertmanagerPort)
+				}
+			}
+			var err error
+			resolved, err = a.resolver.Resolve(ctx, host, qtype)
+			if err != nil {
+				return errors.Wrap(err, "alertmanager resolve")
+			}
+		} else {
+			resolved = []string{host}
+		}
+
+		for _, r := range resolved {
+			result = append(result, &url.URL{
+				Scheme: a.scheme,
+				Host:   r,
+				Path:   a.prefix,
+			})
+		}
+	}
+
+	a.mtx.Lock()
+	a.current = result
+	a.mtx.Unlock()
+	return nil
+}
+
+// Endpoints returns the currently resolved URLs of the Alertmanager replicas.
+func (a *Alertmanager) Endpoints() []*url.URL {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.current
+}
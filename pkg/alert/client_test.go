@@ -0,0 +1,69 @@
+package alert
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/discovery/dns"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/pkg/errors"
+)
+
+type mockResolver struct {
+	resultIPs map[string][]string
+}
+
+func (m mockResolver) Resolve(ctx context.Context, name string, qtype dns.QType) ([]string, error) {
+	if res, ok := m.resultIPs[name]; ok {
+		return res, nil
+	}
+	return nil, errors.Errorf("mockResolver not found response for name: %s", name)
+}
+
+func TestAlertmanager_Resolve(t *testing.T) {
+	resolver := mockResolver{
+		resultIPs: map[string][]string{
+			"alertmanager.com:9093":  {"1.1.1.1:9300"},
+			"alertmanager.com:19093": {"1.1.1.2:9300", "1.1.1.3:9300"},
+		},
+	}
+
+	for _, tcase := range []struct {
+		addr     string
+		expected []*url.URL
+	}{
+		{
+			// Port defaults to 9093 for A lookups.
+			addr:     "dns+http://alertmanager.com",
+			expected: []*url.URL{{Scheme: "http", Host: "1.1.1.1:9300"}},
+		},
+		{
+			addr: "dns+https://alertmanager.com:19093/prefix",
+			expected: []*url.URL{
+				{Scheme: "https", Host: "1.1.1.2:9300", Path: "/prefix"},
+				{Scheme: "https", Host: "1.1.1.3:9300", Path: "/prefix"},
+			},
+		},
+		{
+			addr:     "http://alertmanager.com:9093",
+			expected: []*url.URL{{Scheme: "http", Host: "alertmanager.com:9093"}},
+		},
+	} {
+		t.Run(tcase.addr, func(t *testing.T) {
+			cfg, err := BuildAlertmanagerConfig(tcase.addr, 0)
+			testutil.Ok(t, err)
+			am, err := NewAlertmanager(nil, cfg, resolver)
+			testutil.Ok(t, err)
+
+			testutil.Ok(t, am.Resolve(context.Background()))
+			testutil.Equals(t, tcase.expected, am.Endpoints())
+		})
+	}
+
+	cfg, err := BuildAlertmanagerConfig("dns+http://unknown.com", 0)
+	testutil.Ok(t, err)
+	am, err := NewAlertmanager(nil, cfg, resolver)
+	testutil.Ok(t, err)
+	testutil.NotOk(t, am.Resolve(context.Background()))
+}
@@ -0,0 +1,112 @@
+package alert
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/file"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// AlertingConfig is the configuration of all Alertmanager clusters alerts are sent to.
+type AlertingConfig struct {
+	// AlertRelabelConfigs are applied to the labels of all alerts before they are sent, as in Prometheus.
+	AlertRelabelConfigs []*relabel.Config    `yaml:"alert_relabel_configs"`
+	Alertmanagers       []AlertmanagerConfig `yaml:"alertmanagers"`
+}
+
+// AlertmanagerConfig configures the client of a set of Alertmanager replicas sharing the same HTTP configuration.
+type AlertmanagerConfig struct {
+	HTTPClientConfig config_util.HTTPClientConfig `yaml:"http_config"`
+	// StaticAddresses are the host:port addresses of the replicas. They may be prefixed with 'dns+' or 'dnssrv+'
+	// to detect the replicas through respective DNS lookups.
+	StaticAddresses []string        `yaml:"static_configs"`
+	FileSDConfigs   []file.SDConfig `yaml:"file_sd_configs"`
+	Scheme          string          `yaml:"scheme"`
+	PathPrefix      string          `yaml:"path_prefix"`
+	Timeout         model.Duration  `yaml:"timeout"`
+}
+
+// DefaultAlertmanagerConfig returns the default Alertmanager client configuration.
+func DefaultAlertmanagerConfig() AlertmanagerConfig {
+	return AlertmanagerConfig{
+		Scheme:  "http",
+		Timeout: model.Duration(10 * time.Second),
+	}
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *AlertmanagerConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultAlertmanagerConfig()
+	type plain AlertmanagerConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Scheme != "http" && c.Scheme != "https" {
+		return errors.Errorf("invalid scheme %q, must be http or https", c.Scheme)
+	}
+	if len(c.StaticAddresses) == 0 && len(c.FileSDConfigs) == 0 {
+		return errors.New("no static_configs or file_sd_configs configured")
+	}
+	for _, addr := range c.StaticAddresses {
+		if strings.Contains(addr, "/") {
+			return errors.Errorf("static address %q must be a host:port address without scheme and path", addr)
+		}
+	}
+	return c.HTTPClientConfig.Validate()
+}
+
+// LoadAlertingConfig loads the Alertmanager clients configuration from the given YAML.
+func LoadAlertingConfig(confYaml []byte) (AlertingConfig, error) {
+	var cfg AlertingConfig
+	if err := yaml.UnmarshalStrict(confYaml, &cfg); err != nil {
+		return cfg, errors.Wrap(err, "unmarshal alerting configuration")
+	}
+	for _, rlcfg := range cfg.AlertRelabelConfigs {
+		if rlcfg == nil {
+			return cfg, errors.New("empty or null alert relabeling rule")
+		}
+	}
+	return cfg, nil
+}
+
+// BuildAlertmanagerConfig builds the client configuration of an Alertmanager URL as given to the
+// --alertmanagers.url flag. User info of the URL is used for basic authentication.
+func BuildAlertmanagerConfig(address string, timeout time.Duration) (AlertmanagerConfig, error) {
+	var qtype string
+	if qtypeAndName := strings.SplitN(address, "+", 2); len(qtypeAndName) == 2 {
+		qtype, address = qtypeAndName[0], qtypeAndName[1]
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return AlertmanagerConfig{}, errors.Wrapf(err, "parse URL %q", address)
+	}
+	if u.Host == "" {
+		return AlertmanagerConfig{}, errors.Errorf("no host in URL %q", address)
+	}
+
+	cfg := DefaultAlertmanagerConfig()
+	cfg.Timeout = model.Duration(timeout)
+	cfg.PathPrefix = u.Path
+	if u.Scheme != "" {
+		cfg.Scheme = u.Scheme
+	}
+	host := u.Host
+	if qtype != "" {
+		host = qtype + "+" + host
+	}
+	cfg.StaticAddresses = []string{host}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		cfg.HTTPClientConfig.BasicAuth = &config_util.BasicAuth{
+			Username: u.User.Username(),
+			Password: config_util.Secret(password),
+		}
+	}
+	return cfg, nil
+}
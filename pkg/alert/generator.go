@@ -0,0 +1,95 @@
+package alert
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// GeneratorURLData is the data the --alert.query-url template is executed with.
+type GeneratorURLData struct {
+	// Expr is the expression of the alerting rule.
+	Expr string
+	// StartsAt is the time the alert became active.
+	StartsAt time.Time
+	// EndsAt is the time the alert was resolved, the current time for firing alerts.
+	EndsAt time.Time
+	// Labels are the labels of the alert.
+	Labels map[string]string
+}
+
+// GeneratorURL builds the links to the source of alerts, i.e. the expression of the alerting rule shown in the
+// Thanos Query UI.
+type GeneratorURL struct {
+	base string
+	tmpl *template.Template
+}
+
+// NewGeneratorURL returns the generator URL builder for the given --alert.query-url. If it contains a
+// template action, it is used as Go template executed with GeneratorURLData, e.g.
+// 'https://query.example/graph?g0.expr={{ urlquery .Expr }}'. Otherwise it is the URL of the Thanos Query UI
+// the graph of the expression over the time the alert was active is linked on.
+func NewGeneratorURL(queryURL string) (*GeneratorURL, error) {
+	if !strings.Contains(queryURL, "{{") {
+		if _, err := url.Parse(queryURL); err != nil {
+			return nil, errors.Wrap(err, "parse alert query URL")
+		}
+		return &GeneratorURL{base: strings.TrimSuffix(queryURL, "/")}, nil
+	}
+
+	tmpl, err := template.New("alert.query-url").Option("missingkey=zero").Parse(queryURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse alert query URL template")
+	}
+	g := &GeneratorURL{tmpl: tmpl}
+
+	// Catch templates failing for all alerts early.
+	if _, err := g.url(GeneratorURLData{Expr: "up", StartsAt: time.Now(), EndsAt: time.Now()}); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// URL returns the link to the source of the alert with the given expression and labels. A zero resolvedAt means
+// the alert is still firing.
+func (g *GeneratorURL) URL(expr string, lset labels.Labels, activeAt, resolvedAt time.Time) (string, error) {
+	d := GeneratorURLData{
+		Expr:     expr,
+		StartsAt: activeAt,
+		EndsAt:   resolvedAt,
+		Labels:   lset.Map(),
+	}
+	if d.EndsAt.IsZero() {
+		d.EndsAt = time.Now()
+	}
+	return g.url(d)
+}
+
+func (g *GeneratorURL) url(d GeneratorURLData) (string, error) {
+	if g.tmpl != nil {
+		var b bytes.Buffer
+		if err := g.tmpl.Execute(&b, d); err != nil {
+			return "", errors.Wrap(err, "execute alert query URL template")
+		}
+		return b.String(), nil
+	}
+
+	// Show some context before the alert became active, like the Prometheus UI does for the default range.
+	rng := d.EndsAt.Sub(d.StartsAt) + time.Hour
+	if d.StartsAt.IsZero() || rng < time.Hour {
+		rng = time.Hour
+	}
+	params := url.Values{}
+	params.Set("g0.expr", d.Expr)
+	params.Set("g0.tab", "0")
+	params.Set("g0.range_input", model.Duration(rng.Round(time.Minute)).String())
+	params.Set("g0.end_input", d.EndsAt.UTC().Format("2006-01-02 15:04"))
+	return fmt.Sprintf("%s/graph?%s", g.base, params.Encode()), nil
+}
@@ -0,0 +1,45 @@
+package alert
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+func TestGeneratorURL(t *testing.T) {
+	var (
+		activeAt   = time.Date(2019, 4, 1, 10, 0, 0, 0, time.UTC)
+		resolvedAt = time.Date(2019, 4, 1, 10, 30, 0, 0, time.UTC)
+		lset       = labels.FromStrings("alertname", "HighLatency", "job", "api")
+	)
+
+	g, err := NewGeneratorURL("https://query.example/")
+	testutil.Ok(t, err)
+	u, err := g.URL(`rate(errors_total{job="api"}[5m]) > 0`, lset, activeAt, resolvedAt)
+	testutil.Ok(t, err)
+
+	parsed, err := url.Parse(u)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "query.example", parsed.Host)
+	testutil.Equals(t, "/graph", parsed.Path)
+	testutil.Equals(t, url.Values{
+		"g0.expr":        []string{`rate(errors_total{job="api"}[5m]) > 0`},
+		"g0.tab":         []string{"0"},
+		"g0.range_input": []string{"90m"},
+		"g0.end_input":   []string{"2019-04-01 10:30"},
+	}, parsed.Query())
+
+	g, err = NewGeneratorURL(`https://grafana.example/explore?expr={{ urlquery .Expr }}&from={{ .StartsAt.Unix }}&to={{ .EndsAt.Unix }}&job={{ .Labels.job }}`)
+	testutil.Ok(t, err)
+	u, err = g.URL("up == 0", lset, activeAt, resolvedAt)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "https://grafana.example/explore?expr=up+%3D%3D+0&from=1554112800&to=1554114600&job=api", u)
+
+	_, err = NewGeneratorURL("https://query.example/{{ .Unknown }}")
+	testutil.NotOk(t, err)
+	_, err = NewGeneratorURL("https://query.example/{{ .Expr")
+	testutil.NotOk(t, err)
+}
@@ -3,18 +3,22 @@
 package block
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/improbable-eng/thanos/pkg/block/metadata"
 
 	"fmt"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/improbable-eng/thanos/pkg/objstore"
 	"github.com/improbable-eng/thanos/pkg/runutil"
 	"github.com/oklog/ulid"
@@ -55,6 +59,20 @@ func Download(ctx context.Context, logger log.Logger, bucket objstore.Bucket, id
 	return nil
 }
 
+// DownloadIndex downloads only the index file of the block into the dst block directory.
+func DownloadIndex(ctx context.Context, logger log.Logger, bucket objstore.BucketReader, id ulid.ULID, dst string) error {
+	if err := os.MkdirAll(dst, 0777); err != nil {
+		return errors.Wrap(err, "create dir")
+	}
+	return objstore.DownloadFile(ctx, logger, bucket, path.Join(id.String(), IndexFilename), filepath.Join(dst, IndexFilename))
+}
+
+// DownloadChunks downloads only the chunks directory of the block into the dst block directory.
+func DownloadChunks(ctx context.Context, logger log.Logger, bucket objstore.BucketReader, id ulid.ULID, dst string) error {
+	// DownloadDir creates the directory even if the block is empty and there are no chunk files to download.
+	return objstore.DownloadDir(ctx, logger, bucket, path.Join(id.String(), ChunksDirname), filepath.Join(dst, ChunksDirname))
+}
+
 // Upload uploads block from given block dir that ends with block id.
 // It makes sure cleanup is done on error to avoid partial block uploads.
 // It also verifies basic features of Thanos block.
@@ -126,6 +144,86 @@ func Delete(ctx context.Context, bucket objstore.Bucket, id ulid.ULID) error {
 	return objstore.DeleteDir(ctx, bucket, id.String())
 }
 
+// MarkForDeletion creates a file which stores information about when the block was marked for deletion.
+// Marked blocks are not removed right away, so components that still have them loaded can finish using them.
+func MarkForDeletion(ctx context.Context, logger log.Logger, bkt objstore.Bucket, id ulid.ULID) error {
+	deletionMarkFile := path.Join(id.String(), metadata.DeletionMarkFilename)
+	deletionMarkExists, err := bkt.Exists(ctx, deletionMarkFile)
+	if err != nil {
+		return errors.Wrapf(err, "check exists %s in bucket", deletionMarkFile)
+	}
+	if deletionMarkExists {
+		level.Warn(logger).Log("msg", "requested to mark for deletion, but file already exists; this should not happen; investigate", "err", errors.Errorf("file %s already exists in bucket", deletionMarkFile))
+		return nil
+	}
+
+	deletionMark, err := json.Marshal(metadata.DeletionMark{
+		ID:           id,
+		DeletionTime: time.Now().Unix(),
+		Version:      metadata.DeletionMarkVersion1,
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encode deletion mark")
+	}
+
+	if err := bkt.Upload(ctx, deletionMarkFile, bytes.NewReader(deletionMark)); err != nil {
+		return errors.Wrapf(err, "upload file %s to bucket", deletionMarkFile)
+	}
+	level.Info(logger).Log("msg", "block has been marked for deletion", "block", id)
+	return nil
+}
+
+// ReadDeletionMark reads the deletion mark of the given block. It returns nil if the block is not marked for deletion.
+func ReadDeletionMark(ctx context.Context, logger log.Logger, bkt objstore.BucketReader, id ulid.ULID) (*metadata.DeletionMark, error) {
+	deletionMarkFile := path.Join(id.String(), metadata.DeletionMarkFilename)
+
+	rc, err := bkt.Get(ctx, deletionMarkFile)
+	if err != nil {
+		if bkt.IsObjNotFoundErr(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "get file %s", deletionMarkFile)
+	}
+	defer runutil.CloseWithLogOnErr(logger, rc, "close deletion mark reader")
+
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read file %s", deletionMarkFile)
+	}
+
+	var deletionMark metadata.DeletionMark
+	if err := json.Unmarshal(b, &deletionMark); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal file %s", deletionMarkFile)
+	}
+	if deletionMark.Version != metadata.DeletionMarkVersion1 {
+		return nil, errors.Errorf("unexpected deletion mark file version %d", deletionMark.Version)
+	}
+	return &deletionMark, nil
+}
+
+// Size returns the total size in bytes of all objects belonging to the given block in the bucket.
+func Size(ctx context.Context, bkt objstore.BucketReader, id ulid.ULID) (uint64, error) {
+	var size uint64
+	var iterFn func(dir string) error
+	iterFn = func(dir string) error {
+		return bkt.Iter(ctx, dir, func(name string) error {
+			if strings.HasSuffix(name, objstore.DirDelim) {
+				return iterFn(name)
+			}
+			s, err := bkt.ObjectSize(ctx, name)
+			if err != nil {
+				return errors.Wrapf(err, "size of %s", name)
+			}
+			size += s
+			return nil
+		})
+	}
+	if err := iterFn(id.String()); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
 // DownloadMeta downloads only meta file from bucket by block ID.
 // TODO(bwplotka): Differentiate between network error & partial upload.
 func DownloadMeta(ctx context.Context, logger log.Logger, bkt objstore.Bucket, id ulid.ULID) (metadata.Meta, error) {
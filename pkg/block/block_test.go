@@ -1,8 +1,12 @@
 package block
 
 import (
+	"context"
+	"strings"
 	"testing"
 
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/objstore/inmem"
 	"github.com/oklog/ulid"
 )
 
@@ -56,3 +60,61 @@ func TestIsBlockDir(t *testing.T) {
 		})
 	}
 }
+
+func TestSize(t *testing.T) {
+	ctx := context.Background()
+	bkt := inmem.NewBucket()
+
+	id := ulid.MustNew(1, nil)
+	for name, content := range map[string]string{
+		id.String() + "/meta.json":                     "{}",
+		id.String() + "/index":                         "@index@",
+		id.String() + "/chunks/000001":                 "@chunks1@",
+		id.String() + "/chunks/000002":                 "@chunks2@",
+		ulid.MustNew(2, nil).String() + "/chunks/0001": "@other block@",
+	} {
+		if err := bkt.Upload(ctx, name, strings.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	size, err := Size(ctx, bkt, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := uint64(len("{}@index@@chunks1@@chunks2@")); size != exp {
+		t.Errorf("expected size %d got %d", exp, size)
+	}
+}
+
+func TestReadDeletionMark(t *testing.T) {
+	ctx := context.Background()
+	bkt := inmem.NewBucket()
+
+	id := ulid.MustNew(1, nil)
+	m, err := ReadDeletionMark(ctx, log.NewNopLogger(), bkt, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != nil {
+		t.Errorf("expected no deletion mark, got %v", m)
+	}
+
+	if err := MarkForDeletion(ctx, log.NewNopLogger(), bkt, id); err != nil {
+		t.Fatal(err)
+	}
+	m, err = ReadDeletionMark(ctx, log.NewNopLogger(), bkt, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m == nil || m.ID != id || m.DeletionTime == 0 {
+		t.Errorf("unexpected deletion mark %v", m)
+	}
+
+	if err := bkt.Upload(ctx, id.String()+"/deletion-mark.json", strings.NewReader(`{"version": 2}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadDeletionMark(ctx, log.NewNopLogger(), bkt, id); err == nil {
+		t.Error("expected error for unknown deletion mark version")
+	}
+}
@@ -0,0 +1,196 @@
+package block
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/block/metadata"
+	"github.com/improbable-eng/thanos/pkg/objstore"
+	"github.com/improbable-eng/thanos/pkg/runutil"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// BucketIndexFilename is the bucket path of the bucket index maintained by the compactor.
+	BucketIndexFilename = "bucket-index.json.gz"
+
+	// BucketIndexVersion1 is the first version of the bucket index format.
+	BucketIndexVersion1 = 1
+)
+
+// ErrBucketIndexNotFound is returned by ReadBucketIndex if the bucket has no bucket index.
+var ErrBucketIndexNotFound = errors.New("bucket index not found")
+
+// BucketIndex lists the metas and deletion marks of all blocks in the bucket, so that readers can sync
+// without iterating the bucket and fetching the meta.json of every block.
+type BucketIndex struct {
+	Version int `json:"version"`
+	// Blocks holds the metas of all blocks with meta.json, sorted by ULID.
+	Blocks []metadata.Meta `json:"blocks"`
+	// DeletionMarks holds the deletion marks of blocks marked for deletion, sorted by ULID.
+	DeletionMarks []metadata.DeletionMark `json:"deletion_marks"`
+	// UpdatedAt is the unix timestamp of when the index was built.
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+// UpdatedAtTime returns the time the index was built.
+func (idx *BucketIndex) UpdatedAtTime() time.Time {
+	return time.Unix(idx.UpdatedAt, 0)
+}
+
+// ReadBucketIndex reads the bucket index. It returns ErrBucketIndexNotFound if there is none.
+func ReadBucketIndex(ctx context.Context, logger log.Logger, bkt objstore.BucketReader) (*BucketIndex, error) {
+	rc, err := bkt.Get(ctx, BucketIndexFilename)
+	if err != nil {
+		if bkt.IsObjNotFoundErr(err) {
+			return nil, ErrBucketIndexNotFound
+		}
+		return nil, errors.Wrapf(err, "get file %s", BucketIndexFilename)
+	}
+	defer runutil.CloseWithLogOnErr(logger, rc, "close bucket index reader")
+
+	gz, err := gzip.NewReader(rc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "create gzip reader for %s", BucketIndexFilename)
+	}
+	defer runutil.CloseWithLogOnErr(logger, gz, "close bucket index gzip reader")
+
+	var idx BucketIndex
+	if err := json.NewDecoder(gz).Decode(&idx); err != nil {
+		return nil, errors.Wrapf(err, "decode file %s", BucketIndexFilename)
+	}
+	if idx.Version != BucketIndexVersion1 {
+		return nil, errors.Errorf("unexpected bucket index version %d", idx.Version)
+	}
+	return &idx, nil
+}
+
+// WriteBucketIndex uploads the given bucket index.
+func WriteBucketIndex(ctx context.Context, bkt objstore.Bucket, idx *BucketIndex) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(idx); err != nil {
+		return errors.Wrap(err, "encode bucket index")
+	}
+	if err := gz.Close(); err != nil {
+		return errors.Wrap(err, "close gzip writer")
+	}
+	return errors.Wrapf(bkt.Upload(ctx, BucketIndexFilename, &buf), "upload file %s", BucketIndexFilename)
+}
+
+// BucketIndexUpdater maintains the bucket index. Metas and deletion marks never change once written, so only
+// those of blocks not known by the previous index are fetched.
+type BucketIndexUpdater struct {
+	logger log.Logger
+	bkt    objstore.Bucket
+
+	metas map[ulid.ULID]metadata.Meta
+	marks map[ulid.ULID]metadata.DeletionMark
+}
+
+// NewBucketIndexUpdater returns a new BucketIndexUpdater for the given bucket.
+func NewBucketIndexUpdater(logger log.Logger, bkt objstore.Bucket) *BucketIndexUpdater {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return &BucketIndexUpdater{logger: logger, bkt: bkt}
+}
+
+// Update builds the bucket index of the current bucket state and uploads it.
+func (u *BucketIndexUpdater) Update(ctx context.Context) (*BucketIndex, error) {
+	if u.metas == nil {
+		u.loadPrevious(ctx)
+	}
+
+	remote := map[ulid.ULID]struct{}{}
+	if err := u.bkt.Iter(ctx, "", func(name string) error {
+		id, ok := IsBlockDir(name)
+		if !ok {
+			return nil
+		}
+		remote[id] = struct{}{}
+
+		if _, ok := u.metas[id]; !ok {
+			meta, err := DownloadMeta(ctx, u.logger, u.bkt, id)
+			if err != nil {
+				// Blocks without meta.json are still uploading or partial, they are not served by anyone.
+				if u.bkt.IsObjNotFoundErr(errors.Cause(err)) {
+					return nil
+				}
+				return err
+			}
+			u.metas[id] = meta
+		}
+		if _, ok := u.marks[id]; ok {
+			return nil
+		}
+		m, err := ReadDeletionMark(ctx, u.logger, u.bkt, id)
+		if err != nil {
+			return err
+		}
+		if m != nil {
+			u.marks[id] = *m
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "iter")
+	}
+
+	idx := &BucketIndex{
+		Version:       BucketIndexVersion1,
+		Blocks:        []metadata.Meta{},
+		DeletionMarks: []metadata.DeletionMark{},
+		UpdatedAt:     time.Now().Unix(),
+	}
+	for id, meta := range u.metas {
+		if _, ok := remote[id]; !ok {
+			delete(u.metas, id)
+			delete(u.marks, id)
+			continue
+		}
+		idx.Blocks = append(idx.Blocks, meta)
+		if m, ok := u.marks[id]; ok {
+			idx.DeletionMarks = append(idx.DeletionMarks, m)
+		}
+	}
+	sort.Slice(idx.Blocks, func(i, j int) bool {
+		return idx.Blocks[i].ULID.Compare(idx.Blocks[j].ULID) < 0
+	})
+	sort.Slice(idx.DeletionMarks, func(i, j int) bool {
+		return idx.DeletionMarks[i].ID.Compare(idx.DeletionMarks[j].ID) < 0
+	})
+
+	if err := WriteBucketIndex(ctx, u.bkt, idx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// loadPrevious initializes the known metas and deletion marks from the bucket index in the bucket, if any.
+// Unreadable indexes are rebuilt from scratch.
+func (u *BucketIndexUpdater) loadPrevious(ctx context.Context) {
+	u.metas = map[ulid.ULID]metadata.Meta{}
+	u.marks = map[ulid.ULID]metadata.DeletionMark{}
+
+	idx, err := ReadBucketIndex(ctx, u.logger, u.bkt)
+	if err == ErrBucketIndexNotFound {
+		return
+	}
+	if err != nil {
+		level.Warn(u.logger).Log("msg", "failed to read previous bucket index; rebuilding it", "err", err)
+		return
+	}
+	for _, meta := range idx.Blocks {
+		u.metas[meta.ULID] = meta
+	}
+	for _, m := range idx.DeletionMarks {
+		u.marks[m.ID] = m
+	}
+}
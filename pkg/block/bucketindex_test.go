@@ -0,0 +1,78 @@
+package block
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/block/metadata"
+	"github.com/improbable-eng/thanos/pkg/objstore/inmem"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/tsdb"
+)
+
+func TestBucketIndexUpdater(t *testing.T) {
+	ctx := context.Background()
+	bkt := inmem.NewBucket()
+
+	if _, err := ReadBucketIndex(ctx, log.NewNopLogger(), bkt); err != ErrBucketIndexNotFound {
+		t.Fatalf("expected bucket index not found error, got %v", err)
+	}
+
+	upload := func(id ulid.ULID) {
+		b, err := json.Marshal(metadata.Meta{BlockMeta: tsdb.BlockMeta{ULID: id, Version: 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bkt.Upload(ctx, path.Join(id.String(), MetaFilename), strings.NewReader(string(b))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	id1, id2, partial := ulid.MustNew(1, nil), ulid.MustNew(2, nil), ulid.MustNew(3, nil)
+	upload(id2)
+	upload(id1)
+	if err := bkt.Upload(ctx, path.Join(partial.String(), IndexFilename), strings.NewReader("@index@")); err != nil {
+		t.Fatal(err)
+	}
+	if err := MarkForDeletion(ctx, log.NewNopLogger(), bkt, id2); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewBucketIndexUpdater(nil, bkt).Update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := ReadBucketIndex(ctx, log.NewNopLogger(), bkt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Blocks) != 2 || idx.Blocks[0].ULID != id1 || idx.Blocks[1].ULID != id2 {
+		t.Fatalf("unexpected blocks %v", idx.Blocks)
+	}
+	if len(idx.DeletionMarks) != 1 || idx.DeletionMarks[0].ID != id2 {
+		t.Fatalf("unexpected deletion marks %v", idx.DeletionMarks)
+	}
+	if idx.UpdatedAt == 0 {
+		t.Error("expected update time to be set")
+	}
+
+	// A new updater starts from the previous index, so metas of known blocks are not fetched again.
+	if err := bkt.Upload(ctx, path.Join(id1.String(), MetaFilename), strings.NewReader("invalid")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Delete(ctx, bkt, id2); err != nil {
+		t.Fatal(err)
+	}
+	idx, err = NewBucketIndexUpdater(nil, bkt).Update(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Blocks) != 1 || idx.Blocks[0].ULID != id1 {
+		t.Fatalf("unexpected blocks %v", idx.Blocks)
+	}
+	if len(idx.DeletionMarks) != 0 {
+		t.Fatalf("unexpected deletion marks %v", idx.DeletionMarks)
+	}
+}
@@ -0,0 +1,286 @@
+// Package indexheader implements the index-header: a small binary file with the parts of the block index that
+// the store gateway needs in memory to serve queries, i.e. the symbol table and the postings offset table.
+// It is built from the block index in the object storage using range requests only, so the full index is never
+// downloaded.
+package indexheader
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/block"
+	"github.com/improbable-eng/thanos/pkg/objstore"
+	"github.com/improbable-eng/thanos/pkg/runutil"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/tsdb/encoding"
+	"github.com/prometheus/tsdb/fileutil"
+	"github.com/prometheus/tsdb/index"
+	"github.com/prometheus/tsdb/labels"
+)
+
+const (
+	// BinaryFilename is the known filename of the binary index-header placed next to the block.
+	BinaryFilename = "index-header"
+
+	// MagicIndexHeader are 4 bytes at the head of an index-header file.
+	MagicIndexHeader = 0xBAAAD792
+
+	// BinaryFormatV1 is the first version of the index-header format.
+	BinaryFormatV1 = 1
+
+	// headerLen is the number of bytes of the index-header preamble: magic, index-header version, index version,
+	// offset of the symbol table in the index and offset of the end of the postings section in the index.
+	headerLen = 4 + 1 + 1 + 8 + 8
+
+	// indexTOCLen is the length of the TOC at the end of the index file.
+	indexTOCLen = 6*8 + crc32.Size
+)
+
+type realByteSlice []byte
+
+func (b realByteSlice) Len() int {
+	return len(b)
+}
+
+func (b realByteSlice) Range(start, end int) []byte {
+	return b[start:end]
+}
+
+func (b realByteSlice) Sub(start, end int) index.ByteSlice {
+	return b[start:end]
+}
+
+// WriteBinary builds the index-header of the given block from its index in the bucket and writes it to fn.
+// Only the index header, TOC, symbol table and postings offset table are fetched.
+func WriteBinary(ctx context.Context, logger log.Logger, bkt objstore.BucketReader, id ulid.ULID, fn string) (err error) {
+	indexFn := path.Join(id.String(), block.IndexFilename)
+	size, err := bkt.ObjectSize(ctx, indexFn)
+	if err != nil {
+		return errors.Wrapf(err, "get size of %s", indexFn)
+	}
+	if size < index.HeaderLen+indexTOCLen {
+		return errors.Errorf("index %s too small: %d bytes", indexFn, size)
+	}
+
+	hdr, err := getRange(ctx, logger, bkt, indexFn, 0, index.HeaderLen)
+	if err != nil {
+		return errors.Wrap(err, "read index header")
+	}
+	if m := binary.BigEndian.Uint32(hdr[0:4]); m != index.MagicIndex {
+		return errors.Errorf("invalid magic number %x in %s", m, indexFn)
+	}
+	indexVersion := hdr[4]
+	if indexVersion != index.FormatV1 && indexVersion != index.FormatV2 {
+		return errors.Errorf("unknown index file version %d", indexVersion)
+	}
+
+	tocBytes, err := getRange(ctx, logger, bkt, indexFn, int64(size)-indexTOCLen, indexTOCLen)
+	if err != nil {
+		return errors.Wrap(err, "read index TOC")
+	}
+	toc, err := index.NewTOCFromByteSlice(realByteSlice(tocBytes))
+	if err != nil {
+		return errors.Wrap(err, "parse index TOC")
+	}
+
+	// Symbols are followed by series that are aligned, so read until series start and cut the padding.
+	symbols, err := getRange(ctx, logger, bkt, indexFn, int64(toc.Symbols), int64(toc.Series-toc.Symbols))
+	if err != nil {
+		return errors.Wrap(err, "read symbols")
+	}
+	symbols, err = section(symbols)
+	if err != nil {
+		return errors.Wrap(err, "symbols")
+	}
+
+	// Postings offset table is followed directly by TOC.
+	postingsTable, err := getRange(ctx, logger, bkt, indexFn, int64(toc.PostingsTable), int64(size)-indexTOCLen-int64(toc.PostingsTable))
+	if err != nil {
+		return errors.Wrap(err, "read postings offset table")
+	}
+	postingsTable, err = section(postingsTable)
+	if err != nil {
+		return errors.Wrap(err, "postings offset table")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fn), 0777); err != nil {
+		return errors.Wrap(err, "create dir")
+	}
+
+	// Make the file appear atomically, so a crash does not leave partial index-header for the next start.
+	tmp := fn + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return errors.Wrap(err, "create index-header file")
+	}
+	defer func() {
+		if err != nil {
+			runutil.CloseWithLogOnErr(logger, f, "index-header writer")
+			if rerr := os.Remove(tmp); rerr != nil && !os.IsNotExist(rerr) {
+				err = errors.Wrapf(err, "remove temporary index-header file: %v", rerr)
+			}
+		}
+	}()
+
+	w := bufio.NewWriter(f)
+	var preamble [headerLen]byte
+	binary.BigEndian.PutUint32(preamble[0:4], MagicIndexHeader)
+	preamble[4] = BinaryFormatV1
+	preamble[5] = indexVersion
+	binary.BigEndian.PutUint64(preamble[6:14], toc.Symbols)
+	// Label indices offset table is the first thing written after the last postings list.
+	binary.BigEndian.PutUint64(preamble[14:22], toc.LabelIndicesTable)
+
+	for _, b := range [][]byte{preamble[:], symbols, postingsTable} {
+		if _, err := w.Write(b); err != nil {
+			return errors.Wrap(err, "write index-header")
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "flush index-header")
+	}
+	if err := f.Sync(); err != nil {
+		return errors.Wrap(err, "sync index-header")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "close index-header")
+	}
+	return errors.Wrap(os.Rename(tmp, fn), "rename index-header")
+}
+
+// section returns the given bytes trimmed to the length-prefixed, CRC32 suffixed section at their beginning.
+func section(b []byte) ([]byte, error) {
+	if len(b) < 4 {
+		return nil, encoding.ErrInvalidSize
+	}
+	l := 4 + int(binary.BigEndian.Uint32(b[:4])) + crc32.Size
+	if len(b) < l {
+		return nil, encoding.ErrInvalidSize
+	}
+	return b[:l], nil
+}
+
+func getRange(ctx context.Context, logger log.Logger, bkt objstore.BucketReader, name string, off, length int64) ([]byte, error) {
+	r, err := bkt.GetRange(ctx, name, off, length)
+	if err != nil {
+		return nil, errors.Wrap(err, "get range reader")
+	}
+	defer runutil.CloseWithLogOnErr(logger, r, "index-header close range reader")
+
+	b, err := ioutil.ReadAll(io.LimitReader(r, length))
+	if err != nil {
+		return nil, errors.Wrap(err, "read range")
+	}
+	if int64(len(b)) != length {
+		return nil, errors.Errorf("unexpected range length %d, expected %d", len(b), length)
+	}
+	return b, nil
+}
+
+// ReadBinary reads the given index-header file. It returns the same information as block.ReadIndexCache.
+func ReadBinary(logger log.Logger, fn string) (
+	version int,
+	symbols map[uint32]string,
+	lvals map[string][]string,
+	postings map[labels.Label]index.Range,
+	err error,
+) {
+	f, err := fileutil.OpenMmapFile(fn)
+	if err != nil {
+		return 0, nil, nil, nil, errors.Wrapf(err, "open mmap index-header file %s", fn)
+	}
+	// Everything we keep after return is copied out of the mmapped file by the decoders.
+	defer runutil.CloseWithLogOnErr(logger, f, "close index-header mmap file %s", fn)
+
+	b := realByteSlice(f.Bytes())
+	if b.Len() < headerLen {
+		return 0, nil, nil, nil, errors.Wrap(encoding.ErrInvalidSize, "index-header preamble")
+	}
+	if m := binary.BigEndian.Uint32(b[0:4]); m != MagicIndexHeader {
+		return 0, nil, nil, nil, errors.Errorf("invalid magic number %x", m)
+	}
+	if v := b[4]; v != BinaryFormatV1 {
+		return 0, nil, nil, nil, errors.Errorf("unknown index-header version %d", v)
+	}
+	version = int(b[5])
+	indexSymbolsOff := binary.BigEndian.Uint64(b[6:14])
+	indexPostingsEnd := binary.BigEndian.Uint64(b[14:22])
+
+	symbolsV2, symbolsV1, err := index.ReadSymbols(b, version, headerLen)
+	if err != nil {
+		return 0, nil, nil, nil, errors.Wrap(err, "read symbols")
+	}
+	symbols = make(map[uint32]string, len(symbolsV1)+len(symbolsV2))
+	// Symbol references of V1 indexes are offsets in the index file, so shift them from the positions in our file.
+	for o, s := range symbolsV1 {
+		symbols[o-uint32(headerLen)+uint32(indexSymbolsOff)] = s
+	}
+	for o, s := range symbolsV2 {
+		symbols[uint32(o)] = s
+	}
+
+	symbolsLen := 4 + int(binary.BigEndian.Uint32(b[headerLen:headerLen+4])) + crc32.Size
+
+	// Most strings we encounter are duplicates of symbols. Dedup string objects that we keep
+	// around after the function returns to reduce total memory usage.
+	strs := make(map[string]string, len(symbols))
+	for _, s := range symbols {
+		strs[s] = s
+	}
+	getStr := func(s string) string {
+		if cs, ok := strs[s]; ok {
+			return cs
+		}
+		strs[s] = s
+		return s
+	}
+
+	type postingOffset struct {
+		l   labels.Label
+		off uint64
+	}
+	var offsets []postingOffset
+	if err := index.ReadOffsetTable(b, uint64(headerLen+symbolsLen), func(key []string, off uint64) error {
+		if len(key) != 2 {
+			return errors.Errorf("unexpected key length for posting table %d", len(key))
+		}
+		offsets = append(offsets, postingOffset{l: labels.Label{Name: getStr(key[0]), Value: getStr(key[1])}, off: off})
+		return nil
+	}); err != nil {
+		return 0, nil, nil, nil, errors.Wrap(err, "read postings offset table")
+	}
+
+	// Postings lists are 4 byte aligned, length prefixed and CRC32 suffixed, and written one after another
+	// until the end of the postings section. This lets us know the end of each without touching the postings.
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i].off < offsets[j].off })
+
+	postings = make(map[labels.Label]index.Range, len(offsets))
+	lvals = map[string][]string{}
+	for i, o := range offsets {
+		end := indexPostingsEnd
+		if i+1 < len(offsets) {
+			end = offsets[i+1].off
+		}
+		postings[o.l] = index.Range{Start: int64(o.off) + 4, End: int64(end) - crc32.Size}
+
+		if o.l.Name == "" {
+			// All postings key.
+			continue
+		}
+		lvals[o.l.Name] = append(lvals[o.l.Name], o.l.Value)
+	}
+	for _, vals := range lvals {
+		sort.Strings(vals)
+	}
+	return version, symbols, lvals, postings, nil
+}
@@ -0,0 +1,73 @@
+package indexheader
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/block"
+	"github.com/improbable-eng/thanos/pkg/objstore/inmem"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/tsdb/index"
+	"github.com/prometheus/tsdb/labels"
+)
+
+func TestWriteReadBinary(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir("", "test-indexheader")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(tmpDir)) }()
+
+	var series []labels.Labels
+	for i := 0; i < 100; i++ {
+		series = append(series, labels.Labels{
+			{Name: "a", Value: string('a' + rune(i%26))},
+			{Name: "i", Value: string('0' + rune(i%10))},
+			{Name: "j", Value: string('0' + rune(i))},
+		})
+	}
+	id, err := testutil.CreateBlock(ctx, tmpDir, series, 100, 0, 1000, labels.Labels{{Name: "ext1", Value: "1"}}, 124)
+	testutil.Ok(t, err)
+
+	bkt := inmem.NewBucket()
+	testutil.Ok(t, block.Upload(ctx, logger, bkt, filepath.Join(tmpDir, id.String())))
+
+	fn := filepath.Join(tmpDir, "header", BinaryFilename)
+	testutil.Ok(t, WriteBinary(ctx, logger, bkt, id, fn))
+
+	version, symbols, lvals, postings, err := ReadBinary(logger, fn)
+	testutil.Ok(t, err)
+
+	// Index-header has to carry the same information as the JSON index cache.
+	cacheFn := filepath.Join(tmpDir, block.IndexCacheFilename)
+	testutil.Ok(t, block.WriteIndexCache(logger, filepath.Join(tmpDir, id.String(), block.IndexFilename), cacheFn))
+	expVersion, expSymbols, expLvals, expPostings, err := block.ReadIndexCache(logger, cacheFn)
+	testutil.Ok(t, err)
+
+	testutil.Equals(t, expVersion, version)
+	testutil.Equals(t, expSymbols, symbols)
+	testutil.Equals(t, expLvals, lvals)
+	testutil.Equals(t, expPostings, postings)
+
+	// Postings ranges have to match the ones computed by the TSDB index reader.
+	indexr, err := index.NewFileReader(filepath.Join(tmpDir, id.String(), block.IndexFilename))
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, indexr.Close()) }()
+
+	ranges, err := indexr.PostingsRanges()
+	testutil.Ok(t, err)
+	testutil.Equals(t, ranges, postings)
+
+	// Corrupted files are detected.
+	b, err := ioutil.ReadFile(fn)
+	testutil.Ok(t, err)
+	b[len(b)-1]++
+	testutil.Ok(t, ioutil.WriteFile(fn, b, os.ModePerm))
+	_, _, _, _, err = ReadBinary(logger, fn)
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,28 @@
+package metadata
+
+import (
+	"github.com/oklog/ulid"
+)
+
+const (
+	// DeletionMarkFilename is the known JSON filename for optional file storing details about when block is marked for deletion.
+	// If such file is present in block dir, it means the block is meant to be deleted after certain delay.
+	DeletionMarkFilename = "deletion-mark.json"
+)
+
+const (
+	// DeletionMarkVersion1 is a enumeration of deletion-mark versions supported by Thanos.
+	DeletionMarkVersion1 = iota + 1
+)
+
+// DeletionMark stores block id and when block was marked for deletion.
+type DeletionMark struct {
+	// ID of the tsdb block.
+	ID ulid.ULID `json:"id"`
+
+	// DeletionTime is a unix timestamp of when the block was marked to be deleted.
+	DeletionTime int64 `json:"deletion_time"`
+
+	// Version of the file.
+	Version int `json:"version"`
+}
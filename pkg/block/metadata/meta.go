@@ -28,6 +28,8 @@ const (
 	CompactorRepairSource SourceType = "compactor.repair"
 	RulerSource           SourceType = "ruler"
 	BucketRepairSource    SourceType = "bucket.repair"
+	BucketRewriteSource   SourceType = "bucket.rewrite"
+	BucketBackfillSource  SourceType = "bucket.backfill"
 	TestSource            SourceType = "test"
 )
 
@@ -58,6 +60,10 @@ type Thanos struct {
 
 	// Source is a real upload source of the block.
 	Source SourceType `json:"source"`
+
+	// ReplicaLabels are the names of labels which only identify the replica of a HA pair that produced the block,
+	// e.g. of a ruler. Blocks with the same labels apart from those are expected to hold the same data.
+	ReplicaLabels []string `json:"replica_labels,omitempty"`
 }
 
 type ThanosDownsample struct {
@@ -0,0 +1,38 @@
+// Package cache implements generic key-value caches shared by Thanos components, backed by memory, memcached or
+// Redis.
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Cache is a generic interface for key-value caches.
+type Cache interface {
+	// Store data into the cache. Stores are best-effort: implementations may store entries asynchronously and
+	// drop them on errors.
+	Store(ctx context.Context, data map[string][]byte, ttl time.Duration)
+
+	// Fetch multiple keys from the cache. Returns a map of the found keys and their values, missing keys are not
+	// present in the map.
+	Fetch(ctx context.Context, keys []string) map[string][]byte
+}
+
+func newCacheMetrics(name string, reg prometheus.Registerer) (requests, hits prometheus.Counter) {
+	requests = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "thanos_cache_requests_total",
+		Help:        "Total number of keys requested from the cache.",
+		ConstLabels: prometheus.Labels{"name": name},
+	})
+	hits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "thanos_cache_hits_total",
+		Help:        "Total number of keys requested from the cache that were found.",
+		ConstLabels: prometheus.Labels{"name": name},
+	})
+	if reg != nil {
+		reg.MustRegister(requests, hits)
+	}
+	return requests, hits
+}
@@ -0,0 +1,324 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	lru "github.com/hashicorp/golang-lru/simplelru"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// diskEntryHeaderLen is the length of the header of a cache file, which holds the expiry time in Unix
+	// nanoseconds or 0 if the entry does not expire.
+	diskEntryHeaderLen = 8
+	diskTmpSuffix      = ".tmp"
+)
+
+// DiskCacheConfig is the config of the disk cache.
+type DiskCacheConfig struct {
+	// Dir is the directory the cache files are stored in. It must not be used by anything else.
+	Dir string `yaml:"dir"`
+	// MaxSizeBytes represents overall maximum number of bytes cache can contain.
+	MaxSizeBytes uint64 `yaml:"max_size_bytes"`
+	// MaxItemSizeBytes represents maximum size of single item.
+	MaxItemSizeBytes uint64 `yaml:"max_item_size_bytes"`
+}
+
+// DiskCache is an LRU cache bounded by the total size of its values, persisted as files in a local directory. The
+// cached entries survive restarts of the process: the LRU order is recovered from the modification times of the files.
+type DiskCache struct {
+	mtx sync.Mutex
+
+	logger           log.Logger
+	dir              string
+	lru              *lru.LRU
+	maxSizeBytes     uint64
+	maxItemSizeBytes uint64
+	curSize          uint64
+
+	requests prometheus.Counter
+	hits     prometheus.Counter
+	evicted  prometheus.Counter
+}
+
+// NewDiskCache makes a new DiskCache from the given YAML configuration.
+func NewDiskCache(name string, logger log.Logger, reg prometheus.Registerer, conf []byte) (*DiskCache, error) {
+	config := DiskCacheConfig{
+		MaxSizeBytes:     10 * 1024 * 1024 * 1024,
+		MaxItemSizeBytes: 125 * 1024 * 1024,
+	}
+	if err := yaml.UnmarshalStrict(conf, &config); err != nil {
+		return nil, errors.Wrap(err, "parse disk cache config")
+	}
+	return NewDiskCacheWithConfig(name, logger, reg, config)
+}
+
+// NewDiskCacheWithConfig makes a new DiskCache. Entries already present in the directory are loaded into the cache.
+func NewDiskCacheWithConfig(name string, logger log.Logger, reg prometheus.Registerer, config DiskCacheConfig) (*DiskCache, error) {
+	if config.Dir == "" {
+		return nil, errors.New("no disk cache directory provided")
+	}
+	if config.MaxItemSizeBytes > config.MaxSizeBytes {
+		return nil, errors.Errorf("max item size (%v) cannot be bigger than overall cache size (%v)", config.MaxItemSizeBytes, config.MaxSizeBytes)
+	}
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	c := &DiskCache{
+		logger:           logger,
+		dir:              config.Dir,
+		maxSizeBytes:     config.MaxSizeBytes,
+		maxItemSizeBytes: config.MaxItemSizeBytes,
+	}
+	c.requests, c.hits = newCacheMetrics(name, reg)
+
+	c.evicted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "thanos_cache_disk_items_evicted_total",
+		Help:        "Total number of items that were evicted from the disk cache.",
+		ConstLabels: prometheus.Labels{"name": name},
+	})
+	if reg != nil {
+		reg.MustRegister(c.evicted)
+	}
+
+	// The LRU is keyed by file path and holds the size of the entry. Evictions are managed by the stored size.
+	l, err := lru.NewLRU(math.MaxInt64, c.onEvict)
+	if err != nil {
+		return nil, err
+	}
+	c.lru = l
+
+	if err := os.MkdirAll(c.dir, 0777); err != nil {
+		return nil, errors.Wrap(err, "create disk cache dir")
+	}
+	if err := c.loadEntries(); err != nil {
+		return nil, errors.Wrap(err, "load disk cache entries")
+	}
+
+	level.Info(logger).Log("msg", "created disk cache", "name", name, "dir", c.dir, "entries", c.lru.Len(),
+		"sizeBytes", c.curSize, "maxItemSizeBytes", c.maxItemSizeBytes, "maxSizeBytes", c.maxSizeBytes)
+	return c, nil
+}
+
+// loadEntries adds all files left by a previous run to the LRU, least recently used first.
+func (c *DiskCache) loadEntries() error {
+	type entry struct {
+		path    string
+		size    uint64
+		modTime time.Time
+	}
+	var entries []entry
+
+	err := filepath.Walk(c.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		// Remove files of interrupted writes.
+		if strings.HasSuffix(path, diskTmpSuffix) {
+			return os.Remove(path)
+		}
+		entries = append(entries, entry{path: path, size: uint64(info.Size()), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for _, e := range entries {
+		c.lru.Add(e.path, e.size)
+		c.curSize += e.size
+	}
+	// The size limit may have been lowered since the previous run.
+	c.ensureSpace(0)
+	return nil
+}
+
+func (c *DiskCache) onEvict(key, val interface{}) {
+	c.curSize -= val.(uint64)
+	if err := os.Remove(key.(string)); err != nil && !os.IsNotExist(err) {
+		level.Warn(c.logger).Log("msg", "failed to remove disk cache file", "path", key, "err", err)
+	}
+}
+
+// ensureSpace evicts the least recently used entries until an entry of the given size fits. It must be called
+// with the mutex held.
+func (c *DiskCache) ensureSpace(size uint64) {
+	for c.curSize+size > c.maxSizeBytes {
+		if _, _, ok := c.lru.RemoveOldest(); !ok {
+			return
+		}
+		c.evicted.Inc()
+	}
+}
+
+// path returns the path of the file of the given key. Keys are hashed, as they can contain any characters. The
+// files are spread over subdirectories to keep the directories small.
+func (c *DiskCache) path(key string) string {
+	h := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(h[:])
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+func (c *DiskCache) Store(_ context.Context, data map[string][]byte, ttl time.Duration) {
+	for key, val := range data {
+		size := uint64(len(val) + diskEntryHeaderLen)
+		if size > c.maxItemSizeBytes {
+			continue
+		}
+
+		var expires int64
+		if ttl > 0 {
+			expires = time.Now().Add(ttl).UnixNano()
+		}
+
+		p := c.path(key)
+		tmp, err := writeDiskEntry(p, expires, val)
+		if err != nil {
+			level.Warn(c.logger).Log("msg", "failed to write disk cache file", "path", p, "err", err)
+			continue
+		}
+		if err := c.add(p, tmp, size); err != nil {
+			level.Warn(c.logger).Log("msg", "failed to add disk cache file", "path", p, "err", err)
+			if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+				level.Warn(c.logger).Log("msg", "failed to remove temporary disk cache file", "path", tmp, "err", err)
+			}
+		}
+	}
+}
+
+// add moves the written temporary file in place of the entry and accounts for it in the LRU. Both happen under the
+// mutex, so that evictions never remove a file that is not accounted for.
+func (c *DiskCache) add(p, tmp string, size uint64) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if v, ok := c.lru.Peek(p); ok {
+		// Forget the size of the replaced entry. It is kept in the LRU with no size to not remove the file,
+		// but may still be evicted to make space below.
+		c.curSize -= v.(uint64)
+		c.lru.Add(p, uint64(0))
+	}
+	c.ensureSpace(size)
+
+	if err := os.Rename(tmp, p); err != nil {
+		c.lru.Remove(p)
+		return errors.Wrap(err, "rename file")
+	}
+	c.lru.Add(p, size)
+	c.curSize += size
+	return nil
+}
+
+func (c *DiskCache) Fetch(_ context.Context, keys []string) map[string][]byte {
+	c.requests.Add(float64(len(keys)))
+
+	results := make(map[string][]byte, len(keys))
+	now := time.Now()
+	for _, key := range keys {
+		p := c.path(key)
+
+		c.mtx.Lock()
+		_, ok := c.lru.Get(p)
+		c.mtx.Unlock()
+		if !ok {
+			continue
+		}
+
+		expires, val, err := readDiskEntry(p)
+		if err != nil {
+			// A missing file was evicted concurrently or removed by something else. In the latter case the entry
+			// has to be dropped, so that its size does not count to the cache size forever.
+			if !os.IsNotExist(errors.Cause(err)) {
+				level.Warn(c.logger).Log("msg", "failed to read disk cache file", "path", p, "err", err)
+			}
+			c.remove(p)
+			continue
+		}
+		if expires != 0 && now.UnixNano() > expires {
+			c.remove(p)
+			continue
+		}
+		// Persist the recent use for the LRU order after a restart.
+		if err := os.Chtimes(p, now, now); err != nil && !os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "failed to update modification time of disk cache file", "path", p, "err", err)
+		}
+		results[key] = val
+	}
+	c.hits.Add(float64(len(results)))
+	return results
+}
+
+func (c *DiskCache) remove(p string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.lru.Remove(p)
+}
+
+// writeDiskEntry writes the entry to a new temporary file next to the file of the entry and returns its path. The
+// temporary file is renamed to the entry afterwards, so that readers never see partial entries. Every write uses its
+// own temporary file, so concurrent writes of the same entry do not interfere.
+func writeDiskEntry(p string, expires int64, val []byte) (_ string, err error) {
+	if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+		return "", errors.Wrap(err, "create dir")
+	}
+
+	b := make([]byte, diskEntryHeaderLen+len(val))
+	binary.BigEndian.PutUint64(b, uint64(expires))
+	copy(b[diskEntryHeaderLen:], val)
+
+	f, err := ioutil.TempFile(filepath.Dir(p), filepath.Base(p)+".*"+diskTmpSuffix)
+	if err != nil {
+		return "", errors.Wrap(err, "create temporary file")
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return "", errors.Wrap(err, "write file")
+	}
+	if err := f.Close(); err != nil {
+		return "", errors.Wrap(err, "close file")
+	}
+	return f.Name(), nil
+}
+
+func readDiskEntry(p string) (expires int64, val []byte, err error) {
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(b) < diskEntryHeaderLen {
+		return 0, nil, errors.Errorf("disk cache file too short (%d bytes)", len(b))
+	}
+	return int64(binary.BigEndian.Uint64(b)), b[diskEntryHeaderLen:], nil
+}
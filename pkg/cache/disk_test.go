@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDiskCache(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "test-disk-cache")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	// Every entry takes 8 bytes of header on top of its value.
+	config := DiskCacheConfig{Dir: dir, MaxSizeBytes: 30, MaxItemSizeBytes: 13}
+
+	c, err := NewDiskCacheWithConfig("test", log.NewNopLogger(), prometheus.NewRegistry(), config)
+	testutil.Ok(t, err)
+
+	c.Store(ctx, map[string][]byte{"a": []byte("aaa"), "b": []byte("bbb"), "too-big": []byte("123456")}, 0)
+	testutil.Equals(t, map[string][]byte{"a": []byte("aaa"), "b": []byte("bbb")}, c.Fetch(ctx, []string{"a", "b", "too-big"}))
+
+	// Least recently used items are evicted to make space, together with their files.
+	c.Store(ctx, map[string][]byte{"c": []byte("ccccc")}, 0)
+	testutil.Equals(t, map[string][]byte{"b": []byte("bbb"), "c": []byte("ccccc")}, c.Fetch(ctx, []string{"a", "b", "c"}))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(c.evicted))
+	testutil.Equals(t, uint64(24), c.curSize)
+	_, err = os.Stat(c.path("a"))
+	testutil.Assert(t, os.IsNotExist(err), "expected file of evicted item to be removed")
+
+	// Overwriting an item does not leak its size.
+	c.Store(ctx, map[string][]byte{"c": []byte("c")}, 0)
+	testutil.Equals(t, uint64(20), c.curSize)
+
+	// Expired items are not returned.
+	c.Store(ctx, map[string][]byte{"d": []byte("d")}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	testutil.Equals(t, map[string][]byte{}, c.Fetch(ctx, []string{"d"}))
+	testutil.Equals(t, uint64(20), c.curSize)
+
+	testutil.Equals(t, 7.0, promtest.ToFloat64(c.requests))
+	testutil.Equals(t, 4.0, promtest.ToFloat64(c.hits))
+
+	_, err = NewDiskCacheWithConfig("invalid", log.NewNopLogger(), nil, DiskCacheConfig{Dir: dir, MaxSizeBytes: 10, MaxItemSizeBytes: 20})
+	testutil.NotOk(t, err)
+	_, err = NewDiskCache("invalid", log.NewNopLogger(), nil, []byte(`max_size_bytes: 10`))
+	testutil.NotOk(t, err)
+}
+
+func TestDiskCache_Persistence(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "test-disk-cache")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	c, err := NewDiskCache("test", log.NewNopLogger(), nil, []byte(`
+dir: `+dir+`
+max_size_bytes: 30
+max_item_size_bytes: 13
+`))
+	testutil.Ok(t, err)
+
+	c.Store(ctx, map[string][]byte{"a": []byte("aaa")}, 0)
+	// Make sure the modification times differ.
+	time.Sleep(10 * time.Millisecond)
+	c.Store(ctx, map[string][]byte{"b": []byte("bbb")}, 0)
+	time.Sleep(10 * time.Millisecond)
+	// Fetching "a" makes it the most recently used item.
+	testutil.Equals(t, map[string][]byte{"a": []byte("aaa")}, c.Fetch(ctx, []string{"a"}))
+
+	// Leftovers of an interrupted write are removed.
+	tmp := filepath.Join(dir, "ab", "abc"+diskTmpSuffix)
+	testutil.Ok(t, os.MkdirAll(filepath.Dir(tmp), 0777))
+	testutil.Ok(t, ioutil.WriteFile(tmp, []byte("partial"), 0666))
+
+	c, err = NewDiskCacheWithConfig("test", log.NewNopLogger(), nil, DiskCacheConfig{Dir: dir, MaxSizeBytes: 30, MaxItemSizeBytes: 13})
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(22), c.curSize)
+	_, err = os.Stat(tmp)
+	testutil.Assert(t, os.IsNotExist(err), "expected temporary file to be removed")
+
+	testutil.Equals(t, map[string][]byte{"b": []byte("bbb")}, c.Fetch(ctx, []string{"b"}))
+	time.Sleep(10 * time.Millisecond)
+	testutil.Equals(t, map[string][]byte{"a": []byte("aaa")}, c.Fetch(ctx, []string{"a"}))
+
+	// The LRU order is kept across restarts: "b" was used before "a" in the previous run and is evicted first.
+	c, err = NewDiskCacheWithConfig("test", log.NewNopLogger(), nil, DiskCacheConfig{Dir: dir, MaxSizeBytes: 15, MaxItemSizeBytes: 13})
+	testutil.Ok(t, err)
+	testutil.Equals(t, map[string][]byte{"a": []byte("aaa")}, c.Fetch(ctx, []string{"a", "b"}))
+}
+
+func TestDiskCache_Replace(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "test-disk-cache")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	c, err := NewDiskCacheWithConfig("test", log.NewNopLogger(), nil, DiskCacheConfig{Dir: dir, MaxSizeBytes: 22, MaxItemSizeBytes: 15})
+	testutil.Ok(t, err)
+
+	c.Store(ctx, map[string][]byte{"a": []byte("a")}, 0)
+	c.Store(ctx, map[string][]byte{"b": []byte("bbbb")}, 0)
+	testutil.Equals(t, uint64(21), c.curSize)
+
+	// Replacing an item with a bigger one makes space for it.
+	c.Store(ctx, map[string][]byte{"b": []byte("bbbbbbb")}, 0)
+	testutil.Equals(t, map[string][]byte{"b": []byte("bbbbbbb")}, c.Fetch(ctx, []string{"a", "b"}))
+	testutil.Equals(t, uint64(15), c.curSize)
+
+	// Entries whose files disappeared are dropped.
+	testutil.Ok(t, os.Remove(c.path("b")))
+	testutil.Equals(t, map[string][]byte{}, c.Fetch(ctx, []string{"b"}))
+	testutil.Equals(t, uint64(0), c.curSize)
+	testutil.Equals(t, 0, c.lru.Len())
+}
+
+func TestDiskCache_Concurrent(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "test-disk-cache")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	c, err := NewDiskCacheWithConfig("test", log.NewNopLogger(), nil, DiskCacheConfig{Dir: dir, MaxSizeBytes: 50, MaxItemSizeBytes: 20})
+	testutil.Ok(t, err)
+
+	keys := []string{"a", "b", "c", "d", "e"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := keys[(i+j)%len(keys)]
+				c.Store(ctx, map[string][]byte{key: []byte(strings.Repeat("x", (i+j)%12))}, 0)
+				c.Fetch(ctx, keys)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// The accounted size matches the files on disk and no temporary files are left behind.
+	var size uint64
+	testutil.Ok(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		testutil.Assert(t, !strings.HasSuffix(path, diskTmpSuffix), "unexpected temporary file %s", path)
+		size += uint64(info.Size())
+		return nil
+	}))
+	testutil.Equals(t, size, c.curSize)
+	testutil.Assert(t, c.curSize <= 50, "cache size %d exceeds the limit", c.curSize)
+}
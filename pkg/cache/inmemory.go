@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	lru "github.com/hashicorp/golang-lru/simplelru"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// InMemoryCacheConfig is the config of the in-memory cache.
+type InMemoryCacheConfig struct {
+	// MaxSizeBytes represents overall maximum number of bytes cache can contain.
+	MaxSizeBytes uint64 `yaml:"max_size_bytes"`
+	// MaxItemSizeBytes represents maximum size of single item.
+	MaxItemSizeBytes uint64 `yaml:"max_item_size_bytes"`
+}
+
+type inMemoryEntry struct {
+	val     []byte
+	expires time.Time
+}
+
+// InMemoryCache is an LRU cache bounded by the total size of its values, local to the process.
+type InMemoryCache struct {
+	mtx sync.Mutex
+
+	logger           log.Logger
+	lru              *lru.LRU
+	maxSizeBytes     uint64
+	maxItemSizeBytes uint64
+	curSize          uint64
+
+	requests prometheus.Counter
+	hits     prometheus.Counter
+	evicted  prometheus.Counter
+}
+
+// NewInMemoryCache makes a new InMemoryCache from the given YAML configuration.
+func NewInMemoryCache(name string, logger log.Logger, reg prometheus.Registerer, conf []byte) (*InMemoryCache, error) {
+	config := InMemoryCacheConfig{
+		MaxSizeBytes:     250 * 1024 * 1024,
+		MaxItemSizeBytes: 125 * 1024 * 1024,
+	}
+	if err := yaml.UnmarshalStrict(conf, &config); err != nil {
+		return nil, errors.Wrap(err, "parse in-memory cache config")
+	}
+	return NewInMemoryCacheWithConfig(name, logger, reg, config)
+}
+
+// NewInMemoryCacheWithConfig makes a new InMemoryCache.
+func NewInMemoryCacheWithConfig(name string, logger log.Logger, reg prometheus.Registerer, config InMemoryCacheConfig) (*InMemoryCache, error) {
+	if config.MaxItemSizeBytes > config.MaxSizeBytes {
+		return nil, errors.Errorf("max item size (%v) cannot be bigger than overall cache size (%v)", config.MaxItemSizeBytes, config.MaxSizeBytes)
+	}
+
+	c := &InMemoryCache{
+		logger:           logger,
+		maxSizeBytes:     config.MaxSizeBytes,
+		maxItemSizeBytes: config.MaxItemSizeBytes,
+	}
+	c.requests, c.hits = newCacheMetrics(name, reg)
+
+	c.evicted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "thanos_cache_inmemory_items_evicted_total",
+		Help:        "Total number of items that were evicted from the in-memory cache.",
+		ConstLabels: prometheus.Labels{"name": name},
+	})
+	if reg != nil {
+		reg.MustRegister(c.evicted)
+	}
+
+	// Initialize LRU cache with a high size limit since we will manage evictions ourselves
+	// based on stored size using `RemoveOldest` method.
+	l, err := lru.NewLRU(math.MaxInt64, c.onEvict)
+	if err != nil {
+		return nil, err
+	}
+	c.lru = l
+
+	level.Info(logger).Log("msg", "created in-memory cache", "name", name,
+		"maxItemSizeBytes", c.maxItemSizeBytes, "maxSizeBytes", c.maxSizeBytes)
+	return c, nil
+}
+
+func (c *InMemoryCache) onEvict(_, val interface{}) {
+	c.curSize -= uint64(len(val.(inMemoryEntry).val))
+}
+
+func (c *InMemoryCache) Store(_ context.Context, data map[string][]byte, ttl time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for key, val := range data {
+		size := uint64(len(val))
+		if size > c.maxItemSizeBytes {
+			continue
+		}
+		if _, ok := c.lru.Peek(key); ok {
+			c.lru.Remove(key)
+		}
+		for c.curSize+size > c.maxSizeBytes {
+			if _, _, ok := c.lru.RemoveOldest(); !ok {
+				break
+			}
+			c.evicted.Inc()
+		}
+
+		// The caller may be passing in a sub-slice of a huge array. Copy the data
+		// to ensure we don't waste huge amounts of space for something small.
+		v := make([]byte, len(val))
+		copy(v, val)
+
+		e := inMemoryEntry{val: v}
+		if ttl > 0 {
+			e.expires = time.Now().Add(ttl)
+		}
+		c.lru.Add(key, e)
+		c.curSize += size
+	}
+}
+
+func (c *InMemoryCache) Fetch(_ context.Context, keys []string) map[string][]byte {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.requests.Add(float64(len(keys)))
+
+	results := make(map[string][]byte, len(keys))
+	now := time.Now()
+	for _, key := range keys {
+		v, ok := c.lru.Get(key)
+		if !ok {
+			continue
+		}
+		e := v.(inMemoryEntry)
+		if !e.expires.IsZero() && now.After(e.expires) {
+			c.lru.Remove(key)
+			continue
+		}
+		results[key] = e.val
+	}
+	c.hits.Add(float64(len(results)))
+	return results
+}
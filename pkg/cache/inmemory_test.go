@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInMemoryCache(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewInMemoryCache("test", log.NewNopLogger(), prometheus.NewRegistry(), []byte(`
+max_size_bytes: 10
+max_item_size_bytes: 5
+`))
+	testutil.Ok(t, err)
+
+	c.Store(ctx, map[string][]byte{"a": []byte("aaa"), "b": []byte("bbb"), "too-big": []byte("123456")}, 0)
+	testutil.Equals(t, map[string][]byte{"a": []byte("aaa"), "b": []byte("bbb")}, c.Fetch(ctx, []string{"a", "b", "too-big"}))
+
+	// Least recently used items are evicted to make space.
+	c.Store(ctx, map[string][]byte{"c": []byte("ccccc")}, 0)
+	testutil.Equals(t, map[string][]byte{"b": []byte("bbb"), "c": []byte("ccccc")}, c.Fetch(ctx, []string{"a", "b", "c"}))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(c.evicted))
+	testutil.Equals(t, uint64(8), c.curSize)
+
+	// Overwriting an item does not leak its size.
+	c.Store(ctx, map[string][]byte{"c": []byte("c")}, 0)
+	testutil.Equals(t, uint64(4), c.curSize)
+
+	// Expired items are not returned.
+	c.Store(ctx, map[string][]byte{"d": []byte("d")}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	testutil.Equals(t, map[string][]byte{}, c.Fetch(ctx, []string{"d"}))
+
+	testutil.Equals(t, 7.0, promtest.ToFloat64(c.requests))
+	testutil.Equals(t, 4.0, promtest.ToFloat64(c.hits))
+
+	_, err = NewInMemoryCache("invalid", log.NewNopLogger(), nil, []byte(`
+max_size_bytes: 10
+max_item_size_bytes: 20
+`))
+	testutil.NotOk(t, err)
+}
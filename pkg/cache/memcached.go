@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/cacheutil"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MemcachedCache is a memcached-based cache.
+type MemcachedCache struct {
+	logger    log.Logger
+	memcached cacheutil.MemcachedClient
+
+	requests prometheus.Counter
+	hits     prometheus.Counter
+}
+
+// NewMemcachedCache makes a new MemcachedCache.
+func NewMemcachedCache(name string, logger log.Logger, memcached cacheutil.MemcachedClient, reg prometheus.Registerer) *MemcachedCache {
+	c := &MemcachedCache{
+		logger:    logger,
+		memcached: memcached,
+	}
+	c.requests, c.hits = newCacheMetrics(name, reg)
+	return c
+}
+
+func (c *MemcachedCache) Store(_ context.Context, data map[string][]byte, ttl time.Duration) {
+	for key, val := range data {
+		if err := c.memcached.SetAsync(key, val, ttl); err != nil {
+			level.Debug(c.logger).Log("msg", "failed to store item into memcached", "key", key, "err", err)
+		}
+	}
+}
+
+func (c *MemcachedCache) Fetch(ctx context.Context, keys []string) map[string][]byte {
+	c.requests.Add(float64(len(keys)))
+	results := c.memcached.GetMulti(ctx, keys)
+	c.hits.Add(float64(len(results)))
+	return results
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/cacheutil"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RedisCache is a Redis-based cache.
+type RedisCache struct {
+	logger log.Logger
+	redis  cacheutil.RedisClient
+
+	requests prometheus.Counter
+	hits     prometheus.Counter
+}
+
+// NewRedisCache makes a new RedisCache.
+func NewRedisCache(name string, logger log.Logger, redis cacheutil.RedisClient, reg prometheus.Registerer) *RedisCache {
+	c := &RedisCache{
+		logger: logger,
+		redis:  redis,
+	}
+	c.requests, c.hits = newCacheMetrics(name, reg)
+	return c
+}
+
+func (c *RedisCache) Store(_ context.Context, data map[string][]byte, ttl time.Duration) {
+	for key, val := range data {
+		if err := c.redis.SetAsync(key, val, ttl); err != nil {
+			level.Debug(c.logger).Log("msg", "failed to store item into redis", "key", key, "err", err)
+		}
+	}
+}
+
+func (c *RedisCache) Fetch(ctx context.Context, keys []string) map[string][]byte {
+	c.requests.Add(float64(len(keys)))
+	results := c.redis.GetMulti(ctx, keys)
+	c.hits.Add(float64(len(results)))
+	return results
+}
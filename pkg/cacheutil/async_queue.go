@@ -0,0 +1,55 @@
+package cacheutil
+
+import (
+	"sync"
+)
+
+// asyncQueue runs enqueued operations on a fixed number of workers. Operations are dropped instead of blocking the
+// caller when the queue is full, so a slow or unavailable cache never slows down the caller.
+type asyncQueue struct {
+	queue   chan func()
+	stop    chan struct{}
+	workers sync.WaitGroup
+}
+
+func newAsyncQueue(bufferSize, concurrency int) *asyncQueue {
+	q := &asyncQueue{
+		queue: make(chan func(), bufferSize),
+		stop:  make(chan struct{}),
+	}
+
+	q.workers.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go q.processLoop()
+	}
+	return q
+}
+
+// enqueue adds the operation to the queue. It returns errAsyncBufferFull if the queue is full.
+func (q *asyncQueue) enqueue(op func()) error {
+	select {
+	case q.queue <- op:
+		return nil
+	default:
+		return errAsyncBufferFull
+	}
+}
+
+func (q *asyncQueue) processLoop() {
+	defer q.workers.Done()
+
+	for {
+		select {
+		case op := <-q.queue:
+			op()
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+// close stops all workers and waits until they have terminated. Pending operations are dropped.
+func (q *asyncQueue) close() {
+	close(q.stop)
+	q.workers.Wait()
+}
@@ -0,0 +1,332 @@
+package cacheutil
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/discovery/dns"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	opSet      = "set"
+	opGetMulti = "getmulti"
+)
+
+var (
+	errAsyncBufferFull       = errors.New("the async buffer is full")
+	errMemcachedItemTooLarge = errors.New("the item is larger than the max item size")
+
+	defaultMemcachedClientConfig = MemcachedClientConfig{
+		Timeout:                   500 * time.Millisecond,
+		MaxIdleConnections:        100,
+		MaxAsyncConcurrency:       20,
+		MaxAsyncBufferSize:        10000,
+		MaxItemSize:               1024 * 1024,
+		DNSProviderUpdateInterval: 10 * time.Second,
+	}
+)
+
+// MemcachedClient is a high level client to interact with memcached.
+type MemcachedClient interface {
+	// GetMulti fetches multiple keys at once from memcached. In case of error, an empty map is returned and the
+	// error tracked and logged.
+	GetMulti(ctx context.Context, keys []string) map[string][]byte
+
+	// SetAsync enqueues an asynchronous operation to store a key into memcached. Returns an error in case it fails
+	// to enqueue the operation. If the underlying async operation fails, the error is tracked and logged.
+	SetAsync(key string, value []byte, ttl time.Duration) error
+
+	// Stop client and release underlying resources.
+	Stop()
+}
+
+// memcachedClientBackend is the subset of the gomemcache client used by memcachedClient.
+type memcachedClientBackend interface {
+	GetMulti(keys []string) (map[string]*memcache.Item, error)
+	Set(item *memcache.Item) error
+}
+
+// MemcachedClientConfig is the config accepted by MemcachedClient.
+type MemcachedClientConfig struct {
+	// Addresses specifies the list of memcached addresses. The addresses get resolved with the DNS provider, so
+	// dns+ and dnssrv+ prefixes are supported to discover memcached servers.
+	Addresses []string `yaml:"addresses"`
+
+	// Timeout specifies the socket read/write timeout.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// MaxIdleConnections specifies the maximum number of idle connections that will be kept open per server.
+	MaxIdleConnections int `yaml:"max_idle_connections"`
+
+	// MaxAsyncConcurrency specifies the maximum number of concurrent asynchronous operations.
+	MaxAsyncConcurrency int `yaml:"max_async_concurrency"`
+
+	// MaxAsyncBufferSize specifies the maximum number of enqueued asynchronous operations allowed.
+	MaxAsyncBufferSize int `yaml:"max_async_buffer_size"`
+
+	// MaxItemSize specifies the maximum size of an item stored in memcached, in bytes. Bigger items are skipped.
+	// It should match the -I flag of the memcached servers.
+	MaxItemSize int `yaml:"max_item_size"`
+
+	// DNSProviderUpdateInterval specifies the DNS discovery update interval.
+	DNSProviderUpdateInterval time.Duration `yaml:"dns_provider_update_interval"`
+}
+
+func (c *MemcachedClientConfig) validate() error {
+	if len(c.Addresses) == 0 {
+		return errors.New("no memcached addresses provided")
+	}
+	if c.MaxAsyncConcurrency <= 0 {
+		return errors.New("max async concurrency must be positive")
+	}
+	if c.MaxAsyncBufferSize < 0 {
+		return errors.New("max async buffer size must not be negative")
+	}
+	if c.DNSProviderUpdateInterval <= 0 {
+		return errors.New("DNS provider update interval must be positive")
+	}
+	return nil
+}
+
+// parseMemcachedClientConfig unmarshals a buffer into a MemcachedClientConfig with default values.
+func parseMemcachedClientConfig(conf []byte) (MemcachedClientConfig, error) {
+	config := defaultMemcachedClientConfig
+	if err := yaml.UnmarshalStrict(conf, &config); err != nil {
+		return MemcachedClientConfig{}, err
+	}
+	return config, nil
+}
+
+type memcachedClient struct {
+	logger   log.Logger
+	config   MemcachedClientConfig
+	client   memcachedClientBackend
+	selector *MemcachedJumpHashSelector
+
+	// DNS provider used to keep the memcached servers list updated.
+	dnsProvider *dns.Provider
+
+	// Channel used to notify the DNS resolution goroutine when it should quit.
+	stop chan struct{}
+
+	// Wait group used to wait for the DNS resolution goroutine on stopping.
+	workers sync.WaitGroup
+
+	// Queue of async operations.
+	asyncQueue *asyncQueue
+
+	operations *prometheus.CounterVec
+	failures   *prometheus.CounterVec
+	skipped    *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+}
+
+// NewMemcachedClient makes a new MemcachedClient from the given YAML configuration.
+func NewMemcachedClient(logger log.Logger, name string, conf []byte, reg prometheus.Registerer) (MemcachedClient, error) {
+	config, err := parseMemcachedClientConfig(conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse memcached client config")
+	}
+	return NewMemcachedClientWithConfig(logger, name, config, reg)
+}
+
+// NewMemcachedClientWithConfig makes a new MemcachedClient.
+func NewMemcachedClientWithConfig(logger log.Logger, name string, config MemcachedClientConfig, reg prometheus.Registerer) (MemcachedClient, error) {
+	if err := config.validate(); err != nil {
+		return nil, errors.Wrap(err, "validate memcached client config")
+	}
+
+	// We use a custom servers selector in order to use a jump hash for servers selection.
+	selector := &MemcachedJumpHashSelector{}
+
+	client := memcache.NewFromSelector(selector)
+	client.Timeout = config.Timeout
+	client.MaxIdleConns = config.MaxIdleConnections
+
+	if reg != nil {
+		reg = prometheus.WrapRegistererWith(prometheus.Labels{"name": name}, reg)
+	}
+	return newMemcachedClient(logger, client, selector, config, reg)
+}
+
+func newMemcachedClient(
+	logger log.Logger,
+	client memcachedClientBackend,
+	selector *MemcachedJumpHashSelector,
+	config MemcachedClientConfig,
+	reg prometheus.Registerer,
+) (*memcachedClient, error) {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	var dnsReg prometheus.Registerer
+	if reg != nil {
+		dnsReg = prometheus.WrapRegistererWithPrefix("thanos_memcached_", reg)
+	}
+
+	c := &memcachedClient{
+		logger:      logger,
+		config:      config,
+		client:      client,
+		selector:    selector,
+		dnsProvider: dns.NewProvider(logger, dnsReg, dns.GolangResolverType),
+		stop:        make(chan struct{}),
+	}
+
+	c.operations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_memcached_operations_total",
+		Help: "Total number of operations against memcached.",
+	}, []string{"operation"})
+	c.operations.WithLabelValues(opGetMulti)
+	c.operations.WithLabelValues(opSet)
+
+	c.failures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_memcached_operation_failures_total",
+		Help: "Total number of operations against memcached that failed.",
+	}, []string{"operation"})
+	c.failures.WithLabelValues(opGetMulti)
+	c.failures.WithLabelValues(opSet)
+
+	c.skipped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_memcached_operation_skipped_total",
+		Help: "Total number of operations against memcached that have been skipped.",
+	}, []string{"operation", "reason"})
+	c.skipped.WithLabelValues(opSet, "max_item_size")
+	c.skipped.WithLabelValues(opSet, "max_async_buffer_size")
+
+	c.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "thanos_memcached_operation_duration_seconds",
+		Help:    "Duration of operations against memcached.",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.2, 0.5, 1},
+	}, []string{"operation"})
+	c.duration.WithLabelValues(opGetMulti)
+	c.duration.WithLabelValues(opSet)
+
+	if reg != nil {
+		reg.MustRegister(c.operations, c.failures, c.skipped, c.duration)
+	}
+
+	// As soon as the client is created it must ensure that memcached server addresses are resolved, so we're going
+	// to trigger an initial addresses resolution here.
+	if err := c.resolveAddrs(); err != nil {
+		return nil, err
+	}
+
+	c.workers.Add(1)
+	go c.resolveAddrsLoop()
+
+	c.asyncQueue = newAsyncQueue(c.config.MaxAsyncBufferSize, c.config.MaxAsyncConcurrency)
+
+	level.Info(logger).Log("msg", "created memcached client", "addresses", len(c.config.Addresses),
+		"maxIdleConnections", c.config.MaxIdleConnections, "maxAsyncConcurrency", c.config.MaxAsyncConcurrency)
+	return c, nil
+}
+
+func (c *memcachedClient) Stop() {
+	close(c.stop)
+	c.asyncQueue.close()
+
+	// Wait until the DNS resolution goroutine has terminated.
+	c.workers.Wait()
+}
+
+func (c *memcachedClient) SetAsync(key string, value []byte, ttl time.Duration) error {
+	// Skip hitting memcached at all if the item is bigger than the max allowed size.
+	if c.config.MaxItemSize > 0 && len(value) > c.config.MaxItemSize {
+		c.skipped.WithLabelValues(opSet, "max_item_size").Inc()
+		return errMemcachedItemTooLarge
+	}
+
+	err := c.asyncQueue.enqueue(func() {
+		start := time.Now()
+		c.operations.WithLabelValues(opSet).Inc()
+
+		item := &memcache.Item{Key: key, Value: value}
+		if ttl > 0 {
+			// Memcached treats expirations longer than 30 days as absolute unix timestamps, so always use those.
+			item.Expiration = int32(start.Add(ttl).Unix())
+		}
+		if err := c.client.Set(item); err != nil {
+			c.failures.WithLabelValues(opSet).Inc()
+			level.Debug(c.logger).Log("msg", "failed to store item to memcached", "key", key, "err", err)
+			return
+		}
+
+		c.duration.WithLabelValues(opSet).Observe(time.Since(start).Seconds())
+	})
+	if err != nil {
+		c.skipped.WithLabelValues(opSet, "max_async_buffer_size").Inc()
+	}
+	return err
+}
+
+func (c *memcachedClient) GetMulti(ctx context.Context, keys []string) map[string][]byte {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	c.operations.WithLabelValues(opGetMulti).Inc()
+
+	// The underlying client has no context support, so we only avoid hitting memcached for requests that are
+	// already done.
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	items, err := c.client.GetMulti(keys)
+	if err != nil {
+		c.failures.WithLabelValues(opGetMulti).Inc()
+		level.Debug(c.logger).Log("msg", "failed to fetch items from memcached", "keys", len(keys), "err", err)
+		return nil
+	}
+	c.duration.WithLabelValues(opGetMulti).Observe(time.Since(start).Seconds())
+
+	hits := make(map[string][]byte, len(items))
+	for key, item := range items {
+		hits[key] = item.Value
+	}
+	return hits
+}
+
+func (c *memcachedClient) resolveAddrsLoop() {
+	defer c.workers.Done()
+
+	ticker := time.NewTicker(c.config.DNSProviderUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.resolveAddrs(); err != nil {
+				level.Warn(c.logger).Log("msg", "failed update memcached servers list", "err", err)
+			}
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *memcachedClient) resolveAddrs() error {
+	// Resolve the addresses with a timeout, to not block forever on a stuck DNS server.
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.DNSProviderUpdateInterval)
+	defer cancel()
+
+	c.dnsProvider.Resolve(ctx, c.config.Addresses)
+
+	// Fail in case no server address is resolved.
+	servers := c.dnsProvider.Addresses()
+	if len(servers) == 0 {
+		return errors.New("no server address resolved")
+	}
+
+	return c.selector.SetServers(servers...)
+}
@@ -0,0 +1,112 @@
+package cacheutil
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+)
+
+func TestMemcachedClientConfig_Parse(t *testing.T) {
+	config, err := parseMemcachedClientConfig([]byte(`
+addresses: ["127.0.0.1:11211", "dns+memcached.local:11211"]
+timeout: 1s
+max_async_concurrency: 5
+`))
+	testutil.Ok(t, err)
+	testutil.Ok(t, config.validate())
+
+	exp := defaultMemcachedClientConfig
+	exp.Addresses = []string{"127.0.0.1:11211", "dns+memcached.local:11211"}
+	exp.Timeout = time.Second
+	exp.MaxAsyncConcurrency = 5
+	testutil.Equals(t, exp, config)
+
+	// Unknown fields are rejected.
+	_, err = parseMemcachedClientConfig([]byte(`addresss: ["127.0.0.1:11211"]`))
+	testutil.NotOk(t, err)
+
+	// At least one address is required.
+	config, err = parseMemcachedClientConfig([]byte(`timeout: 1s`))
+	testutil.Ok(t, err)
+	testutil.NotOk(t, config.validate())
+}
+
+func TestMemcachedClient_SetAsyncGetMulti(t *testing.T) {
+	config := defaultMemcachedClientConfig
+	config.Addresses = []string{"127.0.0.1:11211"}
+	config.MaxItemSize = 10
+
+	backend := newMockMemcachedBackend()
+	c, err := newMemcachedClient(nil, backend, &MemcachedJumpHashSelector{}, config, nil)
+	testutil.Ok(t, err)
+	defer c.Stop()
+
+	testutil.Ok(t, c.SetAsync("key1", []byte("value1"), time.Hour))
+	testutil.Ok(t, c.SetAsync("key2", []byte("value2"), time.Hour))
+	testutil.Equals(t, errMemcachedItemTooLarge, c.SetAsync("key3", []byte("too large value"), time.Hour))
+
+	// Sets are asynchronous, so wait until they reach the backend.
+	testutil.Ok(t, backend.waitItems(2))
+
+	testutil.Equals(t, map[string][]byte{
+		"key1": []byte("value1"),
+		"key2": []byte("value2"),
+	}, c.GetMulti(context.Background(), []string{"key1", "key2", "key3"}))
+
+	// Failed requests are reported as misses.
+	backend.err = memcache.ErrServerError
+	testutil.Equals(t, 0, len(c.GetMulti(context.Background(), []string{"key1"})))
+}
+
+type mockMemcachedBackend struct {
+	mtx   sync.Mutex
+	items map[string]*memcache.Item
+	err   error
+}
+
+func newMockMemcachedBackend() *mockMemcachedBackend {
+	return &mockMemcachedBackend{items: map[string]*memcache.Item{}}
+}
+
+func (m *mockMemcachedBackend) GetMulti(keys []string) (map[string]*memcache.Item, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+	items := map[string]*memcache.Item{}
+	for _, k := range keys {
+		if item, ok := m.items[k]; ok {
+			items[k] = item
+		}
+	}
+	return items, nil
+}
+
+func (m *mockMemcachedBackend) Set(item *memcache.Item) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.items[item.Key] = item
+	return nil
+}
+
+func (m *mockMemcachedBackend) waitItems(n int) error {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		m.mtx.Lock()
+		l := len(m.items)
+		m.mtx.Unlock()
+
+		if l >= n {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return memcache.ErrCacheMiss
+}
@@ -0,0 +1,99 @@
+package cacheutil
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/cespare/xxhash"
+	"github.com/pkg/errors"
+)
+
+// MemcachedJumpHashSelector implements the memcache.ServerSelector interface. It picks a server for a key using
+// jump consistent hashing, so when a server is added or removed only the minimal share of keys moves to another
+// server. Servers are sorted on each update, so all clients configured with the same servers agree on where a key
+// is stored, regardless of the order in which addresses were resolved.
+type MemcachedJumpHashSelector struct {
+	mtx   sync.RWMutex
+	addrs []net.Addr
+}
+
+// SetServers changes the MemcachedJumpHashSelector's set of servers at runtime and is safe for concurrent use by
+// multiple goroutines.
+//
+// Each server is given equal weight. A server is given more weight if it's listed multiple times.
+//
+// SetServers returns an error if any of the server names fail to resolve. No attempt is made to connect to the
+// server. If any error occurs, no changes are made to the internal server list.
+func (s *MemcachedJumpHashSelector) SetServers(servers ...string) error {
+	sortedServers := make([]string, len(servers))
+	copy(sortedServers, servers)
+	sort.Strings(sortedServers)
+
+	naddrs := make([]net.Addr, len(sortedServers))
+	for i, server := range sortedServers {
+		if strings.Contains(server, "/") {
+			addr, err := net.ResolveUnixAddr("unix", server)
+			if err != nil {
+				return errors.Wrapf(err, "resolve unix address %s", server)
+			}
+			naddrs[i] = addr
+			continue
+		}
+
+		tcpAddr, err := net.ResolveTCPAddr("tcp", server)
+		if err != nil {
+			return errors.Wrapf(err, "resolve tcp address %s", server)
+		}
+		naddrs[i] = tcpAddr
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.addrs = naddrs
+	return nil
+}
+
+// PickServer returns the server address that a given item should be stored on.
+func (s *MemcachedJumpHashSelector) PickServer(key string) (net.Addr, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if len(s.addrs) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+	if len(s.addrs) == 1 {
+		return s.addrs[0], nil
+	}
+	return s.addrs[jumpHash(xxhash.Sum64String(key), len(s.addrs))], nil
+}
+
+// Each iterates over each server and calls the given function. If f returns a non-nil error, iteration will stop
+// and that error will be returned.
+func (s *MemcachedJumpHashSelector) Each(f func(net.Addr) error) error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	for _, addr := range s.addrs {
+		if err := f(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jumpHash consistently chooses a bucket in the range [0, numBuckets) for the given key. See
+// "A Fast, Minimal Memory, Consistent Hash Algorithm" by John Lamping and Eric Veach
+// (https://arxiv.org/abs/1406.2294).
+func jumpHash(key uint64, numBuckets int) int32 {
+	var b, j int64
+
+	for j < int64(numBuckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int32(b)
+}
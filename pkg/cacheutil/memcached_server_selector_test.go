@@ -0,0 +1,85 @@
+package cacheutil
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+)
+
+func TestMemcachedJumpHashSelector_PickServer(t *testing.T) {
+	s := &MemcachedJumpHashSelector{}
+
+	_, err := s.PickServer("key")
+	testutil.Equals(t, memcache.ErrNoServers, err)
+
+	testutil.Ok(t, s.SetServers("127.0.0.1:11211"))
+	addr, err := s.PickServer("key")
+	testutil.Ok(t, err)
+	testutil.Equals(t, "127.0.0.1:11211", addr.String())
+}
+
+func TestMemcachedJumpHashSelector_Distribution(t *testing.T) {
+	const numKeys = 10000
+
+	servers := []string{"127.0.0.1:11211", "127.0.0.2:11211", "127.0.0.3:11211"}
+
+	s := &MemcachedJumpHashSelector{}
+	testutil.Ok(t, s.SetServers(servers...))
+
+	// Keys have to be spread roughly equally across the servers.
+	picked := map[string]string{}
+	counts := map[string]int{}
+	for i := 0; i < numKeys; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		addr, err := s.PickServer(key)
+		testutil.Ok(t, err)
+		picked[key] = addr.String()
+		counts[addr.String()]++
+	}
+	testutil.Equals(t, len(servers), len(counts))
+	for addr, c := range counts {
+		testutil.Assert(t, c > numKeys/len(servers)*8/10, "server %s got only %d keys", addr, c)
+	}
+
+	// The order of the servers does not matter.
+	reordered := &MemcachedJumpHashSelector{}
+	testutil.Ok(t, reordered.SetServers(servers[2], servers[0], servers[1]))
+	for key, exp := range picked {
+		addr, err := reordered.PickServer(key)
+		testutil.Ok(t, err)
+		testutil.Equals(t, exp, addr.String())
+	}
+
+	// Adding a server moves only keys to the new server.
+	testutil.Ok(t, s.SetServers(append(servers, "127.0.0.4:11211")...))
+	moved := 0
+	for key, prev := range picked {
+		addr, err := s.PickServer(key)
+		testutil.Ok(t, err)
+		if addr.String() == prev {
+			continue
+		}
+		testutil.Equals(t, "127.0.0.4:11211", addr.String())
+		moved++
+	}
+	testutil.Assert(t, moved < numKeys/3, "too many keys moved: %d", moved)
+}
+
+func TestMemcachedJumpHashSelector_Each(t *testing.T) {
+	s := &MemcachedJumpHashSelector{}
+	testutil.Ok(t, s.SetServers("127.0.0.2:11211", "127.0.0.1:11211"))
+
+	var addrs []string
+	testutil.Ok(t, s.Each(func(addr net.Addr) error {
+		addrs = append(addrs, addr.String())
+		return nil
+	}))
+	testutil.Equals(t, []string{"127.0.0.1:11211", "127.0.0.2:11211"}, addrs)
+
+	// Unresolvable servers do not change the servers list.
+	testutil.NotOk(t, s.SetServers("127.0.0.3:11211", "127.0.0.1:invalid-port"))
+	testutil.Equals(t, 2, len(s.addrs))
+}
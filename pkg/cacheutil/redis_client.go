@@ -0,0 +1,257 @@
+package cacheutil
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-redis/redis"
+	"github.com/improbable-eng/thanos/pkg/runutil"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultRedisClientConfig is the default configuration of the Redis client.
+var DefaultRedisClientConfig = RedisClientConfig{
+	DialTimeout:         5 * time.Second,
+	ReadTimeout:         3 * time.Second,
+	WriteTimeout:        3 * time.Second,
+	PoolSize:            100,
+	MaxAsyncConcurrency: 20,
+	MaxAsyncBufferSize:  10000,
+}
+
+// RedisClient is a high level client to interact with Redis.
+type RedisClient interface {
+	// GetMulti fetches multiple keys at once from Redis. In case of error, an empty map is returned and the
+	// error tracked and logged.
+	GetMulti(ctx context.Context, keys []string) map[string][]byte
+
+	// SetAsync enqueues an asynchronous operation to store a key into Redis. Returns an error in case it fails
+	// to enqueue the operation. If the underlying async operation fails, the error is tracked and logged.
+	SetAsync(key string, value []byte, ttl time.Duration) error
+
+	// Stop client and release underlying resources.
+	Stop()
+}
+
+// RedisClientConfig is the config accepted by RedisClient.
+type RedisClientConfig struct {
+	// Addresses specifies the list of Redis addresses. A single address connects to a single Redis server, multiple
+	// addresses are seeds of a Redis Cluster, or Sentinel addresses if MasterName is set.
+	Addresses []string `yaml:"addresses"`
+
+	// MasterName is the name of the master monitored by Redis Sentinel. If set, Addresses are Sentinel addresses.
+	MasterName string `yaml:"master_name"`
+
+	// ClusterMode forces a Redis Cluster client, which is otherwise used only for more than one address.
+	ClusterMode bool `yaml:"cluster_mode"`
+
+	// Password to authenticate with.
+	Password string `yaml:"password"`
+
+	// DB is the database selected after connecting. It is not supported by Redis Cluster.
+	DB int `yaml:"db"`
+
+	// DialTimeout specifies the timeout of establishing new connections.
+	DialTimeout time.Duration `yaml:"dial_timeout"`
+
+	// ReadTimeout specifies the socket read timeout.
+	ReadTimeout time.Duration `yaml:"read_timeout"`
+
+	// WriteTimeout specifies the socket write timeout.
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+
+	// PoolSize specifies the maximum number of socket connections per server.
+	PoolSize int `yaml:"pool_size"`
+
+	// MaxAsyncConcurrency specifies the maximum number of concurrent asynchronous operations.
+	MaxAsyncConcurrency int `yaml:"max_async_concurrency"`
+
+	// MaxAsyncBufferSize specifies the maximum number of enqueued asynchronous operations allowed.
+	MaxAsyncBufferSize int `yaml:"max_async_buffer_size"`
+}
+
+func (c *RedisClientConfig) validate() error {
+	if len(c.Addresses) == 0 {
+		return errors.New("no redis addresses provided")
+	}
+	if c.MasterName != "" && c.ClusterMode {
+		return errors.New("master name of redis sentinel and cluster mode cannot be used together")
+	}
+	if c.ClusterMode && c.DB != 0 {
+		return errors.New("redis cluster supports only db 0")
+	}
+	if c.MaxAsyncConcurrency <= 0 {
+		return errors.New("max async concurrency must be positive")
+	}
+	if c.MaxAsyncBufferSize < 0 {
+		return errors.New("max async buffer size must not be negative")
+	}
+	return nil
+}
+
+type redisClient struct {
+	logger log.Logger
+	config RedisClientConfig
+	client redis.UniversalClient
+
+	// Queue of async operations.
+	asyncQueue *asyncQueue
+
+	operations *prometheus.CounterVec
+	failures   *prometheus.CounterVec
+	skipped    *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+}
+
+// NewRedisClient makes a new RedisClient from the given YAML configuration.
+func NewRedisClient(logger log.Logger, name string, conf []byte, reg prometheus.Registerer) (RedisClient, error) {
+	config := DefaultRedisClientConfig
+	if err := yaml.UnmarshalStrict(conf, &config); err != nil {
+		return nil, errors.Wrap(err, "parse redis client config")
+	}
+	return NewRedisClientWithConfig(logger, name, config, reg)
+}
+
+// NewRedisClientWithConfig makes a new RedisClient. Depending on the config it connects to a single Redis server,
+// a Redis Cluster or a master monitored by Redis Sentinel.
+func NewRedisClientWithConfig(logger log.Logger, name string, config RedisClientConfig, reg prometheus.Registerer) (RedisClient, error) {
+	if err := config.validate(); err != nil {
+		return nil, errors.Wrap(err, "validate redis client config")
+	}
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	var client redis.UniversalClient
+	if config.ClusterMode {
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        config.Addresses,
+			Password:     config.Password,
+			DialTimeout:  config.DialTimeout,
+			ReadTimeout:  config.ReadTimeout,
+			WriteTimeout: config.WriteTimeout,
+			PoolSize:     config.PoolSize,
+		})
+	} else {
+		client = redis.NewUniversalClient(&redis.UniversalOptions{
+			Addrs:        config.Addresses,
+			MasterName:   config.MasterName,
+			Password:     config.Password,
+			DB:           config.DB,
+			DialTimeout:  config.DialTimeout,
+			ReadTimeout:  config.ReadTimeout,
+			WriteTimeout: config.WriteTimeout,
+			PoolSize:     config.PoolSize,
+		})
+	}
+
+	c := &redisClient{
+		logger:     logger,
+		config:     config,
+		client:     client,
+		asyncQueue: newAsyncQueue(config.MaxAsyncBufferSize, config.MaxAsyncConcurrency),
+	}
+
+	c.operations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_redis_operations_total",
+		Help: "Total number of operations against redis.",
+	}, []string{"operation"})
+	c.operations.WithLabelValues(opGetMulti)
+	c.operations.WithLabelValues(opSet)
+
+	c.failures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_redis_operation_failures_total",
+		Help: "Total number of operations against redis that failed.",
+	}, []string{"operation"})
+	c.failures.WithLabelValues(opGetMulti)
+	c.failures.WithLabelValues(opSet)
+
+	c.skipped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_redis_operation_skipped_total",
+		Help: "Total number of operations against redis that have been skipped.",
+	}, []string{"operation", "reason"})
+	c.skipped.WithLabelValues(opSet, "max_async_buffer_size")
+
+	c.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "thanos_redis_operation_duration_seconds",
+		Help:    "Duration of operations against redis.",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.2, 0.5, 1},
+	}, []string{"operation"})
+	c.duration.WithLabelValues(opGetMulti)
+	c.duration.WithLabelValues(opSet)
+
+	if reg != nil {
+		reg = prometheus.WrapRegistererWith(prometheus.Labels{"name": name}, reg)
+		reg.MustRegister(c.operations, c.failures, c.skipped, c.duration)
+	}
+
+	level.Info(logger).Log("msg", "created redis client", "addresses", len(config.Addresses),
+		"sentinel", config.MasterName != "", "cluster", config.ClusterMode || (config.MasterName == "" && len(config.Addresses) > 1))
+	return c, nil
+}
+
+func (c *redisClient) Stop() {
+	c.asyncQueue.close()
+	runutil.CloseWithLogOnErr(c.logger, c.client, "redis client")
+}
+
+func (c *redisClient) SetAsync(key string, value []byte, ttl time.Duration) error {
+	err := c.asyncQueue.enqueue(func() {
+		start := time.Now()
+		c.operations.WithLabelValues(opSet).Inc()
+
+		if err := c.client.Set(key, value, ttl).Err(); err != nil {
+			c.failures.WithLabelValues(opSet).Inc()
+			level.Debug(c.logger).Log("msg", "failed to store item to redis", "key", key, "err", err)
+			return
+		}
+
+		c.duration.WithLabelValues(opSet).Observe(time.Since(start).Seconds())
+	})
+	if err != nil {
+		c.skipped.WithLabelValues(opSet, "max_async_buffer_size").Inc()
+	}
+	return err
+}
+
+func (c *redisClient) GetMulti(ctx context.Context, keys []string) map[string][]byte {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	c.operations.WithLabelValues(opGetMulti).Inc()
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	// MGET fails on Redis Cluster for keys in different hash slots, so pipeline single GETs instead. The cluster
+	// client splits the pipeline by node.
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.StringCmd, 0, len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, pipe.Get(key))
+	}
+	// Exec returns the first error of the commands, which is redis.Nil on any miss.
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		c.failures.WithLabelValues(opGetMulti).Inc()
+		level.Debug(c.logger).Log("msg", "failed to fetch items from redis", "keys", len(keys), "err", err)
+		return nil
+	}
+	c.duration.WithLabelValues(opGetMulti).Observe(time.Since(start).Seconds())
+
+	hits := make(map[string][]byte, len(keys))
+	for i, cmd := range cmds {
+		b, err := cmd.Bytes()
+		if err != nil {
+			continue
+		}
+		hits[keys[i]] = b
+	}
+	return hits
+}
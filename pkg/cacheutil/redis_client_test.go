@@ -0,0 +1,80 @@
+package cacheutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+)
+
+func TestRedisClientConfig_Validate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config func(*RedisClientConfig)
+		ok     bool
+	}{
+		{name: "single server", config: func(c *RedisClientConfig) { c.Addresses = []string{"127.0.0.1:6379"} }, ok: true},
+		{name: "no addresses", config: func(c *RedisClientConfig) {}},
+		{
+			name: "sentinel and cluster",
+			config: func(c *RedisClientConfig) {
+				c.Addresses = []string{"127.0.0.1:26379"}
+				c.MasterName = "master"
+				c.ClusterMode = true
+			},
+		},
+		{
+			name: "cluster with db",
+			config: func(c *RedisClientConfig) {
+				c.Addresses = []string{"127.0.0.1:6379"}
+				c.ClusterMode = true
+				c.DB = 1
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := DefaultRedisClientConfig
+			tc.config(&config)
+			if tc.ok {
+				testutil.Ok(t, config.validate())
+				return
+			}
+			testutil.NotOk(t, config.validate())
+		})
+	}
+}
+
+func TestRedisClient_SetAsyncGetMulti(t *testing.T) {
+	s, err := miniredis.Run()
+	testutil.Ok(t, err)
+	defer s.Close()
+
+	config := DefaultRedisClientConfig
+	config.Addresses = []string{s.Addr()}
+	c, err := NewRedisClientWithConfig(nil, "test", config, nil)
+	testutil.Ok(t, err)
+	defer c.Stop()
+
+	testutil.Ok(t, c.SetAsync("key1", []byte("value1"), time.Hour))
+	testutil.Ok(t, c.SetAsync("key2", []byte("value2"), 0))
+
+	// Sets are asynchronous, so wait until they reach Redis.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.Keys()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	testutil.Equals(t, []string{"key1", "key2"}, s.Keys())
+	testutil.Equals(t, time.Hour, s.TTL("key1"))
+	testutil.Equals(t, time.Duration(0), s.TTL("key2"))
+
+	testutil.Equals(t, map[string][]byte{
+		"key1": []byte("value1"),
+		"key2": []byte("value2"),
+	}, c.GetMulti(context.Background(), []string{"key1", "key2", "key3"}))
+
+	// Failed requests are reported as misses.
+	s.Close()
+	testutil.Equals(t, 0, len(c.GetMulti(context.Background(), []string{"key1"})))
+}
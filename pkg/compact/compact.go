@@ -19,10 +19,13 @@ import (
 	"github.com/improbable-eng/thanos/pkg/block"
 	"github.com/improbable-eng/thanos/pkg/compact/downsample"
 	"github.com/improbable-eng/thanos/pkg/objstore"
+	"github.com/improbable-eng/thanos/pkg/runutil"
 	"github.com/oklog/ulid"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/tsdb"
+	"github.com/prometheus/tsdb/chunks"
+	"github.com/prometheus/tsdb/index"
 	"github.com/prometheus/tsdb/labels"
 )
 
@@ -45,8 +48,13 @@ type Syncer struct {
 	reg                  prometheus.Registerer
 	bkt                  objstore.Bucket
 	consistencyDelay     time.Duration
+	deleteDelay          time.Duration
 	mtx                  sync.Mutex
 	blocks               map[ulid.ULID]*metadata.Meta
+	deletionMarks        map[ulid.ULID]*metadata.DeletionMark
+	unmarkedChecks       map[ulid.ULID]time.Time
+	unsupportedBlocks    map[ulid.ULID]struct{}
+	groupKeys            map[string]struct{}
 	blocksMtx            sync.Mutex
 	blockSyncConcurrency int
 	metrics              *syncerMetrics
@@ -61,8 +69,14 @@ type syncerMetrics struct {
 	garbageCollections        prometheus.Counter
 	garbageCollectionFailures prometheus.Counter
 	garbageCollectionDuration prometheus.Histogram
+	blocksMarkedForDeletion   prometheus.Gauge
+	blocksCleaned             prometheus.Counter
+	blockCleanupFailures      prometheus.Counter
+	blocksUnsupported         prometheus.Gauge
 	compactions               *prometheus.CounterVec
 	compactionFailures        *prometheus.CounterVec
+	lastSuccessfulRun         *prometheus.GaugeVec
+	lastFailedRun             *prometheus.GaugeVec
 	indexCacheBlocks          prometheus.Counter
 	indexCacheTraverse        prometheus.Counter
 	indexCacheFailures        prometheus.Counter
@@ -108,6 +122,23 @@ func newSyncerMetrics(reg prometheus.Registerer) *syncerMetrics {
 		},
 	})
 
+	m.blocksMarkedForDeletion = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "thanos_compact_blocks_marked_for_deletion",
+		Help: "Number of blocks marked for deletion in the bucket, which are excluded from compaction and downsampling.",
+	})
+	m.blocksCleaned = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "thanos_compact_blocks_cleaned_total",
+		Help: "Total number of blocks deleted after they were marked for deletion longer than the delete delay.",
+	})
+	m.blockCleanupFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "thanos_compact_block_cleanup_failures_total",
+		Help: "Total number of blocks marked for deletion that failed to be deleted.",
+	})
+
+	m.blocksUnsupported = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "thanos_compact_blocks_skipped_unsupported_chunks",
+		Help: "Number of blocks excluded from compaction because they contain chunks that cannot be compacted, e.g. native histograms.",
+	})
 	m.compactions = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "thanos_compact_group_compactions_total",
 		Help: "Total number of group compactions attempts.",
@@ -116,6 +147,14 @@ func newSyncerMetrics(reg prometheus.Registerer) *syncerMetrics {
 		Name: "thanos_compact_group_compactions_failures_total",
 		Help: "Total number of failed group compactions.",
 	}, []string{"group"})
+	m.lastSuccessfulRun = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "thanos_compact_group_last_successful_run_timestamp",
+		Help: "Unix timestamp of the last successful compaction run of the group.",
+	}, []string{"group"})
+	m.lastFailedRun = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "thanos_compact_group_last_failed_run_timestamp",
+		Help: "Unix timestamp of the last failed compaction run of the group.",
+	}, []string{"group"})
 
 	if reg != nil {
 		reg.MustRegister(
@@ -126,16 +165,24 @@ func newSyncerMetrics(reg prometheus.Registerer) *syncerMetrics {
 			m.garbageCollections,
 			m.garbageCollectionFailures,
 			m.garbageCollectionDuration,
+			m.blocksMarkedForDeletion,
+			m.blocksCleaned,
+			m.blockCleanupFailures,
+			m.blocksUnsupported,
 			m.compactions,
 			m.compactionFailures,
+			m.lastSuccessfulRun,
+			m.lastFailedRun,
 		)
 	}
 	return &m
 }
 
 // NewSyncer returns a new Syncer for the given Bucket and directory.
-// Blocks must be at least as old as the sync delay for being considered.
-func NewSyncer(logger log.Logger, reg prometheus.Registerer, bkt objstore.Bucket, consistencyDelay time.Duration, blockSyncConcurrency int, acceptMalformedIndex bool) (*Syncer, error) {
+// Blocks must be at least as old as the sync delay for being considered. Blocks marked for deletion are never
+// considered and are deleted by CleanMarkedBlocks once they were marked longer than the delete delay ago.
+// Unmarked blocks are checked for new marks at most once per delete delay, and again right before they are compacted.
+func NewSyncer(logger log.Logger, reg prometheus.Registerer, bkt objstore.Bucket, consistencyDelay time.Duration, deleteDelay time.Duration, blockSyncConcurrency int, acceptMalformedIndex bool) (*Syncer, error) {
 	if logger == nil {
 		logger = log.NewNopLogger()
 	}
@@ -143,7 +190,12 @@ func NewSyncer(logger log.Logger, reg prometheus.Registerer, bkt objstore.Bucket
 		logger:               logger,
 		reg:                  reg,
 		consistencyDelay:     consistencyDelay,
+		deleteDelay:          deleteDelay,
 		blocks:               map[ulid.ULID]*metadata.Meta{},
+		deletionMarks:        map[ulid.ULID]*metadata.DeletionMark{},
+		unmarkedChecks:       map[ulid.ULID]time.Time{},
+		unsupportedBlocks:    map[ulid.ULID]struct{}{},
+		groupKeys:            map[string]struct{}{},
 		bkt:                  bkt,
 		metrics:              newSyncerMetrics(reg),
 		blockSyncConcurrency: blockSyncConcurrency,
@@ -185,11 +237,23 @@ func (c *Syncer) syncMetas(ctx context.Context) error {
 			defer wg.Done()
 
 			for id := range metaIDsChan {
-				// Check if we already have this block cached locally.
+				// Blocks can be marked for deletion at any time, e.g. after a rewrite, so check known blocks as well,
+				// but only once per delete delay. Marks are checked again before compaction.
+				marked, err := c.syncDeletionMark(workCtx, id)
+				if err != nil {
+					errChan <- err
+					return
+				}
+				if marked {
+					continue
+				}
+
+				// Check if we already have this block cached locally or know that it cannot be compacted.
 				c.blocksMtx.Lock()
 				_, seen := c.blocks[id]
+				_, unsupported := c.unsupportedBlocks[id]
 				c.blocksMtx.Unlock()
-				if seen {
+				if seen || unsupported {
 					continue
 				}
 
@@ -248,10 +312,134 @@ func (c *Syncer) syncMetas(ctx context.Context) error {
 			delete(c.blocks, id)
 		}
 	}
+	for id := range c.deletionMarks {
+		if _, ok := remote[id]; !ok {
+			delete(c.deletionMarks, id)
+		}
+	}
+	for id := range c.unmarkedChecks {
+		if _, ok := remote[id]; !ok {
+			delete(c.unmarkedChecks, id)
+		}
+	}
+	c.metrics.blocksMarkedForDeletion.Set(float64(len(c.deletionMarks)))
+	for id := range c.unsupportedBlocks {
+		if _, ok := remote[id]; !ok {
+			delete(c.unsupportedBlocks, id)
+		}
+	}
+	c.metrics.blocksUnsupported.Set(float64(len(c.unsupportedBlocks)))
 
 	return nil
 }
 
+// skipUnsupportedBlocks removes the given blocks from the synced blocks and excludes them from the following syncs,
+// so that blocks with chunks that cannot be compacted do not block compaction of the rest of the bucket.
+// The blocks are kept in the bucket untouched.
+func (c *Syncer) skipUnsupportedBlocks(ids ...ulid.ULID) {
+	c.blocksMtx.Lock()
+	defer c.blocksMtx.Unlock()
+
+	for _, id := range ids {
+		c.unsupportedBlocks[id] = struct{}{}
+		delete(c.blocks, id)
+	}
+	c.metrics.blocksUnsupported.Set(float64(len(c.unsupportedBlocks)))
+}
+
+// syncDeletionMark records the deletion mark of the block, if any, and removes marked blocks from the synced blocks,
+// so that they are not compacted again into new unmarked blocks. It returns true if the block is marked for deletion.
+// Blocks found unmarked are not checked again until the delete delay passed, as a mark is only acted upon by
+// CleanMarkedBlocks after the delay anyway; blocks about to be compacted are checked by the group instead.
+func (c *Syncer) syncDeletionMark(ctx context.Context, id ulid.ULID) (bool, error) {
+	c.blocksMtx.Lock()
+	_, ok := c.deletionMarks[id]
+	checked, wasChecked := c.unmarkedChecks[id]
+	c.blocksMtx.Unlock()
+	if ok {
+		return true, nil
+	}
+	if wasChecked && time.Since(checked) < c.deleteDelay {
+		return false, nil
+	}
+
+	checked = time.Now()
+	m, err := readDeletionMark(ctx, c.logger, c.bkt, id)
+	if err != nil {
+		return false, err
+	}
+	if m == nil {
+		c.blocksMtx.Lock()
+		c.unmarkedChecks[id] = checked
+		c.blocksMtx.Unlock()
+		return false, nil
+	}
+
+	c.addDeletionMarks(m)
+	return true, nil
+}
+
+// addDeletionMarks records the given deletion marks and removes the marked blocks from the synced blocks.
+func (c *Syncer) addDeletionMarks(marks ...*metadata.DeletionMark) {
+	c.blocksMtx.Lock()
+	defer c.blocksMtx.Unlock()
+
+	for _, m := range marks {
+		c.deletionMarks[m.ID] = m
+		delete(c.unmarkedChecks, m.ID)
+		delete(c.blocks, m.ID)
+	}
+	c.metrics.blocksMarkedForDeletion.Set(float64(len(c.deletionMarks)))
+}
+
+// readDeletionMark returns the deletion mark of the block or nil if it is not marked.
+func readDeletionMark(ctx context.Context, logger log.Logger, bkt objstore.BucketReader, id ulid.ULID) (*metadata.DeletionMark, error) {
+	// Check the existence first, as most blocks are not marked and it is cheaper than getting the mark.
+	exists, err := bkt.Exists(ctx, path.Join(id.String(), metadata.DeletionMarkFilename))
+	if err != nil {
+		return nil, errors.Wrapf(err, "check deletion mark of block %s", id)
+	}
+	if !exists {
+		return nil, nil
+	}
+	m, err := block.ReadDeletionMark(ctx, logger, bkt, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read deletion mark of block %s", id)
+	}
+	return m, nil
+}
+
+// CleanMarkedBlocks deletes all blocks from the bucket that were marked for deletion longer than the delete delay ago.
+// The delay gives store gateways time to stop serving the blocks before their files disappear.
+func (c *Syncer) CleanMarkedBlocks(ctx context.Context) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for id, m := range c.deletionMarks {
+		if time.Since(time.Unix(m.DeletionTime, 0)) <= c.deleteDelay {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		level.Info(c.logger).Log("msg", "deleting block marked for deletion", "block", id, "marked", time.Unix(m.DeletionTime, 0))
+		// Delete the meta file first, so a partially deleted block is never read as a complete one.
+		if err := c.bkt.Delete(ctx, path.Join(id.String(), block.MetaFilename)); err != nil && !c.bkt.IsObjNotFoundErr(err) {
+			c.metrics.blockCleanupFailures.Inc()
+			return retry(errors.Wrapf(err, "delete meta of block %s marked for deletion", id))
+		}
+		if err := block.Delete(ctx, c.bkt, id); err != nil {
+			c.metrics.blockCleanupFailures.Inc()
+			return retry(errors.Wrapf(err, "delete block %s marked for deletion", id))
+		}
+		delete(c.deletionMarks, id)
+		c.metrics.blocksCleaned.Inc()
+	}
+	c.metrics.blocksMarkedForDeletion.Set(float64(len(c.deletionMarks)))
+	return nil
+}
+
 func (c *Syncer) downloadMeta(ctx context.Context, id ulid.ULID) (*metadata.Meta, error) {
 	level.Debug(c.logger).Log("msg", "download meta", "block", id)
 
@@ -283,7 +471,7 @@ func (c *Syncer) downloadMeta(ctx context.Context, id ulid.ULID) (*metadata.Meta
 }
 
 // removeIfMalformed removes a block from the bucket if that block does not have a meta file.  It ignores blocks that
-// are younger than MinimumAgeForRemoval.
+// are younger than the maximum of consistencyDelay and MinimumAgeForRemoval.
 func (c *Syncer) removeIfMetaMalformed(ctx context.Context, id ulid.ULID) (removedOrIgnored bool) {
 	metaExists, err := c.bkt.Exists(ctx, path.Join(id.String(), block.MetaFilename))
 	if err != nil {
@@ -295,7 +483,11 @@ func (c *Syncer) removeIfMetaMalformed(ctx context.Context, id ulid.ULID) (remov
 		return false
 	}
 
-	if ulid.Now()-id.Time() <= uint64(MinimumAgeForRemoval/time.Millisecond) {
+	minAge := MinimumAgeForRemoval
+	if c.consistencyDelay > minAge {
+		minAge = c.consistencyDelay
+	}
+	if ulid.Now()-id.Time() <= uint64(minAge/time.Millisecond) {
 		// Minimum delay has not expired, ignore for now
 		return true
 	}
@@ -337,6 +529,8 @@ func (c *Syncer) Groups() (res []*Group, err error) {
 				c.acceptMalformedIndex,
 				c.metrics.compactions.WithLabelValues(GroupKey(*m)),
 				c.metrics.compactionFailures.WithLabelValues(GroupKey(*m)),
+				c.metrics.lastSuccessfulRun.WithLabelValues(GroupKey(*m)),
+				c.metrics.lastFailedRun.WithLabelValues(GroupKey(*m)),
 				c.metrics.garbageCollectedBlocks,
 			)
 			if err != nil {
@@ -352,6 +546,18 @@ func (c *Syncer) Groups() (res []*Group, err error) {
 	sort.Slice(res, func(i, j int) bool {
 		return res[i].Key() < res[j].Key()
 	})
+
+	// Groups are gone once all their blocks were deleted, e.g. by retention, so stop reporting their last runs.
+	for key := range c.groupKeys {
+		if _, ok := groups[key]; !ok {
+			c.metrics.lastSuccessfulRun.DeleteLabelValues(key)
+			c.metrics.lastFailedRun.DeleteLabelValues(key)
+		}
+	}
+	c.groupKeys = make(map[string]struct{}, len(groups))
+	for key := range groups {
+		c.groupKeys[key] = struct{}{}
+	}
 	return res, nil
 }
 
@@ -483,6 +689,8 @@ type Group struct {
 	acceptMalformedIndex        bool
 	compactions                 prometheus.Counter
 	compactionFailures          prometheus.Counter
+	lastSuccessfulRun           prometheus.Gauge
+	lastFailedRun               prometheus.Gauge
 	groupGarbageCollectedBlocks prometheus.Counter
 }
 
@@ -495,6 +703,8 @@ func newGroup(
 	acceptMalformedIndex bool,
 	compactions prometheus.Counter,
 	compactionFailures prometheus.Counter,
+	lastSuccessfulRun prometheus.Gauge,
+	lastFailedRun prometheus.Gauge,
 	groupGarbageCollectedBlocks prometheus.Counter,
 ) (*Group, error) {
 	if logger == nil {
@@ -509,6 +719,8 @@ func newGroup(
 		acceptMalformedIndex:        acceptMalformedIndex,
 		compactions:                 compactions,
 		compactionFailures:          compactionFailures,
+		lastSuccessfulRun:           lastSuccessfulRun,
+		lastFailedRun:               lastFailedRun,
 		groupGarbageCollectedBlocks: groupGarbageCollectedBlocks,
 	}
 	return g, nil
@@ -519,6 +731,26 @@ func (cg *Group) Key() string {
 	return groupKey(cg.resolution, cg.labels)
 }
 
+// replicaLabels returns the replica labels of all blocks of the group. As blocks of a group have the same labels, they
+// usually come from the same source and have the same replica labels.
+func (cg *Group) replicaLabels() []string {
+	set := map[string]struct{}{}
+	for _, m := range cg.blocks {
+		for _, n := range m.Thanos.ReplicaLabels {
+			set[n] = struct{}{}
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	res := make([]string, 0, len(set))
+	for n := range set {
+		res = append(res, n)
+	}
+	sort.Strings(res)
+	return res
+}
+
 // Add the block with the given meta to the group.
 func (cg *Group) Add(meta *metadata.Meta) error {
 	cg.mtx.Lock()
@@ -573,6 +805,9 @@ func (cg *Group) Compact(ctx context.Context, dir string, comp tsdb.Compactor) (
 	shouldRerun, compID, err := cg.compact(ctx, subDir, comp)
 	if err != nil {
 		cg.compactionFailures.Inc()
+		cg.lastFailedRun.SetToCurrentTime()
+	} else {
+		cg.lastSuccessfulRun.SetToCurrentTime()
 	}
 	cg.compactions.Inc()
 	return shouldRerun, compID, err
@@ -599,6 +834,113 @@ func IsIssue347Error(err error) bool {
 	return ok
 }
 
+// UnsupportedChunksError is a type wrapper for errors caused by blocks with chunks that cannot be compacted,
+// e.g. native histograms.
+type UnsupportedChunksError struct {
+	err error
+
+	ids []ulid.ULID
+}
+
+// unsupportedChunksError returns an UnsupportedChunksError with the blocks to skip. If no block can be blamed, skipping
+// nothing would only plan the same compaction again, so the error halts the compactor instead.
+func unsupportedChunksError(err error, blocks []ulid.ULID) error {
+	if len(blocks) == 0 {
+		return halt(err)
+	}
+	return UnsupportedChunksError{err: err, ids: blocks}
+}
+
+func (e UnsupportedChunksError) Error() string {
+	return e.err.Error()
+}
+
+// IsUnsupportedChunksError returns true if the base error is a UnsupportedChunksError.
+func IsUnsupportedChunksError(err error) bool {
+	_, ok := errors.Cause(err).(UnsupportedChunksError)
+	return ok
+}
+
+// DeletionMarkedError is a type wrapper for errors caused by planned blocks that were marked for deletion since the last
+// check of their marks.
+type DeletionMarkedError struct {
+	err error
+
+	marks []*metadata.DeletionMark
+}
+
+func (e DeletionMarkedError) Error() string {
+	return e.err.Error()
+}
+
+// IsDeletionMarkedError returns true if the base error is a DeletionMarkedError.
+func IsDeletionMarkedError(err error) bool {
+	_, ok := errors.Cause(err).(DeletionMarkedError)
+	return ok
+}
+
+// unsupportedChunksBlocks returns the blocks of the plan having chunks that cannot be decoded. If a block cannot be
+// checked, it is reported as well, as the whole plan failed to compact anyway.
+func unsupportedChunksBlocks(logger log.Logger, plan []string) (ids []ulid.ULID) {
+	for _, pdir := range plan {
+		id, err := ulid.Parse(filepath.Base(pdir))
+		if err != nil {
+			continue
+		}
+		ok, err := hasUnsupportedChunks(logger, pdir)
+		if err != nil {
+			level.Warn(logger).Log("msg", "failed to check chunk encodings of block", "block", id, "err", err)
+		}
+		if ok || err != nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func hasUnsupportedChunks(logger log.Logger, dir string) (_ bool, err error) {
+	b, err := tsdb.OpenBlock(logger, dir, downsample.NewPool())
+	if err != nil {
+		return false, errors.Wrap(err, "open block")
+	}
+	defer runutil.CloseWithErrCapture(&err, b, "block")
+
+	ir, err := b.Index()
+	if err != nil {
+		return false, errors.Wrap(err, "open index")
+	}
+	defer runutil.CloseWithErrCapture(&err, ir, "index reader")
+
+	cr, err := b.Chunks()
+	if err != nil {
+		return false, errors.Wrap(err, "open chunks")
+	}
+	defer runutil.CloseWithErrCapture(&err, cr, "chunk reader")
+
+	p, err := ir.Postings(index.AllPostingsKey())
+	if err != nil {
+		return false, errors.Wrap(err, "get all postings")
+	}
+	var (
+		lset labels.Labels
+		chks []chunks.Meta
+	)
+	for p.Next() {
+		if err := ir.Series(p.At(), &lset, &chks); err != nil {
+			return false, errors.Wrap(err, "read series")
+		}
+		for _, c := range chks {
+			if _, err := cr.Chunk(c.Ref); err != nil {
+				if downsample.IsUnsupportedEncodingError(err) {
+					return true, nil
+				}
+				return false, errors.Wrapf(err, "read chunk %d", c.Ref)
+			}
+		}
+	}
+	return false, errors.Wrap(p.Err(), "iterate postings")
+}
+
 // HaltError is a type wrapper for errors that should halt any further progress on compactions.
 type HaltError struct {
 	err error
@@ -769,7 +1111,28 @@ func (cg *Group) compact(ctx context.Context, dir string, comp tsdb.Compactor) (
 	// This is one potential source of how we could end up with duplicated chunks.
 	uniqueSources := map[ulid.ULID]struct{}{}
 
-	// Once we have a plan we need to download the actual data.
+	// The syncer checks the marks of known blocks only once per delete delay, so make sure that no block marked since
+	// then is compacted again into a new unmarked block.
+	var marks []*metadata.DeletionMark
+	for _, pdir := range plan {
+		id, err := ulid.Parse(filepath.Base(pdir))
+		if err != nil {
+			return false, ulid.ULID{}, errors.Wrapf(err, "plan dir %s", pdir)
+		}
+		m, err := readDeletionMark(ctx, cg.logger, cg.bkt, id)
+		if err != nil {
+			return false, ulid.ULID{}, retry(err)
+		}
+		if m != nil {
+			marks = append(marks, m)
+		}
+	}
+	if len(marks) > 0 {
+		return false, ulid.ULID{}, DeletionMarkedError{err: errors.Errorf("blocks of plan %v marked for deletion", plan), marks: marks}
+	}
+
+	// Once we have a plan we need to download the actual data. Indexes of all planned blocks are downloaded and
+	// verified first, so that we do not fetch chunks of a plan that cannot be compacted anyway.
 	begin := time.Now()
 
 	for _, pdir := range plan {
@@ -798,8 +1161,8 @@ func (cg *Group) compact(ctx context.Context, dir string, comp tsdb.Compactor) (
 			return false, ulid.ULID{}, errors.Errorf("mismatch between meta %s and dir %s", meta.ULID, id)
 		}
 
-		if err := block.Download(ctx, cg.logger, cg.bkt, id, pdir); err != nil {
-			return false, ulid.ULID{}, retry(errors.Wrapf(err, "download block %s", id))
+		if err := block.DownloadIndex(ctx, cg.logger, cg.bkt, id, pdir); err != nil {
+			return false, ulid.ULID{}, retry(errors.Wrapf(err, "download index of block %s", id))
 		}
 
 		// Ensure all input blocks are valid.
@@ -821,6 +1184,16 @@ func (cg *Group) compact(ctx context.Context, dir string, comp tsdb.Compactor) (
 				"block id %s, try running with --debug.accept-malformed-index", id)
 		}
 	}
+
+	for _, pdir := range plan {
+		id, err := ulid.Parse(filepath.Base(pdir))
+		if err != nil {
+			return false, ulid.ULID{}, errors.Wrapf(err, "plan dir %s", pdir)
+		}
+		if err := block.DownloadChunks(ctx, cg.logger, cg.bkt, id, pdir); err != nil {
+			return false, ulid.ULID{}, retry(errors.Wrapf(err, "download chunks of block %s", id))
+		}
+	}
 	level.Debug(cg.logger).Log("msg", "downloaded and verified blocks",
 		"blocks", fmt.Sprintf("%v", plan), "duration", time.Since(begin))
 
@@ -828,6 +1201,9 @@ func (cg *Group) compact(ctx context.Context, dir string, comp tsdb.Compactor) (
 
 	compID, err = comp.Compact(dir, plan, nil)
 	if err != nil {
+		if downsample.IsUnsupportedEncodingError(err) {
+			return false, ulid.ULID{}, unsupportedChunksError(errors.Wrapf(err, "compact blocks %v", plan), unsupportedChunksBlocks(cg.logger, plan))
+		}
 		return false, ulid.ULID{}, halt(errors.Wrapf(err, "compact blocks %v", plan))
 	}
 	if compID == (ulid.ULID{}) {
@@ -856,9 +1232,10 @@ func (cg *Group) compact(ctx context.Context, dir string, comp tsdb.Compactor) (
 	indexCache := filepath.Join(bdir, block.IndexCacheFilename)
 
 	newMeta, err := metadata.InjectThanos(cg.logger, bdir, metadata.Thanos{
-		Labels:     cg.labels.Map(),
-		Downsample: metadata.ThanosDownsample{Resolution: cg.resolution},
-		Source:     metadata.CompactorSource,
+		Labels:        cg.labels.Map(),
+		Downsample:    metadata.ThanosDownsample{Resolution: cg.resolution},
+		Source:        metadata.CompactorSource,
+		ReplicaLabels: cg.replicaLabels(),
 	}, nil)
 	if err != nil {
 		return false, ulid.ULID{}, errors.Wrapf(err, "failed to finalize the block %s", bdir)
@@ -984,6 +1361,25 @@ func (c *BucketCompactor) Compact(ctx context.Context) error {
 						continue
 					}
 
+					if IsUnsupportedChunksError(err) {
+						ie := errors.Cause(err).(UnsupportedChunksError)
+						level.Warn(c.logger).Log("msg", "skipping blocks with chunks that cannot be compacted; they stay in the bucket uncompacted",
+							"group", g.Key(), "blocks", fmt.Sprintf("%v", ie.ids), "err", err)
+						c.sy.skipUnsupportedBlocks(ie.ids...)
+						mtx.Lock()
+						finishedAllGroups = false
+						mtx.Unlock()
+						continue
+					}
+					if IsDeletionMarkedError(err) {
+						de := errors.Cause(err).(DeletionMarkedError)
+						level.Info(c.logger).Log("msg", "excluding blocks marked for deletion from compaction", "group", g.Key(), "err", err)
+						c.sy.addDeletionMarks(de.marks...)
+						mtx.Lock()
+						finishedAllGroups = false
+						mtx.Unlock()
+						continue
+					}
 					if IsIssue347Error(err) {
 						if err := RepairIssue347(workCtx, c.logger, c.bkt, err); err == nil {
 							mtx.Lock()
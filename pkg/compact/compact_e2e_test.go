@@ -22,6 +22,7 @@ import (
 	"github.com/improbable-eng/thanos/pkg/testutil"
 	"github.com/oklog/ulid"
 	"github.com/pkg/errors"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/tsdb"
 	"github.com/prometheus/tsdb/index"
 	"github.com/prometheus/tsdb/labels"
@@ -32,7 +33,7 @@ func TestSyncer_SyncMetas_e2e(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 		defer cancel()
 
-		sy, err := NewSyncer(nil, nil, bkt, 0, 1, false)
+		sy, err := NewSyncer(nil, nil, bkt, 0, 0, 1, false)
 		testutil.Ok(t, err)
 
 		// Generate 15 blocks. Initially the first 10 are synced into memory and only the last
@@ -134,7 +135,7 @@ func TestSyncer_GarbageCollect_e2e(t *testing.T) {
 		}
 
 		// Do one initial synchronization with the bucket.
-		sy, err := NewSyncer(nil, nil, bkt, 0, 1, false)
+		sy, err := NewSyncer(nil, nil, bkt, 0, 0, 1, false)
 		testutil.Ok(t, err)
 		testutil.Ok(t, sy.SyncMetas(ctx))
 
@@ -247,6 +248,8 @@ func TestGroup_Compact_e2e(t *testing.T) {
 			false,
 			metrics.compactions.WithLabelValues(""),
 			metrics.compactionFailures.WithLabelValues(""),
+			metrics.lastSuccessfulRun.WithLabelValues(""),
+			metrics.lastFailedRun.WithLabelValues(""),
 			metrics.garbageCollectedBlocks,
 		)
 		testutil.Ok(t, err)
@@ -257,6 +260,8 @@ func TestGroup_Compact_e2e(t *testing.T) {
 		shouldRerun, id, err := g.Compact(ctx, dir, comp)
 		testutil.Ok(t, err)
 		testutil.Assert(t, !shouldRerun, "group should be empty, but compactor did a compaction and told us to rerun")
+		testutil.Assert(t, promtestutil.ToFloat64(metrics.lastSuccessfulRun.WithLabelValues("")) > 0, "expected last successful run to be recorded")
+		testutil.Equals(t, 0.0, promtestutil.ToFloat64(metrics.lastFailedRun.WithLabelValues("")))
 
 		// Add all metas that would be gathered by syncMetas.
 		for _, m := range metas {
@@ -3,14 +3,24 @@ package compact
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/improbable-eng/thanos/pkg/block/metadata"
+	"github.com/improbable-eng/thanos/pkg/objstore"
 	"github.com/improbable-eng/thanos/pkg/objstore/inmem"
 	"github.com/improbable-eng/thanos/pkg/testutil"
 	"github.com/oklog/ulid"
 	"github.com/pkg/errors"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/tsdb"
 )
 
 func TestHaltError(t *testing.T) {
@@ -49,7 +59,7 @@ func TestSyncer_SyncMetas_HandlesMalformedBlocks(t *testing.T) {
 	defer cancel()
 
 	bkt := inmem.NewBucket()
-	sy, err := NewSyncer(nil, nil, bkt, 10*time.Second, 1, false)
+	sy, err := NewSyncer(nil, nil, bkt, 10*time.Second, 0, 1, false)
 	testutil.Ok(t, err)
 
 	// Generate 1 block which is older than MinimumAgeForRemoval which has chunk data but no meta.  Compactor should delete it.
@@ -77,3 +87,251 @@ func TestSyncer_SyncMetas_HandlesMalformedBlocks(t *testing.T) {
 	testutil.Ok(t, err)
 	testutil.Equals(t, true, exists)
 }
+
+func TestSyncer_SyncMetas_HonorsConsistencyDelayForMalformedBlocks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	bkt := inmem.NewBucket()
+	sy, err := NewSyncer(nil, nil, bkt, 2*time.Hour, 0, 1, false)
+	testutil.Ok(t, err)
+
+	var fakeChunk bytes.Buffer
+	fakeChunk.Write([]byte{0, 1, 2, 3})
+
+	// Generate 1 block which is older than MinimumAgeForRemoval, but younger than consistencyDelay, and which has chunk
+	// data but no meta. It might be still uploading, so compactor should ignore it.
+	shouldIgnoreId, err := ulid.New(uint64(time.Now().Add(-time.Hour).Unix()*1000), nil)
+	testutil.Ok(t, err)
+	testutil.Ok(t, bkt.Upload(ctx, path.Join(shouldIgnoreId.String(), "chunks", "000001"), bytes.NewReader(fakeChunk.Bytes())))
+
+	// Generate 1 block which is older than consistencyDelay, and which has chunk data but no meta. Compactor should delete it.
+	shouldDeleteId, err := ulid.New(uint64(time.Now().Add(-3*time.Hour).Unix()*1000), nil)
+	testutil.Ok(t, err)
+	testutil.Ok(t, bkt.Upload(ctx, path.Join(shouldDeleteId.String(), "chunks", "000001"), bytes.NewReader(fakeChunk.Bytes())))
+
+	testutil.Ok(t, sy.SyncMetas(ctx))
+
+	exists, err := bkt.Exists(ctx, path.Join(shouldDeleteId.String(), "chunks", "000001"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, false, exists)
+
+	exists, err = bkt.Exists(ctx, path.Join(shouldIgnoreId.String(), "chunks", "000001"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, true, exists)
+}
+
+// existsCountingBucket counts the Exists calls, i.e. the checks of deletion marks.
+type existsCountingBucket struct {
+	objstore.Bucket
+
+	mtx    sync.Mutex
+	exists int
+}
+
+func (b *existsCountingBucket) Exists(ctx context.Context, name string) (bool, error) {
+	b.mtx.Lock()
+	b.exists++
+	b.mtx.Unlock()
+	return b.Bucket.Exists(ctx, name)
+}
+
+func (b *existsCountingBucket) count() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.exists
+}
+
+// planAllCompactor plans all blocks of a group for compaction.
+type planAllCompactor struct {
+	tsdb.Compactor
+}
+
+func (planAllCompactor) Plan(dir string) ([]string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var plan []string
+	for _, fi := range fis {
+		plan = append(plan, filepath.Join(dir, fi.Name()))
+	}
+	return plan, nil
+}
+
+func TestSyncer_DeletionMarks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "test-deletion-marks")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	bkt := &existsCountingBucket{Bucket: inmem.NewBucket()}
+	sy, err := NewSyncer(nil, nil, bkt, 0, time.Hour, 1, false)
+	testutil.Ok(t, err)
+
+	var ids []ulid.ULID
+	for i := 0; i < 3; i++ {
+		id := ulid.MustNew(uint64(i), nil)
+		meta := metadata.Meta{BlockMeta: tsdb.BlockMeta{Version: 1, ULID: id, MinTime: int64(i) * 10, MaxTime: int64(i+1) * 10}}
+		b, err := json.Marshal(&meta)
+		testutil.Ok(t, err)
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(id.String(), metadata.MetaFilename), bytes.NewReader(b)))
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(id.String(), "chunks", "000001"), bytes.NewReader([]byte{0, 1, 2, 3})))
+		ids = append(ids, id)
+	}
+	mark := func(id ulid.ULID, at time.Time) {
+		b, err := json.Marshal(metadata.DeletionMark{ID: id, DeletionTime: at.Unix(), Version: metadata.DeletionMarkVersion1})
+		testutil.Ok(t, err)
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(id.String(), metadata.DeletionMarkFilename), bytes.NewReader(b)))
+	}
+
+	testutil.Ok(t, sy.SyncMetas(ctx))
+	groups, err := sy.Groups()
+	testutil.Ok(t, err)
+	testutil.Equals(t, ids, groups[0].IDs())
+	testutil.Equals(t, 3, bkt.count())
+
+	// Marks of known blocks are not checked on every sync.
+	mark(ids[0], time.Now().Add(-2*time.Hour))
+
+	testutil.Ok(t, sy.SyncMetas(ctx))
+	groups, err = sy.Groups()
+	testutil.Ok(t, err)
+	testutil.Equals(t, ids, groups[0].IDs())
+	testutil.Equals(t, 3, bkt.count())
+
+	// Once the delete delay passed, they are checked again.
+	for id := range sy.unmarkedChecks {
+		sy.unmarkedChecks[id] = time.Now().Add(-time.Hour)
+	}
+	testutil.Ok(t, sy.SyncMetas(ctx))
+	groups, err = sy.Groups()
+	testutil.Ok(t, err)
+	testutil.Equals(t, ids[1:], groups[0].IDs())
+	testutil.Equals(t, 6, bkt.count())
+	testutil.Equals(t, 1.0, promtestutil.ToFloat64(sy.metrics.blocksMarkedForDeletion))
+
+	// Blocks marked in between are excluded right before they would be compacted.
+	mark(ids[1], time.Now().Add(-10*time.Minute))
+
+	testutil.Ok(t, sy.SyncMetas(ctx))
+	groups, err = sy.Groups()
+	testutil.Ok(t, err)
+	testutil.Equals(t, ids[1:], groups[0].IDs())
+
+	_, _, err = groups[0].Compact(ctx, dir, planAllCompactor{})
+	testutil.Assert(t, IsDeletionMarkedError(err), "not a deletion marked error")
+	sy.addDeletionMarks(errors.Cause(err).(DeletionMarkedError).marks...)
+
+	testutil.Ok(t, sy.SyncMetas(ctx))
+	groups, err = sy.Groups()
+	testutil.Ok(t, err)
+	testutil.Equals(t, ids[2:], groups[0].IDs())
+	testutil.Equals(t, 2.0, promtestutil.ToFloat64(sy.metrics.blocksMarkedForDeletion))
+
+	// Only the block marked longer than the delete delay ago is deleted.
+	testutil.Ok(t, sy.CleanMarkedBlocks(ctx))
+	for _, tcase := range []struct {
+		id     ulid.ULID
+		exists bool
+	}{
+		{id: ids[0], exists: false},
+		{id: ids[1], exists: true},
+		{id: ids[2], exists: true},
+	} {
+		for _, f := range []string{metadata.MetaFilename, path.Join("chunks", "000001")} {
+			exists, err := bkt.Exists(ctx, path.Join(tcase.id.String(), f))
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.exists, exists)
+		}
+	}
+	testutil.Equals(t, 1.0, promtestutil.ToFloat64(sy.metrics.blocksCleaned))
+
+	testutil.Ok(t, sy.SyncMetas(ctx))
+	groups, err = sy.Groups()
+	testutil.Ok(t, err)
+	testutil.Equals(t, ids[2:], groups[0].IDs())
+	testutil.Equals(t, 1.0, promtestutil.ToFloat64(sy.metrics.blocksMarkedForDeletion))
+}
+
+func TestSyncer_UnsupportedBlocks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	bkt := inmem.NewBucket()
+	sy, err := NewSyncer(nil, nil, bkt, 0, time.Hour, 1, false)
+	testutil.Ok(t, err)
+
+	var ids []ulid.ULID
+	for i := 0; i < 3; i++ {
+		id := ulid.MustNew(uint64(i), nil)
+		meta := metadata.Meta{BlockMeta: tsdb.BlockMeta{Version: 1, ULID: id}}
+		b, err := json.Marshal(&meta)
+		testutil.Ok(t, err)
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(id.String(), metadata.MetaFilename), bytes.NewReader(b)))
+		ids = append(ids, id)
+	}
+
+	testutil.Ok(t, sy.SyncMetas(ctx))
+
+	// Without blocks to skip, the same plan would fail again on every run.
+	err = errors.Wrap(unsupportedChunksError(errors.New("test"), nil), "compaction failed")
+	testutil.Assert(t, !IsUnsupportedChunksError(err), "unsupported chunks error without blocks")
+	testutil.Assert(t, IsHaltError(err), "not a halt error")
+
+	err = errors.Wrap(unsupportedChunksError(errors.New("test"), ids[:1]), "compaction failed")
+	testutil.Assert(t, IsUnsupportedChunksError(err), "not an unsupported chunks error")
+	sy.skipUnsupportedBlocks(errors.Cause(err).(UnsupportedChunksError).ids...)
+
+	// Skipped blocks stay in the bucket, but are not synced again.
+	testutil.Ok(t, sy.SyncMetas(ctx))
+	groups, err := sy.Groups()
+	testutil.Ok(t, err)
+	testutil.Equals(t, ids[1:], groups[0].IDs())
+	testutil.Equals(t, 1.0, promtestutil.ToFloat64(sy.metrics.blocksUnsupported))
+
+	testutil.Ok(t, bkt.Delete(ctx, path.Join(ids[0].String(), metadata.MetaFilename)))
+	testutil.Ok(t, sy.SyncMetas(ctx))
+	testutil.Equals(t, 0.0, promtestutil.ToFloat64(sy.metrics.blocksUnsupported))
+}
+
+func TestSyncer_Groups_DeletesRunTimestampsOfRemovedGroups(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	bkt := inmem.NewBucket()
+	sy, err := NewSyncer(nil, nil, bkt, 0, time.Hour, 1, false)
+	testutil.Ok(t, err)
+
+	var ids []ulid.ULID
+	for i := 0; i < 2; i++ {
+		id := ulid.MustNew(uint64(i), nil)
+		meta := metadata.Meta{BlockMeta: tsdb.BlockMeta{Version: 1, ULID: id}}
+		meta.Thanos.Labels = map[string]string{"a": fmt.Sprintf("%d", i)}
+		b, err := json.Marshal(&meta)
+		testutil.Ok(t, err)
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(id.String(), metadata.MetaFilename), bytes.NewReader(b)))
+		ids = append(ids, id)
+	}
+
+	testutil.Ok(t, sy.SyncMetas(ctx))
+	groups, err := sy.Groups()
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(groups))
+	for i, g := range groups {
+		g.lastSuccessfulRun.Set(float64(i + 1))
+		g.lastFailedRun.Set(float64(i + 1))
+	}
+
+	testutil.Ok(t, bkt.Delete(ctx, path.Join(ids[1].String(), metadata.MetaFilename)))
+	testutil.Ok(t, sy.SyncMetas(ctx))
+	groups, err = sy.Groups()
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(groups))
+
+	// Only the remaining group is reported.
+	testutil.Equals(t, 1.0, promtestutil.ToFloat64(sy.metrics.lastSuccessfulRun))
+	testutil.Equals(t, 1.0, promtestutil.ToFloat64(sy.metrics.lastFailedRun))
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/fortytw2/leaktest"
 	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/pkg/errors"
+	"github.com/prometheus/tsdb"
 	"github.com/prometheus/tsdb/chunkenc"
 )
 
@@ -46,3 +48,22 @@ func TestAggrChunk(t *testing.T) {
 	}
 	testutil.Equals(t, input, res)
 }
+
+func TestPool_UnsupportedEncodings(t *testing.T) {
+	p := NewPool()
+
+	for _, enc := range []chunkenc.Encoding{ChunkEncHistogram, ChunkEncFloatHistogram} {
+		_, err := p.Get(enc, []byte{0, 0})
+		testutil.NotOk(t, err)
+		testutil.Assert(t, IsUnsupportedEncodingError(errors.Wrap(err, "read chunk")), "expected unsupported encoding error, got %v", err)
+
+		var merr tsdb.MultiError
+		merr.Add(errors.Wrap(err, "populate block"))
+		merr.Add(errors.New("close"))
+		testutil.Assert(t, IsUnsupportedEncodingError(merr), "expected unsupported encoding error in %v", merr)
+	}
+
+	c, err := p.Get(chunkenc.EncXOR, chunkenc.NewXORChunk().Bytes())
+	testutil.Ok(t, err)
+	testutil.Equals(t, chunkenc.EncXOR, c.Encoding())
+}
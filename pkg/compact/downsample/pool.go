@@ -1,11 +1,51 @@
 package downsample
 
 import (
+	"fmt"
 	"sync"
 
+	"github.com/pkg/errors"
+	"github.com/prometheus/tsdb"
 	"github.com/prometheus/tsdb/chunkenc"
 )
 
+// Chunk encodings used by newer Prometheus versions for native histograms. The TSDB version Thanos is built
+// against cannot decode them, so we only recognize them to fail with a meaningful error instead of
+// a generic "invalid encoding" one.
+const (
+	ChunkEncHistogram      = chunkenc.Encoding(2)
+	ChunkEncFloatHistogram = chunkenc.Encoding(3)
+)
+
+// UnsupportedEncodingError is returned when a block contains chunks that cannot be compacted or downsampled.
+type UnsupportedEncodingError struct {
+	Encoding chunkenc.Encoding
+}
+
+func (e UnsupportedEncodingError) Error() string {
+	switch e.Encoding {
+	case ChunkEncHistogram, ChunkEncFloatHistogram:
+		return fmt.Sprintf("native histogram chunks (encoding %d) are not supported", e.Encoding)
+	}
+	return fmt.Sprintf("unsupported chunk encoding %d", e.Encoding)
+}
+
+// IsUnsupportedEncodingError returns true if the base error, or any of the errors gathered
+// in a TSDB multi error, is an UnsupportedEncodingError.
+func IsUnsupportedEncodingError(err error) bool {
+	switch e := errors.Cause(err).(type) {
+	case UnsupportedEncodingError:
+		return true
+	case tsdb.MultiError:
+		for _, err := range e {
+			if IsUnsupportedEncodingError(err) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Pool is a memory pool of chunk objects, supporting Thanos aggregated chunk encoding.
 // It maintains separate pools for xor and aggr chunks.
 type pool struct {
@@ -31,10 +71,11 @@ func (p *pool) Get(e chunkenc.Encoding, b []byte) (chunkenc.Chunk, error) {
 		c := p.aggr.Get().(*AggrChunk)
 		*c = AggrChunk(b)
 		return c, nil
+	case ChunkEncHistogram, ChunkEncFloatHistogram:
+		return nil, UnsupportedEncodingError{Encoding: e}
 	}
 
 	return p.wrapped.Get(e, b)
-
 }
 
 func (p *pool) Put(c chunkenc.Chunk) error {
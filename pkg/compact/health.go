@@ -0,0 +1,261 @@
+package compact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/block"
+	"github.com/improbable-eng/thanos/pkg/block/metadata"
+	"github.com/improbable-eng/thanos/pkg/objstore"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/tsdb"
+)
+
+const (
+	// HealthReportPath is the bucket path of the health report written by the compactor.
+	HealthReportPath = "debug/health-report.json"
+
+	// HealthReportVersion1 is the first version of the health report format.
+	HealthReportVersion1 = 1
+
+	// largestBlocksInReport is the number of largest blocks listed in the health report.
+	largestBlocksInReport = 10
+)
+
+// HealthReport is a machine-readable summary of the bucket state as seen by the compactor.
+type HealthReport struct {
+	Version   int       `json:"version"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// Groups holds the state of each group of blocks with the same external labels and resolution.
+	Groups []GroupHealth `json:"groups"`
+	// PartialBlocks are block directories older than consistency delay without meta.json, likely
+	// leftovers of failed uploads.
+	PartialBlocks []ulid.ULID `json:"partial_blocks"`
+	// LargestBlocks are the biggest blocks in the bucket, sorted by size descending.
+	LargestBlocks []BlockHealth `json:"largest_blocks"`
+	// Bytes is the total size of all blocks with meta.json.
+	Bytes uint64 `json:"bytes"`
+}
+
+// GroupHealth describes a single group of blocks.
+type GroupHealth struct {
+	Key        string            `json:"key"`
+	Labels     map[string]string `json:"labels"`
+	Resolution int64             `json:"resolution"`
+	Blocks     int               `json:"blocks"`
+	Bytes      uint64            `json:"bytes"`
+	MinTime    int64             `json:"min_time"`
+	MaxTime    int64             `json:"max_time"`
+	// Overlaps are sets of blocks with overlapping time ranges. Compactor halts on those.
+	Overlaps []OverlapHealth `json:"overlaps"`
+	// Gaps are time ranges not covered by any block of the group between its min and max time.
+	Gaps []TimeRange `json:"gaps"`
+}
+
+// TimeRange is a time range in milliseconds.
+type TimeRange struct {
+	MinTime int64 `json:"min_time"`
+	MaxTime int64 `json:"max_time"`
+}
+
+// OverlapHealth describes blocks overlapping in the given time range.
+type OverlapHealth struct {
+	Range  TimeRange   `json:"range"`
+	Blocks []ulid.ULID `json:"blocks"`
+}
+
+// BlockHealth describes a single block.
+type BlockHealth struct {
+	ID      ulid.ULID `json:"id"`
+	Group   string    `json:"group"`
+	MinTime int64     `json:"min_time"`
+	MaxTime int64     `json:"max_time"`
+	Bytes   uint64    `json:"bytes"`
+}
+
+// HealthReporter builds health reports of the bucket. Metas and sizes of blocks are cached between runs, since
+// blocks are immutable.
+type HealthReporter struct {
+	logger           log.Logger
+	bkt              objstore.Bucket
+	consistencyDelay time.Duration
+
+	metas map[ulid.ULID]*metadata.Meta
+	sizes map[ulid.ULID]uint64
+}
+
+// NewHealthReporter returns a new HealthReporter for the given bucket.
+func NewHealthReporter(logger log.Logger, bkt objstore.Bucket, consistencyDelay time.Duration) *HealthReporter {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return &HealthReporter{
+		logger:           logger,
+		bkt:              bkt,
+		consistencyDelay: consistencyDelay,
+		metas:            map[ulid.ULID]*metadata.Meta{},
+		sizes:            map[ulid.ULID]uint64{},
+	}
+}
+
+// Report builds the health report of the current bucket state.
+func (r *HealthReporter) Report(ctx context.Context) (*HealthReport, error) {
+	report := &HealthReport{
+		Version:       HealthReportVersion1,
+		Timestamp:     time.Now(),
+		Groups:        []GroupHealth{},
+		PartialBlocks: []ulid.ULID{},
+		LargestBlocks: []BlockHealth{},
+	}
+
+	remote := map[ulid.ULID]struct{}{}
+	if err := r.bkt.Iter(ctx, "", func(name string) error {
+		id, ok := block.IsBlockDir(name)
+		if !ok {
+			return nil
+		}
+		remote[id] = struct{}{}
+
+		if _, ok := r.metas[id]; ok {
+			return nil
+		}
+
+		meta, err := block.DownloadMeta(ctx, r.logger, r.bkt, id)
+		if err != nil {
+			if !r.bkt.IsObjNotFoundErr(errors.Cause(err)) {
+				return err
+			}
+			// Fresh blocks without meta.json may still be uploading.
+			if ulid.Now()-id.Time() >= uint64(r.consistencyDelay/time.Millisecond) {
+				report.PartialBlocks = append(report.PartialBlocks, id)
+			}
+			return nil
+		}
+
+		size, err := block.Size(ctx, r.bkt, id)
+		if err != nil {
+			return errors.Wrapf(err, "get size of block %s", id)
+		}
+		r.metas[id] = &meta
+		r.sizes[id] = size
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "iterate bucket")
+	}
+
+	// Forget blocks that were deleted from the bucket.
+	for id := range r.metas {
+		if _, ok := remote[id]; !ok {
+			delete(r.metas, id)
+			delete(r.sizes, id)
+		}
+	}
+
+	groups := map[string][]*metadata.Meta{}
+	for id, m := range r.metas {
+		key := GroupKey(*m)
+		groups[key] = append(groups[key], m)
+
+		report.LargestBlocks = append(report.LargestBlocks, BlockHealth{
+			ID:      id,
+			Group:   key,
+			MinTime: m.MinTime,
+			MaxTime: m.MaxTime,
+			Bytes:   r.sizes[id],
+		})
+		report.Bytes += r.sizes[id]
+	}
+
+	for key, metas := range groups {
+		report.Groups = append(report.Groups, r.groupHealth(key, metas))
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		return report.Groups[i].Key < report.Groups[j].Key
+	})
+
+	sort.Slice(report.PartialBlocks, func(i, j int) bool {
+		return report.PartialBlocks[i].Compare(report.PartialBlocks[j]) < 0
+	})
+
+	sort.Slice(report.LargestBlocks, func(i, j int) bool {
+		if report.LargestBlocks[i].Bytes == report.LargestBlocks[j].Bytes {
+			return report.LargestBlocks[i].ID.Compare(report.LargestBlocks[j].ID) < 0
+		}
+		return report.LargestBlocks[i].Bytes > report.LargestBlocks[j].Bytes
+	})
+	if len(report.LargestBlocks) > largestBlocksInReport {
+		report.LargestBlocks = report.LargestBlocks[:largestBlocksInReport]
+	}
+
+	return report, nil
+}
+
+func (r *HealthReporter) groupHealth(key string, metas []*metadata.Meta) GroupHealth {
+	sort.Slice(metas, func(i, j int) bool {
+		return metas[i].MinTime < metas[j].MinTime
+	})
+
+	g := GroupHealth{
+		Key:        key,
+		Labels:     metas[0].Thanos.Labels,
+		Resolution: metas[0].Thanos.Downsample.Resolution,
+		Blocks:     len(metas),
+		MinTime:    metas[0].MinTime,
+		MaxTime:    metas[0].MaxTime,
+		Overlaps:   []OverlapHealth{},
+		Gaps:       []TimeRange{},
+	}
+
+	bms := make([]tsdb.BlockMeta, 0, len(metas))
+	for _, m := range metas {
+		g.Bytes += r.sizes[m.ULID]
+		bms = append(bms, m.BlockMeta)
+
+		// Blocks are sorted by MinTime, so any uncovered space before the current block is a gap.
+		if m.MinTime > g.MaxTime {
+			g.Gaps = append(g.Gaps, TimeRange{MinTime: g.MaxTime, MaxTime: m.MinTime})
+		}
+		if m.MaxTime > g.MaxTime {
+			g.MaxTime = m.MaxTime
+		}
+	}
+
+	for tr, overlapping := range tsdb.OverlappingBlocks(bms) {
+		o := OverlapHealth{Range: TimeRange{MinTime: tr.Min, MaxTime: tr.Max}}
+		for _, m := range overlapping {
+			o.Blocks = append(o.Blocks, m.ULID)
+		}
+		g.Overlaps = append(g.Overlaps, o)
+	}
+	sort.Slice(g.Overlaps, func(i, j int) bool {
+		return g.Overlaps[i].Range.MinTime < g.Overlaps[j].Range.MinTime
+	})
+	return g
+}
+
+// WriteReport builds the health report and uploads it to the bucket under HealthReportPath.
+func (r *HealthReporter) WriteReport(ctx context.Context) error {
+	report, err := r.Report(ctx)
+	if err != nil {
+		return errors.Wrap(err, "build health report")
+	}
+
+	b, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "encode health report")
+	}
+	if err := r.bkt.Upload(ctx, HealthReportPath, bytes.NewReader(b)); err != nil {
+		return errors.Wrapf(err, "upload health report to %s", HealthReportPath)
+	}
+
+	level.Info(r.logger).Log("msg", "uploaded bucket health report", "groups", len(report.Groups),
+		"partialBlocks", len(report.PartialBlocks), "bytes", report.Bytes)
+	return nil
+}
@@ -0,0 +1,100 @@
+package compact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/block"
+	"github.com/improbable-eng/thanos/pkg/block/metadata"
+	"github.com/improbable-eng/thanos/pkg/objstore/inmem"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/tsdb"
+)
+
+func TestHealthReporter_Report(t *testing.T) {
+	ctx := context.Background()
+	bkt := inmem.NewBucket()
+
+	now := ulid.Now()
+	upload := func(ms uint64, minTime, maxTime int64, lset map[string]string, chunkContent string) ulid.ULID {
+		id := ulid.MustNew(ms, nil)
+		meta := metadata.Meta{
+			Version:   metadata.MetaVersion1,
+			BlockMeta: tsdb.BlockMeta{ULID: id, MinTime: minTime, MaxTime: maxTime},
+			Thanos:    metadata.Thanos{Labels: lset},
+		}
+		b, err := json.Marshal(meta)
+		testutil.Ok(t, err)
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(id.String(), block.MetaFilename), bytes.NewReader(b)))
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(id.String(), block.ChunksDirname, "000001"), strings.NewReader(chunkContent)))
+		return id
+	}
+
+	// Group a: two contiguous blocks, a gap and then two overlapping blocks.
+	a1 := upload(1, 0, 100, map[string]string{"a": "1"}, strings.Repeat("1", 10))
+	a2 := upload(2, 100, 200, map[string]string{"a": "1"}, strings.Repeat("2", 20))
+	a3 := upload(3, 300, 400, map[string]string{"a": "1"}, strings.Repeat("3", 30))
+	a4 := upload(4, 350, 450, map[string]string{"a": "1"}, strings.Repeat("4", 40))
+	// Group b: single block.
+	b1 := upload(5, 0, 1000, map[string]string{"a": "2"}, strings.Repeat("5", 50))
+
+	// Old block without meta is partial, fresh one is still uploading.
+	partial := ulid.MustNew(6, nil)
+	testutil.Ok(t, bkt.Upload(ctx, path.Join(partial.String(), block.ChunksDirname, "000001"), strings.NewReader("x")))
+	fresh := ulid.MustNew(now, nil)
+	testutil.Ok(t, bkt.Upload(ctx, path.Join(fresh.String(), block.ChunksDirname, "000001"), strings.NewReader("x")))
+
+	r := NewHealthReporter(nil, bkt, 30*time.Minute)
+	report, err := r.Report(ctx)
+	testutil.Ok(t, err)
+
+	size := func(id ulid.ULID) uint64 {
+		s, err := block.Size(ctx, bkt, id)
+		testutil.Ok(t, err)
+		return s
+	}
+
+	testutil.Equals(t, []ulid.ULID{partial}, report.PartialBlocks)
+	testutil.Equals(t, size(a1)+size(a2)+size(a3)+size(a4)+size(b1), report.Bytes)
+	testutil.Equals(t, 5, len(report.LargestBlocks))
+	testutil.Equals(t, b1, report.LargestBlocks[0].ID)
+	testutil.Equals(t, a1, report.LargestBlocks[4].ID)
+
+	testutil.Equals(t, 2, len(report.Groups))
+	ga := report.Groups[0]
+	testutil.Equals(t, "0@{a=\"1\"}", ga.Key)
+	testutil.Equals(t, 4, ga.Blocks)
+	testutil.Equals(t, int64(0), ga.MinTime)
+	testutil.Equals(t, int64(450), ga.MaxTime)
+	testutil.Equals(t, []TimeRange{{MinTime: 200, MaxTime: 300}}, ga.Gaps)
+	testutil.Equals(t, []OverlapHealth{{Range: TimeRange{MinTime: 350, MaxTime: 400}, Blocks: []ulid.ULID{a3, a4}}}, ga.Overlaps)
+
+	gb := report.Groups[1]
+	testutil.Equals(t, 1, gb.Blocks)
+	testutil.Equals(t, size(b1), gb.Bytes)
+	testutil.Equals(t, []TimeRange{}, gb.Gaps)
+	testutil.Equals(t, []OverlapHealth{}, gb.Overlaps)
+
+	// Deleted blocks disappear from the next report.
+	testutil.Ok(t, block.Delete(ctx, bkt, b1))
+	testutil.Ok(t, r.WriteReport(ctx))
+
+	rc, err := bkt.Get(ctx, HealthReportPath)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, rc.Close()) }()
+	b, err := ioutil.ReadAll(rc)
+	testutil.Ok(t, err)
+
+	var written HealthReport
+	testutil.Ok(t, json.Unmarshal(b, &written))
+	testutil.Equals(t, HealthReportVersion1, written.Version)
+	testutil.Equals(t, 1, len(written.Groups))
+	testutil.Equals(t, 4, len(written.LargestBlocks))
+}
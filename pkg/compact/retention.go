@@ -2,11 +2,13 @@ package compact
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/improbable-eng/thanos/pkg/block"
+	"github.com/improbable-eng/thanos/pkg/block/metadata"
 	"github.com/improbable-eng/thanos/pkg/objstore"
 	"github.com/pkg/errors"
 )
@@ -25,13 +27,13 @@ func ApplyRetentionPolicyByResolution(ctx context.Context, logger log.Logger, bk
 			return errors.Wrap(err, "download metadata")
 		}
 
-		retentionDuration := retentionByResolution[ResolutionLevel(m.Thanos.Downsample.Resolution)]
-		if retentionDuration.Seconds() == 0 {
+		deletionTime, ok := RetentionDeletionTime(&m, retentionByResolution)
+		if !ok {
 			return nil
 		}
 
-		maxTime := time.Unix(m.MaxTime/1000, 0)
-		if time.Now().After(maxTime.Add(retentionDuration)) {
+		if time.Now().After(deletionTime) {
+			maxTime := time.Unix(m.MaxTime/1000, 0)
 			level.Info(logger).Log("msg", "deleting block", "id", id, "maxTime", maxTime.String())
 			if err := block.Delete(ctx, bkt, id); err != nil {
 				return errors.Wrap(err, "delete block")
@@ -46,3 +48,39 @@ func ApplyRetentionPolicyByResolution(ctx context.Context, logger log.Logger, bk
 	level.Info(logger).Log("msg", "optional retention apply done")
 	return nil
 }
+
+// RetentionDeletionTime returns the time after which the retention policy deletes the given block.
+// It returns false if retention is disabled for the block's resolution.
+func RetentionDeletionTime(m *metadata.Meta, retentionByResolution map[ResolutionLevel]time.Duration) (time.Time, bool) {
+	retentionDuration := retentionByResolution[ResolutionLevel(m.Thanos.Downsample.Resolution)]
+	if retentionDuration.Seconds() == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(m.MaxTime/1000, 0).Add(retentionDuration), true
+}
+
+// RetentionCandidate is a block that the retention policy is going to delete.
+type RetentionCandidate struct {
+	Meta         *metadata.Meta
+	DeletionTime time.Time
+}
+
+// PlanRetentionByResolution returns blocks from the given metas which the retentionByResolution policy deletes
+// before the given time, sorted by deletion time. Nothing is deleted.
+func PlanRetentionByResolution(metas []*metadata.Meta, retentionByResolution map[ResolutionLevel]time.Duration, until time.Time) []RetentionCandidate {
+	var res []RetentionCandidate
+	for _, m := range metas {
+		deletionTime, ok := RetentionDeletionTime(m, retentionByResolution)
+		if !ok || deletionTime.After(until) {
+			continue
+		}
+		res = append(res, RetentionCandidate{Meta: m, DeletionTime: deletionTime})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].DeletionTime.Equal(res[j].DeletionTime) {
+			return res[i].Meta.ULID.Compare(res[j].Meta.ULID) < 0
+		}
+		return res[i].DeletionTime.Before(res[j].DeletionTime)
+	})
+	return res
+}
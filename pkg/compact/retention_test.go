@@ -251,6 +251,51 @@ func TestApplyRetentionPolicyByResolution(t *testing.T) {
 	}
 }
 
+func TestPlanRetentionByResolution(t *testing.T) {
+	now := time.Now()
+	newMeta := func(id string, maxTime time.Time, resolution compact.ResolutionLevel) *metadata.Meta {
+		return &metadata.Meta{
+			BlockMeta: tsdb.BlockMeta{ULID: ulid.MustParse(id), MaxTime: maxTime.Unix() * 1000},
+			Thanos:    metadata.Thanos{Downsample: metadata.ThanosDownsample{Resolution: int64(resolution)}},
+		}
+	}
+	metas := []*metadata.Meta{
+		newMeta("01CPHBEX20729MJQZXE3W0BW40", now.Add(-2*24*time.Hour), compact.ResolutionLevelRaw),
+		newMeta("01CPHBEX20729MJQZXE3W0BW41", now.Add(-3*24*time.Hour), compact.ResolutionLevelRaw),
+		newMeta("01CPHBEX20729MJQZXE3W0BW42", now.Add(-12*time.Hour), compact.ResolutionLevelRaw),
+		newMeta("01CPHBEX20729MJQZXE3W0BW43", now.Add(-6*24*time.Hour), compact.ResolutionLevel5m),
+		newMeta("01CPHBEX20729MJQZXE3W0BW44", now.Add(-30*24*time.Hour), compact.ResolutionLevel1h),
+	}
+	retentionByResolution := map[compact.ResolutionLevel]time.Duration{
+		compact.ResolutionLevelRaw: 24 * time.Hour,
+		compact.ResolutionLevel5m:  7 * 24 * time.Hour,
+	}
+
+	ids := func(cs []compact.RetentionCandidate) []string {
+		res := []string{}
+		for _, c := range cs {
+			res = append(res, c.Meta.ULID.String())
+		}
+		return res
+	}
+
+	// Only blocks already past retention.
+	got := compact.PlanRetentionByResolution(metas, retentionByResolution, now)
+	testutil.Equals(t, []string{"01CPHBEX20729MJQZXE3W0BW41", "01CPHBEX20729MJQZXE3W0BW40"}, ids(got))
+	testutil.Equals(t, time.Unix(metas[1].MaxTime/1000, 0).Add(24*time.Hour), got[0].DeletionTime)
+
+	// Blocks that will pass retention within the next 2 days. 1h resolution has retention disabled.
+	got = compact.PlanRetentionByResolution(metas, retentionByResolution, now.Add(2*24*time.Hour))
+	testutil.Equals(t, []string{
+		"01CPHBEX20729MJQZXE3W0BW41",
+		"01CPHBEX20729MJQZXE3W0BW40",
+		"01CPHBEX20729MJQZXE3W0BW42",
+		"01CPHBEX20729MJQZXE3W0BW43",
+	}, ids(got))
+
+	testutil.Equals(t, []string{}, ids(compact.PlanRetentionByResolution(metas, map[compact.ResolutionLevel]time.Duration{}, now.Add(365*24*time.Hour))))
+}
+
 func uploadMockBlock(t *testing.T, bkt objstore.Bucket, id string, minTime, maxTime time.Time, resolutionLevel int64) {
 	t.Helper()
 	meta1 := metadata.Meta{
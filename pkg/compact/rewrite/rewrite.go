@@ -0,0 +1,104 @@
+// Package rewrite implements rewriting of Thanos external labels of blocks already present in the bucket.
+// External labels are stored in meta.json only, so the series data is copied unchanged into a new block.
+package rewrite
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/block"
+	"github.com/improbable-eng/thanos/pkg/block/metadata"
+	"github.com/improbable-eng/thanos/pkg/objstore"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"gopkg.in/yaml.v2"
+)
+
+// ParseRelabelConfig parses relabel configs in the Prometheus relabel_configs YAML format.
+func ParseRelabelConfig(content []byte) ([]*relabel.Config, error) {
+	var cfgs []*relabel.Config
+	if err := yaml.UnmarshalStrict(content, &cfgs); err != nil {
+		return nil, errors.Wrap(err, "parsing relabel configuration")
+	}
+	return cfgs, nil
+}
+
+// RelabelExternalLabels returns the external labels of the given block after applying relabel configs.
+// It returns false if relabeling does not change the labels.
+func RelabelExternalLabels(meta *metadata.Meta, cfgs []*relabel.Config) (map[string]string, bool, error) {
+	lset := labels.FromMap(meta.Thanos.Labels)
+
+	res := relabel.Process(lset, cfgs...)
+	if len(res) == 0 {
+		return nil, false, errors.Errorf("relabel configuration drops all external labels of block %s", meta.ULID)
+	}
+	if labels.Equal(lset, res) {
+		return meta.Thanos.Labels, false, nil
+	}
+	return res.Map(), true, nil
+}
+
+// Block uploads a copy of the given block with its external labels rewritten by the relabel configs under a new ULID.
+// The block is downloaded to dir for the time of rewrite. It returns false if relabeling does not change the labels,
+// in which case nothing is uploaded. Deletion of the original block is left to the caller.
+func Block(
+	ctx context.Context,
+	logger log.Logger,
+	bkt objstore.Bucket,
+	dir string,
+	meta *metadata.Meta,
+	cfgs []*relabel.Config,
+) (newID ulid.ULID, changed bool, err error) {
+	newLabels, changed, err := RelabelExternalLabels(meta, cfgs)
+	if err != nil {
+		return newID, false, err
+	}
+	if !changed {
+		return newID, false, nil
+	}
+
+	newID = ulid.MustNew(ulid.Now(), rand.New(rand.NewSource(time.Now().UnixNano())))
+
+	bdir := filepath.Join(dir, newID.String())
+	defer func() {
+		if err := os.RemoveAll(bdir); err != nil {
+			level.Warn(logger).Log("msg", "failed to clean directory", "dir", bdir, "err", err)
+		}
+	}()
+
+	if err := block.Download(ctx, logger, bkt, meta.ULID, bdir); err != nil {
+		return newID, false, errors.Wrapf(err, "download block %s", meta.ULID)
+	}
+
+	// Drop the deletion mark in case the original block was already marked.
+	if err := os.RemoveAll(filepath.Join(bdir, metadata.DeletionMarkFilename)); err != nil {
+		return newID, false, errors.Wrap(err, "remove deletion mark")
+	}
+
+	newMeta, err := metadata.Read(bdir)
+	if err != nil {
+		return newID, false, errors.Wrapf(err, "read meta of block %s", meta.ULID)
+	}
+	newMeta.ULID = newID
+	newMeta.Thanos.Labels = newLabels
+	newMeta.Thanos.Source = metadata.BucketRewriteSource
+
+	if err := metadata.Write(logger, bdir, newMeta); err != nil {
+		return newID, false, errors.Wrap(err, "write new meta")
+	}
+
+	if err := block.Upload(ctx, logger, bkt, bdir); err != nil {
+		return newID, false, errors.Wrapf(err, "upload rewritten block %s", newID)
+	}
+	level.Info(logger).Log("msg", "rewrote block", "from", meta.ULID, "to", newID,
+		"labels", labels.FromMap(newLabels).String())
+
+	return newID, true, nil
+}
@@ -0,0 +1,120 @@
+package rewrite
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/block"
+	"github.com/improbable-eng/thanos/pkg/block/metadata"
+	"github.com/improbable-eng/thanos/pkg/objstore/inmem"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/tsdb/labels"
+)
+
+func TestRelabelExternalLabels(t *testing.T) {
+	cfgs, err := ParseRelabelConfig([]byte(`
+- action: replace
+  source_labels: [replica]
+  regex: (.+)
+  target_label: replica
+  replacement: rule-$1
+- action: labeldrop
+  regex: dc
+`))
+	testutil.Ok(t, err)
+
+	meta := &metadata.Meta{Thanos: metadata.Thanos{Labels: map[string]string{"replica": "a", "dc": "eu", "cluster": "one"}}}
+	lset, changed, err := RelabelExternalLabels(meta, cfgs)
+	testutil.Ok(t, err)
+	testutil.Assert(t, changed, "labels should be changed")
+	testutil.Equals(t, map[string]string{"replica": "rule-a", "cluster": "one"}, lset)
+
+	meta = &metadata.Meta{Thanos: metadata.Thanos{Labels: map[string]string{"cluster": "one"}}}
+	lset, changed, err = RelabelExternalLabels(meta, cfgs)
+	testutil.Ok(t, err)
+	testutil.Assert(t, !changed, "labels should not be changed")
+	testutil.Equals(t, map[string]string{"cluster": "one"}, lset)
+
+	cfgs, err = ParseRelabelConfig([]byte(`
+- action: labeldrop
+  regex: .*
+`))
+	testutil.Ok(t, err)
+	_, _, err = RelabelExternalLabels(meta, cfgs)
+	testutil.NotOk(t, err)
+
+	_, err = ParseRelabelConfig([]byte(`- action: replace\n  unknown_field: 1`))
+	testutil.NotOk(t, err)
+}
+
+func TestBlock(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "test-rewrite")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	bkt := inmem.NewBucket()
+	logger := log.NewNopLogger()
+
+	id, err := testutil.CreateBlock(ctx, dir, []labels.Labels{
+		{{Name: "a", Value: "1"}},
+		{{Name: "a", Value: "2"}},
+	}, 100, 0, 1000, labels.Labels{{Name: "cluster", Value: "old"}}, 0)
+	testutil.Ok(t, err)
+	testutil.Ok(t, block.Upload(ctx, logger, bkt, filepath.Join(dir, id.String())))
+	testutil.Ok(t, block.MarkForDeletion(ctx, logger, bkt, id))
+
+	meta, err := block.DownloadMeta(ctx, logger, bkt, id)
+	testutil.Ok(t, err)
+
+	cfgs, err := ParseRelabelConfig([]byte(`
+- action: replace
+  target_label: cluster
+  replacement: new
+`))
+	testutil.Ok(t, err)
+
+	tmpDir := filepath.Join(dir, "tmp")
+	newID, changed, err := Block(ctx, logger, bkt, tmpDir, &meta, cfgs)
+	testutil.Ok(t, err)
+	testutil.Assert(t, changed, "block should be rewritten")
+	testutil.Assert(t, newID != id, "rewritten block should have a new ULID")
+
+	newMeta, err := block.DownloadMeta(ctx, logger, bkt, newID)
+	testutil.Ok(t, err)
+	testutil.Equals(t, newID, newMeta.ULID)
+	testutil.Equals(t, map[string]string{"cluster": "new"}, newMeta.Thanos.Labels)
+	testutil.Equals(t, metadata.BucketRewriteSource, newMeta.Thanos.Source)
+	testutil.Equals(t, meta.MinTime, newMeta.MinTime)
+	testutil.Equals(t, meta.MaxTime, newMeta.MaxTime)
+	testutil.Equals(t, meta.Stats, newMeta.Stats)
+
+	exists, err := bkt.Exists(ctx, path.Join(newID.String(), block.IndexFilename))
+	testutil.Ok(t, err)
+	testutil.Assert(t, exists, "index of rewritten block should be uploaded")
+
+	exists, err = bkt.Exists(ctx, path.Join(newID.String(), metadata.DeletionMarkFilename))
+	testutil.Ok(t, err)
+	testutil.Assert(t, !exists, "rewritten block should not carry the deletion mark")
+
+	// Original block is left untouched.
+	oldMeta, err := block.DownloadMeta(ctx, logger, bkt, id)
+	testutil.Ok(t, err)
+	testutil.Equals(t, meta, oldMeta)
+
+	_, err = os.Stat(filepath.Join(tmpDir, newID.String()))
+	testutil.Assert(t, os.IsNotExist(err), "temporary block directory should be removed")
+
+	// Relabeling to the same labels is a noop.
+	_, changed, err = Block(ctx, logger, bkt, tmpDir, &newMeta, cfgs)
+	testutil.Ok(t, err)
+	testutil.Assert(t, !changed, "block should not be rewritten")
+}
@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+)
+
+func TestCache(t *testing.T) {
+	c := New()
+	group := func(source string, addrs ...string) *targetgroup.Group {
+		tg := &targetgroup.Group{Source: source}
+		for _, a := range addrs {
+			tg.Targets = append(tg.Targets, model.LabelSet{model.AddressLabel: model.LabelValue(a)})
+		}
+		return tg
+	}
+	addresses := func() []string {
+		res := c.Addresses()
+		sort.Strings(res)
+		return res
+	}
+
+	c.Update([]*targetgroup.Group{group("a.yaml:0", "store-1:10901", "store-2:10901"), group("b.json:0", "dns+store-3:10901"), nil})
+	testutil.Equals(t, []string{"dns+store-3:10901", "store-1:10901", "store-2:10901"}, addresses())
+
+	// Groups are replaced entirely, empty groups are removed files.
+	c.Update([]*targetgroup.Group{group("a.yaml:0", "store-1:10901"), group("b.json:0")})
+	testutil.Equals(t, []string{"store-1:10901"}, addresses())
+}
@@ -15,11 +15,15 @@ import (
 // Provider is a stateful cache for asynchronous DNS resolutions. It provides a way to resolve addresses and obtain them.
 type Provider struct {
 	sync.Mutex
-	resolver Resolver
+	// resolveMtx serializes resolutions, which are done without holding the lock so that slow lookups do not
+	// block readers of the addresses.
+	resolveMtx sync.Mutex
+	resolver   Resolver
 	// A map from domain name to a slice of resolved targets.
 	resolved map[string][]string
 	logger   log.Logger
 
+	resolverAddrs         *prometheus.GaugeVec
 	resolverLookupsCount  prometheus.Counter
 	resolverFailuresCount prometheus.Counter
 }
@@ -52,6 +56,10 @@ func NewProvider(logger log.Logger, reg prometheus.Registerer, resolverType Reso
 		resolver: NewResolver(resolverType.ToResolver(logger)),
 		resolved: make(map[string][]string),
 		logger:   logger,
+		resolverAddrs: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "dns_provider_results",
+			Help: "The number of resolved endpoints for each configured address",
+		}, []string{"addr"}),
 		resolverLookupsCount: prometheus.NewCounter(prometheus.CounterOpts{
 			Name: "dns_lookups_total",
 			Help: "The number of DNS lookups resolutions attempts",
@@ -63,6 +71,7 @@ func NewProvider(logger log.Logger, reg prometheus.Registerer, resolverType Reso
 	}
 
 	if reg != nil {
+		reg.MustRegister(p.resolverAddrs)
 		reg.MustRegister(p.resolverLookupsCount)
 		reg.MustRegister(p.resolverFailuresCount)
 	}
@@ -74,40 +83,50 @@ func NewProvider(logger log.Logger, reg prometheus.Registerer, resolverType Reso
 // Addresses prefixed with `dns+` or `dnssrv+` will be resolved through respective DNS lookup (A/AAAA or SRV).
 // defaultPort is used for non-SRV records when a port is not supplied.
 func (p *Provider) Resolve(ctx context.Context, addrs []string) {
+	p.resolveMtx.Lock()
+	defer p.resolveMtx.Unlock()
+
 	p.Lock()
-	defer p.Unlock()
+	old := p.resolved
+	p.Unlock()
 
+	resolved := make(map[string][]string, len(addrs))
 	for _, addr := range addrs {
-		var resolved []string
 		qtypeAndName := strings.SplitN(addr, "+", 2)
 		if len(qtypeAndName) != 2 {
 			// No lookup specified. Add to results and continue to the next address.
-			p.resolved[addr] = []string{addr}
+			resolved[addr] = []string{addr}
 			continue
 		}
 		qtype, name := qtypeAndName[0], qtypeAndName[1]
 
-		resolved, err := p.resolver.Resolve(ctx, name, QType(qtype))
+		res, err := p.resolver.Resolve(ctx, name, QType(qtype))
 		p.resolverLookupsCount.Inc()
 		if err != nil {
 			// The DNS resolution failed. Continue without modifying the old records.
 			p.resolverFailuresCount.Inc()
 			level.Error(p.logger).Log("msg", "dns resolution failed", "addr", addr, "err", err)
+			if prev, ok := old[addr]; ok {
+				resolved[addr] = prev
+			}
 			continue
 		}
-		p.resolved[addr] = resolved
+		resolved[addr] = res
 	}
 
-	// Remove stored addresses that are no longer requested.
-	var entriesToDelete []string
-	for existingAddr := range p.resolved {
-		if !contains(addrs, existingAddr) {
-			entriesToDelete = append(entriesToDelete, existingAddr)
+	// Addresses that are no longer requested are not carried over.
+	for addr := range old {
+		if _, ok := resolved[addr]; !ok {
+			p.resolverAddrs.DeleteLabelValues(addr)
 		}
 	}
-	for _, toDelete := range entriesToDelete {
-		delete(p.resolved, toDelete)
+	for addr, res := range resolved {
+		p.resolverAddrs.WithLabelValues(addr).Set(float64(len(res)))
 	}
+
+	p.Lock()
+	p.resolved = resolved
+	p.Unlock()
 }
 
 // Addresses returns the latest addresses present in the Provider.
@@ -121,12 +140,3 @@ func (p *Provider) Addresses() []string {
 	}
 	return result
 }
-
-func contains(slice []string, str string) bool {
-	for _, s := range slice {
-		if str == s {
-			return true
-		}
-	}
-	return false
-}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/improbable-eng/thanos/pkg/testutil"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestProvider(t *testing.T) {
@@ -60,6 +61,8 @@ func TestProvider(t *testing.T) {
 	result = prv.Addresses()
 	sort.Strings(result)
 	testutil.Equals(t, ips, result)
+	testutil.Equals(t, 2.0, promtest.ToFloat64(prv.resolverAddrs.WithLabelValues("any+a")))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(prv.resolverAddrs.WithLabelValues("any+c")))
 }
 
 type mockResolver struct {
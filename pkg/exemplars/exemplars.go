@@ -0,0 +1,141 @@
+// Package exemplars implements the federation of the exemplars API of Prometheus.
+package exemplars
+
+import (
+	"context"
+	"sort"
+
+	"github.com/improbable-eng/thanos/pkg/exemplars/exemplarspb"
+	"github.com/improbable-eng/thanos/pkg/federation"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/pkg/errors"
+)
+
+// GRPCClient queries an exemplars server, e.g. the Proxy, within the same process and deduplicates the exemplars
+// of replicas.
+type GRPCClient struct {
+	proxy         exemplarspb.ExemplarsServer
+	replicaLabels federation.ReplicaLabels
+}
+
+// NewGRPCClient returns a new GRPCClient merging the exemplars of series that only differ in the given replica labels.
+func NewGRPCClient(es exemplarspb.ExemplarsServer, replicaLabels []string) *GRPCClient {
+	return &GRPCClient{proxy: es, replicaLabels: federation.NewReplicaLabels(replicaLabels)}
+}
+
+type exemplarsServer struct {
+	// This field just exist to pseudo-implement the unused methods of the interface.
+	exemplarspb.Exemplars_ExemplarsServer
+	ctx context.Context
+
+	data     []*exemplarspb.ExemplarData
+	warnings []error
+}
+
+func (s *exemplarsServer) Send(r *exemplarspb.ExemplarsResponse) error {
+	if w := r.GetWarning(); w != "" {
+		s.warnings = append(s.warnings, errors.New(w))
+		return nil
+	}
+	if d := r.GetData(); d != nil {
+		s.data = append(s.data, d)
+		return nil
+	}
+	return errors.New("no exemplar data or warning")
+}
+
+func (s *exemplarsServer) Context() context.Context {
+	return s.ctx
+}
+
+// Exemplars returns the exemplars of the series selected by the request. If dedup is true, the replica labels are
+// removed from the series labels and equal exemplars of replicas are returned once.
+func (c *GRPCClient) Exemplars(ctx context.Context, r *exemplarspb.ExemplarsRequest, dedup bool) ([]*exemplarspb.ExemplarData, []error, error) {
+	resp := &exemplarsServer{ctx: ctx}
+	if err := c.proxy.Exemplars(r, resp); err != nil {
+		return nil, nil, errors.Wrap(err, "proxy Exemplars()")
+	}
+
+	replicaLabels := c.replicaLabels
+	if !dedup {
+		replicaLabels = nil
+	}
+	return dedupExemplarData(resp.data, replicaLabels), resp.warnings, nil
+}
+
+// dedupExemplarData removes the replica labels from the series labels and merges the exemplars of equal series.
+// Equal exemplars of replicas are kept once. The result is sorted by series labels, the exemplars by timestamp.
+func dedupExemplarData(data []*exemplarspb.ExemplarData, replicaLabels federation.ReplicaLabels) []*exemplarspb.ExemplarData {
+	if len(data) == 0 {
+		return []*exemplarspb.ExemplarData{}
+	}
+
+	for _, d := range data {
+		d.SeriesLabels = replicaLabels.Remove(d.SeriesLabels)
+		sortLabels(d.SeriesLabels)
+	}
+	sort.SliceStable(data, func(i, j int) bool {
+		return storepb.CompareLabels(data[i].SeriesLabels, data[j].SeriesLabels) < 0
+	})
+
+	res := data[:1]
+	for _, d := range data[1:] {
+		last := res[len(res)-1]
+		if storepb.CompareLabels(last.SeriesLabels, d.SeriesLabels) != 0 {
+			res = append(res, d)
+			continue
+		}
+		last.Exemplars = append(last.Exemplars, d.Exemplars...)
+	}
+
+	for _, d := range res {
+		d.Exemplars = dedupExemplars(d.Exemplars)
+	}
+	return res
+}
+
+// dedupExemplars sorts the exemplars by timestamp and removes duplicates.
+func dedupExemplars(exemplars []exemplarspb.Exemplar) []exemplarspb.Exemplar {
+	for _, e := range exemplars {
+		sortLabels(e.Labels)
+	}
+	sort.SliceStable(exemplars, func(i, j int) bool {
+		return compareExemplars(exemplars[i], exemplars[j]) < 0
+	})
+
+	if len(exemplars) == 0 {
+		return exemplars
+	}
+	res := exemplars[:1]
+	for _, e := range exemplars[1:] {
+		if compareExemplars(res[len(res)-1], e) != 0 {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func compareExemplars(a, b exemplarspb.Exemplar) int {
+	if a.Ts != b.Ts {
+		if a.Ts < b.Ts {
+			return -1
+		}
+		return 1
+	}
+	if c := storepb.CompareLabels(a.Labels, b.Labels); c != 0 {
+		return c
+	}
+	if a.Value != b.Value {
+		if a.Value < b.Value {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func sortLabels(lset []storepb.Label) {
+	sort.Slice(lset, func(i, j int) bool {
+		return lset[i].Name < lset[j].Name
+	})
+}
@@ -0,0 +1,111 @@
+package exemplars
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/exemplars/exemplarspb"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func lbls(kv ...string) []storepb.Label {
+	var lset []storepb.Label
+	for i := 0; i < len(kv); i += 2 {
+		lset = append(lset, storepb.Label{Name: kv[i], Value: kv[i+1]})
+	}
+	return lset
+}
+
+type testExemplarsClient struct {
+	resps []*exemplarspb.ExemplarsResponse
+	err   error
+}
+
+func (c *testExemplarsClient) Exemplars(ctx context.Context, r *exemplarspb.ExemplarsRequest, _ ...grpc.CallOption) (exemplarspb.Exemplars_ExemplarsClient, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &testExemplarsStream{resps: c.resps}, nil
+}
+
+type testExemplarsStream struct {
+	grpc.ClientStream
+	resps []*exemplarspb.ExemplarsResponse
+}
+
+func (s *testExemplarsStream) Recv() (*exemplarspb.ExemplarsResponse, error) {
+	if len(s.resps) == 0 {
+		return nil, io.EOF
+	}
+	r := s.resps[0]
+	s.resps = s.resps[1:]
+	return r, nil
+}
+
+func TestGRPCClient_Exemplars(t *testing.T) {
+	replica := func(replica string) []*exemplarspb.ExemplarsResponse {
+		return []*exemplarspb.ExemplarsResponse{
+			exemplarspb.NewExemplarsResponse(&exemplarspb.ExemplarData{
+				SeriesLabels: lbls("__name__", "http_requests_total", "replica", replica),
+				Exemplars: []exemplarspb.Exemplar{
+					{Labels: lbls("trace_id", "b"), Value: 2, Ts: 2000},
+					{Labels: lbls("trace_id", "a"), Value: 1, Ts: 1000},
+				},
+			}),
+		}
+	}
+	proxy := NewProxy(nil, func() []Client {
+		return []Client{
+			{ExemplarsClient: &testExemplarsClient{resps: replica("1")}, Addr: "1"},
+			{ExemplarsClient: &testExemplarsClient{resps: replica("2")}, Addr: "2"},
+			// Stores without exemplars API are skipped silently.
+			{ExemplarsClient: &testExemplarsClient{err: status.Error(codes.Unimplemented, "unknown service")}, Addr: "3"},
+			{ExemplarsClient: &testExemplarsClient{err: errors.New("unavailable")}, Addr: "4"},
+		}
+	})
+	c := NewGRPCClient(proxy, []string{"replica"})
+
+	res, warnings, err := c.Exemplars(context.Background(), &exemplarspb.ExemplarsRequest{Query: "http_requests_total"}, true)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(warnings))
+	testutil.Equals(t, []*exemplarspb.ExemplarData{{
+		SeriesLabels: lbls("__name__", "http_requests_total"),
+		Exemplars: []exemplarspb.Exemplar{
+			{Labels: lbls("trace_id", "a"), Value: 1, Ts: 1000},
+			{Labels: lbls("trace_id", "b"), Value: 2, Ts: 2000},
+		},
+	}}, res)
+
+	res, _, err = c.Exemplars(context.Background(), &exemplarspb.ExemplarsRequest{Query: "http_requests_total"}, false)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(res))
+
+	// Failing endpoints fail the whole request with the abort strategy.
+	_, _, err = c.Exemplars(context.Background(), &exemplarspb.ExemplarsRequest{
+		Query:                   "http_requests_total",
+		PartialResponseStrategy: storepb.PartialResponseStrategy_ABORT,
+	}, true)
+	testutil.NotOk(t, err)
+}
+
+func TestExemplarData_JSON(t *testing.T) {
+	b := []byte(`{"seriesLabels":{"__name__":"up","job":"a"},"exemplars":[{"labels":{"trace_id":"x"},"value":"1.5","timestamp":1600096945.479}]}`)
+
+	var d exemplarspb.ExemplarData
+	testutil.Ok(t, json.Unmarshal(b, &d))
+	testutil.Equals(t, exemplarspb.ExemplarData{
+		SeriesLabels: lbls("__name__", "up", "job", "a"),
+		Exemplars:    []exemplarspb.Exemplar{{Labels: lbls("trace_id", "x"), Value: 1.5, Ts: 1600096945479}},
+	}, d)
+
+	out, err := json.Marshal(&d)
+	testutil.Ok(t, err)
+	testutil.Equals(t, string(b), string(out))
+}
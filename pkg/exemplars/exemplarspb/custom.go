@@ -0,0 +1,77 @@
+package exemplarspb
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/pkg/errors"
+)
+
+func NewWarningExemplarsResponse(err error) *ExemplarsResponse {
+	return &ExemplarsResponse{
+		Result: &ExemplarsResponse_Warning{
+			Warning: err.Error(),
+		},
+	}
+}
+
+func NewExemplarsResponse(e *ExemplarData) *ExemplarsResponse {
+	return &ExemplarsResponse{
+		Result: &ExemplarsResponse_Data{
+			Data: e,
+		},
+	}
+}
+
+// exemplarDataJSON is the JSON format of exemplar data of the Prometheus exemplars API.
+type exemplarDataJSON struct {
+	SeriesLabels map[string]string `json:"seriesLabels"`
+	Exemplars    []exemplarJSON    `json:"exemplars"`
+}
+
+type exemplarJSON struct {
+	Labels    map[string]string `json:"labels"`
+	Value     string            `json:"value"`
+	Timestamp float64           `json:"timestamp"`
+}
+
+// MarshalJSON marshals the exemplar data the way the Prometheus exemplars API does.
+func (m *ExemplarData) MarshalJSON() ([]byte, error) {
+	d := exemplarDataJSON{
+		SeriesLabels: storepb.LabelsToMap(m.SeriesLabels),
+		Exemplars:    make([]exemplarJSON, 0, len(m.Exemplars)),
+	}
+	for _, e := range m.Exemplars {
+		d.Exemplars = append(d.Exemplars, exemplarJSON{
+			Labels:    storepb.LabelsToMap(e.Labels),
+			Value:     strconv.FormatFloat(e.Value, 'f', -1, 64),
+			Timestamp: float64(e.Ts) / 1000,
+		})
+	}
+	return json.Marshal(d)
+}
+
+// UnmarshalJSON unmarshals exemplar data of the Prometheus exemplars API.
+func (m *ExemplarData) UnmarshalJSON(b []byte) error {
+	var d exemplarDataJSON
+	if err := json.Unmarshal(b, &d); err != nil {
+		return err
+	}
+
+	m.SeriesLabels = storepb.LabelsFromMap(d.SeriesLabels)
+	m.Exemplars = make([]Exemplar, 0, len(d.Exemplars))
+	for _, e := range d.Exemplars {
+		v, err := strconv.ParseFloat(e.Value, 64)
+		if err != nil {
+			return errors.Wrapf(err, "parse exemplar value %q", e.Value)
+		}
+		m.Exemplars = append(m.Exemplars, Exemplar{
+			Labels: storepb.LabelsFromMap(e.Labels),
+			Value:  v,
+			Ts:     int64(math.Round(e.Timestamp * 1000)),
+		})
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package exemplars
+
+import (
+	"net/url"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/exemplars/exemplarspb"
+	"github.com/improbable-eng/thanos/pkg/promclient"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/prometheus/tsdb/labels"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Prometheus implements exemplarspb.ExemplarsServer on top of the exemplars API of Prometheus.
+type Prometheus struct {
+	logger         log.Logger
+	base           *url.URL
+	externalLabels func() labels.Labels
+}
+
+// NewPrometheus creates a new exemplars server querying the Prometheus at the given URL. The external labels are
+// attached to the labels of all series.
+func NewPrometheus(logger log.Logger, base *url.URL, externalLabels func() labels.Labels) *Prometheus {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return &Prometheus{
+		logger:         logger,
+		base:           base,
+		externalLabels: externalLabels,
+	}
+}
+
+// Exemplars returns the exemplars of the series selected by the requested query.
+func (p *Prometheus) Exemplars(r *exemplarspb.ExemplarsRequest, s exemplarspb.Exemplars_ExemplarsServer) error {
+	exemplars, err := promclient.QueryExemplars(s.Context(), p.logger, p.base, r.Query, r.Start, r.End)
+	if err != nil {
+		return status.Error(codes.Unknown, err.Error())
+	}
+
+	ext := p.externalLabels()
+	for _, e := range exemplars {
+		e.SeriesLabels = extendLabels(e.SeriesLabels, ext)
+		if err := s.Send(exemplarspb.NewExemplarsResponse(e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extendLabels returns the given sorted labels with the external labels attached, overwriting existing ones on
+// collision.
+func extendLabels(lset []storepb.Label, ext labels.Labels) []storepb.Label {
+	res := make([]storepb.Label, 0, len(lset)+len(ext))
+	for _, l := range lset {
+		if ext.Get(l.Name) != "" {
+			continue
+		}
+		res = append(res, l)
+	}
+	for _, l := range ext {
+		res = append(res, storepb.Label{Name: l.Name, Value: l.Value})
+	}
+	sortLabels(res)
+	return res
+}
@@ -0,0 +1,54 @@
+package exemplars
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/exemplars/exemplarspb"
+	"github.com/improbable-eng/thanos/pkg/federation"
+)
+
+// Client is an exemplars API client of a single endpoint.
+type Client struct {
+	exemplarspb.ExemplarsClient
+
+	// Addr is used for errors and warnings.
+	Addr string
+}
+
+// Proxy implements exemplarspb.ExemplarsServer by fanning requests out to the exemplars APIs of all given endpoints.
+type Proxy struct {
+	logger  log.Logger
+	clients func() []Client
+}
+
+// NewProxy returns a new exemplars proxy over the clients returned by the given function.
+func NewProxy(logger log.Logger, clients func() []Client) *Proxy {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return &Proxy{
+		logger:  logger,
+		clients: clients,
+	}
+}
+
+// Exemplars returns the exemplars of all endpoints. Endpoints not implementing the exemplars API are skipped.
+func (p *Proxy) Exemplars(r *exemplarspb.ExemplarsRequest, s exemplarspb.Exemplars_ExemplarsServer) error {
+	clients := p.clients()
+	streams := make([]federation.Stream, 0, len(clients))
+	for _, c := range clients {
+		c := c
+		streams = append(streams, federation.Stream{Addr: c.Addr, Open: func(ctx context.Context) (func() (interface{}, error), error) {
+			stream, err := c.Exemplars(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+			return func() (interface{}, error) { return stream.Recv() }, nil
+		}})
+	}
+	return federation.FanOut(s.Context(), p.logger, "exemplars", r.PartialResponseStrategy, streams,
+		func(resp interface{}) error { return s.Send(resp.(*exemplarspb.ExemplarsResponse)) },
+		func(err error) interface{} { return exemplarspb.NewWarningExemplarsResponse(err) },
+	)
+}
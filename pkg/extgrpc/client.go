@@ -0,0 +1,93 @@
+package extgrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSConfig configures TLS of gRPC clients.
+type TLSConfig struct {
+	// CAFile is the CA certificates file to verify servers with. The system certificate pool is used if empty.
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile are the client certificate and key presented to servers, if set.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ServerName is the name to verify the hostname of server certificates with.
+	ServerName string `yaml:"server_name"`
+}
+
+// ClientTLSConfig returns the client TLS configuration described by the given config.
+func ClientTLSConfig(logger log.Logger, conf TLSConfig) (*tls.Config, error) {
+	var certPool *x509.CertPool
+
+	if conf.CAFile != "" {
+		caPEM, err := ioutil.ReadFile(conf.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading client CA")
+		}
+
+		certPool = x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.Errorf("building client CA from %s: no certificates found", conf.CAFile)
+		}
+		level.Info(logger).Log("msg", "TLS Client using provided certificate pool", "ca", conf.CAFile)
+	} else {
+		var err error
+		certPool, err = x509.SystemCertPool()
+		if err != nil {
+			return nil, errors.Wrap(err, "reading system certificate pool")
+		}
+		level.Info(logger).Log("msg", "TLS Client using system certificate pool")
+	}
+
+	tlsCfg := &tls.Config{
+		RootCAs: certPool,
+	}
+
+	if conf.ServerName != "" {
+		tlsCfg.ServerName = conf.ServerName
+	}
+
+	if conf.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "client credentials")
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+		level.Info(logger).Log("msg", "TLS Client authentication enabled")
+	}
+	return tlsCfg, nil
+}
+
+// bearerToken sends a bearer token with every call. Tokens are only sent over TLS connections.
+type bearerToken string
+
+// NewBearerTokenCredentials returns per RPC credentials sending the given bearer token in the authorization header.
+func NewBearerTokenCredentials(token string) credentials.PerRPCCredentials {
+	return bearerToken(token)
+}
+
+// NewBearerTokenFileCredentials returns per RPC credentials sending the bearer token read from the given file.
+func NewBearerTokenFileCredentials(file string) (credentials.PerRPCCredentials, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read bearer token file %s", file)
+	}
+	return bearerToken(strings.TrimSpace(string(b))), nil
+}
+
+func (t bearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t bearerToken) RequireTransportSecurity() bool {
+	return true
+}
@@ -0,0 +1,31 @@
+package extgrpc
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+)
+
+func TestBearerTokenCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bearer-token")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	f := filepath.Join(dir, "token")
+	testutil.Ok(t, ioutil.WriteFile(f, []byte("secret\n"), 0600))
+
+	creds, err := NewBearerTokenFileCredentials(f)
+	testutil.Ok(t, err)
+	testutil.Assert(t, creds.RequireTransportSecurity(), "bearer tokens must not be sent without TLS")
+
+	md, err := creds.GetRequestMetadata(context.Background())
+	testutil.Ok(t, err)
+	testutil.Equals(t, map[string]string{"authorization": "Bearer secret"}, md)
+
+	_, err = NewBearerTokenFileCredentials(filepath.Join(dir, "missing"))
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,100 @@
+// Package extgrpc contains extensions of gRPC shared by Thanos components.
+package extgrpc
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// Compressions of gRPC messages supported by Thanos components.
+const (
+	CompressionNone   = "none"
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+// Compressions lists all supported compressions.
+var Compressions = []string{CompressionNone, CompressionSnappy, CompressionZstd}
+
+func init() {
+	// Servers respond with the compression requested by the client, so every component serving gRPC supports all of them.
+	encoding.RegisterCompressor(newBufferedCompressor(CompressionSnappy,
+		func(b []byte) []byte { return snappy.Encode(nil, b) },
+		func(b []byte) ([]byte, error) { return snappy.Decode(nil, b) },
+	))
+
+	// Encoder and decoder used for whole messages are safe for concurrent use.
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		panic(err)
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		panic(err)
+	}
+	encoding.RegisterCompressor(newBufferedCompressor(CompressionZstd,
+		func(b []byte) []byte { return enc.EncodeAll(b, nil) },
+		func(b []byte) ([]byte, error) { return dec.DecodeAll(b, nil) },
+	))
+}
+
+// CallOption returns the gRPC call option requesting the given compression for requests and responses.
+func CallOption(compression string) (grpc.CallOption, error) {
+	switch compression {
+	case CompressionNone:
+		return grpc.EmptyCallOption{}, nil
+	case CompressionSnappy, CompressionZstd:
+		return grpc.UseCompressor(compression), nil
+	}
+	return nil, errors.Errorf("unknown gRPC compression %q", compression)
+}
+
+// bufferedCompressor compresses whole messages, which gRPC buffers in memory anyway.
+type bufferedCompressor struct {
+	name   string
+	encode func([]byte) []byte
+	decode func([]byte) ([]byte, error)
+}
+
+func newBufferedCompressor(name string, encode func([]byte) []byte, decode func([]byte) ([]byte, error)) *bufferedCompressor {
+	return &bufferedCompressor{name: name, encode: encode, decode: decode}
+}
+
+func (c *bufferedCompressor) Name() string { return c.name }
+
+func (c *bufferedCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return &compressWriter{w: w, encode: c.encode}, nil
+}
+
+func (c *bufferedCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s message", c.name)
+	}
+	b, err = c.decode(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decompress %s message", c.name)
+	}
+	return bytes.NewReader(b), nil
+}
+
+// compressWriter writes the compressed message on Close.
+type compressWriter struct {
+	buf    bytes.Buffer
+	w      io.Writer
+	encode func([]byte) []byte
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) { return c.buf.Write(p) }
+
+func (c *compressWriter) Close() error {
+	_, err := c.w.Write(c.encode(c.buf.Bytes()))
+	return err
+}
@@ -0,0 +1,48 @@
+package extgrpc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"google.golang.org/grpc/encoding"
+)
+
+func TestCompressors(t *testing.T) {
+	msg := bytes.Repeat([]byte("series chunk data "), 1000)
+
+	for _, name := range []string{CompressionSnappy, CompressionZstd} {
+		t.Run(name, func(t *testing.T) {
+			c := encoding.GetCompressor(name)
+			testutil.Assert(t, c != nil, "compressor not registered")
+
+			var buf bytes.Buffer
+			w, err := c.Compress(&buf)
+			testutil.Ok(t, err)
+			_, err = w.Write(msg[:100])
+			testutil.Ok(t, err)
+			_, err = w.Write(msg[100:])
+			testutil.Ok(t, err)
+			testutil.Ok(t, w.Close())
+			testutil.Assert(t, buf.Len() < len(msg), "message not compressed")
+
+			r, err := c.Decompress(&buf)
+			testutil.Ok(t, err)
+			res, err := ioutil.ReadAll(r)
+			testutil.Ok(t, err)
+			testutil.Equals(t, msg, res)
+
+			_, err = c.Decompress(bytes.NewReader([]byte("garbage")))
+			testutil.NotOk(t, err)
+
+			_, err = CallOption(name)
+			testutil.Ok(t, err)
+		})
+	}
+
+	_, err := CallOption(CompressionNone)
+	testutil.Ok(t, err)
+	_, err = CallOption("gzip")
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,91 @@
+// Package federation implements what the federated APIs of the querier share: fanning requests out to the
+// streaming APIs of all endpoints and removing replica labels to deduplicate the responses of replicas.
+package federation
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Stream is the response stream of a single endpoint.
+type Stream struct {
+	// Addr is used for errors and warnings.
+	Addr string
+	// Open sends the request to the endpoint. The returned function receives the next response and returns io.EOF
+	// once all responses were received.
+	Open func(ctx context.Context) (recv func() (interface{}, error), err error)
+}
+
+// FanOut receives the responses of all streams concurrently and passes them to send, one at a time. Endpoints not
+// implementing the API are skipped. With the abort strategy, a failing endpoint fails the whole request. Otherwise
+// the error is logged and the response returned by warning is sent instead. The api names the API in errors.
+func FanOut(
+	ctx context.Context,
+	logger log.Logger,
+	api string,
+	strategy storepb.PartialResponseStrategy,
+	streams []Stream,
+	send func(resp interface{}) error,
+	warning func(err error) interface{},
+) error {
+	var (
+		g, gctx = errgroup.WithContext(ctx)
+		sendMtx sync.Mutex
+	)
+	syncSend := func(resp interface{}) error {
+		sendMtx.Lock()
+		defer sendMtx.Unlock()
+		return send(resp)
+	}
+
+	for _, st := range streams {
+		st := st
+		g.Go(func() error {
+			err := fetch(gctx, api, st, syncSend)
+			if err == nil {
+				return nil
+			}
+			if strategy == storepb.PartialResponseStrategy_ABORT {
+				return err
+			}
+			level.Warn(logger).Log("msg", "fetching "+api+" failed", "address", st.Addr, "err", err)
+			return syncSend(warning(err))
+		})
+	}
+	return g.Wait()
+}
+
+func fetch(ctx context.Context, api string, st Stream, send func(interface{}) error) error {
+	recv, err := st.Open(ctx)
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return nil
+		}
+		return errors.Wrapf(err, "fetch %s from %s", api, st.Addr)
+	}
+
+	for {
+		resp, err := recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if status.Code(err) == codes.Unimplemented {
+				return nil
+			}
+			return errors.Wrapf(err, "receive %s from %s", api, st.Addr)
+		}
+		if err := send(resp); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package federation
+
+import (
+	"context"
+	"io"
+	"sort"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testStream(addr string, openErr error, resps ...string) Stream {
+	return Stream{Addr: addr, Open: func(context.Context) (func() (interface{}, error), error) {
+		if openErr != nil {
+			return nil, openErr
+		}
+		return func() (interface{}, error) {
+			if len(resps) == 0 {
+				return nil, io.EOF
+			}
+			r := resps[0]
+			resps = resps[1:]
+			return r, nil
+		}, nil
+	}}
+}
+
+func TestFanOut(t *testing.T) {
+	streams := func() []Stream {
+		return []Stream{
+			testStream("1", nil, "a", "b"),
+			testStream("2", nil, "c"),
+			// Endpoints without the API are skipped silently.
+			testStream("3", status.Error(codes.Unimplemented, "unknown service")),
+			testStream("4", errors.New("unavailable")),
+		}
+	}
+
+	var got []string
+	send := func(resp interface{}) error {
+		got = append(got, resp.(string))
+		return nil
+	}
+	warning := func(err error) interface{} { return "warning: " + err.Error() }
+
+	testutil.Ok(t, FanOut(context.Background(), log.NewNopLogger(), "test", storepb.PartialResponseStrategy_WARN, streams(), send, warning))
+	sort.Strings(got)
+	testutil.Equals(t, []string{"a", "b", "c", "warning: fetch test from 4: unavailable"}, got)
+
+	// Failing endpoints fail the whole request with the abort strategy.
+	testutil.NotOk(t, FanOut(context.Background(), log.NewNopLogger(), "test", storepb.PartialResponseStrategy_ABORT, streams(), send, warning))
+}
+
+func TestReplicaLabels_Remove(t *testing.T) {
+	lset := []storepb.Label{{Name: "a", Value: "1"}, {Name: "replica", Value: "x"}}
+
+	testutil.Equals(t, []storepb.Label{{Name: "a", Value: "1"}}, NewReplicaLabels([]string{"replica"}).Remove(lset))
+	testutil.Equals(t, lset, NewReplicaLabels(nil).Remove(lset))
+	testutil.Equals(t, storepb.Label{Name: "replica", Value: "x"}, lset[1])
+}
@@ -0,0 +1,29 @@
+package federation
+
+import "github.com/improbable-eng/thanos/pkg/store/storepb"
+
+// ReplicaLabels is a set of label names that only differ between replicas of the same endpoint.
+type ReplicaLabels map[string]struct{}
+
+// NewReplicaLabels returns the set of the given replica label names.
+func NewReplicaLabels(names []string) ReplicaLabels {
+	r := make(ReplicaLabels, len(names))
+	for _, n := range names {
+		r[n] = struct{}{}
+	}
+	return r
+}
+
+// Remove returns the given labels without the replica labels. The given labels are not modified.
+func (r ReplicaLabels) Remove(lset []storepb.Label) []storepb.Label {
+	if len(r) == 0 {
+		return lset
+	}
+	res := make([]storepb.Label, 0, len(lset))
+	for _, l := range lset {
+		if _, ok := r[l.Name]; !ok {
+			res = append(res, l)
+		}
+	}
+	return res
+}
@@ -0,0 +1,88 @@
+// Package metadata implements the federation of the metric metadata API of Prometheus.
+package metadata
+
+import (
+	"context"
+	"sort"
+
+	"github.com/improbable-eng/thanos/pkg/metadata/metadatapb"
+	"github.com/pkg/errors"
+)
+
+// GRPCClient queries a metadata server, e.g. the Proxy, within the same process and merges the metadata of all
+// endpoints.
+type GRPCClient struct {
+	proxy metadatapb.MetadataServer
+}
+
+// NewGRPCClient returns a new GRPCClient.
+func NewGRPCClient(ms metadatapb.MetadataServer) *GRPCClient {
+	return &GRPCClient{proxy: ms}
+}
+
+type metadataServer struct {
+	// This field just exist to pseudo-implement the unused methods of the interface.
+	metadatapb.Metadata_MetricMetadataServer
+	ctx context.Context
+
+	metadata map[string][]metadatapb.Meta
+	warnings []error
+}
+
+func (s *metadataServer) Send(r *metadatapb.MetricMetadataResponse) error {
+	if w := r.GetWarning(); w != "" {
+		s.warnings = append(s.warnings, errors.New(w))
+		return nil
+	}
+	m := r.GetMetadata()
+	if m == nil {
+		return errors.New("no metric metadata or warning")
+	}
+
+	for name, entry := range m.Metadata {
+		if entry == nil {
+			continue
+		}
+	Metas:
+		for _, meta := range entry.Metas {
+			for _, existing := range s.metadata[name] {
+				if existing.Type == meta.Type && existing.Help == meta.Help && existing.Unit == meta.Unit {
+					continue Metas
+				}
+			}
+			s.metadata[name] = append(s.metadata[name], meta)
+		}
+	}
+	return nil
+}
+
+func (s *metadataServer) Context() context.Context {
+	return s.ctx
+}
+
+// MetricMetadata returns the metadata of the requested metrics. Different metadata of the same metric, e.g. help
+// strings changed between versions of an application, are all returned once. At most limit metrics are returned
+// if limit is not negative.
+func (c *GRPCClient) MetricMetadata(ctx context.Context, r *metadatapb.MetricMetadataRequest) (map[string][]metadatapb.Meta, []error, error) {
+	srv := &metadataServer{ctx: ctx, metadata: map[string][]metadatapb.Meta{}}
+	if err := c.proxy.MetricMetadata(r, srv); err != nil {
+		return nil, nil, errors.Wrap(err, "proxy MetricMetadata()")
+	}
+
+	if r.Limit < 0 || len(srv.metadata) <= int(r.Limit) {
+		return srv.metadata, srv.warnings, nil
+	}
+
+	// Keep the same metrics regardless of the order the endpoints responded in.
+	names := make([]string, 0, len(srv.metadata))
+	for name := range srv.metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := make(map[string][]metadatapb.Meta, r.Limit)
+	for _, name := range names[:r.Limit] {
+		res[name] = srv.metadata[name]
+	}
+	return res, srv.warnings, nil
+}
@@ -0,0 +1,84 @@
+package metadata
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/metadata/metadatapb"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testMetadataClient struct {
+	resps []*metadatapb.MetricMetadataResponse
+	err   error
+}
+
+func (c *testMetadataClient) MetricMetadata(ctx context.Context, r *metadatapb.MetricMetadataRequest, _ ...grpc.CallOption) (metadatapb.Metadata_MetricMetadataClient, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &testMetadataStream{resps: c.resps}, nil
+}
+
+type testMetadataStream struct {
+	grpc.ClientStream
+	resps []*metadatapb.MetricMetadataResponse
+}
+
+func (s *testMetadataStream) Recv() (*metadatapb.MetricMetadataResponse, error) {
+	if len(s.resps) == 0 {
+		return nil, io.EOF
+	}
+	r := s.resps[0]
+	s.resps = s.resps[1:]
+	return r, nil
+}
+
+func TestGRPCClient_MetricMetadata(t *testing.T) {
+	upGauge := metadatapb.Meta{Type: "gauge", Help: "Up of the target."}
+	proxy := NewProxy(nil, func() []Client {
+		return []Client{
+			{MetadataClient: &testMetadataClient{resps: []*metadatapb.MetricMetadataResponse{
+				metadatapb.NewMetricMetadataResponse(metadatapb.FromMetadataMap(map[string][]metadatapb.Meta{
+					"up":                  {upGauge},
+					"http_requests_total": {{Type: "counter", Help: "Requests."}},
+				})),
+			}}, Addr: "1"},
+			{MetadataClient: &testMetadataClient{resps: []*metadatapb.MetricMetadataResponse{
+				metadatapb.NewMetricMetadataResponse(metadatapb.FromMetadataMap(map[string][]metadatapb.Meta{
+					"up":                  {upGauge},
+					"http_requests_total": {{Type: "counter", Help: "Total number of requests."}},
+				})),
+			}}, Addr: "2"},
+			// Stores without metadata API are skipped silently.
+			{MetadataClient: &testMetadataClient{err: status.Error(codes.Unimplemented, "unknown service")}, Addr: "3"},
+			{MetadataClient: &testMetadataClient{err: errors.New("unavailable")}, Addr: "4"},
+		}
+	})
+	c := NewGRPCClient(proxy)
+
+	md, warnings, err := c.MetricMetadata(context.Background(), &metadatapb.MetricMetadataRequest{Limit: -1})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(warnings))
+	testutil.Equals(t, 2, len(md))
+	testutil.Equals(t, []metadatapb.Meta{upGauge}, md["up"])
+	testutil.Equals(t, 2, len(md["http_requests_total"]))
+
+	md, _, err = c.MetricMetadata(context.Background(), &metadatapb.MetricMetadataRequest{Limit: 1})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(md))
+	testutil.Equals(t, 2, len(md["http_requests_total"]))
+
+	// Failing endpoints fail the whole request with the abort strategy.
+	_, _, err = c.MetricMetadata(context.Background(), &metadatapb.MetricMetadataRequest{
+		Limit:                   -1,
+		PartialResponseStrategy: storepb.PartialResponseStrategy_ABORT,
+	})
+	testutil.NotOk(t, err)
+}
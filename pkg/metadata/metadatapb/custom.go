@@ -0,0 +1,26 @@
+package metadatapb
+
+func NewWarningMetricMetadataResponse(err error) *MetricMetadataResponse {
+	return &MetricMetadataResponse{
+		Result: &MetricMetadataResponse_Warning{
+			Warning: err.Error(),
+		},
+	}
+}
+
+func NewMetricMetadataResponse(m *MetricMetadata) *MetricMetadataResponse {
+	return &MetricMetadataResponse{
+		Result: &MetricMetadataResponse_Metadata{
+			Metadata: m,
+		},
+	}
+}
+
+// FromMetadataMap returns the metric metadata of the given map from metric names to their metadata.
+func FromMetadataMap(m map[string][]Meta) *MetricMetadata {
+	res := &MetricMetadata{Metadata: make(map[string]*MetricMetadataEntry, len(m))}
+	for name, metas := range m {
+		res.Metadata[name] = &MetricMetadataEntry{Metas: metas}
+	}
+	return res
+}
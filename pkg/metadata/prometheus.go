@@ -0,0 +1,37 @@
+package metadata
+
+import (
+	"net/url"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/metadata/metadatapb"
+	"github.com/improbable-eng/thanos/pkg/promclient"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Prometheus implements metadatapb.MetadataServer on top of the metadata API of Prometheus.
+type Prometheus struct {
+	logger log.Logger
+	base   *url.URL
+}
+
+// NewPrometheus creates a new metadata server querying the Prometheus at the given URL.
+func NewPrometheus(logger log.Logger, base *url.URL) *Prometheus {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return &Prometheus{
+		logger: logger,
+		base:   base,
+	}
+}
+
+// MetricMetadata returns the metadata of the requested metrics of Prometheus.
+func (p *Prometheus) MetricMetadata(r *metadatapb.MetricMetadataRequest, s metadatapb.Metadata_MetricMetadataServer) error {
+	md, err := promclient.MetricMetadata(s.Context(), p.logger, p.base, r.Metric, int(r.Limit))
+	if err != nil {
+		return status.Error(codes.Unknown, err.Error())
+	}
+	return s.Send(metadatapb.NewMetricMetadataResponse(metadatapb.FromMetadataMap(md)))
+}
@@ -0,0 +1,54 @@
+package metadata
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/federation"
+	"github.com/improbable-eng/thanos/pkg/metadata/metadatapb"
+)
+
+// Client is a metadata API client of a single endpoint.
+type Client struct {
+	metadatapb.MetadataClient
+
+	// Addr is used for errors and warnings.
+	Addr string
+}
+
+// Proxy implements metadatapb.MetadataServer by fanning requests out to the metadata APIs of all given endpoints.
+type Proxy struct {
+	logger  log.Logger
+	clients func() []Client
+}
+
+// NewProxy returns a new metadata proxy over the clients returned by the given function.
+func NewProxy(logger log.Logger, clients func() []Client) *Proxy {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return &Proxy{
+		logger:  logger,
+		clients: clients,
+	}
+}
+
+// MetricMetadata returns the metric metadata of all endpoints. Endpoints not implementing the metadata API are skipped.
+func (p *Proxy) MetricMetadata(r *metadatapb.MetricMetadataRequest, s metadatapb.Metadata_MetricMetadataServer) error {
+	clients := p.clients()
+	streams := make([]federation.Stream, 0, len(clients))
+	for _, c := range clients {
+		c := c
+		streams = append(streams, federation.Stream{Addr: c.Addr, Open: func(ctx context.Context) (func() (interface{}, error), error) {
+			stream, err := c.MetricMetadata(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+			return func() (interface{}, error) { return stream.Recv() }, nil
+		}})
+	}
+	return federation.FanOut(s.Context(), p.logger, "metric metadata", r.PartialResponseStrategy, streams,
+		func(resp interface{}) error { return s.Send(resp.(*metadatapb.MetricMetadataResponse)) },
+		func(err error) interface{} { return metadatapb.NewWarningMetricMetadataResponse(err) },
+	)
+}
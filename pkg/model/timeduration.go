@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// TimeOrDurationValue is a custom kingpin parser for time in RFC3339 or duration in Prometheus duration format,
+// such as "300ms", "-1h" or "-2w". A duration is relative to the current time. Only one of them is set.
+type TimeOrDurationValue struct {
+	Time *time.Time
+	Dur  *model.Duration
+}
+
+// Set converts string to TimeOrDurationValue.
+func (tdv *TimeOrDurationValue) Set(s string) error {
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		tdv.Time = &t
+		tdv.Dur = nil
+		return nil
+	}
+
+	// Not a time, let's try a duration.
+	var minus bool
+	if len(s) > 0 && s[0] == '-' {
+		minus = true
+		s = s[1:]
+	}
+	dur, err := model.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	if minus {
+		dur = dur * -1
+	}
+	tdv.Time = nil
+	tdv.Dur = &dur
+	return nil
+}
+
+// String returns either time or duration.
+func (tdv *TimeOrDurationValue) String() string {
+	switch {
+	case tdv.Time != nil:
+		return tdv.Time.String()
+	case tdv.Dur != nil:
+		if v := *tdv.Dur; v < 0 {
+			return "-" + (-v).String()
+		}
+		return tdv.Dur.String()
+	}
+	return "nil"
+}
+
+// PrometheusTimestamp returns TimeOrDurationValue converted to a Prometheus timestamp in milliseconds.
+// If a duration is set, now+duration is returned.
+func (tdv *TimeOrDurationValue) PrometheusTimestamp() int64 {
+	switch {
+	case tdv.Time != nil:
+		return timestamp.FromTime(*tdv.Time)
+	case tdv.Dur != nil:
+		return timestamp.FromTime(time.Now().Add(time.Duration(*tdv.Dur)))
+	}
+	return 0
+}
+
+// TimeOrDuration is a helper for parsing TimeOrDurationValue flags with kingpin.
+func TimeOrDuration(flags *kingpin.FlagClause) *TimeOrDurationValue {
+	value := new(TimeOrDurationValue)
+	flags.SetValue(value)
+	return value
+}
@@ -0,0 +1,45 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/model"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func TestTimeOrDurationValue(t *testing.T) {
+	cmd := kingpin.New("test", "test")
+
+	minTime := model.TimeOrDuration(cmd.Flag("min-time", "Start of time range limit to serve"))
+
+	maxTime := model.TimeOrDuration(cmd.Flag("max-time", "End of time range limit to serve").
+		Default("9999-12-31T23:59:59Z"))
+
+	_, err := cmd.Parse([]string{"--min-time", "10s"})
+	testutil.Ok(t, err)
+
+	testutil.Equals(t, "10s", minTime.String())
+	testutil.Equals(t, "9999-12-31 23:59:59 +0000 UTC", maxTime.String())
+
+	prevTime := timestamp.FromTime(time.Now())
+	afterTime := timestamp.FromTime(time.Now().Add(15 * time.Second))
+
+	testutil.Assert(t, minTime.PrometheusTimestamp() > prevTime, "minTime prometheus timestamp is less than expected")
+	testutil.Assert(t, minTime.PrometheusTimestamp() < afterTime, "minTime prometheus timestamp is more than expected")
+
+	testutil.Equals(t, int64(253402300799000), maxTime.PrometheusTimestamp())
+
+	_, err = cmd.Parse([]string{"--min-time=-2w"})
+	testutil.Ok(t, err)
+	testutil.Equals(t, "-2w", minTime.String())
+
+	twoWeeksAgo := timestamp.FromTime(time.Now().Add(-14 * 24 * time.Hour))
+	testutil.Assert(t, minTime.PrometheusTimestamp() <= twoWeeksAgo+1000, "minTime prometheus timestamp is more than expected")
+	testutil.Assert(t, minTime.PrometheusTimestamp() >= twoWeeksAgo-1000, "minTime prometheus timestamp is less than expected")
+
+	_, err = cmd.Parse([]string{"--min-time", "yesterday"})
+	testutil.NotOk(t, err)
+}
@@ -140,6 +140,15 @@ func (b *Bucket) Exists(ctx context.Context, name string) (bool, error) {
 	return false, nil
 }
 
+// ObjectSize returns the size of the specified object.
+func (b *Bucket) ObjectSize(ctx context.Context, name string) (uint64, error) {
+	attrs, err := b.bkt.Object(name).Attrs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(attrs.Size), nil
+}
+
 // Upload writes the file specified in src to remote GCS location specified as target.
 func (b *Bucket) Upload(ctx context.Context, name string, r io.Reader) error {
 	w := b.bkt.Object(name).NewWriter(ctx)
@@ -0,0 +1,67 @@
+package objstore
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"golang.org/x/time/rate"
+)
+
+// BucketWithUploadLimit takes a bucket and limits the bandwidth of all uploads to the bucket together to the given
+// number of bytes per second. It returns the bucket as is for a limit of 0 or less.
+func BucketWithUploadLimit(b Bucket, bytesPerSecond int64) Bucket {
+	if bytesPerSecond <= 0 {
+		return b
+	}
+	return &uploadLimitBucket{
+		Bucket:  b,
+		limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), int(bytesPerSecond)),
+	}
+}
+
+type uploadLimitBucket struct {
+	Bucket
+
+	limiter *rate.Limiter
+}
+
+func (b *uploadLimitBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	lr := &limitedReader{ctx: ctx, r: r, limiter: b.limiter}
+	if f, ok := r.(*os.File); ok {
+		// Keep the file size available for clients that need it upfront, e.g. for multipart uploads.
+		return b.Bucket.Upload(ctx, name, &limitedFile{limitedReader: lr, f: f})
+	}
+	return b.Bucket.Upload(ctx, name, lr)
+}
+
+type limitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	// Reads are not allowed to exceed the burst of the limiter.
+	if burst := r.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.limiter.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+type limitedFile struct {
+	*limitedReader
+
+	f *os.File
+}
+
+// Stat returns the file info of the uploaded file.
+func (f *limitedFile) Stat() (os.FileInfo, error) {
+	return f.f.Stat()
+}
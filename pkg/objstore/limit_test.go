@@ -0,0 +1,48 @@
+package objstore_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/objstore"
+	"github.com/improbable-eng/thanos/pkg/objstore/inmem"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+)
+
+func TestBucketWithUploadLimit(t *testing.T) {
+	ctx := context.Background()
+	inner := inmem.NewBucket()
+	testutil.Equals(t, objstore.Bucket(inner), objstore.BucketWithUploadLimit(inner, 0))
+
+	bkt := objstore.BucketWithUploadLimit(inner, 1000)
+
+	// The first second worth of bytes is uploaded immediately, the rest at the limit.
+	data := bytes.Repeat([]byte("a"), 1500)
+	begin := time.Now()
+	testutil.Ok(t, bkt.Upload(ctx, "obj", bytes.NewReader(data)))
+	took := time.Since(begin)
+	testutil.Assert(t, took >= 400*time.Millisecond, "upload took %s, expected it to be limited", took)
+
+	rc, err := inner.Get(ctx, "obj")
+	testutil.Ok(t, err)
+	b, err := ioutil.ReadAll(rc)
+	testutil.Ok(t, err)
+	testutil.Ok(t, rc.Close())
+	testutil.Equals(t, data, b)
+
+	// Uploaded files keep exposing their size.
+	f, err := ioutil.TempFile("", "limit-test")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.Remove(f.Name())) }()
+	_, err = f.Write([]byte("file"))
+	testutil.Ok(t, err)
+	_, err = f.Seek(0, 0)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, f.Close()) }()
+
+	testutil.Ok(t, bkt.Upload(ctx, "file", f))
+}
@@ -47,6 +47,9 @@ type BucketReader interface {
 	// TODO(bplotka): Consider removing Exists in favor of helper that do Get & IsObjNotFoundErr (less code to maintain).
 	Exists(ctx context.Context, name string) (bool, error)
 
+	// ObjectSize returns the size of the specified object in bytes.
+	ObjectSize(ctx context.Context, name string) (uint64, error)
+
 	// IsObjNotFoundErr returns true if error means that object is not found. Relevant to Get operations.
 	IsObjNotFoundErr(err error) bool
 }
@@ -295,6 +298,20 @@ func (b *metricBucket) Exists(ctx context.Context, name string) (bool, error) {
 	return ok, err
 }
 
+func (b *metricBucket) ObjectSize(ctx context.Context, name string) (uint64, error) {
+	const op = "objectsize"
+	start := time.Now()
+
+	size, err := b.bkt.ObjectSize(ctx, name)
+	if err != nil {
+		b.opsFailures.WithLabelValues(op).Inc()
+	}
+	b.ops.WithLabelValues(op).Inc()
+	b.opsDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+
+	return size, err
+}
+
 func (b *metricBucket) Upload(ctx context.Context, name string, r io.Reader) error {
 	const op = "upload"
 	start := time.Now()
@@ -51,6 +51,14 @@ func TestObjStore_AcceptanceTest_e2e(t *testing.T) {
 		testutil.Ok(t, err)
 		testutil.Assert(t, ok, "expected exits")
 
+		size, err := bkt.ObjectSize(context.Background(), "id1/obj_1.some")
+		testutil.Ok(t, err)
+		testutil.Equals(t, uint64(len("@test-data@")), size)
+
+		_, err = bkt.ObjectSize(context.Background(), "id1/obj_2.some")
+		testutil.NotOk(t, err)
+		testutil.Assert(t, bkt.IsObjNotFoundErr(err), "expected not found error got %s", err)
+
 		// Upload other objects.
 		testutil.Ok(t, bkt.Upload(context.Background(), "id1/obj_2.some", strings.NewReader("@test-data2@")))
 		testutil.Ok(t, bkt.Upload(context.Background(), "id1/obj_3.some", strings.NewReader("@test-data3@")))
@@ -147,6 +147,15 @@ func (c *Container) Exists(ctx context.Context, name string) (bool, error) {
 	return false, err
 }
 
+// ObjectSize returns the size of the specified object.
+func (c *Container) ObjectSize(ctx context.Context, name string) (uint64, error) {
+	response, err := objects.Get(c.client, c.name, name, nil).Extract()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(response.ContentLength), nil
+}
+
 // IsObjNotFoundErr returns true if error means that object is not found. Relevant to Get operations.
 func (c *Container) IsObjNotFoundErr(err error) bool {
 	_, ok := err.(gophercloud.ErrDefault404)
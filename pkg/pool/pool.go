@@ -5,8 +5,26 @@ import (
 	"sync/atomic"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// Bytes is a pool of byte slices.
+type Bytes interface {
+	// Get returns a byte slice with a capacity of at least sz bytes.
+	Get(sz int) ([]byte, error)
+	// Put returns a byte slice obtained with Get to the pool.
+	Put(b []byte)
+}
+
+// NoopBytes is a Bytes pool which allocates a new slice for every Get and does no pooling at all.
+type NoopBytes struct{}
+
+// Get implements Bytes.
+func (NoopBytes) Get(sz int) ([]byte, error) { return make([]byte, 0, sz), nil }
+
+// Put implements Bytes.
+func (NoopBytes) Put([]byte) {}
+
 // BytesPool is a bucketed pool for variably sized byte slices. It can be configured to not allow
 // more than a maximum number of bytes being used at a given time.
 // Every byte slice obtained from the pool must be returned.
@@ -17,12 +35,17 @@ type BytesPool struct {
 	usedTotal uint64
 
 	new func(s int) []byte
+
+	gets      prometheus.Counter
+	misses    prometheus.Counter
+	exhausted prometheus.Counter
 }
 
 // NewBytesPool returns a new BytesPool with size buckets for minSize to maxSize
 // increasing by the given factor and maximum number of used bytes.
 // No more than maxTotal bytes can be used at any given time unless maxTotal is set to 0.
-func NewBytesPool(minSize, maxSize int, factor float64, maxTotal uint64) (*BytesPool, error) {
+// Metrics of the pool are registered with reg if it is not nil.
+func NewBytesPool(minSize, maxSize int, factor float64, maxTotal uint64, reg prometheus.Registerer) (*BytesPool, error) {
 	if minSize < 1 {
 		return nil, errors.New("invalid minimum pool size")
 	}
@@ -45,6 +68,33 @@ func NewBytesPool(minSize, maxSize int, factor float64, maxTotal uint64) (*Bytes
 		new: func(sz int) []byte {
 			return make([]byte, 0, sz)
 		},
+		gets: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pool_gets_total",
+			Help: "Total number of byte slices requested from the pool.",
+		}),
+		misses: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pool_misses_total",
+			Help: "Total number of byte slices requested from the pool which had to be allocated.",
+		}),
+		exhausted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pool_exhausted_total",
+			Help: "Total number of byte slices requested from the pool which failed as the pool was exhausted.",
+		}),
+	}
+	if reg != nil {
+		reg.MustRegister(
+			p.gets,
+			p.misses,
+			p.exhausted,
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Name: "pool_used_bytes",
+				Help: "Number of bytes currently used from the pool.",
+			}, func() float64 { return float64(atomic.LoadUint64(&p.usedTotal)) }),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Name: "pool_max_bytes",
+				Help: "Maximum number of bytes which can be used from the pool. 0 means no limit.",
+			}, func() float64 { return float64(p.maxTotal) }),
+		)
 	}
 	return p, nil
 }
@@ -54,9 +104,11 @@ var ErrPoolExhausted = errors.New("pool exhausted")
 
 // Get returns a new byte slices that fits the given size.
 func (p *BytesPool) Get(sz int) ([]byte, error) {
+	p.gets.Inc()
 	used := atomic.LoadUint64(&p.usedTotal)
 
 	if p.maxTotal > 0 && used+uint64(sz) > p.maxTotal {
+		p.exhausted.Inc()
 		return nil, ErrPoolExhausted
 	}
 	for i, bktSize := range p.sizes {
@@ -65,6 +117,7 @@ func (p *BytesPool) Get(sz int) ([]byte, error) {
 		}
 		b, ok := p.buckets[i].Get().([]byte)
 		if !ok {
+			p.misses.Inc()
 			b = p.new(bktSize)
 		}
 		atomic.AddUint64(&p.usedTotal, uint64(cap(b)))
@@ -72,12 +125,17 @@ func (p *BytesPool) Get(sz int) ([]byte, error) {
 	}
 
 	// The requested size exceeds that of our highest bucket, allocate it directly.
+	p.misses.Inc()
 	atomic.AddUint64(&p.usedTotal, uint64(sz))
 	return p.new(sz), nil
 }
 
 // Put returns a byte slice to the right bucket in the pool.
 func (p *BytesPool) Put(b []byte) {
+	sz := uint64(cap(b))
+	if sz == 0 {
+		return
+	}
 	for i, bktSize := range p.sizes {
 		if cap(b) > bktSize {
 			continue
@@ -85,5 +143,15 @@ func (p *BytesPool) Put(b []byte) {
 		p.buckets[i].Put(b[:0])
 		break
 	}
-	atomic.AddUint64(&p.usedTotal, ^uint64(p.usedTotal-1))
+	// Slices grown after Get may be larger than the bytes accounted for them.
+	for {
+		used := atomic.LoadUint64(&p.usedTotal)
+		next := uint64(0)
+		if used > sz {
+			next = used - sz
+		}
+		if atomic.CompareAndSwapUint64(&p.usedTotal, used, next) {
+			return
+		}
+	}
 }
@@ -1,13 +1,16 @@
 package pool
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestBytesPool(t *testing.T) {
-	chunkPool, err := NewBytesPool(10, 100, 2, 1000)
+	chunkPool, err := NewBytesPool(10, 100, 2, 1000, nil)
 	testutil.Ok(t, err)
 
 	// Inject alloc counter.
@@ -58,3 +61,42 @@ func TestBytesPool(t *testing.T) {
 	testutil.Equals(t, uint64(0), chunkPool.usedTotal)
 	testutil.Equals(t, uint64(4), allocs)
 }
+
+func TestBytesPool_Metrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	chunkPool, err := NewBytesPool(10, 100, 2, 100, reg)
+	testutil.Ok(t, err)
+
+	b1, err := chunkPool.Get(40)
+	testutil.Ok(t, err)
+	b2, err := chunkPool.Get(40)
+	testutil.Ok(t, err)
+	_, err = chunkPool.Get(40)
+	testutil.Equals(t, ErrPoolExhausted, err)
+
+	chunkPool.Put(b1)
+	b1, err = chunkPool.Get(40)
+	testutil.Ok(t, err)
+
+	testutil.Equals(t, 4.0, promtest.ToFloat64(chunkPool.gets))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(chunkPool.exhausted))
+	testutil.Assert(t, promtest.ToFloat64(chunkPool.misses) >= 2, "expected at least 2 misses")
+
+	chunkPool.Put(b1)
+	chunkPool.Put(b2)
+	testutil.Ok(t, promtest.GatherAndCompare(reg, strings.NewReader(`
+# HELP pool_used_bytes Number of bytes currently used from the pool.
+# TYPE pool_used_bytes gauge
+pool_used_bytes 0
+`), "pool_used_bytes"))
+}
+
+func TestNoopBytes(t *testing.T) {
+	var p Bytes = NoopBytes{}
+
+	b, err := p.Get(100)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(b))
+	testutil.Equals(t, 100, cap(b))
+	p.Put(b)
+}
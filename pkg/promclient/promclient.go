@@ -23,8 +23,12 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/exemplars/exemplarspb"
+	"github.com/improbable-eng/thanos/pkg/metadata/metadatapb"
+	"github.com/improbable-eng/thanos/pkg/rules/rulespb"
 	"github.com/improbable-eng/thanos/pkg/runutil"
 	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/targets/targetspb"
 	"github.com/improbable-eng/thanos/pkg/tracing"
 	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
@@ -211,6 +215,29 @@ func ConfiguredFlags(ctx context.Context, logger log.Logger, base *url.URL) (Fla
 
 }
 
+// IsReady returns no error if the Prometheus at base reports to be ready to serve traffic on its /-/ready endpoint.
+func IsReady(ctx context.Context, logger log.Logger, base *url.URL) error {
+	u := *base
+	u.Path = path.Join(u.Path, "/-/ready")
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrapf(err, "request readiness against %s", u.String())
+	}
+	defer runutil.CloseWithLogOnErr(logger, resp.Body, "readiness body")
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("Prometheus is not ready: got non-200 response code: %v, response: %v", resp.StatusCode, string(b))
+	}
+	return nil
+}
+
 // Snapshot will request Prometheus to perform snapshot in directory returned by this function.
 // Returned directory is relative to Prometheus data-dir.
 // NOTE: `--web.enable-admin-api` flag has to be set on Prometheus.
@@ -398,6 +425,113 @@ func PromqlQueryInstant(ctx context.Context, logger log.Logger, base *url.URL, q
 	return vec, warnings, nil
 }
 
+// QueryRange performs range query and returns results in model.Matrix type.
+func QueryRange(ctx context.Context, logger log.Logger, base *url.URL, query string, start, end time.Time, step time.Duration, opts QueryOptions) (model.Matrix, []string, error) {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	params, err := url.ParseQuery(base.RawQuery)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "parse raw query %s", base.RawQuery)
+	}
+	params.Add("query", query)
+	params.Add("start", start.Format(time.RFC3339Nano))
+	params.Add("end", end.Format(time.RFC3339Nano))
+	params.Add("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	if err := opts.AddTo(params); err != nil {
+		return nil, nil, errors.Wrap(err, "add thanos opts query params")
+	}
+
+	u := *base
+	u.Path = path.Join(u.Path, "/api/v1/query_range")
+	u.RawQuery = params.Encode()
+
+	level.Debug(logger).Log("msg", "querying range", "url", u.String())
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "create GET request")
+	}
+
+	req = req.WithContext(ctx)
+
+	client := &http.Client{
+		Transport: tracing.HTTPTripperware(logger, http.DefaultTransport),
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "perform GET request against %s", u.String())
+	}
+	defer runutil.CloseWithLogOnErr(logger, resp.Body, "query body")
+
+	var m struct {
+		Data struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+
+		Error     string `json:"error,omitempty"`
+		ErrorType string `json:"errorType,omitempty"`
+		// Extra field supported by Thanos Querier.
+		Warnings []string `json:"warnings"`
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "read query range response")
+	}
+
+	if err = json.Unmarshal(body, &m); err != nil {
+		return nil, nil, errors.Wrap(err, "unmarshal query range response")
+	}
+
+	if m.Data.ResultType != promql.ValueTypeMatrix {
+		if m.Warnings != nil {
+			return nil, nil, errors.Errorf("error: %s, type: %s, warning: %s", m.Error, m.ErrorType, strings.Join(m.Warnings, ", "))
+		}
+		if m.Error != "" {
+			return nil, nil, errors.Errorf("error: %s, type: %s", m.Error, m.ErrorType)
+		}
+
+		return nil, nil, errors.Errorf("received status code: %d, unknown response type: '%q'", resp.StatusCode, m.Data.ResultType)
+	}
+
+	var matrixResult model.Matrix
+	if err = json.Unmarshal(m.Data.Result, &matrixResult); err != nil {
+		return nil, nil, errors.Wrap(err, "decode result into ValueTypeMatrix")
+	}
+	return matrixResult, m.Warnings, nil
+}
+
+// PromqlQueryRange performs range query and returns results in promql.Matrix type that is compatible with promql package.
+func PromqlQueryRange(ctx context.Context, logger log.Logger, base *url.URL, query string, start, end time.Time, step time.Duration, opts QueryOptions) (promql.Matrix, []string, error) {
+	matrixResult, warnings, err := QueryRange(ctx, logger, base, query, start, end, step, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matrix := make(promql.Matrix, 0, len(matrixResult))
+	for _, ss := range matrixResult {
+		lset := make(promlabels.Labels, 0, len(ss.Metric))
+		for k, v := range ss.Metric {
+			lset = append(lset, promlabels.Label{
+				Name:  string(k),
+				Value: string(v),
+			})
+		}
+		sort.Sort(lset)
+
+		series := promql.Series{Metric: lset, Points: make([]promql.Point, 0, len(ss.Values))}
+		for _, p := range ss.Values {
+			series.Points = append(series.Points, promql.Point{T: int64(p.Timestamp), V: float64(p.Value)})
+		}
+		matrix = append(matrix, series)
+	}
+
+	return matrix, warnings, nil
+}
+
 // Scalar response consists of array with mixed types so it needs to be
 // unmarshaled separately.
 func convertScalarJSONToVector(scalarJSONResult json.RawMessage) (model.Vector, error) {
@@ -500,3 +634,226 @@ func MetricValues(ctx context.Context, logger log.Logger, base *url.URL, perMetr
 		}
 	}
 }
+
+// QueryExemplars returns the exemplars of the series selected by the given query within the given time range,
+// in milliseconds, using the exemplars API of Prometheus.
+func QueryExemplars(ctx context.Context, logger log.Logger, base *url.URL, query string, mint, maxt int64) ([]*exemplarspb.ExemplarData, error) {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	params := url.Values{}
+	params.Add("query", query)
+	params.Add("start", strconv.FormatFloat(float64(mint)/1000, 'f', -1, 64))
+	params.Add("end", strconv.FormatFloat(float64(maxt)/1000, 'f', -1, 64))
+
+	u := *base
+	u.Path = path.Join(u.Path, "/api/v1/query_exemplars")
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "create GET request")
+	}
+
+	req = req.WithContext(ctx)
+
+	client := &http.Client{
+		Transport: tracing.HTTPTripperware(logger, http.DefaultTransport),
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "perform GET request against %s", u.String())
+	}
+	defer runutil.CloseWithLogOnErr(logger, resp.Body, "query exemplars body")
+
+	var m struct {
+		Status    string                      `json:"status"`
+		Data      []*exemplarspb.ExemplarData `json:"data"`
+		Error     string                      `json:"error,omitempty"`
+		ErrorType string                      `json:"errorType,omitempty"`
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read query exemplars response")
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.New("exemplars API not found, Prometheus 2.26 or newer is required")
+	}
+	if err = json.Unmarshal(body, &m); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal query exemplars response, status code %d", resp.StatusCode)
+	}
+	if m.Status != "success" {
+		return nil, errors.Errorf("error: %s, type: %s", m.Error, m.ErrorType)
+	}
+	return m.Data, nil
+}
+
+// MetricMetadata returns the metadata of the given metric, or of all metrics if empty, using the metadata API of
+// Prometheus. At most limit metrics are returned, all of them if limit is negative.
+func MetricMetadata(ctx context.Context, logger log.Logger, base *url.URL, metric string, limit int) (map[string][]metadatapb.Meta, error) {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	params := url.Values{}
+	if metric != "" {
+		params.Add("metric", metric)
+	}
+	if limit >= 0 {
+		params.Add("limit", strconv.Itoa(limit))
+	}
+
+	u := *base
+	u.Path = path.Join(u.Path, "/api/v1/metadata")
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "create GET request")
+	}
+
+	req = req.WithContext(ctx)
+
+	client := &http.Client{
+		Transport: tracing.HTTPTripperware(logger, http.DefaultTransport),
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "perform GET request against %s", u.String())
+	}
+	defer runutil.CloseWithLogOnErr(logger, resp.Body, "metric metadata body")
+
+	var m struct {
+		Status    string                       `json:"status"`
+		Data      map[string][]metadatapb.Meta `json:"data"`
+		Error     string                       `json:"error,omitempty"`
+		ErrorType string                       `json:"errorType,omitempty"`
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read metric metadata response")
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.New("metadata API not found, Prometheus 2.15 or newer is required")
+	}
+	if err = json.Unmarshal(body, &m); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal metric metadata response, status code %d", resp.StatusCode)
+	}
+	if m.Status != "success" {
+		return nil, errors.Errorf("error: %s, type: %s", m.Error, m.ErrorType)
+	}
+	return m.Data, nil
+}
+
+// Targets returns the active and dropped targets of Prometheus using its targets API. State may be "active" or
+// "dropped" to return only those targets, or empty to return all of them.
+func Targets(ctx context.Context, logger log.Logger, base *url.URL, state string) (*targetspb.TargetDiscovery, error) {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	params := url.Values{}
+	if state != "" {
+		params.Add("state", state)
+	}
+
+	u := *base
+	u.Path = path.Join(u.Path, "/api/v1/targets")
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "create GET request")
+	}
+
+	req = req.WithContext(ctx)
+
+	client := &http.Client{
+		Transport: tracing.HTTPTripperware(logger, http.DefaultTransport),
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "perform GET request against %s", u.String())
+	}
+	defer runutil.CloseWithLogOnErr(logger, resp.Body, "targets body")
+
+	var m struct {
+		Status    string                    `json:"status"`
+		Data      targetspb.TargetDiscovery `json:"data"`
+		Error     string                    `json:"error,omitempty"`
+		ErrorType string                    `json:"errorType,omitempty"`
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read targets response")
+	}
+
+	if err = json.Unmarshal(body, &m); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal targets response, status code %d", resp.StatusCode)
+	}
+	if m.Status != "success" {
+		return nil, errors.Errorf("error: %s, type: %s", m.Error, m.ErrorType)
+	}
+	return &m.Data, nil
+}
+
+// Rules returns the rule groups of Prometheus using its rules API. Type may be "alert" or "record" to return only
+// those rules, or empty to return all of them.
+func Rules(ctx context.Context, logger log.Logger, base *url.URL, ruleType string) ([]*rulespb.RuleGroup, error) {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	params := url.Values{}
+	if ruleType != "" {
+		params.Add("type", ruleType)
+	}
+
+	u := *base
+	u.Path = path.Join(u.Path, "/api/v1/rules")
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "create GET request")
+	}
+
+	req = req.WithContext(ctx)
+
+	client := &http.Client{
+		Transport: tracing.HTTPTripperware(logger, http.DefaultTransport),
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "perform GET request against %s", u.String())
+	}
+	defer runutil.CloseWithLogOnErr(logger, resp.Body, "rules body")
+
+	var m struct {
+		Status string `json:"status"`
+		Data   struct {
+			Groups []*rulespb.RuleGroup `json:"groups"`
+		} `json:"data"`
+		Error     string `json:"error,omitempty"`
+		ErrorType string `json:"errorType,omitempty"`
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read rules response")
+	}
+
+	if err = json.Unmarshal(body, &m); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal rules response, status code %d", resp.StatusCode)
+	}
+	if m.Status != "success" {
+		return nil, errors.Errorf("error: %s, type: %s", m.Error, m.ErrorType)
+	}
+	return m.Data.Groups, nil
+}
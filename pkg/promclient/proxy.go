@@ -0,0 +1,110 @@
+package promclient
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+type originKey struct{}
+
+// Proxy proxies HTTP requests to Prometheus. If the external URL Prometheus is configured with is known and the
+// proxy runs behind a trusted reverse proxy, absolute links to it in redirects and HTML pages are rewritten to the
+// origin the request was sent to, so the pages keep working when Prometheus is only reachable through the proxy.
+type Proxy struct {
+	logger      log.Logger
+	proxy       *httputil.ReverseProxy
+	externalURL *url.URL
+}
+
+// NewProxy returns a proxy to the Prometheus at base. The external URL is optional. The origin of requests is taken
+// from the X-Forwarded-Host and X-Forwarded-Proto headers only if trustForwarded is set, since any client can set
+// them otherwise. Without it links keep pointing to the external URL.
+func NewProxy(logger log.Logger, base *url.URL, externalURL *url.URL, trustForwarded bool) *Proxy {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	p := &Proxy{
+		logger:      logger,
+		proxy:       httputil.NewSingleHostReverseProxy(base),
+		externalURL: externalURL,
+	}
+	if !trustForwarded {
+		p.externalURL = nil
+	}
+	if p.externalURL != nil {
+		director := p.proxy.Director
+		p.proxy.Director = func(r *http.Request) {
+			director(r)
+			// Let the transport handle compression, so bodies can be rewritten.
+			r.Header.Del("Accept-Encoding")
+		}
+		p.proxy.ModifyResponse = p.rewrite
+	}
+	p.proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		level.Warn(p.logger).Log("msg", "proxying request to Prometheus failed", "path", r.URL.Path, "err", err)
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	return p
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if p.externalURL != nil {
+		r = r.WithContext(context.WithValue(r.Context(), originKey{}, requestOrigin(r)))
+	}
+	p.proxy.ServeHTTP(w, r)
+}
+
+// requestOrigin returns the scheme and host the client sent the request to, as given by the reverse proxy in front.
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if s := r.Header.Get("X-Forwarded-Proto"); s != "" {
+		scheme = s
+	}
+	host := r.Host
+	if h := r.Header.Get("X-Forwarded-Host"); h != "" {
+		host = h
+	}
+	return scheme + "://" + host
+}
+
+func (p *Proxy) rewrite(resp *http.Response) error {
+	origin, ok := resp.Request.Context().Value(originKey{}).(string)
+	if !ok {
+		return nil
+	}
+	external := p.externalURL.Scheme + "://" + p.externalURL.Host
+
+	if loc, err := resp.Location(); err == nil && loc.Scheme == p.externalURL.Scheme && loc.Host == p.externalURL.Host {
+		resp.Header.Set("Location", origin+loc.RequestURI())
+	}
+
+	if mt, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err != nil || mt != "text/html" {
+		return nil
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "read response body")
+	}
+	if err := resp.Body.Close(); err != nil {
+		return errors.Wrap(err, "close response body")
+	}
+	b = bytes.Replace(b, []byte(external), []byte(origin), -1)
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	resp.ContentLength = int64(len(b))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(b)))
+	return nil
+}
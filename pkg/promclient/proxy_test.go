@@ -0,0 +1,95 @@
+package promclient
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+)
+
+func TestProxy(t *testing.T) {
+	prom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "http://prometheus.example.com/graph", http.StatusFound)
+		case "/graph":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("Content-Encoding", "gzip")
+			gw := gzip.NewWriter(w)
+			_, _ = gw.Write([]byte(`<a href="http://prometheus.example.com/alerts">Alerts</a>`))
+			_ = gw.Close()
+		case "/api/v1/query":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"url":"http://prometheus.example.com/graph"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer prom.Close()
+
+	promURL, err := url.Parse(prom.URL)
+	testutil.Ok(t, err)
+	externalURL, err := url.Parse("http://prometheus.example.com")
+	testutil.Ok(t, err)
+
+	srv := httptest.NewServer(NewProxy(nil, promURL, externalURL, true))
+	defer srv.Close()
+
+	c := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	// Redirects to the external URL are rewritten.
+	resp, err := c.Get(srv.URL + "/")
+	testutil.Ok(t, err)
+	testutil.Ok(t, resp.Body.Close())
+	testutil.Equals(t, http.StatusFound, resp.StatusCode)
+	testutil.Equals(t, srv.URL+"/graph", resp.Header.Get("Location"))
+
+	// Links in pages are rewritten, also for compressed responses.
+	resp, err = c.Get(srv.URL + "/graph")
+	testutil.Ok(t, err)
+	b, err := ioutil.ReadAll(resp.Body)
+	testutil.Ok(t, err)
+	testutil.Ok(t, resp.Body.Close())
+	testutil.Equals(t, `<a href="`+srv.URL+`/alerts">Alerts</a>`, string(b))
+
+	// API responses are passed through unchanged.
+	resp, err = c.Get(srv.URL + "/api/v1/query")
+	testutil.Ok(t, err)
+	b, err = ioutil.ReadAll(resp.Body)
+	testutil.Ok(t, err)
+	testutil.Ok(t, resp.Body.Close())
+	testutil.Equals(t, `{"url":"http://prometheus.example.com/graph"}`, string(b))
+
+	// Forwarded hosts are taken into account.
+	req, err := http.NewRequest("GET", srv.URL+"/", nil)
+	testutil.Ok(t, err)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "sidecar.example.com")
+	resp, err = c.Do(req)
+	testutil.Ok(t, err)
+	testutil.Ok(t, resp.Body.Close())
+	testutil.Equals(t, "https://sidecar.example.com/graph", resp.Header.Get("Location"))
+
+	// Without trusting the forwarded headers, links keep pointing to the external URL.
+	untrusted := httptest.NewServer(NewProxy(nil, promURL, externalURL, false))
+	defer untrusted.Close()
+
+	req, err = http.NewRequest("GET", untrusted.URL+"/", nil)
+	testutil.Ok(t, err)
+	req.Header.Set("X-Forwarded-Host", "evil.example.com")
+	resp, err = c.Do(req)
+	testutil.Ok(t, err)
+	testutil.Ok(t, resp.Body.Close())
+	testutil.Equals(t, "http://prometheus.example.com/graph", resp.Header.Get("Location"))
+
+	resp, err = c.Get(untrusted.URL + "/graph")
+	testutil.Ok(t, err)
+	b, err = ioutil.ReadAll(resp.Body)
+	testutil.Ok(t, err)
+	testutil.Ok(t, resp.Body.Close())
+	testutil.Equals(t, `<a href="http://prometheus.example.com/alerts">Alerts</a>`, string(b))
+}
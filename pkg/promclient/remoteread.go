@@ -0,0 +1,81 @@
+package promclient
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// StreamedChunksContentType is the content type of remote read responses streaming XOR chunks, i.e. of response type
+// STREAMED_XOR_CHUNKS. Prometheus 2.13 and newer support it, older versions respond with samples.
+const StreamedChunksContentType = "application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse"
+
+// DefaultChunkedReadLimit is the maximum size of a single frame of a streamed remote read response, as used by Prometheus.
+const DefaultChunkedReadLimit = 50 * 1024 * 1024
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ChunkedReader reads the frames of a streamed remote read response. Each frame is a message preceded by its uvarint
+// size and its big endian CRC32 Castagnoli checksum.
+type ChunkedReader struct {
+	b         *bufio.Reader
+	data      []byte
+	sizeLimit uint64
+}
+
+// NewChunkedReader returns a reader of the frames in r, which fails on frames larger than sizeLimit. The data buffer
+// is reused to read frames into if it's large enough.
+func NewChunkedReader(r io.Reader, sizeLimit uint64, data []byte) *ChunkedReader {
+	return &ChunkedReader{b: bufio.NewReader(r), sizeLimit: sizeLimit, data: data}
+}
+
+// Next returns the next frame. The returned bytes are valid until the next call. It returns io.EOF once all frames
+// were read.
+func (r *ChunkedReader) Next() ([]byte, error) {
+	size, err := binary.ReadUvarint(r.b)
+	if err != nil {
+		// The response ends between frames with io.EOF.
+		return nil, err
+	}
+	if size > r.sizeLimit {
+		return nil, errors.Errorf("frame of %d bytes exceeds the limit of %d bytes", size, r.sizeLimit)
+	}
+
+	var crc [4]byte
+	if _, err := io.ReadFull(r.b, crc[:]); err != nil {
+		return nil, errors.Wrap(noEOF(err), "read frame checksum")
+	}
+
+	if uint64(cap(r.data)) < size {
+		r.data = make([]byte, size)
+	}
+	r.data = r.data[:size]
+	if _, err := io.ReadFull(r.b, r.data); err != nil {
+		return nil, errors.Wrap(noEOF(err), "read frame")
+	}
+	if exp, got := binary.BigEndian.Uint32(crc[:]), crc32.Checksum(r.data, castagnoliTable); exp != got {
+		return nil, errors.Errorf("frame checksum mismatch: expected %x, got %x", exp, got)
+	}
+	return r.data, nil
+}
+
+// NextProto unmarshals the next frame into pb. It returns io.EOF once all frames were read.
+func (r *ChunkedReader) NextProto(pb proto.Message) error {
+	b, err := r.Next()
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, pb)
+}
+
+// noEOF turns io.EOF within a frame into io.ErrUnexpectedEOF, as only the end of the response between frames is expected.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
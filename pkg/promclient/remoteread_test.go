@@ -0,0 +1,54 @@
+package promclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+)
+
+func writeFrame(buf *bytes.Buffer, b []byte) {
+	var tmp [binary.MaxVarintLen64]byte
+	buf.Write(tmp[:binary.PutUvarint(tmp[:], uint64(len(b)))])
+	binary.BigEndian.PutUint32(tmp[:4], crc32.Checksum(b, castagnoliTable))
+	buf.Write(tmp[:4])
+	buf.Write(b)
+}
+
+func TestChunkedReader(t *testing.T) {
+	var buf bytes.Buffer
+	writeFrame(&buf, []byte("first"))
+	writeFrame(&buf, []byte("second frame"))
+	writeFrame(&buf, []byte{})
+
+	r := NewChunkedReader(bytes.NewReader(buf.Bytes()), 100, nil)
+	for _, exp := range []string{"first", "second frame", ""} {
+		b, err := r.Next()
+		testutil.Ok(t, err)
+		testutil.Equals(t, exp, string(b))
+	}
+	_, err := r.Next()
+	testutil.Equals(t, io.EOF, err)
+
+	// Frames over the limit are rejected.
+	_, err = NewChunkedReader(bytes.NewReader(buf.Bytes()), 4, nil).Next()
+	testutil.NotOk(t, err)
+
+	// Corrupted frames are detected.
+	corrupted := append([]byte{}, buf.Bytes()...)
+	// The data of the second frame starts after the first frame and the size and checksum of the second one.
+	corrupted[(1+4+len("first"))+(1+4)]++
+	r = NewChunkedReader(bytes.NewReader(corrupted), 100, nil)
+	_, err = r.Next()
+	testutil.Ok(t, err)
+	_, err = r.Next()
+	testutil.NotOk(t, err)
+
+	// Truncated responses are detected.
+	_, err = NewChunkedReader(bytes.NewReader(buf.Bytes()[:8]), 100, nil).Next()
+	testutil.NotOk(t, err)
+	testutil.Assert(t, err != io.EOF, "truncated frame reported as end of response")
+}
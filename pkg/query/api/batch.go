@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/improbable-eng/thanos/pkg/query"
+	"github.com/pkg/errors"
+)
+
+// maxBatchQueries bounds the number of queries of a batch.
+const maxBatchQueries = 100
+
+// batchConcurrency returns the number of queries of a batch evaluated at the same time. A batch takes at most half of
+// the --query.max-concurrent slots, so that a single request cannot occupy all of them or fill the query queue.
+func (api *API) batchConcurrency() int {
+	if api.scheduler == nil {
+		return maxBatchQueries
+	}
+	if n := cap(api.scheduler.slots) / 2; n > 0 {
+		return n
+	}
+	return 1
+}
+
+// batchQueryResult is the result of a single query of a batch, having either data or an error.
+type batchQueryResult struct {
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType ErrorType   `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// queryBatch evaluates all query parameters of the request concurrently, as range queries if a step is given and
+// as instant queries otherwise. All other parameters apply to all queries. Identical Series requests of the queries
+// are sent to the stores once, which saves a lot of work for dashboards with many panels selecting the same series.
+// The results are returned in the order of the queries, with errors of single queries not failing the batch.
+func (api *API) queryBatch(instant, queryRange ApiFunc) ApiFunc {
+	return func(r *http.Request) (interface{}, []error, *ApiError) {
+		if err := r.ParseForm(); err != nil {
+			return nil, nil, &ApiError{ErrorInternal, errors.Wrap(err, "parse form")}
+		}
+		queries := r.Form["query"]
+		if len(queries) == 0 {
+			return nil, nil, &ApiError{errorBadData, fmt.Errorf("no query parameter provided")}
+		}
+		if len(queries) > maxBatchQueries {
+			return nil, nil, &ApiError{errorBadData, errors.Errorf("batch of %d queries exceeds the maximum of %d", len(queries), maxBatchQueries)}
+		}
+
+		f := instant
+		if r.Form.Get("step") != "" {
+			f = queryRange
+		}
+		// Series requests shared by the queries run with the context of the batch, which ends with the last query.
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		ctx = query.ContextWithSharedSeries(ctx)
+
+		var (
+			wg       sync.WaitGroup
+			mtx      sync.Mutex
+			warnings []error
+			results  = make([]batchQueryResult, len(queries))
+			gate     = make(chan struct{}, api.batchConcurrency())
+		)
+		for i, qs := range queries {
+			form := make(url.Values, len(r.Form))
+			for k, v := range r.Form {
+				form[k] = v
+			}
+			form["query"] = []string{qs}
+
+			sub := r.WithContext(ctx)
+			sub.Form = form
+			// Prevent parsing the already consumed body again.
+			sub.PostForm = url.Values{}
+
+			wg.Add(1)
+			go func(i int, sub *http.Request) {
+				defer wg.Done()
+
+				select {
+				case gate <- struct{}{}:
+					defer func() { <-gate }()
+				case <-ctx.Done():
+					results[i] = batchQueryResult{ErrorType: errorCanceled, Error: ctx.Err().Error()}
+					return
+				}
+
+				data, ws, apiErr := f(sub)
+				if apiErr != nil {
+					results[i] = batchQueryResult{ErrorType: apiErr.Typ, Error: apiErr.Err.Error()}
+					return
+				}
+				results[i] = batchQueryResult{Data: data}
+
+				mtx.Lock()
+				warnings = append(warnings, ws...)
+				mtx.Unlock()
+			}(i, sub)
+		}
+		wg.Wait()
+
+		return results, warnings, nil
+	}
+}
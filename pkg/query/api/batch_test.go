@@ -0,0 +1,136 @@
+package v1
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/query"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql"
+)
+
+type countingStore struct {
+	storepb.StoreServer
+	calls int32
+}
+
+func (s *countingStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	atomic.AddInt32(&s.calls, 1)
+	return s.StoreServer.Series(r, srv)
+}
+
+func TestAPI_QueryBatch(t *testing.T) {
+	st := &countingStore{StoreServer: &seriesStore{series: []labels.Labels{
+		labels.FromStrings("__name__", "up", "job", "a"),
+		labels.FromStrings("__name__", "up", "job", "b"),
+	}}}
+	api := &API{
+		logger:               log.NewNopLogger(),
+		queryableCreate:      query.NewQueryableCreator(nil, st, nil, nil),
+		queryEngine:          promql.NewEngine(promql.EngineOpts{MaxConcurrent: 10, MaxSamples: 1e8, Timeout: time.Minute}),
+		instantQueryDuration: prometheus.NewHistogram(prometheus.HistogramOpts{}),
+		rangeQueryDuration:   prometheus.NewHistogram(prometheus.HistogramOpts{}),
+		now:                  time.Now,
+	}
+	batch := api.queryBatch(api.query, api.queryRange)
+	request := func(params url.Values) *http.Request {
+		r, err := http.NewRequest("POST", "http://example.com/api/v1/query_batch", strings.NewReader(params.Encode()))
+		testutil.Ok(t, err)
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+
+	t.Run("range", func(t *testing.T) {
+		atomic.StoreInt32(&st.calls, 0)
+		data, _, apiErr := batch(request(url.Values{
+			"query": []string{`sum(up)`, `sum by (job) (up)`, `up offset 1m`, `up +`},
+			"start": []string{"0"},
+			"end":   []string{"120"},
+			"step":  []string{"10"},
+		}))
+		testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+
+		results := data.([]batchQueryResult)
+		testutil.Equals(t, 4, len(results))
+		testutil.Equals(t, promql.Matrix{{
+			Metric: labels.Labels{},
+			Points: points(0, 120000, 10000, 1),
+		}}, results[0].Data.(*queryData).Result)
+		testutil.Equals(t, promql.Matrix{{
+			Metric: labels.FromStrings("job", "a"),
+			Points: points(0, 120000, 10000, 0),
+		}, {
+			Metric: labels.FromStrings("job", "b"),
+			Points: points(0, 120000, 10000, 1),
+		}}, results[1].Data.(*queryData).Result)
+		testutil.Equals(t, 2, len(results[2].Data.(*queryData).Result.(promql.Matrix)))
+		testutil.Equals(t, ErrorType(errorBadData), results[3].ErrorType)
+
+		// Both sums select the same series.
+		testutil.Equals(t, int32(2), atomic.LoadInt32(&st.calls))
+	})
+
+	t.Run("instant", func(t *testing.T) {
+		data, _, apiErr := batch(request(url.Values{
+			"query": []string{`sum(up)`, `max(up)`},
+			"time":  []string{"60"},
+		}))
+		testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+
+		results := data.([]batchQueryResult)
+		testutil.Equals(t, 2, len(results))
+		testutil.Equals(t, promql.Vector{{Metric: labels.Labels{}, Point: promql.Point{T: 60000, V: 1}}}, results[0].Data.(*queryData).Result)
+		testutil.Equals(t, promql.Vector{{Metric: labels.Labels{}, Point: promql.Point{T: 60000, V: 1}}}, results[1].Data.(*queryData).Result)
+	})
+
+	t.Run("no query", func(t *testing.T) {
+		_, _, apiErr := batch(request(url.Values{"time": []string{"60"}}))
+		testutil.Assert(t, apiErr != nil, "expected error")
+		testutil.Equals(t, ErrorType(errorBadData), apiErr.Typ)
+	})
+}
+
+func TestAPI_QueryBatch_Concurrency(t *testing.T) {
+	api := &API{scheduler: newQueryScheduler(4, 0, nil)}
+
+	var running, maxRunning int32
+	f := func(*http.Request) (interface{}, []error, *ApiError) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil, nil, nil
+	}
+
+	r, err := http.NewRequest("GET", "http://example.com/api/v1/query_batch?"+url.Values{
+		"query": []string{"a", "b", "c", "d", "e", "f"},
+	}.Encode(), nil)
+	testutil.Ok(t, err)
+	data, _, apiErr := api.queryBatch(f, f)(r)
+	testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+	testutil.Equals(t, 6, len(data.([]batchQueryResult)))
+
+	// A batch takes at most half of the query slots.
+	testutil.Equals(t, int32(2), atomic.LoadInt32(&maxRunning))
+}
+
+func points(mint, maxt, step int64, v float64) []promql.Point {
+	var res []promql.Point
+	for t := mint; t <= maxt; t += step {
+		res = append(res, promql.Point{T: t, V: v})
+	}
+	return res
+}
@@ -0,0 +1,171 @@
+package v1
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+)
+
+// maxInstantQueryCacheEntries bounds the memory used by the instant query cache. Results of further queries are not
+// cached until entries expire.
+const maxInstantQueryCacheEntries = 10000
+
+// instantQueryKey identifies instant queries answered with the same result.
+type instantQueryKey struct {
+	query string
+	// ts is the evaluation timestamp in milliseconds rounded down to the cache step.
+	ts              int64
+	dedup           bool
+	partialResponse bool
+	// tenant is the tenant the query is limited to, if tenancy is enforced.
+	tenant string
+}
+
+type cachedInstantQuery struct {
+	data     interface{}
+	warnings []error
+	expires  time.Time
+}
+
+// instantQueryCache caches results of instant queries for a short TTL, so that identical queries, e.g. of the same
+// dashboard opened by many users or alerts evaluated by many rulers, are only evaluated once. Evaluation timestamps
+// are rounded down to the step, so that queries at now refreshed every few seconds hit the same entry. Queries are
+// evaluated at the rounded timestamp, so the entry is the result of all queries sharing it.
+type instantQueryCache struct {
+	ttl  time.Duration
+	step time.Duration
+	now  func() time.Time
+
+	mtx     sync.Mutex
+	entries map[instantQueryKey]cachedInstantQuery
+
+	requests prometheus.Counter
+	hits     prometheus.Counter
+}
+
+// newInstantQueryCache returns a cache keeping results for the given TTL. A step of 0 rounds timestamps to the TTL.
+func newInstantQueryCache(ttl, step time.Duration, reg prometheus.Registerer) *instantQueryCache {
+	if step <= 0 {
+		step = ttl
+	}
+	c := &instantQueryCache{
+		ttl:     ttl,
+		step:    step,
+		now:     time.Now,
+		entries: map[instantQueryKey]cachedInstantQuery{},
+		requests: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "thanos_query_api_instant_cache_requests_total",
+			Help: "Total number of instant queries looked up in the results cache.",
+		}),
+		hits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "thanos_query_api_instant_cache_hits_total",
+			Help: "Total number of instant queries answered from the results cache.",
+		}),
+	}
+	if reg != nil {
+		reg.MustRegister(c.requests, c.hits)
+	}
+	return c
+}
+
+func (c *instantQueryCache) key(query string, ts time.Time, dedup, partialResponse bool) instantQueryKey {
+	stepMillis := int64(c.step / time.Millisecond)
+	millis := timestamp.FromTime(ts)
+	if stepMillis > 0 {
+		millis -= millis % stepMillis
+	}
+	return instantQueryKey{query: query, ts: millis, dedup: dedup, partialResponse: partialResponse}
+}
+
+func (c *instantQueryCache) get(k instantQueryKey) (interface{}, []error, bool) {
+	c.requests.Inc()
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.entries[k]
+	if !ok {
+		return nil, nil, false
+	}
+	if !c.now().Before(e.expires) {
+		delete(c.entries, k)
+		return nil, nil, false
+	}
+	c.hits.Inc()
+	return e.data, e.warnings, true
+}
+
+func (c *instantQueryCache) set(k instantQueryKey, data interface{}, warnings []error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	now := c.now()
+	if len(c.entries) >= maxInstantQueryCacheEntries {
+		for key, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, key)
+			}
+		}
+		if len(c.entries) >= maxInstantQueryCacheEntries {
+			return
+		}
+	}
+	c.entries[k] = cachedInstantQuery{data: data, warnings: warnings, expires: now.Add(c.ttl)}
+}
+
+// cached wraps the given instant query function so that successful results are served from the instant query cache.
+// Queries missing the cache are evaluated at the timestamp of their key. Queries requesting statistics are never
+// cached, as the statistics would not describe the request.
+func (api *API) cached(f ApiFunc) ApiFunc {
+	if api.instantCache == nil {
+		return f
+	}
+	return func(r *http.Request) (interface{}, []error, *ApiError) {
+		if r.FormValue("stats") != "" {
+			return f(r)
+		}
+		k, ok := api.instantQueryKey(r)
+		if !ok {
+			// Let the query function report the invalid parameters.
+			return f(r)
+		}
+		if data, warnings, ok := api.instantCache.get(k); ok {
+			return data, warnings, nil
+		}
+
+		r.Form.Set("time", timestamp.Time(k.ts).Format(time.RFC3339Nano))
+		data, warnings, apiErr := f(r)
+		if apiErr == nil {
+			api.instantCache.set(k, data, warnings)
+		}
+		return data, warnings, apiErr
+	}
+}
+
+// instantQueryKey returns the cache key of the instant query request, false if its parameters are invalid.
+func (api *API) instantQueryKey(r *http.Request) (instantQueryKey, bool) {
+	ts := api.now()
+	if t := r.FormValue("time"); t != "" {
+		var err error
+		ts, err = parseTime(t)
+		if err != nil {
+			return instantQueryKey{}, false
+		}
+	}
+	enableDedup, apiErr := api.parseEnableDedupParam(r)
+	if apiErr != nil {
+		return instantQueryKey{}, false
+	}
+	enablePartialResponse, apiErr := api.parsePartialResponseParam(r)
+	if apiErr != nil {
+		return instantQueryKey{}, false
+	}
+	k := api.instantCache.key(r.FormValue("query"), ts, enableDedup, enablePartialResponse)
+	if api.tenantLabel != "" {
+		k.tenant = r.Header.Get(api.tenantHeader)
+	}
+	return k, true
+}
@@ -0,0 +1,121 @@
+package v1
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+)
+
+func TestAPI_CachedInstantQuery(t *testing.T) {
+	now := time.Unix(1000, 0)
+	api := &API{
+		enablePartialResponse: true,
+		instantCache:          newInstantQueryCache(time.Minute, 10*time.Second, nil),
+		now:                   func() time.Time { return now },
+	}
+	api.instantCache.now = func() time.Time { return now }
+
+	var evaluated int
+	f := api.cached(func(r *http.Request) (interface{}, []error, *ApiError) {
+		evaluated++
+		if r.FormValue("query") == "fail" {
+			return nil, nil, &ApiError{errorExec, errors.New("failed")}
+		}
+		return evaluated, []error{errors.New("warning")}, nil
+	})
+	call := func(values url.Values) (interface{}, []error, *ApiError) {
+		r, err := http.NewRequest("GET", "http://example.com?"+values.Encode(), nil)
+		testutil.Ok(t, err)
+		return f(r)
+	}
+
+	data, warnings, apiErr := call(url.Values{"query": {"up"}, "time": {"1000"}})
+	testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+	testutil.Equals(t, 1, data)
+	testutil.Equals(t, 1, len(warnings))
+
+	// Times within the same step and the default time now share the result, warnings included.
+	for _, values := range []url.Values{
+		{"query": {"up"}, "time": {"1009.9"}},
+		{"query": {"up"}},
+		{"query": {"up"}, "dedup": {"true"}},
+	} {
+		data, warnings, apiErr = call(values)
+		testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+		testutil.Equals(t, 1, data)
+		testutil.Equals(t, 1, len(warnings))
+	}
+	testutil.Equals(t, 1, evaluated)
+
+	// Other steps, queries, dedup and partial response settings are evaluated.
+	for i, values := range []url.Values{
+		{"query": {"up"}, "time": {"1010"}},
+		{"query": {"down"}, "time": {"1000"}},
+		{"query": {"up"}, "time": {"1000"}, "dedup": {"false"}},
+		{"query": {"up"}, "time": {"1000"}, "partial_response_strategy": {"abort"}},
+	} {
+		data, _, apiErr = call(values)
+		testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+		testutil.Equals(t, i+2, data)
+	}
+
+	// Requests for statistics, invalid parameters and errors are never cached.
+	for _, values := range []url.Values{
+		{"query": {"up"}, "time": {"1000"}, "stats": {"true"}},
+		{"query": {"up"}, "time": {"1000"}, "dedup": {"maybe"}},
+		{"query": {"fail"}, "time": {"1000"}},
+		{"query": {"fail"}, "time": {"1000"}},
+	} {
+		evaluated = 0
+		_, _, _ = call(values)
+		testutil.Equals(t, 1, evaluated)
+	}
+
+	// Expired results are evaluated again.
+	evaluated = 10
+	now = now.Add(time.Minute)
+	data, _, apiErr = call(url.Values{"query": {"up"}, "time": {"1000"}})
+	testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+	testutil.Equals(t, 11, data)
+}
+
+func TestAPI_CachedInstantQuery_RoundedTime(t *testing.T) {
+	now := time.Unix(1005, 0)
+	api := &API{
+		enablePartialResponse: true,
+		instantCache:          newInstantQueryCache(time.Minute, 10*time.Second, nil),
+		now:                   func() time.Time { return now },
+	}
+	api.instantCache.now = func() time.Time { return now }
+
+	var evaluated []int64
+	f := api.cached(func(r *http.Request) (interface{}, []error, *ApiError) {
+		ts, err := parseTime(r.FormValue("time"))
+		testutil.Ok(t, err)
+		evaluated = append(evaluated, timestamp.FromTime(ts))
+		return ts.Unix(), nil, nil
+	})
+	call := func(values url.Values) interface{} {
+		r, err := http.NewRequest("GET", "http://example.com?"+values.Encode(), nil)
+		testutil.Ok(t, err)
+		data, _, apiErr := f(r)
+		testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+		return data
+	}
+
+	// Queries at different times within a step get the result at the start of the step, whichever comes first.
+	testutil.Equals(t, int64(1000), call(url.Values{"query": {"up"}, "time": {"1007.5"}}))
+	testutil.Equals(t, int64(1000), call(url.Values{"query": {"up"}, "time": {"1002"}}))
+	testutil.Equals(t, int64(1000), call(url.Values{"query": {"up"}}))
+	testutil.Equals(t, []int64{1000000}, evaluated)
+
+	// The rounded timestamp keeps milliseconds.
+	api.instantCache.step = 10 * time.Millisecond
+	call(url.Values{"query": {"up"}, "time": {"1000.127"}})
+	testutil.Equals(t, int64(1000120), evaluated[1])
+}
@@ -0,0 +1,118 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/query"
+	"github.com/improbable-eng/thanos/pkg/runutil"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"github.com/prometheus/prometheus/promql"
+)
+
+var exportContentTypes = map[string]string{
+	query.ExportCSV:    "text/csv",
+	query.ExportNDJSON: "application/x-ndjson",
+}
+
+// export streams the raw samples of all series matching the match[] selectors between start and end in the
+// requested format, without PromQL evaluation. Errors after the first bytes have been written can only be logged.
+func (api *API) export(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		RespondError(w, &ApiError{ErrorInternal, errors.Wrap(err, "parse form")}, nil)
+		return
+	}
+	if len(r.Form["match[]"]) == 0 {
+		RespondError(w, &ApiError{errorBadData, fmt.Errorf("no match[] parameter provided")}, nil)
+		return
+	}
+
+	start, end := minTime, maxTime
+	if t := r.FormValue("start"); t != "" {
+		var err error
+		if start, err = parseTime(t); err != nil {
+			RespondError(w, &ApiError{errorBadData, err}, nil)
+			return
+		}
+	}
+	if t := r.FormValue("end"); t != "" {
+		var err error
+		if end, err = parseTime(t); err != nil {
+			RespondError(w, &ApiError{errorBadData, err}, nil)
+			return
+		}
+	}
+	if end.Before(start) {
+		RespondError(w, &ApiError{errorBadData, errors.New("end timestamp must not be before start time")}, nil)
+		return
+	}
+
+	var matcherSets [][]*labels.Matcher
+	for _, s := range r.Form["match[]"] {
+		matchers, err := promql.ParseMetricSelector(s)
+		if err != nil {
+			RespondError(w, &ApiError{errorBadData, err}, nil)
+			return
+		}
+		matcherSets = append(matcherSets, matchers)
+	}
+
+	enableDedup, apiErr := api.parseEnableDedupParam(r)
+	if apiErr != nil {
+		RespondError(w, apiErr, nil)
+		return
+	}
+	enablePartialResponse, apiErr := api.parsePartialResponseParam(r)
+	if apiErr != nil {
+		RespondError(w, apiErr, nil)
+		return
+	}
+
+	if api.tenantLabel != "" {
+		tenant := r.Header.Get(api.tenantHeader)
+		if tenant == "" {
+			RespondError(w, &ApiError{errorBadData, errors.Errorf("missing tenant in header %s", api.tenantHeader)}, nil)
+			return
+		}
+		for _, ms := range matcherSets {
+			if err := query.ValidateTenantMatchers(api.tenantLabel, tenant, ms); err != nil {
+				RespondError(w, &ApiError{errorBadData, err}, nil)
+				return
+			}
+		}
+		ctx = query.ContextWithTenant(ctx, api.tenantLabel, tenant)
+	}
+
+	format := r.FormValue("format")
+	if format == "" {
+		format = query.ExportCSV
+	}
+	// Series writers buffer their output, so nothing is written before the export started.
+	sw, err := query.NewSeriesWriter(format, w)
+	if err != nil {
+		RespondError(w, &ApiError{errorBadData, err}, nil)
+		return
+	}
+
+	warningReporter := func(err error) {
+		level.Warn(api.logger).Log("msg", "partial response of series export", "err", err)
+	}
+	mint, maxt := timestamp.FromTime(start), timestamp.FromTime(end)
+	q, err := api.queryableCreate(enableDedup, 0, enablePartialResponse, warningReporter).Querier(ctx, mint, maxt)
+	if err != nil {
+		RespondError(w, &ApiError{errorExec, err}, nil)
+		return
+	}
+	defer runutil.CloseWithLogOnErr(api.logger, q, "queryable export")
+
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	if err := query.ExportSeries(q, mint, maxt, matcherSets, sw); err != nil {
+		// The status has been written with the first bytes already.
+		level.Warn(api.logger).Log("msg", "writing series export", "err", err)
+	}
+}
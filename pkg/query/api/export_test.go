@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/query"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+func TestAPI_Export(t *testing.T) {
+	st := &seriesStore{series: []labels.Labels{
+		labels.FromStrings("__name__", "up", "job", "a"),
+		labels.FromStrings("__name__", "up", "job", "b"),
+	}}
+	api := &API{
+		logger:          log.NewNopLogger(),
+		queryableCreate: query.NewQueryableCreator(nil, st, nil, nil),
+	}
+	export := func(params string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("GET", "http://example.com/api/v1/export?"+params, nil)
+		testutil.Ok(t, err)
+		w := httptest.NewRecorder()
+		api.export(w, r)
+		return w
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		// Both selectors match job="a", it is exported once.
+		w := export(`match[]=up&match[]=up{job="a"}&start=10&end=50`)
+		testutil.Equals(t, http.StatusOK, w.Code)
+		testutil.Equals(t, "text/csv", w.Header().Get("Content-Type"))
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		testutil.Ok(t, err)
+		testutil.Equals(t, 1+2*41, len(records))
+		testutil.Equals(t, []string{"series", "timestamp", "value"}, records[0])
+		testutil.Equals(t, []string{`{__name__="up", job="a"}`, "10000", "0"}, records[1])
+		testutil.Equals(t, []string{`{__name__="up", job="a"}`, "50000", "0"}, records[41])
+		testutil.Equals(t, []string{`{__name__="up", job="b"}`, "10000", "1"}, records[42])
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		w := export(`match[]=up&start=10&end=50&format=ndjson`)
+		testutil.Equals(t, http.StatusOK, w.Code)
+		testutil.Equals(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+		var series []map[string]interface{}
+		sc := bufio.NewScanner(w.Body)
+		for sc.Scan() {
+			var s map[string]interface{}
+			testutil.Ok(t, json.Unmarshal(sc.Bytes(), &s))
+			series = append(series, s)
+		}
+		testutil.Ok(t, sc.Err())
+		testutil.Equals(t, 2, len(series))
+		for i, s := range series {
+			testutil.Equals(t, st.series[i].Map()["job"], s["labels"].(map[string]interface{})["job"])
+			testutil.Equals(t, 41, len(s["timestamps"].([]interface{})))
+			testutil.Equals(t, 41, len(s["values"].([]interface{})))
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, params := range []string{
+			`start=10&end=50`,
+			`match[]=up&format=parquet`,
+			`match[]=up&format=xml`,
+			`match[]=up&start=50&end=10`,
+		} {
+			w := export(params)
+			testutil.Equals(t, http.StatusBadRequest, w.Code)
+			testutil.Equals(t, "application/json", w.Header().Get("Content-Type"))
+		}
+	})
+}
@@ -0,0 +1,320 @@
+package v1
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/improbable-eng/thanos/pkg/query"
+	"github.com/improbable-eng/thanos/pkg/store/prompb"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/tsdb/chunkenc"
+)
+
+const (
+	// remoteReadMaxFrameBytes is the size from which on the chunks of a series are split into multiple frames.
+	remoteReadMaxFrameBytes = 1024 * 1024
+	// remoteReadMaxChunkSamples is the number of samples per chunk of streamed responses, as in TSDB blocks.
+	remoteReadMaxChunkSamples = 120
+)
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// remoteRead serves the Prometheus remote read API over the deduplicated data of all stores, so that other
+// Prometheus servers or Cortex can read data from Thanos. Both sampled and streamed chunked responses are supported.
+func (api *API) remoteRead(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	enableDedup, apiErr := api.parseEnableDedupParam(r)
+	if apiErr != nil {
+		http.Error(w, apiErr.Err.Error(), http.StatusBadRequest)
+		return
+	}
+	enablePartialResponse, apiErr := api.parsePartialResponseParam(r)
+	if apiErr != nil {
+		http.Error(w, apiErr.Err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req, err := decodeReadRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	matchers := make([][]*labels.Matcher, 0, len(req.Queries))
+	for _, q := range req.Queries {
+		ms, err := fromPromMatchers(q.Matchers)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		matchers = append(matchers, ms)
+	}
+
+	if api.tenantLabel != "" {
+		tenant := r.Header.Get(api.tenantHeader)
+		if tenant == "" {
+			http.Error(w, errors.Errorf("missing tenant in header %s", api.tenantHeader).Error(), http.StatusBadRequest)
+			return
+		}
+		for _, ms := range matchers {
+			if err := query.ValidateTenantMatchers(api.tenantLabel, tenant, ms); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		ctx = query.ContextWithTenant(ctx, api.tenantLabel, tenant)
+	}
+
+	warningReporter := func(err error) {
+		level.Warn(api.logger).Log("msg", "partial response of remote read", "err", err)
+	}
+	queryable := api.queryableCreate(enableDedup, 0, enablePartialResponse, warningReporter)
+
+	// The first accepted response type we support is used.
+	responseType := prompb.ReadRequest_SAMPLES
+	for _, t := range req.AcceptedResponseTypes {
+		if t == prompb.ReadRequest_SAMPLES || t == prompb.ReadRequest_STREAMED_XOR_CHUNKS {
+			responseType = t
+			break
+		}
+	}
+
+	if responseType == prompb.ReadRequest_SAMPLES {
+		resp := &prompb.ReadResponse{Results: make([]prompb.QueryResult, 0, len(req.Queries))}
+		for i, q := range req.Queries {
+			var res prompb.QueryResult
+			if err := selectRemoteRead(ctx, queryable, q, matchers[i], func(lset labels.Labels, it storage.SeriesIterator) error {
+				ts := prompb.TimeSeries{Labels: toPromLabels(lset)}
+				for ok := true; ok; ok = it.Next() {
+					t, v := it.At()
+					if t > q.EndTimestampMs {
+						break
+					}
+					ts.Samples = append(ts.Samples, prompb.Sample{Timestamp: t, Value: v})
+				}
+				if err := it.Err(); err != nil {
+					return err
+				}
+				res.Timeseries = append(res.Timeseries, ts)
+				return nil
+			}); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			resp.Results = append(resp.Results, res)
+		}
+
+		b, err := proto.Marshal(resp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Header().Set("Content-Encoding", "snappy")
+		if _, err := w.Write(snappy.Encode(nil, b)); err != nil {
+			level.Warn(api.logger).Log("msg", "writing remote read response", "err", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse")
+	fw := &frameWriter{w: w}
+	if f, ok := w.(http.Flusher); ok {
+		fw.flusher = f
+	}
+	for i, q := range req.Queries {
+		if err := selectRemoteRead(ctx, queryable, q, matchers[i], func(lset labels.Labels, it storage.SeriesIterator) error {
+			return streamChunkedSeries(fw, int64(i), toPromLabels(lset), it, q.EndTimestampMs)
+		}); err != nil {
+			// The status has been written with the first frame already.
+			level.Warn(api.logger).Log("msg", "streaming remote read response", "err", err)
+			return
+		}
+	}
+}
+
+func decodeReadRequest(r *http.Request) (*prompb.ReadRequest, error) {
+	compressed, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read request body")
+	}
+	b, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, errors.Wrap(err, "decompress request body")
+	}
+	var req prompb.ReadRequest
+	if err := proto.Unmarshal(b, &req); err != nil {
+		return nil, errors.Wrap(err, "unmarshal read request")
+	}
+	return &req, nil
+}
+
+// selectRemoteRead selects the series of the remote read query and calls f with each of them with the iterator
+// positioned at the first sample of the query time range.
+func selectRemoteRead(
+	ctx context.Context,
+	queryable storage.Queryable,
+	q prompb.Query,
+	matchers []*labels.Matcher,
+	f func(labels.Labels, storage.SeriesIterator) error,
+) error {
+	querier, err := queryable.Querier(ctx, q.StartTimestampMs, q.EndTimestampMs)
+	if err != nil {
+		return errors.Wrap(err, "create querier")
+	}
+	defer func() { _ = querier.Close() }()
+
+	set, _, err := querier.Select(&storage.SelectParams{Start: q.StartTimestampMs, End: q.EndTimestampMs}, matchers...)
+	if err != nil {
+		return errors.Wrap(err, "select series")
+	}
+	for set.Next() {
+		s := set.At()
+		it := s.Iterator()
+		if !it.Seek(q.StartTimestampMs) {
+			if err := it.Err(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := f(s.Labels(), it); err != nil {
+			return err
+		}
+	}
+	return set.Err()
+}
+
+// streamChunkedSeries writes the samples of the series up to maxt as XOR chunks in frames of the chunked response.
+func streamChunkedSeries(fw *frameWriter, queryIndex int64, lset []prompb.Label, it storage.SeriesIterator, maxt int64) error {
+	var (
+		chks       []prompb.Chunk
+		frameBytes int
+		chk        *chunkenc.XORChunk
+		app        chunkenc.Appender
+		mint, t    int64
+	)
+	flushChunk := func() {
+		if chk == nil {
+			return
+		}
+		chks = append(chks, prompb.Chunk{MinTimeMs: mint, MaxTimeMs: t, Type: prompb.Chunk_XOR, Data: chk.Bytes()})
+		frameBytes += len(chk.Bytes())
+		chk = nil
+	}
+	flushFrame := func() error {
+		if len(chks) == 0 {
+			return nil
+		}
+		err := fw.write(&prompb.ChunkedReadResponse{
+			ChunkedSeries: []*prompb.ChunkedSeries{{Labels: lset, Chunks: chks}},
+			QueryIndex:    queryIndex,
+		})
+		chks, frameBytes = nil, 0
+		return err
+	}
+
+	for ok := true; ok; ok = it.Next() {
+		st, v := it.At()
+		if st > maxt {
+			break
+		}
+		if chk == nil {
+			chk = chunkenc.NewXORChunk()
+			var err error
+			if app, err = chk.Appender(); err != nil {
+				return err
+			}
+			mint = st
+		}
+		app.Append(st, v)
+		t = st
+
+		if chk.NumSamples() >= remoteReadMaxChunkSamples {
+			flushChunk()
+			if frameBytes >= remoteReadMaxFrameBytes {
+				if err := flushFrame(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	flushChunk()
+	return flushFrame()
+}
+
+// frameWriter writes messages delimited by their uvarint size and followed by their CRC32 Castagnoli checksum, as
+// expected by Prometheus for streamed remote read responses.
+type frameWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+	buf     [binary.MaxVarintLen64]byte
+}
+
+func (fw *frameWriter) write(m proto.Message) error {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, "marshal frame")
+	}
+
+	n := binary.PutUvarint(fw.buf[:], uint64(len(b)))
+	if _, err := fw.w.Write(fw.buf[:n]); err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(fw.buf[:4], crc32.Checksum(b, castagnoliTable))
+	if _, err := fw.w.Write(fw.buf[:4]); err != nil {
+		return err
+	}
+	if _, err := fw.w.Write(b); err != nil {
+		return err
+	}
+	if fw.flusher != nil {
+		fw.flusher.Flush()
+	}
+	return nil
+}
+
+func fromPromMatchers(pms []prompb.LabelMatcher) ([]*labels.Matcher, error) {
+	res := make([]*labels.Matcher, 0, len(pms))
+	for _, pm := range pms {
+		var t labels.MatchType
+		switch pm.Type {
+		case prompb.LabelMatcher_EQ:
+			t = labels.MatchEqual
+		case prompb.LabelMatcher_NEQ:
+			t = labels.MatchNotEqual
+		case prompb.LabelMatcher_RE:
+			t = labels.MatchRegexp
+		case prompb.LabelMatcher_NRE:
+			t = labels.MatchNotRegexp
+		default:
+			return nil, errors.Errorf("unknown matcher type %d", pm.Type)
+		}
+		m, err := labels.NewMatcher(t, pm.Name, pm.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "matcher %s", pm.Name)
+		}
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+func toPromLabels(lset labels.Labels) []prompb.Label {
+	res := make([]prompb.Label, 0, len(lset))
+	for _, l := range lset {
+		res = append(res, prompb.Label{Name: l.Name, Value: l.Value})
+	}
+	return res
+}
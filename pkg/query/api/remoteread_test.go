@@ -0,0 +1,115 @@
+package v1
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/improbable-eng/thanos/pkg/query"
+	"github.com/improbable-eng/thanos/pkg/store/prompb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/tsdb/chunkenc"
+)
+
+func remoteReadRequest(t *testing.T, req *prompb.ReadRequest) *http.Request {
+	b, err := proto.Marshal(req)
+	testutil.Ok(t, err)
+	r, err := http.NewRequest("POST", "http://example.com/api/v1/read", bytes.NewReader(snappy.Encode(nil, b)))
+	testutil.Ok(t, err)
+	return r
+}
+
+func TestAPI_RemoteRead(t *testing.T) {
+	st := &seriesStore{series: []labels.Labels{
+		labels.FromStrings("__name__", "up", "job", "a"),
+		labels.FromStrings("__name__", "up", "job", "b"),
+	}}
+	api := &API{
+		logger:          log.NewNopLogger(),
+		queryableCreate: query.NewQueryableCreator(nil, st, nil, nil),
+	}
+	q := prompb.Query{
+		StartTimestampMs: 10000,
+		EndTimestampMs:   50000,
+		Matchers:         []prompb.LabelMatcher{{Type: prompb.LabelMatcher_EQ, Name: "__name__", Value: "up"}},
+	}
+
+	t.Run("samples", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		api.remoteRead(w, remoteReadRequest(t, &prompb.ReadRequest{Queries: []prompb.Query{q}}))
+		testutil.Equals(t, http.StatusOK, w.Code)
+		testutil.Equals(t, "snappy", w.Header().Get("Content-Encoding"))
+
+		b, err := snappy.Decode(nil, w.Body.Bytes())
+		testutil.Ok(t, err)
+		var resp prompb.ReadResponse
+		testutil.Ok(t, proto.Unmarshal(b, &resp))
+
+		testutil.Equals(t, 1, len(resp.Results))
+		testutil.Equals(t, 2, len(resp.Results[0].Timeseries))
+		for i, ts := range resp.Results[0].Timeseries {
+			testutil.Equals(t, toPromLabels(st.series[i]), ts.Labels)
+			testutil.Equals(t, 41, len(ts.Samples))
+			testutil.Equals(t, prompb.Sample{Timestamp: 10000, Value: float64(i)}, ts.Samples[0])
+			testutil.Equals(t, prompb.Sample{Timestamp: 50000, Value: float64(i)}, ts.Samples[40])
+		}
+	})
+
+	t.Run("streamed chunks", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		api.remoteRead(w, remoteReadRequest(t, &prompb.ReadRequest{
+			Queries:               []prompb.Query{q, q},
+			AcceptedResponseTypes: []prompb.ReadRequest_ResponseType{prompb.ReadRequest_STREAMED_XOR_CHUNKS},
+		}))
+		testutil.Equals(t, http.StatusOK, w.Code)
+		testutil.Equals(t, "application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse", w.Header().Get("Content-Type"))
+
+		var frames []prompb.ChunkedReadResponse
+		rd := bufio.NewReader(w.Body)
+		for {
+			size, err := binary.ReadUvarint(rd)
+			if err == io.EOF {
+				break
+			}
+			testutil.Ok(t, err)
+			crc := make([]byte, 4)
+			_, err = io.ReadFull(rd, crc)
+			testutil.Ok(t, err)
+			b := make([]byte, size)
+			_, err = io.ReadFull(rd, b)
+			testutil.Ok(t, err)
+			testutil.Equals(t, binary.BigEndian.Uint32(crc), crc32.Checksum(b, crc32.MakeTable(crc32.Castagnoli)))
+
+			var frame prompb.ChunkedReadResponse
+			testutil.Ok(t, proto.Unmarshal(b, &frame))
+			frames = append(frames, frame)
+		}
+
+		// One frame per series and query.
+		testutil.Equals(t, 4, len(frames))
+		for i, frame := range frames {
+			testutil.Equals(t, int64(i/2), frame.QueryIndex)
+			testutil.Equals(t, 1, len(frame.ChunkedSeries))
+			testutil.Equals(t, toPromLabels(st.series[i%2]), frame.ChunkedSeries[0].Labels)
+
+			var samples int
+			for _, c := range frame.ChunkedSeries[0].Chunks {
+				chk, err := chunkenc.FromData(chunkenc.EncXOR, c.Data)
+				testutil.Ok(t, err)
+				samples += chk.NumSamples()
+				testutil.Equals(t, int64(10000), c.MinTimeMs)
+				testutil.Equals(t, int64(50000), c.MaxTimeMs)
+			}
+			testutil.Equals(t, 41, samples)
+		}
+	})
+}
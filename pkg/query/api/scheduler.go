@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// errQueueFull is returned by the scheduler if no query can be queued anymore.
+var errQueueFull = errors.New("too many queries in flight and queued, try again later")
+
+// queryScheduler limits the number of concurrently evaluated PromQL queries. Queries waiting for their turn are
+// queued, up to a maximum number of queued queries, further queries are rejected right away so that bursts of
+// queries, e.g. of dashboards opened at once, cannot exhaust the memory of the querier.
+type queryScheduler struct {
+	slots chan struct{}
+
+	mtx       sync.Mutex
+	queued    int
+	maxQueued int
+
+	inflight      prometheus.Gauge
+	queuedGauge   prometheus.Gauge
+	rejected      prometheus.Counter
+	queueDuration prometheus.Histogram
+}
+
+// newQueryScheduler returns a scheduler evaluating at most maxConcurrent queries at a time and queueing at most
+// maxQueued queries. maxQueued of 0 means no limit of queued queries.
+func newQueryScheduler(maxConcurrent, maxQueued int, reg prometheus.Registerer) *queryScheduler {
+	s := &queryScheduler{
+		slots:     make(chan struct{}, maxConcurrent),
+		maxQueued: maxQueued,
+		inflight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "thanos_query_api_queries_in_flight",
+			Help: "Number of PromQL queries that are currently evaluated.",
+		}),
+		queuedGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "thanos_query_api_queries_queued",
+			Help: "Number of PromQL queries that are currently waiting to be evaluated.",
+		}),
+		rejected: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "thanos_query_api_queries_rejected_total",
+			Help: "Total number of PromQL queries rejected because the queue was full.",
+		}),
+		queueDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "thanos_query_api_queue_duration_seconds",
+			Help: "How many seconds PromQL queries waited in the queue before their evaluation.",
+			Buckets: []float64{
+				0.01, 0.05, 0.1, 0.25, 0.6, 1, 2, 3.5, 5, 10, 20,
+			},
+		}),
+	}
+	if reg != nil {
+		reg.MustRegister(s.inflight, s.queuedGauge, s.rejected, s.queueDuration)
+	}
+	return s
+}
+
+// start waits until the query can be evaluated. It returns errQueueFull if the query cannot be queued or the
+// context error if the query was canceled while waiting. Done has to be called after the evaluation if start
+// returned no error.
+func (s *queryScheduler) start(ctx context.Context) error {
+	select {
+	case s.slots <- struct{}{}:
+		s.queueDuration.Observe(0)
+		s.inflight.Inc()
+		return nil
+	default:
+	}
+
+	s.mtx.Lock()
+	if s.maxQueued > 0 && s.queued >= s.maxQueued {
+		s.mtx.Unlock()
+		s.rejected.Inc()
+		return errQueueFull
+	}
+	s.queued++
+	s.mtx.Unlock()
+	s.queuedGauge.Inc()
+
+	begin := time.Now()
+	defer func() {
+		s.mtx.Lock()
+		s.queued--
+		s.mtx.Unlock()
+		s.queuedGauge.Dec()
+		s.queueDuration.Observe(time.Since(begin).Seconds())
+	}()
+
+	select {
+	case s.slots <- struct{}{}:
+		s.inflight.Inc()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// done finishes the evaluation of a query.
+func (s *queryScheduler) done() {
+	s.inflight.Dec()
+	<-s.slots
+}
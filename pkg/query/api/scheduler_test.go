@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestQueryScheduler(t *testing.T) {
+	s := newQueryScheduler(1, 1, prometheus.NewRegistry())
+
+	testutil.Ok(t, s.start(context.Background()))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(s.inflight))
+
+	// The second query waits for the first one.
+	started := make(chan error)
+	go func() {
+		started <- s.start(context.Background())
+	}()
+	for promtest.ToFloat64(s.queuedGauge) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The third query does not fit into the queue anymore.
+	testutil.Equals(t, errQueueFull, s.start(context.Background()))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(s.rejected))
+
+	s.done()
+	testutil.Ok(t, <-started)
+	testutil.Equals(t, 0.0, promtest.ToFloat64(s.queuedGauge))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(s.inflight))
+
+	// Queued queries give up once their context is canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	testutil.Equals(t, context.Canceled, s.start(ctx))
+
+	s.done()
+	testutil.Equals(t, 0.0, promtest.ToFloat64(s.inflight))
+}
+
+func TestScheduled_QueueFull(t *testing.T) {
+	api := &API{scheduler: newQueryScheduler(1, 1, nil)}
+	testutil.Ok(t, api.scheduler.start(context.Background()))
+	api.scheduler.queued = 1
+
+	f := api.scheduled(func(r *http.Request) (interface{}, []error, *ApiError) {
+		t.Fatal("query should not be evaluated")
+		return nil, nil, nil
+	})
+	_, _, apiErr := f(httptest.NewRequest("GET", "/query", nil))
+	testutil.Assert(t, apiErr != nil, "expected error")
+	testutil.Equals(t, ErrorType(errorTooManyRequests), apiErr.Typ)
+
+	w := httptest.NewRecorder()
+	RespondError(w, apiErr, nil)
+	testutil.Equals(t, http.StatusTooManyRequests, w.Code)
+	testutil.Equals(t, queueFullRetryAfter, w.Header().Get("Retry-After"))
+}
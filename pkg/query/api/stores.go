@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+)
+
+// storeStatus is the status of a StoreAPI as returned by the stores API.
+type storeStatus struct {
+	Name      string          `json:"name"`
+	Health    string          `json:"health"`
+	LastCheck time.Time       `json:"lastCheck"`
+	LastError string          `json:"lastError,omitempty"`
+	Labels    []storepb.Label `json:"labels"`
+	MinTime   int64           `json:"minTime"`
+	MaxTime   int64           `json:"maxTime"`
+}
+
+// stores returns the status of all known StoreAPIs grouped by their type, the same data as the stores page of the UI.
+func (api *API) stores(r *http.Request) (interface{}, []error, *ApiError) {
+	res := map[string][]storeStatus{}
+	for _, s := range api.storeStatuses() {
+		st := storeStatus{
+			Name:      s.Name,
+			Health:    "up",
+			LastCheck: s.LastCheck,
+			Labels:    s.Labels,
+			MinTime:   s.MinTime,
+			MaxTime:   s.MaxTime,
+		}
+		if st.Labels == nil {
+			st.Labels = []storepb.Label{}
+		}
+		if s.LastError != nil {
+			st.Health = "down"
+			st.LastError = s.LastError.Error()
+		}
+
+		typ := "unknown"
+		if s.StoreType != nil {
+			typ = s.StoreType.String()
+		}
+		res[typ] = append(res[typ], st)
+	}
+	return res, nil, nil
+}
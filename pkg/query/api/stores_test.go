@@ -0,0 +1,34 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/component"
+	"github.com/improbable-eng/thanos/pkg/query"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+)
+
+func TestAPI_Stores(t *testing.T) {
+	now := time.Unix(100, 0)
+	api := &API{storeStatuses: func() []query.StoreStatus {
+		return []query.StoreStatus{
+			{Name: "sidecar:10901", LastCheck: now, Labels: []storepb.Label{{Name: "replica", Value: "a"}}, StoreType: component.Sidecar, MinTime: 1, MaxTime: 2},
+			{Name: "store:10901", LastCheck: now, StoreType: component.Store, MinTime: 3, MaxTime: 4},
+			{Name: "down:10901", LastCheck: now, LastError: errors.New("connection refused")},
+		}
+	}}
+
+	r, err := http.NewRequest("GET", "http://example.com/api/v1/stores", nil)
+	testutil.Ok(t, err)
+	data, _, apiErr := api.stores(r)
+	testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+	testutil.Equals(t, map[string][]storeStatus{
+		"sidecar": {{Name: "sidecar:10901", Health: "up", LastCheck: now, Labels: []storepb.Label{{Name: "replica", Value: "a"}}, MinTime: 1, MaxTime: 2}},
+		"store":   {{Name: "store:10901", Health: "up", LastCheck: now, Labels: []storepb.Label{}, MinTime: 3, MaxTime: 4}},
+		"unknown": {{Name: "down:10901", Health: "down", LastCheck: now, LastError: "connection refused", Labels: []storepb.Label{}}},
+	}, data)
+}
@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/improbable-eng/thanos/pkg/query"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/promql"
+)
+
+// tenancy wraps the given function so that it only returns series of the tenant given in the tenant header. Requests
+// without tenant and requests whose query or match[] selectors select series of other tenants are rejected, all other
+// requests get a matcher on the tenant label added to all their selectors.
+func (api *API) tenancy(f ApiFunc) ApiFunc {
+	if api.tenantLabel == "" {
+		return f
+	}
+	return func(r *http.Request) (interface{}, []error, *ApiError) {
+		tenant := r.Header.Get(api.tenantHeader)
+		if tenant == "" {
+			return nil, nil, &ApiError{errorBadData, errors.Errorf("missing tenant in header %s", api.tenantHeader)}
+		}
+
+		if qs := r.FormValue("query"); qs != "" {
+			// Unparsable queries are reported by the query function.
+			if expr, err := promql.ParseExpr(qs); err == nil {
+				if err := query.ValidateTenantQuery(api.tenantLabel, tenant, expr); err != nil {
+					return nil, nil, &ApiError{errorBadData, err}
+				}
+			}
+		}
+		for _, s := range r.Form["match[]"] {
+			matchers, err := promql.ParseMetricSelector(s)
+			if err != nil {
+				continue
+			}
+			if err := query.ValidateTenantMatchers(api.tenantLabel, tenant, matchers); err != nil {
+				return nil, nil, &ApiError{errorBadData, err}
+			}
+		}
+		return f(r.WithContext(query.ContextWithTenant(r.Context(), api.tenantLabel, tenant)))
+	}
+}
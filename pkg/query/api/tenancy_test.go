@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/store"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAPI_Tenancy(t *testing.T) {
+	api := &API{tenantLabel: "tenant", tenantHeader: store.DefaultTenantHeader}
+
+	var tenant []string
+	f := api.tenancy(func(r *http.Request) (interface{}, []error, *ApiError) {
+		md, _ := metadata.FromOutgoingContext(r.Context())
+		tenant = md.Get(store.DefaultTenantHeader)
+		return "ok", nil, nil
+	})
+	call := func(url, tenant string) *ApiError {
+		r, err := http.NewRequest("GET", url, nil)
+		testutil.Ok(t, err)
+		if tenant != "" {
+			r.Header.Set(store.DefaultTenantHeader, tenant)
+		}
+		_, _, apiErr := f(r)
+		return apiErr
+	}
+
+	// Requests without tenant are rejected.
+	testutil.Assert(t, call("http://example.com/api/v1/query?query=up", "") != nil, "expected error without tenant")
+
+	testutil.Assert(t, call("http://example.com/api/v1/query?query=up", "team-a") == nil, "unexpected error")
+	testutil.Equals(t, []string{"team-a"}, tenant)
+
+	// Selectors of other tenants are rejected.
+	apiErr := call(`http://example.com/api/v1/query?query=up{tenant="team-b"}`, "team-a")
+	testutil.Assert(t, apiErr != nil && apiErr.Typ == errorBadData, "expected bad data error, got %v", apiErr)
+	apiErr = call(`http://example.com/api/v1/series?match[]=up&match[]=up{tenant=~"team-[bc]"}`, "team-a")
+	testutil.Assert(t, apiErr != nil && apiErr.Typ == errorBadData, "expected bad data error, got %v", apiErr)
+	testutil.Assert(t, call(`http://example.com/api/v1/series?match[]=up{tenant="team-a"}`, "team-a") == nil, "unexpected error")
+}
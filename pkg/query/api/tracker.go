@@ -0,0 +1,152 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// activeQueryEntrySize is the size of a slot of the active query file. Longer queries are truncated.
+const activeQueryEntrySize = 1000
+
+// ActiveQueryTracker records the queries being evaluated in a file of fixed size slots, one per concurrently evaluated
+// query. The file is written on every query start and end, so that the queries active when the querier crashed, e.g.
+// because it ran out of memory, can be logged after the restart.
+type ActiveQueryTracker struct {
+	logger log.Logger
+	f      *os.File
+	slots  chan int
+}
+
+// activeQuery is the entry of a query in the active query file.
+type activeQuery struct {
+	Query string `json:"query"`
+	// Start, End and Step are the parameters of range queries, Start is the evaluation time of instant queries.
+	Start     time.Time     `json:"start"`
+	End       *time.Time    `json:"end,omitempty"`
+	Step      time.Duration `json:"step,omitempty"`
+	StartedAt time.Time     `json:"startedAt"`
+}
+
+// NewActiveQueryTracker logs the queries recorded in the file at the given path, which were still active when the
+// previous process ended, and recreates the file with maxConcurrent empty slots.
+func NewActiveQueryTracker(logger log.Logger, path string, maxConcurrent int) (*ActiveQueryTracker, error) {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	if maxConcurrent <= 0 {
+		return nil, errors.Errorf("invalid number of concurrent queries %d", maxConcurrent)
+	}
+
+	if b, err := ioutil.ReadFile(path); err == nil {
+		logUnfinishedQueries(logger, path, b)
+	} else if !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "read active query file %s", path)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, errors.Wrapf(err, "create active query file %s", path)
+	}
+	if _, err := f.Write(bytes.Repeat(emptyActiveQueryEntry(), maxConcurrent)); err != nil {
+		_ = f.Close()
+		return nil, errors.Wrapf(err, "initialize active query file %s", path)
+	}
+
+	t := &ActiveQueryTracker{
+		logger: logger,
+		f:      f,
+		slots:  make(chan int, maxConcurrent),
+	}
+	for i := 0; i < maxConcurrent; i++ {
+		t.slots <- i
+	}
+	return t, nil
+}
+
+func emptyActiveQueryEntry() []byte {
+	b := bytes.Repeat([]byte(" "), activeQueryEntrySize)
+	b[len(b)-1] = '\n'
+	return b
+}
+
+func logUnfinishedQueries(logger log.Logger, path string, b []byte) {
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var q activeQuery
+		if err := json.Unmarshal(line, &q); err != nil {
+			level.Warn(logger).Log("msg", "skipping corrupted entry of active query file", "file", path, "err", err)
+			continue
+		}
+		level.Warn(logger).Log("msg", "query did not finish before the previous querier process ended",
+			"query", q.Query, "start", q.Start, "end", q.End, "step", q.Step, "startedAt", q.StartedAt)
+	}
+}
+
+// insert records the query in a free slot and returns a function removing it again. If all slots are taken, the
+// query is not recorded. A nil tracker records nothing.
+func (t *ActiveQueryTracker) insert(q activeQuery) func() {
+	if t == nil {
+		return func() {}
+	}
+
+	var slot int
+	select {
+	case slot = <-t.slots:
+	default:
+		return func() {}
+	}
+
+	entry, err := activeQueryEntry(q)
+	if err == nil {
+		_, err = t.f.WriteAt(entry, int64(slot*activeQueryEntrySize))
+	}
+	if err != nil {
+		level.Error(t.logger).Log("msg", "failed to record active query", "err", err)
+	}
+
+	return func() {
+		if _, err := t.f.WriteAt(emptyActiveQueryEntry(), int64(slot*activeQueryEntrySize)); err != nil {
+			level.Error(t.logger).Log("msg", "failed to remove active query", "err", err)
+		}
+		t.slots <- slot
+	}
+}
+
+// activeQueryEntry returns the padded slot content of the query, truncating the query text if needed.
+func activeQueryEntry(q activeQuery) ([]byte, error) {
+	for {
+		b, err := json.Marshal(q)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) < activeQueryEntrySize {
+			entry := emptyActiveQueryEntry()
+			copy(entry, b)
+			return entry, nil
+		}
+		if q.Query == "" {
+			return nil, errors.New("active query entry exceeds slot size")
+		}
+		// Cut off at least the excess, JSON escaping may make the query longer than its text.
+		cut := len(b) - activeQueryEntrySize + 1
+		if cut > len(q.Query) {
+			cut = len(q.Query)
+		}
+		q.Query = q.Query[:len(q.Query)-cut]
+	}
+}
+
+// Close closes the active query file.
+func (t *ActiveQueryTracker) Close() error {
+	return t.f.Close()
+}
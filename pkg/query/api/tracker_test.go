@@ -0,0 +1,52 @@
+package v1
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+)
+
+func TestActiveQueryTracker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "active-queries")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	path := filepath.Join(dir, "queries.active")
+	tracker, err := NewActiveQueryTracker(nil, path, 2)
+	testutil.Ok(t, err)
+
+	done := tracker.insert(activeQuery{Query: "up", Start: time.Unix(100, 0)})
+	_ = tracker.insert(activeQuery{Query: "sum(" + strings.Repeat("x", 2*activeQueryEntrySize) + ")", Start: time.Unix(100, 0)})
+	// All slots are taken, the query is not recorded.
+	_ = tracker.insert(activeQuery{Query: "not recorded"})
+	done()
+	_ = tracker.insert(activeQuery{Query: "rate(http_requests_total[5m])", Start: time.Unix(100, 0)})
+
+	b, err := ioutil.ReadFile(path)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2*activeQueryEntrySize, len(b))
+	testutil.Ok(t, tracker.Close())
+
+	// The queries still active are logged by the next tracker, which starts with empty slots.
+	var buf bytes.Buffer
+	tracker, err = NewActiveQueryTracker(log.NewLogfmtLogger(&buf), path, 1)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, tracker.Close()) }()
+
+	logged := buf.String()
+	testutil.Equals(t, 2, strings.Count(logged, "query did not finish"))
+	testutil.Assert(t, strings.Contains(logged, "rate(http_requests_total[5m])"), "query missing in log: %s", logged)
+	testutil.Assert(t, strings.Contains(logged, "sum(xxx"), "truncated query missing in log: %s", logged)
+	testutil.Assert(t, !strings.Contains(logged, "not recorded"), "unexpected query in log: %s", logged)
+
+	b, err = ioutil.ReadFile(path)
+	testutil.Ok(t, err)
+	testutil.Equals(t, emptyActiveQueryEntry(), b)
+}
@@ -22,15 +22,28 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/NYTimes/gziphandler"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/exemplars"
+	"github.com/improbable-eng/thanos/pkg/exemplars/exemplarspb"
+	"github.com/improbable-eng/thanos/pkg/metadata"
+	"github.com/improbable-eng/thanos/pkg/metadata/metadatapb"
 	"github.com/improbable-eng/thanos/pkg/query"
+	"github.com/improbable-eng/thanos/pkg/rules"
+	"github.com/improbable-eng/thanos/pkg/rules/rulespb"
 	"github.com/improbable-eng/thanos/pkg/runutil"
+	"github.com/improbable-eng/thanos/pkg/store"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/targets"
+	"github.com/improbable-eng/thanos/pkg/targets/targetspb"
 	"github.com/improbable-eng/thanos/pkg/tracing"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
@@ -53,14 +66,21 @@ const (
 type ErrorType string
 
 const (
-	errorNone     ErrorType = ""
-	errorTimeout            = "timeout"
-	errorCanceled           = "canceled"
-	errorExec               = "execution"
-	errorBadData            = "bad_data"
-	ErrorInternal           = "internal"
+	errorNone            ErrorType = ""
+	errorTimeout                   = "timeout"
+	errorCanceled                  = "canceled"
+	errorExec                      = "execution"
+	errorBadData                   = "bad_data"
+	ErrorInternal                  = "internal"
+	errorTooManyRequests           = "too_many_requests"
+	// errorTooManySamples is returned for queries exceeding the sample limit, so that clients can tell queries too
+	// expensive to evaluate from failures of the querier or stores.
+	errorTooManySamples = "too_many_samples"
 )
 
+// queueFullRetryAfter is the Retry-After header value, in seconds, of queries rejected because the queue was full.
+const queueFullRetryAfter = "5"
+
 var corsHeaders = map[string]string{
 	"Access-Control-Allow-Headers":  "Accept, Accept-Encoding, Authorization, Content-Type, Origin",
 	"Access-Control-Allow-Methods":  "GET, OPTIONS",
@@ -100,12 +120,38 @@ type API struct {
 	logger          log.Logger
 	queryableCreate query.QueryableCreator
 	queryEngine     *promql.Engine
+	// exemplars serves the exemplars API, nil disables it.
+	exemplars *exemplars.GRPCClient
+	// metadata serves the metric metadata API, nil disables it.
+	metadata *metadata.GRPCClient
+	// targets serves the targets API, nil disables it.
+	targets *targets.GRPCClient
+	// rules serves the rules API, nil disables it.
+	rules *rules.GRPCClient
+	// storeStatuses returns the status of the StoreAPIs for the stores API, nil disables it.
+	storeStatuses func() []query.StoreStatus
 
 	instantQueryDuration   prometheus.Histogram
 	rangeQueryDuration     prometheus.Histogram
 	enableAutodownsampling bool
-	enablePartialResponse  bool
-	now                    func() time.Time
+	// autoDownsamplingMaxResolution caps the automatically selected resolution, 0 means no cap.
+	autoDownsamplingMaxResolution time.Duration
+	enablePartialResponse         bool
+	// scheduler limits concurrent evaluations of queries, nil means no limit.
+	scheduler *queryScheduler
+	// tracker records the queries being evaluated, nil disables it.
+	tracker *ActiveQueryTracker
+	// slowQueryLogThreshold is the duration from which on queries are logged, 0 disables the slow query log.
+	slowQueryLogThreshold time.Duration
+	// verticalShards is the number of partial queries shardable aggregations are split into, at most 1 disables it.
+	verticalShards int
+	// instantCache caches results of instant queries, nil disables it.
+	instantCache *instantQueryCache
+	// tenantLabel is the label of the tenant series requests are limited to, empty disables tenancy enforcement. The
+	// tenant of a request is given in the tenantHeader HTTP header.
+	tenantLabel  string
+	tenantHeader string
+	now          func() time.Time
 }
 
 // NewAPI returns an initialized API type.
@@ -114,8 +160,23 @@ func NewAPI(
 	reg *prometheus.Registry,
 	qe *promql.Engine,
 	c query.QueryableCreator,
+	ec *exemplars.GRPCClient,
+	mc *metadata.GRPCClient,
+	tc *targets.GRPCClient,
+	rc *rules.GRPCClient,
+	storeStatuses func() []query.StoreStatus,
 	enableAutodownsampling bool,
+	autoDownsamplingMaxResolution time.Duration,
 	enablePartialResponse bool,
+	maxConcurrentQueries int,
+	maxQueuedQueries int,
+	tracker *ActiveQueryTracker,
+	slowQueryLogThreshold time.Duration,
+	verticalShards int,
+	instantCacheTTL time.Duration,
+	instantCacheStep time.Duration,
+	tenantLabel string,
+	tenantHeader string,
 ) *API {
 	instantQueryDuration := prometheus.NewHistogram(prometheus.HistogramOpts{
 		Name: "thanos_query_api_instant_query_duration_seconds",
@@ -136,14 +197,36 @@ func NewAPI(
 		instantQueryDuration,
 		rangeQueryDuration,
 	)
+	var scheduler *queryScheduler
+	if maxConcurrentQueries > 0 {
+		scheduler = newQueryScheduler(maxConcurrentQueries, maxQueuedQueries, reg)
+	}
+	var instantCache *instantQueryCache
+	if instantCacheTTL > 0 {
+		instantCache = newInstantQueryCache(instantCacheTTL, instantCacheStep, reg)
+	}
+
 	return &API{
-		logger:                 logger,
-		queryEngine:            qe,
-		queryableCreate:        c,
-		instantQueryDuration:   instantQueryDuration,
-		rangeQueryDuration:     rangeQueryDuration,
-		enableAutodownsampling: enableAutodownsampling,
-		enablePartialResponse:  enablePartialResponse,
+		logger:                        logger,
+		queryEngine:                   qe,
+		queryableCreate:               c,
+		exemplars:                     ec,
+		metadata:                      mc,
+		targets:                       tc,
+		rules:                         rc,
+		storeStatuses:                 storeStatuses,
+		instantQueryDuration:          instantQueryDuration,
+		rangeQueryDuration:            rangeQueryDuration,
+		enableAutodownsampling:        enableAutodownsampling,
+		autoDownsamplingMaxResolution: autoDownsamplingMaxResolution,
+		enablePartialResponse:         enablePartialResponse,
+		scheduler:                     scheduler,
+		tracker:                       tracker,
+		slowQueryLogThreshold:         slowQueryLogThreshold,
+		verticalShards:                verticalShards,
+		instantCache:                  instantCache,
+		tenantLabel:                   tenantLabel,
+		tenantHeader:                  tenantHeader,
 
 		now: time.Now,
 	}
@@ -167,18 +250,70 @@ func (api *API) Register(r *route.Router, tracer opentracing.Tracer, logger log.
 
 	r.Options("/*path", instr("options", api.options))
 
-	r.Get("/query", instr("query", api.query))
-	r.Post("/query", instr("query", api.query))
+	instantQuery := api.tenancy(api.cached(api.scheduled(api.query)))
+	r.Get("/query", instr("query", instantQuery))
+	r.Post("/query", instr("query", instantQuery))
+
+	rangeQuery := api.tenancy(api.scheduled(api.queryRange))
+	r.Get("/query_range", instr("query_range", rangeQuery))
+	r.Post("/query_range", instr("query_range", rangeQuery))
+
+	r.Get("/query_batch", instr("query_batch", api.queryBatch(instantQuery, rangeQuery)))
+	r.Post("/query_batch", instr("query_batch", api.queryBatch(instantQuery, rangeQuery)))
+
+	r.Get("/label/:name/values", instr("label_values", api.tenancy(api.labelValues)))
+
+	r.Get("/series", instr("series", api.tenancy(api.series)))
+	r.Post("/series", instr("series", api.tenancy(api.series)))
 
-	r.Get("/query_range", instr("query_range", api.queryRange))
-	r.Post("/query_range", instr("query_range", api.queryRange))
+	r.Get("/labels", instr("label_names", api.tenancy(api.labelNames)))
 
-	r.Get("/label/:name/values", instr("label_values", api.labelValues))
+	// Remote read responses are protobuf messages compressed with snappy or streamed frames, they are not gzipped.
+	r.Post("/read", prometheus.InstrumentHandler("remote_read", tracing.HTTPMiddleware(tracer, "remote_read", logger, http.HandlerFunc(api.remoteRead))))
+
+	// Exports are streamed in their own format instead of the JSON responses of the other endpoints.
+	export := prometheus.InstrumentHandler("export", tracing.HTTPMiddleware(tracer, "export", logger, gziphandler.GzipHandler(http.HandlerFunc(api.export))))
+	r.Get("/export", export)
+	r.Post("/export", export)
+
+	if api.tenantLabel != "" {
+		// The other APIs of the endpoints are not limited to tenants.
+		return
+	}
+	if api.exemplars != nil {
+		r.Get("/query_exemplars", instr("exemplars", api.queryExemplars))
+		r.Post("/query_exemplars", instr("exemplars", api.queryExemplars))
+	}
+	if api.metadata != nil {
+		r.Get("/metadata", instr("metadata", api.metricMetadata))
+	}
+	if api.targets != nil {
+		r.Get("/targets", instr("targets", api.targetsHandler))
+	}
+	if api.storeStatuses != nil {
+		r.Get("/stores", instr("stores", api.stores))
+	}
+	if api.rules != nil {
+		r.Get("/rules", instr("rules", api.rulesHandler))
+	}
+}
 
-	r.Get("/series", instr("series", api.series))
-	r.Post("/series", instr("series", api.series))
+// scheduled wraps the given query function so that queries wait for their turn in the scheduler.
+func (api *API) scheduled(f ApiFunc) ApiFunc {
+	if api.scheduler == nil {
+		return f
+	}
+	return func(r *http.Request) (interface{}, []error, *ApiError) {
+		if err := api.scheduler.start(r.Context()); err != nil {
+			if err == errQueueFull {
+				return nil, nil, &ApiError{errorTooManyRequests, err}
+			}
+			return nil, nil, &ApiError{errorCanceled, errors.Wrap(err, "waiting in the query queue")}
+		}
+		defer api.scheduler.done()
 
-	r.Get("/labels", instr("label_names", api.labelNames))
+		return f(r)
+	}
 }
 
 type queryData struct {
@@ -187,6 +322,52 @@ type queryData struct {
 
 	// Additional Thanos Response field.
 	Warnings []error `json:"warnings,omitempty"`
+	// Stats are only set if requested with the stats parameter.
+	Stats *queryStats `json:"stats,omitempty"`
+}
+
+// queryStats are the statistics of the store requests of a query.
+type queryStats struct {
+	Stores []store.StoreStats `json:"stores"`
+}
+
+// withQueryStats returns a context collecting the per store statistics of the query if the stats parameter is set or
+// the slow query log is enabled.
+func (api *API) withQueryStats(ctx context.Context, r *http.Request) (context.Context, *store.QueryStats) {
+	if r.FormValue("stats") == "" && api.slowQueryLogThreshold <= 0 {
+		return ctx, nil
+	}
+	stats := store.NewQueryStats()
+	return store.ContextWithQueryStats(ctx, stats), stats
+}
+
+// newQueryStats returns the response statistics of the collected statistics if the stats parameter is set.
+func newQueryStats(r *http.Request, stats *store.QueryStats) *queryStats {
+	if stats == nil || r.FormValue("stats") == "" {
+		return nil
+	}
+	return &queryStats{Stores: stats.Stores()}
+}
+
+// logSlowQuery logs the query with the statistics of the stores it touched if its evaluation, which began at the
+// given time, took at least the slow query log threshold.
+func (api *API) logSlowQuery(q activeQuery, stats *store.QueryStats, begin time.Time) {
+	took := time.Since(begin)
+	if api.slowQueryLogThreshold <= 0 || took < api.slowQueryLogThreshold {
+		return
+	}
+
+	var stores []string
+	for _, st := range stats.Stores() {
+		stores = append(stores, fmt.Sprintf("%s(requests=%d series=%d chunks=%d took=%s warnings=%d)",
+			st.Store, st.Requests, st.Series, st.Chunks, st.MaxDuration, len(st.Warnings)))
+	}
+	kvs := []interface{}{"msg", "slow query", "query", q.Query, "start", q.Start}
+	if q.End != nil {
+		kvs = append(kvs, "end", *q.End, "step", q.Step)
+	}
+	kvs = append(kvs, "took", took, "stores", strings.Join(stores, ","))
+	level.Warn(api.logger).Log(kvs...)
 }
 
 func (api *API) parseEnableDedupParam(r *http.Request) (enableDeduplication bool, _ *ApiError) {
@@ -203,15 +384,21 @@ func (api *API) parseEnableDedupParam(r *http.Request) (enableDeduplication bool
 	return enableDeduplication, nil
 }
 
-func (api *API) parseDownsamplingParamMillis(r *http.Request, step time.Duration) (maxResolutionMillis int64, _ *ApiError) {
+// autoDownsamplingMaxSamples is the number of samples per series up to which raw data is used for automatically
+// downsampled queries. Longer ranges use the resolution still giving that many samples, i.e. 5m downsampled data
+// for ranges longer than about 3.5 days and 1h downsampled data for ranges longer than about 42 days.
+const autoDownsamplingMaxSamples = 1000
+
+func (api *API) parseDownsamplingParamMillis(r *http.Request, queryRange, step time.Duration) (maxResolutionMillis int64, _ *ApiError) {
 	const maxSourceResolutionParam = "max_source_resolution"
 	maxSourceResolution := 0 * time.Second
 
 	if api.enableAutodownsampling {
-		// If no max_source_resolution is specified fit at least 5 samples between steps.
-		maxSourceResolution = step / 5
+		maxSourceResolution = api.autoDownsamplingResolution(queryRange, step)
 	}
-	if val := r.FormValue(maxSourceResolutionParam); val != "" {
+	if val := r.FormValue(maxSourceResolutionParam); val == "auto" {
+		maxSourceResolution = api.autoDownsamplingResolution(queryRange, step)
+	} else if val != "" {
 		var err error
 		maxSourceResolution, err = parseDuration(val)
 		if err != nil {
@@ -226,10 +413,46 @@ func (api *API) parseDownsamplingParamMillis(r *http.Request, step time.Duration
 	return int64(maxSourceResolution / time.Millisecond), nil
 }
 
+// autoDownsamplingResolution returns the maximum resolution of the data that should be used to evaluate a query
+// over the given range with the given step.
+func (api *API) autoDownsamplingResolution(queryRange, step time.Duration) time.Duration {
+	// Fit at least 5 samples between steps.
+	res := step / 5
+	if r := queryRange / autoDownsamplingMaxSamples; r > res {
+		res = r
+	}
+	if api.autoDownsamplingMaxResolution > 0 && res > api.autoDownsamplingMaxResolution {
+		res = api.autoDownsamplingMaxResolution
+	}
+	return res
+}
+
+// rejectLookbackDeltaParam rejects the lookback_delta parameter of newer Prometheus versions. The embedded engine only
+// has a process wide lookback delta set by --query.lookback-delta, so the parameter cannot be honored and silently
+// ignoring it would return data the client did not ask for.
+func rejectLookbackDeltaParam(r *http.Request) *ApiError {
+	if r.FormValue("lookback_delta") != "" {
+		return &ApiError{errorBadData, errors.New("the lookback_delta parameter is not supported, the lookback delta is set for all queries by --query.lookback-delta")}
+	}
+	return nil
+}
+
 func (api *API) parsePartialResponseParam(r *http.Request) (enablePartialResponse bool, _ *ApiError) {
-	const partialResponseParam = "partial_response"
+	const (
+		partialResponseParam         = "partial_response"
+		partialResponseStrategyParam = "partial_response_strategy"
+	)
 	enablePartialResponse = api.enablePartialResponse
 
+	// The strategy takes precedence over the older boolean parameter.
+	if val := r.FormValue(partialResponseStrategyParam); val != "" {
+		strategy, ok := storepb.PartialResponseStrategy_value[strings.ToUpper(val)]
+		if !ok {
+			return false, &ApiError{errorBadData, errors.Errorf("'%s' parameter: unknown strategy %q, expected warn or abort", partialResponseStrategyParam, val)}
+		}
+		return storepb.PartialResponseStrategy(strategy) == storepb.PartialResponseStrategy_WARN, nil
+	}
+
 	if val := r.FormValue(partialResponseParam); val != "" {
 		var err error
 		enablePartialResponse, err = strconv.ParseBool(val)
@@ -278,6 +501,10 @@ func (api *API) query(r *http.Request) (interface{}, []error, *ApiError) {
 		return nil, nil, apiErr
 	}
 
+	if apiErr := rejectLookbackDeltaParam(r); apiErr != nil {
+		return nil, nil, apiErr
+	}
+
 	var (
 		warnmtx  sync.Mutex
 		warnings []error
@@ -291,20 +518,27 @@ func (api *API) query(r *http.Request) (interface{}, []error, *ApiError) {
 	// We are starting promQL tracing span here, because we have no control over promQL code.
 	span, ctx := tracing.StartSpan(r.Context(), "promql_instant_query")
 	defer span.Finish()
+	ctx, stats := api.withQueryStats(ctx, r)
 
 	begin := api.now()
-	qry, err := api.queryEngine.NewInstantQuery(api.queryableCreate(enableDedup, 0, enablePartialResponse, warningReporter), r.FormValue("query"), ts)
+	aq := activeQuery{Query: r.FormValue("query"), Start: ts, StartedAt: begin}
+	defer api.tracker.insert(aq)()
+	defer api.logSlowQuery(aq, stats, begin)
+
+	res, err := api.execQuery(ctx, r.FormValue("query"), func() (promql.Query, error) {
+		return api.queryEngine.NewInstantQuery(api.queryableCreate(enableDedup, 0, enablePartialResponse, warningReporter), r.FormValue("query"), ts)
+	})
 	if err != nil {
 		return nil, nil, &ApiError{errorBadData, err}
 	}
-
-	res := qry.Exec(ctx)
 	if res.Err != nil {
 		switch res.Err.(type) {
 		case promql.ErrQueryCanceled:
 			return nil, nil, &ApiError{errorCanceled, res.Err}
 		case promql.ErrQueryTimeout:
 			return nil, nil, &ApiError{errorTimeout, res.Err}
+		case promql.ErrTooManySamples:
+			return nil, nil, &ApiError{errorTooManySamples, res.Err}
 		case promql.ErrStorage:
 			return nil, nil, &ApiError{ErrorInternal, res.Err}
 		}
@@ -315,9 +549,92 @@ func (api *API) query(r *http.Request) (interface{}, []error, *ApiError) {
 	return &queryData{
 		ResultType: res.Value.Type(),
 		Result:     res.Value,
+		Stats:      newQueryStats(r, stats),
 	}, warnings, nil
 }
 
+// execQuery evaluates the query created by newQuery. Queries with aggregations that can be sharded are evaluated as
+// api.verticalShards partial queries in parallel, each over one shard of the series, and their results are merged.
+// The returned error is one creating the query, errors of the evaluation are part of the result.
+func (api *API) execQuery(ctx context.Context, qs string, newQuery func() (promql.Query, error)) (*promql.Result, error) {
+	var (
+		shardLabels []string
+		sharded     bool
+	)
+	if api.verticalShards > 1 {
+		if expr, err := promql.ParseExpr(qs); err == nil {
+			shardLabels, sharded = query.ShardingLabels(expr)
+		}
+	}
+	if !sharded {
+		qry, err := newQuery()
+		if err != nil {
+			return nil, err
+		}
+		return qry.Exec(ctx), nil
+	}
+
+	qrys := make([]promql.Query, api.verticalShards)
+	for i := range qrys {
+		qry, err := newQuery()
+		if err != nil {
+			return nil, err
+		}
+		qrys[i] = qry
+	}
+
+	var (
+		wg      sync.WaitGroup
+		results = make([]*promql.Result, len(qrys))
+	)
+	for i, qry := range qrys {
+		wg.Add(1)
+		go func(i int, qry promql.Query) {
+			defer wg.Done()
+			results[i] = qry.Exec(query.ContextWithShardInfo(ctx, &storepb.ShardInfo{
+				ShardIndex:  int64(i),
+				TotalShards: int64(len(qrys)),
+				Labels:      shardLabels,
+			}))
+		}(i, qry)
+	}
+	wg.Wait()
+	return mergeShardResults(results), nil
+}
+
+// mergeShardResults merges the results of the partial queries over all shards. As the shards are disjoint, the
+// result is the union of the partial results. The first error fails the whole query.
+func mergeShardResults(results []*promql.Result) *promql.Result {
+	res := &promql.Result{}
+	for _, r := range results {
+		if r.Err != nil {
+			return r
+		}
+		res.Warnings = append(res.Warnings, r.Warnings...)
+		switch v := r.Value.(type) {
+		case promql.Vector:
+			vec, ok := res.Value.(promql.Vector)
+			if !ok {
+				vec = promql.Vector{}
+			}
+			res.Value = append(vec, v...)
+		case promql.Matrix:
+			mat, ok := res.Value.(promql.Matrix)
+			if !ok {
+				mat = promql.Matrix{}
+			}
+			res.Value = append(mat, v...)
+		default:
+			// Sharded queries are aggregations of vectors, other values can only be returned as they are.
+			return r
+		}
+	}
+	if mat, ok := res.Value.(promql.Matrix); ok {
+		sort.Sort(mat)
+	}
+	return res
+}
+
 func (api *API) queryRange(r *http.Request) (interface{}, []error, *ApiError) {
 	start, err := parseTime(r.FormValue("start"))
 	if err != nil {
@@ -366,7 +683,7 @@ func (api *API) queryRange(r *http.Request) (interface{}, []error, *ApiError) {
 		return nil, nil, apiErr
 	}
 
-	maxSourceResolution, apiErr := api.parseDownsamplingParamMillis(r, step)
+	maxSourceResolution, apiErr := api.parseDownsamplingParamMillis(r, end.Sub(start), step)
 	if apiErr != nil {
 		return nil, nil, apiErr
 	}
@@ -376,6 +693,10 @@ func (api *API) queryRange(r *http.Request) (interface{}, []error, *ApiError) {
 		return nil, nil, apiErr
 	}
 
+	if apiErr := rejectLookbackDeltaParam(r); apiErr != nil {
+		return nil, nil, apiErr
+	}
+
 	var (
 		warnmtx  sync.Mutex
 		warnings []error
@@ -389,26 +710,35 @@ func (api *API) queryRange(r *http.Request) (interface{}, []error, *ApiError) {
 	// We are starting promQL tracing span here, because we have no control over promQL code.
 	span, ctx := tracing.StartSpan(r.Context(), "promql_range_query")
 	defer span.Finish()
+	ctx, stats := api.withQueryStats(ctx, r)
 
 	begin := api.now()
-	qry, err := api.queryEngine.NewRangeQuery(
-		api.queryableCreate(enableDedup, maxSourceResolution, enablePartialResponse, warningReporter),
-		r.FormValue("query"),
-		start,
-		end,
-		step,
-	)
+	aq := activeQuery{Query: r.FormValue("query"), Start: start, End: &end, Step: step, StartedAt: begin}
+	defer api.tracker.insert(aq)()
+	defer api.logSlowQuery(aq, stats, begin)
+
+	res, err := api.execQuery(ctx, r.FormValue("query"), func() (promql.Query, error) {
+		return api.queryEngine.NewRangeQuery(
+			api.queryableCreate(enableDedup, maxSourceResolution, enablePartialResponse, warningReporter),
+			r.FormValue("query"),
+			start,
+			end,
+			step,
+		)
+	})
 	if err != nil {
 		return nil, nil, &ApiError{errorBadData, err}
 	}
-
-	res := qry.Exec(ctx)
 	if res.Err != nil {
 		switch res.Err.(type) {
 		case promql.ErrQueryCanceled:
 			return nil, nil, &ApiError{errorCanceled, res.Err}
 		case promql.ErrQueryTimeout:
 			return nil, nil, &ApiError{errorTimeout, res.Err}
+		case promql.ErrTooManySamples:
+			return nil, nil, &ApiError{errorTooManySamples, res.Err}
+		case promql.ErrStorage:
+			return nil, nil, &ApiError{ErrorInternal, res.Err}
 		}
 		return nil, nil, &ApiError{errorExec, res.Err}
 	}
@@ -417,6 +747,7 @@ func (api *API) queryRange(r *http.Request) (interface{}, []error, *ApiError) {
 	return &queryData{
 		ResultType: res.Value.Type(),
 		Result:     res.Value,
+		Stats:      newQueryStats(r, stats),
 	}, warnings, nil
 }
 
@@ -443,7 +774,12 @@ func (api *API) labelValues(r *http.Request) (interface{}, []error, *ApiError) {
 		warnmtx.Unlock()
 	}
 
-	q, err := api.queryableCreate(true, 0, enablePartialResponse, warningReporter).Querier(ctx, math.MinInt64, math.MaxInt64)
+	mint, maxt, matcherSets, apiErr := parseLabelsParams(r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
+	q, err := api.queryableCreate(true, 0, enablePartialResponse, warningReporter).Querier(ctx, mint, maxt)
 	if err != nil {
 		return nil, nil, &ApiError{errorExec, err}
 	}
@@ -451,12 +787,82 @@ func (api *API) labelValues(r *http.Request) (interface{}, []error, *ApiError) {
 
 	// TODO(fabxc): add back request context.
 
-	vals, err := q.LabelValues(name)
-	if err != nil {
-		return nil, nil, &ApiError{errorExec, err}
+	if len(matcherSets) == 0 {
+		vals, err := q.LabelValues(name)
+		if err != nil {
+			return nil, nil, &ApiError{errorExec, err}
+		}
+		return vals, warnings, nil
 	}
 
-	return vals, warnings, nil
+	lq, ok := q.(query.LabelQuerier)
+	if !ok {
+		return nil, nil, &ApiError{ErrorInternal, errors.New("querier does not support match[] for label values")}
+	}
+	var sets [][]string
+	for _, matchers := range matcherSets {
+		vals, err := lq.LabelValuesMatching(name, matchers...)
+		if err != nil {
+			return nil, nil, &ApiError{errorExec, err}
+		}
+		sets = append(sets, vals)
+	}
+	return mergeSortedStrings(sets), warnings, nil
+}
+
+// parseLabelsParams parses the optional start, end and match[] parameters of the label names and values APIs. The
+// whole time range is used if start and end are not given.
+func parseLabelsParams(r *http.Request) (mint, maxt int64, matcherSets [][]*labels.Matcher, _ *ApiError) {
+	if err := r.ParseForm(); err != nil {
+		return 0, 0, nil, &ApiError{ErrorInternal, errors.Wrap(err, "parse form")}
+	}
+
+	mint, maxt = math.MinInt64, math.MaxInt64
+	if t := r.FormValue("start"); t != "" {
+		start, err := parseTime(t)
+		if err != nil {
+			return 0, 0, nil, &ApiError{errorBadData, err}
+		}
+		mint = timestamp.FromTime(start)
+	}
+	if t := r.FormValue("end"); t != "" {
+		end, err := parseTime(t)
+		if err != nil {
+			return 0, 0, nil, &ApiError{errorBadData, err}
+		}
+		maxt = timestamp.FromTime(end)
+	}
+	if mint > maxt {
+		return 0, 0, nil, &ApiError{errorBadData, errors.New("end timestamp must not be before start time")}
+	}
+
+	for _, s := range r.Form["match[]"] {
+		matchers, err := promql.ParseMetricSelector(s)
+		if err != nil {
+			return 0, 0, nil, &ApiError{errorBadData, err}
+		}
+		matcherSets = append(matcherSets, matchers)
+	}
+	return mint, maxt, matcherSets, nil
+}
+
+// mergeSortedStrings returns the sorted union of the given sorted string slices.
+func mergeSortedStrings(sets [][]string) []string {
+	if len(sets) == 1 {
+		return sets[0]
+	}
+	unique := map[string]struct{}{}
+	for _, set := range sets {
+		for _, s := range set {
+			unique[s] = struct{}{}
+		}
+	}
+	res := make([]string, 0, len(unique))
+	for s := range unique {
+		res = append(res, s)
+	}
+	sort.Strings(res)
+	return res
 }
 
 var (
@@ -533,7 +939,7 @@ func (api *API) series(r *http.Request) (interface{}, []error, *ApiError) {
 
 	var sets []storage.SeriesSet
 	for _, mset := range matcherSets {
-		s, _, err := q.Select(&storage.SelectParams{}, mset...)
+		s, _, err := q.Select(&storage.SelectParams{Func: "series"}, mset...)
 		if err != nil {
 			return nil, nil, &ApiError{errorExec, err}
 		}
@@ -553,6 +959,168 @@ func (api *API) series(r *http.Request) (interface{}, []error, *ApiError) {
 	return metrics, warnings, nil
 }
 
+func (api *API) queryExemplars(r *http.Request) (interface{}, []error, *ApiError) {
+	// Only validate the query, it is evaluated by the exemplars APIs of the endpoints.
+	if _, err := promql.ParseExpr(r.FormValue("query")); err != nil {
+		return nil, nil, &ApiError{errorBadData, err}
+	}
+
+	start := minTime
+	if t := r.FormValue("start"); t != "" {
+		var err error
+		start, err = parseTime(t)
+		if err != nil {
+			return nil, nil, &ApiError{errorBadData, err}
+		}
+	}
+	end := maxTime
+	if t := r.FormValue("end"); t != "" {
+		var err error
+		end, err = parseTime(t)
+		if err != nil {
+			return nil, nil, &ApiError{errorBadData, err}
+		}
+	}
+	if end.Before(start) {
+		return nil, nil, &ApiError{errorBadData, errors.New("end timestamp must not be before start timestamp")}
+	}
+
+	enableDedup, apiErr := api.parseEnableDedupParam(r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
+	enablePartialResponse, apiErr := api.parsePartialResponseParam(r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+	strategy := storepb.PartialResponseStrategy_ABORT
+	if enablePartialResponse {
+		strategy = storepb.PartialResponseStrategy_WARN
+	}
+
+	res, warnings, err := api.exemplars.Exemplars(r.Context(), &exemplarspb.ExemplarsRequest{
+		Query:                   r.FormValue("query"),
+		Start:                   timestamp.FromTime(start),
+		End:                     timestamp.FromTime(end),
+		PartialResponseStrategy: strategy,
+	}, enableDedup)
+	if err != nil {
+		return nil, nil, &ApiError{errorExec, err}
+	}
+	return res, warnings, nil
+}
+
+func (api *API) metricMetadata(r *http.Request) (interface{}, []error, *ApiError) {
+	limit := int32(-1)
+	if s := r.FormValue("limit"); s != "" {
+		l, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, nil, &ApiError{errorBadData, errors.New("limit must be a number")}
+		}
+		limit = int32(l)
+	}
+
+	enablePartialResponse, apiErr := api.parsePartialResponseParam(r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+	strategy := storepb.PartialResponseStrategy_ABORT
+	if enablePartialResponse {
+		strategy = storepb.PartialResponseStrategy_WARN
+	}
+
+	res, warnings, err := api.metadata.MetricMetadata(r.Context(), &metadatapb.MetricMetadataRequest{
+		Metric:                  r.FormValue("metric"),
+		Limit:                   limit,
+		PartialResponseStrategy: strategy,
+	})
+	if err != nil {
+		return nil, nil, &ApiError{errorExec, err}
+	}
+	return res, warnings, nil
+}
+
+func (api *API) targetsHandler(r *http.Request) (interface{}, []error, *ApiError) {
+	state := targetspb.TargetsRequest_ANY
+	switch s := strings.ToLower(r.FormValue("state")); s {
+	case "", "any":
+	case "active":
+		state = targetspb.TargetsRequest_ACTIVE
+	case "dropped":
+		state = targetspb.TargetsRequest_DROPPED
+	default:
+		return nil, nil, &ApiError{errorBadData, errors.Errorf("invalid targets state %q", s)}
+	}
+
+	enableDedup, apiErr := api.parseEnableDedupParam(r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
+	enablePartialResponse, apiErr := api.parsePartialResponseParam(r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+	strategy := storepb.PartialResponseStrategy_ABORT
+	if enablePartialResponse {
+		strategy = storepb.PartialResponseStrategy_WARN
+	}
+
+	res, warnings, err := api.targets.Targets(r.Context(), &targetspb.TargetsRequest{
+		State:                   state,
+		PartialResponseStrategy: strategy,
+	}, enableDedup)
+	if err != nil {
+		return nil, nil, &ApiError{errorExec, err}
+	}
+	return res, warnings, nil
+}
+
+// ruleDiscovery is the data of the rules API response.
+type ruleDiscovery struct {
+	RuleGroups []*rulespb.RuleGroup `json:"groups"`
+}
+
+func (api *API) rulesHandler(r *http.Request) (interface{}, []error, *ApiError) {
+	ruleType := rulespb.RulesRequest_ALL
+	switch t := strings.ToLower(r.FormValue("type")); t {
+	case "":
+	case "alert":
+		ruleType = rulespb.RulesRequest_ALERT
+	case "record":
+		ruleType = rulespb.RulesRequest_RECORD
+	default:
+		return nil, nil, &ApiError{errorBadData, errors.Errorf("invalid rule type %q", t)}
+	}
+
+	enableDedup, apiErr := api.parseEnableDedupParam(r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
+	enablePartialResponse, apiErr := api.parsePartialResponseParam(r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+	strategy := storepb.PartialResponseStrategy_ABORT
+	if enablePartialResponse {
+		strategy = storepb.PartialResponseStrategy_WARN
+	}
+
+	groups, warnings, err := api.rules.Rules(r.Context(), &rulespb.RulesRequest{
+		Type:                    ruleType,
+		PartialResponseStrategy: strategy,
+	}, enableDedup)
+	if err != nil {
+		return nil, nil, &ApiError{errorExec, err}
+	}
+	if groups == nil {
+		groups = []*rulespb.RuleGroup{}
+	}
+	return &ruleDiscovery{RuleGroups: groups}, warnings, nil
+}
+
 func Respond(w http.ResponseWriter, data interface{}, warnings []error) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -561,7 +1129,13 @@ func Respond(w http.ResponseWriter, data interface{}, warnings []error) {
 		Status: statusSuccess,
 		Data:   data,
 	}
+	// Each select of a query reports the warnings of the stores again, a warning is returned once.
+	seen := make(map[string]struct{}, len(warnings))
 	for _, warn := range warnings {
+		if _, ok := seen[warn.Error()]; ok {
+			continue
+		}
+		seen[warn.Error()] = struct{}{}
 		resp.Warnings = append(resp.Warnings, warn.Error())
 	}
 	_ = json.NewEncoder(w).Encode(resp)
@@ -574,12 +1148,15 @@ func RespondError(w http.ResponseWriter, apiErr *ApiError, data interface{}) {
 	switch apiErr.Typ {
 	case errorBadData:
 		code = http.StatusBadRequest
-	case errorExec:
+	case errorExec, errorTooManySamples:
 		code = 422
 	case errorCanceled, errorTimeout:
 		code = http.StatusServiceUnavailable
 	case ErrorInternal:
 		code = http.StatusInternalServerError
+	case errorTooManyRequests:
+		code = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", queueFullRetryAfter)
 	default:
 		code = http.StatusInternalServerError
 	}
@@ -636,16 +1213,36 @@ func (api *API) labelNames(r *http.Request) (interface{}, []error, *ApiError) {
 		warnmtx.Unlock()
 	}
 
-	q, err := api.queryableCreate(true, 0, enablePartialResponse, warningReporter).Querier(ctx, math.MinInt64, math.MaxInt64)
+	mint, maxt, matcherSets, apiErr := parseLabelsParams(r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
+	q, err := api.queryableCreate(true, 0, enablePartialResponse, warningReporter).Querier(ctx, mint, maxt)
 	if err != nil {
 		return nil, nil, &ApiError{errorExec, err}
 	}
 	defer runutil.CloseWithLogOnErr(api.logger, q, "queryable labelNames")
 
-	names, err := q.LabelNames()
-	if err != nil {
-		return nil, nil, &ApiError{errorExec, err}
+	if len(matcherSets) == 0 {
+		names, err := q.LabelNames()
+		if err != nil {
+			return nil, nil, &ApiError{errorExec, err}
+		}
+		return names, warnings, nil
 	}
 
-	return names, warnings, nil
+	lq, ok := q.(query.LabelQuerier)
+	if !ok {
+		return nil, nil, &ApiError{ErrorInternal, errors.New("querier does not support match[] for label names")}
+	}
+	var sets [][]string
+	for _, matchers := range matcherSets {
+		names, err := lq.LabelNamesMatching(matchers...)
+		if err != nil {
+			return nil, nil, &ApiError{errorExec, err}
+		}
+		sets = append(sets, names)
+	}
+	return mergeSortedStrings(sets), warnings, nil
 }
@@ -32,6 +32,7 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/improbable-eng/thanos/pkg/compact"
 	"github.com/improbable-eng/thanos/pkg/query"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
 	"github.com/improbable-eng/thanos/pkg/testutil"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
@@ -40,6 +41,7 @@ import (
 	"github.com/prometheus/prometheus/pkg/timestamp"
 	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/tsdb/chunkenc"
 )
 
 func testQueryableCreator(queryable storage.Queryable) query.QueryableCreator {
@@ -172,6 +174,26 @@ func TestEndpoints(t *testing.T) {
 				},
 			},
 		},
+		// The lookback delta cannot be set per query.
+		{
+			endpoint: api.query,
+			query: url.Values{
+				"query":          []string{"0.333"},
+				"lookback_delta": []string{"1h"},
+			},
+			errType: errorBadData,
+		},
+		{
+			endpoint: api.queryRange,
+			query: url.Values{
+				"query":          []string{"time()"},
+				"start":          []string{"0"},
+				"end":            []string{"2"},
+				"step":           []string{"1"},
+				"lookback_delta": []string{"1h"},
+			},
+			errType: errorBadData,
+		},
 		// Missing query params in range queries.
 		{
 			endpoint: api.queryRange,
@@ -627,6 +649,19 @@ func TestRespondSuccess(t *testing.T) {
 	}
 }
 
+func TestRespondSuccess_Warnings(t *testing.T) {
+	w := httptest.NewRecorder()
+	Respond(w, "test", []error{
+		storepb.NewWarning(storepb.WarningStoreError, "store-0:10901", errors.New("failed")),
+		errors.New("other"),
+		storepb.NewWarning(storepb.WarningStoreError, "store-0:10901", errors.New("failed")),
+	})
+
+	var res response
+	testutil.Ok(t, json.Unmarshal(w.Body.Bytes(), &res))
+	testutil.Equals(t, []string{"code=store_error store=store-0:10901: failed", "other"}, res.Warnings)
+}
+
 func TestRespondError(t *testing.T) {
 	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		RespondError(w, &ApiError{errorTimeout, errors.New("message")}, "test")
@@ -839,9 +874,11 @@ func TestParseDownsamplingParamMillis(t *testing.T) {
 	var tests = []struct {
 		maxSourceResolutionParam string
 		result                   int64
+		queryRange               time.Duration
 		step                     time.Duration
 		fail                     bool
 		enableAutodownsampling   bool
+		maxAutoResolution        time.Duration
 	}{
 		{
 			maxSourceResolutionParam: "0s",
@@ -885,15 +922,46 @@ func TestParseDownsamplingParamMillis(t *testing.T) {
 			result:                   int64((1 * time.Hour) / 6),
 			fail:                     true,
 		},
+		// Long ranges use downsampled data even for short steps.
+		{
+			maxSourceResolutionParam: "",
+			enableAutodownsampling:   true,
+			queryRange:               180 * 24 * time.Hour,
+			step:                     time.Hour,
+			result:                   int64(180 * 24 * time.Hour / 1000 / time.Millisecond),
+		},
+		{
+			maxSourceResolutionParam: "",
+			enableAutodownsampling:   true,
+			queryRange:               180 * 24 * time.Hour,
+			step:                     time.Hour,
+			maxAutoResolution:        5 * time.Minute,
+			result:                   int64(compact.ResolutionLevel5m),
+		},
+		// Automatic selection can be requested per query.
+		{
+			maxSourceResolutionParam: "auto",
+			enableAutodownsampling:   false,
+			queryRange:               time.Hour,
+			step:                     time.Hour,
+			result:                   int64(time.Hour / 5 / time.Millisecond),
+		},
+		{
+			maxSourceResolutionParam: "0s",
+			enableAutodownsampling:   true,
+			queryRange:               180 * 24 * time.Hour,
+			step:                     time.Hour,
+			result:                   int64(compact.ResolutionLevelRaw),
+		},
 	}
 
 	for i, test := range tests {
-		api := API{enableAutodownsampling: test.enableAutodownsampling}
+		api := API{enableAutodownsampling: test.enableAutodownsampling, autoDownsamplingMaxResolution: test.maxAutoResolution}
 		v := url.Values{}
 		v.Set("max_source_resolution", test.maxSourceResolutionParam)
 		r := http.Request{PostForm: v}
 
-		maxResMillis, _ := api.parseDownsamplingParamMillis(&r, test.step)
+		maxResMillis, _ := api.parseDownsamplingParamMillis(&r, test.queryRange, test.step)
 		if test.fail == false {
 			testutil.Assert(t, maxResMillis == test.result, "case %v: expected %v to be equal to %v", i, maxResMillis, test.result)
 		} else {
@@ -902,3 +970,155 @@ func TestParseDownsamplingParamMillis(t *testing.T) {
 
 	}
 }
+
+func TestParsePartialResponseParam(t *testing.T) {
+	var tests = []struct {
+		partialResponseParam  string
+		strategyParam         string
+		enablePartialResponse bool
+		result                bool
+		fail                  bool
+	}{
+		{enablePartialResponse: true, result: true},
+		{enablePartialResponse: false, result: false},
+		{partialResponseParam: "false", enablePartialResponse: true, result: false},
+		{strategyParam: "abort", enablePartialResponse: true, result: false},
+		{strategyParam: "WARN", enablePartialResponse: false, result: true},
+		{strategyParam: "warn", partialResponseParam: "false", result: true},
+		{strategyParam: "ignore", fail: true},
+	}
+
+	for i, test := range tests {
+		api := API{enablePartialResponse: test.enablePartialResponse}
+		v := url.Values{}
+		if test.partialResponseParam != "" {
+			v.Set("partial_response", test.partialResponseParam)
+		}
+		if test.strategyParam != "" {
+			v.Set("partial_response_strategy", test.strategyParam)
+		}
+		r := http.Request{PostForm: v}
+
+		enablePartialResponse, apiErr := api.parsePartialResponseParam(&r)
+		if test.fail {
+			testutil.Assert(t, apiErr != nil, "case %v: expected error", i)
+			continue
+		}
+		testutil.Assert(t, apiErr == nil, "case %v: unexpected error %v", i, apiErr)
+		testutil.Assert(t, enablePartialResponse == test.result, "case %v: expected %v to be equal to %v", i, enablePartialResponse, test.result)
+	}
+}
+
+// seriesStore serves a fixed set of series with a sample at every second of the first 2 minutes, ignoring all
+// parameters of Series requests.
+type seriesStore struct {
+	storepb.StoreServer
+
+	series []labels.Labels
+}
+
+func (s *seriesStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	for i, lset := range s.series {
+		c := chunkenc.NewXORChunk()
+		app, err := c.Appender()
+		if err != nil {
+			return err
+		}
+		for ts := int64(0); ts <= 120000; ts += 1000 {
+			app.Append(ts, float64(i))
+		}
+
+		var series storepb.Series
+		for _, l := range lset {
+			series.Labels = append(series.Labels, storepb.Label{Name: l.Name, Value: l.Value})
+		}
+		series.Chunks = []storepb.AggrChunk{{
+			MinTime: 0,
+			MaxTime: 120000,
+			Raw:     &storepb.Chunk{Type: storepb.Chunk_XOR, Data: c.Bytes()},
+		}}
+		if err := srv.Send(storepb.NewSeriesResponse(&series)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExecQuery_VerticalSharding(t *testing.T) {
+	st := &seriesStore{}
+	for i := 0; i < 20; i++ {
+		st.series = append(st.series, labels.FromStrings("__name__", "up", "instance", fmt.Sprintf("%d", i), "job", fmt.Sprintf("job-%d", i%7)))
+	}
+
+	engine := promql.NewEngine(promql.EngineOpts{MaxConcurrent: 10, MaxSamples: 1e8, Timeout: time.Minute})
+	queryable := query.NewQueryableCreator(nil, st, nil, nil)(false, 0, false, nil)
+	api := &API{queryEngine: engine}
+
+	for _, qs := range []string{
+		`sum by (job) (up)`,
+		`count by (job, instance) (up > 5)`,
+		// Not shardable, evaluated as a single query.
+		`sum(up)`,
+	} {
+		t.Run(qs, func(t *testing.T) {
+			eval := func(shards int, instant bool) promql.Value {
+				api.verticalShards = shards
+				res, err := api.execQuery(context.Background(), qs, func() (promql.Query, error) {
+					if instant {
+						return engine.NewInstantQuery(queryable, qs, timestamp.Time(60000))
+					}
+					return engine.NewRangeQuery(queryable, qs, timestamp.Time(0), timestamp.Time(120000), 10*time.Second)
+				})
+				testutil.Ok(t, err)
+				testutil.Ok(t, res.Err)
+				return res.Value
+			}
+
+			expected := eval(1, false).(promql.Matrix)
+			testutil.Equals(t, expected, eval(4, false))
+
+			expectedVec := eval(1, true).(promql.Vector)
+			vec := eval(4, true).(promql.Vector)
+			testutil.Equals(t, len(expectedVec), len(vec))
+			for _, s := range expectedVec {
+				found := false
+				for _, o := range vec {
+					if labels.Equal(s.Metric, o.Metric) {
+						testutil.Equals(t, s.Point, o.Point)
+						found = true
+					}
+				}
+				testutil.Assert(t, found, "series %s missing in sharded result", s.Metric)
+			}
+		})
+	}
+}
+
+func TestQuery_TooManySamples(t *testing.T) {
+	st := &seriesStore{series: []labels.Labels{labels.FromStrings("__name__", "up", "job", "a")}}
+	api := &API{
+		queryableCreate:      query.NewQueryableCreator(nil, st, nil, nil),
+		queryEngine:          promql.NewEngine(promql.EngineOpts{MaxConcurrent: 10, MaxSamples: 10, Timeout: time.Minute}),
+		instantQueryDuration: prometheus.NewHistogram(prometheus.HistogramOpts{}),
+		rangeQueryDuration:   prometheus.NewHistogram(prometheus.HistogramOpts{}),
+		now:                  time.Now,
+	}
+
+	for _, tcase := range []struct {
+		f      ApiFunc
+		params url.Values
+	}{
+		{f: api.query, params: url.Values{"query": []string{"up[2m]"}, "time": []string{"120"}}},
+		{f: api.queryRange, params: url.Values{"query": []string{"up"}, "start": []string{"0"}, "end": []string{"120"}, "step": []string{"1"}}},
+	} {
+		r, err := http.NewRequest("GET", "http://example.com?"+tcase.params.Encode(), nil)
+		testutil.Ok(t, err)
+		_, _, apiErr := tcase.f(r)
+		testutil.Assert(t, apiErr != nil, "expected error")
+		testutil.Equals(t, ErrorType(errorTooManySamples), apiErr.Typ)
+
+		w := httptest.NewRecorder()
+		RespondError(w, apiErr, nil)
+		testutil.Equals(t, 422, w.Code)
+	}
+}
@@ -0,0 +1,58 @@
+package query
+
+import (
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Names of the deduplication algorithms.
+const (
+	DedupPenalty = "penalty"
+	DedupChain   = "chain"
+)
+
+// Deduplicator merges the samples of replicas of a series, i.e. series equal without their replica labels, into a
+// single series.
+type Deduplicator interface {
+	// Merge returns an iterator over the deduplicated samples of the given iterators over the replicas of a series.
+	Merge(replicas ...storage.SeriesIterator) storage.SeriesIterator
+}
+
+// NewDeduplicator returns the deduplicator of the algorithm with the given name.
+func NewDeduplicator(algorithm string) (Deduplicator, error) {
+	switch algorithm {
+	case DedupPenalty:
+		return PenaltyDeduplicator{}, nil
+	case DedupChain:
+		return ChainDeduplicator{}, nil
+	}
+	return nil, errors.Errorf("unknown deduplication algorithm %q, expected %s or %s", algorithm, DedupPenalty, DedupChain)
+}
+
+// PenaltyDeduplicator sticks to one replica as long as it has samples and switches to another replica only after a
+// gap of twice the last scrape interval. Gaps of one replica are filled without mixing samples of replicas scraped
+// at different times, which would exaggerate the sampling frequency.
+type PenaltyDeduplicator struct{}
+
+// Merge implements Deduplicator.
+func (PenaltyDeduplicator) Merge(replicas ...storage.SeriesIterator) storage.SeriesIterator {
+	it := replicas[0]
+	for _, o := range replicas[1:] {
+		it = newDedupSeriesIterator(it, o)
+	}
+	return it
+}
+
+// ChainDeduplicator merges the samples of all replicas ordered by timestamp. Of samples with the same timestamp, the
+// one of the first replica is used. Unlike the penalty algorithm it never skips samples, so counters of replicas
+// restarted at different times may show resets of either replica, but no samples are silently dropped either.
+type ChainDeduplicator struct{}
+
+// Merge implements Deduplicator.
+func (ChainDeduplicator) Merge(replicas ...storage.SeriesIterator) storage.SeriesIterator {
+	it := replicas[0]
+	for _, o := range replicas[1:] {
+		it = newChainSeriesIterator(it, o)
+	}
+	return it
+}
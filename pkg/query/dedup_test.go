@@ -0,0 +1,81 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func TestNewDeduplicator(t *testing.T) {
+	d, err := NewDeduplicator(DedupPenalty)
+	testutil.Ok(t, err)
+	testutil.Equals(t, PenaltyDeduplicator{}, d)
+
+	d, err = NewDeduplicator(DedupChain)
+	testutil.Ok(t, err)
+	testutil.Equals(t, ChainDeduplicator{}, d)
+
+	_, err = NewDeduplicator("best")
+	testutil.NotOk(t, err)
+}
+
+func TestChainDeduplicator(t *testing.T) {
+	cases := []struct {
+		replicas [][]sample
+		exp      []sample
+	}{
+		{ // Samples of the first replica win on equal timestamps.
+			replicas: [][]sample{
+				{{10000, 10}, {20000, 11}, {30000, 12}},
+				{{10000, 20}, {20000, 21}, {30000, 22}},
+			},
+			exp: []sample{{10000, 10}, {20000, 11}, {30000, 12}},
+		},
+		{ // Samples of staggered replicas are interleaved, nothing is skipped.
+			replicas: [][]sample{
+				{{10000, 1}, {20000, 1}, {40000, 1}},
+				{{15000, 2}, {25000, 2}, {35000, 2}, {45000, 2}},
+			},
+			exp: []sample{{10000, 1}, {15000, 2}, {20000, 1}, {25000, 2}, {35000, 2}, {40000, 1}, {45000, 2}},
+		},
+		{ // Gaps of one replica are filled by the others.
+			replicas: [][]sample{
+				{{10000, 1}, {60000, 1}},
+				{},
+				{{10000, 3}, {20000, 3}, {30000, 3}, {60000, 3}},
+			},
+			exp: []sample{{10000, 1}, {20000, 3}, {30000, 3}, {60000, 1}},
+		},
+	}
+	for i, c := range cases {
+		t.Logf("case %d:", i)
+		var its []storage.SeriesIterator
+		for _, r := range c.replicas {
+			its = append(its, &SampleIterator{l: r, i: -1})
+		}
+		testutil.Equals(t, c.exp, expandSeries(t, ChainDeduplicator{}.Merge(its...)))
+	}
+}
+
+func TestChainDeduplicator_Seek(t *testing.T) {
+	it := ChainDeduplicator{}.Merge(
+		&SampleIterator{l: []sample{{10000, 1}, {30000, 1}, {50000, 1}}, i: -1},
+		&SampleIterator{l: []sample{{20000, 2}, {40000, 2}}, i: -1},
+	)
+	testutil.Assert(t, it.Seek(25000), "expected sample after 25000")
+	ts, v := it.At()
+	testutil.Equals(t, sample{30000, 1}, sample{ts, v})
+
+	// Seeking backwards stays at the current sample.
+	testutil.Assert(t, it.Seek(15000), "expected current sample")
+	ts, v = it.At()
+	testutil.Equals(t, sample{30000, 1}, sample{ts, v})
+
+	testutil.Assert(t, it.Next(), "expected next sample")
+	ts, v = it.At()
+	testutil.Equals(t, sample{40000, 2}, sample{ts, v})
+
+	testutil.Assert(t, !it.Seek(60000), "expected no sample after 60000")
+	testutil.Assert(t, !it.Next(), "expected exhausted iterator")
+}
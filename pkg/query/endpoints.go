@@ -0,0 +1,40 @@
+package query
+
+import (
+	"github.com/improbable-eng/thanos/pkg/extgrpc"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// EndpointGroupConfig configures a group of StoreAPI addresses dialed with the same client configuration, e.g. sidecars
+// of another cluster behind their own certificate authority.
+type EndpointGroupConfig struct {
+	// Addresses of the StoreAPI servers. They may be prefixed with `dns+` or `dnssrv+` for DNS lookups.
+	Addresses []string `yaml:"addresses"`
+	// TLSConfig enables TLS if set.
+	TLSConfig *extgrpc.TLSConfig `yaml:"tls_config"`
+	// BearerToken or the content of BearerTokenFile is sent as bearer token with every call. It requires TLS.
+	BearerToken     string `yaml:"bearer_token"`
+	BearerTokenFile string `yaml:"bearer_token_file"`
+}
+
+// LoadEndpointGroupConfigs parses the YAML list of endpoint groups. Empty content means no endpoint groups.
+func LoadEndpointGroupConfigs(confContentYaml []byte) ([]EndpointGroupConfig, error) {
+	var confs []EndpointGroupConfig
+	if err := yaml.UnmarshalStrict(confContentYaml, &confs); err != nil {
+		return nil, errors.Wrap(err, "parsing config YAML")
+	}
+
+	for i, c := range confs {
+		if len(c.Addresses) == 0 {
+			return nil, errors.Errorf("endpoint group %d: no addresses given", i)
+		}
+		if c.BearerToken != "" && c.BearerTokenFile != "" {
+			return nil, errors.Errorf("endpoint group %d: at most one of bearer_token and bearer_token_file may be set", i)
+		}
+		if (c.BearerToken != "" || c.BearerTokenFile != "") && c.TLSConfig == nil {
+			return nil, errors.Errorf("endpoint group %d: bearer tokens are only sent over TLS, tls_config is required", i)
+		}
+	}
+	return confs, nil
+}
@@ -0,0 +1,42 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/extgrpc"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+)
+
+func TestLoadEndpointGroupConfigs(t *testing.T) {
+	confs, err := LoadEndpointGroupConfigs([]byte(""))
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(confs))
+
+	confs, err = LoadEndpointGroupConfigs([]byte(`
+- addresses: ["dns+sidecar.eu-1.example.com:10901"]
+  tls_config:
+    ca_file: /etc/eu-1/ca.pem
+    server_name: sidecar.eu-1.example.com
+  bearer_token_file: /etc/eu-1/token
+- addresses: ["store-1:10901", "store-2:10901"]
+`))
+	testutil.Ok(t, err)
+	testutil.Equals(t, []EndpointGroupConfig{
+		{
+			Addresses:       []string{"dns+sidecar.eu-1.example.com:10901"},
+			TLSConfig:       &extgrpc.TLSConfig{CAFile: "/etc/eu-1/ca.pem", ServerName: "sidecar.eu-1.example.com"},
+			BearerTokenFile: "/etc/eu-1/token",
+		},
+		{Addresses: []string{"store-1:10901", "store-2:10901"}},
+	}, confs)
+
+	for _, invalid := range []string{
+		`- tls_config: {}`,
+		`- {addresses: ["a:1"], bearer_token: "x"}`,
+		`- {addresses: ["a:1"], tls_config: {}, bearer_token: "x", bearer_token_file: "y"}`,
+		`- {addresses: ["a:1"], unknown: true}`,
+	} {
+		_, err := LoadEndpointGroupConfigs([]byte(invalid))
+		testutil.NotOk(t, err)
+	}
+}
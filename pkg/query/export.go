@@ -0,0 +1,129 @@
+package query
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Formats of exported series.
+const (
+	// ExportCSV writes one `series,timestamp,value` line per sample with timestamps in milliseconds.
+	ExportCSV = "csv"
+	// ExportNDJSON writes one JSON object per series with its labels and the timestamps and values of its samples.
+	ExportNDJSON = "ndjson"
+)
+
+// SeriesWriter writes exported series.
+type SeriesWriter interface {
+	// WriteSeries writes the samples of the series up to maxt. The iterator is positioned at the first sample.
+	WriteSeries(lset labels.Labels, it storage.SeriesIterator, maxt int64) error
+	// Flush writes buffered data to the underlying writer.
+	Flush() error
+}
+
+// NewSeriesWriter returns the writer of series in the given format to w.
+func NewSeriesWriter(format string, w io.Writer) (SeriesWriter, error) {
+	switch format {
+	case ExportCSV:
+		cw := csv.NewWriter(w)
+		return &csvSeriesWriter{w: cw}, cw.Write([]string{"series", "timestamp", "value"})
+	case ExportNDJSON:
+		bw := bufio.NewWriter(w)
+		return &ndjsonSeriesWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+	}
+	return nil, errors.Errorf("unknown export format %q, expected %s or %s", format, ExportCSV, ExportNDJSON)
+}
+
+// ExportSeries writes the raw samples between mint and maxt of all series matching any of the matcher sets to w,
+// without any PromQL evaluation. Series matching multiple matcher sets are written once.
+func ExportSeries(q storage.Querier, mint, maxt int64, matcherSets [][]*labels.Matcher, w SeriesWriter) error {
+	var sets []storage.SeriesSet
+	for _, ms := range matcherSets {
+		s, _, err := q.Select(&storage.SelectParams{Start: mint, End: maxt}, ms...)
+		if err != nil {
+			return errors.Wrap(err, "select series")
+		}
+		sets = append(sets, s)
+	}
+
+	set := storage.NewMergeSeriesSet(sets, nil)
+	for set.Next() {
+		s := set.At()
+		it := s.Iterator()
+		if !it.Seek(mint) {
+			if err := it.Err(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.WriteSeries(s.Labels(), it, maxt); err != nil {
+			return errors.Wrapf(err, "write series %s", s.Labels())
+		}
+	}
+	if err := set.Err(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+type csvSeriesWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvSeriesWriter) WriteSeries(lset labels.Labels, it storage.SeriesIterator, maxt int64) error {
+	series := lset.String()
+	for ok := true; ok; ok = it.Next() {
+		t, v := it.At()
+		if t > maxt {
+			break
+		}
+		if err := w.w.Write([]string{series, strconv.FormatInt(t, 10), strconv.FormatFloat(v, 'f', -1, 64)}); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+func (w *csvSeriesWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type ndjsonSeries struct {
+	Labels     map[string]string `json:"labels"`
+	Timestamps []int64           `json:"timestamps"`
+	// Values are strings as in the query API, so that NaN and infinite values can be represented.
+	Values []string `json:"values"`
+}
+
+type ndjsonSeriesWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *ndjsonSeriesWriter) WriteSeries(lset labels.Labels, it storage.SeriesIterator, maxt int64) error {
+	s := ndjsonSeries{Labels: lset.Map()}
+	for ok := true; ok; ok = it.Next() {
+		t, v := it.At()
+		if t > maxt {
+			break
+		}
+		s.Timestamps = append(s.Timestamps, t)
+		s.Values = append(s.Values, strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	return w.enc.Encode(s)
+}
+
+func (w *ndjsonSeriesWriter) Flush() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,28 @@
+package query
+
+import (
+	"github.com/improbable-eng/thanos/pkg/store"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/pkg/errors"
+)
+
+// gatedStore limits the number of concurrent Series calls to the underlying store.
+type gatedStore struct {
+	storepb.StoreServer
+	gate *store.Gate
+}
+
+// NewGatedStore returns the store with its Series calls limited by the gate, e.g. to bound the number of selects of
+// all queries fanned out to the stores at the same time.
+func NewGatedStore(s storepb.StoreServer, gate *store.Gate) storepb.StoreServer {
+	return &gatedStore{StoreServer: s, gate: gate}
+}
+
+func (s *gatedStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	if err := s.gate.IsMyTurn(srv.Context()); err != nil {
+		return errors.Wrap(err, "waiting for a select slot")
+	}
+	defer s.gate.Done()
+
+	return s.StoreServer.Series(r, srv)
+}
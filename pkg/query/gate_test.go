@@ -0,0 +1,52 @@
+package query
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/store"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+)
+
+type concurrencyStore struct {
+	storepb.StoreServer
+	current, max int32
+}
+
+func (s *concurrencyStore) Series(*storepb.SeriesRequest, storepb.Store_SeriesServer) error {
+	c := atomic.AddInt32(&s.current, 1)
+	defer atomic.AddInt32(&s.current, -1)
+	for {
+		m := atomic.LoadInt32(&s.max)
+		if c <= m || atomic.CompareAndSwapInt32(&s.max, m, c) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return nil
+}
+
+func TestGatedStore(t *testing.T) {
+	st := &concurrencyStore{}
+	gated := NewGatedStore(st, store.NewGate(2, 0, nil))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testutil.Ok(t, gated.Series(&storepb.SeriesRequest{}, &seriesServer{ctx: context.Background()}))
+		}()
+	}
+	wg.Wait()
+	testutil.Equals(t, int32(2), st.max)
+
+	// Selects canceled while waiting for a slot fail.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	testutil.NotOk(t, NewGatedStore(st, store.NewGate(0, 0, nil)).Series(&storepb.SeriesRequest{}, &seriesServer{ctx: ctx}))
+}
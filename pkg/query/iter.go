@@ -162,6 +162,9 @@ func getFirstIterator(cs ...*storepb.Chunk) chunkenc.Iterator {
 		if c == nil {
 			continue
 		}
+		if c.Type == storepb.Chunk_HISTOGRAM || c.Type == storepb.Chunk_FLOAT_HISTOGRAM {
+			return errSeriesIterator{errors.Errorf("native histogram chunks (%s) are not supported", c.Type)}
+		}
 		chk, err := chunkenc.FromData(chunkEncoding(c.Type), c.Data)
 		if err != nil {
 			return errSeriesIterator{err}
@@ -292,8 +295,9 @@ func (it *chunkSeriesIterator) Err() error {
 }
 
 type dedupSeriesSet struct {
-	set          storage.SeriesSet
-	replicaLabel string
+	set           storage.SeriesSet
+	replicaLabels map[string]struct{}
+	dedup         Deduplicator
 
 	replicas []storage.Series
 	lset     labels.Labels
@@ -301,8 +305,10 @@ type dedupSeriesSet struct {
 	ok       bool
 }
 
-func newDedupSeriesSet(set storage.SeriesSet, replicaLabel string) storage.SeriesSet {
-	s := &dedupSeriesSet{set: set, replicaLabel: replicaLabel}
+// newDedupSeriesSet returns a series set deduplicating series that are equal without the replica labels. Replica labels
+// must be sorted to the end of the label sets. The samples of the replicas are merged by the given deduplicator.
+func newDedupSeriesSet(set storage.SeriesSet, replicaLabels map[string]struct{}, dedup Deduplicator) storage.SeriesSet {
+	s := &dedupSeriesSet{set: set, replicaLabels: replicaLabels, dedup: dedup}
 	s.ok = s.set.Next()
 	if s.ok {
 		s.peek = s.set.At()
@@ -315,20 +321,24 @@ func (s *dedupSeriesSet) Next() bool {
 		return false
 	}
 	// Set the label set we are currently gathering to the peek element
-	// without the replica label if it exists.
+	// without the replica labels if they exist.
 	s.lset = s.peekLset()
 	s.replicas = append(s.replicas[:0], s.peek)
 	return s.next()
 }
 
 // peekLset returns the label set of the current peek element stripped from the
-// replica label if it exists
+// replica labels if they exist.
 func (s *dedupSeriesSet) peekLset() labels.Labels {
 	lset := s.peek.Labels()
-	if lset[len(lset)-1].Name != s.replicaLabel {
-		return lset
+	i := len(lset)
+	for i > 0 {
+		if _, ok := s.replicaLabels[lset[i-1].Name]; !ok {
+			break
+		}
+		i--
 	}
-	return lset[:len(lset)-1]
+	return lset[:i]
 }
 
 func (s *dedupSeriesSet) next() bool {
@@ -341,7 +351,7 @@ func (s *dedupSeriesSet) next() bool {
 	s.peek = s.set.At()
 	nextLset := s.peekLset()
 
-	// If the label set modulo the replica label is equal to the current label set
+	// If the label set modulo the replica labels is equal to the current label set
 	// look for more replicas, otherwise a series is complete.
 	if !labels.Equal(s.lset, nextLset) {
 		return true
@@ -358,7 +368,7 @@ func (s *dedupSeriesSet) At() storage.Series {
 	// before advancing.
 	repl := make([]storage.Series, len(s.replicas))
 	copy(repl, s.replicas)
-	return newDedupSeries(s.lset, repl...)
+	return newDedupSeries(s.lset, s.dedup, repl...)
 }
 
 func (s *dedupSeriesSet) Err() error {
@@ -374,23 +384,24 @@ func (s seriesWithLabels) Labels() labels.Labels { return s.lset }
 
 type dedupSeries struct {
 	lset     labels.Labels
+	dedup    Deduplicator
 	replicas []storage.Series
 }
 
-func newDedupSeries(lset labels.Labels, replicas ...storage.Series) *dedupSeries {
-	return &dedupSeries{lset: lset, replicas: replicas}
+func newDedupSeries(lset labels.Labels, dedup Deduplicator, replicas ...storage.Series) *dedupSeries {
+	return &dedupSeries{lset: lset, dedup: dedup, replicas: replicas}
 }
 
 func (s *dedupSeries) Labels() labels.Labels {
 	return s.lset
 }
 
-func (s *dedupSeries) Iterator() (it storage.SeriesIterator) {
-	it = s.replicas[0].Iterator()
-	for _, o := range s.replicas[1:] {
-		it = newDedupSeriesIterator(it, o.Iterator())
+func (s *dedupSeries) Iterator() storage.SeriesIterator {
+	its := make([]storage.SeriesIterator, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		its = append(its, r.Iterator())
 	}
-	return it
+	return s.dedup.Merge(its...)
 }
 
 type dedupSeriesIterator struct {
@@ -401,6 +412,8 @@ type dedupSeriesIterator struct {
 	lastT      int64
 	penA, penB int64
 	useA       bool
+	// chain disables the penalties, so that the samples of both iterators are merged by timestamp.
+	chain bool
 }
 
 func newDedupSeriesIterator(a, b storage.SeriesIterator) *dedupSeriesIterator {
@@ -413,6 +426,14 @@ func newDedupSeriesIterator(a, b storage.SeriesIterator) *dedupSeriesIterator {
 	}
 }
 
+// newChainSeriesIterator returns an iterator over the samples of a and b ordered by timestamp. Of samples with the
+// same timestamp, the one of a is used.
+func newChainSeriesIterator(a, b storage.SeriesIterator) *dedupSeriesIterator {
+	it := newDedupSeriesIterator(a, b)
+	it.chain = true
+	return it
+}
+
 func (it *dedupSeriesIterator) Next() bool {
 	// Advance both iterators to at least the next highest timestamp plus the potential penalty.
 	if it.aok {
@@ -445,6 +466,15 @@ func (it *dedupSeriesIterator) Next() bool {
 
 	it.useA = ta <= tb
 
+	if it.chain {
+		if it.useA {
+			it.lastT = ta
+		} else {
+			it.lastT = tb
+		}
+		return true
+	}
+
 	// For the series we didn't pick, add a penalty twice as high as the delta of the last two
 	// samples to the next seek against it.
 	// This ensures that we don't pick a sample too close, which would increase the overall
@@ -476,9 +506,11 @@ func (it *dedupSeriesIterator) Next() bool {
 
 func (it *dedupSeriesIterator) Seek(t int64) bool {
 	for {
-		ts, _ := it.At()
-		if ts > 0 && ts >= t {
-			return true
+		// The iterators have no current sample before the first call to Next.
+		if it.lastT != math.MinInt64 {
+			if ts, _ := it.At(); ts >= t {
+				return true
+			}
 		}
 		if !it.Next() {
 			return false
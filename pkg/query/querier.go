@@ -2,6 +2,7 @@ package query
 
 import (
 	"context"
+	"math"
 	"sort"
 	"strings"
 
@@ -21,17 +22,19 @@ import (
 type WarningReporter func(error)
 
 // QueryableCreator returns implementation of promql.Queryable that fetches data from the proxy store API endpoints.
-// If deduplication is enabled, all data retrieved from it will be deduplicated along the replicaLabel by default.
+// If deduplication is enabled, all data retrieved from it will be deduplicated along all replicaLabels by default.
 // maxResolutionMillis controls downsampling resolution that is allowed (specified in milliseconds).
 // partialResponse controls `partialResponseDisabled` option of StoreAPI and partial response behaviour of proxy.
+// Replicas are merged by the deduplicator given to NewQueryableCreator.
 type QueryableCreator func(deduplicate bool, maxResolutionMillis int64, partialResponse bool, r WarningReporter) storage.Queryable
 
-// NewQueryableCreator creates QueryableCreator.
-func NewQueryableCreator(logger log.Logger, proxy storepb.StoreServer, replicaLabel string) QueryableCreator {
+// NewQueryableCreator creates QueryableCreator. A nil deduplicator uses the penalty algorithm.
+func NewQueryableCreator(logger log.Logger, proxy storepb.StoreServer, replicaLabels []string, dedup Deduplicator) QueryableCreator {
 	return func(deduplicate bool, maxResolutionMillis int64, partialResponse bool, r WarningReporter) storage.Queryable {
 		return &queryable{
 			logger:              logger,
-			replicaLabel:        replicaLabel,
+			replicaLabels:       replicaLabels,
+			dedup:               dedup,
 			proxy:               proxy,
 			deduplicate:         deduplicate,
 			maxResolutionMillis: maxResolutionMillis,
@@ -43,7 +46,8 @@ func NewQueryableCreator(logger log.Logger, proxy storepb.StoreServer, replicaLa
 
 type queryable struct {
 	logger              log.Logger
-	replicaLabel        string
+	replicaLabels       []string
+	dedup               Deduplicator
 	proxy               storepb.StoreServer
 	deduplicate         bool
 	maxResolutionMillis int64
@@ -53,7 +57,7 @@ type queryable struct {
 
 // Querier returns a new storage querier against the underlying proxy store API.
 func (q *queryable) Querier(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
-	return newQuerier(ctx, q.logger, mint, maxt, q.replicaLabel, q.proxy, q.deduplicate, int64(q.maxResolutionMillis), q.partialResponse, q.warningReporter), nil
+	return newQuerier(ctx, q.logger, mint, maxt, q.replicaLabels, q.dedup, q.proxy, q.deduplicate, int64(q.maxResolutionMillis), q.partialResponse, q.warningReporter), nil
 }
 
 type querier struct {
@@ -61,12 +65,19 @@ type querier struct {
 	logger              log.Logger
 	cancel              func()
 	mint, maxt          int64
-	replicaLabel        string
+	replicaLabels       []string
+	dedup               Deduplicator
 	proxy               storepb.StoreServer
 	deduplicate         bool
 	maxResolutionMillis int64
 	partialResponse     bool
 	warningReporter     WarningReporter
+	// shard limits the selected series to a shard, nil selects all series.
+	shard *storepb.ShardInfo
+	// tenant is added to the matchers of all requests, nil if series of all tenants may be selected.
+	tenant *labels.Matcher
+	// shared holds Series responses shared with other queriers, nil if responses are not shared.
+	shared *sharedSeries
 }
 
 // newQuerier creates implementation of storage.Querier that fetches data from the proxy
@@ -75,7 +86,8 @@ func newQuerier(
 	ctx context.Context,
 	logger log.Logger,
 	mint, maxt int64,
-	replicaLabel string,
+	replicaLabels []string,
+	dedup Deduplicator,
 	proxy storepb.StoreServer,
 	deduplicate bool,
 	maxResolutionMillis int64,
@@ -88,6 +100,9 @@ func newQuerier(
 	if warningReporter == nil {
 		warningReporter = func(error) {}
 	}
+	if dedup == nil {
+		dedup = PenaltyDeduplicator{}
+	}
 	ctx, cancel := context.WithCancel(ctx)
 	return &querier{
 		ctx:                 ctx,
@@ -95,17 +110,21 @@ func newQuerier(
 		cancel:              cancel,
 		mint:                mint,
 		maxt:                maxt,
-		replicaLabel:        replicaLabel,
+		replicaLabels:       replicaLabels,
+		dedup:               dedup,
 		proxy:               proxy,
 		deduplicate:         deduplicate,
 		maxResolutionMillis: maxResolutionMillis,
 		partialResponse:     partialResponse,
 		warningReporter:     warningReporter,
+		shard:               withoutReplicaLabels(shardInfoFromContext(ctx), replicaLabels),
+		tenant:              tenantMatcherFromContext(ctx),
+		shared:              sharedSeriesFromContext(ctx),
 	}
 }
 
 func (q *querier) isDedupEnabled() bool {
-	return q.deduplicate && q.replicaLabel != ""
+	return q.deduplicate && len(q.replicaLabels) > 0
 }
 
 type seriesServer struct {
@@ -123,6 +142,11 @@ func (s *seriesServer) Send(r *storepb.SeriesResponse) error {
 		return nil
 	}
 
+	// Hints are not used by the querier.
+	if r.GetHints() != nil {
+		return nil
+	}
+
 	if r.GetSeries() == nil {
 		return errors.New("no seriesSet")
 	}
@@ -167,30 +191,73 @@ func aggrsFromFunc(f string) ([]storepb.Aggr, resAggr) {
 	return []storepb.Aggr{storepb.Aggr_COUNT, storepb.Aggr_SUM}, resAggrAvg
 }
 
+// partialResponseStrategy returns the strategy passed to the StoreAPIs, so that queriers behind the proxy handle
+// partial responses the same way.
+func (q *querier) partialResponseStrategy() storepb.PartialResponseStrategy {
+	if q.partialResponse {
+		return storepb.PartialResponseStrategy_WARN
+	}
+	return storepb.PartialResponseStrategy_ABORT
+}
+
 func (q *querier) Select(params *storage.SelectParams, ms ...*labels.Matcher) (storage.SeriesSet, storage.Warnings, error) {
 	span, ctx := tracing.StartSpan(q.ctx, "querier_select")
 	defer span.Finish()
 
-	sms, err := translateMatchers(ms...)
+	sms, err := translateMatchers(withTenant(q.tenant, ms)...)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "convert matchers")
 	}
 
 	queryAggrs, resAggr := aggrsFromFunc(params.Func)
-
-	resp := &seriesServer{ctx: ctx}
-	if err := q.proxy.Series(&storepb.SeriesRequest{
+	req := &storepb.SeriesRequest{
 		MinTime:                 q.mint,
 		MaxTime:                 q.maxt,
 		Matchers:                sms,
 		MaxResolutionWindow:     q.maxResolutionMillis,
 		Aggregates:              queryAggrs,
 		PartialResponseDisabled: !q.partialResponse,
-	}, resp); err != nil {
-		return nil, nil, errors.Wrap(err, "proxy Series()")
+		PartialResponseStrategy: q.partialResponseStrategy(),
+		// Only the labels are needed for the series API.
+		SkipChunks: params.Func == "series",
+		ShardInfo:  q.shard,
+	}
+
+	// TODO(fabxc): this could potentially pushed further down into the store API
+	// to make true streaming possible.
+	replicaLabels := make(map[string]struct{}, len(q.replicaLabels))
+	for _, l := range q.replicaLabels {
+		replicaLabels[l] = struct{}{}
+	}
+
+	// Responses are shared as sorted for deduplication or not, so the deduplication setting is part of the key.
+	// Requests of different tenants are sent with different metadata, so they are never shared.
+	key := req.String()
+	if q.isDedupEnabled() {
+		key += " dedup"
+	}
+	if q.tenant != nil {
+		key += " tenant=" + q.tenant.Value
+	}
+	seriesSet, warnings, err := q.shared.do(ctx, key, func(ctx context.Context) ([]storepb.Series, []string, error) {
+		resp := &seriesServer{ctx: ctx}
+		if err := q.proxy.Series(req, resp); err != nil {
+			return nil, nil, errors.Wrap(err, "proxy Series()")
+		}
+		if q.shard != nil {
+			// Stores not supporting sharding return all series.
+			resp.seriesSet = filterShard(resp.seriesSet, q.shard)
+		}
+		if q.isDedupEnabled() {
+			sortDedupLabels(resp.seriesSet, replicaLabels)
+		}
+		return resp.seriesSet, resp.warnings, nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	for _, w := range resp.warnings {
+	for _, w := range warnings {
 		// NOTE(bwplotka): We could use warnings return arguments here, however need reporter anyway for LabelValues and LabelNames method,
 		// so we choose to be consistent and keep reporter.
 		q.warningReporter(errors.New(w))
@@ -201,56 +268,106 @@ func (q *querier) Select(params *storage.SelectParams, ms ...*labels.Matcher) (s
 		return promSeriesSet{
 			mint: q.mint,
 			maxt: q.maxt,
-			set:  newStoreSeriesSet(resp.seriesSet),
+			set:  newStoreSeriesSet(seriesSet),
 			aggr: resAggr,
 		}, nil, nil
 	}
 
-	// TODO(fabxc): this could potentially pushed further down into the store API
-	// to make true streaming possible.
-	sortDedupLabels(resp.seriesSet, q.replicaLabel)
-
 	set := promSeriesSet{
 		mint: q.mint,
 		maxt: q.maxt,
-		set:  newStoreSeriesSet(resp.seriesSet),
+		set:  newStoreSeriesSet(seriesSet),
 		aggr: resAggr,
 	}
 
 	// The merged series set assembles all potentially-overlapping time ranges
 	// of the same series into a single one. The series are ordered so that equal series
 	// from different replicas are sequential. We can now deduplicate those.
-	return newDedupSeriesSet(set, q.replicaLabel), nil, nil
+	return newDedupSeriesSet(set, replicaLabels, q.dedup), nil, nil
+}
+
+// filterShard returns the series of the set that belong to the shard.
+func filterShard(set []storepb.Series, shard *storepb.ShardInfo) []storepb.Series {
+	res := set[:0]
+	for _, s := range set {
+		if shard.Matches(s.Labels) {
+			res = append(res, s)
+		}
+	}
+	return res
 }
 
 // sortDedupLabels resorts the set so that the same series with different replica
 // labels are coming right after each other.
-func sortDedupLabels(set []storepb.Series, replicaLabel string) {
+func sortDedupLabels(set []storepb.Series, replicaLabels map[string]struct{}) {
 	for _, s := range set {
-		// Move the replica label to the very end.
+		// Move the replica labels to the very end.
 		sort.Slice(s.Labels, func(i, j int) bool {
-			if s.Labels[i].Name == replicaLabel {
-				return false
-			}
-			if s.Labels[j].Name == replicaLabel {
-				return true
+			_, iReplica := replicaLabels[s.Labels[i].Name]
+			_, jReplica := replicaLabels[s.Labels[j].Name]
+			if iReplica != jReplica {
+				return jReplica
 			}
 			return s.Labels[i].Name < s.Labels[j].Name
 		})
 	}
-	// With the re-ordered label sets, re-sorting all series aligns the same series
-	// from different replicas sequentially.
+	// With the re-ordered label sets, re-sorting all series by their labels without the replica
+	// labels first aligns the same series from different replicas sequentially, whichever of the
+	// replica labels they have.
 	sort.Slice(set, func(i, j int) bool {
-		return storepb.CompareLabels(set[i].Labels, set[j].Labels) < 0
+		li, ri := splitReplicaLabels(set[i].Labels, replicaLabels)
+		lj, rj := splitReplicaLabels(set[j].Labels, replicaLabels)
+		if c := storepb.CompareLabels(li, lj); c != 0 {
+			return c < 0
+		}
+		return storepb.CompareLabels(ri, rj) < 0
 	})
 }
 
+// splitReplicaLabels splits labels sorted by sortDedupLabels into the labels without and the trailing
+// replica labels.
+func splitReplicaLabels(lset []storepb.Label, replicaLabels map[string]struct{}) ([]storepb.Label, []storepb.Label) {
+	i := len(lset)
+	for i > 0 {
+		if _, ok := replicaLabels[lset[i-1].Name]; !ok {
+			break
+		}
+		i--
+	}
+	return lset[:i], lset[i:]
+}
+
+// LabelQuerier is implemented by queriers able to scope label names and values to the series matching the
+// given matchers within the time range of the querier.
+type LabelQuerier interface {
+	LabelNamesMatching(matchers ...*labels.Matcher) ([]string, error)
+	LabelValuesMatching(name string, matchers ...*labels.Matcher) ([]string, error)
+}
+
 // LabelValues returns all potential values for a label name.
 func (q *querier) LabelValues(name string) ([]string, error) {
+	return q.LabelValuesMatching(name)
+}
+
+// LabelValuesMatching returns all potential values for a label name of the series matching the given matchers.
+func (q *querier) LabelValuesMatching(name string, matchers ...*labels.Matcher) ([]string, error) {
 	span, ctx := tracing.StartSpan(q.ctx, "querier_label_values")
 	defer span.Finish()
 
-	resp, err := q.proxy.LabelValues(ctx, &storepb.LabelValuesRequest{Label: name, PartialResponseDisabled: !q.partialResponse})
+	sms, err := translateMatchers(withTenant(q.tenant, matchers)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "convert matchers")
+	}
+
+	mint, maxt := q.labelRequestTimeRange()
+	resp, err := q.proxy.LabelValues(ctx, &storepb.LabelValuesRequest{
+		Label:                   name,
+		PartialResponseDisabled: !q.partialResponse,
+		PartialResponseStrategy: q.partialResponseStrategy(),
+		MinTime:                 mint,
+		MaxTime:                 maxt,
+		Matchers:                sms,
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "proxy LabelValues()")
 	}
@@ -264,10 +381,27 @@ func (q *querier) LabelValues(name string) ([]string, error) {
 
 // LabelNames returns all the unique label names present in the block in sorted order.
 func (q *querier) LabelNames() ([]string, error) {
+	return q.LabelNamesMatching()
+}
+
+// LabelNamesMatching returns all the unique label names of the series matching the given matchers in sorted order.
+func (q *querier) LabelNamesMatching(matchers ...*labels.Matcher) ([]string, error) {
 	span, ctx := tracing.StartSpan(q.ctx, "querier_label_names")
 	defer span.Finish()
 
-	resp, err := q.proxy.LabelNames(ctx, &storepb.LabelNamesRequest{PartialResponseDisabled: !q.partialResponse})
+	sms, err := translateMatchers(withTenant(q.tenant, matchers)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "convert matchers")
+	}
+
+	mint, maxt := q.labelRequestTimeRange()
+	resp, err := q.proxy.LabelNames(ctx, &storepb.LabelNamesRequest{
+		PartialResponseDisabled: !q.partialResponse,
+		PartialResponseStrategy: q.partialResponseStrategy(),
+		MinTime:                 mint,
+		MaxTime:                 maxt,
+		Matchers:                sms,
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "proxy LabelNames()")
 	}
@@ -279,6 +413,15 @@ func (q *querier) LabelNames() ([]string, error) {
 	return resp.Names, nil
 }
 
+// labelRequestTimeRange returns the time range of label requests. Queriers over the whole time range request
+// labels without any time range, which stores treat as their whole time range.
+func (q *querier) labelRequestTimeRange() (int64, int64) {
+	if q.mint == math.MinInt64 && q.maxt == math.MaxInt64 {
+		return 0, 0
+	}
+	return q.mint, q.maxt
+}
+
 func (q *querier) Close() error {
 	q.cancel()
 	return nil
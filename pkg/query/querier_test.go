@@ -22,7 +22,7 @@ import (
 func TestQueryableCreator_MaxResolution(t *testing.T) {
 	defer leaktest.CheckTimeout(t, 10*time.Second)()
 	testProxy := &storeServer{resps: []*storepb.SeriesResponse{}}
-	queryableCreator := NewQueryableCreator(nil, testProxy, "test")
+	queryableCreator := NewQueryableCreator(nil, testProxy, []string{"test"}, nil)
 
 	oneHourMillis := int64(1*time.Hour) / int64(time.Millisecond)
 	queryable := queryableCreator(false, oneHourMillis, false, func(err error) {})
@@ -52,7 +52,7 @@ func TestQuerier_Series(t *testing.T) {
 
 	// Querier clamps the range to [1,300], which should drop some samples of the result above.
 	// The store API allows endpoints to send more data then initially requested.
-	q := newQuerier(context.Background(), nil, 1, 300, "", testProxy, false, 0, true, nil)
+	q := newQuerier(context.Background(), nil, 1, 300, nil, nil, testProxy, false, 0, true, nil)
 	defer func() { testutil.Ok(t, q.Close()) }()
 
 	res, _, err := q.Select(&storage.SelectParams{})
@@ -120,7 +120,7 @@ func TestSortReplicaLabel(t *testing.T) {
 		}},
 	}
 
-	sortDedupLabels(set, "b")
+	sortDedupLabels(set, map[string]struct{}{"b": {}})
 
 	exp := []storepb.Series{
 		{Labels: []storepb.Label{
@@ -157,6 +157,47 @@ func expandSeries(t testing.TB, it storage.SeriesIterator) (res []sample) {
 	return res
 }
 
+func TestSortReplicaLabels_Multiple(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	set := []storepb.Series{
+		{Labels: []storepb.Label{
+			{Name: "a", Value: "1"},
+			{Name: "prometheus_replica", Value: "x"},
+			{Name: "replica", Value: "A"},
+		}},
+		{Labels: []storepb.Label{
+			{Name: "a", Value: "1"},
+			{Name: "q", Value: "1"},
+		}},
+		{Labels: []storepb.Label{
+			{Name: "a", Value: "1"},
+			{Name: "replica", Value: "B"},
+		}},
+	}
+
+	// Series with different replica labels end up next to each other, also if other series sort in between
+	// them by their full label sets.
+	sortDedupLabels(set, map[string]struct{}{"replica": {}, "prometheus_replica": {}})
+
+	exp := []storepb.Series{
+		{Labels: []storepb.Label{
+			{Name: "a", Value: "1"},
+			{Name: "prometheus_replica", Value: "x"},
+			{Name: "replica", Value: "A"},
+		}},
+		{Labels: []storepb.Label{
+			{Name: "a", Value: "1"},
+			{Name: "replica", Value: "B"},
+		}},
+		{Labels: []storepb.Label{
+			{Name: "a", Value: "1"},
+			{Name: "q", Value: "1"},
+		}},
+	}
+	testutil.Equals(t, exp, set)
+}
+
 func TestDedupSeriesSet(t *testing.T) {
 	defer leaktest.CheckTimeout(t, 10*time.Second)()
 
@@ -188,6 +229,9 @@ func TestDedupSeriesSet(t *testing.T) {
 		}, {
 			lset: []storepb.Label{{Name: "a", Value: "2"}, {Name: "c", Value: "3"}, {Name: "replica", Value: "replica-3"}},
 			vals: []sample{{60000, 3}, {70000, 4}},
+		}, {
+			lset: []storepb.Label{{Name: "a", Value: "2"}, {Name: "c", Value: "3"}, {Name: "prometheus_replica", Value: "x"}, {Name: "replica", Value: "replica-4"}},
+			vals: []sample{{200000, 5}, {210000, 6}},
 		},
 	}
 	exp := []struct {
@@ -212,7 +256,7 @@ func TestDedupSeriesSet(t *testing.T) {
 		},
 		{
 			lset: labels.Labels{{"a", "2"}, {"c", "3"}},
-			vals: []sample{{10000, 1}, {20000, 2}, {60000, 3}, {70000, 4}},
+			vals: []sample{{10000, 1}, {20000, 2}, {60000, 3}, {70000, 4}, {200000, 5}, {210000, 6}},
 		},
 	}
 	var series []storepb.Series
@@ -234,7 +278,7 @@ func TestDedupSeriesSet(t *testing.T) {
 		maxt: math.MaxInt64,
 		set:  newStoreSeriesSet(series),
 	}
-	dedupSet := newDedupSeriesSet(set, "replica")
+	dedupSet := newDedupSeriesSet(set, map[string]struct{}{"replica": {}, "prometheus_replica": {}}, PenaltyDeduplicator{})
 
 	i := 0
 	for dedupSet.Next() {
@@ -343,6 +387,62 @@ func BenchmarkDedupSeriesIterator(b *testing.B) {
 	})
 }
 
+type labelStoreServer struct {
+	// This field just exist to pseudo-implement the unused methods of the interface.
+	storepb.StoreServer
+
+	namesReqs  []*storepb.LabelNamesRequest
+	valuesReqs []*storepb.LabelValuesRequest
+}
+
+func (s *labelStoreServer) LabelNames(_ context.Context, r *storepb.LabelNamesRequest) (*storepb.LabelNamesResponse, error) {
+	s.namesReqs = append(s.namesReqs, r)
+	return &storepb.LabelNamesResponse{Names: []string{"a"}}, nil
+}
+
+func (s *labelStoreServer) LabelValues(_ context.Context, r *storepb.LabelValuesRequest) (*storepb.LabelValuesResponse, error) {
+	s.valuesReqs = append(s.valuesReqs, r)
+	return &storepb.LabelValuesResponse{Values: []string{"b"}, Warnings: []string{"partial error"}}, nil
+}
+
+func TestQuerier_LabelsMatching(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	testProxy := &labelStoreServer{}
+
+	var warnings []error
+	q := newQuerier(context.Background(), nil, 1, 300, nil, nil, testProxy, false, 0, true, func(err error) {
+		warnings = append(warnings, err)
+	})
+	defer func() { testutil.Ok(t, q.Close()) }()
+
+	names, err := q.LabelNamesMatching(&labels.Matcher{Type: labels.MatchEqual, Name: "a", Value: "b"})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"a"}, names)
+	testutil.Equals(t, &storepb.LabelNamesRequest{
+		PartialResponseStrategy: storepb.PartialResponseStrategy_WARN,
+		MinTime:                 1,
+		MaxTime:                 300,
+		Matchers:                []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "a", Value: "b"}},
+	}, testProxy.namesReqs[0])
+
+	vals, err := q.LabelValuesMatching("a", &labels.Matcher{Type: labels.MatchRegexp, Name: "a", Value: "b.*"})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"b"}, vals)
+	testutil.Equals(t, []storepb.LabelMatcher{{Type: storepb.LabelMatcher_RE, Name: "a", Value: "b.*"}}, testProxy.valuesReqs[0].Matchers)
+	testutil.Equals(t, 1, len(warnings))
+
+	// Queriers over the whole time range leave the time range to the stores.
+	q2 := newQuerier(context.Background(), nil, math.MinInt64, math.MaxInt64, nil, nil, testProxy, false, 0, true, nil)
+	defer func() { testutil.Ok(t, q2.Close()) }()
+
+	_, err = q2.LabelValues("a")
+	testutil.Ok(t, err)
+	testutil.Equals(t, int64(0), testProxy.valuesReqs[1].MinTime)
+	testutil.Equals(t, int64(0), testProxy.valuesReqs[1].MaxTime)
+	testutil.Equals(t, 0, len(testProxy.valuesReqs[1].Matchers))
+}
+
 type sample struct {
 	t int64
 	v float64
@@ -366,7 +466,7 @@ func (s *SampleIterator) Next() bool {
 		return false
 	}
 	s.i++
-	return true
+	return s.i < len(s.l)
 }
 
 func (s *SampleIterator) Seek(t int64) bool {
@@ -424,3 +524,16 @@ func storeSeriesResponse(t testing.TB, lset labels.Labels, smplChunks ...[]sampl
 	}
 	return storepb.NewSeriesResponse(&s)
 }
+
+func TestChunkSeries_NativeHistograms(t *testing.T) {
+	for _, typ := range []storepb.Chunk_Encoding{storepb.Chunk_HISTOGRAM, storepb.Chunk_FLOAT_HISTOGRAM} {
+		s := newChunkSeries(
+			[]storepb.Label{{Name: "a", Value: "1"}},
+			[]storepb.AggrChunk{{MinTime: 0, MaxTime: 10, Raw: &storepb.Chunk{Type: typ, Data: []byte{0, 1}}}},
+			0, 10, resAggrCount,
+		)
+		it := s.Iterator()
+		testutil.Assert(t, !it.Next(), "expected no samples for %s chunk", typ)
+		testutil.NotOk(t, it.Err())
+	}
+}
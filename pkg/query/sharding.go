@@ -0,0 +1,180 @@
+package query
+
+import (
+	"context"
+	"sort"
+
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql"
+)
+
+type shardInfoKey struct{}
+
+// ContextWithShardInfo returns a context making queriers select only the series of the given shard.
+func ContextWithShardInfo(ctx context.Context, shard *storepb.ShardInfo) context.Context {
+	return context.WithValue(ctx, shardInfoKey{}, shard)
+}
+
+// shardInfoFromContext returns the shard of the context or nil if all series are selected.
+func shardInfoFromContext(ctx context.Context) *storepb.ShardInfo {
+	s, _ := ctx.Value(shardInfoKey{}).(*storepb.ShardInfo)
+	return s
+}
+
+// withoutReplicaLabels returns the shard with the replica labels removed from the labels to shard by, so that all
+// replicas of a series are in the same shard and deduplication is not affected by sharding.
+func withoutReplicaLabels(shard *storepb.ShardInfo, replicaLabels []string) *storepb.ShardInfo {
+	if shard == nil {
+		return nil
+	}
+	res := *shard
+	res.Labels = make([]string, 0, len(shard.Labels))
+Labels:
+	for _, l := range shard.Labels {
+		for _, rl := range replicaLabels {
+			if l == rl {
+				continue Labels
+			}
+		}
+		res.Labels = append(res.Labels, l)
+	}
+	return &res
+}
+
+// seriesLocalFuncs are the functions computing each output series from a single input series without changing the
+// labels relevant for sharding.
+var seriesLocalFuncs = map[string]struct{}{
+	"abs": {}, "avg_over_time": {}, "ceil": {}, "changes": {}, "clamp_max": {}, "clamp_min": {},
+	"count_over_time": {}, "days_in_month": {}, "day_of_month": {}, "day_of_week": {}, "delta": {}, "deriv": {},
+	"exp": {}, "floor": {}, "holt_winters": {}, "hour": {}, "idelta": {}, "increase": {}, "irate": {}, "ln": {},
+	"log2": {}, "log10": {}, "max_over_time": {}, "min_over_time": {}, "minute": {}, "month": {},
+	"predict_linear": {}, "quantile_over_time": {}, "rate": {}, "resets": {}, "round": {}, "sqrt": {},
+	"stddev_over_time": {}, "stdvar_over_time": {}, "sum_over_time": {}, "timestamp": {}, "year": {},
+}
+
+// ShardingLabels returns the labels to shard the series of the query by if the query can be evaluated as partial
+// queries over disjoint shards of the series whose results are simply merged. This is the case for aggregations
+// grouping by labels, optionally combined with literals, whose groups only depend on series sharing the values of the
+// grouping labels.
+func ShardingLabels(expr promql.Expr) ([]string, bool) {
+	switch e := expr.(type) {
+	case *promql.ParenExpr:
+		return ShardingLabels(e.Expr)
+	case *promql.UnaryExpr:
+		return ShardingLabels(e.Expr)
+	case *promql.BinaryExpr:
+		if isLiteral(e.RHS) {
+			return ShardingLabels(e.LHS)
+		}
+		if isLiteral(e.LHS) {
+			return ShardingLabels(e.RHS)
+		}
+		return nil, false
+	case *promql.AggregateExpr:
+		if e.Without || len(e.Grouping) == 0 || !shardableAggregation(e) {
+			return nil, false
+		}
+		for _, l := range e.Grouping {
+			// Most functions and operators drop the metric name, its values in the stores do not tell the groups.
+			if l == labels.MetricName {
+				return nil, false
+			}
+		}
+		if !seriesLocal(e.Expr, e.Grouping) {
+			return nil, false
+		}
+		res := append([]string(nil), e.Grouping...)
+		sort.Strings(res)
+		return res, true
+	}
+	return nil, false
+}
+
+// shardableAggregation returns whether the aggregation keeps the labels of its groups, which is not the case for
+// count_values adding a label, the only aggregation with a string parameter.
+func shardableAggregation(e *promql.AggregateExpr) bool {
+	return e.Param == nil || (isLiteral(e.Param) && e.Param.Type() != promql.ValueTypeString)
+}
+
+func isLiteral(expr promql.Expr) bool {
+	switch e := expr.(type) {
+	case *promql.NumberLiteral, *promql.StringLiteral:
+		return true
+	case *promql.ParenExpr:
+		return isLiteral(e.Expr)
+	case *promql.UnaryExpr:
+		return isLiteral(e.Expr)
+	case *promql.BinaryExpr:
+		return isLiteral(e.LHS) && isLiteral(e.RHS)
+	}
+	return false
+}
+
+func contains(ls []string, l string) bool {
+	for _, x := range ls {
+		if x == l {
+			return true
+		}
+	}
+	return false
+}
+
+// seriesLocal returns whether every series of the expression only depends on input series with the same values of
+// the grouping labels as itself.
+func seriesLocal(expr promql.Expr, grouping []string) bool {
+	switch e := expr.(type) {
+	case *promql.VectorSelector, *promql.MatrixSelector, *promql.NumberLiteral, *promql.StringLiteral:
+		return true
+	case *promql.ParenExpr:
+		return seriesLocal(e.Expr, grouping)
+	case *promql.UnaryExpr:
+		return seriesLocal(e.Expr, grouping)
+	case *promql.SubqueryExpr:
+		return seriesLocal(e.Expr, grouping)
+	case *promql.Call:
+		if _, ok := seriesLocalFuncs[e.Func.Name]; !ok {
+			// histogram_quantile combines the buckets of a histogram, which only differ in the le label.
+			if e.Func.Name != "histogram_quantile" || contains(grouping, "le") {
+				return false
+			}
+		}
+		for _, a := range e.Args {
+			if !seriesLocal(a, grouping) {
+				return false
+			}
+		}
+		return true
+	case *promql.BinaryExpr:
+		if !seriesLocal(e.LHS, grouping) || !seriesLocal(e.RHS, grouping) {
+			return false
+		}
+		m := e.VectorMatching
+		if m == nil {
+			return true
+		}
+		// Labels included from the other side may overwrite the grouping labels.
+		if len(m.Include) > 0 {
+			return false
+		}
+		// Matched series have to agree on the grouping labels.
+		for _, l := range grouping {
+			if m.On != contains(m.MatchingLabels, l) {
+				return false
+			}
+		}
+		return true
+	case *promql.AggregateExpr:
+		if !shardableAggregation(e) {
+			return false
+		}
+		// The groups of the inner aggregation have to agree on the grouping labels.
+		for _, l := range grouping {
+			if e.Without == contains(e.Grouping, l) {
+				return false
+			}
+		}
+		return seriesLocal(e.Expr, grouping)
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func TestShardingLabels(t *testing.T) {
+	for _, tcase := range []struct {
+		query  string
+		labels []string
+	}{
+		{query: `sum by (job, instance) (rate(http_requests_total[5m]))`, labels: []string{"instance", "job"}},
+		{query: `count by (job) (up == 0) * 100`, labels: []string{"job"}},
+		{query: `(max by (job) (sum by (job, instance) (up)))`, labels: []string{"job"}},
+		{query: `topk by (job) (3, sum without (cpu) (rate(node_cpu_seconds_total[1m])))`, labels: []string{"job"}},
+		{query: `sum by (job) (rate(errors_total[5m]) / on (job, instance) rate(requests_total[5m]))`, labels: []string{"job"}},
+		{query: `histogram_quantile(0.9, sum by (job, le) (rate(duration_bucket[5m])))`},
+		{query: `sum by (job) (histogram_quantile(0.9, rate(duration_bucket[5m])))`, labels: []string{"job"}},
+		{query: `sum by (le) (histogram_quantile(0.9, rate(duration_bucket[5m])))`},
+		{query: `sum(up)`},
+		{query: `sum without (instance) (up)`},
+		{query: `sum by (__name__) (up)`},
+		{query: `sum by (job) (up) / sum by (job) (up)`},
+		{query: `sum by (job) (label_replace(up, "job", "$1", "instance", "(.*)"))`},
+		{query: `sum by (job) (rate(errors_total[5m]) / on (instance) rate(requests_total[5m]))`},
+		{query: `sum by (job) (errors_total * on (instance) group_left (job) info)`},
+		{query: `count_values by (job) ("value", up)`},
+		{query: `sum by (job) (sum by (instance) (up))`},
+		{query: `rate(http_requests_total[5m])`},
+	} {
+		t.Run(tcase.query, func(t *testing.T) {
+			expr, err := promql.ParseExpr(tcase.query)
+			testutil.Ok(t, err)
+
+			lbls, ok := ShardingLabels(expr)
+			testutil.Equals(t, tcase.labels != nil, ok)
+			testutil.Equals(t, tcase.labels, lbls)
+		})
+	}
+}
+
+func TestQuerier_Select_Shard(t *testing.T) {
+	var resps []*storepb.SeriesResponse
+	for _, job := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		for _, replica := range []string{"1", "2"} {
+			resps = append(resps, storeSeriesResponse(t, labels.FromStrings("job", job, "replica", replica), []sample{{1, 1}}))
+		}
+	}
+	testProxy := &storeServer{resps: resps}
+
+	seen := map[string]int{}
+	for i := int64(0); i < 3; i++ {
+		ctx := ContextWithShardInfo(context.Background(), &storepb.ShardInfo{
+			ShardIndex:  i,
+			TotalShards: 3,
+			Labels:      []string{"job", "replica"},
+		})
+		q := newQuerier(ctx, nil, 1, 300, []string{"replica"}, nil, testProxy, false, 0, true, nil)
+
+		res, _, err := q.Select(&storage.SelectParams{})
+		testutil.Ok(t, err)
+
+		shardJobs := map[string]int{}
+		for res.Next() {
+			shardJobs[res.At().Labels().Get("job")]++
+		}
+		testutil.Ok(t, res.Err())
+		testutil.Ok(t, q.Close())
+
+		for job, n := range shardJobs {
+			// Replicas are in the same shard as the replica label is not used for sharding.
+			testutil.Equals(t, 2, n)
+			seen[job]++
+		}
+	}
+
+	testutil.Equals(t, 8, len(seen))
+	for job, n := range seen {
+		testutil.Assert(t, n == 1, "job %s in %d shards", job, n)
+	}
+}
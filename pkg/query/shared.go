@@ -0,0 +1,93 @@
+package query
+
+import (
+	"context"
+	"sync"
+
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"google.golang.org/grpc/metadata"
+)
+
+type sharedSeriesKey struct{}
+
+// ContextWithSharedSeries returns a context making all queriers created with it share the responses of identical
+// Series requests, e.g. the queries of a batch selecting the same series. Shared requests are canceled with the given
+// context instead of the one of the query sending them first, so that this query being canceled does not fail the
+// request for the others. They still carry the outgoing gRPC metadata, e.g. the tenant, and the deadline of that
+// query, e.g. the query timeout. Successful responses are kept for the lifetime of the context, so it must not outlive
+// the queries and should be canceled once they are done.
+func ContextWithSharedSeries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sharedSeriesKey{}, &sharedSeries{ctx: ctx, calls: map[string]*sharedSeriesCall{}})
+}
+
+// sharedSeriesFromContext returns the shared Series responses of the context or nil if responses are not shared.
+func sharedSeriesFromContext(ctx context.Context) *sharedSeries {
+	s, _ := ctx.Value(sharedSeriesKey{}).(*sharedSeries)
+	return s
+}
+
+type sharedSeries struct {
+	ctx context.Context
+
+	mtx   sync.Mutex
+	calls map[string]*sharedSeriesCall
+}
+
+type sharedSeriesCall struct {
+	done     chan struct{}
+	series   []storepb.Series
+	warnings []string
+	err      error
+}
+
+// do returns the response of the call with the given key, calling f only once for concurrent and later calls of the
+// same key. f is called with the shared context carrying the metadata and deadline of the first caller, callers stop
+// waiting for the response once their context is done. Failed calls are not kept, so later callers call f again.
+// A nil receiver always calls f with the context of the caller. Returned series must not be modified.
+func (s *sharedSeries) do(ctx context.Context, key string, f func(context.Context) ([]storepb.Series, []string, error)) ([]storepb.Series, []string, error) {
+	if s == nil {
+		return f(ctx)
+	}
+
+	s.mtx.Lock()
+	c, ok := s.calls[key]
+	if !ok {
+		c = &sharedSeriesCall{done: make(chan struct{})}
+		s.calls[key] = c
+	}
+	s.mtx.Unlock()
+
+	if !ok {
+		callCtx, cancel := s.callContext(ctx)
+		go func() {
+			defer cancel()
+
+			c.series, c.warnings, c.err = f(callCtx)
+			if c.err != nil {
+				s.mtx.Lock()
+				delete(s.calls, key)
+				s.mtx.Unlock()
+			}
+			close(c.done)
+		}()
+	}
+	select {
+	case <-c.done:
+		return c.series, c.warnings, c.err
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+// callContext returns the context of a call sent for the caller with the given context. It is canceled with the shared
+// context, but has the outgoing gRPC metadata and the deadline of the caller.
+func (s *sharedSeries) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	callCtx := s.ctx
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		callCtx = metadata.NewOutgoingContext(callCtx, md.Copy())
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(callCtx, deadline)
+	}
+	return context.WithCancel(callCtx)
+}
@@ -0,0 +1,148 @@
+package query
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/store"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestSharedSeries_Do(t *testing.T) {
+	var calls int32
+	f := func(context.Context) ([]storepb.Series, []string, error) {
+		atomic.AddInt32(&calls, 1)
+		return []storepb.Series{{Labels: []storepb.Label{{Name: "a", Value: "1"}}}}, []string{"warning"}, nil
+	}
+
+	// Without shared series in the context all calls are made.
+	s := sharedSeriesFromContext(context.Background())
+	_, _, _ = s.do(context.Background(), "a", f)
+	_, _, _ = s.do(context.Background(), "a", f)
+	testutil.Equals(t, int32(2), calls)
+
+	atomic.StoreInt32(&calls, 0)
+	s = sharedSeriesFromContext(ContextWithSharedSeries(context.Background()))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			series, warnings, err := s.do(context.Background(), "a", f)
+			testutil.Ok(t, err)
+			testutil.Equals(t, 1, len(series))
+			testutil.Equals(t, []string{"warning"}, warnings)
+		}()
+	}
+	wg.Wait()
+	_, _, _ = s.do(context.Background(), "b", f)
+	testutil.Equals(t, int32(2), calls)
+}
+
+func TestSharedSeries_Do_CallerCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := sharedSeriesFromContext(ContextWithSharedSeries(ctx))
+
+	release := make(chan struct{})
+	f := func(ctx context.Context) ([]storepb.Series, []string, error) {
+		<-release
+		// The request runs with the shared context, not the one of the canceled caller.
+		return []storepb.Series{{}}, nil, ctx.Err()
+	}
+
+	// The caller sending the request gives up, while the request keeps running for the others.
+	firstCtx, firstCancel := context.WithCancel(context.Background())
+	firstCancel()
+	_, _, err := s.do(firstCtx, "a", f)
+	testutil.Equals(t, context.Canceled, err)
+
+	close(release)
+	series, _, err := s.do(context.Background(), "a", f)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(series))
+}
+
+func TestSharedSeries_Do_CallContext(t *testing.T) {
+	sharedCtx, sharedCancel := context.WithCancel(context.Background())
+	defer sharedCancel()
+	s := sharedSeriesFromContext(ContextWithSharedSeries(sharedCtx))
+
+	// The request has the metadata and deadline of the first caller.
+	callerCtx, callerCancel := context.WithTimeout(ContextWithTenant(context.Background(), "tenant", "team-a"), time.Minute)
+	defer callerCancel()
+	callerDeadline, _ := callerCtx.Deadline()
+	_, _, err := s.do(callerCtx, "a", func(ctx context.Context) ([]storepb.Series, []string, error) {
+		md, ok := metadata.FromOutgoingContext(ctx)
+		testutil.Assert(t, ok, "expected outgoing metadata")
+		testutil.Equals(t, []string{"team-a"}, md.Get(store.DefaultTenantHeader))
+		deadline, ok := ctx.Deadline()
+		testutil.Assert(t, ok, "expected deadline")
+		testutil.Equals(t, callerDeadline, deadline)
+		return nil, nil, nil
+	})
+	testutil.Ok(t, err)
+
+	// Failed requests are sent again by later callers.
+	var calls int32
+	failing := func(context.Context) ([]storepb.Series, []string, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, nil, errors.New("failed")
+	}
+	_, _, err = s.do(context.Background(), "b", failing)
+	testutil.NotOk(t, err)
+	_, _, err = s.do(context.Background(), "b", failing)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, int32(2), calls)
+
+	// Requests are canceled with the shared context.
+	_, _, err = s.do(context.Background(), "c", func(ctx context.Context) ([]storepb.Series, []string, error) {
+		sharedCancel()
+		<-ctx.Done()
+		return nil, nil, ctx.Err()
+	})
+	testutil.Equals(t, context.Canceled, err)
+}
+
+// tenantsStoreServer records the tenants of the Series requests it gets.
+type tenantsStoreServer struct {
+	storepb.StoreServer
+
+	mtx     sync.Mutex
+	tenants []string
+}
+
+func (s *tenantsStoreServer) Series(_ *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	md, _ := metadata.FromOutgoingContext(srv.Context())
+	s.mtx.Lock()
+	s.tenants = append(s.tenants, md.Get(store.DefaultTenantHeader)...)
+	s.mtx.Unlock()
+	return nil
+}
+
+func TestQuerier_Select_SharedSeriesTenants(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = ContextWithSharedSeries(ctx)
+
+	testProxy := &tenantsStoreServer{}
+	for _, tenant := range []string{"team-a", "team-b", "team-a"} {
+		q := newQuerier(ContextWithTenant(ctx, "tenant", tenant), nil, 1, 300, nil, nil, testProxy, false, 0, true, nil)
+		_, _, err := q.Select(&storage.SelectParams{}, &labels.Matcher{Type: labels.MatchEqual, Name: "a", Value: "b"})
+		testutil.Ok(t, err)
+		testutil.Ok(t, q.Close())
+	}
+
+	// Requests of the same tenant are shared, the ones of other tenants are sent with their own tenant.
+	sort.Strings(testProxy.tenants)
+	testutil.Equals(t, []string{"team-a", "team-b"}, testProxy.tenants)
+}
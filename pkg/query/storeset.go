@@ -3,6 +3,7 @@ package query
 import (
 	"context"
 	"fmt"
+	"math"
 	"sort"
 	"sync"
 	"time"
@@ -10,9 +11,17 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/improbable-eng/thanos/pkg/component"
+	"github.com/improbable-eng/thanos/pkg/exemplars"
+	"github.com/improbable-eng/thanos/pkg/exemplars/exemplarspb"
+	"github.com/improbable-eng/thanos/pkg/metadata"
+	"github.com/improbable-eng/thanos/pkg/metadata/metadatapb"
+	"github.com/improbable-eng/thanos/pkg/rules"
+	"github.com/improbable-eng/thanos/pkg/rules/rulespb"
 	"github.com/improbable-eng/thanos/pkg/runutil"
 	"github.com/improbable-eng/thanos/pkg/store"
 	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/targets"
+	"github.com/improbable-eng/thanos/pkg/targets/targetspb"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/tsdb/labels"
@@ -33,6 +42,10 @@ type StoreSpec interface {
 	// NOTE: It is implementation responsibility to retry until context timeout, but a caller responsibility to manage
 	// given store connection.
 	Metadata(ctx context.Context, client storepb.StoreClient) (labels []storepb.Label, mint int64, maxt int64, err error)
+	// StrictStatic returns true if the store should always be kept in the store set, even if it is unhealthy.
+	StrictStatic() bool
+	// DialOpts returns the options to dial the store with. The dial options of the store set are used if nil.
+	DialOpts() []grpc.DialOption
 }
 
 type StoreStatus struct {
@@ -46,13 +59,26 @@ type StoreStatus struct {
 }
 
 type grpcStoreSpec struct {
-	addr string
+	addr         string
+	strictStatic bool
+	dialOpts     []grpc.DialOption
 }
 
 // NewGRPCStoreSpec creates store pure gRPC spec.
 // It uses Info gRPC call to get Metadata.
-func NewGRPCStoreSpec(addr string) StoreSpec {
-	return &grpcStoreSpec{addr: addr}
+// Strict static stores are never removed from the store set. If they are unhealthy, queries fail or get warnings
+// for them instead of silently missing their data.
+// Dial options replace those of the store set if given, e.g. for stores requiring their own TLS configuration.
+func NewGRPCStoreSpec(addr string, strictStatic bool, dialOpts ...grpc.DialOption) StoreSpec {
+	return &grpcStoreSpec{addr: addr, strictStatic: strictStatic, dialOpts: dialOpts}
+}
+
+func (s *grpcStoreSpec) StrictStatic() bool {
+	return s.strictStatic
+}
+
+func (s *grpcStoreSpec) DialOpts() []grpc.DialOption {
+	return s.dialOpts
 }
 
 func (s *grpcStoreSpec) Addr() string {
@@ -208,7 +234,7 @@ func (s *storeRef) close() {
 // Update updates the store set. It fetches current list of store specs from function and updates the fresh metadata
 // from all stores.
 func (s *StoreSet) Update(ctx context.Context) {
-	healthyStores := s.getHealthyStores(ctx)
+	healthyStores, strictErrs := s.getHealthyStores(ctx)
 
 	// Record the number of occurrences of external label combinations for current store slice.
 	externalLabelStores := map[string]int{}
@@ -246,12 +272,12 @@ func (s *StoreSet) Update(ctx context.Context) {
 		}
 
 		if _, ok := s.stores[addr]; ok {
-			s.updateStoreStatus(store, nil)
+			s.updateStoreStatus(store, strictErrs[addr])
 			continue
 		}
 
 		s.stores[addr] = store
-		s.updateStoreStatus(store, nil)
+		s.updateStoreStatus(store, strictErrs[addr])
 		level.Info(s.logger).Log("msg", "adding new store to query storeset", "address", addr)
 	}
 	s.externalLabelStores = externalLabelStores
@@ -259,11 +285,14 @@ func (s *StoreSet) Update(ctx context.Context) {
 	s.cleanUpStoreStatuses()
 }
 
-func (s *StoreSet) getHealthyStores(ctx context.Context) map[string]*storeRef {
+// getHealthyStores returns the healthy stores and the unhealthy strict static stores, which are returned together
+// with their errors.
+func (s *StoreSet) getHealthyStores(ctx context.Context) (map[string]*storeRef, map[string]error) {
 	var (
 		unique = make(map[string]struct{})
 
 		healthyStores = make(map[string]*storeRef, len(s.stores))
+		strictErrs    = map[string]error{}
 		mtx           sync.Mutex
 		wg            sync.WaitGroup
 	)
@@ -285,20 +314,36 @@ func (s *StoreSet) getHealthyStores(ctx context.Context) map[string]*storeRef {
 			ctx, cancel := context.WithTimeout(ctx, s.gRPCInfoCallTimeout)
 			defer cancel()
 
+			var strictErr error
 			store, ok := s.stores[addr]
 			if ok {
 				// Check existing store. Is it healthy? What are current metadata?
 				labels, minTime, maxTime, err := spec.Metadata(ctx, store.StoreClient)
 				if err != nil {
-					// Peer unhealthy. Do not include in healthy stores.
 					s.updateStoreStatus(store, err)
 					level.Warn(s.logger).Log("msg", "update of store node failed", "err", err, "address", addr)
-					return
+					if !spec.StrictStatic() {
+						// Peer unhealthy. Do not include in healthy stores.
+						return
+					}
+					// Strict static stores are kept with their last known metadata.
+					strictErr = err
+				} else {
+					store.Update(labels, minTime, maxTime)
+				}
+				if strictErr == nil && store.storeType == nil {
+					// Strict static store which was unreachable when added.
+					if resp, err := store.StoreClient.Info(ctx, &storepb.InfoRequest{}, grpc.FailFast(false)); err == nil {
+						store.storeType = component.FromProto(resp.StoreType)
+					}
 				}
-				store.Update(labels, minTime, maxTime)
 			} else {
 				// New store or was unhealthy and was removed in the past - create new one.
-				conn, err := grpc.DialContext(ctx, addr, s.dialOpts...)
+				dialOpts := spec.DialOpts()
+				if dialOpts == nil {
+					dialOpts = s.dialOpts
+				}
+				conn, err := grpc.DialContext(ctx, addr, dialOpts...)
 				if err != nil {
 					s.updateStoreStatus(&storeRef{addr: addr}, err)
 					level.Warn(s.logger).Log("msg", "update of store node failed", "err", errors.Wrap(err, "dialing connection"), "address", addr)
@@ -309,25 +354,34 @@ func (s *StoreSet) getHealthyStores(ctx context.Context) map[string]*storeRef {
 				// Initial info call for all types of stores to check gRPC StoreAPI.
 				resp, err := store.StoreClient.Info(ctx, &storepb.InfoRequest{}, grpc.FailFast(false))
 				if err != nil {
-					store.close()
 					s.updateStoreStatus(store, err)
 					level.Warn(s.logger).Log("msg", "update of store node failed", "err", errors.Wrap(err, "initial store client info fetch"), "address", addr)
-					return
+					if !spec.StrictStatic() {
+						store.close()
+						return
+					}
+					// Nothing is known about the strict static store yet, so it has to be queried for all data.
+					store.Update(nil, math.MinInt64, math.MaxInt64)
+					strictErr = err
+				} else {
+					store.storeType = component.FromProto(resp.StoreType)
+					store.Update(resp.Labels, resp.MinTime, resp.MaxTime)
 				}
-				store.storeType = component.FromProto(resp.StoreType)
-				store.Update(resp.Labels, resp.MinTime, resp.MaxTime)
 			}
 
 			mtx.Lock()
 			defer mtx.Unlock()
 
 			healthyStores[addr] = store
+			if strictErr != nil {
+				strictErrs[addr] = strictErr
+			}
 		}(storeSpec)
 	}
 
 	wg.Wait()
 
-	return healthyStores
+	return healthyStores, strictErrs
 }
 
 func externalLabelsFromStore(store *storeRef) string {
@@ -405,6 +459,94 @@ func (s *StoreSet) Get() []store.Client {
 	return stores
 }
 
+// GetExemplarsClients returns the exemplars API clients of the active sidecars and queriers, the only StoreAPIs that
+// may serve exemplars.
+func (s *StoreSet) GetExemplarsClients() []exemplars.Client {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	clients := make([]exemplars.Client, 0, len(s.stores))
+	for _, st := range s.stores {
+		st.mtx.RLock()
+		t := st.storeType
+		st.mtx.RUnlock()
+		if t != component.Sidecar && t != component.Query {
+			continue
+		}
+		clients = append(clients, exemplars.Client{
+			ExemplarsClient: exemplarspb.NewExemplarsClient(st.cc),
+			Addr:            st.addr,
+		})
+	}
+	return clients
+}
+
+// GetMetadataClients returns the metadata API clients of the active sidecars and queriers, the only StoreAPIs that
+// may serve metric metadata.
+func (s *StoreSet) GetMetadataClients() []metadata.Client {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	clients := make([]metadata.Client, 0, len(s.stores))
+	for _, st := range s.stores {
+		st.mtx.RLock()
+		t := st.storeType
+		st.mtx.RUnlock()
+		if t != component.Sidecar && t != component.Query {
+			continue
+		}
+		clients = append(clients, metadata.Client{
+			MetadataClient: metadatapb.NewMetadataClient(st.cc),
+			Addr:           st.addr,
+		})
+	}
+	return clients
+}
+
+// GetTargetsClients returns the targets API clients of the active sidecars and queriers, the only StoreAPIs that
+// may serve targets.
+func (s *StoreSet) GetTargetsClients() []targets.Client {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	clients := make([]targets.Client, 0, len(s.stores))
+	for _, st := range s.stores {
+		st.mtx.RLock()
+		t := st.storeType
+		st.mtx.RUnlock()
+		if t != component.Sidecar && t != component.Query {
+			continue
+		}
+		clients = append(clients, targets.Client{
+			TargetsClient: targetspb.NewTargetsClient(st.cc),
+			Addr:          st.addr,
+		})
+	}
+	return clients
+}
+
+// GetRulesClients returns the rules API clients of the active rulers, sidecars and queriers, the only StoreAPIs that
+// may serve rules.
+func (s *StoreSet) GetRulesClients() []rules.Client {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	clients := make([]rules.Client, 0, len(s.stores))
+	for _, st := range s.stores {
+		st.mtx.RLock()
+		t := st.storeType
+		st.mtx.RUnlock()
+		if t != component.Rule && t != component.Sidecar && t != component.Query {
+			continue
+		}
+		clients = append(clients, rules.Client{
+			RulesClient: rulespb.NewRulesClient(st.cc),
+			Addr:        st.addr,
+		})
+	}
+	return clients
+}
+
 func (s *StoreSet) Close() {
 	for _, st := range s.stores {
 		st.close()
@@ -125,7 +125,7 @@ func (s *testStores) CloseOne(addr string) {
 func specsFromAddrFunc(addrs []string) func() []StoreSpec {
 	return func() (specs []StoreSpec) {
 		for _, addr := range addrs {
-			specs = append(specs, NewGRPCStoreSpec(addr))
+			specs = append(specs, NewGRPCStoreSpec(addr, false))
 		}
 		return specs
 	}
@@ -226,6 +226,45 @@ func TestStoreSet_StaticStores_NoneAvailable(t *testing.T) {
 	// Leak test will ensure that we don't keep client connection around.
 }
 
+func TestStoreSet_StrictStaticStores(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	st, err := newTestStores(2)
+	testutil.Ok(t, err)
+	defer st.Close()
+
+	initialStoreAddr := st.StoreAddresses()
+	st.CloseOne(initialStoreAddr[0])
+
+	storeSet := NewStoreSet(nil, nil, func() (specs []StoreSpec) {
+		for _, addr := range initialStoreAddr {
+			specs = append(specs, NewGRPCStoreSpec(addr, true))
+		}
+		return specs
+	}, testGRPCOpts, time.Minute)
+	storeSet.gRPCInfoCallTimeout = 2 * time.Second
+	defer storeSet.Close()
+
+	storeSet.Update(context.Background())
+	testutil.Assert(t, len(storeSet.stores) == 2, "strict static stores should be kept even if unavailable")
+
+	// Unreachable strict stores are queried for all data.
+	mint, maxt := storeSet.stores[initialStoreAddr[0]].TimeRange()
+	testutil.Equals(t, int64(math.MinInt64), mint)
+	testutil.Equals(t, int64(math.MaxInt64), maxt)
+	testutil.Equals(t, 1, len(storeSet.stores[initialStoreAddr[1]].Labels()))
+
+	st.CloseOne(initialStoreAddr[1])
+	storeSet.Update(context.Background())
+	testutil.Assert(t, len(storeSet.stores) == 2, "strict static stores should be kept even if unavailable")
+
+	// The last known metadata is kept, the errors are reported in the statuses.
+	testutil.Equals(t, 1, len(storeSet.stores[initialStoreAddr[1]].Labels()))
+	for _, status := range storeSet.GetStoreStatus() {
+		testutil.Assert(t, status.LastError != nil, "expected error in status of %s", status.Name)
+	}
+}
+
 func TestStoreSet_AllAvailable_BlockExtLsetDuplicates(t *testing.T) {
 	defer leaktest.CheckTimeout(t, 10*time.Second)()
 
@@ -0,0 +1,69 @@
+package query
+
+import (
+	"context"
+
+	"github.com/improbable-eng/thanos/pkg/store"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql"
+	"google.golang.org/grpc/metadata"
+)
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a context limiting queriers created with it to the series of the given tenant, i.e. the
+// series with the given value of the tenant label. The tenant is passed on to the stores in the gRPC metadata key
+// store.DefaultTenantHeader, so that stores enforcing tenancy can validate it.
+func ContextWithTenant(ctx context.Context, labelName, tenant string) context.Context {
+	ctx = metadata.AppendToOutgoingContext(ctx, store.DefaultTenantHeader, tenant)
+	return context.WithValue(ctx, tenantKey{}, &labels.Matcher{Type: labels.MatchEqual, Name: labelName, Value: tenant})
+}
+
+// tenantMatcherFromContext returns the matcher of the tenant of the context, nil if no tenant is set.
+func tenantMatcherFromContext(ctx context.Context) *labels.Matcher {
+	m, _ := ctx.Value(tenantKey{}).(*labels.Matcher)
+	return m
+}
+
+// withTenant returns the matchers limited to the series of the tenant. A nil tenant returns the matchers as they are.
+func withTenant(tenant *labels.Matcher, ms []*labels.Matcher) []*labels.Matcher {
+	if tenant == nil {
+		return ms
+	}
+	res := make([]*labels.Matcher, 0, len(ms)+1)
+	res = append(res, ms...)
+	return append(res, tenant)
+}
+
+// ValidateTenantMatchers returns an error if any of the matchers on the tenant label does not match the tenant, e.g.
+// because a query asks for the series of another tenant.
+func ValidateTenantMatchers(labelName, tenant string, ms []*labels.Matcher) error {
+	for _, m := range ms {
+		if m.Name == labelName && !m.Matches(tenant) {
+			return errors.Errorf("matcher %s does not match tenant %q of the request", m, tenant)
+		}
+	}
+	return nil
+}
+
+// ValidateTenantQuery returns an error if any selector of the query has a matcher on the tenant label not matching the
+// tenant.
+func ValidateTenantQuery(labelName, tenant string, expr promql.Expr) (err error) {
+	promql.Inspect(expr, func(node promql.Node, _ []promql.Node) error {
+		var ms []*labels.Matcher
+		switch n := node.(type) {
+		case *promql.VectorSelector:
+			ms = n.LabelMatchers
+		case *promql.MatrixSelector:
+			ms = n.LabelMatchers
+		default:
+			return nil
+		}
+		if err == nil {
+			err = ValidateTenantMatchers(labelName, tenant, ms)
+		}
+		return err
+	})
+	return err
+}
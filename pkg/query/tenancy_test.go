@@ -0,0 +1,55 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/store"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestQuerier_Tenant(t *testing.T) {
+	testProxy := &labelStoreServer{}
+	ctx := ContextWithTenant(context.Background(), "tenant", "team-a")
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	testutil.Assert(t, ok, "expected outgoing metadata")
+	testutil.Equals(t, []string{"team-a"}, md.Get(store.DefaultTenantHeader))
+
+	q := newQuerier(ctx, nil, 1, 300, nil, nil, testProxy, false, 0, true, nil)
+	defer func() { testutil.Ok(t, q.Close()) }()
+
+	tenant := storepb.LabelMatcher{Type: storepb.LabelMatcher_EQ, Name: "tenant", Value: "team-a"}
+
+	_, err := q.LabelNames()
+	testutil.Ok(t, err)
+	testutil.Equals(t, []storepb.LabelMatcher{tenant}, testProxy.namesReqs[0].Matchers)
+
+	_, err = q.LabelValuesMatching("a", &labels.Matcher{Type: labels.MatchEqual, Name: "a", Value: "b"})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "a", Value: "b"}, tenant}, testProxy.valuesReqs[0].Matchers)
+}
+
+func TestValidateTenantQuery(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		ok    bool
+	}{
+		{query: `up`, ok: true},
+		{query: `up{tenant="team-a"}`, ok: true},
+		{query: `sum(rate(up{tenant=~"team-.*"}[5m]))`, ok: true},
+		{query: `up{tenant="team-b"}`},
+		{query: `up / on() rate(down{tenant!="team-a"}[5m])`},
+		{query: `sum(rate(up{tenant=""}[5m]))`},
+	} {
+		expr, err := promql.ParseExpr(tc.query)
+		testutil.Ok(t, err)
+
+		err = ValidateTenantQuery("tenant", "team-a", expr)
+		testutil.Assert(t, tc.ok == (err == nil), "query %s: unexpected error %v", tc.query, err)
+	}
+}
@@ -0,0 +1,78 @@
+package queryfrontend
+
+import (
+	"hash/fnv"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/cache"
+	"github.com/improbable-eng/thanos/pkg/cacheutil"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ResponseCacheProvider is the type of the backend caching results of split queries.
+type ResponseCacheProvider string
+
+const (
+	INMEMORY  ResponseCacheProvider = "IN-MEMORY"
+	MEMCACHED ResponseCacheProvider = "MEMCACHED"
+	REDIS     ResponseCacheProvider = "REDIS"
+)
+
+// ResponseCacheConfig is the config of the results cache.
+type ResponseCacheConfig struct {
+	Type   ResponseCacheProvider `yaml:"type"`
+	Config interface{}           `yaml:"config"`
+}
+
+// NewResponseCacheFromConfig returns the results cache described by the given YAML configuration. Empty
+// configuration disables caching and returns a nil cache.
+func NewResponseCacheFromConfig(logger log.Logger, confContentYaml []byte, reg prometheus.Registerer) (cache.Cache, error) {
+	if len(strings.TrimSpace(string(confContentYaml))) == 0 {
+		return nil, nil
+	}
+
+	config := &ResponseCacheConfig{}
+	if err := yaml.UnmarshalStrict(confContentYaml, config); err != nil {
+		return nil, errors.Wrap(err, "parsing config YAML file")
+	}
+
+	backendConfig, err := yaml.Marshal(config.Config)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal content of cache backend configuration")
+	}
+
+	const name = "query-frontend"
+
+	var c cache.Cache
+	switch strings.ToUpper(string(config.Type)) {
+	case string(INMEMORY):
+		c, err = cache.NewInMemoryCache(name, logger, reg, backendConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "create in-memory cache")
+		}
+	case string(MEMCACHED):
+		memcached, err := cacheutil.NewMemcachedClient(logger, name, backendConfig, reg)
+		if err != nil {
+			return nil, errors.Wrap(err, "create memcached client")
+		}
+		c = cache.NewMemcachedCache(name, logger, memcached, reg)
+	case string(REDIS):
+		redis, err := cacheutil.NewRedisClient(logger, name, backendConfig, reg)
+		if err != nil {
+			return nil, errors.Wrap(err, "create redis client")
+		}
+		c = cache.NewRedisCache(name, logger, redis, reg)
+	default:
+		return nil, errors.Errorf("cache with type %s is not supported", config.Type)
+	}
+	return c, nil
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum64()
+}
@@ -0,0 +1,404 @@
+// Package queryfrontend implements a HTTP frontend for the Thanos Query API. Range queries are split into
+// queries over shorter time ranges which are executed in parallel, retried on failures and cached, everything
+// else is proxied unchanged to the downstream querier.
+package queryfrontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/cache"
+	"github.com/improbable-eng/thanos/pkg/runutil"
+	"github.com/improbable-eng/thanos/pkg/store"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/tsdb/labels"
+)
+
+const rangeQueryPath = "/api/v1/query_range"
+
+// Config configures query splitting, retries and caching of the frontend.
+type Config struct {
+	// SplitInterval is the interval range queries are split by. Zero disables splitting.
+	SplitInterval time.Duration
+	// MaxRetries is the number of times a split query is retried on server and transport errors.
+	MaxRetries int
+	// AlignWithStep aligns start and end of range queries with their step.
+	AlignWithStep bool
+	// MaxConcurrency is the maximum number of split queries of a range query executed in parallel.
+	MaxConcurrency int
+	// MaxCacheFreshness is the period before now for which results are not cached, as they may still change.
+	MaxCacheFreshness time.Duration
+	// CacheTTL is the TTL of cached results. Zero means no expiration.
+	CacheTTL time.Duration
+	// TenantHeader is the HTTP header carrying the tenant of a request. Cached results are kept per tenant.
+	TenantHeader string
+}
+
+// DefaultConfig returns the default frontend configuration.
+func DefaultConfig() Config {
+	return Config{
+		SplitInterval:     24 * time.Hour,
+		MaxRetries:        5,
+		AlignWithStep:     true,
+		MaxConcurrency:    14,
+		MaxCacheFreshness: 10 * time.Minute,
+		TenantHeader:      store.DefaultTenantHeader,
+	}
+}
+
+// Frontend is a http.Handler serving the Query API of a downstream querier.
+type Frontend struct {
+	logger     log.Logger
+	downstream *url.URL
+	client     *http.Client
+	proxy      *httputil.ReverseProxy
+	cache      cache.Cache
+	conf       Config
+
+	rangeQueries prometheus.Counter
+	splitQueries prometheus.Counter
+	retries      prometheus.Counter
+}
+
+// NewFrontend returns a new Frontend for the querier at the given URL. The cache may be nil, in which case
+// results are not cached.
+func NewFrontend(logger log.Logger, reg prometheus.Registerer, downstream *url.URL, c cache.Cache, conf Config) *Frontend {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	if conf.MaxConcurrency <= 0 {
+		conf.MaxConcurrency = 1
+	}
+	f := &Frontend{
+		logger:     logger,
+		downstream: downstream,
+		client:     &http.Client{},
+		proxy:      httputil.NewSingleHostReverseProxy(downstream),
+		cache:      c,
+		conf:       conf,
+	}
+
+	f.rangeQueries = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "thanos_query_frontend_range_queries_total",
+		Help: "Total number of range queries received.",
+	})
+	f.splitQueries = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "thanos_query_frontend_split_queries_total",
+		Help: "Total number of split queries range queries were split into.",
+	})
+	f.retries = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "thanos_query_frontend_retries_total",
+		Help: "Total number of retried split queries.",
+	})
+	if reg != nil {
+		reg.MustRegister(f.rangeQueries, f.splitQueries, f.retries)
+	}
+	return f
+}
+
+func (f *Frontend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/-/healthy":
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "Thanos Query Frontend is Healthy.\n")
+	case strings.HasSuffix(r.URL.Path, rangeQueryPath):
+		f.serveRangeQuery(w, r)
+	default:
+		f.proxy.ServeHTTP(w, r)
+	}
+}
+
+// apiResponse is the subset of the Query API response used for merging range query results.
+type apiResponse struct {
+	Status    string   `json:"status"`
+	Data      *apiData `json:"data,omitempty"`
+	ErrorType string   `json:"errorType,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+type apiData struct {
+	ResultType string         `json:"resultType"`
+	Result     []sampleStream `json:"result"`
+}
+
+// sampleStream is a series of a matrix result. Samples are kept in their encoded form.
+type sampleStream struct {
+	Metric map[string]string `json:"metric"`
+	Values []json.RawMessage `json:"values"`
+}
+
+// splitResult is the result of a single split query.
+type splitResult struct {
+	body   []byte
+	status int
+	header http.Header
+	resp   *apiResponse
+	cached bool
+}
+
+func (f *Frontend) serveRangeQuery(w http.ResponseWriter, r *http.Request) {
+	f.rangeQueries.Inc()
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_data", errors.Wrap(err, "parse form"))
+		return
+	}
+	req, err := parseRangeRequest(r.URL.Path, r.Form)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_data", err)
+		return
+	}
+	if f.conf.TenantHeader != "" {
+		req.tenant = r.Header.Get(f.conf.TenantHeader)
+	}
+	if f.conf.AlignWithStep {
+		req.alignWithStep()
+	}
+
+	splits := req.split(f.conf.SplitInterval)
+	f.splitQueries.Add(float64(len(splits)))
+
+	results := make([]*splitResult, len(splits))
+	f.fetchCached(r.Context(), splits, results)
+
+	var (
+		wg   sync.WaitGroup
+		mtx  sync.Mutex
+		ferr error
+		gate = make(chan struct{}, f.conf.MaxConcurrency)
+	)
+	for i, s := range splits {
+		if results[i] != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, s *rangeRequest) {
+			defer wg.Done()
+			gate <- struct{}{}
+			defer func() { <-gate }()
+
+			res, err := f.do(r.Context(), r.Header, s)
+			if err != nil {
+				mtx.Lock()
+				if ferr == nil {
+					ferr = err
+				}
+				mtx.Unlock()
+				return
+			}
+			results[i] = res
+		}(i, s)
+	}
+	wg.Wait()
+
+	if ferr != nil {
+		writeError(w, http.StatusBadGateway, "unavailable", ferr)
+		return
+	}
+
+	// Errors of any split query fail the whole query, they are passed to the client as returned by the querier.
+	for _, res := range results {
+		if res.status != http.StatusOK || res.resp == nil || res.resp.Status != "success" {
+			copyHeader(w.Header(), res.header)
+			w.WriteHeader(res.status)
+			_, _ = w.Write(res.body)
+			return
+		}
+	}
+
+	f.storeCached(r.Context(), splits, results)
+
+	merged, err := merge(results)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", err)
+		return
+	}
+	b, err := json.Marshal(merged)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", errors.Wrap(err, "marshal response"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b)
+}
+
+func (f *Frontend) fetchCached(ctx context.Context, splits []*rangeRequest, results []*splitResult) {
+	if f.cache == nil {
+		return
+	}
+	keys := make([]string, 0, len(splits))
+	for _, s := range splits {
+		keys = append(keys, s.cacheKey())
+	}
+	hits := f.cache.Fetch(ctx, keys)
+	for i, k := range keys {
+		b, ok := hits[k]
+		if !ok {
+			continue
+		}
+		var resp apiResponse
+		if err := json.Unmarshal(b, &resp); err != nil {
+			level.Warn(f.logger).Log("msg", "failed to decode cached result; querying it again", "key", k, "err", err)
+			continue
+		}
+		results[i] = &splitResult{body: b, status: http.StatusOK, resp: &resp, cached: true}
+	}
+}
+
+// storeCached caches successful results without warnings of split queries which ended long enough ago for
+// their result to not change anymore.
+func (f *Frontend) storeCached(ctx context.Context, splits []*rangeRequest, results []*splitResult) {
+	if f.cache == nil {
+		return
+	}
+	maxEnd := time.Now().Add(-f.conf.MaxCacheFreshness).UnixNano() / int64(time.Millisecond)
+
+	data := map[string][]byte{}
+	for i, s := range splits {
+		res := results[i]
+		if res.cached || len(res.resp.Warnings) > 0 || s.end > maxEnd {
+			continue
+		}
+		data[s.cacheKey()] = res.body
+	}
+	if len(data) > 0 {
+		f.cache.Store(ctx, data, f.conf.CacheTTL)
+	}
+}
+
+// do executes the given split query against the downstream querier. Transport and server errors are retried.
+// Client errors are returned as a result, as retrying them does not help.
+func (f *Frontend) do(ctx context.Context, header http.Header, s *rangeRequest) (*splitResult, error) {
+	u := *f.downstream
+	u.Path = strings.TrimSuffix(u.Path, "/") + s.path
+	u.RawQuery = s.values().Encode()
+
+	var lastErr error
+	for attempt := 0; attempt <= f.conf.MaxRetries; attempt++ {
+		if attempt > 0 {
+			f.retries.Inc()
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		res, err := f.doOnce(ctx, header, u.String())
+		if err == nil && res.status < 500 {
+			return res, nil
+		}
+		if err == nil {
+			err = errors.Errorf("server error %d: %s", res.status, strings.TrimSpace(string(res.body)))
+		}
+		lastErr = err
+		level.Debug(f.logger).Log("msg", "split query failed", "attempt", attempt, "url", u.String(), "err", err)
+	}
+	return nil, errors.Wrapf(lastErr, "query %s", u.Path)
+}
+
+func (f *Frontend) doOnce(ctx context.Context, header http.Header, u string) (*splitResult, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "create request")
+	}
+	copyHeader(req.Header, header)
+	req.Header.Del("Accept-Encoding")
+
+	resp, err := f.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer runutil.CloseWithLogOnErr(f.logger, resp.Body, "close split query response body")
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read response body")
+	}
+	res := &splitResult{body: b, status: resp.StatusCode, header: resp.Header}
+	if resp.StatusCode == http.StatusOK {
+		var r apiResponse
+		if err := json.Unmarshal(b, &r); err != nil {
+			return nil, errors.Wrap(err, "decode response")
+		}
+		res.resp = &r
+	}
+	return res, nil
+}
+
+// merge concatenates the results of split queries, which cover consecutive and disjoint time ranges.
+func merge(results []*splitResult) (*apiResponse, error) {
+	var (
+		series   = map[string]*sampleStream{}
+		lsets    = map[string]labels.Labels{}
+		warnings []string
+		seen     = map[string]struct{}{}
+	)
+	for _, res := range results {
+		for _, w := range res.resp.Warnings {
+			if _, ok := seen[w]; ok {
+				continue
+			}
+			seen[w] = struct{}{}
+			warnings = append(warnings, w)
+		}
+		if res.resp.Data == nil {
+			continue
+		}
+		if res.resp.Data.ResultType != "matrix" {
+			return nil, errors.Errorf("unexpected result type %q of range query", res.resp.Data.ResultType)
+		}
+		for _, s := range res.resp.Data.Result {
+			lset := labels.FromMap(s.Metric)
+			k := lset.String()
+			if m, ok := series[k]; ok {
+				m.Values = append(m.Values, s.Values...)
+				continue
+			}
+			s := s
+			series[k] = &s
+			lsets[k] = lset
+		}
+	}
+
+	keys := make([]string, 0, len(series))
+	for k := range series {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return labels.Compare(lsets[keys[i]], lsets[keys[j]]) < 0
+	})
+
+	data := &apiData{ResultType: "matrix", Result: make([]sampleStream, 0, len(keys))}
+	for _, k := range keys {
+		data.Result = append(data.Result, *series[k])
+	}
+	return &apiResponse{Status: "success", Data: data, Warnings: warnings}, nil
+}
+
+func writeError(w http.ResponseWriter, status int, typ string, err error) {
+	b, _ := json.Marshal(&apiResponse{Status: "error", ErrorType: typ, Error: err.Error()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(b)
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, vs := range src {
+		for _, v := range vs {
+			dst.Add(k, v)
+		}
+	}
+}
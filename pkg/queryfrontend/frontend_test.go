@@ -0,0 +1,246 @@
+package queryfrontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/cache"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRangeRequest_Split(t *testing.T) {
+	hour := int64(time.Hour / time.Millisecond)
+
+	for _, tcase := range []struct {
+		start, end, step int64
+		interval         time.Duration
+		expected         [][2]int64
+	}{
+		{
+			start: 0, end: 2 * hour, step: 1000, interval: 0,
+			expected: [][2]int64{{0, 2 * hour}},
+		},
+		{
+			start: 0, end: hour - 1000, step: 1000, interval: time.Hour,
+			expected: [][2]int64{{0, hour - 1000}},
+		},
+		{
+			start: 0, end: 2 * hour, step: 1000, interval: time.Hour,
+			expected: [][2]int64{{0, hour - 1000}, {hour, 2*hour - 1000}, {2 * hour, 2 * hour}},
+		},
+		{
+			// Evaluation timestamps not at multiples of the step are kept.
+			start: 500, end: 2 * hour, step: 7 * 60 * 1000, interval: time.Hour,
+			expected: [][2]int64{{500, 500 + 8*420000}, {500 + 9*420000, 500 + 17*420000}},
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			r := &rangeRequest{start: tcase.start, end: tcase.end, step: tcase.step}
+			var res [][2]int64
+			for _, s := range r.split(tcase.interval) {
+				res = append(res, [2]int64{s.start, s.end})
+			}
+			testutil.Equals(t, tcase.expected, res)
+		})
+	}
+}
+
+// fakeQuerier serves range queries returning a single series with the evaluation timestamps as values.
+type fakeQuerier struct {
+	mtx      sync.Mutex
+	requests []url.Values
+	// failures is the number of requests failing with a server error before succeeding.
+	failures int
+}
+
+func (q *fakeQuerier) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if r.URL.Path != "/api/v1/query_range" {
+		fmt.Fprintf(w, "proxied %s", r.URL.Path)
+		return
+	}
+	if q.failures > 0 {
+		q.failures--
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	q.requests = append(q.requests, r.URL.Query())
+
+	if r.URL.Query().Get("query") == "invalid" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+		return
+	}
+
+	start, _ := parseTime(r.URL.Query().Get("start"))
+	end, _ := parseTime(r.URL.Query().Get("end"))
+	step, _ := parseDuration(r.URL.Query().Get("step"))
+
+	s := sampleStream{Metric: map[string]string{"__name__": "up"}}
+	if tenant := r.Header.Get("thanos-tenant"); tenant != "" {
+		s.Metric["tenant"] = tenant
+	}
+	for t := start; t <= end; t += int64(step / time.Millisecond) {
+		s.Values = append(s.Values, json.RawMessage(fmt.Sprintf(`[%s,"%d"]`, formatTime(t), t)))
+	}
+	b, _ := json.Marshal(&apiResponse{Status: "success", Data: &apiData{ResultType: "matrix", Result: []sampleStream{s}}})
+	_, _ = w.Write(b)
+}
+
+// ranges returns the time ranges of the received requests, ordered by start.
+func (q *fakeQuerier) ranges() []string {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	var res []string
+	for _, r := range q.requests {
+		res = append(res, r.Get("start")+"-"+r.Get("end"))
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return len(res[i]) < len(res[j]) || len(res[i]) == len(res[j]) && res[i] < res[j]
+	})
+	return res
+}
+
+func queryRange(t *testing.T, h http.Handler, query string, start, end int64, step string) (int, *apiResponse) {
+	return queryRangeAs(t, h, "", query, start, end, step)
+}
+
+// queryRangeAs sends the range query with the given tenant in the default tenant header, if not empty.
+func queryRangeAs(t *testing.T, h http.Handler, tenant, query string, start, end int64, step string) (int, *apiResponse) {
+	v := url.Values{}
+	v.Set("query", query)
+	v.Set("start", strconv.FormatInt(start, 10))
+	v.Set("end", strconv.FormatInt(end, 10))
+	v.Set("step", step)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/query_range?"+v.Encode(), nil)
+	if tenant != "" {
+		req.Header.Set("thanos-tenant", tenant)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp apiResponse
+	testutil.Ok(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, &resp
+}
+
+func newTestFrontend(t *testing.T, q *fakeQuerier, c cache.Cache, conf Config) (*Frontend, func()) {
+	srv := httptest.NewServer(q)
+
+	u, err := url.Parse(srv.URL)
+	testutil.Ok(t, err)
+	return NewFrontend(log.NewNopLogger(), nil, u, c, conf), srv.Close
+}
+
+func TestFrontend_RangeQuery(t *testing.T) {
+	c, err := cache.NewInMemoryCache("test", log.NewNopLogger(), nil, []byte("max_size_bytes: 1000000\nmax_item_size_bytes: 100000"))
+	testutil.Ok(t, err)
+
+	q := &fakeQuerier{}
+	conf := DefaultConfig()
+	conf.SplitInterval = time.Hour
+	f, closeFn := newTestFrontend(t, q, c, conf)
+	defer closeFn()
+
+	// Three hours starting at 00:30, aligned down to the 10 minute step.
+	code, resp := queryRange(t, f, "up", 1805, 1805+3*3600, "600")
+	testutil.Equals(t, http.StatusOK, code)
+	testutil.Equals(t, []string{"1800-3000", "3600-6600", "7200-10200", "10800-12600"}, q.ranges())
+
+	testutil.Equals(t, 1, len(resp.Data.Result))
+	testutil.Equals(t, map[string]string{"__name__": "up"}, resp.Data.Result[0].Metric)
+	// 19 evaluation timestamps from 1800 to 12600, without duplicates.
+	testutil.Equals(t, 19, len(resp.Data.Result[0].Values))
+	testutil.Equals(t, `[1800,"1800000"]`, string(resp.Data.Result[0].Values[0]))
+	testutil.Equals(t, `[12600,"12600000"]`, string(resp.Data.Result[0].Values[18]))
+
+	// All splits ended long ago, so the same query is served from the cache.
+	_, cached := queryRange(t, f, "up", 1800, 1800+3*3600, "600")
+	testutil.Equals(t, 4, len(q.requests))
+	testutil.Equals(t, resp, cached)
+
+	// Splits of a longer query are partially served from the cache.
+	_, _ = queryRange(t, f, "up", 1800, 1800+5*3600, "600")
+	testutil.Equals(t, 7, len(q.requests))
+
+	// Queries which are not range queries are proxied.
+	rec := httptest.NewRecorder()
+	f.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil))
+	b, err := ioutil.ReadAll(rec.Body)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "proxied /api/v1/labels", string(b))
+}
+
+func TestFrontend_RangeQuery_CachedPerTenant(t *testing.T) {
+	c, err := cache.NewInMemoryCache("test", log.NewNopLogger(), nil, []byte("max_size_bytes: 1000000\nmax_item_size_bytes: 100000"))
+	testutil.Ok(t, err)
+
+	q := &fakeQuerier{}
+	f, closeFn := newTestFrontend(t, q, c, DefaultConfig())
+	defer closeFn()
+
+	_, a := queryRangeAs(t, f, "a", "up", 0, 600, "60")
+	testutil.Equals(t, map[string]string{"__name__": "up", "tenant": "a"}, a.Data.Result[0].Metric)
+
+	// The same query of another tenant is not answered with the cached results of the first one.
+	_, b := queryRangeAs(t, f, "b", "up", 0, 600, "60")
+	testutil.Equals(t, map[string]string{"__name__": "up", "tenant": "b"}, b.Data.Result[0].Metric)
+	testutil.Equals(t, 2, len(q.requests))
+
+	_, cached := queryRangeAs(t, f, "a", "up", 0, 600, "60")
+	testutil.Equals(t, a, cached)
+	testutil.Equals(t, 2, len(q.requests))
+}
+
+func TestFrontend_RangeQuery_NotCachedRecent(t *testing.T) {
+	c, err := cache.NewInMemoryCache("test", log.NewNopLogger(), nil, []byte("max_size_bytes: 1000000\nmax_item_size_bytes: 100000"))
+	testutil.Ok(t, err)
+
+	q := &fakeQuerier{}
+	f, closeFn := newTestFrontend(t, q, c, DefaultConfig())
+	defer closeFn()
+
+	now := time.Now().Unix()
+	_, _ = queryRange(t, f, "up", now-60, now, "15")
+	_, _ = queryRange(t, f, "up", now-60, now, "15")
+	testutil.Equals(t, 2, len(q.requests))
+}
+
+func TestFrontend_RangeQuery_Retries(t *testing.T) {
+	q := &fakeQuerier{failures: 2}
+	conf := DefaultConfig()
+	conf.MaxRetries = 2
+	f, closeFn := newTestFrontend(t, q, nil, conf)
+	defer closeFn()
+
+	code, resp := queryRange(t, f, "up", 0, 60, "15")
+	testutil.Equals(t, http.StatusOK, code)
+	testutil.Equals(t, 5, len(resp.Data.Result[0].Values))
+	testutil.Equals(t, 2.0, promtest.ToFloat64(f.retries))
+
+	q.failures = 3
+	code, resp = queryRange(t, f, "up", 0, 60, "15")
+	testutil.Equals(t, http.StatusBadGateway, code)
+	testutil.Equals(t, "error", resp.Status)
+
+	// Client errors are not retried and returned as is.
+	code, resp = queryRange(t, f, "invalid", 0, 60, "15")
+	testutil.Equals(t, http.StatusBadRequest, code)
+	testutil.Equals(t, "parse error", resp.Error)
+	testutil.Equals(t, 4.0, promtest.ToFloat64(f.retries))
+}
@@ -0,0 +1,172 @@
+package queryfrontend
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// rangeRequest is a parsed range query request. Times are in milliseconds.
+type rangeRequest struct {
+	path       string
+	query      string
+	start, end int64
+	step       int64
+	// tenant is the value of the tenant header of the request. Results of different tenants differ if the querier
+	// enforces tenancy, so it's part of the cache key.
+	tenant string
+	// params holds all other parameters of the request, e.g. dedup or partial_response, which are passed through.
+	params url.Values
+}
+
+func parseRangeRequest(path string, form url.Values) (*rangeRequest, error) {
+	r := &rangeRequest{path: path, query: form.Get("query"), params: url.Values{}}
+
+	start, err := parseTime(form.Get("start"))
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTime(form.Get("end"))
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, fmt.Errorf("end timestamp must not be before start time")
+	}
+	step, err := parseDuration(form.Get("step"))
+	if err != nil {
+		return nil, err
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("zero or negative query resolution step widths are not accepted. Try a positive integer")
+	}
+	r.start, r.end, r.step = start, end, int64(step/time.Millisecond)
+	if r.step == 0 {
+		return nil, fmt.Errorf("query resolution step widths below 1ms are not accepted")
+	}
+
+	for k, vs := range form {
+		switch k {
+		case "query", "start", "end", "step":
+			continue
+		}
+		r.params[k] = vs
+	}
+	return r, nil
+}
+
+// values returns the parameters of the request to be sent downstream.
+func (r *rangeRequest) values() url.Values {
+	v := url.Values{}
+	for k, vs := range r.params {
+		v[k] = vs
+	}
+	v.Set("query", r.query)
+	v.Set("start", formatTime(r.start))
+	v.Set("end", formatTime(r.end))
+	v.Set("step", strconv.FormatFloat(float64(r.step)/1000, 'f', -1, 64))
+	return v
+}
+
+// cacheKey returns the key of the results of the request. Requests differing only in their time range share
+// the same prefix.
+func (r *rangeRequest) cacheKey() string {
+	keys := make([]string, 0, len(r.params))
+	for k := range r.params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(r.path)
+	b.WriteByte(0)
+	b.WriteString(r.query)
+	b.WriteByte(0)
+	b.WriteString(r.tenant)
+	for _, k := range keys {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strings.Join(r.params[k], ","))
+	}
+	return fmt.Sprintf("qfe:%x:%d:%d:%d", hash(b.String()), r.step, r.start, r.end)
+}
+
+// alignWithStep moves start and end of the request to multiples of the step, which makes results of queries
+// with slightly different time ranges, e.g. refreshed dashboards, cacheable.
+func (r *rangeRequest) alignWithStep() {
+	r.start = (r.start / r.step) * r.step
+	r.end = (r.end / r.step) * r.step
+}
+
+// split splits the request into requests covering consecutive time ranges that do not cross multiples of the
+// interval. All evaluation timestamps of the request are kept, so the merged results equal those of the request.
+func (r *rangeRequest) split(interval time.Duration) []*rangeRequest {
+	ms := int64(interval / time.Millisecond)
+	if ms <= 0 {
+		return []*rangeRequest{r}
+	}
+
+	var res []*rangeRequest
+	for start := r.start; start <= r.end; {
+		// The last evaluation timestamp before the next multiple of the interval.
+		boundary := (floorDiv(start, ms) + 1) * ms
+		end := start + ((boundary-1-start)/r.step)*r.step
+		if end > r.end {
+			end = r.end
+		}
+		res = append(res, &rangeRequest{
+			path:   r.path,
+			query:  r.query,
+			start:  start,
+			end:    end,
+			step:   r.step,
+			tenant: r.tenant,
+			params: r.params,
+		})
+		start = end + r.step
+	}
+	return res
+}
+
+func floorDiv(a, b int64) int64 {
+	if a < 0 && a%b != 0 {
+		return a/b - 1
+	}
+	return a / b
+}
+
+func parseTime(s string) (int64, error) {
+	if t, err := strconv.ParseFloat(s, 64); err == nil {
+		s, ns := math.Modf(t)
+		return time.Unix(int64(s), int64(ns*float64(time.Second))).UnixNano() / int64(time.Millisecond), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UnixNano() / int64(time.Millisecond), nil
+	}
+	return 0, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+}
+
+func formatTime(ms int64) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', -1, 64)
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if d, err := strconv.ParseFloat(s, 64); err == nil {
+		ts := d * float64(time.Second)
+		if ts > float64(math.MaxInt64) || ts < float64(math.MinInt64) {
+			return 0, fmt.Errorf("cannot parse %q to a valid duration. It overflows int64", s)
+		}
+		return time.Duration(ts), nil
+	}
+	if d, err := model.ParseDuration(s); err == nil {
+		return time.Duration(d), nil
+	}
+	return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+}
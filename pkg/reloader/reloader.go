@@ -10,6 +10,10 @@
 //
 // Once any of those two changes Prometheus on given `reloadURL` will be notified, causing Prometheus to reload configuration and rules.
 //
+// To watch several config files and directories, e.g. mounted from multiple Kubernetes ConfigMaps, use NewWithFiles.
+// Watched directories with an output directory are copied there atomically with environment variables substituted in
+// all files, so Prometheus never sees a partially copied directory tree.
+//
 // This and below for reloader:
 //
 // 	u, _ := url.Parse("http://localhost:9090")
@@ -46,6 +50,7 @@
 //   global:
 //     external_labels:
 //       replica: '$(HOSTNAME)'
+//       cluster: '${CLUSTER}'
 package reloader
 
 import (
@@ -74,18 +79,36 @@ import (
 
 // Reloader can watch config files and trigger reloads of a Prometheus server.
 // It optionally substitutes environment variables in the configuration.
-// Referenced environment variables must be of the form `$(var)` (not `$var` or `${var}`).
+// Referenced environment variables must be of the form `$(var)` or `${var}` (not `$var`). References of the form
+// `${var}` to unset variables are kept as is, as they are also used for regex capture groups in relabel configs.
 type Reloader struct {
 	logger        log.Logger
 	reloadURL     *url.URL
-	cfgFile       string
-	cfgOutputFile string
-	ruleDirs      []string
+	cfgFiles      []ConfigFile
+	dirs          []Dir
 	watchInterval time.Duration
 	retryInterval time.Duration
 
 	lastCfgHash  []byte
 	lastRuleHash []byte
+	// lastCopiedHashes are the hashes of the directories by index when they were last copied to their output.
+	lastCopiedHashes map[int][]byte
+}
+
+// ConfigFile is a config file watched by the reloader.
+type ConfigFile struct {
+	Path string
+	// OutputPath is optional. If set, the config file is decompressed if needed, environment variables are substituted
+	// and the result is written to it.
+	OutputPath string
+}
+
+// Dir is a directory watched by the reloader, e.g. with rule files.
+type Dir struct {
+	Path string
+	// OutputPath is optional. If set, the directory tree is copied to it with environment variables substituted in all
+	// files. The output path is replaced atomically by a symlink to a new copy on every change.
+	OutputPath string
 }
 
 var firstGzipBytes = []byte{0x1f, 0x8b, 0x08}
@@ -96,17 +119,31 @@ var firstGzipBytes = []byte{0x1f, 0x8b, 0x08}
 // will be substituted and the output written into the given path. Prometheus should then use
 // cfgOutputFile as its config file path.
 func New(logger log.Logger, reloadURL *url.URL, cfgFile string, cfgOutputFile string, ruleDirs []string) *Reloader {
+	var cfgFiles []ConfigFile
+	if cfgFile != "" {
+		cfgFiles = append(cfgFiles, ConfigFile{Path: cfgFile, OutputPath: cfgOutputFile})
+	}
+	dirs := make([]Dir, 0, len(ruleDirs))
+	for _, d := range ruleDirs {
+		dirs = append(dirs, Dir{Path: d})
+	}
+	return NewWithFiles(logger, reloadURL, cfgFiles, dirs)
+}
+
+// NewWithFiles creates a new reloader that watches the given config files and directories and triggers a Prometheus
+// reload upon changes.
+func NewWithFiles(logger log.Logger, reloadURL *url.URL, cfgFiles []ConfigFile, dirs []Dir) *Reloader {
 	if logger == nil {
 		logger = log.NewNopLogger()
 	}
 	return &Reloader{
-		logger:        logger,
-		reloadURL:     reloadURL,
-		cfgFile:       cfgFile,
-		cfgOutputFile: cfgOutputFile,
-		ruleDirs:      ruleDirs,
-		watchInterval: 3 * time.Minute,
-		retryInterval: 5 * time.Second,
+		logger:           logger,
+		reloadURL:        reloadURL,
+		cfgFiles:         cfgFiles,
+		dirs:             dirs,
+		watchInterval:    3 * time.Minute,
+		retryInterval:    5 * time.Second,
+		lastCopiedHashes: map[int][]byte{},
 	}
 }
 
@@ -129,22 +166,27 @@ func (r *Reloader) Watch(ctx context.Context) error {
 	defer runutil.CloseWithLogOnErr(r.logger, watcher, "config watcher close")
 
 	watchables := map[string]struct{}{}
-	if r.cfgFile != "" {
-		watchables[filepath.Dir(r.cfgFile)] = struct{}{}
-		if err := watcher.Add(r.cfgFile); err != nil {
-			return errors.Wrapf(err, "add config file %s to watcher", r.cfgFile)
+	for _, cfg := range r.cfgFiles {
+		watchables[filepath.Dir(cfg.Path)] = struct{}{}
+		if err := watcher.Add(cfg.Path); err != nil {
+			return errors.Wrapf(err, "add config file %s to watcher", cfg.Path)
 		}
+	}
 
-		if err := r.apply(ctx); err != nil {
-			return err
+	// Watch rule dirs in best effort manner.
+	var copies bool
+	for _, dir := range r.dirs {
+		watchables[filepath.Dir(dir.Path)] = struct{}{}
+		if err := watcher.Add(dir.Path); err != nil {
+			return errors.Wrapf(err, "add rule dir %s to watcher", dir.Path)
 		}
+		copies = copies || dir.OutputPath != ""
 	}
 
-	// Watch rule dirs in best effort manner.
-	for _, ruleDir := range r.ruleDirs {
-		watchables[filepath.Dir(ruleDir)] = struct{}{}
-		if err := watcher.Add(ruleDir); err != nil {
-			return errors.Wrapf(err, "add rule dir %s to watcher", ruleDir)
+	// Write the outputs Prometheus reads up front.
+	if len(r.cfgFiles) > 0 || copies {
+		if err := r.apply(ctx); err != nil {
+			return err
 		}
 	}
 
@@ -152,10 +194,10 @@ func (r *Reloader) Watch(ctx context.Context) error {
 	defer tick.Stop()
 
 	level.Info(r.logger).Log(
-		"msg", "started watching config file and non-recursively rule dirs for changes",
-		"cfg", r.cfgFile,
-		"out", r.cfgOutputFile,
-		"dirs", strings.Join(r.ruleDirs, ","))
+		"msg", "started watching config files and non-recursively rule dirs for changes",
+		"cfg", strings.Join(r.cfgPaths(), ","),
+		"out", strings.Join(r.cfgOutputPaths(), ","),
+		"dirs", strings.Join(r.dirPaths(), ","))
 
 	for {
 		select {
@@ -179,91 +221,81 @@ func (r *Reloader) Watch(ctx context.Context) error {
 	}
 }
 
-// apply triggers Prometheus reload if rules or config changed. If cfgOutputFile is set, we also
-// expand env vars into config file before reloading.
+func (r *Reloader) cfgPaths() (paths []string) {
+	for _, cfg := range r.cfgFiles {
+		paths = append(paths, cfg.Path)
+	}
+	return paths
+}
+
+func (r *Reloader) cfgOutputPaths() (paths []string) {
+	for _, cfg := range r.cfgFiles {
+		if cfg.OutputPath != "" {
+			paths = append(paths, cfg.OutputPath)
+		}
+	}
+	return paths
+}
+
+func (r *Reloader) dirPaths() (paths []string) {
+	for _, dir := range r.dirs {
+		paths = append(paths, dir.Path)
+	}
+	return paths
+}
+
+// apply triggers Prometheus reload if rules or config changed. If an output file is set for a config file, we also
+// expand env vars into it before reloading. Directories with an output path are copied there if they changed.
 // Reload is retried in retryInterval until watchInterval.
 func (r *Reloader) apply(ctx context.Context) error {
 	var (
 		cfgHash  []byte
 		ruleHash []byte
 	)
-	if r.cfgFile != "" {
+	if len(r.cfgFiles) > 0 {
 		h := sha256.New()
-		if err := hashFile(h, r.cfgFile); err != nil {
-			return errors.Wrap(err, "hash file")
-		}
-		cfgHash = h.Sum(nil)
-		if r.cfgOutputFile != "" {
-			b, err := ioutil.ReadFile(r.cfgFile)
-			if err != nil {
-				return errors.Wrap(err, "read file")
+		for _, cfg := range r.cfgFiles {
+			if err := hashFile(h, cfg.Path); err != nil {
+				return errors.Wrap(err, "hash file")
 			}
-
-			// detect and extract gzipped file
-			if bytes.Equal(b[0:3], firstGzipBytes) {
-				zr, err := gzip.NewReader(bytes.NewReader(b))
+			if cfg.OutputPath == "" {
+				// Prometheus cannot read compressed config files.
+				gz, err := isGzipped(r.logger, cfg.Path)
 				if err != nil {
-					return errors.Wrap(err, "create gzip reader")
+					return errors.Wrap(err, "detect compression")
 				}
-				defer runutil.CloseWithLogOnErr(r.logger, zr, "gzip reader close")
-
-				b, err = ioutil.ReadAll(zr)
-				if err != nil {
-					return errors.Wrap(err, "read compressed config file")
+				if gz {
+					return errors.Errorf("config file %s is gzip compressed, an output file is required to decompress it", cfg.Path)
 				}
+				continue
 			}
-
-			b, err = expandEnv(b)
-			if err != nil {
-				return errors.Wrap(err, "expand environment variables")
-			}
-
-			tmpFile := r.cfgOutputFile + ".tmp"
-			defer func() {
-				_ = os.Remove(tmpFile)
-			}()
-			if err := ioutil.WriteFile(tmpFile, b, 0666); err != nil {
-				return errors.Wrap(err, "write file")
-			}
-			if err := os.Rename(tmpFile, r.cfgOutputFile); err != nil {
-				return errors.Wrap(err, "rename file")
+			if err := writeOutputFile(r.logger, cfg.Path, cfg.OutputPath); err != nil {
+				return err
 			}
 		}
+		cfgHash = h.Sum(nil)
 	}
 
 	h := sha256.New()
-	for _, ruleDir := range r.ruleDirs {
-		walkDir, err := filepath.EvalSymlinks(ruleDir)
-		if err != nil {
-			return errors.Wrap(err, "ruleDir symlink eval")
+	for i, dir := range r.dirs {
+		dh := sha256.New()
+		if err := hashDir(dh, dir.Path); err != nil {
+			return errors.Wrap(err, "build hash")
 		}
-		err = filepath.Walk(walkDir, func(path string, f os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-
-			// filepath.Walk uses Lstat to retriev os.FileInfo. Lstat does not
-			// follow symlinks. Make sure to follow a symlink before checking
-			// if it is a directory.
-			targetFile, err := os.Stat(path)
-			if err != nil {
-				return err
-			}
-
-			if targetFile.IsDir() {
-				return nil
-			}
-
-			if err := hashFile(h, path); err != nil {
-				return err
-			}
-			return nil
-		})
-		if err != nil {
+		dirHash := dh.Sum(nil)
+		if _, err := h.Write(dirHash); err != nil {
 			return errors.Wrap(err, "build hash")
 		}
+
+		if dir.OutputPath == "" || bytes.Equal(r.lastCopiedHashes[i], dirHash) {
+			continue
+		}
+		if err := copyDirAtomically(r.logger, dir.Path, dir.OutputPath); err != nil {
+			return errors.Wrapf(err, "copy dir %s to %s", dir.Path, dir.OutputPath)
+		}
+		r.lastCopiedHashes[i] = dirHash
 	}
-	if len(r.ruleDirs) > 0 {
+	if len(r.dirs) > 0 {
 		ruleHash = h.Sum(nil)
 	}
 
@@ -285,9 +317,9 @@ func (r *Reloader) apply(ctx context.Context) error {
 		r.lastRuleHash = ruleHash
 		level.Info(r.logger).Log(
 			"msg", "Prometheus reload triggered",
-			"cfg_in", r.cfgFile,
-			"cfg_out", r.cfgOutputFile,
-			"rule_dirs", strings.Join(r.ruleDirs, ", "))
+			"cfg_in", strings.Join(r.cfgPaths(), ", "),
+			"cfg_out", strings.Join(r.cfgOutputPaths(), ", "),
+			"rule_dirs", strings.Join(r.dirPaths(), ", "))
 		return nil
 	}); err != nil {
 		level.Error(r.logger).Log("msg", "Failed to trigger reload. Retrying.", "err", err)
@@ -296,6 +328,193 @@ func (r *Reloader) apply(ctx context.Context) error {
 	return nil
 }
 
+// readConfig reads the file, decompresses it if it is gzip compressed, e.g. to fit large configs into the 1MB limit of
+// Kubernetes ConfigMaps, and substitutes environment variables.
+func readConfig(logger log.Logger, fn string) ([]byte, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, errors.Wrap(err, "read file")
+	}
+
+	// detect and extract gzipped file
+	if bytes.HasPrefix(b, firstGzipBytes) {
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, errors.Wrap(err, "create gzip reader")
+		}
+		defer runutil.CloseWithLogOnErr(logger, zr, "gzip reader close")
+
+		b, err = ioutil.ReadAll(zr)
+		if err != nil {
+			return nil, errors.Wrap(err, "read compressed config file")
+		}
+	}
+
+	b, err = expandEnv(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "expand environment variables")
+	}
+	return b, nil
+}
+
+// isGzipped returns true if the file starts with the gzip header.
+func isGzipped(logger log.Logger, fn string) (bool, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return false, err
+	}
+	defer runutil.CloseWithLogOnErr(logger, f, "close config file %s", fn)
+
+	b := make([]byte, len(firstGzipBytes))
+	if _, err := io.ReadFull(f, b); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return false, nil
+		}
+		return false, err
+	}
+	return bytes.Equal(b, firstGzipBytes), nil
+}
+
+// writeOutputFile writes the config file with environment variables substituted to the output file atomically.
+func writeOutputFile(logger log.Logger, fn, outputFn string) error {
+	b, err := readConfig(logger, fn)
+	if err != nil {
+		return err
+	}
+
+	tmpFile := outputFn + ".tmp"
+	defer func() {
+		_ = os.Remove(tmpFile)
+	}()
+	if err := ioutil.WriteFile(tmpFile, b, 0666); err != nil {
+		return errors.Wrap(err, "write file")
+	}
+	if err := os.Rename(tmpFile, outputFn); err != nil {
+		return errors.Wrap(err, "rename file")
+	}
+	return nil
+}
+
+// walkDir walks all files of the directory tree following symlinks.
+func walkDir(dir string, f func(path string, fi os.FileInfo) error) error {
+	walkDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return errors.Wrap(err, "ruleDir symlink eval")
+	}
+	return filepath.Walk(walkDir, func(path string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// filepath.Walk uses Lstat to retriev os.FileInfo. Lstat does not
+		// follow symlinks. Make sure to follow a symlink before checking
+		// if it is a directory.
+		targetFile, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		return f(path, targetFile)
+	})
+}
+
+func hashDir(h hash.Hash, dir string) error {
+	return walkDir(dir, func(path string, fi os.FileInfo) error {
+		if fi.IsDir() {
+			return nil
+		}
+		return hashFile(h, path)
+	})
+}
+
+// copyDirAtomically copies the directory tree of src with environment variables substituted into a new directory next
+// to dst and replaces dst by a symlink to it, which is atomic. The previous copy is removed afterwards. A dst which is
+// not such a symlink is only replaced if it is an empty directory.
+func copyDirAtomically(logger log.Logger, src, dst string) error {
+	var (
+		parent = filepath.Dir(dst)
+		prefix = "." + filepath.Base(dst) + "-"
+		old    string
+	)
+	if fi, err := os.Lstat(dst); err == nil {
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			if old, err = os.Readlink(dst); err != nil {
+				return errors.Wrap(err, "read output symlink")
+			}
+		case fi.IsDir():
+			// The directory must be empty, e.g. the mount point of a volume.
+			if err := os.Remove(dst); err != nil {
+				return errors.Wrap(err, "output exists and is not a symlink managed by the reloader")
+			}
+		default:
+			return errors.New("output exists and is not a symlink managed by the reloader")
+		}
+	} else if !os.IsNotExist(err) {
+		return errors.Wrap(err, "stat output")
+	}
+
+	tmp, err := ioutil.TempDir(parent, prefix)
+	if err != nil {
+		return errors.Wrap(err, "create copy dir")
+	}
+	if err := copyDir(logger, src, tmp); err != nil {
+		if rerr := os.RemoveAll(tmp); rerr != nil {
+			level.Warn(logger).Log("msg", "failed to remove copy dir", "dir", tmp, "err", rerr)
+		}
+		return err
+	}
+
+	link := dst + ".tmp"
+	if err := os.RemoveAll(link); err != nil {
+		return errors.Wrap(err, "remove temporary symlink")
+	}
+	if err := os.Symlink(filepath.Base(tmp), link); err != nil {
+		return errors.Wrap(err, "create temporary symlink")
+	}
+	if err := os.Rename(link, dst); err != nil {
+		return errors.Wrap(err, "replace output symlink")
+	}
+
+	// Only remove previous copies created by the reloader.
+	if old != "" && filepath.Dir(old) == "." && strings.HasPrefix(old, prefix) {
+		if err := os.RemoveAll(filepath.Join(parent, old)); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove previous copy dir", "dir", old, "err", err)
+		}
+	}
+	return nil
+}
+
+func copyDir(logger log.Logger, src, dst string) error {
+	root, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return errors.Wrap(err, "ruleDir symlink eval")
+	}
+	return walkDir(src, func(path string, fi os.FileInfo) error {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		// Skip hidden files and directories, e.g. the ..data directories of Kubernetes ConfigMap volumes, which
+		// would otherwise duplicate all files.
+		if rel != "." && strings.HasPrefix(filepath.Base(rel), ".") {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+
+		if fi.IsDir() {
+			return os.MkdirAll(target, fi.Mode().Perm())
+		}
+		b, err := readConfig(logger, path)
+		if err != nil {
+			return errors.Wrapf(err, "read %s", path)
+		}
+		return ioutil.WriteFile(target, b, fi.Mode().Perm())
+	})
+}
+
 func hashFile(h hash.Hash, fn string) error {
 	f, err := os.Open(fn)
 	if err != nil {
@@ -344,7 +563,10 @@ func ReloadURLFromBase(u *url.URL) *url.URL {
 	return &r
 }
 
-var envRe = regexp.MustCompile(`\$\(([a-zA-Z_0-9]+)\)`)
+var (
+	envRe       = regexp.MustCompile(`\$\(([a-zA-Z_0-9]+)\)`)
+	bracesEnvRe = regexp.MustCompile(`\$\{([a-zA-Z_][a-zA-Z_0-9]*)\}`)
+)
 
 func expandEnv(b []byte) (r []byte, err error) {
 	r = envRe.ReplaceAllFunc(b, func(n []byte) []byte {
@@ -360,5 +582,15 @@ func expandEnv(b []byte) (r []byte, err error) {
 		}
 		return []byte(v)
 	})
-	return r, err
+	if err != nil {
+		return nil, err
+	}
+	r = bracesEnvRe.ReplaceAllFunc(r, func(n []byte) []byte {
+		v, ok := os.LookupEnv(string(n[2 : len(n)-1]))
+		if !ok {
+			return n
+		}
+		return []byte(v)
+	})
+	return r, nil
 }
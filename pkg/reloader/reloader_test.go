@@ -1,11 +1,14 @@
 package reloader
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path"
@@ -267,3 +270,151 @@ func TestReloader_RuleApply(t *testing.T) {
 	testutil.Ok(t, err)
 	testutil.Equals(t, 5, reloads.Load().(int))
 }
+
+func TestReloader_MultipleFilesAndDirsApply(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	var reloads int32
+	srv := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reloads, 1)
+		resp.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	reloadURL, err := url.Parse(srv.URL)
+	testutil.Ok(t, err)
+
+	dir, err := ioutil.TempDir("", "reloader-multi-test")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	for _, d := range []string{"in", "out", "rules-a", "rules-b/sub", "rules-b/..data"} {
+		testutil.Ok(t, os.MkdirAll(path.Join(dir, d), os.ModePerm))
+	}
+	testutil.Ok(t, os.Setenv("TEST_RELOADER_THANOS_MULTI_ENV", "eu-west"))
+
+	testutil.Ok(t, ioutil.WriteFile(path.Join(dir, "in", "cfg.yaml"), []byte("replica: $(TEST_RELOADER_THANOS_MULTI_ENV)\n"), os.ModePerm))
+	testutil.Ok(t, ioutil.WriteFile(path.Join(dir, "in", "scrape.yaml"), []byte("region: ${TEST_RELOADER_THANOS_MULTI_ENV}\nreplacement: ${1}\n"), os.ModePerm))
+	testutil.Ok(t, ioutil.WriteFile(path.Join(dir, "rules-a", "a.yaml"), []byte("a"), os.ModePerm))
+	testutil.Ok(t, ioutil.WriteFile(path.Join(dir, "rules-b", "b.yaml"), []byte("b: ${TEST_RELOADER_THANOS_MULTI_ENV}"), os.ModePerm))
+	testutil.Ok(t, ioutil.WriteFile(path.Join(dir, "rules-b", "sub", "c.yaml"), []byte("c"), os.ModePerm))
+	testutil.Ok(t, ioutil.WriteFile(path.Join(dir, "rules-b", "..data", "b.yaml"), []byte("hidden"), os.ModePerm))
+
+	// Output directory of a volume mount.
+	testutil.Ok(t, os.Mkdir(path.Join(dir, "out", "rules-b"), os.ModePerm))
+
+	reloader := NewWithFiles(nil, reloadURL, []ConfigFile{
+		{Path: path.Join(dir, "in", "cfg.yaml"), OutputPath: path.Join(dir, "out", "cfg.yaml")},
+		{Path: path.Join(dir, "in", "scrape.yaml"), OutputPath: path.Join(dir, "out", "scrape.yaml")},
+	}, []Dir{
+		{Path: path.Join(dir, "rules-a")},
+		{Path: path.Join(dir, "rules-b"), OutputPath: path.Join(dir, "out", "rules-b")},
+	})
+	reloader.retryInterval = 100 * time.Millisecond
+
+	ctx := context.Background()
+	testutil.Ok(t, reloader.apply(ctx))
+	testutil.Equals(t, int32(1), atomic.LoadInt32(&reloads))
+
+	expectFile := func(fn, exp string) {
+		t.Helper()
+		b, err := ioutil.ReadFile(path.Join(dir, "out", fn))
+		testutil.Ok(t, err)
+		testutil.Equals(t, exp, string(b))
+	}
+	expectFile("cfg.yaml", "replica: eu-west\n")
+	// References to unset variables in braces are kept, e.g. regex capture groups.
+	expectFile("scrape.yaml", "region: eu-west\nreplacement: ${1}\n")
+	expectFile("rules-b/b.yaml", "b: eu-west")
+	expectFile("rules-b/sub/c.yaml", "c")
+	_, err = os.Stat(path.Join(dir, "out", "rules-b", "..data"))
+	testutil.Assert(t, os.IsNotExist(err), "hidden directories are not copied")
+
+	// Nothing changed, nothing to do.
+	testutil.Ok(t, reloader.apply(ctx))
+	testutil.Equals(t, int32(1), atomic.LoadInt32(&reloads))
+
+	// The changed directory is copied again and the previous copy is removed.
+	first, err := os.Readlink(path.Join(dir, "out", "rules-b"))
+	testutil.Ok(t, err)
+	testutil.Ok(t, os.Remove(path.Join(dir, "rules-b", "sub", "c.yaml")))
+	testutil.Ok(t, ioutil.WriteFile(path.Join(dir, "rules-b", "d.yaml"), []byte("d"), os.ModePerm))
+
+	testutil.Ok(t, reloader.apply(ctx))
+	testutil.Equals(t, int32(2), atomic.LoadInt32(&reloads))
+	expectFile("rules-b/d.yaml", "d")
+	_, err = os.Stat(path.Join(dir, "out", "rules-b", "sub", "c.yaml"))
+	testutil.Assert(t, os.IsNotExist(err), "removed file still copied")
+	_, err = os.Stat(path.Join(dir, "out", first))
+	testutil.Assert(t, os.IsNotExist(err), "previous copy not removed")
+
+	// A changed directory without output triggers a reload as well.
+	testutil.Ok(t, ioutil.WriteFile(path.Join(dir, "rules-a", "a.yaml"), []byte("a-changed"), os.ModePerm))
+	testutil.Ok(t, reloader.apply(ctx))
+	testutil.Equals(t, int32(3), atomic.LoadInt32(&reloads))
+}
+
+func TestReloader_GzipApply(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	var reloads int32
+	srv := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reloads, 1)
+		resp.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	reloadURL, err := url.Parse(srv.URL)
+	testutil.Ok(t, err)
+
+	dir, err := ioutil.TempDir("", "reloader-gzip-test")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+	testutil.Ok(t, os.Mkdir(path.Join(dir, "rules"), os.ModePerm))
+	testutil.Ok(t, os.Setenv("TEST_RELOADER_THANOS_GZIP_ENV", "1"))
+
+	writeGzipped := func(fn, content string) {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write([]byte(content))
+		testutil.Ok(t, err)
+		testutil.Ok(t, zw.Close())
+		testutil.Ok(t, ioutil.WriteFile(fn, buf.Bytes(), os.ModePerm))
+	}
+	var (
+		input  = path.Join(dir, "cfg.yaml.gz")
+		output = path.Join(dir, "cfg.yaml")
+	)
+	writeGzipped(input, "replica: $(TEST_RELOADER_THANOS_GZIP_ENV)\n")
+	writeGzipped(path.Join(dir, "rules", "rules.yaml.gz"), "rules")
+
+	reloader := NewWithFiles(nil, reloadURL,
+		[]ConfigFile{{Path: input, OutputPath: output}},
+		[]Dir{{Path: path.Join(dir, "rules"), OutputPath: path.Join(dir, "rules-out")}},
+	)
+	reloader.retryInterval = 100 * time.Millisecond
+
+	ctx := context.Background()
+	testutil.Ok(t, reloader.apply(ctx))
+	testutil.Equals(t, int32(1), atomic.LoadInt32(&reloads))
+
+	b, err := ioutil.ReadFile(output)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "replica: 1\n", string(b))
+	b, err = ioutil.ReadFile(path.Join(dir, "rules-out", "rules.yaml.gz"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, "rules", string(b))
+
+	// Changed compressed config is decompressed again.
+	writeGzipped(input, "replica: changed\n")
+	testutil.Ok(t, reloader.apply(ctx))
+	testutil.Equals(t, int32(2), atomic.LoadInt32(&reloads))
+	b, err = ioutil.ReadFile(output)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "replica: changed\n", string(b))
+
+	// Compressed config files cannot be passed to Prometheus as they are.
+	reloader = NewWithFiles(nil, reloadURL, []ConfigFile{{Path: input}}, nil)
+	testutil.NotOk(t, reloader.apply(ctx))
+	testutil.Equals(t, int32(2), atomic.LoadInt32(&reloads))
+}
@@ -0,0 +1,225 @@
+package thanosrule
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/block"
+	"github.com/improbable-eng/thanos/pkg/block/metadata"
+	"github.com/improbable-eng/thanos/pkg/objstore"
+	"github.com/improbable-eng/thanos/pkg/runutil"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/tsdb"
+	tsdblabels "github.com/prometheus/tsdb/labels"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// QueryRangeFunc evaluates the expression at every step between start and end, both inclusive.
+type QueryRangeFunc func(ctx context.Context, expr string, start, end time.Time, step time.Duration, strategy storepb.PartialResponseStrategy) (promql.Matrix, error)
+
+// BackfillOptions configures the backfill of recording rules.
+type BackfillOptions struct {
+	// Start and End are the time range to evaluate the rules for, End is exclusive.
+	Start, End time.Time
+	// DefaultInterval is the evaluation interval of groups without an interval.
+	DefaultInterval time.Duration
+	// BlockDuration is the time range of the written blocks. Blocks are aligned to it like TSDB head blocks.
+	BlockDuration time.Duration
+	// ExternalLabels are the external labels of the written blocks.
+	ExternalLabels labels.Labels
+	// TenantLabel is the name of the tenant label for groups with a tenant, see UpdateOptions.TenantLabel.
+	TenantLabel string
+	// Dir is the directory blocks are written to before they are uploaded.
+	Dir string
+}
+
+// LoadRuleGroups reads the rule groups of the given rule files.
+func LoadRuleGroups(files []string) ([]RuleGroup, error) {
+	var groups []RuleGroup
+	for _, fn := range files {
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return nil, err
+		}
+		var rgs RuleGroups
+		if err := yaml.Unmarshal(b, &rgs); err != nil {
+			return nil, errors.Wrapf(err, "parse rule file %s", fn)
+		}
+		plain := rulefmt.RuleGroups{}
+		for _, rg := range rgs.Groups {
+			plain.Groups = append(plain.Groups, rg.RuleGroup)
+		}
+		if errs := plain.Validate(); len(errs) > 0 {
+			return nil, errors.Wrapf(tsdb.MultiError(errs), "validate rule file %s", fn)
+		}
+		groups = append(groups, rgs.Groups...)
+	}
+	return groups, nil
+}
+
+// Backfill evaluates the recording rules of the groups over the configured time range through the query function and
+// uploads the results as one block per block duration to the bucket. Alerting rules are skipped. The results of
+// recording rules are not visible to other rules, so rules depending on other backfilled rules yield no data.
+// It returns the IDs of the uploaded blocks.
+func Backfill(
+	ctx context.Context,
+	logger log.Logger,
+	bkt objstore.Bucket,
+	query QueryRangeFunc,
+	groups []RuleGroup,
+	opts BackfillOptions,
+) ([]ulid.ULID, error) {
+	if !opts.Start.Before(opts.End) {
+		return nil, errors.Errorf("start %s must be before end %s", opts.Start, opts.End)
+	}
+	if opts.BlockDuration <= 0 || opts.DefaultInterval <= 0 {
+		return nil, errors.New("block duration and default interval must be positive")
+	}
+
+	var (
+		ids       []ulid.ULID
+		blockSize = int64(opts.BlockDuration / time.Millisecond)
+		start     = timestamp.FromTime(opts.Start)
+		end       = timestamp.FromTime(opts.End)
+	)
+	for wstart := start - start%blockSize; wstart < end; wstart += blockSize {
+		mint, maxt := wstart, wstart+blockSize
+		if mint < start {
+			mint = start
+		}
+		if maxt > end {
+			maxt = end
+		}
+
+		id, err := backfillBlock(ctx, logger, query, groups, opts, mint, maxt)
+		if err != nil {
+			return ids, errors.Wrapf(err, "backfill block %s - %s", timestamp.Time(mint), timestamp.Time(maxt))
+		}
+		if id == (ulid.ULID{}) {
+			level.Info(logger).Log("msg", "no rule results, skipping block", "mint", mint, "maxt", maxt)
+			continue
+		}
+
+		bdir := filepath.Join(opts.Dir, id.String())
+		err = block.Upload(ctx, logger, bkt, bdir)
+		if rerr := os.RemoveAll(bdir); rerr != nil {
+			level.Warn(logger).Log("msg", "failed to clean directory", "dir", bdir, "err", rerr)
+		}
+		if err != nil {
+			return ids, errors.Wrapf(err, "upload block %s", id)
+		}
+		level.Info(logger).Log("msg", "uploaded backfilled block", "block", id, "mint", mint, "maxt", maxt)
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// backfillBlock evaluates all recording rules for [mint, maxt) and writes the results into a new block. It returns
+// an empty ID if there were no results.
+func backfillBlock(
+	ctx context.Context,
+	logger log.Logger,
+	query QueryRangeFunc,
+	groups []RuleGroup,
+	opts BackfillOptions,
+	mint, maxt int64,
+) (id ulid.ULID, err error) {
+	// Each rule appends samples over the whole range, so the head must accept samples down to mint after
+	// samples up to maxt were appended.
+	h, err := tsdb.NewHead(nil, logger, nil, 2*(maxt-mint))
+	if err != nil {
+		return id, errors.Wrap(err, "create head")
+	}
+	defer runutil.CloseWithErrCapture(&err, h, "TSDB head")
+
+	for _, tg := range groups {
+		g, err := tg.withTenant(opts.TenantLabel)
+		if err != nil {
+			return id, err
+		}
+		interval := opts.DefaultInterval
+		if g.Interval != 0 {
+			interval = time.Duration(g.Interval)
+		}
+		step := int64(interval / time.Millisecond)
+
+		// Evaluate at multiples of the interval like the rule manager does for aligned groups.
+		first := mint
+		if r := first % step; r != 0 {
+			first += step - r
+		}
+		if first >= maxt {
+			continue
+		}
+
+		strategy := storepb.PartialResponseStrategy_ABORT
+		if tg.PartialResponseStrategy != nil {
+			strategy = *tg.PartialResponseStrategy
+		}
+
+		for _, r := range g.Rules {
+			if r.Record == "" {
+				continue
+			}
+			matrix, err := query(ctx, r.Expr, timestamp.Time(first), timestamp.Time(maxt-1), interval, strategy)
+			if err != nil {
+				return id, errors.Wrapf(err, "query rule %s of group %s", r.Record, g.Name)
+			}
+
+			app := h.Appender()
+			for _, s := range matrix {
+				lb := labels.NewBuilder(s.Metric)
+				lb.Set(labels.MetricName, r.Record)
+				for n, v := range r.Labels {
+					lb.Set(n, v)
+				}
+				lset := tsdblabels.FromMap(lb.Labels().Map())
+
+				for _, p := range s.Points {
+					if p.T < mint || p.T >= maxt {
+						continue
+					}
+					if _, err := app.Add(lset, p.T, p.V); err != nil {
+						_ = app.Rollback()
+						return id, errors.Wrapf(err, "add sample of rule %s of group %s", r.Record, g.Name)
+					}
+				}
+			}
+			if err := app.Commit(); err != nil {
+				return id, errors.Wrap(err, "commit")
+			}
+		}
+	}
+
+	c, err := tsdb.NewLeveledCompactor(ctx, nil, logger, []int64{maxt - mint}, nil)
+	if err != nil {
+		return id, errors.Wrap(err, "create compactor")
+	}
+	id, err = c.Write(opts.Dir, h, mint, maxt, nil)
+	if err != nil {
+		return id, errors.Wrap(err, "write block")
+	}
+	if id == (ulid.ULID{}) {
+		return id, nil
+	}
+
+	if _, err := metadata.InjectThanos(logger, filepath.Join(opts.Dir, id.String()), metadata.Thanos{
+		Labels:     opts.ExternalLabels.Map(),
+		Downsample: metadata.ThanosDownsample{Resolution: 0},
+		Source:     metadata.BucketBackfillSource,
+	}, nil); err != nil {
+		return id, errors.Wrap(err, "inject thanos meta")
+	}
+	return id, nil
+}
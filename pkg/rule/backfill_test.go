@@ -0,0 +1,90 @@
+package thanosrule
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/block"
+	"github.com/improbable-eng/thanos/pkg/block/metadata"
+	"github.com/improbable-eng/thanos/pkg/objstore/inmem"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"github.com/prometheus/prometheus/promql"
+)
+
+func TestBackfill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test_rule_backfill")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	testutil.Ok(t, ioutil.WriteFile(path.Join(dir, "rules.yaml"), []byte(`
+groups:
+- name: "recording"
+  interval: 1m
+  partial_response_strategy: "warn"
+  rules:
+  - record: "job:up:sum"
+    expr: "sum(up) by (job)"
+    labels:
+      team: "a"
+  - alert: "skipped"
+    expr: "up == 0"
+`), os.ModePerm))
+	groups, err := LoadRuleGroups([]string{path.Join(dir, "rules.yaml")})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(groups))
+
+	var queried []string
+	query := func(_ context.Context, expr string, start, end time.Time, step time.Duration, strategy storepb.PartialResponseStrategy) (promql.Matrix, error) {
+		queried = append(queried, expr)
+		testutil.Equals(t, time.Minute, step)
+		testutil.Equals(t, storepb.PartialResponseStrategy_WARN, strategy)
+
+		s := promql.Series{Metric: labels.FromStrings("job", "a")}
+		for ts := start; !ts.After(end); ts = ts.Add(step) {
+			s.Points = append(s.Points, promql.Point{T: timestamp.FromTime(ts), V: 1})
+		}
+		return promql.Matrix{s}, nil
+	}
+
+	bkt := inmem.NewBucket()
+	ids, err := Backfill(context.Background(), log.NewNopLogger(), bkt, query, groups, BackfillOptions{
+		Start:           time.Unix(3600, 0),
+		End:             time.Unix(4*3600, 0),
+		DefaultInterval: 30 * time.Second,
+		BlockDuration:   2 * time.Hour,
+		ExternalLabels:  labels.FromStrings("replica", "backfill"),
+		Dir:             dir,
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(ids))
+	testutil.Equals(t, []string{"sum(up) by (job)", "sum(up) by (job)"}, queried)
+
+	for i, exp := range []struct {
+		mint, maxt int64
+		samples    uint64
+	}{
+		{mint: 3600 * 1000, maxt: 2 * 3600 * 1000, samples: 60},
+		{mint: 2 * 3600 * 1000, maxt: 4 * 3600 * 1000, samples: 120},
+	} {
+		meta, err := block.DownloadMeta(context.Background(), log.NewNopLogger(), bkt, ids[i])
+		testutil.Ok(t, err)
+		testutil.Equals(t, exp.mint, meta.MinTime)
+		testutil.Equals(t, exp.maxt, meta.MaxTime)
+		testutil.Equals(t, uint64(1), meta.Stats.NumSeries)
+		testutil.Equals(t, exp.samples, meta.Stats.NumSamples)
+		testutil.Equals(t, map[string]string{"replica": "backfill"}, meta.Thanos.Labels)
+		testutil.Equals(t, metadata.BucketBackfillSource, meta.Thanos.Source)
+
+		// Blocks are removed locally after the upload.
+		_, err = os.Stat(path.Join(dir, ids[i].String()))
+		testutil.Assert(t, os.IsNotExist(err), "expected block dir to be removed")
+	}
+}
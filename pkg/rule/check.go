@@ -0,0 +1,81 @@
+package thanosrule
+
+import (
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+	"github.com/prometheus/tsdb"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// RuleCounts are the numbers of rules in rule files.
+type RuleCounts struct {
+	Groups    int
+	Recording int
+	Alerting  int
+}
+
+// Add adds the counts of o.
+func (c *RuleCounts) Add(o RuleCounts) {
+	c.Groups += o.Groups
+	c.Recording += o.Recording
+	c.Alerting += o.Alerting
+}
+
+// strictRuleGroups mirrors RuleGroups with all known fields, so unknown fields can be rejected.
+type strictRuleGroups struct {
+	Groups []struct {
+		rulefmt.RuleGroup       `yaml:",inline"`
+		PartialResponseStrategy string `yaml:"partial_response_strategy"`
+		Tenant                  string `yaml:"tenant"`
+		QueryTimeout            string `yaml:"query_timeout"`
+		SampleLimit             int    `yaml:"sample_limit"`
+	} `yaml:"groups"`
+}
+
+// CheckRuleFile validates the rule file like the ruler loads it with the given tenant label name. Unlike the ruler,
+// it rejects unknown fields, which are most likely typos. The rules are validated after the tenant matchers were
+// injected, so the checked expressions are the evaluated ones.
+func CheckRuleFile(fn string, tenantLabel string) (RuleCounts, error) {
+	var counts RuleCounts
+
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return counts, err
+	}
+	if err := yaml.UnmarshalStrict(b, &strictRuleGroups{}); err != nil {
+		return counts, errors.Wrapf(err, "parse rule file %s", fn)
+	}
+	var rgs RuleGroups
+	if err := yaml.Unmarshal(b, &rgs); err != nil {
+		return counts, errors.Wrapf(err, "parse rule file %s", fn)
+	}
+
+	var (
+		errs  tsdb.MultiError
+		plain rulefmt.RuleGroups
+	)
+	for _, rg := range rgs.Groups {
+		g, err := rg.withTenant(tenantLabel)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		plain.Groups = append(plain.Groups, g)
+
+		counts.Groups++
+		for _, r := range g.Rules {
+			if r.Alert != "" {
+				counts.Alerting++
+				continue
+			}
+			counts.Recording++
+		}
+	}
+	errs = append(errs, plain.Validate()...)
+	if err := errs.Err(); err != nil {
+		return RuleCounts{}, errors.Wrapf(err, "validate rule file %s", fn)
+	}
+	return counts, nil
+}
@@ -0,0 +1,122 @@
+package thanosrule
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+)
+
+func TestCheckRuleFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test_rule_check")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	for _, tcase := range []struct {
+		name    string
+		content string
+
+		expCounts RuleCounts
+		expErr    bool
+	}{
+		{
+			name: "valid",
+			content: `
+groups:
+- name: "thanos"
+  partial_response_strategy: "warn"
+  tenant: "team-a"
+  rules:
+  - record: "job:up:sum"
+    expr: "sum(up) by (job)"
+  - alert: "Down"
+    expr: "up == 0"
+    for: 5m
+- name: "default"
+  rules:
+  - alert: "Absent"
+    expr: "absent(up)"
+`,
+			expCounts: RuleCounts{Groups: 2, Recording: 1, Alerting: 2},
+		},
+		{
+			name: "unknown strategy",
+			content: `
+groups:
+- name: "thanos"
+  partial_response_strategy: "ignore"
+  rules:
+  - alert: "Down"
+    expr: "up == 0"
+`,
+			expErr: true,
+		},
+		{
+			name: "unknown field",
+			content: `
+groups:
+- name: "thanos"
+  partial_response_stategy: "warn"
+  rules:
+  - alert: "Down"
+    expr: "up == 0"
+`,
+			expErr: true,
+		},
+		{
+			name: "invalid expression",
+			content: `
+groups:
+- name: "thanos"
+  rules:
+  - record: "job:up:sum"
+    expr: "sum(up) by job"
+`,
+			expErr: true,
+		},
+		{
+			name: "matcher of another tenant",
+			content: `
+groups:
+- name: "thanos"
+  tenant: "team-a"
+  rules:
+  - alert: "Down"
+    expr: "up{tenant=\"team-b\"} == 0"
+`,
+			expErr: true,
+		},
+		{
+			name: "duplicate group",
+			content: `
+groups:
+- name: "thanos"
+  rules:
+  - alert: "Down"
+    expr: "up == 0"
+- name: "thanos"
+  rules:
+  - alert: "Down"
+    expr: "up == 0"
+`,
+			expErr: true,
+		},
+	} {
+		if ok := t.Run(tcase.name, func(t *testing.T) {
+			fn := path.Join(dir, "rules.yaml")
+			testutil.Ok(t, ioutil.WriteFile(fn, []byte(tcase.content), os.ModePerm))
+
+			counts, err := CheckRuleFile(fn, "tenant")
+			if tcase.expErr {
+				testutil.NotOk(t, err)
+				return
+			}
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expCounts, counts)
+		}); !ok {
+			return
+		}
+	}
+}
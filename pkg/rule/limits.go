@@ -0,0 +1,73 @@
+package thanosrule
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+	"github.com/prometheus/prometheus/promql"
+)
+
+// GroupLimits are the limits of the queries evaluating the rules of a group. Zero values mean no limit.
+type GroupLimits struct {
+	// QueryTimeout is the maximum duration of the query of a single rule.
+	QueryTimeout time.Duration
+	// SampleLimit is the maximum number of samples returned by the query of a single rule.
+	SampleLimit int
+}
+
+// merge returns the stricter limits of both.
+func (l GroupLimits) merge(o GroupLimits) GroupLimits {
+	if o.QueryTimeout > 0 && (l.QueryTimeout == 0 || o.QueryTimeout < l.QueryTimeout) {
+		l.QueryTimeout = o.QueryTimeout
+	}
+	if o.SampleLimit > 0 && (l.SampleLimit == 0 || o.SampleLimit < l.SampleLimit) {
+		l.SampleLimit = o.SampleLimit
+	}
+	return l
+}
+
+// Limits holds the limits of the loaded rule groups by rule expression, as the Prometheus rule manager only passes
+// the expression to the query function. If the same expression is used by groups with different limits, the
+// stricter ones apply.
+type Limits struct {
+	mtx    sync.RWMutex
+	byExpr map[string]GroupLimits
+}
+
+// NewLimits returns empty limits.
+func NewLimits() *Limits {
+	return &Limits{byExpr: map[string]GroupLimits{}}
+}
+
+// For returns the limits of the query with the given expression.
+func (l *Limits) For(expr string) GroupLimits {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+
+	return l.byExpr[expr]
+}
+
+func (l *Limits) set(byExpr map[string]GroupLimits) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.byExpr = byExpr
+}
+
+// addGroupLimits adds the limits of the group to the limits by expression. Expressions are keyed in the form the
+// rule manager passes them to the query function.
+func addGroupLimits(byExpr map[string]GroupLimits, g rulefmt.RuleGroup, limits GroupLimits) error {
+	if limits == (GroupLimits{}) {
+		return nil
+	}
+	for _, r := range g.Rules {
+		expr, err := promql.ParseExpr(r.Expr)
+		if err != nil {
+			return errors.Wrapf(err, "parse expression of group %s", g.Name)
+		}
+		byExpr[expr.String()] = byExpr[expr.String()].merge(limits)
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+package thanosrule
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/improbable-eng/thanos/pkg/runutil"
+	"github.com/improbable-eng/thanos/pkg/store/prompb"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/prometheus/prometheus/storage"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const defaultRemoteWriteTimeout = model.Duration(30 * time.Second)
+
+// Requests failing with network or server errors are retried a few times with backoff, bounded so that a
+// failing endpoint does not hold up rule evaluation for long.
+const (
+	remoteWriteMaxRetries = 3
+	remoteWriteMinBackoff = 100 * time.Millisecond
+	remoteWriteMaxBackoff = 1 * time.Second
+)
+
+// RemoteWriteConfig configures a remote write endpoint rule results are written to. It follows the
+// remote_write section of the Prometheus configuration without the queue configuration.
+type RemoteWriteConfig struct {
+	URL                 *config_util.URL  `yaml:"url"`
+	RemoteTimeout       model.Duration    `yaml:"remote_timeout,omitempty"`
+	WriteRelabelConfigs []*relabel.Config `yaml:"write_relabel_configs,omitempty"`
+
+	HTTPClientConfig config_util.HTTPClientConfig `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *RemoteWriteConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = RemoteWriteConfig{RemoteTimeout: defaultRemoteWriteTimeout}
+	type plain RemoteWriteConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.URL == nil {
+		return errors.New("url for remote_write is empty")
+	}
+	for _, rlcfg := range c.WriteRelabelConfigs {
+		if rlcfg == nil {
+			return errors.New("empty or null relabeling rule in remote write config")
+		}
+	}
+	return c.HTTPClientConfig.Validate()
+}
+
+// LoadRemoteWriteConfigs parses the remote write configuration from the given YAML, which holds the
+// endpoints in a remote_write list as in the Prometheus configuration.
+func LoadRemoteWriteConfigs(confYaml []byte) ([]*RemoteWriteConfig, error) {
+	var c struct {
+		RemoteWrite []*RemoteWriteConfig `yaml:"remote_write"`
+	}
+	if err := yaml.UnmarshalStrict(confYaml, &c); err != nil {
+		return nil, errors.Wrap(err, "unmarshal remote write configuration")
+	}
+	return c.RemoteWrite, nil
+}
+
+type remoteWriteClient struct {
+	logger         log.Logger
+	url            string
+	client         *http.Client
+	timeout        time.Duration
+	relabelConfigs []*relabel.Config
+}
+
+// recoverableError is an error of a write request which may succeed when retried.
+type recoverableError struct {
+	error
+}
+
+// storeWithRetries sends the snappy compressed write request to the endpoint and retries it with backoff on
+// recoverable errors.
+func (c *remoteWriteClient) storeWithRetries(req []byte, retries prometheus.Counter) error {
+	backoff := remoteWriteMinBackoff
+	for i := 0; ; i++ {
+		err := c.store(req)
+		if _, ok := err.(recoverableError); !ok || i == remoteWriteMaxRetries {
+			return err
+		}
+		level.Warn(c.logger).Log("msg", "remote write failed; retrying", "remote", c.url, "backoff", backoff, "err", err)
+		retries.Inc()
+
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > remoteWriteMaxBackoff {
+			backoff = remoteWriteMaxBackoff
+		}
+	}
+}
+
+// store sends the snappy compressed write request to the endpoint.
+func (c *remoteWriteClient) store(req []byte) error {
+	httpReq, err := http.NewRequest("POST", c.url, bytes.NewReader(req))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Add("Content-Encoding", "snappy")
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	resp, err := c.client.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		// Errors of the client are network errors, which may be temporary.
+		return recoverableError{err}
+	}
+	defer runutil.CloseWithLogOnErr(c.logger, resp.Body, "remote write response body")
+
+	if resp.StatusCode/100 != 2 {
+		scanner := bufio.NewScanner(io.LimitReader(resp.Body, 256))
+		line := ""
+		if scanner.Scan() {
+			line = scanner.Text()
+		}
+		err = errors.Errorf("server returned HTTP status %s: %s", resp.Status, line)
+	}
+	if resp.StatusCode/100 == 5 {
+		return recoverableError{err}
+	}
+	return err
+}
+
+// RemoteWriteStorage is a storage which does not keep any data locally, but writes all appended samples
+// to remote write endpoints on commit. It allows running rule evaluation without local state.
+type RemoteWriteStorage struct {
+	clients        []*remoteWriteClient
+	externalLabels labels.Labels
+
+	samples       *prometheus.CounterVec
+	failedSamples *prometheus.CounterVec
+	retries       *prometheus.CounterVec
+}
+
+// NewRemoteWriteStorage creates a storage writing to the given remote write endpoints. External labels are
+// added to all written series which do not have them already.
+func NewRemoteWriteStorage(
+	logger log.Logger,
+	reg prometheus.Registerer,
+	confs []*RemoteWriteConfig,
+	externalLabels labels.Labels,
+) (*RemoteWriteStorage, error) {
+	if len(confs) == 0 {
+		return nil, errors.New("no remote write endpoints configured")
+	}
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	s := &RemoteWriteStorage{
+		externalLabels: externalLabels,
+		samples: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_rule_remote_write_samples_total",
+			Help: "Total number of rule result samples sent to remote write endpoints.",
+		}, []string{"remote"}),
+		failedSamples: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_rule_remote_write_failed_samples_total",
+			Help: "Total number of rule result samples which could not be sent to remote write endpoints.",
+		}, []string{"remote"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_rule_remote_write_retries_total",
+			Help: "Total number of retried write requests to remote write endpoints.",
+		}, []string{"remote"}),
+	}
+	for _, conf := range confs {
+		c, err := config_util.NewClientFromConfig(conf.HTTPClientConfig, "remote_write")
+		if err != nil {
+			return nil, errors.Wrapf(err, "create remote write client for %s", conf.URL)
+		}
+		s.clients = append(s.clients, &remoteWriteClient{
+			logger:         logger,
+			url:            conf.URL.String(),
+			client:         c,
+			timeout:        time.Duration(conf.RemoteTimeout),
+			relabelConfigs: conf.WriteRelabelConfigs,
+		})
+		s.samples.WithLabelValues(conf.URL.String())
+		s.failedSamples.WithLabelValues(conf.URL.String())
+		s.retries.WithLabelValues(conf.URL.String())
+	}
+	if reg != nil {
+		reg.MustRegister(s.samples, s.failedSamples, s.retries)
+	}
+	return s, nil
+}
+
+// Querier returns a querier without any data, as nothing is stored locally.
+func (s *RemoteWriteStorage) Querier(context.Context, int64, int64) (storage.Querier, error) {
+	return storage.NoopQuerier(), nil
+}
+
+// StartTime returns the latest possible timestamp, as nothing is stored locally.
+func (s *RemoteWriteStorage) StartTime() (int64, error) {
+	return int64(model.Latest), nil
+}
+
+// Appender returns an appender which buffers samples until they are sent on commit.
+func (s *RemoteWriteStorage) Appender() (storage.Appender, error) {
+	return &remoteWriteAppender{s: s, series: map[string]*prompb.TimeSeries{}, lsets: map[string]labels.Labels{}}, nil
+}
+
+// Close implements storage.Storage.
+func (s *RemoteWriteStorage) Close() error {
+	return nil
+}
+
+type remoteWriteAppender struct {
+	s      *RemoteWriteStorage
+	series map[string]*prompb.TimeSeries
+	lsets  map[string]labels.Labels
+}
+
+func (a *remoteWriteAppender) Add(l labels.Labels, t int64, v float64) (uint64, error) {
+	k := l.String()
+	ts, ok := a.series[k]
+	if !ok {
+		ts = &prompb.TimeSeries{}
+		a.series[k] = ts
+		a.lsets[k] = l
+	}
+	ts.Samples = append(ts.Samples, prompb.Sample{Timestamp: t, Value: v})
+	return 0, nil
+}
+
+func (a *remoteWriteAppender) AddFast(labels.Labels, uint64, int64, float64) error {
+	return storage.ErrNotFound
+}
+
+// Commit sends the buffered samples to all remote write endpoints, retrying network and server errors. All
+// endpoints are tried even if some of them fail, the first error is returned.
+func (a *remoteWriteAppender) Commit() error {
+	defer a.Rollback()
+
+	if len(a.series) == 0 {
+		return nil
+	}
+
+	var firstErr error
+	for _, c := range a.s.clients {
+		req, samples := a.writeRequest(c.relabelConfigs)
+		if samples == 0 {
+			continue
+		}
+		b, err := proto.Marshal(req)
+		if err == nil {
+			err = c.storeWithRetries(snappy.Encode(nil, b), a.s.retries.WithLabelValues(c.url))
+		}
+		if err != nil {
+			a.s.failedSamples.WithLabelValues(c.url).Add(float64(samples))
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "remote write to %s", c.url)
+			}
+			continue
+		}
+		a.s.samples.WithLabelValues(c.url).Add(float64(samples))
+	}
+	return firstErr
+}
+
+func (a *remoteWriteAppender) Rollback() error {
+	a.series = map[string]*prompb.TimeSeries{}
+	a.lsets = map[string]labels.Labels{}
+	return nil
+}
+
+// writeRequest builds the write request of all buffered series after adding external labels and
+// applying the relabel configs of the endpoint. It returns the number of samples in the request.
+func (a *remoteWriteAppender) writeRequest(relabelConfigs []*relabel.Config) (*prompb.WriteRequest, int) {
+	req := &prompb.WriteRequest{Timeseries: make([]prompb.TimeSeries, 0, len(a.series))}
+	samples := 0
+	for k, ts := range a.series {
+		lset := relabel.Process(withExternalLabels(a.lsets[k], a.s.externalLabels), relabelConfigs...)
+		if lset == nil {
+			continue
+		}
+		pts := prompb.TimeSeries{Labels: make([]prompb.Label, 0, len(lset)), Samples: ts.Samples}
+		for _, l := range lset {
+			pts.Labels = append(pts.Labels, prompb.Label{Name: l.Name, Value: l.Value})
+		}
+		req.Timeseries = append(req.Timeseries, pts)
+		samples += len(ts.Samples)
+	}
+	return req, samples
+}
+
+// withExternalLabels returns the label set with all external labels added which are not set already.
+func withExternalLabels(lset, externalLabels labels.Labels) labels.Labels {
+	if len(externalLabels) == 0 {
+		return lset
+	}
+	res := make(labels.Labels, 0, len(lset)+len(externalLabels))
+	res = append(res, lset...)
+	for _, l := range externalLabels {
+		if lset.Get(l.Name) == "" {
+			res = append(res, l)
+		}
+	}
+	sort.Sort(res)
+	return res
+}
@@ -0,0 +1,122 @@
+package thanosrule
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/improbable-eng/thanos/pkg/store/prompb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+func TestRemoteWriteStorage(t *testing.T) {
+	var (
+		reqs   []prompb.WriteRequest
+		status = http.StatusOK
+		// Number of the next requests failing with 503 before status is returned again.
+		failures int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compressed, err := ioutil.ReadAll(r.Body)
+		testutil.Ok(t, err)
+		b, err := snappy.Decode(nil, compressed)
+		testutil.Ok(t, err)
+		var req prompb.WriteRequest
+		testutil.Ok(t, proto.Unmarshal(b, &req))
+		reqs = append(reqs, req)
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	confs, err := LoadRemoteWriteConfigs([]byte(`
+remote_write:
+- url: ` + srv.URL + `
+  write_relabel_configs:
+  - source_labels: [__name__]
+    regex: dropped
+    action: drop
+`))
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(confs))
+	testutil.Equals(t, defaultRemoteWriteTimeout, confs[0].RemoteTimeout)
+
+	s, err := NewRemoteWriteStorage(nil, nil, confs, labels.FromStrings("replica", "a", "job", "ext"))
+	testutil.Ok(t, err)
+
+	app, err := s.Appender()
+	testutil.Ok(t, err)
+	_, err = app.Add(labels.FromStrings("__name__", "job:up:sum", "job", "a"), 1000, 1)
+	testutil.Ok(t, err)
+	_, err = app.Add(labels.FromStrings("__name__", "job:up:sum", "job", "a"), 2000, 2)
+	testutil.Ok(t, err)
+	_, err = app.Add(labels.FromStrings("__name__", "dropped"), 1000, 3)
+	testutil.Ok(t, err)
+	testutil.Ok(t, app.Commit())
+
+	testutil.Equals(t, 1, len(reqs))
+	testutil.Equals(t, []prompb.TimeSeries{{
+		Labels: []prompb.Label{
+			{Name: "__name__", Value: "job:up:sum"},
+			{Name: "job", Value: "a"},
+			{Name: "replica", Value: "a"},
+		},
+		Samples: []prompb.Sample{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: 2}},
+	}}, reqs[0].Timeseries)
+
+	// Nothing is sent for empty commits and samples are not kept after failed ones.
+	testutil.Ok(t, app.Commit())
+	testutil.Equals(t, 1, len(reqs))
+
+	// Server errors are retried until the request succeeds.
+	failures = 2
+	_, err = app.Add(labels.FromStrings("__name__", "job:up:sum", "job", "a"), 3000, 3)
+	testutil.Ok(t, err)
+	testutil.Ok(t, app.Commit())
+	testutil.Equals(t, 4, len(reqs))
+	testutil.Equals(t, []prompb.Sample{{Timestamp: 3000, Value: 3}}, reqs[3].Timeseries[0].Samples)
+
+	// Persistent server errors are retried a limited number of times, client errors are not retried.
+	status = http.StatusInternalServerError
+	_, err = app.Add(labels.FromStrings("__name__", "job:up:sum", "job", "a"), 4000, 4)
+	testutil.Ok(t, err)
+	testutil.NotOk(t, app.Commit())
+	testutil.Equals(t, 4+1+remoteWriteMaxRetries, len(reqs))
+
+	status = http.StatusBadRequest
+	_, err = app.Add(labels.FromStrings("__name__", "job:up:sum", "job", "a"), 5000, 5)
+	testutil.Ok(t, err)
+	testutil.NotOk(t, app.Commit())
+	testutil.Equals(t, 4+1+remoteWriteMaxRetries+1, len(reqs))
+
+	status = http.StatusOK
+	_, err = app.Add(labels.FromStrings("__name__", "job:up:sum", "job", "b"), 6000, 6)
+	testutil.Ok(t, err)
+	testutil.Ok(t, app.Commit())
+	last := reqs[len(reqs)-1]
+	testutil.Equals(t, 1, len(last.Timeseries))
+	testutil.Equals(t, []prompb.Sample{{Timestamp: 6000, Value: 6}}, last.Timeseries[0].Samples)
+
+	// Nothing is stored locally.
+	q, err := s.Querier(context.Background(), 0, 5000)
+	testutil.Ok(t, err)
+	set, _, err := q.Select(nil)
+	testutil.Ok(t, err)
+	testutil.Assert(t, !set.Next(), "expected no series")
+}
+
+func TestWithExternalLabels(t *testing.T) {
+	lset := withExternalLabels(labels.FromStrings("b", "1"), labels.FromStrings("a", "2", "b", "2", "c", "2"))
+	testutil.Assert(t, sort.IsSorted(lset), "labels not sorted")
+	testutil.Equals(t, labels.FromStrings("a", "2", "b", "1", "c", "2"), lset)
+}
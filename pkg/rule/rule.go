@@ -2,15 +2,18 @@ package thanosrule
 
 import (
 	"fmt"
+	"hash/fnv"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/improbable-eng/thanos/pkg/store/storepb"
 	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/rulefmt"
 	"github.com/prometheus/prometheus/rules"
 	"github.com/prometheus/tsdb"
@@ -36,10 +39,44 @@ type RuleGroups struct {
 type RuleGroup struct {
 	rulefmt.RuleGroup
 	PartialResponseStrategy *storepb.PartialResponseStrategy
+	// Tenant limits the rules of the group to the series of the tenant, see UpdateOptions.TenantLabel.
+	Tenant string
+	// Limits are the limits of the queries of the group rules, see UpdateOptions.Limits.
+	Limits GroupLimits
 }
 
 type Managers map[storepb.PartialResponseStrategy]*rules.Manager
 
+// Shard selects the rule groups evaluated by one of Count ruler instances sharing the same rule files. Groups are
+// assigned by the hash of their name, so every group is evaluated by exactly one instance. A Count of 0 or 1 means all
+// groups are evaluated.
+type Shard struct {
+	Index int
+	Count int
+}
+
+// Validate returns an error if the shard index is out of range.
+func (s Shard) Validate() error {
+	if s.Count < 0 {
+		return errors.Errorf("invalid shard count %d", s.Count)
+	}
+	if s.Count > 0 && (s.Index < 0 || s.Index >= s.Count) {
+		return errors.Errorf("shard index %d out of range for %d shards", s.Index, s.Count)
+	}
+	return nil
+}
+
+// Owns returns true if the group with the given name belongs to the shard.
+func (s Shard) Owns(group string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(group))
+	return h.Sum64()%uint64(s.Count) == uint64(s.Index)
+}
+
+// RuleGroups returns the rule groups of all managers sorted by name, file and partial response strategy.
 func (m Managers) RuleGroups() []Group {
 	var res []Group
 	for s, r := range m {
@@ -47,6 +84,15 @@ func (m Managers) RuleGroups() []Group {
 			res = append(res, Group{Group: group, PartialResponseStrategy: s})
 		}
 	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Name() != res[j].Name() {
+			return res[i].Name() < res[j].Name()
+		}
+		if res[i].File() != res[j].File() {
+			return res[i].File() < res[j].File()
+		}
+		return res[i].PartialResponseStrategy < res[j].PartialResponseStrategy
+	})
 	return res
 }
 
@@ -63,6 +109,7 @@ func (m Managers) AlertingRules() []AlertingRule {
 func (r *RuleGroup) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	rs := struct {
 		String string `yaml:"partial_response_strategy"`
+		Tenant string `yaml:"tenant"`
 	}{}
 
 	errMsg := fmt.Sprintf("failed to unmarshal 'partial_response_strategy'. Possible values are %s", strings.Join(storepb.PartialResponseStrategyValues, ","))
@@ -85,9 +132,22 @@ func (r *RuleGroup) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		p = storepb.PartialResponseStrategy_value[storepb.PartialResponseStrategy_ABORT.String()]
 	}
 
+	limits := struct {
+		QueryTimeout model.Duration `yaml:"query_timeout"`
+		SampleLimit  int            `yaml:"sample_limit"`
+	}{}
+	if err := unmarshal(&limits); err != nil {
+		return errors.Wrap(err, "failed to unmarshal 'query_timeout' or 'sample_limit'")
+	}
+	if limits.SampleLimit < 0 {
+		return errors.Errorf("negative sample_limit %d of group %s", limits.SampleLimit, rg.Name)
+	}
+
 	ps := storepb.PartialResponseStrategy(p)
 	r.RuleGroup = rg
 	r.PartialResponseStrategy = &ps
+	r.Tenant = rs.Tenant
+	r.Limits = GroupLimits{QueryTimeout: time.Duration(limits.QueryTimeout), SampleLimit: limits.SampleLimit}
 	return nil
 }
 
@@ -101,19 +161,46 @@ func (r RuleGroup) MarshalYAML() (interface{}, error) {
 	rs := struct {
 		RuleGroup               rulefmt.RuleGroup `yaml:",inline"`
 		PartialResponseStrategy *string           `yaml:"partial_response_strategy,omitempty"`
+		Tenant                  string            `yaml:"tenant,omitempty"`
+		QueryTimeout            model.Duration    `yaml:"query_timeout,omitempty"`
+		SampleLimit             int               `yaml:"sample_limit,omitempty"`
 	}{
 		RuleGroup:               r.RuleGroup,
 		PartialResponseStrategy: ps,
+		Tenant:                  r.Tenant,
+		QueryTimeout:            model.Duration(r.Limits.QueryTimeout),
+		SampleLimit:             r.Limits.SampleLimit,
 	}
 	return rs, nil
 }
 
+// tmpRuleFileName returns the name of the temporary file holding the groups of the given strategy from a rule file. It
+// includes a hash of the full path, so rule files with the same name in different directories do not overwrite each
+// other, and is stable across reloads, so groups keep their state.
+func tmpRuleFileName(fn string, s storepb.PartialResponseStrategy) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(fn))
+	return fmt.Sprintf("%s.%x.%s", filepath.Base(fn), h.Sum32(), s.String())
+}
+
+// UpdateOptions configures how rule groups are loaded by Managers.Update.
+type UpdateOptions struct {
+	// Shard selects the groups to load. Groups not owned by the shard are skipped.
+	Shard Shard
+	// TenantLabel is the name of the label identifying the tenant of a series. The rules of groups with a tenant
+	// only select series with this label set to the tenant and produce series and alerts with it.
+	TenantLabel string
+	// Limits, if set, is updated with the limits of the loaded groups.
+	Limits *Limits
+}
+
 // Update updates rules from given files to all managers we hold. We decide which groups should go where, based on
 // special field in RuleGroup file.
-func (m *Managers) Update(dataDir string, evalInterval time.Duration, files []string) error {
+func (m *Managers) Update(dataDir string, evalInterval time.Duration, files []string, opts UpdateOptions) error {
 	var (
 		errs     tsdb.MultiError
 		filesMap = map[storepb.PartialResponseStrategy][]string{}
+		limits   = map[string]GroupLimits{}
 	)
 
 	if err := os.RemoveAll(path.Join(dataDir, tmpRuleDir)); err != nil {
@@ -140,13 +227,25 @@ func (m *Managers) Update(dataDir string, evalInterval time.Duration, files []st
 		// rules.Manager. The problem is that it uses yaml.UnmarshalStrict for some reasons.
 		mapped := map[storepb.PartialResponseStrategy]*rulefmt.RuleGroups{}
 		for _, rg := range rg.Groups {
+			if !opts.Shard.Owns(rg.Name) {
+				continue
+			}
+			g, err := rg.withTenant(opts.TenantLabel)
+			if err != nil {
+				errs = append(errs, errors.Wrapf(err, "rule file %s", fn))
+				continue
+			}
+			if err := addGroupLimits(limits, g, rg.Limits); err != nil {
+				errs = append(errs, errors.Wrapf(err, "rule file %s", fn))
+				continue
+			}
 			if _, ok := mapped[*rg.PartialResponseStrategy]; !ok {
 				mapped[*rg.PartialResponseStrategy] = &rulefmt.RuleGroups{}
 			}
 
 			mapped[*rg.PartialResponseStrategy].Groups = append(
 				mapped[*rg.PartialResponseStrategy].Groups,
-				rg.RuleGroup,
+				g,
 			)
 		}
 
@@ -157,7 +256,7 @@ func (m *Managers) Update(dataDir string, evalInterval time.Duration, files []st
 				continue
 			}
 
-			newFn := path.Join(dataDir, tmpRuleDir, filepath.Base(fn)+"."+s.String())
+			newFn := path.Join(dataDir, tmpRuleDir, tmpRuleFileName(fn, s))
 			if err := ioutil.WriteFile(newFn, b, os.ModePerm); err != nil {
 				errs = append(errs, err)
 				continue
@@ -168,13 +267,19 @@ func (m *Managers) Update(dataDir string, evalInterval time.Duration, files []st
 
 	}
 
-	for s, fs := range filesMap {
-		updater, ok := (*m)[s]
-		if !ok {
+	if opts.Limits != nil {
+		opts.Limits.set(limits)
+	}
+
+	for s := range filesMap {
+		if _, ok := (*m)[s]; !ok {
 			errs = append(errs, errors.Errorf("no updater found for %v", s))
-			continue
 		}
-		if err := updater.Update(evalInterval, fs); err != nil {
+	}
+	// All managers are updated, so that groups removed from all files of a strategy are stopped as well.
+	// Groups with the same name and file keep their state, e.g. active alerts.
+	for s, updater := range *m {
+		if err := updater.Update(evalInterval, filesMap[s]); err != nil {
 			errs = append(errs, err)
 			continue
 		}
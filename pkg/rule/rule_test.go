@@ -1,6 +1,8 @@
 package thanosrule
 
 import (
+	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
@@ -12,8 +14,11 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/improbable-eng/thanos/pkg/store/storepb"
 	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/pkg/rulefmt"
+	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -99,7 +104,7 @@ groups:
 		path.Join(dir, "wrong.yaml"),
 		path.Join(dir, "combined.yaml"),
 		path.Join(dir, "combined_wrong.yaml"),
-	})
+	}, UpdateOptions{})
 
 	testutil.NotOk(t, err)
 	testutil.Assert(t, strings.HasPrefix(err.Error(), "2 errors: failed to unmarshal 'partial_response_strategy'"), err.Error())
@@ -125,6 +130,108 @@ groups:
 	testutil.Equals(t, "something7", g[3].Name())
 }
 
+func TestUpdate_SameFileNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test_rule_rule_groups_same_names")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	for _, sub := range []string{"a", "b"} {
+		testutil.Ok(t, os.MkdirAll(path.Join(dir, sub), os.ModePerm))
+		testutil.Ok(t, ioutil.WriteFile(path.Join(dir, sub, "rules.yaml"), []byte(`
+groups:
+- name: "something_`+sub+`"
+  rules:
+  - alert: "some"
+    expr: "up"
+`), os.ModePerm))
+	}
+
+	opts := rules.ManagerOptions{
+		Logger: log.NewLogfmtLogger(os.Stderr),
+	}
+	m := Managers{
+		storepb.PartialResponseStrategy_ABORT: rules.NewManager(&opts),
+		storepb.PartialResponseStrategy_WARN:  rules.NewManager(&opts),
+	}
+	testutil.Ok(t, m.Update(dir, 10*time.Second, []string{
+		path.Join(dir, "a", "rules.yaml"),
+		path.Join(dir, "b", "rules.yaml"),
+	}, UpdateOptions{}))
+
+	g := m.RuleGroups()
+	testutil.Equals(t, 2, len(g))
+	testutil.Equals(t, "something_a", g[0].Name())
+	testutil.Equals(t, "something_b", g[1].Name())
+}
+
+func TestUpdate_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test_rule_reload")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	testutil.Ok(t, ioutil.WriteFile(path.Join(dir, "warn.yaml"), []byte(`
+groups:
+- name: "something1"
+  partial_response_strategy: "warn"
+  rules:
+  - alert: "some"
+    expr: "up"
+    for: 1h
+`), os.ModePerm))
+	testutil.Ok(t, ioutil.WriteFile(path.Join(dir, "abort.yaml"), []byte(`
+groups:
+- name: "something2"
+  rules:
+  - alert: "some"
+    expr: "up"
+`), os.ModePerm))
+
+	opts := rules.ManagerOptions{
+		Logger:     log.NewNopLogger(),
+		Context:    context.Background(),
+		Appendable: nopAppendable{},
+		NotifyFunc: func(context.Context, string, ...*rules.Alert) {},
+		QueryFunc: func(context.Context, string, time.Time) (promql.Vector, error) {
+			return promql.Vector{{Metric: labels.FromStrings("job", "a"), Point: promql.Point{V: 1}}}, nil
+		},
+	}
+	m := Managers{
+		storepb.PartialResponseStrategy_ABORT: rules.NewManager(&opts),
+		storepb.PartialResponseStrategy_WARN:  rules.NewManager(&opts),
+	}
+	for _, mgr := range m {
+		// Groups can only be stopped on reload once they run.
+		mgr.Run()
+		defer mgr.Stop()
+	}
+	testutil.Ok(t, m.Update(dir, time.Hour, []string{path.Join(dir, "warn.yaml"), path.Join(dir, "abort.yaml")}, UpdateOptions{}))
+	testutil.Equals(t, 1, len(m[storepb.PartialResponseStrategy_ABORT].RuleGroups()))
+
+	g := m[storepb.PartialResponseStrategy_WARN].RuleGroups()
+	testutil.Equals(t, 1, len(g))
+	g[0].Eval(context.Background(), time.Now())
+	testutil.Equals(t, 1, len(g[0].Rules()[0].(*rules.AlertingRule).ActiveAlerts()))
+
+	// The pending alert is kept for the unchanged group, the group of the removed file is stopped.
+	testutil.Ok(t, m.Update(dir, time.Hour, []string{path.Join(dir, "warn.yaml")}, UpdateOptions{}))
+	testutil.Equals(t, 0, len(m[storepb.PartialResponseStrategy_ABORT].RuleGroups()))
+
+	g = m[storepb.PartialResponseStrategy_WARN].RuleGroups()
+	testutil.Equals(t, 1, len(g))
+	testutil.Equals(t, 1, len(g[0].Rules()[0].(*rules.AlertingRule).ActiveAlerts()))
+}
+
+type nopAppendable struct{}
+
+func (nopAppendable) Appender() (storage.Appender, error) { return nopAppender{}, nil }
+
+type nopAppender struct{}
+
+func (nopAppender) Add(labels.Labels, int64, float64) (uint64, error)   { return 0, nil }
+func (nopAppender) AddFast(labels.Labels, uint64, int64, float64) error { return nil }
+func (nopAppender) Commit() error                                       { return nil }
+func (nopAppender) Rollback() error                                     { return nil }
+
 func TestRuleGroupMarshalYAML(t *testing.T) {
 	const expected = `groups:
 - name: something1
@@ -172,3 +279,130 @@ func TestRuleGroupMarshalYAML(t *testing.T) {
 
 	testutil.Equals(t, expected, string(b))
 }
+
+func TestShard(t *testing.T) {
+	testutil.Ok(t, Shard{}.Validate())
+	testutil.Ok(t, Shard{Index: 2, Count: 3}.Validate())
+	testutil.NotOk(t, Shard{Index: 3, Count: 3}.Validate())
+	testutil.NotOk(t, Shard{Index: -1, Count: 3}.Validate())
+	testutil.NotOk(t, Shard{Count: -1}.Validate())
+
+	// Every group is owned by exactly one shard.
+	shards := []Shard{{Index: 0, Count: 3}, {Index: 1, Count: 3}, {Index: 2, Count: 3}}
+	owned := make([]int, len(shards))
+	for i := 0; i < 100; i++ {
+		group := fmt.Sprintf("group-%d", i)
+		testutil.Assert(t, Shard{}.Owns(group), "expected group to be owned without sharding")
+
+		owners := 0
+		for j, s := range shards {
+			if s.Owns(group) {
+				owners++
+				owned[j]++
+			}
+		}
+		testutil.Equals(t, 1, owners)
+	}
+	for j := range shards {
+		testutil.Assert(t, owned[j] > 0, "expected shard %d to own groups", j)
+	}
+}
+
+func TestRuleGroup_WithTenant(t *testing.T) {
+	var rgs RuleGroups
+	testutil.Ok(t, yaml.Unmarshal([]byte(`
+groups:
+- name: "tenant"
+  tenant: "team-a"
+  rules:
+  - record: "job:up:sum"
+    expr: "sum(up{job=\"a\"}) by (job) / sum(rate(requests_total[5m]))"
+  - alert: "down"
+    expr: "up{tenant=~\"team-.*\"} == 0"
+    labels:
+      severity: "critical"
+- name: "other tenant"
+  tenant: "team-a"
+  rules:
+  - alert: "down"
+    expr: "up{tenant=\"team-b\"} == 0"
+- name: "no tenant"
+  rules:
+  - record: "up:sum"
+    expr: "sum(up)"
+`), &rgs))
+	testutil.Equals(t, 3, len(rgs.Groups))
+
+	g, err := rgs.Groups[0].withTenant("tenant")
+	testutil.Ok(t, err)
+	testutil.Equals(t, `sum by(job) (up{job="a",tenant="team-a"}) / sum(rate(requests_total{tenant="team-a"}[5m]))`, g.Rules[0].Expr)
+	testutil.Equals(t, map[string]string{"tenant": "team-a"}, g.Rules[0].Labels)
+	testutil.Equals(t, `up{tenant="team-a",tenant=~"team-.*"} == 0`, g.Rules[1].Expr)
+	testutil.Equals(t, map[string]string{"tenant": "team-a", "severity": "critical"}, g.Rules[1].Labels)
+	// The original group is not modified.
+	testutil.Equals(t, map[string]string{"severity": "critical"}, rgs.Groups[0].Rules[1].Labels)
+
+	_, err = rgs.Groups[0].withTenant("")
+	testutil.NotOk(t, err)
+
+	_, err = rgs.Groups[1].withTenant("tenant")
+	testutil.NotOk(t, err)
+
+	g, err = rgs.Groups[2].withTenant("tenant")
+	testutil.Ok(t, err)
+	testutil.Equals(t, "sum(up)", g.Rules[0].Expr)
+}
+
+func TestUpdate_Limits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test_rule_rule_groups_limits")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	testutil.Ok(t, ioutil.WriteFile(path.Join(dir, "rules.yaml"), []byte(`
+groups:
+- name: "limited"
+  query_timeout: 30s
+  sample_limit: 1000
+  tenant: "team-a"
+  rules:
+  - record: "job:up:sum"
+    expr: "sum(up) by (job)"
+  - alert: "some"
+    expr: "up == 0"
+- name: "stricter"
+  query_timeout: 10s
+  tenant: "team-a"
+  rules:
+  - alert: "some"
+    expr: "up == 0"
+- name: "unlimited"
+  rules:
+  - alert: "other"
+    expr: "absent(up)"
+`), os.ModePerm))
+
+	opts := rules.ManagerOptions{
+		Logger: log.NewLogfmtLogger(os.Stderr),
+	}
+	m := Managers{
+		storepb.PartialResponseStrategy_ABORT: rules.NewManager(&opts),
+		storepb.PartialResponseStrategy_WARN:  rules.NewManager(&opts),
+	}
+	limits := NewLimits()
+	testutil.Ok(t, m.Update(dir, 10*time.Second, []string{path.Join(dir, "rules.yaml")}, UpdateOptions{TenantLabel: "tenant", Limits: limits}))
+
+	// Limits are looked up by the expressions the rule manager queries, i.e. after the tenant matcher was injected.
+	testutil.Equals(t, GroupLimits{QueryTimeout: 30 * time.Second, SampleLimit: 1000}, limits.For(`sum by(job) (up{tenant="team-a"})`))
+	testutil.Equals(t, GroupLimits{QueryTimeout: 10 * time.Second, SampleLimit: 1000}, limits.For(`up{tenant="team-a"} == 0`))
+	testutil.Equals(t, GroupLimits{}, limits.For(`absent(up)`))
+
+	var rg RuleGroups
+	testutil.NotOk(t, yaml.Unmarshal([]byte(`
+groups:
+- name: "something"
+  sample_limit: -1
+  rules:
+  - alert: "some"
+    expr: "up"
+`), &rg))
+}
@@ -0,0 +1,78 @@
+package thanosrule
+
+import (
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+	"github.com/prometheus/prometheus/promql"
+)
+
+// withTenant returns the rules of the group limited to its tenant: a matcher on the tenant label is added to every
+// selector of the rule expressions and the tenant label is set on all produced series and alerts. Groups without a
+// tenant are returned as they are.
+func (r RuleGroup) withTenant(labelName string) (rulefmt.RuleGroup, error) {
+	if r.Tenant == "" {
+		return r.RuleGroup, nil
+	}
+	if labelName == "" {
+		return rulefmt.RuleGroup{}, errors.Errorf("group %s has tenant %q, but no tenant label name is configured", r.Name, r.Tenant)
+	}
+
+	g := r.RuleGroup
+	g.Rules = make([]rulefmt.Rule, 0, len(r.Rules))
+	for _, rule := range r.Rules {
+		expr, err := promql.ParseExpr(rule.Expr)
+		if err != nil {
+			return rulefmt.RuleGroup{}, errors.Wrapf(err, "parse expression of group %s", r.Name)
+		}
+		if err := injectTenantMatcher(expr, labelName, r.Tenant); err != nil {
+			return rulefmt.RuleGroup{}, errors.Wrapf(err, "group %s", r.Name)
+		}
+		rule.Expr = expr.String()
+
+		if v, ok := rule.Labels[labelName]; ok && v != r.Tenant {
+			return rulefmt.RuleGroup{}, errors.Errorf("rule of group %s sets label %s=%q of another tenant than %q", r.Name, labelName, v, r.Tenant)
+		}
+		lset := make(map[string]string, len(rule.Labels)+1)
+		for n, v := range rule.Labels {
+			lset[n] = v
+		}
+		lset[labelName] = r.Tenant
+		rule.Labels = lset
+
+		g.Rules = append(g.Rules, rule)
+	}
+	return g, nil
+}
+
+// injectTenantMatcher adds a matcher on the tenant label to all selectors of the expression. It returns an error if a
+// selector already has a matcher on the tenant label which does not match the tenant.
+func injectTenantMatcher(expr promql.Expr, labelName, tenant string) (err error) {
+	m, err := labels.NewMatcher(labels.MatchEqual, labelName, tenant)
+	if err != nil {
+		return err
+	}
+	promql.Inspect(expr, func(node promql.Node, _ []promql.Node) error {
+		if err != nil {
+			return err
+		}
+		var ms *[]*labels.Matcher
+		switch n := node.(type) {
+		case *promql.VectorSelector:
+			ms = &n.LabelMatchers
+		case *promql.MatrixSelector:
+			ms = &n.LabelMatchers
+		default:
+			return nil
+		}
+		for _, sm := range *ms {
+			if sm.Name == labelName && !sm.Matches(tenant) {
+				err = errors.Errorf("matcher %s does not match tenant %q", sm, tenant)
+				return err
+			}
+		}
+		*ms = append(*ms, m)
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,100 @@
+package rules
+
+import (
+	"net/url"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/promclient"
+	"github.com/improbable-eng/thanos/pkg/rules/rulespb"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/prometheus/tsdb/labels"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Prometheus implements rulespb.RulesServer on top of the rules API of Prometheus.
+type Prometheus struct {
+	logger         log.Logger
+	base           *url.URL
+	externalLabels func() labels.Labels
+}
+
+// NewPrometheus creates a new rules server querying the Prometheus at the given URL. The external labels are
+// attached to the labels of all rules and alerts, so that the Prometheus evaluating them can be told apart.
+func NewPrometheus(logger log.Logger, base *url.URL, externalLabels func() labels.Labels) *Prometheus {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return &Prometheus{
+		logger:         logger,
+		base:           base,
+		externalLabels: externalLabels,
+	}
+}
+
+// Rules returns the rule groups of Prometheus with rules of the requested type.
+func (p *Prometheus) Rules(r *rulespb.RulesRequest, s rulespb.Rules_RulesServer) error {
+	var ruleType string
+	switch r.Type {
+	case rulespb.RulesRequest_ALERT:
+		ruleType = "alert"
+	case rulespb.RulesRequest_RECORD:
+		ruleType = "record"
+	}
+
+	groups, err := promclient.Rules(s.Context(), p.logger, p.base, ruleType)
+	if err != nil {
+		return status.Error(codes.Unknown, err.Error())
+	}
+
+	ext := p.externalLabels()
+	for _, g := range groups {
+		// Older Prometheus versions ignore the type parameter.
+		g.Rules = filterRules(g.Rules, r.Type)
+		if len(g.Rules) == 0 && r.Type != rulespb.RulesRequest_ALL {
+			continue
+		}
+		extendRuleLabels(g, ext)
+		if err := s.Send(rulespb.NewRuleGroupRulesResponse(g)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterRules returns the rules of the requested type.
+func filterRules(rules []*rulespb.Rule, t rulespb.RulesRequest_Type) []*rulespb.Rule {
+	if t == rulespb.RulesRequest_ALL {
+		return rules
+	}
+	want := rulespb.RuleTypeAlerting
+	if t == rulespb.RulesRequest_RECORD {
+		want = rulespb.RuleTypeRecording
+	}
+	res := rules[:0]
+	for _, r := range rules {
+		if r.Type == want {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// extendRuleLabels attaches the external labels to the labels of all rules and alerts of the group.
+func extendRuleLabels(g *rulespb.RuleGroup, ext labels.Labels) {
+	for _, r := range g.Rules {
+		r.Labels = extendLabels(r.Labels, ext)
+		for _, a := range r.Alerts {
+			a.Labels = extendLabels(a.Labels, ext)
+		}
+	}
+}
+
+// extendLabels returns the given labels with the external labels attached, overwriting existing ones on collision.
+func extendLabels(lset []storepb.Label, ext labels.Labels) []storepb.Label {
+	m := storepb.LabelsToMap(lset)
+	for _, l := range ext {
+		m[l.Name] = l.Value
+	}
+	return storepb.LabelsFromMap(m)
+}
@@ -0,0 +1,54 @@
+package rules
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/federation"
+	"github.com/improbable-eng/thanos/pkg/rules/rulespb"
+)
+
+// Client is a rules API client of a single endpoint.
+type Client struct {
+	rulespb.RulesClient
+
+	// Addr is used for errors and warnings.
+	Addr string
+}
+
+// Proxy implements rulespb.RulesServer by fanning requests out to the rules APIs of all given endpoints.
+type Proxy struct {
+	logger  log.Logger
+	clients func() []Client
+}
+
+// NewProxy returns a new rules proxy over the clients returned by the given function.
+func NewProxy(logger log.Logger, clients func() []Client) *Proxy {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return &Proxy{
+		logger:  logger,
+		clients: clients,
+	}
+}
+
+// Rules returns the rule groups of all endpoints. Endpoints not implementing the rules API are skipped.
+func (p *Proxy) Rules(r *rulespb.RulesRequest, s rulespb.Rules_RulesServer) error {
+	clients := p.clients()
+	streams := make([]federation.Stream, 0, len(clients))
+	for _, c := range clients {
+		c := c
+		streams = append(streams, federation.Stream{Addr: c.Addr, Open: func(ctx context.Context) (func() (interface{}, error), error) {
+			stream, err := c.Rules(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+			return func() (interface{}, error) { return stream.Recv() }, nil
+		}})
+	}
+	return federation.FanOut(s.Context(), p.logger, "rules", r.PartialResponseStrategy, streams,
+		func(resp interface{}) error { return s.Send(resp.(*rulespb.RulesResponse)) },
+		func(err error) interface{} { return rulespb.NewWarningRulesResponse(err) },
+	)
+}
@@ -0,0 +1,112 @@
+package rules
+
+import (
+	thanosrule "github.com/improbable-eng/thanos/pkg/rule"
+	"github.com/improbable-eng/thanos/pkg/rules/rulespb"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/pkg/errors"
+	promlabels "github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/tsdb/labels"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RuleGroupsRetriever gives access to the rule groups evaluated by a ruler, e.g. thanosrule.Managers.
+type RuleGroupsRetriever interface {
+	RuleGroups() []thanosrule.Group
+}
+
+// Ruler implements rulespb.RulesServer on top of the rule groups evaluated by the Thanos ruler.
+type Ruler struct {
+	groups         RuleGroupsRetriever
+	externalLabels labels.Labels
+}
+
+// NewRuler creates a new rules server for the given rule groups. The external labels of the ruler are attached to
+// the labels of all rules and alerts.
+func NewRuler(groups RuleGroupsRetriever, externalLabels labels.Labels) *Ruler {
+	return &Ruler{
+		groups:         groups,
+		externalLabels: externalLabels,
+	}
+}
+
+// Rules returns the rule groups of the ruler with rules of the requested type.
+func (r *Ruler) Rules(req *rulespb.RulesRequest, s rulespb.Rules_RulesServer) error {
+	for _, grp := range r.groups.RuleGroups() {
+		g, err := ruleGroupToProto(grp)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		g.Rules = filterRules(g.Rules, req.Type)
+		if len(g.Rules) == 0 && req.Type != rulespb.RulesRequest_ALL {
+			continue
+		}
+		extendRuleLabels(g, r.externalLabels)
+		if err := s.Send(rulespb.NewRuleGroupRulesResponse(g)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ruleGroupToProto(grp thanosrule.Group) (*rulespb.RuleGroup, error) {
+	g := &rulespb.RuleGroup{
+		Name:                    grp.Name(),
+		File:                    grp.File(),
+		Interval:                grp.Interval().Seconds(),
+		PartialResponseStrategy: grp.PartialResponseStrategy,
+	}
+	for _, r := range grp.Rules() {
+		var lastError string
+		if r.LastError() != nil {
+			lastError = r.LastError().Error()
+		}
+
+		switch rule := r.(type) {
+		case *rules.AlertingRule:
+			pr := &rulespb.Rule{
+				Type:        rulespb.RuleTypeAlerting,
+				Name:        rule.Name(),
+				Query:       rule.Query().String(),
+				Labels:      promLabelsToLabels(rule.Labels()),
+				Health:      string(rule.Health()),
+				LastError:   lastError,
+				Duration:    rule.Duration().Seconds(),
+				Annotations: promLabelsToLabels(rule.Annotations()),
+			}
+			for _, a := range rule.ActiveAlerts() {
+				pr.Alerts = append(pr.Alerts, &rulespb.Alert{
+					Labels:      promLabelsToLabels(a.Labels),
+					Annotations: promLabelsToLabels(a.Annotations),
+					State:       a.State.String(),
+					ActiveAt:    timestamp.FromTime(a.ActiveAt),
+					Value:       a.Value,
+				})
+			}
+			g.Rules = append(g.Rules, pr)
+		case *rules.RecordingRule:
+			g.Rules = append(g.Rules, &rulespb.Rule{
+				Type:      rulespb.RuleTypeRecording,
+				Name:      rule.Name(),
+				Query:     rule.Query().String(),
+				Labels:    promLabelsToLabels(rule.Labels()),
+				Health:    string(rule.Health()),
+				LastError: lastError,
+			})
+		default:
+			return nil, errors.Errorf("failed to assert type of rule %q", rule.Name())
+		}
+	}
+	return g, nil
+}
+
+func promLabelsToLabels(lset promlabels.Labels) []storepb.Label {
+	res := make([]storepb.Label, 0, len(lset))
+	for _, l := range lset {
+		res = append(res, storepb.Label{Name: l.Name, Value: l.Value})
+	}
+	return res
+}
@@ -0,0 +1,167 @@
+// Package rules implements the federation of the rules API of Prometheus and the Thanos ruler.
+package rules
+
+import (
+	"context"
+	"sort"
+
+	"github.com/improbable-eng/thanos/pkg/federation"
+	"github.com/improbable-eng/thanos/pkg/rules/rulespb"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/pkg/errors"
+)
+
+// GRPCClient queries a rules server, e.g. the Proxy, within the same process and deduplicates the rule groups of
+// replicas.
+type GRPCClient struct {
+	proxy         rulespb.RulesServer
+	replicaLabels federation.ReplicaLabels
+}
+
+// NewGRPCClient returns a new GRPCClient merging rule groups of replicas, whose labels only differ in the given replica
+// labels.
+func NewGRPCClient(rs rulespb.RulesServer, replicaLabels []string) *GRPCClient {
+	return &GRPCClient{proxy: rs, replicaLabels: federation.NewReplicaLabels(replicaLabels)}
+}
+
+type rulesServer struct {
+	// This field just exist to pseudo-implement the unused methods of the interface.
+	rulespb.Rules_RulesServer
+	ctx context.Context
+
+	groups   []*rulespb.RuleGroup
+	warnings []error
+}
+
+func (s *rulesServer) Send(r *rulespb.RulesResponse) error {
+	if w := r.GetWarning(); w != "" {
+		s.warnings = append(s.warnings, errors.New(w))
+		return nil
+	}
+	g := r.GetGroup()
+	if g == nil {
+		return errors.New("no group or warning")
+	}
+	s.groups = append(s.groups, g)
+	return nil
+}
+
+func (s *rulesServer) Context() context.Context {
+	return s.ctx
+}
+
+// Rules returns the rule groups of all endpoints. If dedup is true, the replica labels are removed from the rule
+// and alert labels and identical groups evaluated by replicas are returned once, with the alerts of all replicas.
+func (c *GRPCClient) Rules(ctx context.Context, r *rulespb.RulesRequest, dedup bool) ([]*rulespb.RuleGroup, []error, error) {
+	srv := &rulesServer{ctx: ctx}
+	if err := c.proxy.Rules(r, srv); err != nil {
+		return nil, nil, errors.Wrap(err, "proxy Rules()")
+	}
+
+	if !dedup {
+		sortGroups(srv.groups)
+		return srv.groups, srv.warnings, nil
+	}
+	return dedupGroups(srv.groups, c.replicaLabels), srv.warnings, nil
+}
+
+// sortGroups sorts the groups by file, name and partial response strategy, keeping the order of equal groups.
+func sortGroups(groups []*rulespb.RuleGroup) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].File != groups[j].File {
+			return groups[i].File < groups[j].File
+		}
+		if groups[i].Name != groups[j].Name {
+			return groups[i].Name < groups[j].Name
+		}
+		return groups[i].PartialResponseStrategy < groups[j].PartialResponseStrategy
+	})
+}
+
+// dedupGroups removes the replica labels from the rule and alert labels and merges groups with equal rules into
+// the first of them. The result is sorted like sortGroups sorts.
+func dedupGroups(groups []*rulespb.RuleGroup, replicaLabels federation.ReplicaLabels) []*rulespb.RuleGroup {
+	for _, g := range groups {
+		for _, r := range g.Rules {
+			r.Labels = replicaLabels.Remove(r.Labels)
+			for _, a := range r.Alerts {
+				a.Labels = replicaLabels.Remove(a.Labels)
+			}
+		}
+	}
+	sortGroups(groups)
+
+	res := make([]*rulespb.RuleGroup, 0, len(groups))
+	// Groups of the same file and name are adjacent, only those from first to the end of res may be merged.
+	first := 0
+Groups:
+	for _, g := range groups {
+		if len(res) > 0 && !sameGroupKey(res[len(res)-1], g) {
+			first = len(res)
+		}
+		for _, kept := range res[first:] {
+			if sameRules(kept, g) {
+				mergeGroup(kept, g)
+				continue Groups
+			}
+		}
+		res = append(res, g)
+	}
+	return res
+}
+
+func sameGroupKey(a, b *rulespb.RuleGroup) bool {
+	return a.File == b.File && a.Name == b.Name && a.PartialResponseStrategy == b.PartialResponseStrategy
+}
+
+// sameRules returns whether both groups define the same rules, regardless of their evaluation state.
+func sameRules(a, b *rulespb.RuleGroup) bool {
+	if a.Interval != b.Interval || len(a.Rules) != len(b.Rules) {
+		return false
+	}
+	for i := range a.Rules {
+		ra, rb := a.Rules[i], b.Rules[i]
+		if ra.Type != rb.Type || ra.Name != rb.Name || ra.Query != rb.Query || ra.Duration != rb.Duration {
+			return false
+		}
+		if storepb.CompareLabels(ra.Labels, rb.Labels) != 0 || storepb.CompareLabels(ra.Annotations, rb.Annotations) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeGroup merges the evaluation state of the rules of src into dst, which have to define the same rules. A rule
+// is healthy if one of the replicas evaluates it successfully. Alerts with the same labels are kept once, firing
+// alerts win over pending ones and the earliest activation is kept.
+func mergeGroup(dst, src *rulespb.RuleGroup) {
+	for i, r := range dst.Rules {
+		s := src.Rules[i]
+		if r.Health != "ok" && s.Health == "ok" {
+			r.Health, r.LastError = s.Health, s.LastError
+		}
+		r.Alerts = mergeAlerts(r.Alerts, s.Alerts)
+	}
+}
+
+func mergeAlerts(a, b []*rulespb.Alert) []*rulespb.Alert {
+	alerts := append(a, b...)
+	sort.SliceStable(alerts, func(i, j int) bool {
+		if c := storepb.CompareLabels(alerts[i].Labels, alerts[j].Labels); c != 0 {
+			return c < 0
+		}
+		if alerts[i].State != alerts[j].State {
+			return alerts[i].State == "firing"
+		}
+		return alerts[i].ActiveAt < alerts[j].ActiveAt
+	})
+
+	res := make([]*rulespb.Alert, 0, len(alerts))
+	for _, al := range alerts {
+		if len(res) > 0 && storepb.CompareLabels(res[len(res)-1].Labels, al.Labels) == 0 {
+			continue
+		}
+		res = append(res, al)
+	}
+	return res
+}
@@ -0,0 +1,145 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/rules/rulespb"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func lbls(kv ...string) []storepb.Label {
+	var lset []storepb.Label
+	for i := 0; i < len(kv); i += 2 {
+		lset = append(lset, storepb.Label{Name: kv[i], Value: kv[i+1]})
+	}
+	return lset
+}
+
+type testRulesClient struct {
+	resps []*rulespb.RulesResponse
+	err   error
+}
+
+func (c *testRulesClient) Rules(ctx context.Context, r *rulespb.RulesRequest, _ ...grpc.CallOption) (rulespb.Rules_RulesClient, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &testRulesStream{resps: c.resps}, nil
+}
+
+type testRulesStream struct {
+	grpc.ClientStream
+	resps []*rulespb.RulesResponse
+}
+
+func (s *testRulesStream) Recv() (*rulespb.RulesResponse, error) {
+	if len(s.resps) == 0 {
+		return nil, io.EOF
+	}
+	r := s.resps[0]
+	s.resps = s.resps[1:]
+	return r, nil
+}
+
+func TestGRPCClient_Rules(t *testing.T) {
+	replica := func(replica string, query string, alerts ...*rulespb.Alert) []*rulespb.RulesResponse {
+		for _, a := range alerts {
+			a.Labels = append(a.Labels, lbls("prometheus", "eu", "replica", replica)...)
+		}
+		return []*rulespb.RulesResponse{
+			rulespb.NewRuleGroupRulesResponse(&rulespb.RuleGroup{
+				Name:     "node",
+				File:     "/etc/prometheus/node.yaml",
+				Interval: 30,
+				Rules: []*rulespb.Rule{
+					{
+						Type:   rulespb.RuleTypeAlerting,
+						Name:   "NodeDown",
+						Query:  query,
+						Labels: lbls("prometheus", "eu", "replica", replica, "severity", "page"),
+						Health: "ok",
+						Alerts: alerts,
+					},
+				},
+			}),
+		}
+	}
+	proxy := NewProxy(nil, func() []Client {
+		return []Client{
+			{RulesClient: &testRulesClient{resps: replica("1", "up == 0",
+				&rulespb.Alert{Labels: lbls("instance", "a"), State: "pending", ActiveAt: 2000},
+				&rulespb.Alert{Labels: lbls("instance", "b"), State: "firing", ActiveAt: 1000},
+			)}, Addr: "1"},
+			{RulesClient: &testRulesClient{resps: replica("2", "up == 0",
+				&rulespb.Alert{Labels: lbls("instance", "a"), State: "firing", ActiveAt: 3000},
+			)}, Addr: "2"},
+			// Same group with a different query is kept separately.
+			{RulesClient: &testRulesClient{resps: replica("3", "up < 1")}, Addr: "3"},
+			// Stores without rules API are skipped silently.
+			{RulesClient: &testRulesClient{err: status.Error(codes.Unimplemented, "unknown service")}, Addr: "4"},
+		}
+	})
+	c := NewGRPCClient(proxy, []string{"replica"})
+
+	res, warnings, err := c.Rules(context.Background(), &rulespb.RulesRequest{}, true)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(warnings))
+	testutil.Equals(t, 2, len(res))
+
+	var merged *rulespb.Rule
+	for _, g := range res {
+		testutil.Equals(t, lbls("prometheus", "eu", "severity", "page"), g.Rules[0].Labels)
+		if g.Rules[0].Query == "up == 0" {
+			merged = g.Rules[0]
+		}
+	}
+	testutil.Assert(t, merged != nil, "merged group not found")
+	testutil.Equals(t, []*rulespb.Alert{
+		// Firing alerts win over pending ones.
+		{Labels: lbls("instance", "a", "prometheus", "eu"), State: "firing", ActiveAt: 3000},
+		{Labels: lbls("instance", "b", "prometheus", "eu"), State: "firing", ActiveAt: 1000},
+	}, merged.Alerts)
+
+	res, _, err = c.Rules(context.Background(), &rulespb.RulesRequest{}, false)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, len(res))
+}
+
+func TestFilterRules(t *testing.T) {
+	rules := func() []*rulespb.Rule {
+		return []*rulespb.Rule{
+			{Type: rulespb.RuleTypeRecording, Name: "job:up:sum"},
+			{Type: rulespb.RuleTypeAlerting, Name: "NodeDown"},
+		}
+	}
+	testutil.Equals(t, 2, len(filterRules(rules(), rulespb.RulesRequest_ALL)))
+	testutil.Equals(t, "NodeDown", filterRules(rules(), rulespb.RulesRequest_ALERT)[0].Name)
+	testutil.Equals(t, []*rulespb.Rule{{Type: rulespb.RuleTypeRecording, Name: "job:up:sum"}}, filterRules(rules(), rulespb.RulesRequest_RECORD))
+}
+
+func TestRuleGroup_JSON(t *testing.T) {
+	b := []byte(`{"name":"node","file":"/etc/prometheus/node.yaml","rules":[{"name":"NodeDown","query":"up == 0","duration":300,"labels":{"severity":"page"},"annotations":{"summary":"Node down"},"alerts":[{"labels":{"alertname":"NodeDown","instance":"a"},"annotations":{"summary":"Node down"},"state":"firing","activeAt":"2020-09-14T15:22:25.479Z","value":"0e+00"}],"health":"ok","type":"alerting"},{"name":"job:up:sum","query":"sum by(job) (up)","health":"ok","type":"recording"}],"interval":30,"partial_response_strategy":"WARN"}`)
+
+	var g rulespb.RuleGroup
+	testutil.Ok(t, json.Unmarshal(b, &g))
+	testutil.Equals(t, storepb.PartialResponseStrategy_WARN, g.PartialResponseStrategy)
+	testutil.Equals(t, 2, len(g.Rules))
+	testutil.Equals(t, int64(1600096945479), g.Rules[0].Alerts[0].ActiveAt)
+	testutil.Equals(t, rulespb.RuleTypeRecording, g.Rules[1].Type)
+
+	out, err := json.Marshal(&g)
+	testutil.Ok(t, err)
+	testutil.Equals(t, string(b), string(out))
+
+	// Older Prometheus versions return the alert value as number.
+	var a rulespb.Alert
+	testutil.Ok(t, json.Unmarshal([]byte(`{"labels":{},"state":"pending","value":1.5}`), &a))
+	testutil.Equals(t, 1.5, a.Value)
+}
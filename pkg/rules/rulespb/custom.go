@@ -0,0 +1,213 @@
+package rulespb
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+)
+
+const (
+	// RuleTypeAlerting is the type of alerting rules.
+	RuleTypeAlerting = "alerting"
+	// RuleTypeRecording is the type of recording rules.
+	RuleTypeRecording = "recording"
+)
+
+func NewWarningRulesResponse(err error) *RulesResponse {
+	return &RulesResponse{
+		Result: &RulesResponse_Warning{
+			Warning: err.Error(),
+		},
+	}
+}
+
+func NewRuleGroupRulesResponse(group *RuleGroup) *RulesResponse {
+	return &RulesResponse{
+		Result: &RulesResponse_Group{
+			Group: group,
+		},
+	}
+}
+
+// ruleGroupJSON is the JSON format of a rule group of the Prometheus rules API.
+type ruleGroupJSON struct {
+	Name                    string  `json:"name"`
+	File                    string  `json:"file"`
+	Rules                   []*Rule `json:"rules"`
+	Interval                float64 `json:"interval"`
+	PartialResponseStrategy string  `json:"partial_response_strategy,omitempty"`
+}
+
+// MarshalJSON marshals the rule group the way the Prometheus rules API does, with the partial response strategy
+// added like the Thanos ruler does.
+func (m *RuleGroup) MarshalJSON() ([]byte, error) {
+	g := ruleGroupJSON{
+		Name:                    m.Name,
+		File:                    m.File,
+		Rules:                   m.Rules,
+		Interval:                m.Interval,
+		PartialResponseStrategy: m.PartialResponseStrategy.String(),
+	}
+	if g.Rules == nil {
+		g.Rules = []*Rule{}
+	}
+	return json.Marshal(g)
+}
+
+// UnmarshalJSON unmarshals a rule group of the Prometheus rules API. Groups without partial response strategy, as
+// returned by Prometheus, get the strategy of the Thanos ruler, which is abort.
+func (m *RuleGroup) UnmarshalJSON(b []byte) error {
+	var g ruleGroupJSON
+	if err := json.Unmarshal(b, &g); err != nil {
+		return err
+	}
+	strategy := storepb.PartialResponseStrategy_ABORT
+	if g.PartialResponseStrategy != "" {
+		s, ok := storepb.PartialResponseStrategy_value[g.PartialResponseStrategy]
+		if !ok {
+			return errors.Errorf("unknown partial response strategy %q", g.PartialResponseStrategy)
+		}
+		strategy = storepb.PartialResponseStrategy(s)
+	}
+	*m = RuleGroup{
+		Name:                    g.Name,
+		File:                    g.File,
+		Rules:                   g.Rules,
+		Interval:                g.Interval,
+		PartialResponseStrategy: strategy,
+	}
+	return nil
+}
+
+type alertingRuleJSON struct {
+	Name        string            `json:"name"`
+	Query       string            `json:"query"`
+	Duration    float64           `json:"duration"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	Alerts      []*Alert          `json:"alerts"`
+	Health      string            `json:"health"`
+	LastError   string            `json:"lastError,omitempty"`
+	Type        string            `json:"type"`
+}
+
+type recordingRuleJSON struct {
+	Name      string            `json:"name"`
+	Query     string            `json:"query"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Health    string            `json:"health"`
+	LastError string            `json:"lastError,omitempty"`
+	Type      string            `json:"type"`
+}
+
+// MarshalJSON marshals the rule the way the Prometheus rules API does. Alerting rule specific fields are omitted
+// for recording rules.
+func (m *Rule) MarshalJSON() ([]byte, error) {
+	if m.Type == RuleTypeRecording {
+		return json.Marshal(recordingRuleJSON{
+			Name:      m.Name,
+			Query:     m.Query,
+			Labels:    storepb.LabelsToMap(m.Labels),
+			Health:    m.Health,
+			LastError: m.LastError,
+			Type:      m.Type,
+		})
+	}
+	r := alertingRuleJSON{
+		Name:        m.Name,
+		Query:       m.Query,
+		Duration:    m.Duration,
+		Labels:      storepb.LabelsToMap(m.Labels),
+		Annotations: storepb.LabelsToMap(m.Annotations),
+		Alerts:      m.Alerts,
+		Health:      m.Health,
+		LastError:   m.LastError,
+		Type:        m.Type,
+	}
+	if r.Alerts == nil {
+		r.Alerts = []*Alert{}
+	}
+	return json.Marshal(r)
+}
+
+// UnmarshalJSON unmarshals a rule of the Prometheus rules API.
+func (m *Rule) UnmarshalJSON(b []byte) error {
+	var r alertingRuleJSON
+	if err := json.Unmarshal(b, &r); err != nil {
+		return err
+	}
+	if r.Type != RuleTypeAlerting && r.Type != RuleTypeRecording {
+		return errors.Errorf("unknown rule type %q", r.Type)
+	}
+	*m = Rule{
+		Type:        r.Type,
+		Name:        r.Name,
+		Query:       r.Query,
+		Labels:      storepb.LabelsFromMap(r.Labels),
+		Health:      r.Health,
+		LastError:   r.LastError,
+		Duration:    r.Duration,
+		Annotations: storepb.LabelsFromMap(r.Annotations),
+		Alerts:      r.Alerts,
+	}
+	return nil
+}
+
+type alertJSON struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	State       string            `json:"state"`
+	ActiveAt    *time.Time        `json:"activeAt,omitempty"`
+	// Value is a number in older and a string in newer Prometheus versions.
+	Value json.RawMessage `json:"value"`
+}
+
+// MarshalJSON marshals the alert the way the Prometheus rules API does.
+func (m *Alert) MarshalJSON() ([]byte, error) {
+	a := alertJSON{
+		Labels:      storepb.LabelsToMap(m.Labels),
+		Annotations: storepb.LabelsToMap(m.Annotations),
+		State:       m.State,
+		Value:       json.RawMessage(strconv.Quote(strconv.FormatFloat(m.Value, 'e', -1, 64))),
+	}
+	if m.ActiveAt != 0 {
+		t := timestamp.Time(m.ActiveAt).UTC()
+		a.ActiveAt = &t
+	}
+	return json.Marshal(a)
+}
+
+// UnmarshalJSON unmarshals an alert of the Prometheus rules API.
+func (m *Alert) UnmarshalJSON(b []byte) error {
+	var a alertJSON
+	if err := json.Unmarshal(b, &a); err != nil {
+		return err
+	}
+	var value float64
+	if len(a.Value) > 0 {
+		var s string
+		if err := json.Unmarshal(a.Value, &s); err == nil {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return errors.Wrapf(err, "parse alert value %q", s)
+			}
+			value = v
+		} else if err := json.Unmarshal(a.Value, &value); err != nil {
+			return errors.Wrap(err, "unmarshal alert value")
+		}
+	}
+	*m = Alert{
+		Labels:      storepb.LabelsFromMap(a.Labels),
+		Annotations: storepb.LabelsFromMap(a.Annotations),
+		State:       a.State,
+		Value:       value,
+	}
+	if a.ActiveAt != nil {
+		m.ActiveAt = timestamp.FromTime(*a.ActiveAt)
+	}
+	return nil
+}
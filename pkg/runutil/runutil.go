@@ -94,6 +94,29 @@ func RetryWithLog(logger log.Logger, interval time.Duration, stopc <-chan struct
 	}
 }
 
+// RetryWithBackoff executes f until no error is returned from f or stopc is closed. The interval between executions
+// starts at minInterval and doubles after each error up to maxInterval.
+func RetryWithBackoff(minInterval, maxInterval time.Duration, stopc <-chan struct{}, f func() error) error {
+	interval := minInterval
+	for {
+		err := f()
+		if err == nil {
+			return nil
+		}
+
+		t := time.NewTimer(interval)
+		select {
+		case <-stopc:
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		if interval *= 2; interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
 // CloseWithLogOnErr is making sure we log every error, even those from best effort tiny closers.
 func CloseWithLogOnErr(logger log.Logger, closer io.Closer, format string, a ...interface{}) {
 	err := closer.Close()
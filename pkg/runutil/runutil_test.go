@@ -3,6 +3,7 @@ package runutil
 import (
 	"io"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -69,3 +70,32 @@ func TestCloseWithErrCapture(t *testing.T) {
 		}
 	}
 }
+
+func TestRetryWithBackoff(t *testing.T) {
+	var calls []time.Time
+	err := RetryWithBackoff(10*time.Millisecond, 20*time.Millisecond, nil, func() error {
+		calls = append(calls, time.Now())
+		if len(calls) < 4 {
+			return errors.New("test")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(calls) != 4 {
+		t.Fatalf("expected 4 calls, got %d", len(calls))
+	}
+	// The intervals double up to the maximum.
+	for i, min := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 20 * time.Millisecond} {
+		if d := calls[i+1].Sub(calls[i]); d < min {
+			t.Errorf("expected interval %d to be at least %s, got %s", i, min, d)
+		}
+	}
+
+	stopc := make(chan struct{})
+	close(stopc)
+	if err := RetryWithBackoff(time.Hour, time.Hour, stopc, func() error { return errors.New("test") }); err == nil {
+		t.Error("expected error after stop")
+	}
+}
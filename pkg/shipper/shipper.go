@@ -5,12 +5,16 @@ package shipper
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"hash/crc32"
 	"io/ioutil"
 	"math"
 	"os"
 	"path"
 	"path/filepath"
 	"sort"
+	"sync"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -27,11 +31,15 @@ import (
 )
 
 type metrics struct {
-	dirSyncs          prometheus.Counter
-	dirSyncFailures   prometheus.Counter
-	uploads           prometheus.Counter
-	uploadFailures    prometheus.Counter
-	uploadedCompacted prometheus.Gauge
+	dirSyncs            prometheus.Counter
+	dirSyncFailures     prometheus.Counter
+	uploads             prometheus.Counter
+	uploadFailures      prometheus.Counter
+	pendingBlocks       prometheus.Gauge
+	uploadedCompacted   prometheus.Gauge
+	uploadedBytes       prometheus.Counter
+	uploadDuration      prometheus.Histogram
+	metaReconciliations prometheus.Counter
 }
 
 func newMetrics(r prometheus.Registerer, uploadCompacted bool) *metrics {
@@ -53,6 +61,23 @@ func newMetrics(r prometheus.Registerer, uploadCompacted bool) *metrics {
 		Name: "thanos_shipper_upload_failures_total",
 		Help: "Total number of failed object uploads",
 	})
+	m.pendingBlocks = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "thanos_shipper_pending_blocks",
+		Help: "Number of local blocks which are not uploaded yet as of the last sync.",
+	})
+	m.uploadedBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "thanos_shipper_upload_bytes_total",
+		Help: "Total number of bytes of successfully uploaded blocks.",
+	})
+	m.uploadDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "thanos_shipper_upload_duration_seconds",
+		Help:    "Duration of successful block uploads.",
+		Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200},
+	})
+	m.metaReconciliations = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "thanos_shipper_meta_reconciliations_total",
+		Help: "Total number of times the unreadable shipper meta file was reconciled with the bucket.",
+	})
 	m.uploadedCompacted = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "thanos_shipper_upload_compacted_done",
 		Help: "If 1 it means shipper uploaded all compacted blocks from the filesystem.",
@@ -64,6 +89,10 @@ func newMetrics(r prometheus.Registerer, uploadCompacted bool) *metrics {
 			m.dirSyncFailures,
 			m.uploads,
 			m.uploadFailures,
+			m.pendingBlocks,
+			m.uploadedBytes,
+			m.uploadDuration,
+			m.metaReconciliations,
 		)
 		if uploadCompacted {
 			r.MustRegister(m.uploadedCompacted)
@@ -75,25 +104,32 @@ func newMetrics(r prometheus.Registerer, uploadCompacted bool) *metrics {
 // Shipper watches a directory for matching files and directories and uploads
 // them to a remote data store.
 type Shipper struct {
-	logger          log.Logger
-	dir             string
-	workDir         string
-	metrics         *metrics
-	bucket          objstore.Bucket
-	labels          func() labels.Labels
-	source          metadata.SourceType
-	uploadCompacted bool
+	logger            log.Logger
+	dir               string
+	workDir           string
+	metrics           *metrics
+	bucket            objstore.Bucket
+	labels            func() labels.Labels
+	replicaLabels     []string
+	source            metadata.SourceType
+	uploadCompacted   bool
+	uploadConcurrency int
+	pending           int
 }
 
 // New creates a new shipper that detects new TSDB blocks in dir and uploads them
 // to remote if necessary. It attaches the Thanos metadata section in each meta JSON file.
+// The given replica labels are recorded in it as the labels identifying the replica of the source.
+// Up to uploadConcurrency blocks are uploaded at the same time, 0 means one.
 func New(
 	logger log.Logger,
 	r prometheus.Registerer,
 	dir string,
 	bucket objstore.Bucket,
 	lbls func() labels.Labels,
+	replicaLabels []string,
 	source metadata.SourceType,
+	uploadConcurrency int,
 ) *Shipper {
 	if logger == nil {
 		logger = log.NewNopLogger()
@@ -101,14 +137,19 @@ func New(
 	if lbls == nil {
 		lbls = func() labels.Labels { return nil }
 	}
+	if uploadConcurrency <= 0 {
+		uploadConcurrency = 1
+	}
 
 	return &Shipper{
-		logger:  logger,
-		dir:     dir,
-		bucket:  bucket,
-		labels:  lbls,
-		metrics: newMetrics(r, false),
-		source:  source,
+		logger:            logger,
+		dir:               dir,
+		bucket:            bucket,
+		labels:            lbls,
+		replicaLabels:     replicaLabels,
+		metrics:           newMetrics(r, false),
+		source:            source,
+		uploadConcurrency: uploadConcurrency,
 	}
 }
 
@@ -121,7 +162,9 @@ func NewWithCompacted(
 	dir string,
 	bucket objstore.Bucket,
 	lbls func() labels.Labels,
+	replicaLabels []string,
 	source metadata.SourceType,
+	uploadConcurrency int,
 ) *Shipper {
 	if logger == nil {
 		logger = log.NewNopLogger()
@@ -129,15 +172,20 @@ func NewWithCompacted(
 	if lbls == nil {
 		lbls = func() labels.Labels { return nil }
 	}
+	if uploadConcurrency <= 0 {
+		uploadConcurrency = 1
+	}
 
 	return &Shipper{
-		logger:          logger,
-		dir:             dir,
-		bucket:          bucket,
-		labels:          lbls,
-		metrics:         newMetrics(r, true),
-		source:          source,
-		uploadCompacted: true,
+		logger:            logger,
+		dir:               dir,
+		bucket:            bucket,
+		labels:            lbls,
+		replicaLabels:     replicaLabels,
+		metrics:           newMetrics(r, true),
+		source:            source,
+		uploadCompacted:   true,
+		uploadConcurrency: uploadConcurrency,
 	}
 }
 
@@ -225,6 +273,19 @@ func (c *lazyOverlapChecker) sync(ctx context.Context) error {
 	return nil
 }
 
+// add makes the checker aware of a block uploaded after the bucket was synced, so later compacted blocks are checked
+// against it as well. Blocks uploaded before the sync are found in the bucket.
+func (c *lazyOverlapChecker) add(m tsdb.BlockMeta) {
+	if !c.synced {
+		return
+	}
+	if _, ok := c.lookupMetas[m.ULID]; ok {
+		return
+	}
+	c.metas = append(c.metas, m)
+	c.lookupMetas[m.ULID] = struct{}{}
+}
+
 func (c *lazyOverlapChecker) IsOverlapping(ctx context.Context, newMeta tsdb.BlockMeta) error {
 	if !c.synced {
 		level.Info(c.logger).Log("msg", "gathering all existing blocks from the remote bucket for check", "id", newMeta.ULID.String())
@@ -252,15 +313,35 @@ func (c *lazyOverlapChecker) IsOverlapping(ctx context.Context, newMeta tsdb.Blo
 //
 // It is not concurrency-safe, however it is compactor-safe (running concurrently with compactor is ok)
 func (s *Shipper) Sync(ctx context.Context) (uploaded int, err error) {
+	// Blocks known to be uploaded, the others are pending even if the sync fails before it tried to upload them.
+	var done map[ulid.ULID]struct{}
+	defer func() {
+		s.updatePending(done)
+	}()
+
 	meta, err := ReadMetaFile(s.dir)
 	if err != nil {
 		// If we encounter any error, proceed with an empty meta file and overwrite it later.
 		// The meta file is only used to avoid unnecessary bucket.Exists call,
 		// which are properly handled by the system if their occur anyway.
 		if !os.IsNotExist(err) {
-			level.Warn(s.logger).Log("msg", "reading meta file failed, will override it", "err", err)
+			// Blocks uploaded before may have been compacted and deleted from the bucket since, so checking
+			// their existence is not enough to not upload them again. Reconcile the state with the bucket instead.
+			level.Warn(s.logger).Log("msg", "reading meta file failed, reconciling it with the bucket", "err", err)
+
+			s.metrics.metaReconciliations.Inc()
+			sources, err := s.bucketSources(ctx)
+			if err != nil {
+				s.metrics.dirSyncFailures.Inc()
+				return 0, errors.Wrap(err, "reconcile meta file with the bucket")
+			}
+			meta = &Meta{Version: 1}
+			for id := range sources {
+				meta.Uploaded = append(meta.Uploaded, id)
+			}
+		} else {
+			meta = &Meta{Version: 1}
 		}
-		meta = &Meta{Version: 1}
 	}
 
 	// Build a map of blocks we already uploaded.
@@ -268,20 +349,21 @@ func (s *Shipper) Sync(ctx context.Context) (uploaded int, err error) {
 	for _, id := range meta.Uploaded {
 		hasUploaded[id] = struct{}{}
 	}
-
-	// Reset the uploaded slice so we can rebuild it only with blocks that still exist locally.
-	meta.Uploaded = nil
+	done = hasUploaded
 
 	var (
 		checker    = newLazyOverlapChecker(s.logger, s.bucket, s.labels)
 		uploadErrs int
+		// Blocks in the order of the local directory, which are or will be uploaded.
+		blocks  []ulid.ULID
+		pending []*metadata.Meta
 	)
 	// Sync non compacted blocks first.
 	if err := s.iterBlockMetas(func(m *metadata.Meta) error {
 		// Do not sync a block if we already uploaded or ignored it. If it's no longer found in the bucket,
 		// it was generally removed by the compaction process.
 		if _, uploaded := hasUploaded[m.ULID]; uploaded {
-			meta.Uploaded = append(meta.Uploaded, m.ULID)
+			blocks = append(blocks, m.ULID)
 			return nil
 		}
 
@@ -297,6 +379,7 @@ func (s *Shipper) Sync(ctx context.Context) (uploaded int, err error) {
 			return errors.Wrap(err, "check exists")
 		}
 		if ok {
+			blocks = append(blocks, m.ULID)
 			return nil
 		}
 
@@ -312,24 +395,31 @@ func (s *Shipper) Sync(ctx context.Context) (uploaded int, err error) {
 				return nil
 			}
 		}
+		// Later compacted blocks must not overlap with the blocks about to be uploaded.
+		checker.add(m.BlockMeta)
 
-		if err := s.upload(ctx, m); err != nil {
-			level.Error(s.logger).Log("msg", "shipping failed", "block", m.ULID, "err", err)
-			// No error returned, just log line. This is because we want other blocks to be uploaded even
-			// though this one failed. It will be retried on second Sync iteration.
-			uploadErrs++
-			return nil
-		}
-		meta.Uploaded = append(meta.Uploaded, m.ULID)
-
-		uploaded++
-		s.metrics.uploads.Inc()
+		blocks = append(blocks, m.ULID)
+		pending = append(pending, m)
 		return nil
 	}); err != nil {
 		s.metrics.dirSyncFailures.Inc()
 		return uploaded, errors.Wrap(err, "iter local block metas")
 	}
 
+	failed := s.uploadAll(ctx, pending)
+	uploadErrs += len(failed)
+	uploaded = len(pending) - len(failed)
+
+	// Rebuild the uploaded slice only with blocks that still exist locally.
+	meta.Uploaded = nil
+	done = make(map[ulid.ULID]struct{}, len(blocks))
+	for _, id := range blocks {
+		if _, ok := failed[id]; !ok {
+			meta.Uploaded = append(meta.Uploaded, id)
+			done[id] = struct{}{}
+		}
+	}
+
 	if err := WriteMetaFile(s.logger, s.dir, meta); err != nil {
 		level.Warn(s.logger).Log("msg", "updating meta file failed", "err", err)
 	}
@@ -345,8 +435,156 @@ func (s *Shipper) Sync(ctx context.Context) (uploaded int, err error) {
 	return uploaded, nil
 }
 
-// sync uploads the block if not exists in remote storage.
-func (s *Shipper) upload(ctx context.Context, meta *metadata.Meta) error {
+// updatePending counts the local blocks which should be uploaded but are not in uploaded.
+func (s *Shipper) updatePending(uploaded map[ulid.ULID]struct{}) {
+	pending := 0
+	if err := s.iterBlockMetas(func(m *metadata.Meta) error {
+		if _, ok := uploaded[m.ULID]; ok {
+			return nil
+		}
+		if !s.uploadable(m) {
+			return nil
+		}
+		pending++
+		return nil
+	}); err != nil {
+		level.Warn(s.logger).Log("msg", "counting pending blocks failed", "err", err)
+		return
+	}
+	s.pending = pending
+	s.metrics.pendingBlocks.Set(float64(pending))
+}
+
+// Pending returns the number of local blocks which were not uploaded yet as of the last Sync.
+// Like Sync, it is not concurrency-safe.
+func (s *Shipper) Pending() int {
+	return s.pending
+}
+
+// uploadable returns true if the block with the given meta is uploaded by Sync.
+func (s *Shipper) uploadable(m *metadata.Meta) bool {
+	return m.Stats.NumSamples > 0 && (m.Compaction.Level <= 1 || s.uploadCompacted)
+}
+
+// MarkDeletable marks the local blocks beyond the given retention as deletable, if the meta file records them as
+// uploaded or they are never uploaded. Like the retention of the TSDB, it is relative to the end of the newest local
+// block. The TSDB deletes marked blocks when it reloads its blocks next, i.e. when it persists its head or is opened,
+// so a TSDB opened without retention never deletes blocks which were not uploaded yet, whatever their age.
+// Like Sync, it is not concurrency-safe.
+func (s *Shipper) MarkDeletable(retention time.Duration) (marked int, err error) {
+	meta, err := ReadMetaFile(s.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, errors.Wrap(err, "read shipper meta file")
+	}
+	uploaded := map[ulid.ULID]struct{}{}
+	if meta != nil {
+		for _, id := range meta.Uploaded {
+			uploaded[id] = struct{}{}
+		}
+	}
+
+	var (
+		metas []*metadata.Meta
+		maxt  int64 = math.MinInt64
+	)
+	if err := s.iterBlockMetas(func(m *metadata.Meta) error {
+		metas = append(metas, m)
+		if m.MaxTime > maxt {
+			maxt = m.MaxTime
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	for _, m := range metas {
+		if m.Compaction.Deletable || maxt-m.MaxTime <= int64(retention/time.Millisecond) {
+			continue
+		}
+		if _, ok := uploaded[m.ULID]; !ok && s.uploadable(m) {
+			continue
+		}
+		m.Compaction.Deletable = true
+		if err := metadata.Write(s.logger, filepath.Join(s.dir, m.ULID.String()), m); err != nil {
+			return marked, errors.Wrapf(err, "mark block %s deletable", m.ULID)
+		}
+		marked++
+	}
+	return marked, nil
+}
+
+// bucketSources returns the IDs of all blocks in the bucket with the external labels of the shipper, together with
+// the IDs of the blocks they were compacted from.
+func (s *Shipper) bucketSources(ctx context.Context) (map[ulid.ULID]struct{}, error) {
+	sources := map[ulid.ULID]struct{}{}
+	if err := s.bucket.Iter(ctx, "", func(path string) error {
+		id, ok := block.IsBlockDir(path)
+		if !ok {
+			return nil
+		}
+
+		m, err := block.DownloadMeta(ctx, s.logger, s.bucket, id)
+		if err != nil {
+			return err
+		}
+		if !labels.FromMap(m.Thanos.Labels).Equals(s.labels()) {
+			return nil
+		}
+
+		sources[m.ULID] = struct{}{}
+		for _, src := range m.Compaction.Sources {
+			sources[src] = struct{}{}
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "get all block meta")
+	}
+	return sources, nil
+}
+
+// uploadAll uploads the blocks with up to the configured upload concurrency. It returns the IDs of the blocks
+// which failed to upload.
+func (s *Shipper) uploadAll(ctx context.Context, metas []*metadata.Meta) map[ulid.ULID]struct{} {
+	var (
+		wg     sync.WaitGroup
+		mtx    sync.Mutex
+		failed = map[ulid.ULID]struct{}{}
+		ch     = make(chan *metadata.Meta)
+	)
+	for i := 0; i < s.uploadConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for m := range ch {
+				begin := time.Now()
+				size, err := s.upload(ctx, m)
+				if err != nil {
+					level.Error(s.logger).Log("msg", "shipping failed", "block", m.ULID, "err", err)
+					// No error returned, just log line. This is because we want other blocks to be uploaded even
+					// though this one failed. It will be retried on second Sync iteration.
+					mtx.Lock()
+					failed[m.ULID] = struct{}{}
+					mtx.Unlock()
+					continue
+				}
+				s.metrics.uploads.Inc()
+				s.metrics.uploadedBytes.Add(float64(size))
+				s.metrics.uploadDuration.Observe(time.Since(begin).Seconds())
+			}
+		}()
+	}
+	for _, m := range metas {
+		ch <- m
+	}
+	close(ch)
+	wg.Wait()
+
+	return failed
+}
+
+// upload uploads the block to the remote storage and returns the number of uploaded bytes.
+func (s *Shipper) upload(ctx context.Context, meta *metadata.Meta) (int64, error) {
 	level.Info(s.logger).Log("msg", "upload new block", "id", meta.ULID)
 
 	// We hard-link the files into a temporary upload directory so we are not affected
@@ -355,10 +593,10 @@ func (s *Shipper) upload(ctx context.Context, meta *metadata.Meta) error {
 
 	// Remove updir just in case.
 	if err := os.RemoveAll(updir); err != nil {
-		return errors.Wrap(err, "clean upload directory")
+		return 0, errors.Wrap(err, "clean upload directory")
 	}
 	if err := os.MkdirAll(updir, 0777); err != nil {
-		return errors.Wrap(err, "create upload dir")
+		return 0, errors.Wrap(err, "create upload dir")
 	}
 	defer func() {
 		if err := os.RemoveAll(updir); err != nil {
@@ -368,17 +606,22 @@ func (s *Shipper) upload(ctx context.Context, meta *metadata.Meta) error {
 
 	dir := filepath.Join(s.dir, meta.ULID.String())
 	if err := hardlinkBlock(dir, updir); err != nil {
-		return errors.Wrap(err, "hard link block")
+		return 0, errors.Wrap(err, "hard link block")
 	}
 	// Attach current labels and write a new meta file with Thanos extensions.
 	if lset := s.labels(); lset != nil {
 		meta.Thanos.Labels = lset.Map()
 	}
+	meta.Thanos.ReplicaLabels = s.replicaLabels
 	meta.Thanos.Source = s.source
 	if err := metadata.Write(s.logger, updir, meta); err != nil {
-		return errors.Wrap(err, "write meta file")
+		return 0, errors.Wrap(err, "write meta file")
+	}
+	size, err := dirSize(updir)
+	if err != nil {
+		return 0, errors.Wrap(err, "get block size")
 	}
-	return block.Upload(ctx, s.logger, s.bucket, updir)
+	return size, block.Upload(ctx, s.logger, s.bucket, updir)
 }
 
 // iterBlockMetas calls f with the block meta for each block found in dir. It logs
@@ -415,6 +658,20 @@ func (s *Shipper) iterBlockMetas(f func(m *metadata.Meta) error) error {
 	return nil
 }
 
+// dirSize returns the total size of the files in the directory.
+func dirSize(dir string) (size int64, err error) {
+	err = filepath.Walk(dir, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
 func hardlinkBlock(src, dst string) error {
 	chunkDir := filepath.Join(dst, block.ChunksDirname)
 
@@ -448,8 +705,29 @@ type Meta struct {
 // MetaFilename is the known JSON filename for meta information.
 const MetaFilename = "thanos.shipper.json"
 
+// metaFile is the on-disk format of Meta. The checksum detects files that were corrupted, e.g. by a crash.
+type metaFile struct {
+	Meta
+	Checksum string `json:"checksum,omitempty"`
+}
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+func metaChecksum(meta Meta) (string, error) {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%08x", crc32.Checksum(b, castagnoliTable)), nil
+}
+
 // WriteMetaFile writes the given meta into <dir>/thanos.shipper.json.
 func WriteMetaFile(logger log.Logger, dir string, meta *Meta) error {
+	checksum, err := metaChecksum(*meta)
+	if err != nil {
+		return errors.Wrap(err, "checksum meta")
+	}
+
 	// Make any changes to the file appear atomic.
 	path := filepath.Join(dir, MetaFilename)
 	tmp := path + ".tmp"
@@ -462,7 +740,12 @@ func WriteMetaFile(logger log.Logger, dir string, meta *Meta) error {
 	enc := json.NewEncoder(f)
 	enc.SetIndent("", "\t")
 
-	if err := enc.Encode(meta); err != nil {
+	if err := enc.Encode(metaFile{Meta: *meta, Checksum: checksum}); err != nil {
+		runutil.CloseWithLogOnErr(logger, f, "write meta file close")
+		return err
+	}
+	// Persist the content before the rename, otherwise a crash can leave an empty file behind.
+	if err := f.Sync(); err != nil {
 		runutil.CloseWithLogOnErr(logger, f, "write meta file close")
 		return err
 	}
@@ -472,13 +755,14 @@ func WriteMetaFile(logger log.Logger, dir string, meta *Meta) error {
 	return renameFile(logger, tmp, path)
 }
 
-// ReadMetaFile reads the given meta from <dir>/thanos.shipper.json.
+// ReadMetaFile reads the given meta from <dir>/thanos.shipper.json. It returns an error if the checksum of the file
+// does not match its content. Files without checksum written by older versions are accepted.
 func ReadMetaFile(dir string) (*Meta, error) {
 	b, err := ioutil.ReadFile(filepath.Join(dir, MetaFilename))
 	if err != nil {
 		return nil, err
 	}
-	var m Meta
+	var m metaFile
 
 	if err := json.Unmarshal(b, &m); err != nil {
 		return nil, err
@@ -486,14 +770,21 @@ func ReadMetaFile(dir string) (*Meta, error) {
 	if m.Version != 1 {
 		return nil, errors.Errorf("unexpected meta file version %d", m.Version)
 	}
+	if m.Checksum != "" {
+		checksum, err := metaChecksum(m.Meta)
+		if err != nil {
+			return nil, errors.Wrap(err, "checksum meta")
+		}
+		if checksum != m.Checksum {
+			return nil, errors.Errorf("meta file checksum mismatch: expected %s, got %s", m.Checksum, checksum)
+		}
+	}
 
-	return &m, nil
+	return &m.Meta, nil
 }
 
+// renameFile replaces the file atomically, the old file is only gone once the new one is in place.
 func renameFile(logger log.Logger, from, to string) error {
-	if err := os.RemoveAll(to); err != nil {
-		return err
-	}
 	if err := os.Rename(from, to); err != nil {
 		return err
 	}
@@ -1,17 +1,27 @@
 package shipper
 
 import (
+	"bytes"
+	"context"
+	"io"
 	"io/ioutil"
 	"math"
 	"os"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/block"
 	"github.com/improbable-eng/thanos/pkg/block/metadata"
+	"github.com/improbable-eng/thanos/pkg/objstore"
+	"github.com/improbable-eng/thanos/pkg/objstore/inmem"
 	"github.com/improbable-eng/thanos/pkg/testutil"
 	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/tsdb"
+	"github.com/prometheus/tsdb/labels"
 )
 
 func TestShipperTimestamps(t *testing.T) {
@@ -21,7 +31,7 @@ func TestShipperTimestamps(t *testing.T) {
 		testutil.Ok(t, os.RemoveAll(dir))
 	}()
 
-	s := New(nil, nil, dir, nil, nil, metadata.TestSource)
+	s := New(nil, nil, dir, nil, nil, nil, metadata.TestSource, 1)
 
 	// Missing thanos meta file.
 	_, _, err = s.Timestamps()
@@ -77,3 +87,313 @@ func TestShipperTimestamps(t *testing.T) {
 	testutil.Equals(t, int64(1000), mint)
 	testutil.Equals(t, int64(2000), maxt)
 }
+
+func TestShipper_ReplicaLabels(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shipper-test")
+	testutil.Ok(t, err)
+	defer func() {
+		testutil.Ok(t, os.RemoveAll(dir))
+	}()
+
+	ctx := context.Background()
+	extLset := labels.FromStrings("cluster", "eu1", "replica", "A")
+
+	id, err := testutil.CreateBlock(ctx, dir, []labels.Labels{labels.FromStrings("a", "1")}, 10, 0, 1000, nil, 0)
+	testutil.Ok(t, err)
+
+	bkt := inmem.NewBucket()
+	s := New(nil, nil, dir, bkt, func() labels.Labels { return extLset }, []string{"replica"}, metadata.RulerSource, 1)
+	uploaded, err := s.Sync(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, uploaded)
+
+	// Replica labels are kept in the external labels, so blocks of the replicas do not overlap.
+	m, err := block.DownloadMeta(ctx, log.NewNopLogger(), bkt, id)
+	testutil.Ok(t, err)
+	testutil.Equals(t, extLset.Map(), m.Thanos.Labels)
+	testutil.Equals(t, []string{"replica"}, m.Thanos.ReplicaLabels)
+	testutil.Equals(t, metadata.RulerSource, m.Thanos.Source)
+}
+
+func TestShipper_UploadCompacted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shipper-test")
+	testutil.Ok(t, err)
+	defer func() {
+		testutil.Ok(t, os.RemoveAll(dir))
+	}()
+
+	ctx := context.Background()
+	extLset := labels.FromStrings("prometheus", "prom-1")
+	bkt := inmem.NewBucket()
+
+	// Block already shipped for the same external labels.
+	shipped := ulid.MustNew(1, nil)
+	writeTestBlock(t, dir, shipped, 0, 1000, 1)
+	s := NewWithCompacted(nil, nil, dir, bkt, func() labels.Labels { return extLset }, nil, metadata.TestSource, 1)
+	uploaded, err := s.Sync(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, uploaded)
+	testutil.Ok(t, os.RemoveAll(path.Join(dir, shipped.String())))
+
+	overlapsBucket := ulid.MustNew(2, nil)
+	writeTestBlock(t, dir, overlapsBucket, 500, 2000, 2)
+	compacted := ulid.MustNew(3, nil)
+	writeTestBlock(t, dir, compacted, 2000, 4000, 2)
+	overlapsCompacted := ulid.MustNew(4, nil)
+	writeTestBlock(t, dir, overlapsCompacted, 3000, 5000, 2)
+
+	// Compacted blocks overlapping with the bucket or with compacted blocks uploaded before are not uploaded.
+	uploaded, err = s.Sync(ctx)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, 1, uploaded)
+
+	for id, exp := range map[ulid.ULID]bool{overlapsBucket: false, compacted: true, overlapsCompacted: false} {
+		ok, err := bkt.Exists(ctx, path.Join(id.String(), block.MetaFilename))
+		testutil.Ok(t, err)
+		testutil.Equals(t, exp, ok)
+	}
+
+	// Uploaded compacted blocks are uploaded only once.
+	testutil.Ok(t, os.RemoveAll(path.Join(dir, overlapsBucket.String())))
+	testutil.Ok(t, os.RemoveAll(path.Join(dir, overlapsCompacted.String())))
+	testutil.Ok(t, block.Delete(ctx, bkt, compacted))
+
+	uploaded, err = s.Sync(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, uploaded)
+}
+
+func TestShipper_UploadConcurrency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shipper-test")
+	testutil.Ok(t, err)
+	defer func() {
+		testutil.Ok(t, os.RemoveAll(dir))
+	}()
+
+	ctx := context.Background()
+	bkt := inmem.NewBucket()
+
+	var ids []ulid.ULID
+	for i := 0; i < 10; i++ {
+		id := ulid.MustNew(uint64(i), nil)
+		writeTestBlock(t, dir, id, int64(i)*1000, int64(i+1)*1000, 1)
+		ids = append(ids, id)
+	}
+
+	s := New(nil, nil, dir, bkt, func() labels.Labels { return labels.FromStrings("prometheus", "prom-1") }, nil, metadata.TestSource, 4)
+	uploaded, err := s.Sync(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, len(ids), uploaded)
+
+	for _, id := range ids {
+		ok, err := bkt.Exists(ctx, path.Join(id.String(), block.MetaFilename))
+		testutil.Ok(t, err)
+		testutil.Assert(t, ok, "block %s not uploaded", id)
+	}
+	// The shipper state lists the uploaded blocks in order regardless of the order their uploads finished in.
+	meta, err := ReadMetaFile(dir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, ids, meta.Uploaded)
+
+	size, err := dirSize(path.Join(dir, ids[0].String()))
+	testutil.Ok(t, err)
+	testutil.Assert(t, promtest.ToFloat64(s.metrics.uploadedBytes) >= float64(len(ids))*float64(size), "uploaded bytes not counted")
+}
+
+// unavailableBucket fails all calls while unavailable is set.
+type unavailableBucket struct {
+	objstore.Bucket
+	unavailable bool
+}
+
+func (b *unavailableBucket) Exists(ctx context.Context, name string) (bool, error) {
+	if b.unavailable {
+		return false, errors.New("bucket unavailable")
+	}
+	return b.Bucket.Exists(ctx, name)
+}
+
+func (b *unavailableBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	if b.unavailable {
+		return errors.New("bucket unavailable")
+	}
+	return b.Bucket.Upload(ctx, name, r)
+}
+
+func TestShipper_PendingBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shipper-test")
+	testutil.Ok(t, err)
+	defer func() {
+		testutil.Ok(t, os.RemoveAll(dir))
+	}()
+
+	ctx := context.Background()
+	bkt := &unavailableBucket{Bucket: inmem.NewBucket()}
+	s := New(nil, nil, dir, bkt, func() labels.Labels { return labels.FromStrings("prometheus", "prom-1") }, nil, metadata.TestSource, 1)
+
+	writeTestBlock(t, dir, ulid.MustNew(1, nil), 0, 1000, 1)
+	_, err = s.Sync(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, s.Pending())
+
+	// Blocks stay pending while the sync fails before trying to upload them.
+	bkt.unavailable = true
+	writeTestBlock(t, dir, ulid.MustNew(2, nil), 1000, 2000, 1)
+	writeTestBlock(t, dir, ulid.MustNew(3, nil), 2000, 3000, 1)
+	// Compacted blocks are not uploaded by this shipper, so they are not pending.
+	writeTestBlock(t, dir, ulid.MustNew(4, nil), 0, 2000, 2)
+	for i := 0; i < 2; i++ {
+		_, err = s.Sync(ctx)
+		testutil.NotOk(t, err)
+		testutil.Equals(t, 2, s.Pending())
+		testutil.Equals(t, 2.0, promtest.ToFloat64(s.metrics.pendingBlocks))
+	}
+
+	bkt.unavailable = false
+	uploaded, err := s.Sync(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, uploaded)
+	testutil.Equals(t, 0, s.Pending())
+	testutil.Equals(t, 0.0, promtest.ToFloat64(s.metrics.pendingBlocks))
+}
+
+func TestShipper_MarkDeletable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shipper-test")
+	testutil.Ok(t, err)
+	defer func() {
+		testutil.Ok(t, os.RemoveAll(dir))
+	}()
+
+	ctx := context.Background()
+	bkt := &unavailableBucket{Bucket: inmem.NewBucket()}
+	lset := func() labels.Labels { return labels.FromStrings("prometheus", "prom-1") }
+	series := []labels.Labels{labels.FromStrings("a", "1")}
+	hour := int64(time.Hour / time.Millisecond)
+
+	uploaded, err := testutil.CreateBlock(ctx, dir, series, 10, 0, hour, nil, 0)
+	testutil.Ok(t, err)
+	s := New(nil, nil, dir, bkt, lset, nil, metadata.TestSource, 1)
+	_, err = s.Sync(ctx)
+	testutil.Ok(t, err)
+
+	// Blocks beyond the retention stay pending during an object storage outage.
+	bkt.unavailable = true
+	pending, err := testutil.CreateBlock(ctx, dir, series, 10, hour, 2*hour, nil, 0)
+	testutil.Ok(t, err)
+	newest, err := testutil.CreateBlock(ctx, dir, series, 10, 10*hour, 12*hour, nil, 0)
+	testutil.Ok(t, err)
+	_, err = s.Sync(ctx)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, 2, s.Pending())
+
+	// After a restart, only uploaded blocks beyond the retention are deleted when the TSDB is opened.
+	restart := func(expected ...ulid.ULID) {
+		s = New(nil, nil, dir, bkt, lset, nil, metadata.TestSource, 1)
+		_, err := s.MarkDeletable(4 * time.Hour)
+		testutil.Ok(t, err)
+
+		db, err := tsdb.Open(dir, nil, nil, &tsdb.Options{BlockRanges: []int64{2 * hour}, NoLockfile: true})
+		testutil.Ok(t, err)
+		// Reload the blocks like the TSDB does whenever it persists its head, the retention is applied on reloads.
+		testutil.Ok(t, db.CleanTombstones())
+		var blocks []ulid.ULID
+		for _, b := range db.Blocks() {
+			blocks = append(blocks, b.Meta().ULID)
+		}
+		testutil.Ok(t, db.Close())
+		testutil.Equals(t, expected, blocks)
+	}
+	restart(pending, newest)
+	restart(pending, newest)
+
+	bkt.unavailable = false
+	_, err = s.Sync(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, s.Pending())
+	restart(newest)
+
+	_, err = os.Stat(path.Join(dir, uploaded.String()))
+	testutil.Assert(t, os.IsNotExist(err), "uploaded block beyond the retention not deleted")
+}
+
+func writeTestBlock(t *testing.T, dir string, id ulid.ULID, mint, maxt int64, level int) {
+	bdir := path.Join(dir, id.String())
+	testutil.Ok(t, os.MkdirAll(path.Join(bdir, block.ChunksDirname), os.ModePerm))
+	testutil.Ok(t, ioutil.WriteFile(path.Join(bdir, block.ChunksDirname, "000001"), []byte("chunks"), os.ModePerm))
+	testutil.Ok(t, ioutil.WriteFile(path.Join(bdir, block.IndexFilename), []byte("index"), os.ModePerm))
+	testutil.Ok(t, metadata.Write(log.NewNopLogger(), bdir, &metadata.Meta{
+		Version: 1,
+		BlockMeta: tsdb.BlockMeta{
+			Version:    1,
+			ULID:       id,
+			MinTime:    mint,
+			MaxTime:    maxt,
+			Stats:      tsdb.BlockStats{NumSamples: 1},
+			Compaction: tsdb.BlockMetaCompaction{Level: level, Sources: []ulid.ULID{id}},
+		},
+	}))
+}
+
+func TestShipper_ReconcileCorruptedMetaFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shipper-test")
+	testutil.Ok(t, err)
+	defer func() {
+		testutil.Ok(t, os.RemoveAll(dir))
+	}()
+
+	ctx := context.Background()
+	extLset := labels.FromStrings("prometheus", "prom-1")
+	bkt := inmem.NewBucket()
+
+	compactedAway := ulid.MustNew(1, nil)
+	writeTestBlock(t, dir, compactedAway, 0, 1000, 1)
+	s := New(nil, nil, dir, bkt, func() labels.Labels { return extLset }, nil, metadata.TestSource, 1)
+	uploaded, err := s.Sync(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, uploaded)
+
+	// Compact the uploaded block into a new one in the bucket.
+	testutil.Ok(t, block.Delete(ctx, bkt, compactedAway))
+	compactedDir, err := ioutil.TempDir("", "shipper-test-compacted")
+	testutil.Ok(t, err)
+	defer func() {
+		testutil.Ok(t, os.RemoveAll(compactedDir))
+	}()
+	compacted := ulid.MustNew(2, nil)
+	testutil.Ok(t, metadata.Write(log.NewNopLogger(), compactedDir, &metadata.Meta{
+		Version: 1,
+		BlockMeta: tsdb.BlockMeta{
+			Version:    1,
+			ULID:       compacted,
+			MinTime:    0,
+			MaxTime:    2000,
+			Compaction: tsdb.BlockMetaCompaction{Level: 2, Sources: []ulid.ULID{compactedAway}},
+		},
+		Thanos: metadata.Thanos{Labels: extLset.Map()},
+	}))
+	testutil.Ok(t, objstore.UploadFile(ctx, log.NewNopLogger(), bkt, path.Join(compactedDir, block.MetaFilename), path.Join(compacted.String(), block.MetaFilename)))
+
+	// Corrupt the meta file.
+	b, err := ioutil.ReadFile(path.Join(dir, MetaFilename))
+	testutil.Ok(t, err)
+	testutil.Ok(t, ioutil.WriteFile(path.Join(dir, MetaFilename), bytes.Replace(b, []byte(compactedAway.String()), []byte(compacted.String()), 1), os.ModePerm))
+	_, err = ReadMetaFile(dir)
+	testutil.NotOk(t, err)
+
+	notUploaded := ulid.MustNew(3, nil)
+	writeTestBlock(t, dir, notUploaded, 2000, 3000, 1)
+
+	// The block compacted away is not uploaded again.
+	uploaded, err = s.Sync(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, uploaded)
+
+	ok, err := bkt.Exists(ctx, path.Join(compactedAway.String(), block.MetaFilename))
+	testutil.Ok(t, err)
+	testutil.Assert(t, !ok, "block compacted away uploaded again")
+
+	meta, err := ReadMetaFile(dir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, &Meta{Version: 1, Uploaded: []ulid.ULID{compactedAway, notUploaded}}, meta)
+	testutil.Equals(t, 1.0, promtest.ToFloat64(s.metrics.metaReconciliations))
+}
@@ -0,0 +1,100 @@
+// Package status serves the status endpoints of the Prometheus HTTP API, so that tools checking them, e.g. the
+// health check of Grafana data sources, work against all Thanos components.
+package status
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"runtime"
+	"time"
+
+	"github.com/prometheus/common/version"
+)
+
+// BuildInfo is the build information of the binary as returned by /api/v1/status/buildinfo.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	Branch    string `json:"branch"`
+	BuildUser string `json:"buildUser"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// RuntimeInfo is the runtime information of the process as returned by /api/v1/status/runtimeinfo.
+type RuntimeInfo struct {
+	StartTime      time.Time `json:"startTime"`
+	CWD            string    `json:"CWD"`
+	GoroutineCount int       `json:"goroutineCount"`
+	GOMAXPROCS     int       `json:"GOMAXPROCS"`
+	GOGC           string    `json:"GOGC"`
+	GODEBUG        string    `json:"GODEBUG"`
+}
+
+// Status serves the status endpoints.
+type Status struct {
+	flags map[string]string
+	birth time.Time
+	cwd   string
+}
+
+// New returns the status endpoints of a process started now with the given flags.
+func New(flags map[string]string) *Status {
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = "<error retrieving current working directory>"
+	}
+	if flags == nil {
+		flags = map[string]string{}
+	}
+	return &Status{flags: flags, birth: time.Now(), cwd: cwd}
+}
+
+// Register registers the status endpoints under the given prefix of the mux.
+func (s *Status) Register(mux *http.ServeMux, prefix string) {
+	mux.HandleFunc(path.Join("/", prefix, "/api/v1/status/buildinfo"), s.buildInfo)
+	mux.HandleFunc(path.Join("/", prefix, "/api/v1/status/flags"), s.flagsHandler)
+	mux.HandleFunc(path.Join("/", prefix, "/api/v1/status/runtimeinfo"), s.runtimeInfo)
+}
+
+func (s *Status) buildInfo(w http.ResponseWriter, _ *http.Request) {
+	respond(w, BuildInfo{
+		Version:   version.Version,
+		Revision:  version.Revision,
+		Branch:    version.Branch,
+		BuildUser: version.BuildUser,
+		BuildDate: version.BuildDate,
+		GoVersion: version.GoVersion,
+	})
+}
+
+func (s *Status) flagsHandler(w http.ResponseWriter, _ *http.Request) {
+	respond(w, s.flags)
+}
+
+func (s *Status) runtimeInfo(w http.ResponseWriter, _ *http.Request) {
+	respond(w, RuntimeInfo{
+		StartTime:      s.birth,
+		CWD:            s.cwd,
+		GoroutineCount: runtime.NumGoroutine(),
+		GOMAXPROCS:     runtime.GOMAXPROCS(0),
+		GOGC:           os.Getenv("GOGC"),
+		GODEBUG:        os.Getenv("GODEBUG"),
+	})
+}
+
+// respond writes the data in the response format of the Prometheus HTTP API.
+func respond(w http.ResponseWriter, data interface{}) {
+	b, err := json.Marshal(struct {
+		Status string      `json:"status"`
+		Data   interface{} `json:"data"`
+	}{Status: "success", Data: data})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,45 @@
+package status
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/common/version"
+)
+
+func TestStatus(t *testing.T) {
+	mux := http.NewServeMux()
+	New(map[string]string{"query.timeout": "2m"}).Register(mux, "/prefix")
+
+	get := func(path string, data interface{}) {
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest("GET", "http://example.com"+path, nil)
+		testutil.Ok(t, err)
+		mux.ServeHTTP(w, r)
+		testutil.Equals(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Status string          `json:"status"`
+			Data   json.RawMessage `json:"data"`
+		}
+		testutil.Ok(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		testutil.Equals(t, "success", resp.Status)
+		testutil.Ok(t, json.Unmarshal(resp.Data, data))
+	}
+
+	var flags map[string]string
+	get("/prefix/api/v1/status/flags", &flags)
+	testutil.Equals(t, map[string]string{"query.timeout": "2m"}, flags)
+
+	var buildInfo BuildInfo
+	get("/prefix/api/v1/status/buildinfo", &buildInfo)
+	testutil.Equals(t, version.GoVersion, buildInfo.GoVersion)
+
+	var runtimeInfo RuntimeInfo
+	get("/prefix/api/v1/status/runtimeinfo", &runtimeInfo)
+	testutil.Assert(t, runtimeInfo.GoroutineCount > 0, "no goroutines")
+	testutil.Assert(t, runtimeInfo.CWD != "", "no working directory")
+}
@@ -19,20 +19,24 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/improbable-eng/thanos/pkg/block"
+	"github.com/improbable-eng/thanos/pkg/block/indexheader"
 	"github.com/improbable-eng/thanos/pkg/block/metadata"
 	"github.com/improbable-eng/thanos/pkg/compact/downsample"
 	"github.com/improbable-eng/thanos/pkg/component"
 	"github.com/improbable-eng/thanos/pkg/extprom"
+	"github.com/improbable-eng/thanos/pkg/model"
 	"github.com/improbable-eng/thanos/pkg/objstore"
 	"github.com/improbable-eng/thanos/pkg/pool"
 	"github.com/improbable-eng/thanos/pkg/runutil"
 	"github.com/improbable-eng/thanos/pkg/store/storepb"
 	"github.com/improbable-eng/thanos/pkg/strutil"
 	"github.com/improbable-eng/thanos/pkg/tracing"
-	"github.com/oklog/run"
 	"github.com/oklog/ulid"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	promlabels "github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/prometheus/prometheus/pkg/timestamp"
 	"github.com/prometheus/tsdb/chunkenc"
 	"github.com/prometheus/tsdb/chunks"
 	"github.com/prometheus/tsdb/fileutil"
@@ -51,23 +55,50 @@ import (
 // Take a look at Figure 6 in this whitepaper http://www.vldb.org/pvldb/vol8/p1816-teller.pdf.
 const maxSamplesPerChunk = 120
 
+// defaultSeriesBatchSize is the default number of series loaded at once from a single block by a Series call. A Series
+// call holds at most one batch of series and chunks of each queried block in memory.
+const defaultSeriesBatchSize = 10000
+
+// Default size classes of the chunk pool used for byte ranges of chunks fetched from the bucket: from 200KB up to 50MB,
+// each class twice as large as the previous one.
+const (
+	DefaultChunkPoolMinBucketSize = 2e5
+	DefaultChunkPoolMaxBucketSize = 50e6
+	DefaultChunkPoolGrowthFactor  = 2
+)
+
 type bucketStoreMetrics struct {
 	blocksLoaded          prometheus.Gauge
 	blockLoads            prometheus.Counter
 	blockLoadFailures     prometheus.Counter
 	blockDrops            prometheus.Counter
 	blockDropFailures     prometheus.Counter
-	seriesDataTouched     *prometheus.SummaryVec
-	seriesDataFetched     *prometheus.SummaryVec
-	seriesDataSizeTouched *prometheus.SummaryVec
-	seriesDataSizeFetched *prometheus.SummaryVec
+	blocksSkipped         *prometheus.GaugeVec
+	bucketIndexLoads      prometheus.Counter
+	bucketIndexFallbacks  prometheus.Counter
+	bucketIndexUpdatedAt  prometheus.Gauge
+	lastSuccessfulSync    prometheus.Gauge
+	blocksStale           prometheus.Gauge
+	seriesDataTouched     *prometheus.HistogramVec
+	seriesDataFetched     *prometheus.HistogramVec
+	seriesDataSizeTouched *prometheus.HistogramVec
+	seriesDataSizeFetched *prometheus.HistogramVec
+	blockDataTouched      *prometheus.HistogramVec
+	blockDataFetched      *prometheus.HistogramVec
+	seriesFetchDuration   *prometheus.HistogramVec
 	seriesBlocksQueried   prometheus.Summary
 	seriesGetAllDuration  prometheus.Histogram
 	seriesMergeDuration   prometheus.Histogram
 	resultSeriesCount     prometheus.Summary
 	chunkSizeBytes        prometheus.Histogram
-	queriesDropped        prometheus.Counter
+	queriesDropped        *prometheus.CounterVec
 	queriesLimit          prometheus.Gauge
+	memoryBudgetUsed      prometheus.Gauge
+	seriesDeduplicated    prometheus.Counter
+
+	indexHeaderLazyLoads        prometheus.Counter
+	indexHeaderLazyLoadFailures prometheus.Counter
+	indexHeaderLazyUnloads      prometheus.Counter
 }
 
 func newBucketStoreMetrics(reg prometheus.Registerer) *bucketStoreMetrics {
@@ -85,31 +116,80 @@ func newBucketStoreMetrics(reg prometheus.Registerer) *bucketStoreMetrics {
 		Name: "thanos_bucket_store_block_drops_total",
 		Help: "Total number of local blocks that were dropped.",
 	})
+	m.blocksSkipped = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "thanos_bucket_store_blocks_skipped",
+		Help: "Number of blocks in the bucket skipped by the last block sync.",
+	}, []string{"reason"})
 	m.blockDropFailures = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: "thanos_bucket_store_block_drop_failures_total",
 		Help: "Total number of local blocks that failed to be dropped.",
 	})
+	m.bucketIndexLoads = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "thanos_bucket_store_bucket_index_loads_total",
+		Help: "Total number of block syncs using the bucket index.",
+	})
+	m.bucketIndexFallbacks = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "thanos_bucket_store_bucket_index_fallbacks_total",
+		Help: "Total number of block syncs iterating the bucket, because the bucket index was missing, unreadable or stale.",
+	})
+	m.bucketIndexUpdatedAt = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "thanos_bucket_store_bucket_index_last_updated_timestamp_seconds",
+		Help: "Unix timestamp of when the last loaded bucket index was built.",
+	})
+	m.lastSuccessfulSync = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "thanos_bucket_store_last_successful_sync_timestamp_seconds",
+		Help: "Unix timestamp of the last successful block sync with object storage.",
+	})
+	m.blocksStale = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "thanos_bucket_store_blocks_stale",
+		Help: "Set to 1 if the last block sync failed and the blocks of the last successful sync are served.",
+	})
 	m.blocksLoaded = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "thanos_bucket_store_blocks_loaded",
 		Help: "Number of currently loaded blocks.",
 	})
 
-	m.seriesDataTouched = prometheus.NewSummaryVec(prometheus.SummaryOpts{
-		Name: "thanos_bucket_store_series_data_touched",
-		Help: "How many items of a data type in a block were touched for a single series request.",
+	// Histograms, unlike summaries, can be aggregated across stores.
+	dataBuckets := prometheus.ExponentialBuckets(1, 4, 12)
+	m.seriesDataTouched = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "thanos_bucket_store_series_data_touched",
+		Help:    "How many items of a data type were touched by a single series request.",
+		Buckets: dataBuckets,
 	}, []string{"data_type"})
-	m.seriesDataFetched = prometheus.NewSummaryVec(prometheus.SummaryOpts{
-		Name: "thanos_bucket_store_series_data_fetched",
-		Help: "How many items of a data type in a block were fetched for a single series request.",
+	m.seriesDataFetched = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "thanos_bucket_store_series_data_fetched",
+		Help:    "How many items of a data type were fetched from the bucket by a single series request.",
+		Buckets: dataBuckets,
 	}, []string{"data_type"})
 
-	m.seriesDataSizeTouched = prometheus.NewSummaryVec(prometheus.SummaryOpts{
-		Name: "thanos_bucket_store_series_data_size_touched_bytes",
-		Help: "Size of all items of a data type in a block were touched for a single series request.",
+	sizeBuckets := prometheus.ExponentialBuckets(1024, 4, 10)
+	m.seriesDataSizeTouched = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "thanos_bucket_store_series_data_size_touched_bytes",
+		Help:    "Size of all items of a data type touched by a single series request.",
+		Buckets: sizeBuckets,
 	}, []string{"data_type"})
-	m.seriesDataSizeFetched = prometheus.NewSummaryVec(prometheus.SummaryOpts{
-		Name: "thanos_bucket_store_series_data_size_fetched_bytes",
-		Help: "Size of all items of a data type in a block were fetched for a single series request.",
+	m.seriesDataSizeFetched = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "thanos_bucket_store_series_data_size_fetched_bytes",
+		Help:    "Size of all items of a data type fetched from the bucket by a single series request.",
+		Buckets: sizeBuckets,
+	}, []string{"data_type"})
+
+	m.blockDataTouched = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "thanos_bucket_store_series_block_data_touched",
+		Help:    "How many items of a data type in a single block were touched by a series request.",
+		Buckets: dataBuckets,
+	}, []string{"data_type"})
+	m.blockDataFetched = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "thanos_bucket_store_series_block_data_fetched",
+		Help:    "How many items of a data type in a single block were fetched from the bucket by a series request.",
+		Buckets: dataBuckets,
+	}, []string{"data_type"})
+	m.seriesFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "thanos_bucket_store_series_fetch_duration_seconds",
+		Help: "Total time spent fetching a data type from the bucket for a single series request, summed over concurrent fetches.",
+		Buckets: []float64{
+			0.01, 0.05, 0.1, 0.25, 0.6, 1, 2, 3.5, 5, 7.5, 10, 15, 30, 60,
+		},
 	}, []string{"data_type"})
 
 	m.seriesBlocksQueried = prometheus.NewSummary(prometheus.SummaryOpts{
@@ -143,14 +223,35 @@ func newBucketStoreMetrics(reg prometheus.Registerer) *bucketStoreMetrics {
 		},
 	})
 
-	m.queriesDropped = prometheus.NewCounter(prometheus.CounterOpts{
+	m.queriesDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "thanos_bucket_store_queries_dropped_total",
-		Help: "Number of queries that were dropped due to the sample limit.",
-	})
+		Help: "Number of queries that were dropped due to a limit.",
+	}, []string{"reason"})
 	m.queriesLimit = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "thanos_bucket_store_queries_concurrent_max",
 		Help: "Number of maximum concurrent queries.",
 	})
+	m.memoryBudgetUsed = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "thanos_bucket_store_series_memory_budget_used_bytes",
+		Help: "Estimated memory of the in-flight Series calls admitted by the memory budget.",
+	})
+	m.seriesDeduplicated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "thanos_bucket_store_series_deduplicated_total",
+		Help: "Total number of Series requests served by an identical request already in flight.",
+	})
+
+	m.indexHeaderLazyLoads = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "thanos_bucket_store_index_header_lazy_load_total",
+		Help: "Total number of index-headers loaded on first use.",
+	})
+	m.indexHeaderLazyLoadFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "thanos_bucket_store_index_header_lazy_load_failed_total",
+		Help: "Total number of index-headers that failed to be loaded on first use.",
+	})
+	m.indexHeaderLazyUnloads = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "thanos_bucket_store_index_header_lazy_unload_total",
+		Help: "Total number of index-headers unloaded after being idle.",
+	})
 
 	if reg != nil {
 		reg.MustRegister(
@@ -158,18 +259,32 @@ func newBucketStoreMetrics(reg prometheus.Registerer) *bucketStoreMetrics {
 			m.blockLoadFailures,
 			m.blockDrops,
 			m.blockDropFailures,
+			m.blocksSkipped,
+			m.bucketIndexLoads,
+			m.bucketIndexFallbacks,
+			m.bucketIndexUpdatedAt,
+			m.lastSuccessfulSync,
+			m.blocksStale,
 			m.blocksLoaded,
 			m.seriesDataTouched,
 			m.seriesDataFetched,
 			m.seriesDataSizeTouched,
 			m.seriesDataSizeFetched,
+			m.blockDataTouched,
+			m.blockDataFetched,
+			m.seriesFetchDuration,
 			m.seriesBlocksQueried,
 			m.seriesGetAllDuration,
 			m.seriesMergeDuration,
 			m.resultSeriesCount,
 			m.chunkSizeBytes,
+			m.seriesDeduplicated,
+			m.memoryBudgetUsed,
 			m.queriesDropped,
 			m.queriesLimit,
+			m.indexHeaderLazyLoads,
+			m.indexHeaderLazyLoadFailures,
+			m.indexHeaderLazyUnloads,
 		)
 	}
 	return &m
@@ -177,9 +292,9 @@ func newBucketStoreMetrics(reg prometheus.Registerer) *bucketStoreMetrics {
 
 type indexCache interface {
 	SetPostings(b ulid.ULID, l labels.Label, v []byte)
-	Postings(b ulid.ULID, l labels.Label) ([]byte, bool)
+	FetchMultiPostings(ctx context.Context, b ulid.ULID, keys []labels.Label) (hits map[labels.Label][]byte, misses []labels.Label)
 	SetSeries(b ulid.ULID, id uint64, v []byte)
-	Series(b ulid.ULID, id uint64) ([]byte, bool)
+	FetchMultiSeries(ctx context.Context, b ulid.ULID, ids []uint64) (hits map[uint64][]byte, misses []uint64)
 }
 
 // BucketStore implements the store API backed by a bucket. It loads all index
@@ -190,7 +305,7 @@ type BucketStore struct {
 	bucket     objstore.BucketReader
 	dir        string
 	indexCache indexCache
-	chunkPool  *pool.BytesPool
+	chunkPool  pool.Bytes
 
 	// Sets of blocks that have the same labels. They are indexed by a hash over their label set.
 	mtx       sync.RWMutex
@@ -199,15 +314,122 @@ type BucketStore struct {
 
 	// Verbose enabled additional logging.
 	debugLogging bool
-	// Number of goroutines to use when syncing blocks from object storage.
+	// Number of goroutines to use when loading new blocks from object storage.
 	blockSyncConcurrency int
+	// Number of goroutines to use when checking the metadata of all blocks in object storage.
+	metaSyncConcurrency int
+	// Blocks marked for deletion for longer than ignoreDeletionMarksDelay are not served anymore.
+	ignoreDeletionMarksDelay time.Duration
+	// Blocks not created by the compactor are served only once they are older than consistencyDelay.
+	consistencyDelay time.Duration
+	// Blocks are synced from the bucket index if it was updated within bucketIndexMaxStalePeriod. 0 disables using
+	// the bucket index.
+	bucketIndexMaxStalePeriod time.Duration
+
+	// Deletion marks of the blocks in the bucket. Marks never change, so they are read only once per block.
+	deletionMarksMtx sync.Mutex
+	deletionMarks    map[ulid.ULID]*metadata.DeletionMark
+
+	// syncErr is the error of the last block sync if it failed, in which case the blocks of the last successful
+	// sync are still served and responses carry a warning about it.
+	syncErrMtx sync.RWMutex
+	syncErr    error
+	syncedAt   time.Time
 
 	// Query gate which limits the maximum amount of concurrent queries.
 	queryGate *Gate
 
-	// samplesLimiter limits the number of samples per each Series() call.
-	samplesLimiter *Limiter
-	partitioner    partitioner
+	// seriesBatchSize is the number of series loaded at once from each block by a Series() call.
+	seriesBatchSize int
+	// Limits of the number of samples, series, chunks and fetched bytes per each Series() call. 0 disables the limit.
+	maxSampleCount  uint64
+	maxSeriesCount  uint64
+	maxChunkCount   uint64
+	maxFetchedBytes uint64
+
+	partitioner partitioner
+
+	// lazyIndexHeader enables loading index-headers into memory on the first query touching the block.
+	lazyIndexHeader bool
+	// indexHeaderIdleTimeout is the time after which a lazily loaded index-header is unloaded if unused.
+	indexHeaderIdleTimeout time.Duration
+	// postingsCompression is the encoding of the postings lists stored in the index cache.
+	postingsCompression string
+	// inflightSeries deduplicates identical concurrent Series requests if enabled.
+	inflightSeries *inflightSeries
+	// memoryBudget limits the estimated memory of all in-flight Series calls if enabled.
+	memoryBudget *memoryBudget
+
+	relabelConfig []*relabel.Config
+	filterConfig  *FilterConfig
+}
+
+// FilterConfig is a configuration, which the store uses to select the blocks it serves.
+type FilterConfig struct {
+	MinTime, MaxTime model.TimeOrDurationValue
+}
+
+// BucketStoreConfig configures a BucketStore. Zero values of the limits disable them.
+type BucketStoreConfig struct {
+	// Dir is the local directory the index headers of the blocks are kept in.
+	Dir string
+	// ChunkPool is the pool of chunk bytes. If nil, an unlimited pool is used.
+	ChunkPool pool.Bytes
+	// MaxSampleCount is the maximum number of samples a single Series request may touch.
+	MaxSampleCount uint64
+	// MaxSeriesCount is the maximum number of series a single Series request may touch.
+	MaxSeriesCount uint64
+	// MaxChunkCount is the maximum number of chunks a single Series request may touch.
+	MaxChunkCount uint64
+	// MaxFetchedBytes is the maximum number of bytes a single Series request may fetch from the bucket.
+	MaxFetchedBytes uint64
+	// MemoryBudget is the maximum estimated memory of all concurrent Series requests.
+	MemoryBudget uint64
+	// MaxConcurrent is the maximum number of Series requests processed concurrently.
+	MaxConcurrent int
+	// MaxQueueDuration is how long Series requests wait for a free slot or memory budget before they fail.
+	MaxQueueDuration time.Duration
+	// DebugLogging enables logging of request details.
+	DebugLogging bool
+	// BlockSyncConcurrency is the number of blocks loaded concurrently during a sync.
+	BlockSyncConcurrency int
+	// MetaSyncConcurrency is the number of meta.json files fetched concurrently during a sync.
+	MetaSyncConcurrency int
+	// IgnoreDeletionMarksDelay is how long blocks marked for deletion are still served.
+	IgnoreDeletionMarksDelay time.Duration
+	// ConsistencyDelay is the minimum age of blocks before they are served. Blocks created by the compactor are served right away.
+	ConsistencyDelay time.Duration
+	// BucketIndexMaxStalePeriod is the maximum age of the bucket index it is synced from. Zero disables the bucket index.
+	BucketIndexMaxStalePeriod time.Duration
+	// LazyIndexHeader loads index headers on first use instead of at sync time.
+	LazyIndexHeader bool
+	// IndexHeaderIdleTimeout is the duration after which lazily loaded index headers that were not used are released.
+	IndexHeaderIdleTimeout time.Duration
+	// PostingsCompression is the codec postings are stored in the index cache with.
+	PostingsCompression string
+	// RangeMaxGapSize is the maximum gap between byte ranges fetched from the bucket in one request.
+	RangeMaxGapSize uint64
+	// RangeMaxSize is the maximum size of merged byte ranges fetched from the bucket in one request.
+	RangeMaxSize uint64
+	// DedupInflightSeries makes concurrent identical Series requests share one evaluation.
+	DedupInflightSeries bool
+	// DedupInflightMaxBuffer is the maximum size of the responses of a shared evaluation buffered for requests joining it.
+	DedupInflightMaxBuffer int
+	// RelabelConfig selects the blocks to serve by their external labels.
+	RelabelConfig []*relabel.Config
+	// FilterConfig selects the blocks to serve by their time range.
+	FilterConfig *FilterConfig
+}
+
+// DefaultBucketStoreConfig returns the default configuration of a BucketStore keeping its data in dir.
+func DefaultBucketStoreConfig(dir string) BucketStoreConfig {
+	return BucketStoreConfig{
+		Dir:                    dir,
+		BlockSyncConcurrency:   20,
+		MetaSyncConcurrency:    20,
+		PostingsCompression:    PostingsCompressionNone,
+		DedupInflightMaxBuffer: 64 << 20,
+	}
 }
 
 // NewBucketStore creates a new bucket backed store that implements the store API against
@@ -216,54 +438,81 @@ func NewBucketStore(
 	logger log.Logger,
 	reg prometheus.Registerer,
 	bucket objstore.BucketReader,
-	dir string,
 	indexCache indexCache,
-	maxChunkPoolBytes uint64,
-	maxSampleCount uint64,
-	maxConcurrent int,
-	debugLogging bool,
-	blockSyncConcurrency int,
+	conf BucketStoreConfig,
 ) (*BucketStore, error) {
 	if logger == nil {
 		logger = log.NewNopLogger()
 	}
 
-	if maxConcurrent < 0 {
-		return nil, errors.Errorf("max concurrency value cannot be lower than 0 (got %v)", maxConcurrent)
+	if conf.MaxConcurrent < 0 {
+		return nil, errors.Errorf("max concurrency value cannot be lower than 0 (got %v)", conf.MaxConcurrent)
+	}
+	if conf.BlockSyncConcurrency <= 0 || conf.MetaSyncConcurrency <= 0 {
+		return nil, errors.Errorf("block and meta sync concurrency must be greater than 0 (got %v and %v)", conf.BlockSyncConcurrency, conf.MetaSyncConcurrency)
 	}
 
-	chunkPool, err := pool.NewBytesPool(2e5, 50e6, 2, maxChunkPoolBytes)
-	if err != nil {
-		return nil, errors.Wrap(err, "create chunk pool")
+	switch conf.PostingsCompression {
+	case PostingsCompressionNone, PostingsCompressionDiffVarint, PostingsCompressionDiffVarintSnappy:
+	default:
+		return nil, errors.Errorf("unknown postings compression %q", conf.PostingsCompression)
 	}
 
-	const maxGapSize = 512 * 1024
+	chunkPool := conf.ChunkPool
+	if chunkPool == nil {
+		p, err := pool.NewBytesPool(DefaultChunkPoolMinBucketSize, DefaultChunkPoolMaxBucketSize, DefaultChunkPoolGrowthFactor, 0, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "create chunk pool")
+		}
+		chunkPool = p
+	}
 
 	metrics := newBucketStoreMetrics(reg)
 	s := &BucketStore{
-		logger:               logger,
-		bucket:               bucket,
-		dir:                  dir,
-		indexCache:           indexCache,
-		chunkPool:            chunkPool,
-		blocks:               map[ulid.ULID]*bucketBlock{},
-		blockSets:            map[uint64]*bucketBlockSet{},
-		debugLogging:         debugLogging,
-		blockSyncConcurrency: blockSyncConcurrency,
+		logger:                    logger,
+		bucket:                    bucket,
+		dir:                       conf.Dir,
+		indexCache:                indexCache,
+		chunkPool:                 chunkPool,
+		blocks:                    map[ulid.ULID]*bucketBlock{},
+		blockSets:                 map[uint64]*bucketBlockSet{},
+		debugLogging:              conf.DebugLogging,
+		blockSyncConcurrency:      conf.BlockSyncConcurrency,
+		metaSyncConcurrency:       conf.MetaSyncConcurrency,
+		ignoreDeletionMarksDelay:  conf.IgnoreDeletionMarksDelay,
+		consistencyDelay:          conf.ConsistencyDelay,
+		bucketIndexMaxStalePeriod: conf.BucketIndexMaxStalePeriod,
+		deletionMarks:             map[ulid.ULID]*metadata.DeletionMark{},
 		queryGate: NewGate(
-			maxConcurrent,
+			conf.MaxConcurrent,
+			conf.MaxQueueDuration,
 			extprom.WrapRegistererWithPrefix("thanos_bucket_store_series_", reg),
 		),
-		samplesLimiter: NewLimiter(maxSampleCount, metrics.queriesDropped),
-		partitioner:    gapBasedPartitioner{maxGapSize: maxGapSize},
+		seriesBatchSize:        defaultSeriesBatchSize,
+		maxSampleCount:         conf.MaxSampleCount,
+		maxSeriesCount:         conf.MaxSeriesCount,
+		maxChunkCount:          conf.MaxChunkCount,
+		maxFetchedBytes:        conf.MaxFetchedBytes,
+		partitioner:            gapBasedPartitioner{maxGapSize: conf.RangeMaxGapSize, maxRangeSize: conf.RangeMaxSize},
+		lazyIndexHeader:        conf.LazyIndexHeader,
+		indexHeaderIdleTimeout: conf.IndexHeaderIdleTimeout,
+		postingsCompression:    conf.PostingsCompression,
+		relabelConfig:          conf.RelabelConfig,
+		filterConfig:           conf.FilterConfig,
 	}
 	s.metrics = metrics
+	if conf.DedupInflightSeries {
+		s.inflightSeries = newInflightSeries(conf.DedupInflightMaxBuffer, metrics.seriesDeduplicated)
+	}
+	if conf.MemoryBudget > 0 {
+		s.memoryBudget = newMemoryBudget(conf.MemoryBudget, conf.MaxQueueDuration, metrics.memoryBudgetUsed, metrics.queriesDropped.WithLabelValues("memory-budget"))
+	}
 
-	if err := os.MkdirAll(dir, 0777); err != nil {
+	if err := os.MkdirAll(conf.Dir, 0777); err != nil {
 		return nil, errors.Wrap(err, "create dir")
 	}
 
-	s.metrics.queriesLimit.Set(float64(maxConcurrent))
+	s.metrics.queriesLimit.Set(float64(conf.MaxConcurrent))
 
 	return s, nil
 }
@@ -285,52 +534,150 @@ func (s *BucketStore) Close() (err error) {
 // SyncBlocks synchronizes the stores state with the Bucket bucket.
 // It will reuse disk space as persistent cache based on s.dir param.
 func (s *BucketStore) SyncBlocks(ctx context.Context) error {
-	var wg sync.WaitGroup
+	// Metadata of all blocks is checked by the meta workers, new blocks to serve are passed to the block workers
+	// which download their index-headers.
+	var metaWg, blockWg sync.WaitGroup
+	// Metas of all blocks if the bucket index is used. Neither the bucket is iterated nor meta.json files or
+	// deletion marks are fetched then.
+	var indexedMetas map[ulid.ULID]*metadata.Meta
+	if idx := s.loadBucketIndex(ctx); idx != nil {
+		indexedMetas = make(map[ulid.ULID]*metadata.Meta, len(idx.Blocks))
+		for i := range idx.Blocks {
+			indexedMetas[idx.Blocks[i].ULID] = &idx.Blocks[i]
+		}
+		s.deletionMarksMtx.Lock()
+		for i := range idx.DeletionMarks {
+			s.deletionMarks[idx.DeletionMarks[i].ID] = &idx.DeletionMarks[i]
+		}
+		s.deletionMarksMtx.Unlock()
+	}
+
+	metac := make(chan ulid.ULID)
 	blockc := make(chan ulid.ULID)
 
+	// Blocks skipped by this sync with the reason. Loaded blocks that are skipped are dropped.
+	var (
+		skippedMtx sync.Mutex
+		skipped    = map[ulid.ULID]string{}
+	)
+
+	for i := 0; i < s.metaSyncConcurrency; i++ {
+		metaWg.Add(1)
+		go func() {
+			defer metaWg.Done()
+
+			for id := range metac {
+				if meta, ok := indexedMetas[id]; ok {
+					if err := writeIndexedMeta(s.logger, filepath.Join(s.dir, id.String()), meta); err != nil {
+						level.Warn(s.logger).Log("msg", "error writing meta.json from bucket index", "id", id, "err", err)
+						continue
+					}
+				}
+				reason, err := s.blockSkipReason(ctx, id, indexedMetas != nil)
+				if err != nil {
+					level.Warn(s.logger).Log("msg", "error checking whether block is skipped", "id", id, "err", err)
+					continue
+				}
+				if reason != "" {
+					skippedMtx.Lock()
+					skipped[id] = reason
+					skippedMtx.Unlock()
+					continue
+				}
+				if b := s.getBlock(id); b != nil {
+					continue
+				}
+
+				selected, err := s.isBlockSelected(ctx, id)
+				if err != nil {
+					level.Warn(s.logger).Log("msg", "error checking whether block is selected", "id", id, "err", err)
+					continue
+				}
+				if !selected {
+					continue
+				}
+
+				select {
+				case <-ctx.Done():
+				case blockc <- id:
+				}
+			}
+		}()
+	}
+
 	for i := 0; i < s.blockSyncConcurrency; i++ {
-		wg.Add(1)
+		blockWg.Add(1)
 		go func() {
+			defer blockWg.Done()
+
 			for id := range blockc {
 				if err := s.addBlock(ctx, id); err != nil {
 					level.Warn(s.logger).Log("msg", "loading block failed", "id", id, "err", err)
 					continue
 				}
 			}
-			wg.Done()
 		}()
 	}
 
 	allIDs := map[ulid.ULID]struct{}{}
-
-	err := s.bucket.Iter(ctx, "", func(name string) error {
-		// Strip trailing slash indicating a directory.
-		id, err := ulid.Parse(name[:len(name)-1])
-		if err != nil {
-			return nil
-		}
+	syncID := func(id ulid.ULID) {
 		allIDs[id] = struct{}{}
 
-		if b := s.getBlock(id); b != nil {
-			return nil
-		}
 		select {
 		case <-ctx.Done():
-		case blockc <- id:
+		case metac <- id:
 		}
-		return nil
-	})
+	}
+
+	var err error
+	if indexedMetas != nil {
+		for id := range indexedMetas {
+			syncID(id)
+		}
+	} else {
+		err = s.bucket.Iter(ctx, "", func(name string) error {
+			// Strip trailing slash indicating a directory.
+			id, err := ulid.Parse(name[:len(name)-1])
+			if err != nil {
+				return nil
+			}
+			syncID(id)
+			return nil
+		})
+	}
 
+	close(metac)
+	metaWg.Wait()
 	close(blockc)
-	wg.Wait()
+	blockWg.Wait()
 
 	if err != nil {
+		// Keep serving the loaded blocks, dropping them would fail all queries of their time range.
+		s.setSyncErr(errors.Wrap(err, "iter"))
 		return errors.Wrap(err, "iter")
 	}
-	// Drop all blocks that are no longer present in the bucket.
-	for id := range s.blocks {
-		if _, ok := allIDs[id]; ok {
-			continue
+	s.setSyncErr(nil)
+
+	s.deletionMarksMtx.Lock()
+	for id := range s.deletionMarks {
+		if _, ok := allIDs[id]; !ok {
+			delete(s.deletionMarks, id)
+		}
+	}
+	s.deletionMarksMtx.Unlock()
+
+	s.metrics.blocksSkipped.WithLabelValues(skipReasonMarkedForDeletion).Set(0)
+	s.metrics.blocksSkipped.WithLabelValues(skipReasonTooFresh).Set(0)
+	for _, reason := range skipped {
+		s.metrics.blocksSkipped.WithLabelValues(reason).Inc()
+	}
+
+	// Drop all blocks that are no longer present in the bucket, are skipped or are not selected anymore.
+	for id, b := range s.blocks {
+		if _, ok := allIDs[id]; ok && s.isMetaSelected(b.meta) {
+			if _, ok := skipped[id]; !ok {
+				continue
+			}
 		}
 		if err := s.removeBlock(id); err != nil {
 			level.Warn(s.logger).Log("msg", "drop outdated block", "block", id, "err", err)
@@ -342,6 +689,69 @@ func (s *BucketStore) SyncBlocks(ctx context.Context) error {
 	return nil
 }
 
+// setSyncErr records the result of a block sync.
+func (s *BucketStore) setSyncErr(err error) {
+	s.syncErrMtx.Lock()
+	defer s.syncErrMtx.Unlock()
+
+	s.syncErr = err
+	if err != nil {
+		s.metrics.blocksStale.Set(1)
+		return
+	}
+	s.syncedAt = time.Now()
+	s.metrics.blocksStale.Set(0)
+	s.metrics.lastSuccessfulSync.Set(float64(s.syncedAt.Unix()))
+}
+
+// staleBlocksWarning returns a warning for responses if the last block sync failed, nil otherwise.
+func (s *BucketStore) staleBlocksWarning() error {
+	s.syncErrMtx.RLock()
+	defer s.syncErrMtx.RUnlock()
+
+	if s.syncErr == nil {
+		return nil
+	}
+	if s.syncedAt.IsZero() {
+		return errors.Wrap(s.syncErr, "blocks may be stale, no block sync with object storage succeeded yet")
+	}
+	return errors.Wrapf(s.syncErr, "blocks may be stale, serving blocks of the last successful block sync at %s", s.syncedAt.Format(time.RFC3339))
+}
+
+// loadBucketIndex returns the bucket index if it is enabled and was updated within the max stale period, nil otherwise.
+func (s *BucketStore) loadBucketIndex(ctx context.Context) *block.BucketIndex {
+	if s.bucketIndexMaxStalePeriod <= 0 {
+		return nil
+	}
+	idx, err := block.ReadBucketIndex(ctx, s.logger, s.bucket)
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "failed to read bucket index; iterating the bucket", "err", err)
+		s.metrics.bucketIndexFallbacks.Inc()
+		return nil
+	}
+	s.metrics.bucketIndexUpdatedAt.Set(float64(idx.UpdatedAt))
+
+	if age := time.Since(idx.UpdatedAtTime()); age > s.bucketIndexMaxStalePeriod {
+		level.Warn(s.logger).Log("msg", "bucket index is stale; iterating the bucket", "age", age)
+		s.metrics.bucketIndexFallbacks.Inc()
+		return nil
+	}
+	s.metrics.bucketIndexLoads.Inc()
+	return idx
+}
+
+// writeIndexedMeta writes the meta of a block taken from the bucket index into the local block directory, if the block
+// was not seen before, so that it does not have to be downloaded.
+func writeIndexedMeta(logger log.Logger, dir string, meta *metadata.Meta) error {
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return errors.Wrap(err, "create dir")
+	}
+	return errors.Wrap(metadata.Write(logger, dir, meta), "write meta.json")
+}
+
 // InitialSync perform blocking sync with extra step at the end to delete locally saved blocks that are no longer
 // present in the bucket. The mismatch of these can only happen between restarts, so we can do that only once per startup.
 func (s *BucketStore) InitialSync(ctx context.Context) error {
@@ -377,6 +787,118 @@ func (s *BucketStore) numBlocks() int {
 	return len(s.blocks)
 }
 
+// isBlockSelected checks whether the block is served by the store according to the relabel config and the time
+// range. It downloads the meta.json of the block to the local disk if needed.
+func (s *BucketStore) isBlockSelected(ctx context.Context, id ulid.ULID) (bool, error) {
+	if s.filterConfig == nil && len(s.relabelConfig) == 0 {
+		return true, nil
+	}
+	dir := filepath.Join(s.dir, id.String())
+	meta, err := loadMeta(ctx, s.logger, s.bucket, dir, id)
+	if err != nil {
+		if err2 := os.RemoveAll(dir); err2 != nil {
+			level.Warn(s.logger).Log("msg", "failed to remove block we cannot load", "err", err2)
+		}
+		return false, err
+	}
+	return s.isMetaSelected(meta), nil
+}
+
+const (
+	skipReasonMarkedForDeletion = "marked-for-deletion"
+	skipReasonTooFresh          = "too-fresh"
+)
+
+// blockSkipReason returns the reason why the block must not be served or an empty string otherwise. Blocks marked for
+// deletion are skipped once the mark is older than the ignore deletion marks delay, so that the store stops using them
+// before the compactor deletes them. Fresh blocks are skipped until they are older than the consistency delay, as they
+// may not be fully uploaded yet.
+func (s *BucketStore) blockSkipReason(ctx context.Context, id ulid.ULID, indexed bool) (string, error) {
+	m, err := s.deletionMark(ctx, id, indexed)
+	if err != nil {
+		return "", err
+	}
+	if m != nil && time.Since(time.Unix(m.DeletionTime, 0)) > s.ignoreDeletionMarksDelay {
+		return skipReasonMarkedForDeletion, nil
+	}
+
+	if ulid.Now()-id.Time() >= uint64(s.consistencyDelay/time.Millisecond) {
+		return "", nil
+	}
+	// Loaded blocks were old enough or created by the compactor already.
+	if b := s.getBlock(id); b != nil {
+		return "", nil
+	}
+	// Blocks produced by the compactor replace blocks that are deleted right after, so they are served immediately.
+	dir := filepath.Join(s.dir, id.String())
+	meta, err := loadMeta(ctx, s.logger, s.bucket, dir, id)
+	if err != nil {
+		if err2 := os.RemoveAll(dir); err2 != nil {
+			level.Warn(s.logger).Log("msg", "failed to remove block we cannot load", "err", err2)
+		}
+		return "", err
+	}
+	switch meta.Thanos.Source {
+	case metadata.CompactorSource, metadata.CompactorRepairSource, metadata.BucketRepairSource:
+		return "", nil
+	}
+	return skipReasonTooFresh, nil
+}
+
+// deletionMark returns the deletion mark of the block or nil if the block is not marked for deletion. If indexed is
+// true, all deletion marks are known from the bucket index already.
+func (s *BucketStore) deletionMark(ctx context.Context, id ulid.ULID, indexed bool) (*metadata.DeletionMark, error) {
+	s.deletionMarksMtx.Lock()
+	m, ok := s.deletionMarks[id]
+	s.deletionMarksMtx.Unlock()
+	if ok || indexed {
+		return m, nil
+	}
+
+	// Check the existence first, as it is cheaper and can be cached by the caching bucket.
+	exists, err := s.bucket.Exists(ctx, path.Join(id.String(), metadata.DeletionMarkFilename))
+	if err != nil {
+		return nil, errors.Wrap(err, "check deletion mark exists")
+	}
+	if !exists {
+		return nil, nil
+	}
+	m, err = block.ReadDeletionMark(ctx, s.logger, s.bucket, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "read deletion mark")
+	}
+	if m == nil {
+		return nil, nil
+	}
+
+	s.deletionMarksMtx.Lock()
+	s.deletionMarks[id] = m
+	s.deletionMarksMtx.Unlock()
+	return m, nil
+}
+
+func (s *BucketStore) isMetaSelected(meta *metadata.Meta) bool {
+	return s.isMetaInMinMaxRange(meta) && s.isMetaSelectedByRelabel(meta)
+}
+
+// isMetaSelectedByRelabel applies the relabel config to the external labels of the block. Blocks
+// for which relabeling drops the label set are not served.
+func (s *BucketStore) isMetaSelectedByRelabel(meta *metadata.Meta) bool {
+	if len(s.relabelConfig) == 0 {
+		return true
+	}
+	return relabel.Process(promlabels.FromMap(meta.Thanos.Labels), s.relabelConfig...) != nil
+}
+
+func (s *BucketStore) isMetaInMinMaxRange(meta *metadata.Meta) bool {
+	if s.filterConfig == nil {
+		return true
+	}
+	// Block max time is exclusive.
+	return meta.MaxTime > s.filterConfig.MinTime.PrometheusTimestamp() &&
+		meta.MinTime <= s.filterConfig.MaxTime.PrometheusTimestamp()
+}
+
 func (s *BucketStore) getBlock(id ulid.ULID) *bucketBlock {
 	s.mtx.RLock()
 	defer s.mtx.RUnlock()
@@ -405,6 +927,9 @@ func (s *BucketStore) addBlock(ctx context.Context, id ulid.ULID) (err error) {
 		s.indexCache,
 		s.chunkPool,
 		s.partitioner,
+		s.lazyIndexHeader,
+		s.postingsCompression,
+		s.metrics,
 	)
 	if err != nil {
 		return errors.Wrap(err, "new bucket block")
@@ -452,6 +977,77 @@ func (s *BucketStore) removeBlock(id ulid.ULID) error {
 	return os.RemoveAll(b.dir)
 }
 
+// UnloadIdleIndexHeaders releases the in-memory index-headers of blocks that were not queried for longer than
+// the index-header idle timeout. It is a no-op unless lazy index-headers are enabled.
+func (s *BucketStore) UnloadIdleIndexHeaders() {
+	if !s.lazyIndexHeader || s.indexHeaderIdleTimeout <= 0 {
+		return
+	}
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	for _, b := range s.blocks {
+		if b.unloadIndexHeaderIfIdle(s.indexHeaderIdleTimeout) {
+			level.Debug(s.logger).Log("msg", "unloaded idle index-header", "block", b.meta.ULID)
+		}
+	}
+}
+
+// WarmUpIndexHeaders loads the index-headers of all blocks with data newer than the given period before now into
+// memory, so the first queries after a restart do not pay for loading them. It is a no-op unless lazy index-headers
+// are enabled, as all index-headers are loaded with their blocks otherwise. Blocks failing to load their index-header
+// are left to load it lazily.
+func (s *BucketStore) WarmUpIndexHeaders(ctx context.Context, period time.Duration) error {
+	if !s.lazyIndexHeader || period <= 0 {
+		return nil
+	}
+
+	mint := timestamp.FromTime(time.Now().Add(-period))
+
+	var wg sync.WaitGroup
+	blockc := make(chan *bucketBlock)
+
+	for i := 0; i < s.blockSyncConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for b := range blockc {
+				if _, err := b.acquireIndexHeader(ctx); err != nil {
+					level.Warn(s.logger).Log("msg", "warming up index-header failed", "id", b.meta.ULID, "err", err)
+					continue
+				}
+				b.releaseIndexHeader()
+			}
+		}()
+	}
+
+	s.mtx.RLock()
+	var blocks []*bucketBlock
+	for _, b := range s.blocks {
+		if b.meta.MaxTime >= mint {
+			blocks = append(blocks, b)
+		}
+	}
+	s.mtx.RUnlock()
+
+	for _, b := range blocks {
+		select {
+		case <-ctx.Done():
+		case blockc <- b:
+		}
+	}
+	close(blockc)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	level.Info(s.logger).Log("msg", "warmed up index-headers", "blocks", len(blocks), "period", period)
+	return nil
+}
+
 // TimeRange returns the minimum and maximum timestamp of data available in the store.
 func (s *BucketStore) TimeRange() (mint, maxt int64) {
 	s.mtx.RLock()
@@ -468,6 +1064,16 @@ func (s *BucketStore) TimeRange() (mint, maxt int64) {
 			maxt = b.meta.MaxTime
 		}
 	}
+
+	// Advertise only the configured time range, even if blocks loaded for it reach beyond.
+	if s.filterConfig != nil {
+		if filterMint := s.filterConfig.MinTime.PrometheusTimestamp(); mint < filterMint {
+			mint = filterMint
+		}
+		if filterMaxt := s.filterConfig.MaxTime.PrometheusTimestamp(); maxt > filterMaxt {
+			maxt = filterMaxt
+		}
+	}
 	return mint, maxt
 }
 
@@ -488,59 +1094,68 @@ type seriesEntry struct {
 	chks []storepb.AggrChunk
 }
 
-type bucketSeriesSet struct {
-	set []seriesEntry
-	i   int
-	err error
-}
+// blockSeriesSet is a storepb.SeriesSet of the series of a single block matching the given postings. Series and
+// their chunks are loaded from the bucket in batches while iterating, so that only a single batch per block is
+// held in memory at once.
+type blockSeriesSet struct {
+	extLset  map[string]string
+	indexr   *bucketIndexReader
+	chunkr   *bucketChunkReader
+	req      *storepb.SeriesRequest
+	limiters *seriesLimiters
 
-func newBucketSeriesSet(set []seriesEntry) *bucketSeriesSet {
-	return &bucketSeriesSet{
-		set: set,
-		i:   -1,
-	}
+	postings  []uint64
+	batchSize int
+	// copyChunks is set if series are loaded in more than one batch. Chunk data of a batch is then copied out of
+	// the pooled buffers, which are reused for the next batch while the merge may still reference the older series.
+	copyChunks bool
+
+	batch []seriesEntry
+	i     int
+	err   error
 }
 
-func (s *bucketSeriesSet) Next() bool {
-	if s.i >= len(s.set)-1 {
+func (s *blockSeriesSet) Next() bool {
+	if s.err != nil {
 		return false
 	}
 	s.i++
+	for s.i >= len(s.batch) {
+		if len(s.postings) == 0 {
+			return false
+		}
+		if s.err = s.loadBatch(); s.err != nil {
+			return false
+		}
+		s.i = 0
+	}
 	return true
 }
 
-func (s *bucketSeriesSet) At() ([]storepb.Label, []storepb.AggrChunk) {
-	return s.set[s.i].lset, s.set[s.i].chks
+func (s *blockSeriesSet) At() ([]storepb.Label, []storepb.AggrChunk) {
+	return s.batch[s.i].lset, s.batch[s.i].chks
 }
 
-func (s *bucketSeriesSet) Err() error {
+func (s *blockSeriesSet) Err() error {
 	return s.err
 }
 
-func blockSeries(
-	ctx context.Context,
-	ulid ulid.ULID,
-	extLset map[string]string,
-	indexr *bucketIndexReader,
-	chunkr *bucketChunkReader,
-	matchers []labels.Matcher,
-	req *storepb.SeriesRequest,
-	samplesLimiter *Limiter,
-) (storepb.SeriesSet, *queryStats, error) {
-	ps, err := indexr.ExpandedPostings(matchers)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "expanded matching posting")
+// loadBatch loads the series and chunks of the next batch of postings.
+func (s *blockSeriesSet) loadBatch() error {
+	n := s.batchSize
+	if n > len(s.postings) {
+		n = len(s.postings)
 	}
+	ps := s.postings[:n]
+	s.postings = s.postings[n:]
 
-	if len(ps) == 0 {
-		return storepb.EmptySeriesSet(), indexr.stats, nil
-	}
+	// Release the data of the previous batch first.
+	s.indexr.resetLoadedSeries()
+	s.chunkr.reset()
 
-	// Preload all series index data.
-	// TODO(bwplotka): Consider not keeping all series in memory all the time.
-	// TODO(bwplotka): Do lazy loading in one step as `ExpandingPostings` method.
-	if err := indexr.PreloadSeries(ps); err != nil {
-		return nil, nil, errors.Wrap(err, "preload series")
+	// Preload the series index data of the batch.
+	if err := s.indexr.PreloadSeries(ps); err != nil {
+		return errors.Wrap(err, "preload series")
 	}
 
 	// Transform all series into the response types and mark their relevant chunks
@@ -551,10 +1166,10 @@ func blockSeries(
 		chks []chunks.Meta
 	)
 	for _, id := range ps {
-		if err := indexr.LoadedSeries(id, &lset, &chks); err != nil {
-			return nil, nil, errors.Wrap(err, "read series")
+		if err := s.indexr.LoadedSeries(id, &lset, &chks); err != nil {
+			return errors.Wrap(err, "read series")
 		}
-		s := seriesEntry{
+		e := seriesEntry{
 			lset: make([]storepb.Label, 0, len(lset)),
 			refs: make([]uint64, 0, len(chks)),
 			chks: make([]storepb.AggrChunk, 0, len(chks)),
@@ -562,65 +1177,135 @@ func blockSeries(
 		for _, l := range lset {
 			// Skip if the external labels of the block overrule the series' label.
 			// NOTE(fabxc): maybe move it to a prefixed version to still ensure uniqueness of series?
-			if extLset[l.Name] != "" {
+			if s.extLset[l.Name] != "" {
 				continue
 			}
-			s.lset = append(s.lset, storepb.Label{
+			e.lset = append(e.lset, storepb.Label{
 				Name:  l.Name,
 				Value: l.Value,
 			})
 		}
-		for ln, lv := range extLset {
-			s.lset = append(s.lset, storepb.Label{
+		for ln, lv := range s.extLset {
+			e.lset = append(e.lset, storepb.Label{
 				Name:  ln,
 				Value: lv,
 			})
 		}
-		sort.Slice(s.lset, func(i, j int) bool {
-			return s.lset[i].Name < s.lset[j].Name
+		sort.Slice(e.lset, func(i, j int) bool {
+			return e.lset[i].Name < e.lset[j].Name
 		})
+		if !s.req.ShardInfo.Matches(e.lset) {
+			continue
+		}
 
+		hasChunks := false
 		for _, meta := range chks {
-			if meta.MaxTime < req.MinTime {
+			if meta.MaxTime < s.req.MinTime {
 				continue
 			}
-			if meta.MinTime > req.MaxTime {
+			if meta.MinTime > s.req.MaxTime {
+				break
+			}
+			hasChunks = true
+			if s.req.SkipChunks {
+				// A single chunk in the requested time range is enough to return the series' labels.
 				break
 			}
 
-			if err := chunkr.addPreload(meta.Ref); err != nil {
-				return nil, nil, errors.Wrap(err, "add chunk preload")
+			if err := s.chunkr.addPreload(meta.Ref); err != nil {
+				return errors.Wrap(err, "add chunk preload")
 			}
-			s.chks = append(s.chks, storepb.AggrChunk{
+			e.chks = append(e.chks, storepb.AggrChunk{
 				MinTime: meta.MinTime,
 				MaxTime: meta.MaxTime,
 			})
-			s.refs = append(s.refs, meta.Ref)
+			e.refs = append(e.refs, meta.Ref)
 		}
-		if len(s.chks) > 0 {
-			res = append(res, s)
+		if hasChunks {
+			res = append(res, e)
 		}
 	}
+	if s.req.SkipChunks {
+		s.batch = res
+		return nil
+	}
 
 	// Preload all chunks that were marked in the previous stage.
-	if err := chunkr.preload(samplesLimiter); err != nil {
-		return nil, nil, errors.Wrap(err, "preload chunks")
+	if err := s.chunkr.preload(s.limiters); err != nil {
+		return errors.Wrap(err, "preload chunks")
 	}
 
 	// Transform all chunks into the response format.
-	for _, s := range res {
-		for i, ref := range s.refs {
-			chk, err := chunkr.Chunk(ref)
+	for _, e := range res {
+		for i, ref := range e.refs {
+			chk, err := s.chunkr.Chunk(ref)
 			if err != nil {
-				return nil, nil, errors.Wrap(err, "get chunk")
+				return errors.Wrap(err, "get chunk")
+			}
+			if err := populateChunk(&e.chks[i], chk, s.req.Aggregates); err != nil {
+				return errors.Wrap(err, "populate chunk")
 			}
-			if err := populateChunk(&s.chks[i], chk, req.Aggregates); err != nil {
-				return nil, nil, errors.Wrap(err, "populate chunk")
+			if s.copyChunks {
+				copyChunkData(&e.chks[i])
 			}
 		}
 	}
 
-	return newBucketSeriesSet(res), indexr.stats.merge(chunkr.stats), nil
+	s.batch = res
+	return nil
+}
+
+// blockSeries returns the series of the block matching the given matchers. The first batch of series is loaded
+// before returning, so that the first batches of all queried blocks can be loaded concurrently.
+func blockSeries(
+	ctx context.Context,
+	ulid ulid.ULID,
+	extLset map[string]string,
+	indexr *bucketIndexReader,
+	chunkr *bucketChunkReader,
+	matchers []labels.Matcher,
+	req *storepb.SeriesRequest,
+	limiters *seriesLimiters,
+	batchSize int,
+) (storepb.SeriesSet, error) {
+	ps, err := indexr.ExpandedPostings(matchers)
+	if err != nil {
+		return nil, errors.Wrap(err, "expanded matching posting")
+	}
+
+	if len(ps) == 0 {
+		return storepb.EmptySeriesSet(), nil
+	}
+
+	if err := limiters.series.Reserve(uint64(len(ps))); err != nil {
+		return nil, errors.Wrap(err, "exceeded series limit")
+	}
+
+	set := &blockSeriesSet{
+		extLset:    extLset,
+		indexr:     indexr,
+		chunkr:     chunkr,
+		req:        req,
+		limiters:   limiters,
+		postings:   ps,
+		batchSize:  batchSize,
+		copyChunks: len(ps) > batchSize,
+		i:          -1,
+	}
+	if err := set.loadBatch(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// copyChunkData replaces the data of all chunks of the given aggregated chunk with copies.
+func copyChunkData(c *storepb.AggrChunk) {
+	for _, chk := range []*storepb.Chunk{c.Raw, c.Count, c.Sum, c.Min, c.Max, c.Counter} {
+		if chk == nil {
+			continue
+		}
+		chk.Data = append([]byte(nil), chk.Data...)
+	}
 }
 
 func populateChunk(out *storepb.AggrChunk, in chunkenc.Chunk, aggrs []storepb.Aggr) error {
@@ -628,6 +1313,16 @@ func populateChunk(out *storepb.AggrChunk, in chunkenc.Chunk, aggrs []storepb.Ag
 		out.Raw = &storepb.Chunk{Type: storepb.Chunk_XOR, Data: in.Bytes()}
 		return nil
 	}
+	// Native histogram chunks can only be found in raw blocks, so they are passed through
+	// for clients able to decode them.
+	if in.Encoding() == downsample.ChunkEncHistogram {
+		out.Raw = &storepb.Chunk{Type: storepb.Chunk_HISTOGRAM, Data: in.Bytes()}
+		return nil
+	}
+	if in.Encoding() == downsample.ChunkEncFloatHistogram {
+		out.Raw = &storepb.Chunk{Type: storepb.Chunk_FLOAT_HISTOGRAM, Data: in.Bytes()}
+		return nil
+	}
 	if in.Encoding() != downsample.ChunkEncAggr {
 		return errors.Errorf("unsupported chunk encoding %d", in.Encoding())
 	}
@@ -707,11 +1402,21 @@ func debugFoundBlockSetOverview(logger log.Logger, mint, maxt, maxResolutionMill
 }
 
 // Series implements the storepb.StoreServer interface.
-func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_SeriesServer) (err error) {
+func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	if s.inflightSeries == nil {
+		return s.series(req, srv)
+	}
+	return s.inflightSeries.serve(req, srv, s.series)
+}
+
+func (s *BucketStore) series(req *storepb.SeriesRequest, srv storepb.Store_SeriesServer) (err error) {
 	{
 		span, _ := tracing.StartSpan(srv.Context(), "store_query_gate_ismyturn")
 		err := s.queryGate.IsMyTurn(srv.Context())
 		span.Finish()
+		if err == errGateTimeout {
+			return status.Error(codes.ResourceExhausted, errors.Wrap(err, "failed to wait for turn").Error())
+		}
 		if err != nil {
 			return errors.Wrapf(err, "failed to wait for turn")
 		}
@@ -722,12 +1427,31 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 	if err != nil {
 		return status.Error(codes.InvalidArgument, err.Error())
 	}
+	// Readers must stay usable after the first batches are loaded, so they get a context which is canceled only
+	// on error or when the request is done.
+	ctx, cancel := context.WithCancel(srv.Context())
+	defer cancel()
+
 	var (
 		stats = &queryStats{}
-		g     run.Group
 		res   []storepb.SeriesSet
 		mtx   sync.Mutex
+		// The group waits for all blocks and returns the first error, so that no error is lost if other blocks
+		// finish successfully earlier.
+		g       errgroup.Group
+		readers []*bucketIndexReader
+		chunkrs []*bucketChunkReader
+		// Fetches of all blocks, started once the query is admitted by the memory budget.
+		fetches   []func() error
+		estimated uint64
 	)
+	limiters := &seriesLimiters{
+		samples: NewLimiter(s.maxSampleCount, s.metrics.queriesDropped.WithLabelValues("samples")),
+		series:  NewLimiter(s.maxSeriesCount, s.metrics.queriesDropped.WithLabelValues("series")),
+		chunks:  NewLimiter(s.maxChunkCount, s.metrics.queriesDropped.WithLabelValues("chunks")),
+		bytes:   NewLimiter(s.maxFetchedBytes, s.metrics.queriesDropped.WithLabelValues("bytes")),
+	}
+
 	s.mtx.RLock()
 
 	for _, bs := range s.blockSets {
@@ -745,57 +1469,86 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 			stats.blocksQueried++
 
 			b := b
-			ctx, cancel := context.WithCancel(srv.Context())
 
 			// We must keep the readers open until all their data has been sent.
-			indexr := b.indexReader(ctx)
-			chunkr := b.chunkReader(ctx)
+			indexr, err := b.indexReader(ctx, limiters.bytes)
+			if err != nil {
+				s.mtx.RUnlock()
+				return status.Error(codes.Internal, errors.Wrapf(err, "get index reader for block %s", b.meta.ULID).Error())
+			}
+			chunkr := b.chunkReader(ctx, limiters.bytes)
 
 			// Defer all closes to the end of Series method.
 			defer runutil.CloseWithLogOnErr(s.logger, indexr, "series block")
 			defer runutil.CloseWithLogOnErr(s.logger, chunkr, "series block")
+			readers = append(readers, indexr)
+			chunkrs = append(chunkrs, chunkr)
+
+			if s.memoryBudget != nil {
+				estimated += indexr.estimateSeriesBytes(blockMatchers, req.MinTime, req.MaxTime, req.SkipChunks)
+			}
 
-			g.Add(func() error {
-				part, pstats, err := blockSeries(ctx,
+			fetches = append(fetches, func() error {
+				part, err := blockSeries(ctx,
 					b.meta.ULID,
 					b.meta.Thanos.Labels,
 					indexr,
 					chunkr,
 					blockMatchers,
 					req,
-					s.samplesLimiter,
+					limiters,
+					s.seriesBatchSize,
 				)
 				if err != nil {
+					// Stop loading other blocks, the request fails anyway.
+					cancel()
 					return errors.Wrapf(err, "fetch series for block %s", b.meta.ULID)
 				}
 
 				mtx.Lock()
 				res = append(res, part)
-				stats = stats.merge(pstats)
 				mtx.Unlock()
 
 				return nil
-			}, func(err error) {
-				if err != nil {
-					cancel()
-				}
 			})
 		}
 	}
 
 	s.mtx.RUnlock()
 
+	if s.memoryBudget != nil && estimated > 0 {
+		span, _ := tracing.StartSpan(srv.Context(), "store_memory_budget_acquire")
+		err := s.memoryBudget.acquire(ctx, estimated)
+		span.Finish()
+		if err != nil {
+			if _, ok := err.(memoryBudgetExceededError); ok {
+				return status.Error(codes.ResourceExhausted, err.Error())
+			}
+			return errors.Wrap(err, "wait for memory budget")
+		}
+		defer s.memoryBudget.release(estimated)
+	}
+	for _, f := range fetches {
+		g.Go(f)
+	}
+
 	defer func() {
-		s.metrics.seriesDataTouched.WithLabelValues("postings").Observe(float64(stats.postingsTouched))
-		s.metrics.seriesDataFetched.WithLabelValues("postings").Observe(float64(stats.postingsFetched))
+		// Series are loaded while merging, so the stats of the readers are complete only at the end.
+		for i := range readers {
+			blockStats := readers[i].stats.merge(chunkrs[i].stats)
+			observeDataStats(s.metrics.blockDataTouched, s.metrics.blockDataFetched, blockStats)
+
+			stats = stats.merge(blockStats)
+		}
+		observeDataStats(s.metrics.seriesDataTouched, s.metrics.seriesDataFetched, stats)
+		s.metrics.seriesFetchDuration.WithLabelValues("postings").Observe(stats.postingsFetchDurationSum.Seconds())
+		s.metrics.seriesFetchDuration.WithLabelValues("series").Observe(stats.seriesFetchDurationSum.Seconds())
+		s.metrics.seriesFetchDuration.WithLabelValues("chunks").Observe(stats.chunksFetchDurationSum.Seconds())
+
 		s.metrics.seriesDataSizeTouched.WithLabelValues("postings").Observe(float64(stats.postingsTouchedSizeSum))
 		s.metrics.seriesDataSizeFetched.WithLabelValues("postings").Observe(float64(stats.postingsFetchedSizeSum))
-		s.metrics.seriesDataTouched.WithLabelValues("series").Observe(float64(stats.seriesTouched))
-		s.metrics.seriesDataFetched.WithLabelValues("series").Observe(float64(stats.seriesFetched))
 		s.metrics.seriesDataSizeTouched.WithLabelValues("series").Observe(float64(stats.seriesTouchedSizeSum))
 		s.metrics.seriesDataSizeFetched.WithLabelValues("series").Observe(float64(stats.seriesFetchedSizeSum))
-		s.metrics.seriesDataTouched.WithLabelValues("chunks").Observe(float64(stats.chunksTouched))
-		s.metrics.seriesDataFetched.WithLabelValues("chunks").Observe(float64(stats.chunksFetched))
 		s.metrics.seriesDataSizeTouched.WithLabelValues("chunks").Observe(float64(stats.chunksTouchedSizeSum))
 		s.metrics.seriesDataSizeFetched.WithLabelValues("chunks").Observe(float64(stats.chunksFetchedSizeSum))
 		s.metrics.resultSeriesCount.Observe(float64(stats.mergedSeriesCount))
@@ -808,11 +1561,15 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 	{
 		span, _ := tracing.StartSpan(srv.Context(), "bucket_store_preload_all")
 		begin := time.Now()
-		err := g.Run()
+		err := g.Wait()
 		span.Finish()
 
 		if err != nil {
-			return status.Error(codes.Aborted, err.Error())
+			code := codes.Aborted
+			if isLimitExceeded(err) {
+				code = codes.ResourceExhausted
+			}
+			return status.Error(code, err.Error())
 		}
 		stats.getAllDuration = time.Since(begin)
 		s.metrics.seriesGetAllDuration.Observe(stats.getAllDuration.Seconds())
@@ -836,21 +1593,64 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 
 			stats.mergedSeriesCount++
 			stats.mergedChunksCount += len(series.Chunks)
-			s.metrics.chunkSizeBytes.Observe(float64(chunksSize(series.Chunks)))
+			if !req.SkipChunks {
+				s.metrics.chunkSizeBytes.Observe(float64(chunksSize(series.Chunks)))
+			}
 
 			if err := srv.Send(storepb.NewSeriesResponse(&series)); err != nil {
 				return status.Error(codes.Unknown, errors.Wrap(err, "send series response").Error())
 			}
 		}
 		if set.Err() != nil {
-			return status.Error(codes.Unknown, errors.Wrap(set.Err(), "expand series set").Error())
+			code := codes.Unknown
+			if isLimitExceeded(set.Err()) {
+				code = codes.ResourceExhausted
+			}
+			return status.Error(code, errors.Wrap(set.Err(), "expand series set").Error())
 		}
 		stats.mergeDuration = time.Since(begin)
 		s.metrics.seriesMergeDuration.Observe(stats.mergeDuration.Seconds())
 	}
+
+	if w := s.staleBlocksWarning(); w != nil {
+		if err := srv.Send(storepb.NewWarnSeriesResponse(w)); err != nil {
+			return status.Error(codes.Unknown, errors.Wrap(err, "send warning response").Error())
+		}
+	}
+	if req.Hints {
+		if err := srv.Send(storepb.NewHintsSeriesResponse(queryHints(readers, chunkrs))); err != nil {
+			return status.Error(codes.Unknown, errors.Wrap(err, "send hints response").Error())
+		}
+	}
 	return nil
 }
 
+// queryHints returns the hints with the statistics of all blocks queried with the given readers.
+func queryHints(readers []*bucketIndexReader, chunkrs []*bucketChunkReader) *storepb.SeriesHints {
+	hints := &storepb.SeriesHints{}
+	for i := range readers {
+		stats := readers[i].stats.merge(chunkrs[i].stats)
+		hints.QueriedBlocks = append(hints.QueriedBlocks, storepb.QueriedBlock{
+			Id:              readers[i].block.meta.ULID.String(),
+			PostingsTouched: int64(stats.postingsTouched),
+			SeriesTouched:   int64(stats.seriesTouched),
+			ChunksTouched:   int64(stats.chunksTouched),
+			FetchedBytes:    int64(stats.postingsFetchedSizeSum + stats.seriesFetchedSizeSum + stats.chunksFetchedSizeSum),
+		})
+	}
+	return hints
+}
+
+// observeDataStats observes the numbers of touched and fetched postings, series and chunks of the stats.
+func observeDataStats(touched, fetched *prometheus.HistogramVec, stats *queryStats) {
+	touched.WithLabelValues("postings").Observe(float64(stats.postingsTouched))
+	fetched.WithLabelValues("postings").Observe(float64(stats.postingsFetched))
+	touched.WithLabelValues("series").Observe(float64(stats.seriesTouched))
+	fetched.WithLabelValues("series").Observe(float64(stats.seriesFetched))
+	touched.WithLabelValues("chunks").Observe(float64(stats.chunksTouched))
+	fetched.WithLabelValues("chunks").Observe(float64(stats.chunksFetched))
+}
+
 func chunksSize(chks []storepb.AggrChunk) (size int) {
 	for _, chk := range chks {
 		size += chk.Size() // This gets the encoded proto size.
@@ -859,22 +1659,52 @@ func chunksSize(chks []storepb.AggrChunk) (size int) {
 }
 
 // LabelNames implements the storepb.StoreServer interface.
-func (s *BucketStore) LabelNames(ctx context.Context, _ *storepb.LabelNamesRequest) (*storepb.LabelNamesResponse, error) {
+func (s *BucketStore) LabelNames(ctx context.Context, req *storepb.LabelNamesRequest) (*storepb.LabelNamesResponse, error) {
+	matchers, err := translateMatchers(req.Matchers)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	g, gctx := errgroup.WithContext(ctx)
+	bytesLimiter := NewLimiter(s.maxFetchedBytes, s.metrics.queriesDropped.WithLabelValues("bytes"))
 
 	s.mtx.RLock()
 
 	var mtx sync.Mutex
 	var sets [][]string
 
-	for _, b := range s.blocks {
-		indexr := b.indexReader(gctx)
+	for b, blockMatchers := range s.labelQueryBlocks(req.MinTime, req.MaxTime, matchers) {
+		b, blockMatchers := b, blockMatchers
 		g.Go(func() error {
+			indexr, err := b.indexReader(gctx, bytesLimiter)
+			if err != nil {
+				return errors.Wrapf(err, "get index reader for block %s", b.meta.ULID)
+			}
 			defer runutil.CloseWithLogOnErr(s.logger, indexr, "label names")
 
-			res := indexr.LabelNames()
+			var res []string
+			if len(blockMatchers) == 0 {
+				res = indexr.LabelNames()
+			} else {
+				names := map[string]struct{}{}
+				err := blockMatchingSeries(indexr, blockMatchers, s.seriesBatchSize, func(lset labels.Labels) {
+					for _, l := range lset {
+						names[l.Name] = struct{}{}
+					}
+				})
+				if err != nil {
+					return errors.Wrapf(err, "get matching series for block %s", b.meta.ULID)
+				}
+				res = make([]string, 0, len(names))
+				for n := range names {
+					res = append(res, n)
+				}
+			}
 			sort.Strings(res)
 
+			if len(res) == 0 {
+				return nil
+			}
 			mtx.Lock()
 			sets = append(sets, res)
 			mtx.Unlock()
@@ -886,31 +1716,69 @@ func (s *BucketStore) LabelNames(ctx context.Context, _ *storepb.LabelNamesReque
 	s.mtx.RUnlock()
 
 	if err := g.Wait(); err != nil {
+		if isLimitExceeded(err) {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
-	return &storepb.LabelNamesResponse{
+	res := &storepb.LabelNamesResponse{
 		Names: strutil.MergeSlices(sets...),
-	}, nil
+	}
+	if w := s.staleBlocksWarning(); w != nil {
+		res.Warnings = append(res.Warnings, w.Error())
+	}
+	return res, nil
 }
 
 // LabelValues implements the storepb.StoreServer interface.
 func (s *BucketStore) LabelValues(ctx context.Context, req *storepb.LabelValuesRequest) (*storepb.LabelValuesResponse, error) {
+	matchers, err := translateMatchers(req.Matchers)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	g, gctx := errgroup.WithContext(ctx)
+	bytesLimiter := NewLimiter(s.maxFetchedBytes, s.metrics.queriesDropped.WithLabelValues("bytes"))
 
 	s.mtx.RLock()
 
 	var mtx sync.Mutex
 	var sets [][]string
 
-	for _, b := range s.blocks {
-		indexr := b.indexReader(gctx)
+	for b, blockMatchers := range s.labelQueryBlocks(req.MinTime, req.MaxTime, matchers) {
+		b, blockMatchers := b, blockMatchers
 		// TODO(fabxc): only aggregate chunk metas first and add a subsequent fetch stage
 		// where we consolidate requests.
 		g.Go(func() error {
+			indexr, err := b.indexReader(gctx, bytesLimiter)
+			if err != nil {
+				return errors.Wrapf(err, "get index reader for block %s", b.meta.ULID)
+			}
 			defer runutil.CloseWithLogOnErr(s.logger, indexr, "label values")
 
-			res := indexr.LabelValues(req.Label)
+			var res []string
+			if len(blockMatchers) == 0 {
+				res = indexr.LabelValues(req.Label)
+			} else {
+				values := map[string]struct{}{}
+				err := blockMatchingSeries(indexr, blockMatchers, s.seriesBatchSize, func(lset labels.Labels) {
+					if v := lset.Get(req.Label); v != "" {
+						values[v] = struct{}{}
+					}
+				})
+				if err != nil {
+					return errors.Wrapf(err, "get matching series for block %s", b.meta.ULID)
+				}
+				res = make([]string, 0, len(values))
+				for v := range values {
+					res = append(res, v)
+				}
+				sort.Strings(res)
+			}
 
+			if len(res) == 0 {
+				return nil
+			}
 			mtx.Lock()
 			sets = append(sets, res)
 			mtx.Unlock()
@@ -922,11 +1790,77 @@ func (s *BucketStore) LabelValues(ctx context.Context, req *storepb.LabelValuesR
 	s.mtx.RUnlock()
 
 	if err := g.Wait(); err != nil {
+		if isLimitExceeded(err) {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
 		return nil, status.Error(codes.Aborted, err.Error())
 	}
-	return &storepb.LabelValuesResponse{
+	res := &storepb.LabelValuesResponse{
 		Values: strutil.MergeSlices(sets...),
-	}, nil
+	}
+	if w := s.staleBlocksWarning(); w != nil {
+		res.Warnings = append(res.Warnings, w.Error())
+	}
+	return res, nil
+}
+
+// labelQueryBlocks returns the blocks overlapping the given time range whose external labels match the matchers,
+// together with the matchers that are left to be applied to their series. If both times are 0, all blocks overlap.
+// It must be called with the read lock held.
+func (s *BucketStore) labelQueryBlocks(mint, maxt int64, matchers []labels.Matcher) map[*bucketBlock][]labels.Matcher {
+	res := map[*bucketBlock][]labels.Matcher{}
+	for _, bs := range s.blockSets {
+		blockMatchers, ok := bs.labelMatchers(matchers...)
+		if !ok {
+			continue
+		}
+		bs.mtx.RLock()
+		for _, blocks := range bs.blocks {
+			for _, b := range blocks {
+				// Block max time is exclusive.
+				if (mint != 0 || maxt != 0) && (b.meta.MaxTime <= mint || b.meta.MinTime > maxt) {
+					continue
+				}
+				res[b] = blockMatchers
+			}
+		}
+		bs.mtx.RUnlock()
+	}
+	return res
+}
+
+// blockMatchingSeries calls f with the labels of every series of the block matching all matchers. Series are loaded
+// in batches of the given size.
+func blockMatchingSeries(indexr *bucketIndexReader, matchers []labels.Matcher, batchSize int, f func(labels.Labels)) error {
+	ps, err := indexr.ExpandedPostings(matchers)
+	if err != nil {
+		return errors.Wrap(err, "expanded matching posting")
+	}
+
+	var (
+		lset labels.Labels
+		chks []chunks.Meta
+	)
+	for len(ps) > 0 {
+		n := batchSize
+		if n > len(ps) {
+			n = len(ps)
+		}
+		batch := ps[:n]
+		ps = ps[n:]
+
+		indexr.resetLoadedSeries()
+		if err := indexr.PreloadSeries(batch); err != nil {
+			return errors.Wrap(err, "preload series")
+		}
+		for _, id := range batch {
+			if err := indexr.LoadedSeries(id, &lset, &chks); err != nil {
+				return errors.Wrap(err, "read series")
+			}
+			f(lset)
+		}
+	}
+	return nil
 }
 
 // bucketBlockSet holds all blocks of an equal label set. It internally splits
@@ -1052,6 +1986,14 @@ func (s *bucketBlockSet) labelMatchers(matchers ...labels.Matcher) ([]labels.Mat
 	return res, true
 }
 
+// seriesLimiters holds the limiters of a single Series call. They are shared by all blocks queried by the call.
+type seriesLimiters struct {
+	samples *Limiter
+	series  *Limiter
+	chunks  *Limiter
+	bytes   *Limiter
+}
+
 // bucketBlock represents a block that is located in a bucket. It holds intermediate
 // state for the block on local disk.
 type bucketBlock struct {
@@ -1060,13 +2002,7 @@ type bucketBlock struct {
 	meta       *metadata.Meta
 	dir        string
 	indexCache indexCache
-	chunkPool  *pool.BytesPool
-
-	indexVersion int
-	symbols      map[uint32]string
-	symbolsV2    map[string]struct{}
-	lvals        map[string][]string
-	postings     map[labels.Label]index.Range
+	chunkPool  pool.Bytes
 
 	id        ulid.ULID
 	chunkObjs []string
@@ -1074,6 +2010,26 @@ type bucketBlock struct {
 	pendingReaders sync.WaitGroup
 
 	partitioner partitioner
+	metrics     *bucketStoreMetrics
+	// postingsCompression is the encoding of the postings lists stored in the index cache.
+	postingsCompression string
+
+	// headerMtx protects the fields below. If lazyIndexHeader is set, header is loaded on first use and can be
+	// unloaded again when no index reader used it for a while.
+	headerMtx       sync.Mutex
+	lazyIndexHeader bool
+	header          *indexHeader
+	headerReaders   int
+	headerLastUsed  time.Time
+}
+
+// indexHeader holds the parts of the block index that are kept in memory to look up symbols, label values and
+// postings offsets without reading the index from the bucket.
+type indexHeader struct {
+	version  int
+	symbols  map[uint32]string
+	lvals    map[string][]string
+	postings map[labels.Label]index.Range
 }
 
 func newBucketBlock(
@@ -1083,23 +2039,34 @@ func newBucketBlock(
 	id ulid.ULID,
 	dir string,
 	indexCache indexCache,
-	chunkPool *pool.BytesPool,
+	chunkPool pool.Bytes,
 	p partitioner,
+	lazyIndexHeader bool,
+	postingsCompression string,
+	metrics *bucketStoreMetrics,
 ) (b *bucketBlock, err error) {
 	b = &bucketBlock{
-		logger:      logger,
-		bucket:      bkt,
-		id:          id,
-		indexCache:  indexCache,
-		chunkPool:   chunkPool,
-		dir:         dir,
-		partitioner: p,
-	}
-	if err = b.loadMeta(ctx, id); err != nil {
+		logger:              logger,
+		bucket:              bkt,
+		id:                  id,
+		indexCache:          indexCache,
+		chunkPool:           chunkPool,
+		dir:                 dir,
+		partitioner:         p,
+		metrics:             metrics,
+		postingsCompression: postingsCompression,
+		lazyIndexHeader:     lazyIndexHeader,
+	}
+	if b.meta, err = loadMeta(ctx, logger, bkt, dir, id); err != nil {
 		return nil, errors.Wrap(err, "load meta")
 	}
-	if err = b.loadIndexCacheFile(ctx); err != nil {
-		return nil, errors.Wrap(err, "load index cache")
+	if lazyIndexHeader {
+		// Only make sure the index-header is on disk, so the first query does not have to build it.
+		if err = b.ensureIndexHeaderFile(ctx); err != nil {
+			return nil, errors.Wrap(err, "build index-header")
+		}
+	} else if b.header, err = b.loadIndexHeader(ctx); err != nil {
+		return nil, errors.Wrap(err, "load index-header")
 	}
 	// Get object handles for all chunk files.
 	err = bkt.Iter(ctx, path.Join(id.String(), block.ChunksDirname), func(n string) error {
@@ -1116,73 +2083,120 @@ func (b *bucketBlock) indexFilename() string {
 	return path.Join(b.id.String(), block.IndexFilename)
 }
 
-func (b *bucketBlock) indexCacheFilename() string {
-	return path.Join(b.id.String(), block.IndexCacheFilename)
-}
-
-func (b *bucketBlock) loadMeta(ctx context.Context, id ulid.ULID) error {
+// loadMeta reads the meta.json of the block from the given local directory. If the directory does not exist yet,
+// the meta.json is downloaded from the bucket first.
+func loadMeta(ctx context.Context, logger log.Logger, bkt objstore.BucketReader, dir string, id ulid.ULID) (*metadata.Meta, error) {
 	// If we haven't seen the block before download the meta.json file.
-	if _, err := os.Stat(b.dir); os.IsNotExist(err) {
-		if err := os.MkdirAll(b.dir, 0777); err != nil {
-			return errors.Wrap(err, "create dir")
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return nil, errors.Wrap(err, "create dir")
 		}
 		src := path.Join(id.String(), block.MetaFilename)
 
-		if err := objstore.DownloadFile(ctx, b.logger, b.bucket, src, b.dir); err != nil {
-			return errors.Wrap(err, "download meta.json")
+		if err := objstore.DownloadFile(ctx, logger, bkt, src, dir); err != nil {
+			return nil, errors.Wrap(err, "download meta.json")
 		}
 	} else if err != nil {
-		return err
+		return nil, err
 	}
-	meta, err := metadata.Read(b.dir)
+	meta, err := metadata.Read(dir)
 	if err != nil {
-		return errors.Wrap(err, "read meta.json")
+		return nil, errors.Wrap(err, "read meta.json")
 	}
-	b.meta = meta
-	return nil
+	return meta, nil
 }
 
-func (b *bucketBlock) loadIndexCacheFile(ctx context.Context) (err error) {
-	cachefn := filepath.Join(b.dir, block.IndexCacheFilename)
-	if err = b.loadIndexCacheFileFromFile(ctx, cachefn); err == nil {
-		return nil
+func (b *bucketBlock) indexHeaderFilename() string {
+	return filepath.Join(b.dir, indexheader.BinaryFilename)
+}
+
+func (b *bucketBlock) loadIndexHeader(ctx context.Context) (*indexHeader, error) {
+	fn := b.indexHeaderFilename()
+	h, err := readIndexHeaderFile(b.logger, fn)
+	if err == nil {
+		return h, nil
 	}
 	if !os.IsNotExist(errors.Cause(err)) {
-		return errors.Wrap(err, "read index cache")
+		level.Warn(b.logger).Log("msg", "failed to read index-header from disk; rebuilding", "block", b.id, "err", err)
 	}
 
-	// Try to download index cache file from object store.
-	if err = objstore.DownloadFile(ctx, b.logger, b.bucket, b.indexCacheFilename(), cachefn); err == nil {
-		return b.loadIndexCacheFileFromFile(ctx, cachefn)
+	if err := b.writeIndexHeaderFile(ctx); err != nil {
+		return nil, err
+	}
+	h, err = readIndexHeaderFile(b.logger, fn)
+	return h, errors.Wrap(err, "read index-header")
+}
+
+// ensureIndexHeaderFile builds the index-header file from the index in the bucket unless it already exists on disk.
+func (b *bucketBlock) ensureIndexHeaderFile(ctx context.Context) error {
+	if _, err := os.Stat(b.indexHeaderFilename()); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return errors.Wrap(err, "stat index-header")
 	}
+	return b.writeIndexHeaderFile(ctx)
+}
 
-	if !b.bucket.IsObjNotFoundErr(errors.Cause(err)) {
-		return errors.Wrap(err, "download index cache file")
+func (b *bucketBlock) writeIndexHeaderFile(ctx context.Context) error {
+	// Remove index cache file left by older versions to free the disk space.
+	if err := os.RemoveAll(filepath.Join(b.dir, block.IndexCacheFilename)); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to remove old index cache file", "block", b.id, "err", err)
 	}
 
-	// No cache exists on disk yet, build it from the downloaded index and retry.
-	fn := filepath.Join(b.dir, block.IndexFilename)
+	// Build the index-header directly from the index in the bucket.
+	return errors.Wrap(indexheader.WriteBinary(ctx, b.logger, b.bucket, b.id, b.indexHeaderFilename()), "write index-header")
+}
 
-	if err := objstore.DownloadFile(ctx, b.logger, b.bucket, b.indexFilename(), fn); err != nil {
-		return errors.Wrap(err, "download index file")
+func readIndexHeaderFile(logger log.Logger, fn string) (*indexHeader, error) {
+	h := &indexHeader{}
+	var err error
+	h.version, h.symbols, h.lvals, h.postings, err = indexheader.ReadBinary(logger, fn)
+	if err != nil {
+		return nil, err
 	}
+	return h, nil
+}
 
-	defer func() {
-		if rerr := os.Remove(fn); rerr != nil {
-			level.Error(b.logger).Log("msg", "failed to remove temp index file", "path", fn, "err", rerr)
-		}
-	}()
+// acquireIndexHeader returns the in-memory index-header of the block, loading it first if needed. Every call must be
+// followed by releaseIndexHeader once the header is not used anymore.
+func (b *bucketBlock) acquireIndexHeader(ctx context.Context) (*indexHeader, error) {
+	b.headerMtx.Lock()
+	defer b.headerMtx.Unlock()
 
-	if err := block.WriteIndexCache(b.logger, fn, cachefn); err != nil {
-		return errors.Wrap(err, "write index cache")
+	if b.header == nil {
+		h, err := b.loadIndexHeader(ctx)
+		if err != nil {
+			b.metrics.indexHeaderLazyLoadFailures.Inc()
+			return nil, errors.Wrap(err, "lazy load index-header")
+		}
+		b.metrics.indexHeaderLazyLoads.Inc()
+		b.header = h
 	}
+	b.headerReaders++
+	b.headerLastUsed = time.Now()
+	return b.header, nil
+}
+
+func (b *bucketBlock) releaseIndexHeader() {
+	b.headerMtx.Lock()
+	defer b.headerMtx.Unlock()
 
-	return errors.Wrap(b.loadIndexCacheFileFromFile(ctx, cachefn), "read index cache")
+	b.headerReaders--
+	b.headerLastUsed = time.Now()
 }
 
-func (b *bucketBlock) loadIndexCacheFileFromFile(ctx context.Context, cache string) (err error) {
-	b.indexVersion, b.symbols, b.lvals, b.postings, err = block.ReadIndexCache(b.logger, cache)
-	return err
+// unloadIndexHeaderIfIdle drops the lazily loaded index-header if it was not used for longer than the given
+// duration and no reader is using it. It returns true if the header was unloaded.
+func (b *bucketBlock) unloadIndexHeaderIfIdle(idleTimeout time.Duration) bool {
+	b.headerMtx.Lock()
+	defer b.headerMtx.Unlock()
+
+	if !b.lazyIndexHeader || b.header == nil || b.headerReaders > 0 || time.Since(b.headerLastUsed) < idleTimeout {
+		return false
+	}
+	b.header = nil
+	b.metrics.indexHeaderLazyUnloads.Inc()
+	return true
 }
 
 func (b *bucketBlock) readIndexRange(ctx context.Context, off, length int64) ([]byte, error) {
@@ -1218,14 +2232,22 @@ func (b *bucketBlock) readChunkRange(ctx context.Context, seq int, off, length i
 	return buf.Bytes(), nil
 }
 
-func (b *bucketBlock) indexReader(ctx context.Context) *bucketIndexReader {
+// indexReader returns a reader of the block index. If bytesLimiter is not nil, the bytes the reader fetches from the
+// bucket are reserved with it.
+func (b *bucketBlock) indexReader(ctx context.Context, bytesLimiter *Limiter) (*bucketIndexReader, error) {
+	h, err := b.acquireIndexHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
 	b.pendingReaders.Add(1)
-	return newBucketIndexReader(ctx, b.logger, b, b.indexCache)
+	return newBucketIndexReader(ctx, b.logger, b, h, b.indexCache, bytesLimiter), nil
 }
 
-func (b *bucketBlock) chunkReader(ctx context.Context) *bucketChunkReader {
+// chunkReader returns a reader of the block chunks. If bytesLimiter is not nil, the bytes the reader fetches from the
+// bucket are reserved with it.
+func (b *bucketBlock) chunkReader(ctx context.Context, bytesLimiter *Limiter) *bucketChunkReader {
 	b.pendingReaders.Add(1)
-	return newBucketChunkReader(ctx, b)
+	return newBucketChunkReader(ctx, b, bytesLimiter)
 }
 
 // Close waits for all pending readers to finish and then closes all underlying resources.
@@ -1240,19 +2262,24 @@ type bucketIndexReader struct {
 	logger log.Logger
 	ctx    context.Context
 	block  *bucketBlock
+	header *indexHeader
 	dec    *index.Decoder
 	stats  *queryStats
 	cache  indexCache
 
+	bytesLimiter *Limiter
+
 	mtx          sync.Mutex
 	loadedSeries map[uint64][]byte
 }
 
-func newBucketIndexReader(ctx context.Context, logger log.Logger, block *bucketBlock, cache indexCache) *bucketIndexReader {
+func newBucketIndexReader(ctx context.Context, logger log.Logger, block *bucketBlock, header *indexHeader, cache indexCache, bytesLimiter *Limiter) *bucketIndexReader {
 	r := &bucketIndexReader{
+		bytesLimiter: bytesLimiter,
 		logger:       logger,
 		ctx:          ctx,
 		block:        block,
+		header:       header,
 		dec:          &index.Decoder{},
 		stats:        &queryStats{},
 		cache:        cache,
@@ -1263,7 +2290,7 @@ func newBucketIndexReader(ctx context.Context, logger log.Logger, block *bucketB
 }
 
 func (r *bucketIndexReader) lookupSymbol(o uint32) (string, error) {
-	s, ok := r.block.symbols[o]
+	s, ok := r.header.symbols[o]
 	if !ok {
 		return "", errors.Errorf("bucketIndexReader: unknown symbol offset %d", o)
 	}
@@ -1308,7 +2335,7 @@ func (r *bucketIndexReader) ExpandedPostings(ms []labels.Matcher) ([]uint64, err
 
 	// As of version two all series entries are 16 byte padded. All references
 	// we get have to account for that to get the correct offset.
-	if r.block.indexVersion >= 2 {
+	if r.header.version >= 2 {
 		for i, id := range ps {
 			ps[i] = id * 16
 		}
@@ -1391,6 +2418,31 @@ func toPostingGroup(lvalsFn func(name string) []string, m labels.Matcher) *posti
 		return newPostingGroup(labels.Labels{{Name: em.Name(), Value: em.Value()}}, merge)
 	}
 
+	// Fast-path for regexps matching a set of values, the postings of which are looked up directly.
+	if sm, ok := m.(*setMatcher); ok {
+		vals := make([]string, 0, len(sm.values))
+		for v := range sm.values {
+			vals = append(vals, v)
+		}
+		// Labels sort by name only, so the values must be sorted on their own.
+		sort.Strings(vals)
+		for _, v := range vals {
+			matchingLabels = append(matchingLabels, labels.Label{Name: sm.Name(), Value: v})
+		}
+		return newPostingGroup(matchingLabels, merge)
+	}
+
+	// Fast-path for prefix regexps. Label values are sorted, so the matching values are found with a binary search.
+	if pm, ok := m.(*prefixMatcher); ok {
+		vals := lvalsFn(pm.Name())
+		for i := sort.SearchStrings(vals, pm.prefix); i < len(vals) && strings.HasPrefix(vals[i], pm.prefix); i++ {
+			if pm.Matches(vals[i]) {
+				matchingLabels = append(matchingLabels, labels.Label{Name: pm.Name(), Value: vals[i]})
+			}
+		}
+		return newPostingGroup(matchingLabels, merge)
+	}
+
 	for _, val := range lvalsFn(m.Name()) {
 		if m.Matches(val) {
 			matchingLabels = append(matchingLabels, labels.Label{Name: m.Name(), Value: val})
@@ -1410,26 +2462,39 @@ type postingPtr struct {
 func (r *bucketIndexReader) fetchPostings(groups []*postingGroup) error {
 	var ptrs []postingPtr
 
-	// Iterate over all groups and fetch posting from cache.
+	// Fetch postings of all groups from the cache at once, so remote caches are asked in a single round trip.
+	var keys []labels.Label
+	for _, g := range groups {
+		keys = append(keys, g.keys...)
+	}
+	cached, _ := r.cache.FetchMultiPostings(r.ctx, r.block.meta.ULID, keys)
+
+	// Iterate over all groups and take postings from the cache hits.
 	// If we have a miss, mark key to be fetched in `ptrs` slice.
 	// Overlaps are well handled by partitioner, so we don't need to deduplicate keys.
 	for i, g := range groups {
 		for j, key := range g.keys {
 			// Get postings for the given key from cache first.
-			if b, ok := r.cache.Postings(r.block.meta.ULID, key); ok {
+			if b, ok := cached[key]; ok {
 				r.stats.postingsTouched++
 				r.stats.postingsTouchedSizeSum += len(b)
 
-				_, l, err := r.dec.Postings(b)
+				l, ok, err := decodePostings(b)
 				if err != nil {
-					return errors.Wrap(err, "decode postings")
+					return errors.Wrap(err, "decode encoded postings")
+				}
+				if !ok {
+					// Raw postings list of the index.
+					if _, l, err = r.dec.Postings(b); err != nil {
+						return errors.Wrap(err, "decode postings")
+					}
 				}
 				g.Fill(j, l)
 				continue
 			}
 
 			// Cache miss; save pointer for actual posting in index stored in object store.
-			ptr, ok := r.block.postings[key]
+			ptr, ok := r.header.postings[key]
 			if !ok {
 				// This block does not have any posting for given key.
 				g.Fill(j, index.EmptyPostings())
@@ -1451,9 +2516,8 @@ func (r *bucketIndexReader) fetchPostings(groups []*postingGroup) error {
 		return uint64(ptrs[i].ptr.Start), uint64(ptrs[i].ptr.End)
 	})
 
-	var g run.Group
+	g, ctx := errgroup.WithContext(r.ctx)
 	for _, part := range parts {
-		ctx, cancel := context.WithCancel(r.ctx)
 		i, j := part.elemRng[0], part.elemRng[1]
 
 		start := int64(part.start)
@@ -1461,7 +2525,10 @@ func (r *bucketIndexReader) fetchPostings(groups []*postingGroup) error {
 		length := int64(part.end) - start
 
 		// Fetch from object storage concurrently and update stats and posting list.
-		g.Add(func() error {
+		g.Go(func() error {
+			if err := r.bytesLimiter.Reserve(uint64(length)); err != nil {
+				return errors.Wrap(err, "exceeded fetched bytes limit")
+			}
 			begin := time.Now()
 
 			b, err := r.block.readIndexRange(ctx, start, length)
@@ -1487,60 +2554,62 @@ func (r *bucketIndexReader) fetchPostings(groups []*postingGroup) error {
 				}
 
 				// Return postings and fill LRU cache.
-				groups[p.groupID].Fill(p.keyID, fetchedPostings)
-				r.cache.SetPostings(r.block.meta.ULID, groups[p.groupID].keys[p.keyID], c)
+				if r.block.postingsCompression == PostingsCompressionNone {
+					groups[p.groupID].Fill(p.keyID, fetchedPostings)
+					r.cache.SetPostings(r.block.meta.ULID, groups[p.groupID].keys[p.keyID], c)
+				} else {
+					ps, err := index.ExpandPostings(fetchedPostings)
+					if err != nil {
+						return errors.Wrap(err, "expand postings list")
+					}
+					groups[p.groupID].Fill(p.keyID, index.NewListPostings(ps))
+
+					encoded, err := encodePostings(r.block.postingsCompression, ps)
+					if err != nil {
+						return errors.Wrap(err, "encode postings list")
+					}
+					r.cache.SetPostings(r.block.meta.ULID, groups[p.groupID].keys[p.keyID], encoded)
+				}
 
 				// If we just fetched it we still have to update the stats for touched postings.
 				r.stats.postingsTouched++
 				r.stats.postingsTouchedSizeSum += len(c)
 			}
 			return nil
-		}, func(err error) {
-			if err != nil {
-				cancel()
-			}
 		})
 	}
 
-	return g.Run()
+	return g.Wait()
 }
 
 func (r *bucketIndexReader) PreloadSeries(ids []uint64) error {
 	const maxSeriesSize = 64 * 1024
 
-	var newIDs []uint64
-
-	for _, id := range ids {
-		if b, ok := r.cache.Series(r.block.meta.ULID, id); ok {
-			r.loadedSeries[id] = b
-			continue
-		}
-		newIDs = append(newIDs, id)
+	cached, ids := r.cache.FetchMultiSeries(r.ctx, r.block.meta.ULID, ids)
+	for id, b := range cached {
+		r.loadedSeries[id] = b
 	}
-	ids = newIDs
 
 	parts := r.block.partitioner.Partition(len(ids), func(i int) (start, end uint64) {
 		return ids[i], ids[i] + maxSeriesSize
 	})
-	var g run.Group
+	g, ctx := errgroup.WithContext(r.ctx)
 
 	for _, p := range parts {
-		ctx, cancel := context.WithCancel(r.ctx)
 		s, e := p.start, p.end
 		i, j := p.elemRng[0], p.elemRng[1]
 
-		g.Add(func() error {
+		g.Go(func() error {
 			return r.loadSeries(ctx, ids[i:j], s, e)
-		}, func(err error) {
-			if err != nil {
-				cancel()
-			}
 		})
 	}
-	return g.Run()
+	return g.Wait()
 }
 
 func (r *bucketIndexReader) loadSeries(ctx context.Context, ids []uint64, start, end uint64) error {
+	if err := r.bytesLimiter.Reserve(end - start); err != nil {
+		return errors.Wrap(err, "exceeded fetched bytes limit")
+	}
 	begin := time.Now()
 
 	b, err := r.block.readIndexRange(ctx, int64(start), int64(end-start))
@@ -1590,11 +2659,14 @@ type partitioner interface {
 
 type gapBasedPartitioner struct {
 	maxGapSize uint64
+	// maxRangeSize is the maximum size of a combined range. 0 means no limit.
+	maxRangeSize uint64
 }
 
 // Partition partitions length entries into n <= length ranges that cover all
 // input ranges by combining entries that are separated by reasonably small gaps.
 // It is used to combine multiple small ranges from object storage into bigger, more efficient/cheaper ones.
+// Entries are not combined into ranges larger than the maximum range size, but single entries may be.
 func (g gapBasedPartitioner) Partition(length int, rng func(int) (uint64, uint64)) (parts []part) {
 	j := 0
 	k := 0
@@ -1612,6 +2684,9 @@ func (g gapBasedPartitioner) Partition(length int, rng func(int) (uint64, uint64
 			if p.end+g.maxGapSize < s {
 				break
 			}
+			if g.maxRangeSize > 0 && e > p.end && e-p.start > g.maxRangeSize {
+				break
+			}
 
 			if p.end <= e {
 				p.end = e
@@ -1623,6 +2698,14 @@ func (g gapBasedPartitioner) Partition(length int, rng func(int) (uint64, uint64
 	return parts
 }
 
+// resetLoadedSeries drops all series loaded by PreloadSeries so far.
+func (r *bucketIndexReader) resetLoadedSeries() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.loadedSeries = map[uint64][]byte{}
+}
+
 // LoadedSeries populates the given labels and chunk metas for the series identified
 // by the reference.
 // Returns ErrNotFound if the ref does not resolve to a known series.
@@ -1640,8 +2723,8 @@ func (r *bucketIndexReader) LoadedSeries(ref uint64, lset *labels.Labels, chks *
 
 // LabelValues returns label values for single name.
 func (r *bucketIndexReader) LabelValues(name string) []string {
-	res := make([]string, 0, len(r.block.lvals[name]))
-	for _, v := range r.block.lvals[name] {
+	res := make([]string, 0, len(r.header.lvals[name]))
+	for _, v := range r.header.lvals[name] {
 		res = append(res, v)
 	}
 	return res
@@ -1649,8 +2732,8 @@ func (r *bucketIndexReader) LabelValues(name string) []string {
 
 // LabelNames returns a list of label names.
 func (r *bucketIndexReader) LabelNames() []string {
-	res := make([]string, 0, len(r.block.lvals))
-	for ln, _ := range r.block.lvals {
+	res := make([]string, 0, len(r.header.lvals))
+	for ln, _ := range r.header.lvals {
 		res = append(res, ln)
 	}
 	return res
@@ -1658,6 +2741,7 @@ func (r *bucketIndexReader) LabelNames() []string {
 
 // Close released the underlying resources of the reader.
 func (r *bucketIndexReader) Close() error {
+	r.block.releaseIndexHeader()
 	r.block.pendingReaders.Done()
 	return nil
 }
@@ -1667,6 +2751,8 @@ type bucketChunkReader struct {
 	block *bucketBlock
 	stats *queryStats
 
+	bytesLimiter *Limiter
+
 	preloads [][]uint32
 	mtx      sync.Mutex
 	chunks   map[uint64]chunkenc.Chunk
@@ -1675,13 +2761,14 @@ type bucketChunkReader struct {
 	chunkBytes [][]byte
 }
 
-func newBucketChunkReader(ctx context.Context, block *bucketBlock) *bucketChunkReader {
+func newBucketChunkReader(ctx context.Context, block *bucketBlock, bytesLimiter *Limiter) *bucketChunkReader {
 	return &bucketChunkReader{
-		ctx:      ctx,
-		block:    block,
-		stats:    &queryStats{},
-		preloads: make([][]uint32, len(block.chunkObjs)),
-		chunks:   map[uint64]chunkenc.Chunk{},
+		ctx:          ctx,
+		block:        block,
+		stats:        &queryStats{},
+		preloads:     make([][]uint32, len(block.chunkObjs)),
+		chunks:       map[uint64]chunkenc.Chunk{},
+		bytesLimiter: bytesLimiter,
 	}
 }
 
@@ -1699,10 +2786,10 @@ func (r *bucketChunkReader) addPreload(id uint64) error {
 }
 
 // preload all added chunk IDs. Must be called before the first call to Chunk is made.
-func (r *bucketChunkReader) preload(samplesLimiter *Limiter) error {
+func (r *bucketChunkReader) preload(limiters *seriesLimiters) error {
 	const maxChunkSize = 16000
 
-	var g run.Group
+	g, ctx := errgroup.WithContext(r.ctx)
 
 	numChunks := uint64(0)
 	for _, offsets := range r.preloads {
@@ -1710,9 +2797,12 @@ func (r *bucketChunkReader) preload(samplesLimiter *Limiter) error {
 			numChunks++
 		}
 	}
-	if err := samplesLimiter.Check(numChunks * maxSamplesPerChunk); err != nil {
+	if err := limiters.samples.Reserve(numChunks * maxSamplesPerChunk); err != nil {
 		return errors.Wrap(err, "exceeded samples limit")
 	}
+	if err := limiters.chunks.Reserve(numChunks); err != nil {
+		return errors.Wrap(err, "exceeded chunks limit")
+	}
 
 	for seq, offsets := range r.preloads {
 		sort.Slice(offsets, func(i, j int) bool {
@@ -1726,23 +2816,21 @@ func (r *bucketChunkReader) preload(samplesLimiter *Limiter) error {
 		offsets := offsets
 
 		for _, p := range parts {
-			ctx, cancel := context.WithCancel(r.ctx)
 			s, e := uint32(p.start), uint32(p.end)
 			m, n := p.elemRng[0], p.elemRng[1]
 
-			g.Add(func() error {
+			g.Go(func() error {
 				return r.loadChunks(ctx, offsets[m:n], seq, s, e)
-			}, func(err error) {
-				if err != nil {
-					cancel()
-				}
 			})
 		}
 	}
-	return g.Run()
+	return g.Wait()
 }
 
 func (r *bucketChunkReader) loadChunks(ctx context.Context, offs []uint32, seq int, start, end uint32) error {
+	if err := r.bytesLimiter.Reserve(uint64(end - start)); err != nil {
+		return errors.Wrap(err, "exceeded fetched bytes limit")
+	}
 	begin := time.Now()
 
 	b, err := r.block.readChunkRange(ctx, seq, int64(start), int64(end-start))
@@ -1812,6 +2900,16 @@ func (b rawChunk) NumSamples() int {
 	panic("invalid call")
 }
 
+// reset releases the chunks loaded so far and prepares the reader for the next preload.
+func (r *bucketChunkReader) reset() {
+	for _, b := range r.chunkBytes {
+		r.block.chunkPool.Put(b)
+	}
+	r.chunkBytes = nil
+	r.preloads = make([][]uint32, len(r.block.chunkObjs))
+	r.chunks = map[uint64]chunkenc.Chunk{}
+}
+
 func (r *bucketChunkReader) Close() error {
 	r.block.pendingReaders.Done()
 
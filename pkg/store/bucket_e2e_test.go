@@ -1,9 +1,13 @@
 package store
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"io/ioutil"
+	"net"
 	"os"
+	"path"
 	"path/filepath"
 	"sync"
 	"testing"
@@ -14,23 +18,36 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/improbable-eng/thanos/pkg/block"
 	"github.com/improbable-eng/thanos/pkg/block/metadata"
+	"github.com/improbable-eng/thanos/pkg/compact/rewrite"
+	"github.com/improbable-eng/thanos/pkg/component"
 	"github.com/improbable-eng/thanos/pkg/objstore"
+	"github.com/improbable-eng/thanos/pkg/objstore/inmem"
 	"github.com/improbable-eng/thanos/pkg/objstore/objtesting"
 	"github.com/improbable-eng/thanos/pkg/runutil"
 	storecache "github.com/improbable-eng/thanos/pkg/store/cache"
 	"github.com/improbable-eng/thanos/pkg/store/storepb"
 	"github.com/improbable-eng/thanos/pkg/testutil"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/prometheus/pkg/timestamp"
 	"github.com/prometheus/tsdb/labels"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type noopCache struct{}
 
-func (noopCache) SetPostings(b ulid.ULID, l labels.Label, v []byte)   {}
-func (noopCache) Postings(b ulid.ULID, l labels.Label) ([]byte, bool) { return nil, false }
-func (noopCache) SetSeries(b ulid.ULID, id uint64, v []byte)          {}
-func (noopCache) Series(b ulid.ULID, id uint64) ([]byte, bool)        { return nil, false }
+func (noopCache) SetPostings(b ulid.ULID, l labels.Label, v []byte) {}
+func (noopCache) FetchMultiPostings(_ context.Context, _ ulid.ULID, keys []labels.Label) (map[labels.Label][]byte, []labels.Label) {
+	return map[labels.Label][]byte{}, keys
+}
+func (noopCache) SetSeries(b ulid.ULID, id uint64, v []byte) {}
+func (noopCache) FetchMultiSeries(_ context.Context, _ ulid.ULID, ids []uint64) (map[uint64][]byte, []uint64) {
+	return map[uint64][]byte{}, ids
+}
 
 type swappableCache struct {
 	ptr indexCache
@@ -44,16 +61,16 @@ func (c *swappableCache) SetPostings(b ulid.ULID, l labels.Label, v []byte) {
 	c.ptr.SetPostings(b, l, v)
 }
 
-func (c *swappableCache) Postings(b ulid.ULID, l labels.Label) ([]byte, bool) {
-	return c.ptr.Postings(b, l)
+func (c *swappableCache) FetchMultiPostings(ctx context.Context, b ulid.ULID, keys []labels.Label) (map[labels.Label][]byte, []labels.Label) {
+	return c.ptr.FetchMultiPostings(ctx, b, keys)
 }
 
 func (c *swappableCache) SetSeries(b ulid.ULID, id uint64, v []byte) {
 	c.ptr.SetSeries(b, id, v)
 }
 
-func (c *swappableCache) Series(b ulid.ULID, id uint64) ([]byte, bool) {
-	return c.ptr.Series(b, id)
+func (c *swappableCache) FetchMultiSeries(ctx context.Context, b ulid.ULID, ids []uint64) (map[uint64][]byte, []uint64) {
+	return c.ptr.FetchMultiSeries(ctx, b, ids)
 }
 
 type storeSuite struct {
@@ -72,7 +89,7 @@ func (s *storeSuite) Close() {
 	s.wg.Wait()
 }
 
-func prepareStoreWithTestBlocks(t testing.TB, dir string, bkt objstore.Bucket, manyParts bool, maxSampleCount uint64) *storeSuite {
+func prepareStoreWithTestBlocks(t testing.TB, dir string, bkt objstore.Bucket, manyParts bool, maxSampleCount uint64, lazyIndexHeader bool) *storeSuite {
 	series := []labels.Labels{
 		labels.FromStrings("a", "1", "b", "1"),
 		labels.FromStrings("a", "1", "b", "2"),
@@ -128,7 +145,15 @@ func prepareStoreWithTestBlocks(t testing.TB, dir string, bkt objstore.Bucket, m
 		testutil.Ok(t, os.RemoveAll(dir2))
 	}
 
-	store, err := NewBucketStore(s.logger, nil, bkt, dir, s.cache, 0, maxSampleCount, 20, false, 20)
+	conf := DefaultBucketStoreConfig(dir)
+	conf.MaxSampleCount = maxSampleCount
+	conf.MaxConcurrent = 20
+	conf.LazyIndexHeader = lazyIndexHeader
+	conf.IndexHeaderIdleTimeout = time.Millisecond
+	conf.PostingsCompression = PostingsCompressionDiffVarintSnappy
+	conf.RangeMaxGapSize = 512 * 1024
+	conf.RangeMaxSize = 16 * 1024
+	store, err := NewBucketStore(s.logger, nil, bkt, s.cache, conf)
 	testutil.Ok(t, err)
 
 	s.store = store
@@ -171,6 +196,68 @@ func testBucketStore_e2e(t testing.TB, ctx context.Context, s *storeSuite) {
 	testutil.Ok(t, err)
 	testutil.Equals(t, []string{"1", "2"}, vals.Values)
 
+	for i, tcase := range []struct {
+		req      *storepb.LabelValuesRequest
+		expected []string
+	}{
+		{
+			req: &storepb.LabelValuesRequest{Label: "a", Matchers: []storepb.LabelMatcher{
+				{Type: storepb.LabelMatcher_EQ, Name: "a", Value: "1"},
+				{Type: storepb.LabelMatcher_EQ, Name: "c", Value: "2"},
+			}},
+			expected: []string{"1"},
+		},
+		{
+			req: &storepb.LabelValuesRequest{Label: "c", Matchers: []storepb.LabelMatcher{
+				{Type: storepb.LabelMatcher_NEQ, Name: "ext2", Value: "value2"},
+			}},
+			expected: nil,
+		},
+		{
+			req:      &storepb.LabelValuesRequest{Label: "a", MinTime: mint, MaxTime: mint + 1},
+			expected: []string{"1", "2"},
+		},
+		{
+			req:      &storepb.LabelValuesRequest{Label: "a", MinTime: maxt + 1, MaxTime: maxt + 1000},
+			expected: nil,
+		},
+	} {
+		t.Log("Run label values", i)
+
+		vals, err := s.store.LabelValues(ctx, tcase.req)
+		testutil.Ok(t, err)
+		testutil.Equals(t, tcase.expected, vals.Values)
+	}
+
+	for i, tcase := range []struct {
+		req      *storepb.LabelNamesRequest
+		expected []string
+	}{
+		{
+			req:      &storepb.LabelNamesRequest{},
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			req: &storepb.LabelNamesRequest{Matchers: []storepb.LabelMatcher{
+				{Type: storepb.LabelMatcher_RE, Name: "c", Value: ".+"},
+			}},
+			expected: []string{"a", "c"},
+		},
+		{
+			// External labels select whole blocks.
+			req: &storepb.LabelNamesRequest{Matchers: []storepb.LabelMatcher{
+				{Type: storepb.LabelMatcher_EQ, Name: "ext1", Value: "value1"},
+			}},
+			expected: []string{"a", "b"},
+		},
+	} {
+		t.Log("Run label names", i)
+
+		names, err := s.store.LabelNames(ctx, tcase.req)
+		testutil.Ok(t, err)
+		testutil.Equals(t, tcase.expected, names.Names)
+	}
+
 	// TODO(bwplotka): Add those test cases to TSDB querier_test.go as well, there are no tests for matching.
 	for i, tcase := range []struct {
 		req      *storepb.SeriesRequest
@@ -359,7 +446,66 @@ func testBucketStore_e2e(t testing.TB, ctx context.Context, s *storeSuite) {
 			testutil.Equals(t, tcase.expected[i], s.Labels)
 			testutil.Equals(t, 3, len(s.Chunks))
 		}
+		testutil.Equals(t, 0, len(srv.Hints))
+
+		// The same series are returned without any chunks if chunks are skipped.
+		skipChunksReq := *tcase.req
+		skipChunksReq.SkipChunks = true
+		srv = newStoreSeriesServer(ctx)
+
+		testutil.Ok(t, s.store.Series(&skipChunksReq, srv))
+		testutil.Equals(t, len(tcase.expected), len(srv.SeriesSet))
+
+		for i, s := range srv.SeriesSet {
+			testutil.Equals(t, tcase.expected[i], s.Labels)
+			testutil.Equals(t, 0, len(s.Chunks))
+		}
 	}
+
+	// Hints list all queried blocks.
+	blockObservations := histogramSampleCount(t, s.store.metrics.blockDataTouched, "series")
+	queryObservations := histogramSampleCount(t, s.store.metrics.seriesDataTouched, "series")
+	srv := newStoreSeriesServer(ctx)
+	testutil.Ok(t, s.store.Series(&storepb.SeriesRequest{
+		Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "a", Value: "1"}},
+		MinTime:  mint,
+		MaxTime:  maxt,
+		Hints:    true,
+	}, srv))
+	testutil.Equals(t, 1, len(srv.Hints))
+	testutil.Equals(t, len(s.store.blocks), len(srv.Hints[0].QueriedBlocks))
+
+	var seriesTouched int64
+	for _, b := range srv.Hints[0].QueriedBlocks {
+		_, err := ulid.Parse(b.Id)
+		testutil.Ok(t, err)
+		seriesTouched += b.SeriesTouched
+	}
+	testutil.Assert(t, seriesTouched >= int64(len(srv.SeriesSet)), "touched %d series for %d series returned", seriesTouched, len(srv.SeriesSet))
+
+	// Touched data is observed per block and per query.
+	testutil.Equals(t, blockObservations+uint64(len(s.store.blocks)), histogramSampleCount(t, s.store.metrics.blockDataTouched, "series"))
+	testutil.Equals(t, queryObservations+1, histogramSampleCount(t, s.store.metrics.seriesDataTouched, "series"))
+
+	// No chunks are touched if chunks are skipped.
+	srv = newStoreSeriesServer(ctx)
+	testutil.Ok(t, s.store.Series(&storepb.SeriesRequest{
+		Matchers:   []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "a", Value: "1"}},
+		MinTime:    mint,
+		MaxTime:    maxt,
+		Hints:      true,
+		SkipChunks: true,
+	}, srv))
+	testutil.Equals(t, 1, len(srv.Hints))
+	for _, b := range srv.Hints[0].QueriedBlocks {
+		testutil.Equals(t, int64(0), b.ChunksTouched)
+	}
+}
+
+func histogramSampleCount(t testing.TB, h *prometheus.HistogramVec, lvs ...string) uint64 {
+	m := &dto.Metric{}
+	testutil.Ok(t, h.WithLabelValues(lvs...).(prometheus.Histogram).Write(m))
+	return m.GetHistogram().GetSampleCount()
 }
 
 func TestBucketStore_e2e(t *testing.T) {
@@ -371,7 +517,7 @@ func TestBucketStore_e2e(t *testing.T) {
 		testutil.Ok(t, err)
 		defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
 
-		s := prepareStoreWithTestBlocks(t, dir, bkt, false, 0)
+		s := prepareStoreWithTestBlocks(t, dir, bkt, false, 0, false)
 		defer s.Close()
 
 		t.Log("Test with no index cache")
@@ -395,6 +541,419 @@ func TestBucketStore_e2e(t *testing.T) {
 		testutil.Ok(t, err)
 		s.cache.SwapWith(indexCache2)
 		testBucketStore_e2e(t, ctx, s)
+
+		t.Log("Test with series loaded in batches")
+		s.cache.SwapWith(noopCache{})
+		s.store.seriesBatchSize = 1
+		testBucketStore_e2e(t, ctx, s)
+	})
+}
+
+func TestBucketStore_LazyIndexHeader_e2e(t *testing.T) {
+	objtesting.ForeachStore(t, func(t testing.TB, bkt objstore.Bucket) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		dir, err := ioutil.TempDir("", "test_bucketstore_e2e")
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+		s := prepareStoreWithTestBlocks(t, dir, bkt, false, 0, true)
+		defer s.Close()
+		s.cache.SwapWith(noopCache{})
+
+		loadedHeaders := func() (n int) {
+			s.store.mtx.RLock()
+			defer s.store.mtx.RUnlock()
+
+			for _, b := range s.store.blocks {
+				b.headerMtx.Lock()
+				if b.header != nil {
+					n++
+				}
+				b.headerMtx.Unlock()
+			}
+			return n
+		}
+
+		// No index-header is loaded before the first query.
+		testutil.Equals(t, 0, loadedHeaders())
+
+		testBucketStore_e2e(t, ctx, s)
+		testutil.Equals(t, 6, loadedHeaders())
+
+		// Warming up loads the index-headers of all blocks with recent data without any query.
+		time.Sleep(10 * time.Millisecond)
+		s.store.UnloadIdleIndexHeaders()
+		testutil.Equals(t, 0, loadedHeaders())
+		testutil.Ok(t, s.store.WarmUpIndexHeaders(ctx, 0))
+		testutil.Equals(t, 0, loadedHeaders())
+		testutil.Ok(t, s.store.WarmUpIndexHeaders(ctx, time.Hour))
+		testutil.Equals(t, 6, loadedHeaders())
+
+		time.Sleep(10 * time.Millisecond)
+		s.store.UnloadIdleIndexHeaders()
+		testutil.Equals(t, 0, loadedHeaders())
+
+		// Unloaded index-headers are loaded again on the next query.
+		testBucketStore_e2e(t, ctx, s)
+		testutil.Equals(t, 6, loadedHeaders())
+	})
+}
+
+func TestBucketStore_TimePartitioning_e2e(t *testing.T) {
+	objtesting.ForeachStore(t, func(t testing.TB, bkt objstore.Bucket) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		dir, err := ioutil.TempDir("", "test_bucketstore_e2e")
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+		s := prepareStoreWithTestBlocks(t, dir, bkt, false, 0, false)
+		defer s.Close()
+
+		// Serve only the last of the three 2h time slots.
+		minTime := timestamp.Time(s.maxTime).Add(-time.Hour)
+		maxTime := timestamp.Time(s.maxTime)
+		filterConf := &FilterConfig{}
+		filterConf.MinTime.Time = &minTime
+		filterConf.MaxTime.Time = &maxTime
+
+		filteredDir, err := ioutil.TempDir("", "test_bucketstore_e2e_filtered")
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, os.RemoveAll(filteredDir)) }()
+
+		conf := DefaultBucketStoreConfig(filteredDir)
+		conf.MaxConcurrent = 20
+		conf.FilterConfig = filterConf
+		store, err := NewBucketStore(s.logger, nil, bkt, noopCache{}, conf)
+		testutil.Ok(t, err)
+		testutil.Ok(t, store.SyncBlocks(ctx))
+		defer func() { testutil.Ok(t, store.Close()) }()
+
+		testutil.Equals(t, 2, store.numBlocks())
+
+		mint, maxt := store.TimeRange()
+		testutil.Equals(t, timestamp.FromTime(minTime), mint)
+		testutil.Equals(t, s.maxTime, maxt)
+
+		resp, err := store.Info(ctx, &storepb.InfoRequest{})
+		testutil.Ok(t, err)
+		testutil.Equals(t, timestamp.FromTime(minTime), resp.MinTime)
+		testutil.Equals(t, s.maxTime, resp.MaxTime)
+	})
+}
+
+func TestBucketStore_RelabelSelection_e2e(t *testing.T) {
+	objtesting.ForeachStore(t, func(t testing.TB, bkt objstore.Bucket) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		dir, err := ioutil.TempDir("", "test_bucketstore_e2e")
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+		s := prepareStoreWithTestBlocks(t, dir, bkt, false, 0, false)
+		defer s.Close()
+
+		relabelConf, err := rewrite.ParseRelabelConfig([]byte(`
+- action: keep
+  source_labels: ["ext2"]
+  regex: "value2"
+`))
+		testutil.Ok(t, err)
+
+		selectedDir, err := ioutil.TempDir("", "test_bucketstore_e2e_selected")
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, os.RemoveAll(selectedDir)) }()
+
+		conf := DefaultBucketStoreConfig(selectedDir)
+		conf.MaxConcurrent = 20
+		conf.RelabelConfig = relabelConf
+		store, err := NewBucketStore(s.logger, nil, bkt, noopCache{}, conf)
+		testutil.Ok(t, err)
+		testutil.Ok(t, store.SyncBlocks(ctx))
+		defer func() { testutil.Ok(t, store.Close()) }()
+
+		// Only the blocks with the ext2 external label are selected.
+		testutil.Equals(t, 3, store.numBlocks())
+		for _, b := range store.blocks {
+			testutil.Equals(t, "value2", b.meta.Thanos.Labels["ext2"])
+		}
+	})
+}
+
+func TestBucketStore_DeletionMarksAndConsistencyDelay_e2e(t *testing.T) {
+	objtesting.ForeachStore(t, func(t testing.TB, bkt objstore.Bucket) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		dir, err := ioutil.TempDir("", "test_bucketstore_e2e")
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+		s := prepareStoreWithTestBlocks(t, dir, bkt, false, 0, false)
+		defer s.Close()
+
+		var ids []ulid.ULID
+		for id := range s.store.blocks {
+			ids = append(ids, id)
+		}
+
+		syncedDir, err := ioutil.TempDir("", "test_bucketstore_e2e_synced")
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, os.RemoveAll(syncedDir)) }()
+
+		conf := DefaultBucketStoreConfig(syncedDir)
+		conf.MaxConcurrent = 20
+		conf.IgnoreDeletionMarksDelay = time.Hour
+		store, err := NewBucketStore(s.logger, nil, bkt, noopCache{}, conf)
+		testutil.Ok(t, err)
+		testutil.Ok(t, store.SyncBlocks(ctx))
+		defer func() { testutil.Ok(t, store.Close()) }()
+		testutil.Equals(t, 6, store.numBlocks())
+
+		// A block marked recently is still served, so queries in flight can finish.
+		testutil.Ok(t, block.MarkForDeletion(ctx, s.logger, bkt, ids[0]))
+		testutil.Ok(t, store.SyncBlocks(ctx))
+		testutil.Equals(t, 6, store.numBlocks())
+
+		// Once the mark is older than the delay, the block is dropped.
+		mark, err := json.Marshal(metadata.DeletionMark{
+			ID:           ids[1],
+			DeletionTime: time.Now().Add(-2 * time.Hour).Unix(),
+			Version:      metadata.DeletionMarkVersion1,
+		})
+		testutil.Ok(t, err)
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(ids[1].String(), metadata.DeletionMarkFilename), bytes.NewReader(mark)))
+		testutil.Ok(t, store.SyncBlocks(ctx))
+		testutil.Equals(t, 5, store.numBlocks())
+		testutil.Assert(t, store.getBlock(ids[1]) == nil, "expected block marked for deletion to be dropped")
+		testutil.Equals(t, 1.0, promtest.ToFloat64(store.metrics.blocksSkipped.WithLabelValues(skipReasonMarkedForDeletion)))
+
+		// None of the test blocks were created by the compactor, so all of them are too fresh to be served.
+		freshDir, err := ioutil.TempDir("", "test_bucketstore_e2e_fresh")
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, os.RemoveAll(freshDir)) }()
+
+		conf.Dir = freshDir
+		conf.ConsistencyDelay = time.Hour
+		freshStore, err := NewBucketStore(s.logger, nil, bkt, noopCache{}, conf)
+		testutil.Ok(t, err)
+		testutil.Ok(t, freshStore.SyncBlocks(ctx))
+		defer func() { testutil.Ok(t, freshStore.Close()) }()
+		testutil.Equals(t, 0, freshStore.numBlocks())
+		testutil.Equals(t, 5.0, promtest.ToFloat64(freshStore.metrics.blocksSkipped.WithLabelValues(skipReasonTooFresh)))
+	})
+}
+
+func TestBucketStore_BucketIndex_e2e(t *testing.T) {
+	objtesting.ForeachStore(t, func(t testing.TB, bkt objstore.Bucket) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		dir, err := ioutil.TempDir("", "test_bucketstore_e2e")
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+		s := prepareStoreWithTestBlocks(t, dir, bkt, false, 0, false)
+		defer s.Close()
+
+		var ids []ulid.ULID
+		for id := range s.store.blocks {
+			ids = append(ids, id)
+		}
+
+		indexedDir, err := ioutil.TempDir("", "test_bucketstore_e2e_indexed")
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, os.RemoveAll(indexedDir)) }()
+
+		conf := DefaultBucketStoreConfig(indexedDir)
+		conf.MaxConcurrent = 20
+		conf.BucketIndexMaxStalePeriod = time.Hour
+		store, err := NewBucketStore(s.logger, nil, bkt, noopCache{}, conf)
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, store.Close()) }()
+
+		// Without bucket index the bucket is iterated.
+		testutil.Ok(t, store.SyncBlocks(ctx))
+		testutil.Equals(t, 6, store.numBlocks())
+		testutil.Equals(t, 1.0, promtest.ToFloat64(store.metrics.bucketIndexFallbacks))
+
+		updater := block.NewBucketIndexUpdater(s.logger, bkt)
+		_, err = updater.Update(ctx)
+		testutil.Ok(t, err)
+		testutil.Ok(t, store.SyncBlocks(ctx))
+		testutil.Equals(t, 6, store.numBlocks())
+		testutil.Equals(t, 1.0, promtest.ToFloat64(store.metrics.bucketIndexLoads))
+
+		// Deletion marks are taken from the bucket index only.
+		mark, err := json.Marshal(metadata.DeletionMark{
+			ID:           ids[0],
+			DeletionTime: time.Now().Add(-48 * time.Hour).Unix(),
+			Version:      metadata.DeletionMarkVersion1,
+		})
+		testutil.Ok(t, err)
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(ids[0].String(), metadata.DeletionMarkFilename), bytes.NewReader(mark)))
+		testutil.Ok(t, store.SyncBlocks(ctx))
+		testutil.Equals(t, 6, store.numBlocks())
+
+		idx, err := updater.Update(ctx)
+		testutil.Ok(t, err)
+		testutil.Equals(t, 6, len(idx.Blocks))
+		testutil.Equals(t, 1, len(idx.DeletionMarks))
+		testutil.Ok(t, store.SyncBlocks(ctx))
+		testutil.Equals(t, 5, store.numBlocks())
+		testutil.Assert(t, store.getBlock(ids[0]) == nil, "expected block marked for deletion in bucket index to be dropped")
+
+		// A stale bucket index is not used.
+		idx.UpdatedAt = time.Now().Add(-2 * time.Hour).Unix()
+		testutil.Ok(t, block.WriteBucketIndex(ctx, bkt, idx))
+		testutil.Ok(t, store.SyncBlocks(ctx))
+		testutil.Equals(t, 5, store.numBlocks())
+		testutil.Equals(t, 2.0, promtest.ToFloat64(store.metrics.bucketIndexFallbacks))
+	})
+}
+
+// unreachableBucket fails to list the bucket if unreachable is set.
+type unreachableBucket struct {
+	objstore.Bucket
+	unreachable bool
+}
+
+func (b *unreachableBucket) Iter(ctx context.Context, dir string, f func(string) error) error {
+	if b.unreachable {
+		return errors.New("connection refused")
+	}
+	return b.Bucket.Iter(ctx, dir, f)
+}
+
+func TestBucketStore_UnreachableBucket_e2e(t *testing.T) {
+	objtesting.ForeachStore(t, func(t testing.TB, bkt objstore.Bucket) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		dir, err := ioutil.TempDir("", "test_bucketstore_e2e")
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+		s := prepareStoreWithTestBlocks(t, dir, bkt, false, 0, false)
+		defer s.Close()
+
+		cachedDir, err := ioutil.TempDir("", "test_bucketstore_e2e_cached")
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, os.RemoveAll(cachedDir)) }()
+
+		ubkt := &unreachableBucket{Bucket: bkt}
+		conf := DefaultBucketStoreConfig(cachedDir)
+		conf.MaxConcurrent = 20
+		store, err := NewBucketStore(s.logger, nil, ubkt, noopCache{}, conf)
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, store.Close()) }()
+		testutil.Ok(t, store.InitialSync(ctx))
+		testutil.Equals(t, 6, store.numBlocks())
+		testutil.Equals(t, 0.0, promtest.ToFloat64(store.metrics.blocksStale))
+
+		// Loaded blocks are kept and responses warn about them being stale.
+		ubkt.unreachable = true
+		testutil.NotOk(t, store.SyncBlocks(ctx))
+		testutil.Equals(t, 6, store.numBlocks())
+		testutil.Equals(t, 1.0, promtest.ToFloat64(store.metrics.blocksStale))
+
+		resp, err := store.LabelValues(ctx, &storepb.LabelValuesRequest{Label: "a"})
+		testutil.Ok(t, err)
+		testutil.Equals(t, []string{"1", "2"}, resp.Values)
+		testutil.Equals(t, 1, len(resp.Warnings))
+
+		srv := newStoreSeriesServer(ctx)
+		testutil.Ok(t, store.Series(&storepb.SeriesRequest{
+			Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "a", Value: "1"}},
+			MinTime:  s.minTime,
+			MaxTime:  s.maxTime,
+		}, srv))
+		testutil.Equals(t, 1, len(srv.Warnings))
+
+		ubkt.unreachable = false
+		testutil.Ok(t, store.SyncBlocks(ctx))
+		testutil.Equals(t, 0.0, promtest.ToFloat64(store.metrics.blocksStale))
+		resp, err = store.LabelValues(ctx, &storepb.LabelValuesRequest{Label: "a"})
+		testutil.Ok(t, err)
+		testutil.Equals(t, 0, len(resp.Warnings))
+	})
+}
+
+func TestBucketStore_SeriesLimits_e2e(t *testing.T) {
+	objtesting.ForeachStore(t, func(t testing.TB, bkt objstore.Bucket) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		dir, err := ioutil.TempDir("", "test_bucketstore_e2e")
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+		s := prepareStoreWithTestBlocks(t, dir, bkt, false, 0, false)
+		defer s.Close()
+
+		req := &storepb.SeriesRequest{
+			Matchers: []storepb.LabelMatcher{
+				{Type: storepb.LabelMatcher_RE, Name: "a", Value: "1|2"},
+			},
+			MinTime: s.minTime,
+			MaxTime: s.maxTime,
+		}
+
+		// All 6 blocks hold 4 series with a single chunk each. Limits have to hold across all batches of a block, so some
+		// cases load a single series per batch.
+		for i, tcase := range []struct {
+			maxSamples, maxSeries, maxChunks, maxFetchedBytes, memoryBudget uint64
+			seriesBatchSize                                                 int
+			expectErr                                                       bool
+		}{
+			{},
+			{maxSamples: 24 * maxSamplesPerChunk, maxSeries: 24, maxChunks: 24, maxFetchedBytes: 1e6, memoryBudget: 1e6},
+			{maxSamples: 24 * maxSamplesPerChunk, maxSeries: 24, maxChunks: 24, seriesBatchSize: 1},
+			{maxSamples: 24*maxSamplesPerChunk - 1, expectErr: true},
+			{maxSamples: 24*maxSamplesPerChunk - 1, seriesBatchSize: 1, expectErr: true},
+			{maxSeries: 23, expectErr: true},
+			{maxSeries: 23, seriesBatchSize: 1, expectErr: true},
+			{maxChunks: 23, expectErr: true},
+			{maxChunks: 23, seriesBatchSize: 1, expectErr: true},
+			{maxFetchedBytes: 1, expectErr: true},
+			{memoryBudget: 1, expectErr: true},
+		} {
+			t.Log("Run ", i)
+
+			limitedDir, err := ioutil.TempDir("", "test_bucketstore_e2e_limited")
+			testutil.Ok(t, err)
+			defer func() { testutil.Ok(t, os.RemoveAll(limitedDir)) }()
+
+			conf := DefaultBucketStoreConfig(limitedDir)
+			conf.MaxSampleCount = tcase.maxSamples
+			conf.MaxSeriesCount = tcase.maxSeries
+			conf.MaxChunkCount = tcase.maxChunks
+			conf.MaxFetchedBytes = tcase.maxFetchedBytes
+			conf.MemoryBudget = tcase.memoryBudget
+			conf.MaxConcurrent = 20
+			store, err := NewBucketStore(s.logger, nil, bkt, noopCache{}, conf)
+			testutil.Ok(t, err)
+			if tcase.seriesBatchSize > 0 {
+				store.seriesBatchSize = tcase.seriesBatchSize
+			}
+			testutil.Ok(t, store.SyncBlocks(ctx))
+
+			srv := newStoreSeriesServer(ctx)
+			err = store.Series(req, srv)
+			testutil.Ok(t, store.Close())
+
+			if !tcase.expectErr {
+				testutil.Ok(t, err)
+				testutil.Equals(t, 8, len(srv.SeriesSet))
+				continue
+			}
+			testutil.NotOk(t, err)
+			testutil.Equals(t, codes.ResourceExhausted, status.Code(err))
+		}
 	})
 }
 
@@ -420,7 +979,7 @@ func TestBucketStore_ManyParts_e2e(t *testing.T) {
 		testutil.Ok(t, err)
 		defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
 
-		s := prepareStoreWithTestBlocks(t, dir, bkt, true, 0)
+		s := prepareStoreWithTestBlocks(t, dir, bkt, true, 0, false)
 		defer s.Close()
 
 		indexCache, err := storecache.NewIndexCache(s.logger, nil, storecache.Opts{
@@ -433,3 +992,75 @@ func TestBucketStore_ManyParts_e2e(t *testing.T) {
 		testBucketStore_e2e(t, ctx, s)
 	})
 }
+
+func TestProxyStore_LabelValuesCache_ExternalLabels_e2e(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "test_proxystore_e2e")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	logger := log.NewNopLogger()
+	bkt := inmem.NewBucket()
+	mint := timestamp.FromTime(time.Now())
+	maxt := mint + int64(2*time.Hour/time.Millisecond)
+	id, err := testutil.CreateBlock(ctx, dir, []labels.Labels{labels.FromStrings("a", "1")}, 10, mint, maxt, labels.FromStrings("cluster", "eu"), 0)
+	testutil.Ok(t, err)
+	testutil.Ok(t, block.Upload(ctx, logger, bkt, filepath.Join(dir, id.String())))
+
+	conf := DefaultBucketStoreConfig(filepath.Join(dir, "store"))
+	conf.MaxConcurrent = 20
+	bs, err := NewBucketStore(logger, nil, bkt, noopCache{}, conf)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, bs.Close()) }()
+	testutil.Ok(t, bs.SyncBlocks(ctx))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	testutil.Ok(t, err)
+	srv := grpc.NewServer()
+	storepb.RegisterStoreServer(srv, bs)
+	go func() { _ = srv.Serve(listener) }()
+	defer srv.Stop()
+
+	conn, err := grpc.DialContext(ctx, listener.Addr().String(), grpc.WithInsecure())
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, conn.Close()) }()
+
+	st := &testClient{
+		StoreClient: storepb.NewStoreClient(conn),
+		labels:      []storepb.Label{{Name: "cluster", Value: "eu"}},
+		minTime:     mint,
+		maxTime:     maxt,
+	}
+	q := NewProxyStore(logger,
+		func() []Client { return []Client{st} },
+		component.Query,
+		nil,
+		0*time.Second,
+		nil, 0, false, time.Minute,
+		"", "",
+	)
+
+	// Stores do not return the values of their external labels, so their cached values must not exclude the store.
+	_, err = q.LabelValues(ctx, &storepb.LabelValuesRequest{Label: "cluster"})
+	testutil.Ok(t, err)
+
+	s := newStoreSeriesServer(ctx)
+	testutil.Ok(t, q.Series(&storepb.SeriesRequest{
+		MinTime: mint,
+		MaxTime: maxt,
+		Matchers: []storepb.LabelMatcher{
+			{Type: storepb.LabelMatcher_EQ, Name: "cluster", Value: "eu"},
+			{Type: storepb.LabelMatcher_EQ, Name: "a", Value: "1"},
+		},
+	}, s))
+	testutil.Equals(t, 1, len(s.SeriesSet))
+
+	resp, err := q.LabelValues(ctx, &storepb.LabelValuesRequest{
+		Label:    "a",
+		Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "cluster", Value: "eu"}},
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"1"}, resp.Values)
+}
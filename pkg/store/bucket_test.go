@@ -16,6 +16,7 @@ import (
 	"github.com/leanovate/gopter/gen"
 	"github.com/leanovate/gopter/prop"
 	"github.com/oklog/ulid"
+	"github.com/prometheus/tsdb/chunkenc"
 	"github.com/prometheus/tsdb/labels"
 )
 
@@ -414,6 +415,40 @@ func TestGapBasedPartitioner_Partition(t *testing.T) {
 	}
 }
 
+func TestGapBasedPartitioner_MaxRangeSize(t *testing.T) {
+	p := gapBasedPartitioner{maxGapSize: 10, maxRangeSize: 20}
+
+	for _, c := range []struct {
+		input    [][2]int
+		expected []part
+	}{
+		{
+			input:    [][2]int{{0, 5}, {8, 15}, {16, 20}},
+			expected: []part{{start: 0, end: 20, elemRng: [2]int{0, 3}}},
+		},
+		{
+			input: [][2]int{{0, 5}, {8, 15}, {16, 21}, {22, 30}},
+			expected: []part{
+				{start: 0, end: 15, elemRng: [2]int{0, 2}},
+				{start: 16, end: 30, elemRng: [2]int{2, 4}},
+			},
+		},
+		// Overlapping ranges are always combined and single ranges may be larger than the maximum.
+		{
+			input: [][2]int{{0, 50}, {10, 20}, {45, 60}},
+			expected: []part{
+				{start: 0, end: 50, elemRng: [2]int{0, 2}},
+				{start: 45, end: 60, elemRng: [2]int{2, 3}},
+			},
+		},
+	} {
+		res := p.Partition(len(c.input), func(i int) (uint64, uint64) {
+			return uint64(c.input[i][0]), uint64(c.input[i][1])
+		})
+		testutil.Equals(t, c.expected, res)
+	}
+}
+
 func TestBucketStore_Info(t *testing.T) {
 	defer leaktest.CheckTimeout(t, 10*time.Second)()
 
@@ -423,7 +458,7 @@ func TestBucketStore_Info(t *testing.T) {
 	dir, err := ioutil.TempDir("", "prometheus-test")
 	testutil.Ok(t, err)
 
-	bucketStore, err := NewBucketStore(nil, nil, nil, dir, noopCache{}, 2e5, 0, 0, false, 20)
+	bucketStore, err := NewBucketStore(nil, nil, nil, noopCache{}, DefaultBucketStoreConfig(dir))
 	testutil.Ok(t, err)
 
 	resp, err := bucketStore.Info(ctx, &storepb.InfoRequest{})
@@ -433,3 +468,15 @@ func TestBucketStore_Info(t *testing.T) {
 	testutil.Equals(t, int64(math.MaxInt64), resp.MinTime)
 	testutil.Equals(t, int64(math.MinInt64), resp.MaxTime)
 }
+
+func TestPopulateChunk_NativeHistograms(t *testing.T) {
+	for enc, typ := range map[chunkenc.Encoding]storepb.Chunk_Encoding{
+		chunkenc.EncXOR:                   storepb.Chunk_XOR,
+		downsample.ChunkEncHistogram:      storepb.Chunk_HISTOGRAM,
+		downsample.ChunkEncFloatHistogram: storepb.Chunk_FLOAT_HISTOGRAM,
+	} {
+		var out storepb.AggrChunk
+		testutil.Ok(t, populateChunk(&out, rawChunk([]byte{byte(enc), 1, 2}), []storepb.Aggr{storepb.Aggr_RAW}))
+		testutil.Equals(t, &storepb.Chunk{Type: typ, Data: []byte{1, 2}}, out.Raw)
+	}
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/tsdb/index"
+	"github.com/prometheus/tsdb/labels"
+)
+
+// Rough average sizes of a series entry in the index and of an encoded chunk, used to estimate the memory of a query.
+const (
+	estimatedSeriesSize = 256
+	estimatedChunkSize  = 512
+)
+
+// memoryBudgetExceededError is returned if a query does not fit into the memory budget.
+type memoryBudgetExceededError struct {
+	estimated, budget uint64
+	queued            bool
+}
+
+func (e memoryBudgetExceededError) Error() string {
+	if e.queued {
+		return fmt.Sprintf("estimated %d bytes of the query do not fit into the memory budget of %d bytes in time", e.estimated, e.budget)
+	}
+	return fmt.Sprintf("estimated %d bytes of the query exceed the memory budget of %d bytes", e.estimated, e.budget)
+}
+
+// memoryBudget limits the total estimated memory of all in-flight queries. Queries not fitting into the budget wait
+// until enough memory is released by other queries, at most for the queue timeout.
+type memoryBudget struct {
+	mtx      sync.Mutex
+	capacity uint64
+	used     uint64
+	// released is closed and replaced whenever memory is released.
+	released chan struct{}
+
+	// queueTimeout is the maximum time a query waits for memory. 0 means no limit.
+	queueTimeout time.Duration
+
+	usedBytes prometheus.Gauge
+	rejected  prometheus.Counter
+}
+
+func newMemoryBudget(capacity uint64, queueTimeout time.Duration, usedBytes prometheus.Gauge, rejected prometheus.Counter) *memoryBudget {
+	return &memoryBudget{
+		capacity:     capacity,
+		released:     make(chan struct{}),
+		queueTimeout: queueTimeout,
+		usedBytes:    usedBytes,
+		rejected:     rejected,
+	}
+}
+
+// acquire reserves n bytes of the budget, waiting for other queries to release memory if needed.
+func (b *memoryBudget) acquire(ctx context.Context, n uint64) error {
+	if n > b.capacity {
+		b.rejected.Inc()
+		return memoryBudgetExceededError{estimated: n, budget: b.capacity}
+	}
+
+	var timeout <-chan time.Time
+	if b.queueTimeout > 0 {
+		t := time.NewTimer(b.queueTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	for {
+		b.mtx.Lock()
+		if b.used+n <= b.capacity {
+			b.used += n
+			b.usedBytes.Set(float64(b.used))
+			b.mtx.Unlock()
+			return nil
+		}
+		released := b.released
+		b.mtx.Unlock()
+
+		select {
+		case <-released:
+		case <-timeout:
+			b.rejected.Inc()
+			return memoryBudgetExceededError{estimated: n, budget: b.capacity, queued: true}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release returns n bytes acquired before to the budget.
+func (b *memoryBudget) release(n uint64) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.used -= n
+	b.usedBytes.Set(float64(b.used))
+	close(b.released)
+	b.released = make(chan struct{})
+}
+
+// estimateSeriesBytes returns a rough upper bound of the bytes a Series call with the given matchers and time range
+// loads from the block. The number of series is bounded by the size of the smallest postings list the matchers select
+// in the index-header, the number of chunks per series is derived from the block stats. No chunks are loaded if
+// skipChunks is set.
+func (r *bucketIndexReader) estimateSeriesBytes(ms []labels.Matcher, mint, maxt int64, skipChunks bool) uint64 {
+	if len(ms) == 0 {
+		return 0
+	}
+
+	allName, allValue := index.AllPostingsKey()
+	var (
+		postingsBytes uint64
+		series        uint64
+	)
+	for i, m := range ms {
+		g := toPostingGroup(r.LabelValues, m)
+
+		var groupBytes, groupMax uint64
+		for j, key := range g.keys {
+			rng, ok := r.header.postings[key]
+			if !ok {
+				continue
+			}
+			size := uint64(rng.End - rng.Start)
+			groupBytes += size
+			// Groups starting with all postings remove the other postings from them.
+			if j == 0 && key.Name == allName && key.Value == allValue {
+				groupMax = size
+			}
+		}
+		postingsBytes += groupBytes
+		if groupMax == 0 {
+			groupMax = groupBytes
+		}
+
+		// Each posting is a 4 byte series reference.
+		if s := groupMax / 4; i == 0 || s < series {
+			series = s
+		}
+	}
+
+	meta := r.block.meta
+	if meta.Stats.NumSeries > 0 && series > meta.Stats.NumSeries {
+		series = meta.Stats.NumSeries
+	}
+	if skipChunks {
+		return postingsBytes + series*estimatedSeriesSize
+	}
+
+	var chunksPerSeries float64
+	if meta.Stats.NumSeries > 0 && meta.MaxTime > meta.MinTime {
+		chunksPerSeries = float64(meta.Stats.NumChunks) / float64(meta.Stats.NumSeries)
+
+		// Only the chunks within the requested time range are loaded.
+		if mint < meta.MinTime {
+			mint = meta.MinTime
+		}
+		if maxt > meta.MaxTime {
+			maxt = meta.MaxTime
+		}
+		if maxt < mint {
+			return postingsBytes
+		}
+		chunksPerSeries *= float64(maxt-mint) / float64(meta.MaxTime-meta.MinTime)
+		if chunksPerSeries < 1 {
+			chunksPerSeries = 1
+		}
+	}
+
+	return postingsBytes + series*estimatedSeriesSize + uint64(float64(series)*chunksPerSeries*estimatedChunkSize)
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBudget(100, 200*time.Millisecond, prometheus.NewGauge(prometheus.GaugeOpts{}), prometheus.NewCounter(prometheus.CounterOpts{}))
+
+	// Queries larger than the whole budget never fit.
+	err := b.acquire(ctx, 101)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, memoryBudgetExceededError{estimated: 101, budget: 100}, err)
+
+	testutil.Ok(t, b.acquire(ctx, 60))
+	testutil.Ok(t, b.acquire(ctx, 40))
+	testutil.Equals(t, 100.0, promtest.ToFloat64(b.usedBytes))
+
+	// Queries wait for memory at most for the queue timeout.
+	err = b.acquire(ctx, 1)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, memoryBudgetExceededError{estimated: 1, budget: 100, queued: true}, err)
+	testutil.Equals(t, 2.0, promtest.ToFloat64(b.rejected))
+
+	acquired := make(chan error)
+	go func() { acquired <- b.acquire(ctx, 50) }()
+	b.release(40)
+	select {
+	case <-acquired:
+		t.Fatal("acquired more memory than released")
+	case <-time.After(10 * time.Millisecond):
+	}
+	b.release(60)
+	testutil.Ok(t, <-acquired)
+	testutil.Equals(t, 50.0, promtest.ToFloat64(b.usedBytes))
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	testutil.Equals(t, context.Canceled, b.acquire(cctx, 60))
+}
@@ -1,6 +1,7 @@
 package storecache
 
 import (
+	"context"
 	"math"
 	"sync"
 
@@ -289,6 +290,19 @@ func (c *IndexCache) Postings(b ulid.ULID, l labels.Label) ([]byte, bool) {
 	return c.get(cacheTypePostings, cacheKey{b, cacheKeyPostings(l)})
 }
 
+// FetchMultiPostings returns the cached postings of the given labels and the labels not found.
+func (c *IndexCache) FetchMultiPostings(_ context.Context, b ulid.ULID, keys []labels.Label) (hits map[labels.Label][]byte, misses []labels.Label) {
+	hits = map[labels.Label][]byte{}
+	for _, l := range keys {
+		if v, ok := c.Postings(b, l); ok {
+			hits[l] = v
+			continue
+		}
+		misses = append(misses, l)
+	}
+	return hits, misses
+}
+
 // SetSeries sets the series identfied by the ulid and id to the value v,
 // if the series already exists in the cache it is not mutated.
 func (c *IndexCache) SetSeries(b ulid.ULID, id uint64, v []byte) {
@@ -298,3 +312,16 @@ func (c *IndexCache) SetSeries(b ulid.ULID, id uint64, v []byte) {
 func (c *IndexCache) Series(b ulid.ULID, id uint64) ([]byte, bool) {
 	return c.get(cacheTypeSeries, cacheKey{b, cacheKeySeries(id)})
 }
+
+// FetchMultiSeries returns the cached series of the given IDs and the IDs not found.
+func (c *IndexCache) FetchMultiSeries(_ context.Context, b ulid.ULID, ids []uint64) (hits map[uint64][]byte, misses []uint64) {
+	hits = map[uint64][]byte{}
+	for _, id := range ids {
+		if v, ok := c.Series(b, id); ok {
+			hits[id] = v
+			continue
+		}
+		misses = append(misses, id)
+	}
+	return hits, misses
+}
@@ -0,0 +1,468 @@
+package storecache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/cache"
+	"github.com/improbable-eng/thanos/pkg/objstore"
+	"github.com/improbable-eng/thanos/pkg/runutil"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	originCache  = "cache"
+	originBucket = "bucket"
+)
+
+const (
+	opIter   = "iter"
+	opExists = "exists"
+	opGet    = "get"
+)
+
+var (
+	chunksMatcher   = regexp.MustCompile(`^[0-9A-Z]{26}/chunks/\d+$`)
+	metafileMatcher = regexp.MustCompile(`^[0-9A-Z]{26}/meta\.json$`)
+	// Block metadata files checked for existence, e.g. in block deletion.
+	metafileExistsMatcher = regexp.MustCompile(`^[0-9A-Z]{26}/(meta|deletion-mark)\.json$`)
+)
+
+// CachingBucketConfig holds the settings of the chunks caching of the CachingBucket.
+type CachingBucketConfig struct {
+	// ChunkSubrangeSize is the size of the aligned subranges of chunk files that are cached.
+	ChunkSubrangeSize int64 `yaml:"chunk_subrange_size"`
+
+	// MaxChunksGetRangeRequests is the maximum number of GetRange requests issued to the bucket to fetch the
+	// subranges missing in the cache for a single request. Missing subranges are merged to stay within it.
+	MaxChunksGetRangeRequests int `yaml:"max_chunks_get_range_requests"`
+
+	// ChunkSubrangeTTL is the TTL of cached subranges.
+	ChunkSubrangeTTL time.Duration `yaml:"chunk_subrange_ttl"`
+
+	// BlocksIterTTL is the TTL of cached Iter results, e.g. the list of blocks of the bucket. 0 disables caching.
+	BlocksIterTTL time.Duration `yaml:"blocks_iter_ttl"`
+
+	// MetafileExistsTTL is the TTL of cached Exists results for existing objects. 0 disables caching.
+	MetafileExistsTTL time.Duration `yaml:"metafile_exists_ttl"`
+
+	// MetafileDoesntExistTTL is the TTL of cached Exists results for missing objects. It should be short, since those
+	// are likely blocks being uploaded. 0 disables caching.
+	MetafileDoesntExistTTL time.Duration `yaml:"metafile_doesnt_exist_ttl"`
+
+	// MetafileContentTTL is the TTL of cached meta.json contents. 0 disables caching.
+	MetafileContentTTL time.Duration `yaml:"metafile_content_ttl"`
+}
+
+// DefaultCachingBucketConfig returns the default CachingBucketConfig.
+func DefaultCachingBucketConfig() CachingBucketConfig {
+	return CachingBucketConfig{
+		ChunkSubrangeSize:         16 * 1024,
+		MaxChunksGetRangeRequests: 3,
+		ChunkSubrangeTTL:          24 * time.Hour,
+		BlocksIterTTL:             5 * time.Minute,
+		MetafileExistsTTL:         2 * time.Hour,
+		MetafileDoesntExistTTL:    2 * time.Minute,
+		MetafileContentTTL:        24 * time.Hour,
+	}
+}
+
+// CachingBucket is a bucket that caches GetRange requests of chunk files. Requested ranges are split into aligned
+// subranges, so overlapping requests for different chunks of the same popular series share their cache entries.
+// It also caches Iter and Exists results and meta.json contents for a short time, so frequent block syncs do not
+// hit the object storage listing API on every run.
+type CachingBucket struct {
+	objstore.Bucket
+
+	logger log.Logger
+	cache  cache.Cache
+	config CachingBucketConfig
+
+	requestedChunkBytes prometheus.Counter
+	fetchedChunkBytes   *prometheus.CounterVec
+	requestedSubranges  prometheus.Counter
+	hitSubranges        prometheus.Counter
+	getRangeRequests    prometheus.Counter
+
+	operationRequests *prometheus.CounterVec
+	operationHits     *prometheus.CounterVec
+}
+
+// NewCachingBucket returns a new CachingBucket caching chunk subranges of b in c.
+func NewCachingBucket(logger log.Logger, b objstore.Bucket, c cache.Cache, config CachingBucketConfig, reg prometheus.Registerer) (*CachingBucket, error) {
+	if b == nil || c == nil {
+		return nil, errors.New("bucket and cache are required")
+	}
+	if config.ChunkSubrangeSize <= 0 {
+		return nil, errors.New("chunk subrange size must be positive")
+	}
+	if config.MaxChunksGetRangeRequests <= 0 {
+		return nil, errors.New("max chunks GetRange requests must be positive")
+	}
+
+	cb := &CachingBucket{
+		Bucket: b,
+		logger: logger,
+		cache:  c,
+		config: config,
+
+		requestedChunkBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "thanos_store_bucket_cache_getrange_requested_bytes_total",
+			Help: "Total number of bytes requested via GetRange of chunk files.",
+		}),
+		fetchedChunkBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_store_bucket_cache_getrange_fetched_bytes_total",
+			Help: "Total number of bytes of chunk files fetched because of GetRange, by origin.",
+		}, []string{"origin"}),
+		requestedSubranges: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "thanos_store_bucket_cache_getrange_subranges_requested_total",
+			Help: "Total number of chunk subranges requested from the cache.",
+		}),
+		hitSubranges: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "thanos_store_bucket_cache_getrange_subranges_hits_total",
+			Help: "Total number of chunk subranges found in the cache.",
+		}),
+		getRangeRequests: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "thanos_store_bucket_cache_getrange_bucket_requests_total",
+			Help: "Total number of GetRange requests issued to the bucket for subranges missing in the cache.",
+		}),
+	}
+	cb.fetchedChunkBytes.WithLabelValues(originCache)
+	cb.fetchedChunkBytes.WithLabelValues(originBucket)
+
+	cb.operationRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_bucket_cache_operation_requests_total",
+		Help: "Total number of cacheable bucket operations, by operation.",
+	}, []string{"operation"})
+	cb.operationHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_bucket_cache_operation_hits_total",
+		Help: "Total number of bucket operations served from the cache, by operation.",
+	}, []string{"operation"})
+	for _, op := range []string{opIter, opExists, opGet} {
+		cb.operationRequests.WithLabelValues(op)
+		cb.operationHits.WithLabelValues(op)
+	}
+
+	if reg != nil {
+		reg.MustRegister(cb.requestedChunkBytes, cb.fetchedChunkBytes, cb.requestedSubranges, cb.hitSubranges, cb.getRangeRequests,
+			cb.operationRequests, cb.operationHits)
+	}
+	return cb, nil
+}
+
+// Iter calls f for each entry in the given directory. Results of complete iterations are cached.
+func (cb *CachingBucket) Iter(ctx context.Context, dir string, f func(string) error) error {
+	if cb.config.BlocksIterTTL <= 0 {
+		return cb.Bucket.Iter(ctx, dir, f)
+	}
+	cb.operationRequests.WithLabelValues(opIter).Inc()
+
+	key := "iter:" + dir
+	if v, ok := cb.cache.Fetch(ctx, []string{key})[key]; ok {
+		var names []string
+		if err := json.Unmarshal(v, &names); err == nil {
+			cb.operationHits.WithLabelValues(opIter).Inc()
+			for _, n := range names {
+				if err := f(n); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		level.Debug(cb.logger).Log("msg", "failed to decode cached iter result", "dir", dir)
+	}
+
+	names := []string{}
+	if err := cb.Bucket.Iter(ctx, dir, func(name string) error {
+		names = append(names, name)
+		return f(name)
+	}); err != nil {
+		return err
+	}
+
+	if v, err := json.Marshal(names); err == nil {
+		cb.cache.Store(ctx, map[string][]byte{key: v}, cb.config.BlocksIterTTL)
+	}
+	return nil
+}
+
+// Exists checks if the given object exists. Results for block metadata files are cached with different TTLs for
+// existing and missing objects.
+func (cb *CachingBucket) Exists(ctx context.Context, name string) (bool, error) {
+	if !metafileExistsMatcher.MatchString(name) || (cb.config.MetafileExistsTTL <= 0 && cb.config.MetafileDoesntExistTTL <= 0) {
+		return cb.Bucket.Exists(ctx, name)
+	}
+	cb.operationRequests.WithLabelValues(opExists).Inc()
+
+	key := "exists:" + name
+	if v, ok := cb.cache.Fetch(ctx, []string{key})[key]; ok && len(v) == 1 {
+		cb.operationHits.WithLabelValues(opExists).Inc()
+		return v[0] == 1, nil
+	}
+
+	ok, err := cb.Bucket.Exists(ctx, name)
+	if err != nil {
+		return false, err
+	}
+
+	if ok && cb.config.MetafileExistsTTL > 0 {
+		cb.cache.Store(ctx, map[string][]byte{key: {1}}, cb.config.MetafileExistsTTL)
+	}
+	if !ok && cb.config.MetafileDoesntExistTTL > 0 {
+		cb.cache.Store(ctx, map[string][]byte{key: {0}}, cb.config.MetafileDoesntExistTTL)
+	}
+	return ok, nil
+}
+
+// Get returns a reader for the given object name. Contents of meta.json files are cached.
+func (cb *CachingBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	if !metafileMatcher.MatchString(name) || cb.config.MetafileContentTTL <= 0 {
+		return cb.Bucket.Get(ctx, name)
+	}
+	cb.operationRequests.WithLabelValues(opGet).Inc()
+
+	key := "content:" + name
+	if v, ok := cb.cache.Fetch(ctx, []string{key})[key]; ok {
+		cb.operationHits.WithLabelValues(opGet).Inc()
+		return ioutil.NopCloser(bytes.NewReader(v)), nil
+	}
+
+	rc, err := cb.Bucket.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer runutil.CloseWithLogOnErr(cb.logger, rc, "close meta.json reader")
+
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", name)
+	}
+	cb.cache.Store(ctx, map[string][]byte{key: b}, cb.config.MetafileContentTTL)
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+// GetRange returns a new range reader for the given object name and range. Ranges of chunk files are served from
+// the cache where possible.
+func (cb *CachingBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	if !chunksMatcher.MatchString(name) || off < 0 || length <= 0 {
+		return cb.Bucket.GetRange(ctx, name, off, length)
+	}
+
+	cb.requestedChunkBytes.Add(float64(length))
+
+	b, err := cb.getRange(ctx, name, off, length)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (cb *CachingBucket) getRange(ctx context.Context, name string, off, length int64) ([]byte, error) {
+	size := cb.config.ChunkSubrangeSize
+
+	// Aligned subranges covering the requested range.
+	start := (off / size) * size
+	end := ((off + length + size - 1) / size) * size
+
+	keys := make([]string, 0, (end-start)/size)
+	offsets := map[string]int64{}
+	for o := start; o < end; o += size {
+		k := subrangeKey(name, o, o+size)
+		keys = append(keys, k)
+		offsets[k] = o
+	}
+
+	cb.requestedSubranges.Add(float64(len(keys)))
+	hits := cb.cache.Fetch(ctx, keys)
+	cb.hitSubranges.Add(float64(len(hits)))
+
+	subranges := make(map[int64][]byte, len(keys))
+	for k, v := range hits {
+		subranges[offsets[k]] = v
+		cb.fetchedChunkBytes.WithLabelValues(originCache).Add(float64(len(v)))
+	}
+
+	// A cached subrange shorter than the subrange size is the end of the object, nothing after it needs to be fetched.
+	fetchEnd := end
+	for o, sub := range subranges {
+		if subEnd := o + int64(len(sub)); int64(len(sub)) < size && subEnd < fetchEnd {
+			fetchEnd = subEnd
+		}
+	}
+
+	missing := false
+	for o := start; o < fetchEnd; o += size {
+		if _, ok := subranges[o]; !ok {
+			missing = true
+			break
+		}
+	}
+	if missing {
+		// Missing subranges may be beyond the end of the object, which some providers fail to serve.
+		objectSize, err := cb.objectSize(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if int64(objectSize) < fetchEnd {
+			fetchEnd = int64(objectSize)
+		}
+		if err := cb.fetchMissingSubranges(ctx, name, start, fetchEnd, subranges); err != nil {
+			return nil, err
+		}
+	}
+
+	// Assemble the subranges. A subrange shorter than the subrange size is the end of the object.
+	b := make([]byte, 0, end-start)
+	for o := start; o < end; o += size {
+		sub, ok := subranges[o]
+		if !ok {
+			break
+		}
+		b = append(b, sub...)
+		if int64(len(sub)) < size {
+			break
+		}
+	}
+
+	from, to := off-start, off-start+length
+	if from > int64(len(b)) {
+		from = int64(len(b))
+	}
+	if to > int64(len(b)) {
+		to = int64(len(b))
+	}
+	return b[from:to], nil
+}
+
+type subrangesRange struct {
+	start, end int64
+}
+
+// fetchMissingSubranges fetches subranges between start and end missing in the given map from the bucket, adds them
+// to the map and stores them in the cache.
+func (cb *CachingBucket) fetchMissingSubranges(ctx context.Context, name string, start, end int64, subranges map[int64][]byte) error {
+	size := cb.config.ChunkSubrangeSize
+
+	// Group consecutive missing subranges.
+	var missing []subrangesRange
+	for o := start; o < end; o += size {
+		if _, ok := subranges[o]; ok {
+			continue
+		}
+		oEnd := o + size
+		if oEnd > end {
+			oEnd = end
+		}
+		if l := len(missing); l > 0 && missing[l-1].end == o {
+			missing[l-1].end = oEnd
+			continue
+		}
+		missing = append(missing, subrangesRange{start: o, end: oEnd})
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	missing = mergeRanges(missing, cb.config.MaxChunksGetRangeRequests)
+
+	var (
+		mtx     sync.Mutex
+		toStore = map[string][]byte{}
+	)
+	g, gctx := errgroup.WithContext(ctx)
+	for _, r := range missing {
+		r := r
+		g.Go(func() error {
+			cb.getRangeRequests.Inc()
+
+			rc, err := cb.Bucket.GetRange(gctx, name, r.start, r.end-r.start)
+			if err != nil {
+				return errors.Wrapf(err, "fetch range %d-%d of %s", r.start, r.end, name)
+			}
+			defer runutil.CloseWithLogOnErr(cb.logger, rc, "close range reader")
+
+			b, err := ioutil.ReadAll(rc)
+			if err != nil {
+				return errors.Wrapf(err, "read range %d-%d of %s", r.start, r.end, name)
+			}
+			// Never cache partial subranges.
+			if int64(len(b)) != r.end-r.start {
+				return errors.Errorf("unexpected length %d of range %d-%d of %s", len(b), r.start, r.end, name)
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+
+			cb.fetchedChunkBytes.WithLabelValues(originBucket).Add(float64(len(b)))
+			for o := r.start; o < r.end; o += size {
+				from, to := o-r.start, o-r.start+size
+				if to > int64(len(b)) {
+					to = int64(len(b))
+				}
+				sub := b[from:to]
+
+				// Merged ranges can include subranges that were already cached.
+				if _, ok := subranges[o]; ok {
+					continue
+				}
+				subranges[o] = sub
+				toStore[subrangeKey(name, o, o+size)] = sub
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	cb.cache.Store(ctx, toStore, cb.config.ChunkSubrangeTTL)
+	return nil
+}
+
+// mergeRanges merges the sorted ranges with the smallest gaps between them until there are at most max ranges.
+func mergeRanges(ranges []subrangesRange, max int) []subrangesRange {
+	for len(ranges) > max {
+		minGap := 0
+		for i := 1; i < len(ranges)-1; i++ {
+			if ranges[i+1].start-ranges[i].end < ranges[minGap+1].start-ranges[minGap].end {
+				minGap = i
+			}
+		}
+		ranges[minGap].end = ranges[minGap+1].end
+		ranges = append(ranges[:minGap+1], ranges[minGap+2:]...)
+	}
+	return ranges
+}
+
+// objectSize returns the size of the given object. Objects in the bucket are immutable, so the size is cached as well.
+func (cb *CachingBucket) objectSize(ctx context.Context, name string) (uint64, error) {
+	key := "size:" + name
+	if v, ok := cb.cache.Fetch(ctx, []string{key})[key]; ok && len(v) == 8 {
+		return binary.BigEndian.Uint64(v), nil
+	}
+
+	size, err := cb.Bucket.ObjectSize(ctx, name)
+	if err != nil {
+		return 0, errors.Wrapf(err, "get size of %s", name)
+	}
+
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, size)
+	cb.cache.Store(ctx, map[string][]byte{key: v}, cb.config.ChunkSubrangeTTL)
+	return size, nil
+}
+
+func subrangeKey(name string, start, end int64) string {
+	return fmt.Sprintf("subrange:%s:%d:%d", name, start, end)
+}
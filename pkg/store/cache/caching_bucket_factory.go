@@ -0,0 +1,66 @@
+package storecache
+
+import (
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/cache"
+	"github.com/improbable-eng/thanos/pkg/cacheutil"
+	"github.com/improbable-eng/thanos/pkg/objstore"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// BucketCacheConfig is the config of the caching bucket: the cache backend and the chunks caching settings.
+type BucketCacheConfig struct {
+	Type   IndexCacheProvider `yaml:"type"`
+	Config interface{}        `yaml:"config"`
+
+	CachingBucketConfig `yaml:",inline"`
+}
+
+// NewCachingBucketFromConfig wraps the given bucket with a CachingBucket described by the given YAML configuration.
+func NewCachingBucketFromConfig(logger log.Logger, confContentYaml []byte, bkt objstore.Bucket, reg prometheus.Registerer) (*CachingBucket, error) {
+	config := &BucketCacheConfig{CachingBucketConfig: DefaultCachingBucketConfig()}
+	if err := yaml.UnmarshalStrict(confContentYaml, config); err != nil {
+		return nil, errors.Wrap(err, "parsing config YAML file")
+	}
+
+	backendConfig, err := yaml.Marshal(config.Config)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal content of cache backend configuration")
+	}
+
+	const name = "chunks"
+
+	var c cache.Cache
+	switch strings.ToUpper(string(config.Type)) {
+	case string(INMEMORY):
+		c, err = cache.NewInMemoryCache(name, logger, reg, backendConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "create in-memory cache")
+		}
+	case string(MEMCACHED):
+		memcached, err := cacheutil.NewMemcachedClient(logger, "caching-bucket", backendConfig, reg)
+		if err != nil {
+			return nil, errors.Wrap(err, "create memcached client")
+		}
+		c = cache.NewMemcachedCache(name, logger, memcached, reg)
+	case string(REDIS):
+		redis, err := cacheutil.NewRedisClient(logger, "caching-bucket", backendConfig, reg)
+		if err != nil {
+			return nil, errors.Wrap(err, "create redis client")
+		}
+		c = cache.NewRedisCache(name, logger, redis, reg)
+	case string(DISK):
+		c, err = cache.NewDiskCache(name, logger, reg, backendConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "create disk cache")
+		}
+	default:
+		return nil, errors.Errorf("cache with type %s is not supported", config.Type)
+	}
+
+	return NewCachingBucket(logger, bkt, c, config.CachingBucketConfig, reg)
+}
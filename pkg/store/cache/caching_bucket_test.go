@@ -0,0 +1,295 @@
+package storecache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/objstore"
+	"github.com/improbable-eng/thanos/pkg/objstore/inmem"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const testChunkFile = "01DN3SK96XDAEKRB1AN30AAW6E/chunks/000001"
+
+func TestCachingBucket_GetRange(t *testing.T) {
+	ctx := context.Background()
+
+	data := make([]byte, 4500)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	inner := &countingBucket{Bucket: inmem.NewBucket()}
+	testutil.Ok(t, inner.Upload(ctx, testChunkFile, bytes.NewReader(data)))
+	testutil.Ok(t, inner.Upload(ctx, "01DN3SK96XDAEKRB1AN30AAW6E/index", bytes.NewReader(data)))
+
+	c := newMockedCache()
+	cb, err := NewCachingBucket(log.NewNopLogger(), inner, c, CachingBucketConfig{
+		ChunkSubrangeSize:         1000,
+		MaxChunksGetRangeRequests: 2,
+		ChunkSubrangeTTL:          time.Hour,
+	}, nil)
+	testutil.Ok(t, err)
+
+	for _, tc := range []struct {
+		name               string
+		off, length        int64
+		expBucketGetRanges int
+	}{
+		{name: "within single subrange", off: 100, length: 200, expBucketGetRanges: 1},
+		{name: "same subrange again is cached", off: 150, length: 300, expBucketGetRanges: 0},
+		{name: "spanning cached and missing subranges", off: 500, length: 1000, expBucketGetRanges: 1},
+		{name: "aligned subranges", off: 1000, length: 2000, expBucketGetRanges: 1},
+		{name: "past the end of object", off: 4000, length: 16000, expBucketGetRanges: 1},
+		{name: "past the end of object with missing subrange", off: 3500, length: 16000, expBucketGetRanges: 1},
+		{name: "past the end of object again", off: 4200, length: 16000, expBucketGetRanges: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			before := inner.getRangeCount()
+
+			rc, err := cb.GetRange(ctx, testChunkFile, tc.off, tc.length)
+			testutil.Ok(t, err)
+			b, err := ioutil.ReadAll(rc)
+			testutil.Ok(t, err)
+			testutil.Ok(t, rc.Close())
+
+			end := tc.off + tc.length
+			if end > int64(len(data)) {
+				end = int64(len(data))
+			}
+			testutil.Equals(t, data[tc.off:end], b)
+			testutil.Equals(t, tc.expBucketGetRanges, inner.getRangeCount()-before)
+		})
+	}
+
+	// Cached subranges are aligned.
+	for k := range c.items {
+		if k == "size:"+testChunkFile {
+			continue
+		}
+		testutil.Assert(t, bytes.HasPrefix([]byte(k), []byte("subrange:"+testChunkFile+":")), "unexpected key %s", k)
+	}
+	testutil.Equals(t, 6, len(c.items))
+	testutil.Equals(t, float64(len(data)), promtest.ToFloat64(cb.fetchedChunkBytes.WithLabelValues(originBucket)))
+
+	// Other objects are not cached.
+	before := inner.getRangeCount()
+	for i := 0; i < 2; i++ {
+		rc, err := cb.GetRange(ctx, "01DN3SK96XDAEKRB1AN30AAW6E/index", 0, 100)
+		testutil.Ok(t, err)
+		testutil.Ok(t, rc.Close())
+	}
+	testutil.Equals(t, 2, inner.getRangeCount()-before)
+	testutil.Equals(t, 6, len(c.items))
+}
+
+func TestCachingBucket_MaxGetRangeRequests(t *testing.T) {
+	ctx := context.Background()
+
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	inner := &countingBucket{Bucket: inmem.NewBucket()}
+	testutil.Ok(t, inner.Upload(ctx, testChunkFile, bytes.NewReader(data)))
+
+	c := newMockedCache()
+	cb, err := NewCachingBucket(log.NewNopLogger(), inner, c, CachingBucketConfig{
+		ChunkSubrangeSize:         1000,
+		MaxChunksGetRangeRequests: 2,
+		ChunkSubrangeTTL:          time.Hour,
+	}, nil)
+	testutil.Ok(t, err)
+
+	// Cache every other subrange, so there are five missing ranges.
+	for _, off := range []int64{1000, 3000, 5000, 7000} {
+		rc, err := cb.GetRange(ctx, testChunkFile, off, 1000)
+		testutil.Ok(t, err)
+		testutil.Ok(t, rc.Close())
+	}
+
+	before := inner.getRangeCount()
+	rc, err := cb.GetRange(ctx, testChunkFile, 0, 10000)
+	testutil.Ok(t, err)
+	b, err := ioutil.ReadAll(rc)
+	testutil.Ok(t, err)
+	testutil.Equals(t, data, b)
+	testutil.Equals(t, 2, inner.getRangeCount()-before)
+}
+
+func TestMergeRanges(t *testing.T) {
+	testutil.Equals(t,
+		[]subrangesRange{{0, 10}, {30, 60}},
+		mergeRanges([]subrangesRange{{0, 10}, {30, 40}, {45, 50}, {55, 60}}, 2),
+	)
+	testutil.Equals(t,
+		[]subrangesRange{{0, 10}, {30, 40}},
+		mergeRanges([]subrangesRange{{0, 10}, {30, 40}}, 3),
+	)
+	testutil.Equals(t,
+		[]subrangesRange{{0, 60}},
+		mergeRanges([]subrangesRange{{0, 10}, {30, 40}, {45, 50}, {55, 60}}, 1),
+	)
+}
+
+func TestCachingBucket_Metadata(t *testing.T) {
+	ctx := context.Background()
+
+	const (
+		id1 = "01DN3SK96XDAEKRB1AN30AAW6E"
+		id2 = "01DN3SK96XDAEKRB1AN30AAW6F"
+	)
+
+	inner := &countingBucket{Bucket: inmem.NewBucket()}
+	testutil.Ok(t, inner.Upload(ctx, id1+"/meta.json", bytes.NewReader([]byte(`{"meta":1}`))))
+	testutil.Ok(t, inner.Upload(ctx, id1+"/index", bytes.NewReader([]byte("index"))))
+
+	cb, err := NewCachingBucket(log.NewNopLogger(), inner, newMockedCache(), DefaultCachingBucketConfig(), nil)
+	testutil.Ok(t, err)
+
+	iter := func() []string {
+		var names []string
+		testutil.Ok(t, cb.Iter(ctx, "", func(name string) error {
+			names = append(names, name)
+			return nil
+		}))
+		return names
+	}
+
+	// Iter results are cached until they expire.
+	testutil.Equals(t, []string{id1 + "/"}, iter())
+	testutil.Ok(t, inner.Upload(ctx, id2+"/meta.json", bytes.NewReader([]byte(`{"meta":2}`))))
+	testutil.Equals(t, []string{id1 + "/"}, iter())
+	testutil.Equals(t, 1, inner.count(opIter))
+
+	// Both existing and missing meta.json files are cached.
+	for i := 0; i < 2; i++ {
+		ok, err := cb.Exists(ctx, id1+"/meta.json")
+		testutil.Ok(t, err)
+		testutil.Assert(t, ok, "expected meta.json to exist")
+
+		ok, err = cb.Exists(ctx, id1+"/deletion-mark.json")
+		testutil.Ok(t, err)
+		testutil.Assert(t, !ok, "expected deletion-mark.json to not exist")
+	}
+	testutil.Equals(t, 2, inner.count(opExists))
+
+	// Other objects are not cached.
+	for i := 0; i < 2; i++ {
+		_, err := cb.Exists(ctx, id1+"/index")
+		testutil.Ok(t, err)
+	}
+	testutil.Equals(t, 4, inner.count(opExists))
+
+	// Content of meta.json is cached.
+	for i := 0; i < 2; i++ {
+		rc, err := cb.Get(ctx, id1+"/meta.json")
+		testutil.Ok(t, err)
+		b, err := ioutil.ReadAll(rc)
+		testutil.Ok(t, err)
+		testutil.Ok(t, rc.Close())
+		testutil.Equals(t, `{"meta":1}`, string(b))
+	}
+	testutil.Equals(t, 1, inner.count(opGet))
+
+	// Missing objects still return not found errors.
+	_, err = cb.Get(ctx, id1+"/missing/meta.json")
+	testutil.NotOk(t, err)
+	_, err = cb.Get(ctx, "01DN3SK96XDAEKRB1AN30AAW6G/meta.json")
+	testutil.Assert(t, cb.IsObjNotFoundErr(err), "expected not found error, got %v", err)
+
+	testutil.Equals(t, 1.0, promtest.ToFloat64(cb.operationHits.WithLabelValues(opIter)))
+	testutil.Equals(t, 2.0, promtest.ToFloat64(cb.operationHits.WithLabelValues(opExists)))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(cb.operationHits.WithLabelValues(opGet)))
+}
+
+type countingBucket struct {
+	objstore.Bucket
+
+	mtx       sync.Mutex
+	getRanges int
+	ops       map[string]int
+}
+
+func (b *countingBucket) inc(op string) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.ops == nil {
+		b.ops = map[string]int{}
+	}
+	b.ops[op]++
+}
+
+func (b *countingBucket) count(op string) int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.ops[op]
+}
+
+func (b *countingBucket) Iter(ctx context.Context, dir string, f func(string) error) error {
+	b.inc(opIter)
+	return b.Bucket.Iter(ctx, dir, f)
+}
+
+func (b *countingBucket) Exists(ctx context.Context, name string) (bool, error) {
+	b.inc(opExists)
+	return b.Bucket.Exists(ctx, name)
+}
+
+func (b *countingBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	b.inc(opGet)
+	return b.Bucket.Get(ctx, name)
+}
+
+func (b *countingBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	b.mtx.Lock()
+	b.getRanges++
+	b.mtx.Unlock()
+	return b.Bucket.GetRange(ctx, name, off, length)
+}
+
+func (b *countingBucket) getRangeCount() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.getRanges
+}
+
+type mockedCache struct {
+	mtx   sync.Mutex
+	items map[string][]byte
+}
+
+func newMockedCache() *mockedCache {
+	return &mockedCache{items: map[string][]byte{}}
+}
+
+func (c *mockedCache) Store(_ context.Context, data map[string][]byte, _ time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for k, v := range data {
+		c.items[k] = v
+	}
+}
+
+func (c *mockedCache) Fetch(_ context.Context, keys []string) map[string][]byte {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	found := map[string][]byte{}
+	for _, k := range keys {
+		if v, ok := c.items[k]; ok {
+			found[k] = v
+		}
+	}
+	return found
+}
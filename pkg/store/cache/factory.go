@@ -0,0 +1,98 @@
+package storecache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/cacheutil"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/tsdb/labels"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type IndexCacheProvider string
+
+const (
+	INMEMORY  IndexCacheProvider = "IN-MEMORY"
+	MEMCACHED IndexCacheProvider = "MEMCACHED"
+	REDIS     IndexCacheProvider = "REDIS"
+	// DISK is only supported by the caching bucket.
+	DISK IndexCacheProvider = "DISK"
+)
+
+// Cache is the index cache for postings and series used by the store gateway.
+type Cache interface {
+	SetPostings(b ulid.ULID, l labels.Label, v []byte)
+	// FetchMultiPostings returns the cached postings of the given labels of the block and the labels not found.
+	FetchMultiPostings(ctx context.Context, b ulid.ULID, keys []labels.Label) (hits map[labels.Label][]byte, misses []labels.Label)
+	SetSeries(b ulid.ULID, id uint64, v []byte)
+	// FetchMultiSeries returns the cached series of the given IDs of the block and the IDs not found.
+	FetchMultiSeries(ctx context.Context, b ulid.ULID, ids []uint64) (hits map[uint64][]byte, misses []uint64)
+}
+
+// IndexCacheConfig specifies the index cache config.
+type IndexCacheConfig struct {
+	Type   IndexCacheProvider `yaml:"type"`
+	Config interface{}        `yaml:"config"`
+}
+
+// InMemoryIndexCacheConfig is the config of the in-memory LRU index cache.
+type InMemoryIndexCacheConfig struct {
+	// MaxSizeBytes represents overall maximum number of bytes cache can contain.
+	MaxSizeBytes uint64 `yaml:"max_size_bytes"`
+	// MaxItemSizeBytes represents maximum size of single item.
+	MaxItemSizeBytes uint64 `yaml:"max_item_size_bytes"`
+}
+
+// NewIndexCacheFromConfig initializes and returns the index cache described by the given YAML configuration.
+func NewIndexCacheFromConfig(logger log.Logger, confContentYaml []byte, reg prometheus.Registerer) (Cache, error) {
+	cacheConf := &IndexCacheConfig{}
+	if err := yaml.UnmarshalStrict(confContentYaml, cacheConf); err != nil {
+		return nil, errors.Wrap(err, "parsing config YAML file")
+	}
+
+	backendConfig, err := yaml.Marshal(cacheConf.Config)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal content of cache backend configuration")
+	}
+
+	switch strings.ToUpper(string(cacheConf.Type)) {
+	case string(INMEMORY):
+		conf := &InMemoryIndexCacheConfig{}
+		if err := yaml.UnmarshalStrict(backendConfig, conf); err != nil {
+			return nil, errors.Wrap(err, "parsing in-memory index cache config")
+		}
+		if conf.MaxItemSizeBytes == 0 {
+			conf.MaxItemSizeBytes = conf.MaxSizeBytes / 2
+		}
+		return NewIndexCache(logger, reg, Opts{
+			MaxSizeBytes:     conf.MaxSizeBytes,
+			MaxItemSizeBytes: conf.MaxItemSizeBytes,
+		})
+	case string(MEMCACHED):
+		memcached, err := cacheutil.NewMemcachedClient(logger, "index-cache", backendConfig, reg)
+		if err != nil {
+			return nil, errors.Wrap(err, "create memcached client")
+		}
+		return NewMemcachedIndexCache(logger, memcached, reg), nil
+	case string(REDIS):
+		conf := &RedisIndexCacheConfig{
+			RedisClientConfig: cacheutil.DefaultRedisClientConfig,
+			TTL:               24 * time.Hour,
+		}
+		if err := yaml.UnmarshalStrict(backendConfig, conf); err != nil {
+			return nil, errors.Wrap(err, "parsing redis index cache config")
+		}
+		redis, err := cacheutil.NewRedisClientWithConfig(logger, "index-cache", conf.RedisClientConfig, reg)
+		if err != nil {
+			return nil, errors.Wrap(err, "create redis client")
+		}
+		return NewRedisIndexCache(logger, redis, conf.TTL, reg), nil
+	default:
+		return nil, errors.Errorf("index cache with type %s is not supported", cacheConf.Type)
+	}
+}
@@ -0,0 +1,31 @@
+package storecache
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+)
+
+func TestNewIndexCacheFromConfig(t *testing.T) {
+	c, err := NewIndexCacheFromConfig(log.NewNopLogger(), []byte(`
+type: IN-MEMORY
+config:
+  max_size_bytes: 1024
+`), nil)
+	testutil.Ok(t, err)
+	inmem, ok := c.(*IndexCache)
+	testutil.Assert(t, ok, "expected in-memory cache, got %T", c)
+	testutil.Equals(t, uint64(1024), inmem.maxSizeBytes)
+	testutil.Equals(t, uint64(512), inmem.maxItemSizeBytes)
+
+	_, err = NewIndexCacheFromConfig(log.NewNopLogger(), []byte(`
+type: MEMCACHED
+config:
+  addresses: []
+`), nil)
+	testutil.NotOk(t, err)
+
+	_, err = NewIndexCacheFromConfig(log.NewNopLogger(), []byte(`type: UNKNOWN`), nil)
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,152 @@
+package storecache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/improbable-eng/thanos/pkg/cacheutil"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/tsdb/labels"
+)
+
+const (
+	memcachedDefaultTTL = 24 * time.Hour
+)
+
+// MemcachedIndexCache is a memcached-based index cache. It allows many store gateway replicas to share the postings
+// and series they fetched from the bucket, instead of each of them keeping a big in-process LRU.
+type MemcachedIndexCache struct {
+	logger    log.Logger
+	memcached cacheutil.MemcachedClient
+
+	requests *prometheus.CounterVec
+	hits     *prometheus.CounterVec
+}
+
+// NewMemcachedIndexCache makes a new MemcachedIndexCache.
+func NewMemcachedIndexCache(logger log.Logger, memcached cacheutil.MemcachedClient, reg prometheus.Registerer) *MemcachedIndexCache {
+	c := &MemcachedIndexCache{
+		logger:    logger,
+		memcached: memcached,
+	}
+
+	c.requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_requests_total",
+		Help: "Total number of requests to the cache.",
+	}, []string{"item_type"})
+	c.requests.WithLabelValues(cacheTypePostings)
+	c.requests.WithLabelValues(cacheTypeSeries)
+
+	c.hits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_hits_total",
+		Help: "Total number of requests to the cache that were a hit.",
+	}, []string{"item_type"})
+	c.hits.WithLabelValues(cacheTypePostings)
+	c.hits.WithLabelValues(cacheTypeSeries)
+
+	if reg != nil {
+		reg.MustRegister(c.requests, c.hits)
+	}
+
+	level.Info(logger).Log("msg", "created memcached index cache")
+	return c
+}
+
+// SetPostings sets the postings identified by the ulid and label to the value v.
+// The function enqueues the request and returns immediately: the entry will be
+// asynchronously stored in the cache.
+func (c *MemcachedIndexCache) SetPostings(b ulid.ULID, l labels.Label, v []byte) {
+	key := postingsKey(b, l)
+
+	if err := c.memcached.SetAsync(key, v, memcachedDefaultTTL); err != nil {
+		level.Debug(c.logger).Log("msg", "failed to cache postings in memcached", "err", err)
+	}
+}
+
+// FetchMultiPostings fetches the postings of all given labels of the block in a single memcached request. It returns
+// the postings found and the labels not found.
+func (c *MemcachedIndexCache) FetchMultiPostings(ctx context.Context, b ulid.ULID, lbls []labels.Label) (hits map[labels.Label][]byte, misses []labels.Label) {
+	keys := make([]string, 0, len(lbls))
+	for _, l := range lbls {
+		keys = append(keys, postingsKey(b, l))
+	}
+	results := c.getMulti(ctx, cacheTypePostings, keys)
+
+	hits = make(map[labels.Label][]byte, len(results))
+	for i, l := range lbls {
+		if v, ok := results[keys[i]]; ok {
+			hits[l] = v
+			continue
+		}
+		misses = append(misses, l)
+	}
+	return hits, misses
+}
+
+// SetSeries sets the series identified by the ulid and id to the value v.
+// The function enqueues the request and returns immediately: the entry will be
+// asynchronously stored in the cache.
+func (c *MemcachedIndexCache) SetSeries(b ulid.ULID, id uint64, v []byte) {
+	key := seriesKey(b, id)
+
+	if err := c.memcached.SetAsync(key, v, memcachedDefaultTTL); err != nil {
+		level.Debug(c.logger).Log("msg", "failed to cache series in memcached", "err", err)
+	}
+}
+
+// FetchMultiSeries fetches the series of all given IDs of the block in a single memcached request. It returns the
+// series found and the IDs not found.
+func (c *MemcachedIndexCache) FetchMultiSeries(ctx context.Context, b ulid.ULID, ids []uint64) (hits map[uint64][]byte, misses []uint64) {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, seriesKey(b, id))
+	}
+	results := c.getMulti(ctx, cacheTypeSeries, keys)
+
+	hits = make(map[uint64][]byte, len(results))
+	for i, id := range ids {
+		if v, ok := results[keys[i]]; ok {
+			hits[id] = v
+			continue
+		}
+		misses = append(misses, id)
+	}
+	return hits, misses
+}
+
+func (c *MemcachedIndexCache) getMulti(ctx context.Context, typ string, keys []string) map[string][]byte {
+	if len(keys) == 0 {
+		return nil
+	}
+	c.requests.WithLabelValues(typ).Add(float64(len(keys)))
+
+	results := c.memcached.GetMulti(ctx, keys)
+	c.hits.WithLabelValues(typ).Add(float64(len(results)))
+	return results
+}
+
+// Stop releases the connections to memcached.
+func (c *MemcachedIndexCache) Stop() {
+	c.memcached.Stop()
+}
+
+// postingsKey returns the memcached key of the given postings. Memcached keys are limited to 250 bytes without spaces
+// or control characters, so the label, which can contain anything, is hashed.
+func postingsKey(b ulid.ULID, l labels.Label) string {
+	h := sha256.New()
+	_, _ = h.Write([]byte(l.Name))
+	_, _ = h.Write([]byte{0xff})
+	_, _ = h.Write([]byte(l.Value))
+
+	return "P:" + b.String() + ":" + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func seriesKey(b ulid.ULID, id uint64) string {
+	return "S:" + b.String() + ":" + strconv.FormatUint(id, 10)
+}
@@ -0,0 +1,95 @@
+package storecache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/tsdb/labels"
+)
+
+func TestMemcachedIndexCache(t *testing.T) {
+	memcached := newMockedRemoteCacheClient()
+	c := NewMemcachedIndexCache(log.NewNopLogger(), memcached, prometheus.NewRegistry())
+
+	ctx := context.Background()
+	id := ulid.MustNew(1, nil)
+	lbl := labels.Label{Name: "name with spaces", Value: "value\nwith\nnewlines"}
+	other := labels.Label{Name: "name with spaces", Value: "value"}
+
+	hits, misses := c.FetchMultiPostings(ctx, id, []labels.Label{lbl})
+	testutil.Equals(t, 0, len(hits))
+	testutil.Equals(t, []labels.Label{lbl}, misses)
+
+	c.SetPostings(id, lbl, []byte("postings"))
+	c.SetSeries(id, 1234, []byte("series"))
+
+	// Other labels do not collide and all keys are fetched in a single request.
+	memcached.calls = 0
+	hits, misses = c.FetchMultiPostings(ctx, id, []labels.Label{lbl, other})
+	testutil.Equals(t, map[labels.Label][]byte{lbl: []byte("postings")}, hits)
+	testutil.Equals(t, []labels.Label{other}, misses)
+	testutil.Equals(t, 1, memcached.calls)
+
+	sHits, sMisses := c.FetchMultiSeries(ctx, id, []uint64{1234, 123})
+	testutil.Equals(t, map[uint64][]byte{1234: []byte("series")}, sHits)
+	testutil.Equals(t, []uint64{123}, sMisses)
+
+	// Other blocks do not collide.
+	hits, _ = c.FetchMultiPostings(ctx, ulid.MustNew(2, nil), []labels.Label{lbl})
+	testutil.Equals(t, 0, len(hits))
+
+	testutil.Equals(t, 4.0, promtest.ToFloat64(c.requests.WithLabelValues(cacheTypePostings)))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(c.hits.WithLabelValues(cacheTypePostings)))
+	testutil.Equals(t, 2.0, promtest.ToFloat64(c.requests.WithLabelValues(cacheTypeSeries)))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(c.hits.WithLabelValues(cacheTypeSeries)))
+
+	// Keys are valid memcached keys.
+	for k := range memcached.items {
+		testutil.Assert(t, len(k) <= 250, "key %q too long", k)
+		for _, r := range k {
+			testutil.Assert(t, r > ' ' && r != 0x7f, "invalid character in key %q", k)
+		}
+	}
+}
+
+type mockedRemoteCacheClient struct {
+	mtx   sync.Mutex
+	items map[string][]byte
+	// calls is the number of GetMulti calls.
+	calls int
+}
+
+func newMockedRemoteCacheClient() *mockedRemoteCacheClient {
+	return &mockedRemoteCacheClient{items: map[string][]byte{}}
+}
+
+func (c *mockedRemoteCacheClient) GetMulti(_ context.Context, keys []string) map[string][]byte {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.calls++
+	hits := map[string][]byte{}
+	for _, k := range keys {
+		if v, ok := c.items[k]; ok {
+			hits[k] = v
+		}
+	}
+	return hits
+}
+
+func (c *mockedRemoteCacheClient) SetAsync(key string, value []byte, _ time.Duration) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.items[key] = value
+	return nil
+}
+
+func (c *mockedRemoteCacheClient) Stop() {}
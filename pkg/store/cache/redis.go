@@ -0,0 +1,147 @@
+package storecache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/snappy"
+	"github.com/improbable-eng/thanos/pkg/cacheutil"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/tsdb/labels"
+)
+
+// RedisIndexCacheConfig is the config of the Redis index cache.
+type RedisIndexCacheConfig struct {
+	cacheutil.RedisClientConfig `yaml:",inline"`
+
+	// TTL is the expiration of cached entries. 0 means entries don't expire and are evicted by Redis only.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// RedisIndexCache is a Redis-based index cache. Values are compressed with snappy, since postings and series
+// compress well and Redis keeps everything in memory.
+type RedisIndexCache struct {
+	logger log.Logger
+	redis  cacheutil.RedisClient
+	ttl    time.Duration
+
+	requests *prometheus.CounterVec
+	hits     *prometheus.CounterVec
+}
+
+// NewRedisIndexCache makes a new RedisIndexCache.
+func NewRedisIndexCache(logger log.Logger, redis cacheutil.RedisClient, ttl time.Duration, reg prometheus.Registerer) *RedisIndexCache {
+	c := &RedisIndexCache{
+		logger: logger,
+		redis:  redis,
+		ttl:    ttl,
+	}
+
+	c.requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_requests_total",
+		Help: "Total number of requests to the cache.",
+	}, []string{"item_type"})
+	c.requests.WithLabelValues(cacheTypePostings)
+	c.requests.WithLabelValues(cacheTypeSeries)
+
+	c.hits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_hits_total",
+		Help: "Total number of requests to the cache that were a hit.",
+	}, []string{"item_type"})
+	c.hits.WithLabelValues(cacheTypePostings)
+	c.hits.WithLabelValues(cacheTypeSeries)
+
+	if reg != nil {
+		reg.MustRegister(c.requests, c.hits)
+	}
+
+	level.Info(logger).Log("msg", "created redis index cache", "ttl", ttl)
+	return c
+}
+
+// SetPostings sets the postings identified by the ulid and label to the value v.
+// The function enqueues the request and returns immediately: the entry will be
+// asynchronously stored in the cache.
+func (c *RedisIndexCache) SetPostings(b ulid.ULID, l labels.Label, v []byte) {
+	if err := c.redis.SetAsync(postingsKey(b, l), snappy.Encode(nil, v), c.ttl); err != nil {
+		level.Debug(c.logger).Log("msg", "failed to cache postings in redis", "err", err)
+	}
+}
+
+// FetchMultiPostings fetches the postings of all given labels of the block in a single pipelined Redis request. It
+// returns the postings found and the labels not found.
+func (c *RedisIndexCache) FetchMultiPostings(ctx context.Context, b ulid.ULID, lbls []labels.Label) (hits map[labels.Label][]byte, misses []labels.Label) {
+	keys := make([]string, 0, len(lbls))
+	for _, l := range lbls {
+		keys = append(keys, postingsKey(b, l))
+	}
+	results := c.getMulti(ctx, cacheTypePostings, keys)
+
+	hits = make(map[labels.Label][]byte, len(results))
+	for i, l := range lbls {
+		if v, ok := results[keys[i]]; ok {
+			hits[l] = v
+			continue
+		}
+		misses = append(misses, l)
+	}
+	return hits, misses
+}
+
+// SetSeries sets the series identified by the ulid and id to the value v.
+// The function enqueues the request and returns immediately: the entry will be
+// asynchronously stored in the cache.
+func (c *RedisIndexCache) SetSeries(b ulid.ULID, id uint64, v []byte) {
+	if err := c.redis.SetAsync(seriesKey(b, id), snappy.Encode(nil, v), c.ttl); err != nil {
+		level.Debug(c.logger).Log("msg", "failed to cache series in redis", "err", err)
+	}
+}
+
+// FetchMultiSeries fetches the series of all given IDs of the block in a single pipelined Redis request. It returns
+// the series found and the IDs not found.
+func (c *RedisIndexCache) FetchMultiSeries(ctx context.Context, b ulid.ULID, ids []uint64) (hits map[uint64][]byte, misses []uint64) {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, seriesKey(b, id))
+	}
+	results := c.getMulti(ctx, cacheTypeSeries, keys)
+
+	hits = make(map[uint64][]byte, len(results))
+	for i, id := range ids {
+		if v, ok := results[keys[i]]; ok {
+			hits[id] = v
+			continue
+		}
+		misses = append(misses, id)
+	}
+	return hits, misses
+}
+
+// getMulti returns the decoded entries of the given keys found in Redis. Entries that cannot be decoded are misses.
+func (c *RedisIndexCache) getMulti(ctx context.Context, typ string, keys []string) map[string][]byte {
+	if len(keys) == 0 {
+		return nil
+	}
+	c.requests.WithLabelValues(typ).Add(float64(len(keys)))
+
+	results := c.redis.GetMulti(ctx, keys)
+	decoded := make(map[string][]byte, len(results))
+	for key, v := range results {
+		d, err := snappy.Decode(nil, v)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to decode cached entry from redis", "key", key, "err", err)
+			continue
+		}
+		decoded[key] = d
+	}
+	c.hits.WithLabelValues(typ).Add(float64(len(decoded)))
+	return decoded
+}
+
+// Stop releases the connections to Redis.
+func (c *RedisIndexCache) Stop() {
+	c.redis.Stop()
+}
@@ -0,0 +1,65 @@
+package storecache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kit/kit/log"
+	"github.com/golang/snappy"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/tsdb/labels"
+)
+
+func TestRedisIndexCache(t *testing.T) {
+	redis := newMockedRemoteCacheClient()
+	c := NewRedisIndexCache(log.NewNopLogger(), redis, 0, prometheus.NewRegistry())
+
+	id := ulid.MustNew(1, nil)
+	lbl := labels.Label{Name: "a", Value: "b"}
+
+	c.SetPostings(id, lbl, []byte("postings postings postings"))
+	c.SetSeries(id, 1234, []byte("series"))
+
+	// Values are stored compressed.
+	stored := redis.GetMulti(context.Background(), []string{postingsKey(id, lbl)})[postingsKey(id, lbl)]
+	testutil.Equals(t, snappy.Encode(nil, []byte("postings postings postings")), stored)
+
+	ctx := context.Background()
+	hits, _ := c.FetchMultiPostings(ctx, id, []labels.Label{lbl})
+	testutil.Equals(t, map[labels.Label][]byte{lbl: []byte("postings postings postings")}, hits)
+
+	// Entries that cannot be decoded are misses.
+	testutil.Ok(t, redis.SetAsync(seriesKey(id, 1), []byte("\xff\xff\xff not snappy"), 0))
+	redis.calls = 0
+	sHits, sMisses := c.FetchMultiSeries(ctx, id, []uint64{1234, 1})
+	testutil.Equals(t, map[uint64][]byte{1234: []byte("series")}, sHits)
+	testutil.Equals(t, []uint64{1}, sMisses)
+	testutil.Equals(t, 1, redis.calls)
+
+	testutil.Equals(t, 1.0, promtest.ToFloat64(c.hits.WithLabelValues(cacheTypePostings)))
+	testutil.Equals(t, 2.0, promtest.ToFloat64(c.requests.WithLabelValues(cacheTypeSeries)))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(c.hits.WithLabelValues(cacheTypeSeries)))
+}
+
+func TestNewIndexCacheFromConfig_Redis(t *testing.T) {
+	s, err := miniredis.Run()
+	testutil.Ok(t, err)
+	defer s.Close()
+
+	c, err := NewIndexCacheFromConfig(log.NewNopLogger(), []byte(`
+type: REDIS
+config:
+  addresses: ["`+s.Addr()+`"]
+  ttl: 1h
+`), nil)
+	testutil.Ok(t, err)
+	rc, ok := c.(*RedisIndexCache)
+	testutil.Assert(t, ok, "expected redis cache, got %T", c)
+	defer rc.Stop()
+	testutil.Equals(t, time.Hour, rc.ttl)
+}
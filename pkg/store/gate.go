@@ -4,25 +4,43 @@ import (
 	"context"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/pkg/gate"
 )
 
+// errGateTimeout is returned by the gate if a query waited in the queue for longer than the max queue duration.
+var errGateTimeout = errors.New("timed out waiting in the query queue")
+
 // Gate wraps the Prometheus gate with extra metrics.
 type Gate struct {
-	g               *gate.Gate
+	g                *gate.Gate
+	maxQueueDuration time.Duration
+
 	inflightQueries prometheus.Gauge
+	queuedQueries   prometheus.Gauge
+	queueTimeouts   prometheus.Counter
 	gateTiming      prometheus.Histogram
 }
 
-// NewGate returns a new query gate.
-func NewGate(maxConcurrent int, reg prometheus.Registerer) *Gate {
+// NewGate returns a new query gate. Queries waiting for their turn longer than maxQueueDuration fail with
+// errGateTimeout. 0 means no limit of the waiting time.
+func NewGate(maxConcurrent int, maxQueueDuration time.Duration, reg prometheus.Registerer) *Gate {
 	g := &Gate{
-		g: gate.New(maxConcurrent),
+		g:                gate.New(maxConcurrent),
+		maxQueueDuration: maxQueueDuration,
 		inflightQueries: prometheus.NewGauge(prometheus.GaugeOpts{
 			Name: "gate_queries_in_flight",
 			Help: "Number of queries that are currently in flight.",
 		}),
+		queuedQueries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "gate_queries_queued",
+			Help: "Number of queries that are currently waiting at the gate.",
+		}),
+		queueTimeouts: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gate_queries_timed_out_total",
+			Help: "Total number of queries that failed because they waited at the gate for longer than the max queue duration.",
+		}),
 		gateTiming: prometheus.NewHistogram(prometheus.HistogramOpts{
 			Name: "gate_duration_seconds",
 			Help: "How many seconds it took for queries to wait at the gate.",
@@ -33,7 +51,7 @@ func NewGate(maxConcurrent int, reg prometheus.Registerer) *Gate {
 	}
 
 	if reg != nil {
-		reg.MustRegister(g.inflightQueries, g.gateTiming)
+		reg.MustRegister(g.inflightQueries, g.queuedQueries, g.queueTimeouts, g.gateTiming)
 	}
 
 	return g
@@ -43,10 +61,25 @@ func NewGate(maxConcurrent int, reg prometheus.Registerer) *Gate {
 func (g *Gate) IsMyTurn(ctx context.Context) error {
 	start := time.Now()
 	defer func() {
-		g.gateTiming.Observe(float64(time.Now().Sub(start)))
+		g.gateTiming.Observe(time.Since(start).Seconds())
 	}()
 
-	if err := g.g.Start(ctx); err != nil {
+	g.queuedQueries.Inc()
+	defer g.queuedQueries.Dec()
+
+	queueCtx := ctx
+	if g.maxQueueDuration > 0 {
+		var cancel context.CancelFunc
+		queueCtx, cancel = context.WithTimeout(ctx, g.maxQueueDuration)
+		defer cancel()
+	}
+
+	if err := g.g.Start(queueCtx); err != nil {
+		// Distinguish our own timeout from the cancellation of the query.
+		if ctx.Err() == nil && queueCtx.Err() == context.DeadlineExceeded {
+			g.queueTimeouts.Inc()
+			return errGateTimeout
+		}
 		return err
 	}
 
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestGate_MaxQueueDuration(t *testing.T) {
+	g := NewGate(1, 50*time.Millisecond, prometheus.NewRegistry())
+	ctx := context.Background()
+
+	testutil.Ok(t, g.IsMyTurn(ctx))
+	testutil.Equals(t, float64(1), prom_testutil.ToFloat64(g.inflightQueries))
+
+	// The only slot is taken, so the second query times out in the queue.
+	testutil.Equals(t, errGateTimeout, g.IsMyTurn(ctx))
+	testutil.Equals(t, float64(1), prom_testutil.ToFloat64(g.queueTimeouts))
+	testutil.Equals(t, float64(0), prom_testutil.ToFloat64(g.queuedQueries))
+
+	// Cancellation of the query itself is not a queue timeout.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	testutil.Equals(t, context.Canceled, g.IsMyTurn(cctx))
+	testutil.Equals(t, float64(1), prom_testutil.ToFloat64(g.queueTimeouts))
+
+	g.Done()
+	testutil.Ok(t, g.IsMyTurn(ctx))
+	g.Done()
+	testutil.Equals(t, float64(0), prom_testutil.ToFloat64(g.inflightQueries))
+}
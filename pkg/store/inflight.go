@@ -0,0 +1,265 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// inflightSeries deduplicates identical concurrent Series requests. The first request starts the fetch and every
+// identical request arriving while it is running subscribes to it. Responses of the fetch are buffered, so late
+// subscribers can replay them from the start, and streamed to all subscribers. Once the buffered responses of a call
+// exceed maxBuffer bytes, no further subscribers can join it and responses are only kept until all subscribers
+// received them. The fetch is canceled once all subscribers are gone.
+type inflightSeries struct {
+	mtx       sync.Mutex
+	calls     map[string]*seriesCall
+	maxBuffer int
+
+	deduplicated prometheus.Counter
+}
+
+func newInflightSeries(maxBuffer int, deduplicated prometheus.Counter) *inflightSeries {
+	return &inflightSeries{
+		calls:        map[string]*seriesCall{},
+		maxBuffer:    maxBuffer,
+		deduplicated: deduplicated,
+	}
+}
+
+// seriesCall is a single fetch shared by identical Series requests.
+type seriesCall struct {
+	mtx sync.Mutex
+	// resps holds the responses from index base on. While the call is shared, these are all responses, afterwards
+	// only the ones not yet received by all subscribers.
+	resps  []*storepb.SeriesResponse
+	base   int
+	size   int
+	shared bool
+	// notify is closed and replaced whenever a response is added or received, or the call is done.
+	notify      chan struct{}
+	done        bool
+	err         error
+	subscribers map[*subscription]struct{}
+
+	maxBuffer int
+	// unshare removes the call from the calls in flight, so that identical requests start a new call.
+	unshare func()
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// subscription is the position of a subscriber in the responses of a call.
+type subscription struct {
+	next int
+}
+
+// broadcast wakes up everyone waiting for the call. It must be called with the mutex of the call held.
+func (c *seriesCall) broadcast() {
+	close(c.notify)
+	c.notify = make(chan struct{})
+}
+
+// trim drops the responses all subscribers received, once the call is no longer shared. It must be called with the
+// mutex of the call held.
+func (c *seriesCall) trim() {
+	if c.shared {
+		return
+	}
+	min := c.base + len(c.resps)
+	for sub := range c.subscribers {
+		if sub.next < min {
+			min = sub.next
+		}
+	}
+	for i := 0; i < min-c.base; i++ {
+		c.size -= c.resps[i].Size()
+		c.resps[i] = nil
+	}
+	c.resps = c.resps[min-c.base:]
+	c.base = min
+}
+
+// Send implements the storepb.Store_SeriesServer interface for the fetch of the call. Once the call is no longer
+// shared, it blocks while the buffered responses exceed the maximum buffer size, until subscribers received them.
+func (c *seriesCall) Send(r *storepb.SeriesResponse) error {
+	// The chunk data of the response may be released to the pool once the fetch is done, but subscribers may
+	// replay the response later.
+	if s := r.GetSeries(); s != nil {
+		for i := range s.Chunks {
+			copyChunkData(&s.Chunks[i])
+		}
+	}
+
+	c.mtx.Lock()
+	c.resps = append(c.resps, r)
+	c.size += r.Size()
+	c.broadcast()
+
+	if c.shared && c.size > c.maxBuffer {
+		// No subscriber may join once responses are dropped. Subscribers join while holding the lock of the calls
+		// in flight, so all of them have joined once the call is removed.
+		c.mtx.Unlock()
+		c.unshare()
+		c.mtx.Lock()
+
+		c.shared = false
+		c.trim()
+	}
+	defer c.mtx.Unlock()
+
+	for !c.shared && c.size > c.maxBuffer {
+		notify := c.notify
+		c.mtx.Unlock()
+
+		select {
+		case <-notify:
+		case <-c.ctx.Done():
+			c.mtx.Lock()
+			return c.ctx.Err()
+		}
+		c.mtx.Lock()
+	}
+	return nil
+}
+
+func (c *seriesCall) finish(err error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.done = true
+	c.err = err
+	c.broadcast()
+}
+
+// seriesCallServer is the storepb.Store_SeriesServer the fetch of a call writes its responses to.
+type seriesCallServer struct {
+	// Only Send and Context are used by the bucket store.
+	storepb.Store_SeriesServer
+
+	call *seriesCall
+}
+
+func (s *seriesCallServer) Send(r *storepb.SeriesResponse) error { return s.call.Send(r) }
+
+func (s *seriesCallServer) Context() context.Context { return s.call.ctx }
+
+// valuesContext has the values of its parent, but neither its deadline nor its cancelation.
+type valuesContext struct {
+	context.Context
+}
+
+func (valuesContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (valuesContext) Done() <-chan struct{} { return nil }
+
+func (valuesContext) Err() error { return nil }
+
+// serve streams the responses of the call identical to the given request to srv, starting the call with fetch
+// if there is none in flight.
+func (f *inflightSeries) serve(
+	req *storepb.SeriesRequest,
+	srv storepb.Store_SeriesServer,
+	fetch func(*storepb.SeriesRequest, storepb.Store_SeriesServer) error,
+) error {
+	b, err := req.Marshal()
+	if err != nil {
+		return errors.Wrap(err, "marshal request")
+	}
+	key := string(b)
+
+	f.mtx.Lock()
+	c, ok := f.calls[key]
+	if ok {
+		f.deduplicated.Inc()
+	} else {
+		// The fetch must not depend on the first subscriber, but keeps the values of its context, e.g. for tracing.
+		ctx, cancel := context.WithCancel(valuesContext{srv.Context()})
+		c = &seriesCall{
+			shared:      true,
+			notify:      make(chan struct{}),
+			subscribers: map[*subscription]struct{}{},
+			maxBuffer:   f.maxBuffer,
+			ctx:         ctx,
+			cancel:      cancel,
+		}
+		c.unshare = func() {
+			f.mtx.Lock()
+			if f.calls[key] == c {
+				delete(f.calls, key)
+			}
+			f.mtx.Unlock()
+		}
+		f.calls[key] = c
+
+		go func() {
+			err := fetch(req, &seriesCallServer{call: c})
+			c.unshare()
+			c.finish(err)
+			cancel()
+		}()
+	}
+	// Calls are removed from the calls in flight before they stop being shared, so all responses are still buffered.
+	sub := &subscription{}
+	c.mtx.Lock()
+	c.subscribers[sub] = struct{}{}
+	c.mtx.Unlock()
+	f.mtx.Unlock()
+
+	defer func() {
+		f.mtx.Lock()
+		c.mtx.Lock()
+		delete(c.subscribers, sub)
+		last := len(c.subscribers) == 0 && !c.done
+		c.trim()
+		c.broadcast()
+		c.mtx.Unlock()
+
+		// Nobody is interested in the result anymore. New identical requests must start a new call.
+		if last && f.calls[key] == c {
+			delete(f.calls, key)
+		}
+		f.mtx.Unlock()
+
+		if last {
+			c.cancel()
+		}
+	}()
+
+	for {
+		c.mtx.Lock()
+		for sub.next >= c.base+len(c.resps) && !c.done {
+			notify := c.notify
+			c.mtx.Unlock()
+
+			select {
+			case <-notify:
+			case <-srv.Context().Done():
+				return srv.Context().Err()
+			}
+			c.mtx.Lock()
+		}
+		if sub.next >= c.base+len(c.resps) {
+			err := c.err
+			c.mtx.Unlock()
+			return err
+		}
+		r := c.resps[sub.next-c.base]
+		sub.next++
+		if !c.shared {
+			// Let the fetch continue if it waits for the buffer to drain.
+			c.trim()
+			c.broadcast()
+		}
+		c.mtx.Unlock()
+
+		if err := srv.Send(r); err != nil {
+			return errors.Wrap(err, "send series response")
+		}
+	}
+}
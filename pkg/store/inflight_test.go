@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInflightSeries_Deduplication(t *testing.T) {
+	f := newInflightSeries(1<<20, prometheus.NewCounter(prometheus.CounterOpts{}))
+
+	var (
+		fetches int
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	fetch := func(req *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+		fetches++
+		if err := srv.Send(storepb.NewSeriesResponse(&storepb.Series{Labels: []storepb.Label{{Name: "a", Value: "1"}}})); err != nil {
+			return err
+		}
+		close(started)
+		<-release
+		return srv.Send(storepb.NewSeriesResponse(&storepb.Series{Labels: []storepb.Label{{Name: "a", Value: "2"}}}))
+	}
+	req := &storepb.SeriesRequest{MinTime: 0, MaxTime: 10, Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "a", Value: "1"}}}
+
+	var wg sync.WaitGroup
+	srvs := []*storeSeriesServer{newStoreSeriesServer(context.Background()), newStoreSeriesServer(context.Background())}
+	for i, srv := range srvs {
+		wg.Add(1)
+		go func(srv *storeSeriesServer) {
+			defer wg.Done()
+			testutil.Ok(t, f.serve(req, srv, fetch))
+		}(srv)
+
+		if i == 0 {
+			<-started
+		}
+	}
+
+	// Release the fetch only once the second call subscribed to it.
+	for promtest.ToFloat64(f.deduplicated) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	testutil.Equals(t, 1, fetches)
+	testutil.Equals(t, 2, len(srvs[0].SeriesSet))
+	testutil.Equals(t, srvs[0].SeriesSet, srvs[1].SeriesSet)
+
+	// Finished calls are not reused.
+	srv := newStoreSeriesServer(context.Background())
+	testutil.Ok(t, f.serve(req, srv, func(req *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+		fetches++
+		return nil
+	}))
+	testutil.Equals(t, 2, fetches)
+	testutil.Equals(t, 0, len(srv.SeriesSet))
+}
+
+func TestInflightSeries_CancelWithoutSubscribers(t *testing.T) {
+	f := newInflightSeries(1<<20, prometheus.NewCounter(prometheus.CounterOpts{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fetchErr := make(chan error, 1)
+	fetch := func(req *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+		cancel()
+		<-srv.Context().Done()
+		fetchErr <- srv.Context().Err()
+		return srv.Context().Err()
+	}
+
+	err := f.serve(&storepb.SeriesRequest{MaxTime: 10}, newStoreSeriesServer(ctx), fetch)
+	testutil.Equals(t, context.Canceled, err)
+
+	// The fetch is canceled once its only subscriber is gone.
+	select {
+	case err := <-fetchErr:
+		testutil.Equals(t, context.Canceled, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("fetch not canceled")
+	}
+}
+
+type testContextKey struct{}
+
+func TestInflightSeries_MaxBuffer(t *testing.T) {
+	series := func(v string) *storepb.SeriesResponse {
+		return storepb.NewSeriesResponse(&storepb.Series{Labels: []storepb.Label{{Name: "a", Value: v}}})
+	}
+	// Only a single response fits into the buffer.
+	f := newInflightSeries(series("1").Size(), prometheus.NewCounter(prometheus.CounterOpts{}))
+
+	var (
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	fetch := func(req *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+		// The fetch keeps the values of the context of the first request, but not its deadline.
+		testutil.Equals(t, "value", srv.Context().Value(testContextKey{}))
+		_, ok := srv.Context().Deadline()
+		testutil.Assert(t, !ok, "unexpected deadline")
+
+		for _, v := range []string{"1", "2"} {
+			if err := srv.Send(series(v)); err != nil {
+				return err
+			}
+		}
+		close(started)
+		<-release
+		for _, v := range []string{"3", "4", "5"} {
+			if err := srv.Send(series(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	req := &storepb.SeriesRequest{MinTime: 0, MaxTime: 10}
+
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), testContextKey{}, "value"), time.Minute)
+	defer cancel()
+
+	srv := newStoreSeriesServer(ctx)
+	errc := make(chan error, 1)
+	go func() { errc <- f.serve(req, srv, fetch) }()
+	<-started
+
+	// The call exceeds the buffer, so identical requests no longer join it.
+	fetches := 0
+	other := newStoreSeriesServer(context.Background())
+	testutil.Ok(t, f.serve(req, other, func(req *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+		fetches++
+		return srv.Send(series("other"))
+	}))
+	testutil.Equals(t, 1, fetches)
+	testutil.Equals(t, 1, len(other.SeriesSet))
+	testutil.Equals(t, 0.0, promtest.ToFloat64(f.deduplicated))
+
+	// Its own subscriber still receives all responses.
+	close(release)
+	testutil.Ok(t, <-errc)
+	testutil.Equals(t, 5, len(srv.SeriesSet))
+	for i, s := range srv.SeriesSet {
+		testutil.Equals(t, []storepb.Label{{Name: "a", Value: strconv.Itoa(i + 1)}}, s.Labels)
+	}
+}
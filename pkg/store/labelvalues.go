@@ -0,0 +1,108 @@
+package store
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+)
+
+// storeLabelValuesCache caches all values of labels returned by stores, so that requests with matchers none of the
+// values of a store match are not sent to that store. Stores gain new label values over time, so the values are used
+// only for the given TTL.
+type storeLabelValuesCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mtx sync.Mutex
+	// values holds the label values of each store address by label name.
+	values map[string]map[string]cachedLabelValues
+}
+
+type cachedLabelValues struct {
+	values  map[string]struct{}
+	updated time.Time
+}
+
+func newStoreLabelValuesCache(ttl time.Duration) *storeLabelValuesCache {
+	return &storeLabelValuesCache{
+		ttl:    ttl,
+		now:    time.Now,
+		values: map[string]map[string]cachedLabelValues{},
+	}
+}
+
+// completeLabelValuesRequest returns true if the response to the given request contains all values of the label the
+// store knows.
+func completeLabelValuesRequest(st Client, r *storepb.LabelValuesRequest) bool {
+	if len(r.Matchers) > 0 {
+		return false
+	}
+	mint, maxt := r.MinTime, r.MaxTime
+	if mint == 0 && maxt == 0 {
+		mint, maxt = math.MinInt64, math.MaxInt64
+	}
+	storeMint, storeMaxt := st.TimeRange()
+	return mint <= storeMint && maxt >= storeMaxt
+}
+
+// set stores all values of the given label of the store with the given address.
+func (c *storeLabelValuesCache) set(addr, name string, values []string) {
+	cached := cachedLabelValues{values: make(map[string]struct{}, len(values)), updated: c.now()}
+	for _, v := range values {
+		cached.values[v] = struct{}{}
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.values[addr] == nil {
+		c.values[addr] = map[string]cachedLabelValues{}
+	}
+	c.values[addr][name] = cached
+}
+
+// mayMatch returns false if the store with the given address provably has no series matching all matchers, which
+// is the case if a matcher not matching the empty value matches none of the cached values of its label.
+func (c *storeLabelValuesCache) mayMatch(addr string, matchers []storepb.LabelMatcher) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	byName, ok := c.values[addr]
+	if !ok {
+		return true
+	}
+	now := c.now()
+
+	for _, m := range matchers {
+		cached, ok := byName[m.Name]
+		if !ok {
+			continue
+		}
+		if now.Sub(cached.updated) > c.ttl {
+			delete(byName, m.Name)
+			continue
+		}
+
+		tm, err := translateMatcher(m)
+		if err != nil {
+			return true
+		}
+		// Series without the label match matchers matching the empty value.
+		if tm.Matches("") {
+			continue
+		}
+		matches := false
+		for v := range cached.values {
+			if tm.Matches(v) {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/leaktest"
+	"github.com/improbable-eng/thanos/pkg/component"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+func TestStoreLabelValuesCache(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := newStoreLabelValuesCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.set("store", "job", []string{"api", "db"})
+
+	for _, tcase := range []struct {
+		name     string
+		addr     string
+		matchers []storepb.LabelMatcher
+		expected bool
+	}{
+		{name: "unknown store", addr: "other", matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "web"}}, expected: true},
+		{name: "unknown label", addr: "store", matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "env", Value: "prod"}}, expected: true},
+		{name: "cached value", addr: "store", matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "db"}}, expected: true},
+		{name: "value not cached", addr: "store", matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "web"}}, expected: false},
+		{name: "regexp matching cached value", addr: "store", matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_RE, Name: "job", Value: "web|api"}}, expected: true},
+		{name: "regexp matching no cached value", addr: "store", matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_RE, Name: "job", Value: "web.*"}}, expected: false},
+		{name: "matcher matching the empty value", addr: "store", matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_NEQ, Name: "job", Value: "api"}}, expected: true},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			testutil.Equals(t, tcase.expected, c.mayMatch(tcase.addr, tcase.matchers))
+		})
+	}
+
+	// Expired values are not used anymore.
+	now = now.Add(2 * time.Minute)
+	testutil.Assert(t, c.mayMatch("store", []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "web"}}), "expired label values used")
+}
+
+func TestProxyStore_LabelValuesCache(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	m := &mockedStoreAPI{
+		RespSeries:      []*storepb.SeriesResponse{storeSeriesResponse(t, labels.FromStrings("job", "api"), []sample{{1, 1}})},
+		RespLabelValues: &storepb.LabelValuesResponse{Values: []string{"api"}},
+	}
+	q := NewProxyStore(nil,
+		func() []Client { return []Client{&testClient{StoreClient: m, minTime: 1, maxTime: 300}} },
+		component.Query,
+		nil,
+		0*time.Second,
+		nil, 0, false, time.Minute,
+		"", "",
+	)
+	ctx := context.Background()
+	req := &storepb.SeriesRequest{MinTime: 1, MaxTime: 300, Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "db"}}}
+
+	// Without cached values the store is queried.
+	testutil.Ok(t, q.Series(req, newStoreSeriesServer(ctx)))
+	testutil.Assert(t, m.LastSeriesReq != nil, "store not queried")
+
+	// Values of requests with matchers are not complete.
+	_, err := q.LabelValues(ctx, &storepb.LabelValuesRequest{Label: "job", Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "env", Value: "prod"}}})
+	testutil.Ok(t, err)
+	m.LastSeriesReq = nil
+	testutil.Ok(t, q.Series(req, newStoreSeriesServer(ctx)))
+	testutil.Assert(t, m.LastSeriesReq != nil, "store not queried")
+
+	// Once all values are known, the store is skipped for values it does not have.
+	_, err = q.LabelValues(ctx, &storepb.LabelValuesRequest{Label: "job"})
+	testutil.Ok(t, err)
+	m.LastSeriesReq = nil
+	testutil.Ok(t, q.Series(req, newStoreSeriesServer(ctx)))
+	testutil.Assert(t, m.LastSeriesReq == nil, "store queried although it has no matching label value")
+
+	req.Matchers[0].Value = "api"
+	testutil.Ok(t, q.Series(req, newStoreSeriesServer(ctx)))
+	testutil.Assert(t, m.LastSeriesReq != nil, "store not queried")
+}
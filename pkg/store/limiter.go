@@ -1,18 +1,37 @@
 package store
 
 import (
+	"fmt"
+	"sync/atomic"
+
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Limiter is a simple mechanism for checking if something has passed a certain threshold.
 type Limiter struct {
-	limit uint64
+	limit    uint64
+	reserved uint64
 
-	// Counter metric which we will increase if Check() fails.
+	// Counter metric which we will increase if Check() or Reserve() fails.
 	failedCounter prometheus.Counter
 }
 
+// limitExceededError is returned by the Limiter when the limit is exceeded.
+type limitExceededError struct {
+	limit, got uint64
+}
+
+func (e limitExceededError) Error() string {
+	return fmt.Sprintf("limit %v violated (got %v)", e.limit, e.got)
+}
+
+// isLimitExceeded returns true if the cause of the given error is an exceeded limit.
+func isLimitExceeded(err error) bool {
+	_, ok := errors.Cause(err).(limitExceededError)
+	return ok
+}
+
 // NewLimiter returns a new limiter with a specified limit. 0 disables the limit.
 func NewLimiter(limit uint64, ctr prometheus.Counter) *Limiter {
 	return &Limiter{limit: limit, failedCounter: ctr}
@@ -20,12 +39,29 @@ func NewLimiter(limit uint64, ctr prometheus.Counter) *Limiter {
 
 // Check checks if the passed number exceeds the limits or not.
 func (l *Limiter) Check(num uint64) error {
-	if l.limit == 0 {
+	if l == nil || l.limit == 0 {
 		return nil
 	}
 	if num > l.limit {
 		l.failedCounter.Inc()
-		return errors.Errorf("limit %v violated (got %v)", l.limit, num)
+		return limitExceededError{limit: l.limit, got: num}
+	}
+	return nil
+}
+
+// Reserve adds the passed number to the total reserved so far and checks if the total exceeds the limit.
+// It is safe to be called concurrently. The failure is counted only once per limiter.
+func (l *Limiter) Reserve(num uint64) error {
+	if l == nil || l.limit == 0 {
+		return nil
+	}
+	reserved := atomic.AddUint64(&l.reserved, num)
+	if reserved > l.limit {
+		// Count only the reservation which crossed the limit.
+		if reserved-num <= l.limit {
+			l.failedCounter.Inc()
+		}
+		return limitExceededError{limit: l.limit, got: reserved}
 	}
 	return nil
 }
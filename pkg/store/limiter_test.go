@@ -0,0 +1,36 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLimiter(t *testing.T) {
+	c := prometheus.NewCounter(prometheus.CounterOpts{})
+	l := NewLimiter(10, c)
+
+	testutil.Ok(t, l.Check(10))
+	testutil.Ok(t, l.Reserve(5))
+	testutil.Ok(t, l.Reserve(5))
+	testutil.Equals(t, float64(0), prom_testutil.ToFloat64(c))
+
+	err := l.Reserve(1)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, isLimitExceeded(errors.Wrap(err, "wrapped")), "expected limit exceeded error")
+	testutil.NotOk(t, l.Reserve(1))
+	// Only the reservation crossing the limit is counted.
+	testutil.Equals(t, float64(1), prom_testutil.ToFloat64(c))
+
+	testutil.NotOk(t, l.Check(11))
+	testutil.Equals(t, float64(2), prom_testutil.ToFloat64(c))
+
+	// Zero limit and nil limiter do not limit anything.
+	testutil.Ok(t, NewLimiter(0, c).Reserve(100))
+	var nilLimiter *Limiter
+	testutil.Ok(t, nilLimiter.Reserve(100))
+	testutil.Ok(t, nilLimiter.Check(100))
+}
@@ -1,6 +1,9 @@
 package store
 
 import (
+	"regexp/syntax"
+	"strings"
+
 	"github.com/improbable-eng/thanos/pkg/store/storepb"
 	"github.com/pkg/errors"
 	"github.com/prometheus/tsdb/labels"
@@ -15,14 +18,10 @@ func translateMatcher(m storepb.LabelMatcher) (labels.Matcher, error) {
 		return labels.Not(labels.NewEqualMatcher(m.Name, m.Value)), nil
 
 	case storepb.LabelMatcher_RE:
-		return labels.NewRegexpMatcher(m.Name, "^(?:"+m.Value+")$")
+		return newRegexpMatcher(m.Name, m.Value, false)
 
 	case storepb.LabelMatcher_NRE:
-		m, err := labels.NewRegexpMatcher(m.Name, "^(?:"+m.Value+")$")
-		if err != nil {
-			return nil, err
-		}
-		return labels.Not(m), nil
+		return newRegexpMatcher(m.Name, m.Value, true)
 	}
 	return nil, errors.Errorf("unknown label matcher type %d", m.Type)
 }
@@ -37,3 +36,166 @@ func translateMatchers(ms []storepb.LabelMatcher) (res []labels.Matcher, err err
 	}
 	return res, nil
 }
+
+// maxSetMatcherValues is the maximum number of values a regexp can match to be turned into a setMatcher.
+const maxSetMatcherValues = 256
+
+// newRegexpMatcher returns a matcher for the given fully anchored regexp. Regexps matching a small set of values, such as
+// "a|b|c", and prefix regexps, such as "foo.*", are turned into matchers which the bucket store resolves with direct
+// postings lookups instead of running the regexp against all values of the label.
+func newRegexpMatcher(name, value string, negate bool) (labels.Matcher, error) {
+	m, err := labels.NewRegexpMatcher(name, "^(?:"+value+")$")
+	if err != nil {
+		return nil, err
+	}
+
+	if re, err := syntax.Parse(value, syntax.Perl); err == nil {
+		re = stripAnchors(re.Simplify())
+		if values, ok := regexpValues(re); ok {
+			m = newSetMatcher(name, values)
+		} else if prefix, ok := regexpPrefix(re); ok {
+			m = &prefixMatcher{name: name, prefix: prefix}
+		}
+	}
+
+	if negate {
+		m = labels.Not(m)
+	}
+	return m, nil
+}
+
+// stripAnchors removes the redundant leading ^ and trailing $ of a regexp, as all label regexps are anchored.
+func stripAnchors(re *syntax.Regexp) *syntax.Regexp {
+	if re.Op != syntax.OpConcat || len(re.Sub) == 0 {
+		return re
+	}
+	sub := re.Sub
+	if sub[0].Op == syntax.OpBeginText {
+		sub = sub[1:]
+	}
+	if len(sub) > 0 && sub[len(sub)-1].Op == syntax.OpEndText {
+		sub = sub[:len(sub)-1]
+	}
+	if len(sub) == len(re.Sub) {
+		return re
+	}
+	res := *re
+	res.Sub = sub
+	return &res
+}
+
+// regexpValues returns all strings the regexp matches if they are few enough to be enumerated.
+func regexpValues(re *syntax.Regexp) ([]string, bool) {
+	switch re.Op {
+	case syntax.OpEmptyMatch:
+		return []string{""}, true
+
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return nil, false
+		}
+		return []string{string(re.Rune)}, true
+
+	case syntax.OpCapture:
+		return regexpValues(re.Sub[0])
+
+	case syntax.OpQuest:
+		values, ok := regexpValues(re.Sub[0])
+		if !ok || len(values) >= maxSetMatcherValues {
+			return nil, false
+		}
+		return append(values, ""), true
+
+	case syntax.OpCharClass:
+		// Rune holds pairs of inclusive ranges.
+		var res []string
+		for i := 0; i < len(re.Rune); i += 2 {
+			for r := re.Rune[i]; r <= re.Rune[i+1]; r++ {
+				if len(res) >= maxSetMatcherValues {
+					return nil, false
+				}
+				res = append(res, string(r))
+			}
+		}
+		return res, true
+
+	case syntax.OpAlternate:
+		var res []string
+		for _, sub := range re.Sub {
+			values, ok := regexpValues(sub)
+			if !ok || len(res)+len(values) > maxSetMatcherValues {
+				return nil, false
+			}
+			res = append(res, values...)
+		}
+		return res, true
+
+	case syntax.OpConcat:
+		// The parser factors out common prefixes of alternations, e.g. "foo|foobar" becomes "foo(?:|bar)".
+		res := []string{""}
+		for _, sub := range re.Sub {
+			values, ok := regexpValues(sub)
+			if !ok || len(res)*len(values) > maxSetMatcherValues {
+				return nil, false
+			}
+			next := make([]string, 0, len(res)*len(values))
+			for _, prefix := range res {
+				for _, v := range values {
+					next = append(next, prefix+v)
+				}
+			}
+			res = next
+		}
+		return res, true
+	}
+	return nil, false
+}
+
+// regexpPrefix returns the literal prefix of a regexp of the form "prefix.*".
+func regexpPrefix(re *syntax.Regexp) (string, bool) {
+	if re.Op != syntax.OpConcat || len(re.Sub) != 2 {
+		return "", false
+	}
+	lit, star := re.Sub[0], re.Sub[1]
+	if lit.Op != syntax.OpLiteral || lit.Flags&syntax.FoldCase != 0 {
+		return "", false
+	}
+	if star.Op != syntax.OpStar || star.Sub[0].Op != syntax.OpAnyCharNotNL {
+		return "", false
+	}
+	return string(lit.Rune), true
+}
+
+// setMatcher matches a fixed set of values.
+type setMatcher struct {
+	name   string
+	values map[string]struct{}
+}
+
+func newSetMatcher(name string, values []string) *setMatcher {
+	m := &setMatcher{name: name, values: make(map[string]struct{}, len(values))}
+	for _, v := range values {
+		m.values[v] = struct{}{}
+	}
+	return m
+}
+
+func (m *setMatcher) Name() string { return m.name }
+
+func (m *setMatcher) Matches(v string) bool {
+	_, ok := m.values[v]
+	return ok
+}
+
+// prefixMatcher matches values starting with the prefix, which contain no new line after it, as '.' does not
+// match new lines.
+type prefixMatcher struct {
+	name   string
+	prefix string
+}
+
+func (m *prefixMatcher) Name() string { return m.name }
+
+func (m *prefixMatcher) Matches(v string) bool {
+	return strings.HasPrefix(v, m.prefix) && !strings.Contains(v[len(m.prefix):], "\n")
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/tsdb/labels"
+)
+
+func TestTranslateMatcher_RegexpOptimization(t *testing.T) {
+	values := []string{"", "a", "b", "c", "ab", "abc", "foo", "foobar", "foo\nbar", "FOO", "bar", "foo1", "x", "xy"}
+
+	for _, tcase := range []struct {
+		regexp   string
+		expected string
+	}{
+		{regexp: "a|b|c", expected: "set"},
+		{regexp: "^(a|b|c)$", expected: "set"},
+		{regexp: "foo|foobar", expected: "set"},
+		{regexp: "foo(bar|1)?", expected: "set"},
+		{regexp: "a|", expected: "set"},
+		{regexp: "[a-c]", expected: "set"},
+		{regexp: "foo.*", expected: "prefix"},
+		{regexp: "^foo.*$", expected: "prefix"},
+		{regexp: "(?i)foo", expected: "regexp"},
+		{regexp: "foo.+", expected: "regexp"},
+		{regexp: ".*foo", expected: "regexp"},
+		{regexp: "[^a]", expected: "regexp"},
+		{regexp: "x(y|)", expected: "set"},
+	} {
+		t.Run(tcase.regexp, func(t *testing.T) {
+			re := regexp.MustCompile("^(?:" + tcase.regexp + ")$")
+
+			m, err := translateMatcher(storepb.LabelMatcher{Type: storepb.LabelMatcher_RE, Name: "n", Value: tcase.regexp})
+			testutil.Ok(t, err)
+			testutil.Equals(t, "n", m.Name())
+			testutil.Equals(t, tcase.expected, matcherKind(m))
+
+			nm, err := translateMatcher(storepb.LabelMatcher{Type: storepb.LabelMatcher_NRE, Name: "n", Value: tcase.regexp})
+			testutil.Ok(t, err)
+
+			// The optimized matchers match exactly like the regexp.
+			for _, v := range values {
+				testutil.Equals(t, re.MatchString(v), m.Matches(v))
+				testutil.Equals(t, !re.MatchString(v), nm.Matches(v))
+			}
+		})
+	}
+
+	_, err := translateMatcher(storepb.LabelMatcher{Type: storepb.LabelMatcher_RE, Name: "n", Value: "a("})
+	testutil.NotOk(t, err)
+}
+
+func matcherKind(m labels.Matcher) string {
+	switch m.(type) {
+	case *setMatcher:
+		return "set"
+	case *prefixMatcher:
+		return "prefix"
+	}
+	return "regexp"
+}
+
+func TestToPostingGroup_OptimizedMatchers(t *testing.T) {
+	lvals := map[string][]string{"n": {"a", "b", "foo", "foo\nbar", "foo1", "foobar", "x"}}
+	lvalsFn := func(name string) []string { return lvals[name] }
+
+	m, err := translateMatcher(storepb.LabelMatcher{Type: storepb.LabelMatcher_RE, Name: "n", Value: "b|a|missing"})
+	testutil.Ok(t, err)
+	// Values are looked up directly, including those that are not present.
+	testutil.Equals(t, labels.Labels{{Name: "n", Value: "a"}, {Name: "n", Value: "b"}, {Name: "n", Value: "missing"}}, toPostingGroup(lvalsFn, m).keys)
+
+	m, err = translateMatcher(storepb.LabelMatcher{Type: storepb.LabelMatcher_RE, Name: "n", Value: "foo.*"})
+	testutil.Ok(t, err)
+	testutil.Equals(t, labels.Labels{{Name: "n", Value: "foo"}, {Name: "n", Value: "foo1"}, {Name: "n", Value: "foobar"}}, toPostingGroup(lvalsFn, m).keys)
+}
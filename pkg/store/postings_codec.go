@@ -0,0 +1,86 @@
+package store
+
+import (
+	"encoding/binary"
+
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+	"github.com/prometheus/tsdb/index"
+)
+
+// Encodings of the postings lists stored in the index cache. Raw postings lists of the index are stored with
+// PostingsCompressionNone.
+const (
+	PostingsCompressionNone             = "none"
+	PostingsCompressionDiffVarint       = "diff-varint"
+	PostingsCompressionDiffVarintSnappy = "diff-varint-snappy"
+)
+
+// Headers of the encoded postings lists. Raw postings lists start with their 4 byte length, which is never this large,
+// so encoded and raw lists can be told apart, e.g. when the compression is changed while a remote cache is in use.
+var (
+	diffVarintHeader       = []byte("dvp")
+	diffVarintSnappyHeader = []byte("dvs")
+)
+
+func isEncodedPostings(b []byte, header []byte) bool {
+	return len(b) >= len(header) && string(b[:len(header)]) == string(header)
+}
+
+// encodePostings encodes the sorted postings with the given compression for the index cache. Postings are stored as
+// deltas between consecutive values, which are small for series of the same label, as uvarints.
+func encodePostings(compression string, ps []uint64) ([]byte, error) {
+	header := diffVarintHeader
+	if compression == PostingsCompressionDiffVarintSnappy {
+		header = diffVarintSnappyHeader
+	}
+
+	buf := make([]byte, 0, len(header)+2*len(ps))
+	buf = append(buf, header...)
+	var (
+		tmp  [binary.MaxVarintLen64]byte
+		prev uint64
+	)
+	for _, p := range ps {
+		if p < prev {
+			return nil, errors.Errorf("postings not sorted: %d after %d", p, prev)
+		}
+		n := binary.PutUvarint(tmp[:], p-prev)
+		buf = append(buf, tmp[:n]...)
+		prev = p
+	}
+
+	if compression != PostingsCompressionDiffVarintSnappy {
+		return buf, nil
+	}
+	return append(append([]byte(nil), header...), snappy.Encode(nil, buf[len(header):])...), nil
+}
+
+// decodePostings decodes postings from the index cache. It returns false if they are raw postings of the index.
+func decodePostings(b []byte) (index.Postings, bool, error) {
+	switch {
+	case isEncodedPostings(b, diffVarintHeader):
+		b = b[len(diffVarintHeader):]
+	case isEncodedPostings(b, diffVarintSnappyHeader):
+		var err error
+		b, err = snappy.Decode(nil, b[len(diffVarintSnappyHeader):])
+		if err != nil {
+			return nil, true, errors.Wrap(err, "snappy decode")
+		}
+	default:
+		return nil, false, nil
+	}
+
+	ps := make([]uint64, 0, len(b))
+	var prev uint64
+	for len(b) > 0 {
+		d, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, true, errors.New("invalid uvarint in postings")
+		}
+		b = b[n:]
+		prev += d
+		ps = append(ps, prev)
+	}
+	return index.NewListPostings(ps), true, nil
+}
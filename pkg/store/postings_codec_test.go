@@ -0,0 +1,43 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"github.com/prometheus/tsdb/index"
+)
+
+func TestPostingsCodec(t *testing.T) {
+	ps := []uint64{1, 2, 10, 100, 1000, 1 << 40, 1<<40 + 16}
+
+	// Raw postings list as stored in the index.
+	raw := []byte{0, 0, 0, 4, 0, 0, 0, 0}
+	res, ok, err := decodePostings(raw)
+	testutil.Ok(t, err)
+	testutil.Assert(t, !ok, "raw postings must not be decoded")
+	testutil.Assert(t, res == nil, "expected no postings")
+
+	for _, compression := range []string{PostingsCompressionDiffVarint, PostingsCompressionDiffVarintSnappy} {
+		t.Run(compression, func(t *testing.T) {
+			b, err := encodePostings(compression, ps)
+			testutil.Ok(t, err)
+			testutil.Assert(t, len(b) < 8*len(ps), "encoded postings are not smaller than raw ones")
+
+			p, ok, err := decodePostings(b)
+			testutil.Ok(t, err)
+			testutil.Assert(t, ok, "expected encoded postings")
+
+			decoded, err := index.ExpandPostings(p)
+			testutil.Ok(t, err)
+			testutil.Equals(t, ps, decoded)
+
+			_, _, err = decodePostings(b[:len(b)-1])
+			if compression == PostingsCompressionDiffVarintSnappy {
+				testutil.NotOk(t, err)
+			}
+		})
+	}
+
+	_, err = encodePostings(PostingsCompressionDiffVarint, []uint64{2, 1})
+	testutil.NotOk(t, err)
+}
@@ -12,6 +12,8 @@ import (
 	"net/url"
 	"path"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/go-kit/kit/log"
@@ -19,6 +21,7 @@ import (
 	"github.com/gogo/protobuf/proto"
 	"github.com/golang/snappy"
 	"github.com/improbable-eng/thanos/pkg/component"
+	"github.com/improbable-eng/thanos/pkg/promclient"
 	"github.com/improbable-eng/thanos/pkg/runutil"
 	"github.com/improbable-eng/thanos/pkg/store/prompb"
 	"github.com/improbable-eng/thanos/pkg/store/storepb"
@@ -124,6 +127,19 @@ func (p *PrometheusStore) Series(r *storepb.SeriesRequest, s storepb.Store_Serie
 	if !match {
 		return nil
 	}
+	// Only serve the advertised time range, which may be limited to not duplicate data served by other stores.
+	if p.timestamps != nil {
+		mint, _ := p.timestamps()
+		if r.MaxTime < mint {
+			return nil
+		}
+		if r.MinTime < mint {
+			r.MinTime = mint
+		}
+	}
+	if r.SkipChunks {
+		return p.seriesLabels(s, r, newMatchers, ext)
+	}
 	q := prompb.Query{StartTimestampMs: r.MinTime, EndTimestampMs: r.MaxTime}
 
 	// TODO(fabxc): import common definitions from prompb once we have a stable gRPC
@@ -146,7 +162,21 @@ func (p *PrometheusStore) Series(r *storepb.SeriesRequest, s storepb.Store_Serie
 		q.Matchers = append(q.Matchers, pm)
 	}
 
-	resp, err := p.promSeries(s.Context(), q)
+	presp, err := p.startPromRemoteRead(s.Context(), q)
+	if err != nil {
+		return errors.Wrap(err, "query Prometheus")
+	}
+	defer runutil.CloseWithLogOnErr(p.logger, presp.Body, "prom series request body")
+
+	if presp.Header.Get("Content-Type") == promclient.StreamedChunksContentType {
+		return p.handleStreamedPrometheusResponse(s, presp.Body, r, ext)
+	}
+	return p.handleSampledPrometheusResponse(s, presp.Body, r, ext)
+}
+
+// handleSampledPrometheusResponse sends the series of a remote read response with samples, which is read as a whole.
+func (p *PrometheusStore) handleSampledPrometheusResponse(s storepb.Store_SeriesServer, body io.Reader, r *storepb.SeriesRequest, ext labels.Labels) error {
+	resp, err := p.decodeSampledResponse(body)
 	if err != nil {
 		return errors.Wrap(err, "query Prometheus")
 	}
@@ -156,6 +186,9 @@ func (p *PrometheusStore) Series(r *storepb.SeriesRequest, s storepb.Store_Serie
 
 	for _, e := range resp.Results[0].Timeseries {
 		lset := p.translateAndExtendLabels(e.Labels, ext)
+		if !r.ShardInfo.Matches(lset) {
+			continue
+		}
 
 		if len(e.Samples) == 0 {
 			// As found in https://github.com/improbable-eng/thanos/issues/381
@@ -189,6 +222,208 @@ func (p *PrometheusStore) Series(r *storepb.SeriesRequest, s storepb.Store_Serie
 	return nil
 }
 
+// handleStreamedPrometheusResponse sends the series of a remote read response streaming XOR chunks while reading it,
+// so only a single series is held in memory at a time. Prometheus may split a series into several frames, they are
+// combined into a single series again.
+func (p *PrometheusStore) handleStreamedPrometheusResponse(s storepb.Store_SeriesServer, body io.Reader, r *storepb.SeriesRequest, ext labels.Labels) error {
+	span, _ := tracing.StartSpan(s.Context(), "transform_and_respond")
+	defer span.Finish()
+
+	buf := p.getBuffer()
+	defer func() { p.putBuffer(buf) }()
+
+	var (
+		reader = promclient.NewChunkedReader(body, promclient.DefaultChunkedReadLimit, buf)
+		lset   []storepb.Label
+		chks   []storepb.AggrChunk
+	)
+	send := func() error {
+		if len(chks) == 0 {
+			return nil
+		}
+		err := s.Send(storepb.NewSeriesResponse(&storepb.Series{Labels: lset, Chunks: chks}))
+		lset, chks = nil, nil
+		return err
+	}
+	for {
+		var res prompb.ChunkedReadResponse
+		if err := reader.NextProto(&res); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return errors.Wrap(err, "read streamed response")
+		}
+
+		for _, series := range res.ChunkedSeries {
+			l := p.translateAndExtendLabels(series.Labels, ext)
+			if !r.ShardInfo.Matches(l) {
+				continue
+			}
+			if storepb.CompareLabels(l, lset) != 0 {
+				if err := send(); err != nil {
+					return err
+				}
+				lset = l
+			}
+			for _, chk := range series.Chunks {
+				if chk.Type != prompb.Chunk_XOR {
+					return errors.Errorf("unsupported chunk encoding %s of series %s", chk.Type, storepb.LabelsToString(l))
+				}
+				chks = append(chks, storepb.AggrChunk{
+					MinTime: chk.MinTimeMs,
+					MaxTime: chk.MaxTimeMs,
+					Raw:     &storepb.Chunk{Type: storepb.Chunk_XOR, Data: chk.Data},
+				})
+			}
+		}
+	}
+	return send()
+}
+
+// seriesLabels sends the label sets of all series matching the request, without any chunks. Instead of reading all
+// samples through the remote read API, it asks the Prometheus series API for the label sets only.
+func (p *PrometheusStore) seriesLabels(s storepb.Store_SeriesServer, r *storepb.SeriesRequest, matchers []storepb.LabelMatcher, ext labels.Labels) error {
+	series, err := p.promSeriesLabels(s.Context(), matchers, r.MinTime, r.MaxTime)
+	if err != nil {
+		return err
+	}
+
+	for _, lset := range series {
+		l := p.translateAndExtendLabels(lset, ext)
+		if !r.ShardInfo.Matches(l) {
+			continue
+		}
+		if err := s.Send(storepb.NewSeriesResponse(&storepb.Series{Labels: l})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// promSeriesLabels returns the label sets of all series matching the given matchers within the given time range
+// using the Prometheus series API. The whole time range of Prometheus is used if both mint and maxt are 0.
+func (p *PrometheusStore) promSeriesLabels(ctx context.Context, matchers []storepb.LabelMatcher, mint, maxt int64) ([][]prompb.Label, error) {
+	selector, err := promSelector(matchers)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	u := *p.base
+	u.Path = path.Join(u.Path, "/api/v1/series")
+
+	q := url.Values{}
+	q.Add("match[]", selector)
+	addPromTimeRange(q, mint, maxt)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	span, ctx := tracing.StartSpan(ctx, "/prom_series HTTP[client]")
+	defer span.Finish()
+
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	defer runutil.CloseWithLogOnErr(p.logger, resp.Body, "series request body")
+
+	if resp.StatusCode/100 != 2 {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("request Prometheus server failed, code %s", resp.Status))
+	}
+
+	var m struct {
+		Data   []map[string]string `json:"data"`
+		Status string              `json:"status"`
+		Error  string              `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if m.Status != "success" {
+		code, exists := statusToCode[resp.StatusCode]
+		if !exists {
+			return nil, status.Error(codes.Internal, m.Error)
+		}
+		return nil, status.Error(code, m.Error)
+	}
+
+	res := make([][]prompb.Label, 0, len(m.Data))
+	for _, series := range m.Data {
+		lset := make([]prompb.Label, 0, len(series))
+		for name, value := range series {
+			lset = append(lset, prompb.Label{Name: name, Value: value})
+		}
+		res = append(res, lset)
+	}
+	return res, nil
+}
+
+// promSelector returns the PromQL series selector of the given matchers. Prometheus requires at least one matcher
+// not matching the empty string, so all series are selected by name if there is no such matcher.
+func promSelector(ms []storepb.LabelMatcher) (string, error) {
+	var (
+		parts    []string
+		nonEmpty bool
+	)
+	for _, m := range ms {
+		tm, err := translateMatcher(m)
+		if err != nil {
+			return "", err
+		}
+		if !tm.Matches("") {
+			nonEmpty = true
+		}
+
+		var op string
+		switch m.Type {
+		case storepb.LabelMatcher_EQ:
+			op = "="
+		case storepb.LabelMatcher_NEQ:
+			op = "!="
+		case storepb.LabelMatcher_RE:
+			op = "=~"
+		case storepb.LabelMatcher_NRE:
+			op = "!~"
+		}
+		parts = append(parts, fmt.Sprintf("%s%s%q", m.Name, op, m.Value))
+	}
+	if !nonEmpty {
+		parts = append(parts, `__name__=~".+"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}", nil
+}
+
+// uniqueLabelValues returns the sorted unique non-empty values the given function returns for the labels of all series.
+func uniqueLabelValues(series [][]prompb.Label, value func(prompb.Label) string) []string {
+	set := map[string]struct{}{}
+	for _, lset := range series {
+		for _, l := range lset {
+			if v := value(l); v != "" {
+				set[v] = struct{}{}
+			}
+		}
+	}
+	res := make([]string, 0, len(set))
+	for v := range set {
+		res = append(res, v)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// addPromTimeRange adds the start and end parameters of the given milliseconds time range the way the Prometheus
+// HTTP API parses them. Nothing is added if both mint and maxt are 0, which stands for the whole time range.
+func addPromTimeRange(q url.Values, mint, maxt int64) {
+	if mint == 0 && maxt == 0 {
+		return
+	}
+	q.Add("start", strconv.FormatFloat(float64(mint)/1000, 'f', -1, 64))
+	q.Add("end", strconv.FormatFloat(float64(maxt)/1000, 'f', -1, 64))
+}
+
 func (p *PrometheusStore) chunkSamples(series prompb.TimeSeries, maxSamplesPerChunk int) (chks []storepb.AggrChunk, err error) {
 	samples := series.Samples
 
@@ -215,11 +450,16 @@ func (p *PrometheusStore) chunkSamples(series prompb.TimeSeries, maxSamplesPerCh
 	return chks, nil
 }
 
-func (p *PrometheusStore) promSeries(ctx context.Context, q prompb.Query) (*prompb.ReadResponse, error) {
+// startPromRemoteRead sends the remote read request for the query and returns the response to read the series from.
+// Streamed XOR chunks are requested, Prometheus versions not supporting them respond with samples.
+func (p *PrometheusStore) startPromRemoteRead(ctx context.Context, q prompb.Query) (*http.Response, error) {
 	span, ctx := tracing.StartSpan(ctx, "query_prometheus")
 	defer span.Finish()
 
-	reqb, err := proto.Marshal(&prompb.ReadRequest{Queries: []prompb.Query{q}})
+	reqb, err := proto.Marshal(&prompb.ReadRequest{
+		Queries:               []prompb.Query{q},
+		AcceptedResponseTypes: []prompb.ReadRequest_ResponseType{prompb.ReadRequest_STREAMED_XOR_CHUNKS},
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "marshal read request")
 	}
@@ -241,17 +481,20 @@ func (p *PrometheusStore) promSeries(ctx context.Context, q prompb.Query) (*prom
 	if err != nil {
 		return nil, errors.Wrap(err, "send request")
 	}
-	defer runutil.CloseWithLogOnErr(p.logger, presp.Body, "prom series request body")
-
 	if presp.StatusCode/100 != 2 {
+		runutil.CloseWithLogOnErr(p.logger, presp.Body, "prom series request body")
 		return nil, errors.Errorf("request failed with code %s", presp.Status)
 	}
+	return presp, nil
+}
 
+// decodeSampledResponse reads a remote read response with samples.
+func (p *PrometheusStore) decodeSampledResponse(body io.Reader) (*prompb.ReadResponse, error) {
 	buf := bytes.NewBuffer(p.getBuffer())
 	defer func() {
 		p.putBuffer(buf.Bytes())
 	}()
-	if _, err := io.Copy(buf, presp.Body); err != nil {
+	if _, err := io.Copy(buf, body); err != nil {
 		return nil, errors.Wrap(err, "copy response")
 	}
 	decomp, err := snappy.Decode(p.getBuffer(), buf.Bytes())
@@ -345,12 +588,32 @@ func extendLset(lset []storepb.Label, extend labels.Labels) []storepb.Label {
 }
 
 // LabelNames returns all known label names.
-func (p *PrometheusStore) LabelNames(ctx context.Context, _ *storepb.LabelNamesRequest) (
+// If matchers are given, the label names are taken from the matching series returned by the Prometheus series API.
+func (p *PrometheusStore) LabelNames(ctx context.Context, r *storepb.LabelNamesRequest) (
 	*storepb.LabelNamesResponse, error,
 ) {
+	if len(r.Matchers) > 0 {
+		match, newMatchers, err := labelsMatches(p.externalLabels(), r.Matchers)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if !match {
+			return &storepb.LabelNamesResponse{Names: []string{}}, nil
+		}
+		series, err := p.promSeriesLabels(ctx, newMatchers, r.MinTime, r.MaxTime)
+		if err != nil {
+			return nil, err
+		}
+		return &storepb.LabelNamesResponse{Names: uniqueLabelValues(series, func(l prompb.Label) string { return l.Name })}, nil
+	}
+
 	u := *p.base
 	u.Path = path.Join(u.Path, "/api/v1/labels")
 
+	q := url.Values{}
+	addPromTimeRange(q, r.MinTime, r.MaxTime)
+	u.RawQuery = q.Encode()
+
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
@@ -408,9 +671,33 @@ func (p *PrometheusStore) LabelValues(ctx context.Context, r *storepb.LabelValue
 		return &storepb.LabelValuesResponse{Values: []string{l}}, nil
 	}
 
+	if len(r.Matchers) > 0 {
+		match, newMatchers, err := labelsMatches(externalLset, r.Matchers)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if !match {
+			return &storepb.LabelValuesResponse{Values: []string{}}, nil
+		}
+		series, err := p.promSeriesLabels(ctx, newMatchers, r.MinTime, r.MaxTime)
+		if err != nil {
+			return nil, err
+		}
+		return &storepb.LabelValuesResponse{Values: uniqueLabelValues(series, func(l prompb.Label) string {
+			if l.Name != r.Label {
+				return ""
+			}
+			return l.Value
+		})}, nil
+	}
+
 	u := *p.base
 	u.Path = path.Join(u.Path, "/api/v1/label/", r.Label, "/values")
 
+	q := url.Values{}
+	addPromTimeRange(q, r.MinTime, r.MaxTime)
+	u.RawQuery = q.Encode()
+
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
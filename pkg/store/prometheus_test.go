@@ -2,14 +2,23 @@ package store
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
+	"hash/crc32"
+	"io/ioutil"
 	"math"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 	"time"
 
 	"github.com/fortytw2/leaktest"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
 	"github.com/improbable-eng/thanos/pkg/component"
+	"github.com/improbable-eng/thanos/pkg/promclient"
+	"github.com/improbable-eng/thanos/pkg/store/prompb"
 	"github.com/improbable-eng/thanos/pkg/store/storepb"
 	"github.com/improbable-eng/thanos/pkg/testutil"
 	"github.com/prometheus/prometheus/pkg/timestamp"
@@ -278,6 +287,121 @@ func TestPrometheusStore_Info(t *testing.T) {
 	testutil.Equals(t, int64(456), resp.MaxTime)
 }
 
+func TestPrometheusStore_Series_SkipChunks(t *testing.T) {
+	var reqs []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testutil.Equals(t, "/api/v1/series", r.URL.Path)
+		reqs = append(reqs, r.URL.Query())
+		_, _ = w.Write([]byte(`{"status":"success","data":[{"__name__":"up","job":"a"},{"__name__":"up","job":"b","region":"us"}]}`))
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	testutil.Ok(t, err)
+
+	proxy, err := NewPrometheusStore(nil, nil, u, component.Sidecar,
+		func() labels.Labels {
+			return labels.FromStrings("region", "eu-west")
+		}, nil)
+	testutil.Ok(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := newStoreSeriesServer(ctx)
+	testutil.Ok(t, proxy.Series(&storepb.SeriesRequest{
+		MinTime: 1000,
+		MaxTime: 2500,
+		Matchers: []storepb.LabelMatcher{
+			{Type: storepb.LabelMatcher_EQ, Name: "__name__", Value: "up"},
+			{Type: storepb.LabelMatcher_RE, Name: "region", Value: "eu.*"},
+		},
+		SkipChunks: true,
+	}, s))
+
+	testutil.Equals(t, 1, len(reqs))
+	testutil.Equals(t, []string{`{__name__="up"}`}, reqs[0]["match[]"])
+	testutil.Equals(t, "1", reqs[0].Get("start"))
+	testutil.Equals(t, "2.5", reqs[0].Get("end"))
+
+	testutil.Equals(t, 2, len(s.SeriesSet))
+	testutil.Equals(t, []storepb.Label{
+		{Name: "__name__", Value: "up"},
+		{Name: "job", Value: "a"},
+		{Name: "region", Value: "eu-west"},
+	}, s.SeriesSet[0].Labels)
+	testutil.Equals(t, []storepb.Label{
+		{Name: "__name__", Value: "up"},
+		{Name: "job", Value: "b"},
+		{Name: "region", Value: "eu-west"},
+	}, s.SeriesSet[1].Labels)
+	testutil.Equals(t, 0, len(s.SeriesSet[0].Chunks))
+
+	// Only matchers matching the empty string are extended with a matcher for all series.
+	s = newStoreSeriesServer(ctx)
+	testutil.Ok(t, proxy.Series(&storepb.SeriesRequest{
+		MinTime: 0,
+		MaxTime: 1000,
+		Matchers: []storepb.LabelMatcher{
+			{Type: storepb.LabelMatcher_RE, Name: "job", Value: ".*"},
+		},
+		SkipChunks: true,
+	}, s))
+	testutil.Equals(t, []string{`{job=~".*",__name__=~".+"}`}, reqs[1]["match[]"])
+}
+
+func TestPrometheusStore_LabelNamesAndValues_Matchers(t *testing.T) {
+	var reqs []*url.URL
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs = append(reqs, r.URL)
+		if r.URL.Path == "/api/v1/series" {
+			_, _ = w.Write([]byte(`{"status":"success","data":[{"__name__":"up","job":"b"},{"__name__":"up","job":"a","instance":"x"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":["a","b","c"]}`))
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	testutil.Ok(t, err)
+
+	proxy, err := NewPrometheusStore(nil, nil, u, component.Sidecar,
+		func() labels.Labels {
+			return labels.FromStrings("region", "eu-west")
+		}, nil)
+	testutil.Ok(t, err)
+
+	ctx := context.Background()
+	matchers := []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "__name__", Value: "up"}}
+
+	names, err := proxy.LabelNames(ctx, &storepb.LabelNamesRequest{Matchers: matchers, MinTime: 1000, MaxTime: 2000})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"__name__", "instance", "job"}, names.Names)
+	testutil.Equals(t, "/api/v1/series", reqs[0].Path)
+	testutil.Equals(t, "1", reqs[0].Query().Get("start"))
+	testutil.Equals(t, "2", reqs[0].Query().Get("end"))
+
+	values, err := proxy.LabelValues(ctx, &storepb.LabelValuesRequest{Label: "job", Matchers: matchers})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"a", "b"}, values.Values)
+	testutil.Equals(t, "", reqs[1].Query().Get("start"))
+
+	// Without matchers the label values API of Prometheus is used.
+	values, err = proxy.LabelValues(ctx, &storepb.LabelValuesRequest{Label: "job", MinTime: 1000, MaxTime: 2000})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"a", "b", "c"}, values.Values)
+	testutil.Equals(t, "/api/v1/label/job/values", reqs[2].Path)
+	testutil.Equals(t, "1", reqs[2].Query().Get("start"))
+
+	// Not matching external labels means no label values.
+	values, err = proxy.LabelValues(ctx, &storepb.LabelValuesRequest{Label: "job", Matchers: []storepb.LabelMatcher{
+		{Type: storepb.LabelMatcher_EQ, Name: "region", Value: "us"},
+	}})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{}, values.Values)
+	testutil.Equals(t, 3, len(reqs))
+}
+
 func testSeries_SplitSamplesIntoChunksWithMaxSizeOfUint16_e2e(t *testing.T, appender tsdb.Appender, newStore func() storepb.StoreServer) {
 	baseT := timestamp.FromTime(time.Now().AddDate(0, 0, -2)) / 1000 * 1000
 
@@ -351,3 +475,177 @@ func TestPrometheusStore_Series_SplitSamplesIntoChunksWithMaxSizeOfUint16_e2e(t
 		return proxy
 	})
 }
+
+func TestPrometheusStore_Series_MinTime(t *testing.T) {
+	var reqs []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs = append(reqs, r.URL.Query())
+		_, _ = w.Write([]byte(`{"status":"success","data":[{"__name__":"up","job":"a"}]}`))
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	testutil.Ok(t, err)
+
+	proxy, err := NewPrometheusStore(nil, nil, u, component.Sidecar,
+		func() labels.Labels {
+			return labels.FromStrings("region", "eu-west")
+		},
+		func() (int64, int64) {
+			return 2000, math.MaxInt64
+		})
+	testutil.Ok(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Requests are limited to the advertised time range.
+	s := newStoreSeriesServer(ctx)
+	testutil.Ok(t, proxy.Series(&storepb.SeriesRequest{
+		MinTime:    1000,
+		MaxTime:    3000,
+		Matchers:   []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "__name__", Value: "up"}},
+		SkipChunks: true,
+	}, s))
+	testutil.Equals(t, 1, len(reqs))
+	testutil.Equals(t, "2", reqs[0].Get("start"))
+	testutil.Equals(t, 1, len(s.SeriesSet))
+
+	// Requests before the advertised time range are not sent to Prometheus.
+	s = newStoreSeriesServer(ctx)
+	testutil.Ok(t, proxy.Series(&storepb.SeriesRequest{
+		MinTime:    0,
+		MaxTime:    1000,
+		Matchers:   []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "__name__", Value: "up"}},
+		SkipChunks: true,
+	}, s))
+	testutil.Equals(t, 1, len(reqs))
+	testutil.Equals(t, 0, len(s.SeriesSet))
+}
+
+func TestPrometheusStore_Series_StreamedChunks(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	chunk := func(mint, maxt int64) prompb.Chunk {
+		c := chunkenc.NewXORChunk()
+		app, err := c.Appender()
+		testutil.Ok(t, err)
+		for ts := mint; ts <= maxt; ts += 10 {
+			app.Append(ts, float64(ts))
+		}
+		return prompb.Chunk{MinTimeMs: mint, MaxTimeMs: maxt, Type: prompb.Chunk_XOR, Data: c.Bytes()}
+	}
+	a := []prompb.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "a"}}
+	b := []prompb.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "b"}}
+	frames := []prompb.ChunkedReadResponse{
+		{ChunkedSeries: []*prompb.ChunkedSeries{{Labels: a, Chunks: []prompb.Chunk{chunk(0, 100)}}}},
+		// Series split into frames.
+		{ChunkedSeries: []*prompb.ChunkedSeries{{Labels: a, Chunks: []prompb.Chunk{chunk(110, 200)}}}},
+		{ChunkedSeries: []*prompb.ChunkedSeries{{Labels: b, Chunks: []prompb.Chunk{chunk(0, 50)}}}},
+	}
+
+	var req prompb.ReadRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compressed, err := ioutil.ReadAll(r.Body)
+		testutil.Ok(t, err)
+		reqb, err := snappy.Decode(nil, compressed)
+		testutil.Ok(t, err)
+		testutil.Ok(t, proto.Unmarshal(reqb, &req))
+
+		w.Header().Set("Content-Type", promclient.StreamedChunksContentType)
+		for _, f := range frames {
+			fb, err := proto.Marshal(&f)
+			testutil.Ok(t, err)
+
+			var tmp [binary.MaxVarintLen64]byte
+			_, _ = w.Write(tmp[:binary.PutUvarint(tmp[:], uint64(len(fb)))])
+			binary.BigEndian.PutUint32(tmp[:4], crc32.Checksum(fb, crc32.MakeTable(crc32.Castagnoli)))
+			_, _ = w.Write(tmp[:4])
+			_, _ = w.Write(fb)
+		}
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	testutil.Ok(t, err)
+
+	proxy, err := NewPrometheusStore(nil, nil, u, component.Sidecar,
+		func() labels.Labels {
+			return labels.FromStrings("region", "eu-west")
+		},
+		func() (int64, int64) {
+			return 0, math.MaxInt64
+		})
+	testutil.Ok(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := newStoreSeriesServer(ctx)
+	testutil.Ok(t, proxy.Series(&storepb.SeriesRequest{
+		MinTime:  0,
+		MaxTime:  300,
+		Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "__name__", Value: "up"}},
+	}, s))
+
+	testutil.Equals(t, []prompb.ReadRequest_ResponseType{prompb.ReadRequest_STREAMED_XOR_CHUNKS}, req.AcceptedResponseTypes)
+
+	testutil.Equals(t, 2, len(s.SeriesSet))
+	testutil.Equals(t, []storepb.Label{
+		{Name: "__name__", Value: "up"},
+		{Name: "job", Value: "a"},
+		{Name: "region", Value: "eu-west"},
+	}, s.SeriesSet[0].Labels)
+	testutil.Equals(t, 2, len(s.SeriesSet[0].Chunks))
+	testutil.Equals(t, int64(110), s.SeriesSet[0].Chunks[1].MinTime)
+	testutil.Equals(t, frames[1].ChunkedSeries[0].Chunks[0].Data, s.SeriesSet[0].Chunks[1].Raw.Data)
+	testutil.Equals(t, []storepb.Label{
+		{Name: "__name__", Value: "up"},
+		{Name: "job", Value: "b"},
+		{Name: "region", Value: "eu-west"},
+	}, s.SeriesSet[1].Labels)
+	testutil.Equals(t, 1, len(s.SeriesSet[1].Chunks))
+}
+
+func TestPrometheusStore_Series_SamplesFallback(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	// Prometheus versions not supporting streamed chunks respond with samples.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := proto.Marshal(&prompb.ReadResponse{Results: []prompb.QueryResult{{Timeseries: []prompb.TimeSeries{{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "up"}},
+			Samples: []prompb.Sample{{Timestamp: 10, Value: 1}, {Timestamp: 20, Value: 2}},
+		}}}}})
+		testutil.Ok(t, err)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Header().Set("Content-Encoding", "snappy")
+		_, _ = w.Write(snappy.Encode(nil, b))
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	testutil.Ok(t, err)
+
+	proxy, err := NewPrometheusStore(nil, nil, u, component.Sidecar,
+		func() labels.Labels {
+			return labels.FromStrings("region", "eu-west")
+		},
+		func() (int64, int64) {
+			return 0, math.MaxInt64
+		})
+	testutil.Ok(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := newStoreSeriesServer(ctx)
+	testutil.Ok(t, proxy.Series(&storepb.SeriesRequest{
+		MinTime:  0,
+		MaxTime:  300,
+		Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "__name__", Value: "up"}},
+	}, s))
+	testutil.Equals(t, 1, len(s.SeriesSet))
+	testutil.Equals(t, 1, len(s.SeriesSet[0].Chunks))
+	testutil.Equals(t, int64(10), s.SeriesSet[0].Chunks[0].MinTime)
+	testutil.Equals(t, int64(20), s.SeriesSet[0].Chunks[0].MaxTime)
+}
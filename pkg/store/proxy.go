@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kit/kit/log"
@@ -45,27 +47,64 @@ type ProxyStore struct {
 	selectorLabels labels.Labels
 
 	responseTimeout time.Duration
+	// storeResponseTimeouts overrides responseTimeout for the stores with the given addresses.
+	storeResponseTimeouts map[string]time.Duration
+	// hedgeDelay is the time after which a Series request is sent to the next replica of a store if the store did not
+	// respond yet. Stores advertising the same labels and time range are replicas. 0 disables hedging.
+	hedgeDelay time.Duration
+	// groupReplicas makes only one replica of a store being queried for series, the next one on failures, even
+	// without hedging. Requests are balanced across replicas.
+	groupReplicas bool
+	// nextReplica rotates the replica queried first.
+	nextReplica uint64
+	// labelValues caches the label values of the stores to skip stores without values matching a request. It is nil if
+	// caching is disabled.
+	labelValues *storeLabelValuesCache
+
+	// zoneLabel is the name of the label advertised by stores with their zone. Stores of other zones than localZone
+	// are queried only if the stores of the local zone cannot serve a request.
+	zoneLabel string
+	localZone string
 }
 
 // NewProxyStore returns a new ProxyStore that uses the given clients that implements storeAPI to fan-in all series to the client.
 // Note that there is no deduplication support. Deduplication should be done on the highest level (just before PromQL)
+// If localZone is not empty, stores advertising another zone in their zoneLabel label are queried only if no store of
+// the local zone covers their data. If hedgeDelay is set or groupReplicas is true, only one of the replicas of a store
+// is queried for series, the others only on failures or if it does not respond within the hedge delay.
+// If labelValuesCacheTTL is set, all values of a label returned by a store are used for that long to skip the store
+// for requests none of the values match.
 func NewProxyStore(
 	logger log.Logger,
 	stores func() []Client,
 	component component.StoreAPI,
 	selectorLabels labels.Labels,
 	responseTimeout time.Duration,
+	storeResponseTimeouts map[string]time.Duration,
+	hedgeDelay time.Duration,
+	groupReplicas bool,
+	labelValuesCacheTTL time.Duration,
+	zoneLabel string,
+	localZone string,
 ) *ProxyStore {
 	if logger == nil {
 		logger = log.NewNopLogger()
 	}
 
 	s := &ProxyStore{
-		logger:          logger,
-		stores:          stores,
-		component:       component,
-		selectorLabels:  selectorLabels,
-		responseTimeout: responseTimeout,
+		logger:                logger,
+		stores:                stores,
+		component:             component,
+		selectorLabels:        selectorLabels,
+		responseTimeout:       responseTimeout,
+		storeResponseTimeouts: storeResponseTimeouts,
+		hedgeDelay:            hedgeDelay,
+		groupReplicas:         groupReplicas,
+		zoneLabel:             zoneLabel,
+		localZone:             localZone,
+	}
+	if labelValuesCacheTTL > 0 {
+		s.labelValues = newStoreLabelValuesCache(labelValuesCacheTTL)
 	}
 	return s
 }
@@ -145,6 +184,8 @@ func (s *ProxyStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSe
 		// Allow to buffer max 10 series response.
 		// Each might be quite large (multi chunk long series given by sidecar).
 		respSender, respRecv, closeFn = newRespCh(gctx, 10)
+
+		stats = QueryStatsFromContext(srv.Context())
 	)
 
 	g.Go(func() error {
@@ -157,7 +198,11 @@ func (s *ProxyStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSe
 				Matchers:                newMatchers,
 				Aggregates:              r.Aggregates,
 				MaxResolutionWindow:     r.MaxResolutionWindow,
-				PartialResponseDisabled: r.PartialResponseDisabled,
+				PartialResponseDisabled: partialResponseDisabled(r.PartialResponseDisabled, r.PartialResponseStrategy),
+				PartialResponseStrategy: r.PartialResponseStrategy,
+				Hints:                   r.Hints,
+				SkipChunks:              r.SkipChunks,
+				ShardInfo:               r.ShardInfo,
 			}
 			wg = &sync.WaitGroup{}
 		)
@@ -167,6 +212,7 @@ func (s *ProxyStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSe
 			closeFn()
 		}()
 
+		var stores []Client
 		for _, st := range s.stores() {
 			// We might be able to skip the store if its meta information indicates
 			// it cannot have series matching our query.
@@ -175,31 +221,63 @@ func (s *ProxyStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSe
 				storeDebugMsgs = append(storeDebugMsgs, fmt.Sprintf("store %s filtered out", st))
 				continue
 			}
+			if !s.labelValuesMayMatch(st, r.Matchers) {
+				storeDebugMsgs = append(storeDebugMsgs, fmt.Sprintf("store %s filtered out by cached label values", st))
+				continue
+			}
+			stores = append(stores, st)
+		}
+
+		stores, remote := s.preferLocalZone(stores, r.MinTime, r.MaxTime)
+		for _, st := range remote {
+			storeDebugMsgs = append(storeDebugMsgs, fmt.Sprintf("store %s of remote zone covered by local zone", st))
+		}
+
+		for _, replicas := range s.replicaSets(stores) {
+			st := replicas[0]
 			storeDebugMsgs = append(storeDebugMsgs, fmt.Sprintf("store %s queried", st))
+			for _, rst := range replicas[1:] {
+				storeDebugMsgs = append(storeDebugMsgs, fmt.Sprintf("store %s queried only as replica", rst))
+			}
 
 			// This is used to cancel this stream when one operations takes too long.
 			seriesCtx, closeSeries := context.WithCancel(gctx)
 			defer closeSeries()
 
-			sc, err := st.Series(seriesCtx, r)
+			var (
+				sc    storepb.Store_SeriesClient
+				err   error
+				begin = time.Now()
+			)
+			if len(replicas) > 1 {
+				st, sc, err = s.hedgedSeries(seriesCtx, replicas, r)
+			} else {
+				sc, err = st.Series(seriesCtx, r)
+			}
 			if err != nil {
 				storeID := fmt.Sprintf("%v", storepb.LabelsToString(st.Labels()))
 				if storeID == "" {
 					storeID = "Store Gateway"
 				}
 				err = errors.Wrapf(err, "fetch series for %s %s", storeID, st)
+				stats.observe(st.Addr(), time.Since(begin), 0, 0, err.Error())
 				if r.PartialResponseDisabled {
 					level.Error(s.logger).Log("err", err, "msg", "partial response disabled; aborting request")
 					return err
 				}
-				respSender.send(storepb.NewWarnSeriesResponse(err))
+				respSender.send(storepb.NewWarnSeriesResponse(storeErrWarning(st.Addr(), err)))
 				continue
 			}
 
+			addr := st.Addr()
+			observe := func(series, chunks int, warnings ...string) {
+				stats.observe(addr, time.Since(begin), series, chunks, warnings...)
+			}
+
 			// Schedule streamSeriesSet that translates gRPC streamed response
-			// into seriesSet (if series) or respCh if warnings.
+			// into seriesSet (if series) or respCh if warnings or hints.
 			seriesSet = append(seriesSet, startStreamSeriesSet(seriesCtx, s.logger, closeSeries,
-				wg, sc, respSender, st.String(), !r.PartialResponseDisabled, s.responseTimeout))
+				wg, sc, respSender, st.String(), addr, !r.PartialResponseDisabled, s.storeResponseTimeout(st), observe))
 		}
 
 		level.Debug(s.logger).Log("msg", strings.Join(storeDebugMsgs, ";"))
@@ -207,7 +285,7 @@ func (s *ProxyStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSe
 			// This is indicates that configured StoreAPIs are not the ones end user expects
 			err := errors.New("No store matched for this query")
 			level.Warn(s.logger).Log("err", err, "stores", strings.Join(storeDebugMsgs, ";"))
-			respSender.send(storepb.NewWarnSeriesResponse(err))
+			respSender.send(storepb.NewWarnSeriesResponse(storepb.NewWarning(storepb.WarningNoStoreMatched, "", err)))
 			return nil
 		}
 
@@ -233,6 +311,217 @@ func (s *ProxyStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSe
 	return nil
 }
 
+// storeResponseTimeout returns the response timeout of the given store.
+func (s *ProxyStore) storeResponseTimeout(st Client) time.Duration {
+	if t, ok := s.storeResponseTimeouts[st.Addr()]; ok {
+		return t
+	}
+	return s.responseTimeout
+}
+
+// replicaSets groups the given stores into sets of replicas, which advertise the same labels and time range, if
+// hedging or grouping of replicas is enabled. Otherwise every store is a set of its own. The order of the replicas
+// of each set is rotated on every call, so that requests are balanced across them.
+func (s *ProxyStore) replicaSets(stores []Client) [][]Client {
+	var (
+		sets  [][]Client
+		index = map[string]int{}
+	)
+	for _, st := range stores {
+		if s.hedgeDelay <= 0 && !s.groupReplicas {
+			sets = append(sets, []Client{st})
+			continue
+		}
+
+		mint, maxt := st.TimeRange()
+		key := fmt.Sprintf("%s;%d;%d", storepb.LabelsToString(st.Labels()), mint, maxt)
+		if i, ok := index[key]; ok {
+			sets[i] = append(sets[i], st)
+			continue
+		}
+		index[key] = len(sets)
+		sets = append(sets, []Client{st})
+	}
+
+	next := atomic.AddUint64(&s.nextReplica, 1)
+	for i, set := range sets {
+		if len(set) < 2 {
+			continue
+		}
+		first := int(next % uint64(len(set)))
+		sets[i] = append(set[first:len(set):len(set)], set[:first]...)
+	}
+	return sets
+}
+
+// hedgedSeries sends the Series request to the first of the given replicas and to the next one each time the
+// replicas queried so far did not respond within the hedge delay or failed. Without hedge delay the next replica is
+// only queried on failures. The stream of the replica responding first is returned, the requests to all other
+// replicas are canceled. If the returned stream fails later on, it is resumed from the other replicas.
+func (s *ProxyStore) hedgedSeries(ctx context.Context, replicas []Client, r *storepb.SeriesRequest) (Client, storepb.Store_SeriesClient, error) {
+	i, sc, err := s.firstSeries(ctx, replicas, r)
+	if err != nil {
+		return replicas[i], nil, err
+	}
+	return replicas[i], &failoverSeriesClient{
+		Store_SeriesClient: sc,
+		ctx:                ctx,
+		logger:             s.logger,
+		proxy:              s,
+		r:                  r,
+		current:            replicas[i],
+		replicas:           without(replicas, i),
+	}, nil
+}
+
+// firstSeries returns the index and stream of the replica responding first to the Series request, as described for
+// hedgedSeries, or the index of the last failed replica and its error.
+func (s *ProxyStore) firstSeries(ctx context.Context, replicas []Client, r *storepb.SeriesRequest) (int, storepb.Store_SeriesClient, error) {
+	type firstResponse struct {
+		i    int
+		sc   storepb.Store_SeriesClient
+		resp *storepb.SeriesResponse
+		err  error
+	}
+	var (
+		// Buffered so that the requests of losing replicas finish without anyone receiving their responses.
+		responses = make(chan firstResponse, len(replicas))
+		cancels   = make([]context.CancelFunc, 0, len(replicas))
+	)
+	defer func() {
+		for _, cancel := range cancels {
+			if cancel != nil {
+				cancel()
+			}
+		}
+	}()
+
+	send := func() {
+		i := len(cancels)
+		rctx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+
+		go func() {
+			sc, err := replicas[i].Series(rctx, r)
+			if err != nil {
+				responses <- firstResponse{i: i, err: err}
+				return
+			}
+			resp, err := sc.Recv()
+			responses <- firstResponse{i: i, sc: sc, resp: resp, err: err}
+		}()
+	}
+
+	send()
+	// A nil channel never fires, so that replicas are only queried on failures without hedge delay.
+	var hedgeC <-chan time.Time
+	resetHedge := func() {}
+	if s.hedgeDelay > 0 {
+		hedge := time.NewTimer(s.hedgeDelay)
+		defer hedge.Stop()
+		hedgeC = hedge.C
+		resetHedge = func() { hedge.Reset(s.hedgeDelay) }
+	}
+
+	var (
+		pending = 1
+		failed  int
+		lastErr error
+	)
+	for pending > 0 {
+		select {
+		case <-hedgeC:
+			if len(cancels) < len(replicas) {
+				level.Debug(s.logger).Log("msg", "hedging series request", "store", replicas[len(cancels)], "delay", s.hedgeDelay)
+				send()
+				pending++
+				resetHedge()
+			}
+		case f := <-responses:
+			pending--
+			if f.err != nil && f.err != io.EOF {
+				cancels[f.i]()
+				failed, lastErr = f.i, f.err
+				if len(cancels) < len(replicas) && ctx.Err() == nil {
+					send()
+					pending++
+					resetHedge()
+				}
+				continue
+			}
+
+			// The winning request stays open until the context of the caller is canceled.
+			cancels[f.i] = nil
+			return f.i, &peekedSeriesClient{Store_SeriesClient: f.sc, resp: f.resp, err: f.err}, nil
+		}
+	}
+	return failed, nil, lastErr
+}
+
+// without returns the given replicas without the one at index i.
+func without(replicas []Client, i int) []Client {
+	rest := make([]Client, 0, len(replicas)-1)
+	rest = append(rest, replicas[:i]...)
+	return append(rest, replicas[i+1:]...)
+}
+
+// failoverSeriesClient resumes a failed Series stream from the remaining replicas. Series are streamed sorted by
+// their labels, so the series up to the last one received before the failure are skipped in the resumed stream.
+type failoverSeriesClient struct {
+	storepb.Store_SeriesClient
+
+	ctx    context.Context
+	logger log.Logger
+	proxy  *ProxyStore
+	r      *storepb.SeriesRequest
+
+	current  Client
+	replicas []Client
+	last     []storepb.Label
+}
+
+func (c *failoverSeriesClient) Recv() (*storepb.SeriesResponse, error) {
+	for {
+		resp, err := c.Store_SeriesClient.Recv()
+		if err == nil {
+			if series := resp.GetSeries(); series != nil {
+				if c.last != nil && storepb.CompareLabels(series.Labels, c.last) <= 0 {
+					continue
+				}
+				c.last = series.Labels
+			}
+			return resp, nil
+		}
+		if err == io.EOF || len(c.replicas) == 0 || c.ctx.Err() != nil {
+			return nil, err
+		}
+
+		level.Warn(c.logger).Log("msg", "series stream failed; resuming from replica", "store", c.current, "err", err)
+		i, sc, ferr := c.proxy.firstSeries(c.ctx, c.replicas, c.r)
+		if ferr != nil {
+			return nil, errors.Wrapf(ferr, "resume series stream failed with %v", err)
+		}
+		c.Store_SeriesClient, c.current, c.replicas = sc, c.replicas[i], without(c.replicas, i)
+	}
+}
+
+// peekedSeriesClient returns the already received first response or error before the rest of the stream.
+type peekedSeriesClient struct {
+	storepb.Store_SeriesClient
+
+	peeked bool
+	resp   *storepb.SeriesResponse
+	err    error
+}
+
+func (c *peekedSeriesClient) Recv() (*storepb.SeriesResponse, error) {
+	if !c.peeked {
+		c.peeked = true
+		return c.resp, c.err
+	}
+	return c.Store_SeriesClient.Recv()
+}
+
 type warnSender interface {
 	send(*storepb.SeriesResponse)
 }
@@ -253,6 +542,7 @@ type streamSeriesSet struct {
 	err    error
 
 	name            string
+	addr            string
 	partialResponse bool
 
 	responseTimeout time.Duration
@@ -267,8 +557,11 @@ func startStreamSeriesSet(
 	stream storepb.Store_SeriesClient,
 	warnCh warnSender,
 	name string,
+	addr string,
 	partialResponse bool,
 	responseTimeout time.Duration,
+	// observe is called with the numbers of received series and chunks and the warnings once the stream ended.
+	observe func(series, chunks int, warnings ...string),
 ) *streamSeriesSet {
 	s := &streamSeriesSet{
 		ctx:             ctx,
@@ -278,14 +571,20 @@ func startStreamSeriesSet(
 		warnCh:          warnCh,
 		recvCh:          make(chan *storepb.Series, 10),
 		name:            name,
+		addr:            addr,
 		partialResponse: partialResponse,
 		responseTimeout: responseTimeout,
 	}
 
 	wg.Add(1)
 	go func() {
+		var (
+			series, chunks int
+			warnings       []string
+		)
 		defer wg.Done()
 		defer close(s.recvCh)
+		defer func() { observe(series, chunks, warnings...) }()
 
 		for {
 			r, err := s.stream.Recv()
@@ -296,8 +595,9 @@ func startStreamSeriesSet(
 
 			if err != nil {
 				wrapErr := errors.Wrapf(err, "receive series from %s", s.name)
+				warnings = append(warnings, wrapErr.Error())
 				if partialResponse {
-					s.warnCh.send(storepb.NewWarnSeriesResponse(wrapErr))
+					s.warnCh.send(storepb.NewWarnSeriesResponse(storeErrWarning(s.addr, wrapErr)))
 					return
 				}
 
@@ -308,10 +608,21 @@ func startStreamSeriesSet(
 			}
 
 			if w := r.GetWarning(); w != "" {
-				s.warnCh.send(storepb.NewWarnSeriesResponse(errors.New(w)))
+				warnings = append(warnings, w)
+				s.warnCh.send(storepb.NewWarnSeriesResponse(storepb.StoreWarning(s.addr, w)))
 				continue
 			}
 
+			// Hints of the store are passed on as they are.
+			if r.GetHints() != nil {
+				s.warnCh.send(r)
+				continue
+			}
+
+			if sr := r.GetSeries(); sr != nil {
+				series++
+				chunks += len(sr.Chunks)
+			}
 			select {
 			case s.recvCh <- r.GetSeries():
 				continue
@@ -347,7 +658,7 @@ func (s *streamSeriesSet) Next() (ok bool) {
 		err := errors.Wrap(ctx.Err(), timeoutMsg)
 		if s.partialResponse {
 			level.Warn(s.logger).Log("err", err, "msg", "returning partial response")
-			s.warnCh.send(storepb.NewWarnSeriesResponse(err))
+			s.warnCh.send(storepb.NewWarnSeriesResponse(storeErrWarning(s.addr, err)))
 			return false
 		}
 		s.errMtx.Lock()
@@ -371,7 +682,16 @@ func (s *streamSeriesSet) Err() error {
 	return errors.Wrap(s.err, s.name)
 }
 
-// matchStore returns true if the given store may hold data for the given label matchers.
+// labelRequestStoreMatches is like storeMatches for label names and values requests, for which a time range of
+// 0 to 0 means the whole time range.
+func labelRequestStoreMatches(s Client, mint, maxt int64, matchers ...storepb.LabelMatcher) (bool, error) {
+	if mint == 0 && maxt == 0 {
+		mint, maxt = math.MinInt64, math.MaxInt64
+	}
+	return storeMatches(s, mint, maxt, matchers...)
+}
+
+// storeMatches returns true if the given store may hold data for the given label matchers.
 func storeMatches(s Client, mint, maxt int64, matchers ...storepb.LabelMatcher) (bool, error) {
 	storeMinTime, storeMaxTime := s.TimeRange()
 	if mint > storeMaxTime || maxt < storeMinTime {
@@ -396,37 +716,163 @@ func storeMatches(s Client, mint, maxt int64, matchers ...storepb.LabelMatcher)
 	return true, nil
 }
 
+// labelValuesMayMatch returns false if the cached label values of the given store show that it has no series
+// matching the matchers. Matchers of external labels of the store are left to storeMatches, as stores do not return
+// the values of their external labels in LabelValues.
+func (s *ProxyStore) labelValuesMayMatch(st Client, matchers []storepb.LabelMatcher) bool {
+	if s.labelValues == nil {
+		return true
+	}
+	var internal []storepb.LabelMatcher
+	for _, m := range matchers {
+		if !hasExternalLabel(st, m.Name) {
+			internal = append(internal, m)
+		}
+	}
+	return s.labelValues.mayMatch(st.Addr(), internal)
+}
+
+// hasExternalLabel returns true if the given store has an external label with the given name.
+func hasExternalLabel(st Client, name string) bool {
+	for _, l := range st.Labels() {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// partialResponseDisabled returns true if a request with the given deprecated flag and strategy must fail instead of
+// returning a partial response.
+func partialResponseDisabled(disabled bool, strategy storepb.PartialResponseStrategy) bool {
+	return disabled || strategy == storepb.PartialResponseStrategy_ABORT
+}
+
+// preferLocalZone splits the stores matching a request for the given time range into the stores to query and the
+// stores of remote zones which are not queried as stores of the local zone cover their data. A remote store is covered
+// by a local store advertising the same labels except the zone label whose time range includes the requested part of
+// the remote store's time range. Stores not advertising a zone are always queried.
+func (s *ProxyStore) preferLocalZone(stores []Client, mint, maxt int64) (queried, remote []Client) {
+	if s.localZone == "" {
+		return stores, nil
+	}
+
+	var local, other []Client
+	for _, st := range stores {
+		if zone, ok := labelValue(st.Labels(), s.zoneLabel); ok && zone != s.localZone {
+			other = append(other, st)
+			continue
+		}
+		local = append(local, st)
+	}
+
+	queried = local
+	for _, st := range other {
+		if s.coveredByLocalZone(st, local, mint, maxt) {
+			remote = append(remote, st)
+			continue
+		}
+		queried = append(queried, st)
+	}
+	return queried, remote
+}
+
+func (s *ProxyStore) coveredByLocalZone(st Client, local []Client, mint, maxt int64) bool {
+	stMint, stMaxt := st.TimeRange()
+	if stMint > mint {
+		mint = stMint
+	}
+	if stMaxt < maxt {
+		maxt = stMaxt
+	}
+	lset := withoutLabel(st.Labels(), s.zoneLabel)
+
+	for _, l := range local {
+		lMint, lMaxt := l.TimeRange()
+		if lMint > mint || lMaxt < maxt {
+			continue
+		}
+		if withoutLabel(l.Labels(), s.zoneLabel).Equals(lset) {
+			return true
+		}
+	}
+	return false
+}
+
+func labelValue(lset []storepb.Label, name string) (string, bool) {
+	for _, l := range lset {
+		if l.Name == name {
+			return l.Value, true
+		}
+	}
+	return "", false
+}
+
+func withoutLabel(lset []storepb.Label, name string) labels.Labels {
+	res := make(labels.Labels, 0, len(lset))
+	for _, l := range lset {
+		if l.Name == name {
+			continue
+		}
+		res = append(res, labels.Label{Name: l.Name, Value: l.Value})
+	}
+	sort.Sort(res)
+	return res
+}
+
 // LabelNames returns all known label names.
 func (s *ProxyStore) LabelNames(ctx context.Context, r *storepb.LabelNamesRequest) (
 	*storepb.LabelNamesResponse, error,
 ) {
+	match, newMatchers, err := labelsMatches(s.selectorLabels, r.Matchers)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !match {
+		return &storepb.LabelNamesResponse{}, nil
+	}
+
 	var (
 		warnings []string
 		names    [][]string
 		mtx      sync.Mutex
 		g, gctx  = errgroup.WithContext(ctx)
+		abort    = partialResponseDisabled(r.PartialResponseDisabled, r.PartialResponseStrategy)
 	)
 
+	var stores []Client
 	for _, st := range s.stores() {
+		if ok, _ := labelRequestStoreMatches(st, r.MinTime, r.MaxTime, newMatchers...); !ok || !s.labelValuesMayMatch(st, newMatchers) {
+			continue
+		}
+		stores = append(stores, st)
+	}
+	stores, _ = s.preferLocalZone(stores, r.MinTime, r.MaxTime)
+
+	for _, st := range stores {
 		st := st
 		g.Go(func() error {
 			resp, err := st.LabelNames(gctx, &storepb.LabelNamesRequest{
-				PartialResponseDisabled: r.PartialResponseDisabled,
+				PartialResponseDisabled: abort,
+				PartialResponseStrategy: r.PartialResponseStrategy,
+				MinTime:                 r.MinTime,
+				MaxTime:                 r.MaxTime,
+				Matchers:                newMatchers,
 			})
 			if err != nil {
 				err = errors.Wrapf(err, "fetch label names from store %s", st)
-				if r.PartialResponseDisabled {
+				if abort {
 					return err
 				}
 
 				mtx.Lock()
-				warnings = append(warnings, err.Error())
+				warnings = append(warnings, storeErrWarning(st.Addr(), err).Error())
 				mtx.Unlock()
 				return nil
 			}
 
 			mtx.Lock()
-			warnings = append(warnings, resp.Warnings...)
+			warnings = append(warnings, storeWarnings(st.Addr(), resp.Warnings)...)
 			names = append(names, resp.Names)
 			mtx.Unlock()
 
@@ -448,34 +894,60 @@ func (s *ProxyStore) LabelNames(ctx context.Context, r *storepb.LabelNamesReques
 func (s *ProxyStore) LabelValues(ctx context.Context, r *storepb.LabelValuesRequest) (
 	*storepb.LabelValuesResponse, error,
 ) {
+	match, newMatchers, err := labelsMatches(s.selectorLabels, r.Matchers)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !match {
+		return &storepb.LabelValuesResponse{}, nil
+	}
+
 	var (
 		warnings []string
 		all      [][]string
 		mtx      sync.Mutex
 		g, gctx  = errgroup.WithContext(ctx)
+		abort    = partialResponseDisabled(r.PartialResponseDisabled, r.PartialResponseStrategy)
 	)
 
+	var stores []Client
 	for _, st := range s.stores() {
+		if ok, _ := labelRequestStoreMatches(st, r.MinTime, r.MaxTime, newMatchers...); !ok || !s.labelValuesMayMatch(st, newMatchers) {
+			continue
+		}
+		stores = append(stores, st)
+	}
+	stores, _ = s.preferLocalZone(stores, r.MinTime, r.MaxTime)
+
+	for _, st := range stores {
 		store := st
 		g.Go(func() error {
 			resp, err := store.LabelValues(gctx, &storepb.LabelValuesRequest{
 				Label:                   r.Label,
-				PartialResponseDisabled: r.PartialResponseDisabled,
+				PartialResponseDisabled: abort,
+				PartialResponseStrategy: r.PartialResponseStrategy,
+				MinTime:                 r.MinTime,
+				MaxTime:                 r.MaxTime,
+				Matchers:                newMatchers,
 			})
 			if err != nil {
 				err = errors.Wrapf(err, "fetch label values from store %s", store)
-				if r.PartialResponseDisabled {
+				if abort {
 					return err
 				}
 
 				mtx.Lock()
-				warnings = append(warnings, errors.Wrap(err, "fetch label values").Error())
+				warnings = append(warnings, storeErrWarning(store.Addr(), err).Error())
 				mtx.Unlock()
 				return nil
 			}
 
+			if s.labelValues != nil && len(resp.Warnings) == 0 && completeLabelValuesRequest(store, r) {
+				s.labelValues.set(store.Addr(), r.Label, resp.Values)
+			}
+
 			mtx.Lock()
-			warnings = append(warnings, resp.Warnings...)
+			warnings = append(warnings, storeWarnings(store.Addr(), resp.Warnings)...)
 			all = append(all, resp.Values)
 			mtx.Unlock()
 
@@ -492,3 +964,21 @@ func (s *ProxyStore) LabelValues(ctx context.Context, r *storepb.LabelValuesRequ
 		Warnings: warnings,
 	}, nil
 }
+
+// storeErrWarning returns the warning of the failed request to the store with the given address.
+func storeErrWarning(addr string, err error) storepb.Warning {
+	code := storepb.WarningStoreError
+	if cause := errors.Cause(err); cause == context.DeadlineExceeded || status.Code(cause) == codes.DeadlineExceeded {
+		code = storepb.WarningStoreTimeout
+	}
+	return storepb.NewWarning(code, addr, err)
+}
+
+// storeWarnings returns the given warnings reported by the store with the given address as warnings with codes.
+func storeWarnings(addr string, warnings []string) []string {
+	res := make([]string, 0, len(warnings))
+	for _, w := range warnings {
+		res = append(res, storepb.StoreWarning(addr, w).Error())
+	}
+	return res
+}
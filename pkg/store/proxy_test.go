@@ -5,6 +5,7 @@ import (
 	"io"
 	"math"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -56,6 +57,8 @@ func TestProxyStore_Info(t *testing.T) {
 		func() []Client { return nil },
 		component.Query,
 		nil, 0*time.Second,
+		nil, 0, false, 0,
+		"", "",
 	)
 
 	resp, err := q.Info(ctx, &storepb.InfoRequest{})
@@ -411,6 +414,8 @@ func TestProxyStore_Series(t *testing.T) {
 				component.Query,
 				tc.selectorLabels,
 				0*time.Second,
+				nil, 0, false, 0,
+				"", "",
 			)
 
 			s := newStoreSeriesServer(context.Background())
@@ -533,6 +538,8 @@ func TestProxyStore_SeriesSlowStores(t *testing.T) {
 				component.Query,
 				tc.selectorLabels,
 				4*time.Second,
+				nil, 0, false, 0,
+				"", "",
 			)
 
 			s := newStoreSeriesServer(context.Background())
@@ -575,6 +582,8 @@ func TestProxyStore_Series_RequestParamsProxied(t *testing.T) {
 		component.Query,
 		nil,
 		0*time.Second,
+		nil, 0, false, 0,
+		"", "",
 	)
 
 	ctx := context.Background()
@@ -596,6 +605,47 @@ func TestProxyStore_Series_RequestParamsProxied(t *testing.T) {
 	testutil.Assert(t, proto.Equal(req, m.LastSeriesReq), "request was not proxied properly to underlying storeAPI: %s vs %s", req, m.LastSeriesReq)
 }
 
+func TestProxyStore_PartialResponseStrategy(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	m := &mockedStoreAPI{RespError: errors.New("test error")}
+	q := NewProxyStore(nil,
+		func() []Client { return []Client{&testClient{StoreClient: m, minTime: 1, maxTime: 300}} },
+		component.Query,
+		nil,
+		0*time.Second,
+		nil, 0, false, 0,
+		"", "",
+	)
+	ctx := context.Background()
+
+	// Errors of single stores are warnings with the warn strategy.
+	s := newStoreSeriesServer(ctx)
+	testutil.Ok(t, q.Series(&storepb.SeriesRequest{MinTime: 1, MaxTime: 300, PartialResponseStrategy: storepb.PartialResponseStrategy_WARN}, s))
+	testutil.Assert(t, len(s.Warnings) > 0 && strings.Contains(s.Warnings[0], "test error"), "expected warning of the failed store, got %v", s.Warnings)
+	testutil.Assert(t, !m.LastSeriesReq.PartialResponseDisabled, "partial response disabled for warn strategy")
+
+	w, ok := storepb.ParseWarning(s.Warnings[0])
+	testutil.Assert(t, ok, "warning without code %s", s.Warnings[0])
+	testutil.Equals(t, storepb.WarningStoreError, w.Code)
+	testutil.Equals(t, "testaddr", w.Store)
+
+	resp, err := q.LabelNames(ctx, &storepb.LabelNamesRequest{PartialResponseStrategy: storepb.PartialResponseStrategy_WARN})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(resp.Warnings))
+
+	// The abort strategy fails the request and is passed on to the stores.
+	testutil.NotOk(t, q.Series(&storepb.SeriesRequest{MinTime: 1, MaxTime: 300, PartialResponseStrategy: storepb.PartialResponseStrategy_ABORT}, newStoreSeriesServer(ctx)))
+	testutil.Assert(t, m.LastSeriesReq.PartialResponseDisabled, "partial response not disabled for abort strategy")
+	testutil.Equals(t, storepb.PartialResponseStrategy_ABORT, m.LastSeriesReq.PartialResponseStrategy)
+
+	_, err = q.LabelNames(ctx, &storepb.LabelNamesRequest{PartialResponseStrategy: storepb.PartialResponseStrategy_ABORT})
+	testutil.NotOk(t, err)
+	_, err = q.LabelValues(ctx, &storepb.LabelValuesRequest{Label: "a", PartialResponseStrategy: storepb.PartialResponseStrategy_ABORT})
+	testutil.NotOk(t, err)
+	testutil.Equals(t, storepb.PartialResponseStrategy_ABORT, m.LastLabelValuesReq.PartialResponseStrategy)
+}
+
 func TestProxyStore_Series_RegressionFillResponseChannel(t *testing.T) {
 	defer leaktest.CheckTimeout(t, 10*time.Second)()
 
@@ -634,6 +684,8 @@ func TestProxyStore_Series_RegressionFillResponseChannel(t *testing.T) {
 		component.Query,
 		tlabels.FromStrings("fed", "a"),
 		0*time.Second,
+		nil, 0, false, 0,
+		"", "",
 	)
 
 	ctx := context.Background()
@@ -672,6 +724,8 @@ func TestProxyStore_LabelValues(t *testing.T) {
 		component.Query,
 		nil,
 		0*time.Second,
+		nil, 0, false, 0,
+		"", "",
 	)
 
 	ctx := context.Background()
@@ -684,7 +738,386 @@ func TestProxyStore_LabelValues(t *testing.T) {
 	testutil.Assert(t, proto.Equal(req, m1.LastLabelValuesReq), "request was not proxied properly to underlying storeAPI: %s vs %s", req, m1.LastLabelValuesReq)
 
 	testutil.Equals(t, []string{"1", "2", "3", "4"}, resp.Values)
-	testutil.Equals(t, 1, len(resp.Warnings))
+	// Warnings of stores are passed on with their store.
+	testutil.Equals(t, []string{"code=store_warning store=testaddr: warning"}, resp.Warnings)
+}
+
+func TestProxyStore_Series_Hedging(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	replica := func(value string, delay time.Duration) *testClient {
+		return &testClient{
+			StoreClient: &mockedStoreAPI{
+				RespSeries:   []*storepb.SeriesResponse{storeSeriesResponse(t, labels.FromStrings("replica", value), []sample{{1, 1}})},
+				RespDuration: delay,
+			},
+			labels:  []storepb.Label{{Name: "ext", Value: "1"}},
+			minTime: 1,
+			maxTime: 300,
+		}
+	}
+	slow, fast := replica("slow", 500*time.Millisecond), replica("fast", 0)
+
+	for _, tcase := range []struct {
+		name       string
+		hedgeDelay time.Duration
+		expected   []string
+	}{
+		{name: "all replicas are queried without hedging", expected: []string{"fast", "slow"}},
+		{name: "the faster replica is used with hedging", hedgeDelay: 10 * time.Millisecond, expected: []string{"fast"}},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			q := NewProxyStore(nil,
+				func() []Client { return []Client{slow, fast} },
+				component.Query,
+				nil,
+				0*time.Second,
+				nil, tcase.hedgeDelay, false, 0,
+				"", "",
+			)
+
+			s := newStoreSeriesServer(context.Background())
+			testutil.Ok(t, q.Series(&storepb.SeriesRequest{MinTime: 1, MaxTime: 300}, s))
+			testutil.Equals(t, 0, len(s.Warnings))
+
+			var replicas []string
+			for _, series := range s.SeriesSet {
+				replicas = append(replicas, series.Labels[0].Value)
+			}
+			testutil.Equals(t, tcase.expected, replicas)
+		})
+	}
+}
+
+func TestProxyStore_Series_GroupReplicas(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	replica := func(value string, err error) *testClient {
+		return &testClient{
+			StoreClient: &mockedStoreAPI{
+				RespSeries: []*storepb.SeriesResponse{storeSeriesResponse(t, labels.FromStrings("replica", value), []sample{{1, 1}})},
+				RespError:  err,
+			},
+			labels:  []storepb.Label{{Name: "ext", Value: "1"}},
+			minTime: 1,
+			maxTime: 300,
+		}
+	}
+	a, b, broken := replica("a", nil), replica("b", nil), replica("broken", errors.New("unavailable"))
+
+	q := NewProxyStore(nil,
+		func() []Client { return []Client{a, b, broken} },
+		component.Query,
+		nil,
+		0*time.Second,
+		nil, 0, true, 0,
+		"", "",
+	)
+
+	counts := map[string]int{}
+	for i := 0; i < 6; i++ {
+		s := newStoreSeriesServer(context.Background())
+		testutil.Ok(t, q.Series(&storepb.SeriesRequest{MinTime: 1, MaxTime: 300}, s))
+		testutil.Equals(t, 0, len(s.Warnings))
+		// Only one replica is queried, requests to the broken one fail over to the next replica.
+		testutil.Equals(t, 1, len(s.SeriesSet))
+		counts[s.SeriesSet[0].Labels[0].Value]++
+	}
+	// Requests are balanced across the replicas.
+	testutil.Equals(t, 2, len(counts))
+	testutil.Assert(t, counts["a"] > 1 && counts["b"] > 1, "unbalanced requests %v", counts)
+}
+
+func TestProxyStore_Series_FailoverMidStream(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	series := func(values ...string) (resps []*storepb.SeriesResponse) {
+		for _, v := range values {
+			resps = append(resps, storeSeriesResponse(t, labels.FromStrings("a", v), []sample{{1, 1}}))
+		}
+		return resps
+	}
+	replica := func(err error, values ...string) *testClient {
+		return &testClient{
+			StoreClient: &mockedStoreAPI{RespSeries: series(values...), RespSeriesError: err},
+			labels:      []storepb.Label{{Name: "ext", Value: "1"}},
+			minTime:     1,
+			maxTime:     300,
+		}
+	}
+
+	for _, tcase := range []struct {
+		name     string
+		replicas []Client
+		expected []string
+		warnings int
+	}{
+		{
+			name: "failed stream resumes from the next replica without duplicates",
+			replicas: []Client{
+				replica(errors.New("connection reset"), "1", "2"),
+				replica(nil, "1", "2", "3", "4"),
+			},
+			expected: []string{"1", "2", "3", "4"},
+		},
+		{
+			name: "failed stream resumes from the replicas until one succeeds",
+			replicas: []Client{
+				replica(errors.New("connection reset"), "1"),
+				replica(errors.New("connection reset"), "1", "2", "3"),
+				replica(nil, "1", "2", "3", "4"),
+			},
+			expected: []string{"1", "2", "3", "4"},
+		},
+		{
+			name: "stream fails once all replicas failed",
+			replicas: []Client{
+				replica(errors.New("connection reset"), "1"),
+				replica(errors.New("connection reset"), "1", "2"),
+			},
+			expected: []string{"1", "2"},
+			warnings: 1,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			q := NewProxyStore(nil,
+				func() []Client { return tcase.replicas },
+				component.Query,
+				nil,
+				0*time.Second,
+				nil, 0, true, 0,
+				"", "",
+			)
+			// Start with the first replica.
+			q.nextReplica = uint64(len(tcase.replicas) - 1)
+
+			s := newStoreSeriesServer(context.Background())
+			testutil.Ok(t, q.Series(&storepb.SeriesRequest{MinTime: 1, MaxTime: 300}, s))
+			testutil.Equals(t, tcase.warnings, len(s.Warnings))
+
+			var values []string
+			for _, series := range s.SeriesSet {
+				values = append(values, series.Labels[0].Value)
+			}
+			testutil.Equals(t, tcase.expected, values)
+		})
+	}
+}
+
+func TestProxyStore_Series_StoreResponseTimeout(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	q := NewProxyStore(nil,
+		func() []Client {
+			return []Client{&testClient{
+				StoreClient: &mockedStoreAPI{
+					RespSeries:   []*storepb.SeriesResponse{storeSeriesResponse(t, labels.FromStrings("a", "b"), []sample{{1, 1}})},
+					RespDuration: 200 * time.Millisecond,
+				},
+				minTime: 1,
+				maxTime: 300,
+			}}
+		},
+		component.Query,
+		nil,
+		0*time.Second,
+		map[string]time.Duration{"testaddr": 50 * time.Millisecond}, 0, false, 0,
+		"", "",
+	)
+
+	s := newStoreSeriesServer(context.Background())
+	testutil.Ok(t, q.Series(&storepb.SeriesRequest{MinTime: 1, MaxTime: 300}, s))
+	testutil.Equals(t, 0, len(s.SeriesSet))
+	testutil.Equals(t, 1, len(s.Warnings))
+	testutil.Assert(t, strings.Contains(s.Warnings[0], "failed to receive any data in 50ms"), "unexpected warning %s", s.Warnings[0])
+
+	w, ok := storepb.ParseWarning(s.Warnings[0])
+	testutil.Assert(t, ok, "warning without code %s", s.Warnings[0])
+	testutil.Equals(t, storepb.WarningStoreTimeout, w.Code)
+	testutil.Equals(t, "testaddr", w.Store)
+}
+
+func TestProxyStore_PreferLocalZone(t *testing.T) {
+	zoneStore := func(zone, cluster string, mint, maxt int64) *testClient {
+		lset := []storepb.Label{{Name: "cluster", Value: cluster}}
+		if zone != "" {
+			lset = append(lset, storepb.Label{Name: "zone", Value: zone})
+		}
+		return &testClient{labels: lset, minTime: mint, maxTime: maxt}
+	}
+	var (
+		localA   = zoneStore("a", "c1", 0, 100)
+		remoteA  = zoneStore("b", "c1", 0, 100)
+		remoteB  = zoneStore("b", "c2", 0, 100)
+		recentA  = zoneStore("a", "c3", 50, 100)
+		longerA  = zoneStore("b", "c3", 0, 100)
+		noZoneA  = zoneStore("", "c1", 0, 100)
+		allZones = []Client{localA, remoteA, remoteB, recentA, longerA, noZoneA}
+	)
+
+	for _, tcase := range []struct {
+		name       string
+		localZone  string
+		mint, maxt int64
+
+		queried []Client
+		remote  []Client
+	}{
+		{name: "no local zone", mint: 0, maxt: 100, queried: allZones},
+		{
+			name:      "remote stores covered by local stores are not queried",
+			localZone: "a", mint: 0, maxt: 100,
+			queried: []Client{localA, recentA, noZoneA, remoteB, longerA},
+			remote:  []Client{remoteA},
+		},
+		{
+			name:      "local stores cover the requested part of remote stores",
+			localZone: "a", mint: 60, maxt: 100,
+			queried: []Client{localA, recentA, noZoneA, remoteB},
+			remote:  []Client{remoteA, longerA},
+		},
+		{
+			name:      "stores without zone are local to all zones",
+			localZone: "b", mint: 0, maxt: 100,
+			queried: []Client{remoteA, remoteB, longerA, noZoneA},
+			remote:  []Client{localA, recentA},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			q := NewProxyStore(nil, func() []Client { return allZones }, component.Query, nil, 0, nil, 0, false, 0, "zone", tcase.localZone)
+
+			queried, remote := q.preferLocalZone(allZones, tcase.mint, tcase.maxt)
+			testutil.Equals(t, tcase.queried, queried)
+			testutil.Equals(t, tcase.remote, remote)
+		})
+	}
+}
+
+func TestProxyStore_Series_Hints(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	hints := storepb.SeriesHints{QueriedBlocks: []storepb.QueriedBlock{{Id: "block", SeriesTouched: 1}}}
+	m := &mockedStoreAPI{
+		RespSeries: []*storepb.SeriesResponse{
+			storeSeriesResponse(t, labels.FromStrings("a", "a"), []sample{{0, 0}, {2, 1}, {3, 2}}),
+			storepb.NewHintsSeriesResponse(&hints),
+		},
+	}
+	q := NewProxyStore(nil,
+		func() []Client {
+			return []Client{&testClient{StoreClient: m, minTime: 1, maxTime: 300}}
+		},
+		component.Query,
+		nil,
+		0*time.Second,
+		nil, 0, false, 0,
+		"", "",
+	)
+
+	s := newStoreSeriesServer(context.Background())
+	testutil.Ok(t, q.Series(&storepb.SeriesRequest{
+		MinTime:  1,
+		MaxTime:  300,
+		Matchers: []storepb.LabelMatcher{{Name: "a", Value: "a", Type: storepb.LabelMatcher_EQ}},
+		Hints:    true,
+	}, s))
+
+	testutil.Assert(t, m.LastSeriesReq.Hints, "hints not requested from the store")
+	testutil.Equals(t, 1, len(s.SeriesSet))
+	testutil.Equals(t, []storepb.SeriesHints{hints}, s.Hints)
+}
+
+func TestProxyStore_Series_QueryStats(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	m := &mockedStoreAPI{
+		RespSeries: []*storepb.SeriesResponse{
+			storeSeriesResponse(t, labels.FromStrings("a", "a"), []sample{{0, 0}, {2, 1}, {3, 2}}),
+			storeSeriesResponse(t, labels.FromStrings("a", "b"), []sample{{0, 0}}),
+			storepb.NewWarnSeriesResponse(errors.New("partial")),
+		},
+	}
+	q := NewProxyStore(nil,
+		func() []Client {
+			return []Client{&testClient{StoreClient: m, minTime: 1, maxTime: 300}}
+		},
+		component.Query,
+		nil,
+		0*time.Second,
+		nil, 0, false, 0,
+		"", "",
+	)
+
+	stats := NewQueryStats()
+	req := &storepb.SeriesRequest{
+		MinTime:  1,
+		MaxTime:  300,
+		Matchers: []storepb.LabelMatcher{{Name: "a", Value: ".+", Type: storepb.LabelMatcher_RE}},
+	}
+	testutil.Ok(t, q.Series(req, newStoreSeriesServer(ContextWithQueryStats(context.Background(), stats))))
+	testutil.Ok(t, q.Series(req, newStoreSeriesServer(ContextWithQueryStats(context.Background(), stats))))
+
+	res := stats.Stores()
+	testutil.Equals(t, 1, len(res))
+	testutil.Equals(t, "testaddr", res[0].Store)
+	testutil.Equals(t, 2, res[0].Requests)
+	testutil.Equals(t, 4, res[0].Series)
+	testutil.Equals(t, 4, res[0].Chunks)
+	testutil.Equals(t, []string{"partial", "partial"}, res[0].Warnings)
+	testutil.Assert(t, res[0].MaxDuration <= res[0].Duration, "max duration exceeds total duration")
+
+	// Requests without statistics in their context are not recorded.
+	testutil.Ok(t, q.Series(req, newStoreSeriesServer(context.Background())))
+	testutil.Equals(t, 2, stats.Stores()[0].Requests)
+}
+
+func TestProxyStore_LabelValues_MatchersAndTimeRange(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	m1 := &mockedStoreAPI{
+		RespLabelValues: &storepb.LabelValuesResponse{Values: []string{"1", "2"}},
+	}
+	cls := []Client{
+		&testClient{
+			StoreClient: m1,
+			labels:      []storepb.Label{{Name: "ext", Value: "1"}},
+			minTime:     0,
+			maxTime:     100,
+		},
+		// Filtered out by the external labels.
+		&testClient{
+			StoreClient: &mockedStoreAPI{RespLabelValues: &storepb.LabelValuesResponse{Values: []string{"3"}}},
+			labels:      []storepb.Label{{Name: "ext", Value: "2"}},
+			minTime:     0,
+			maxTime:     100,
+		},
+		// Filtered out by the time range.
+		&testClient{
+			StoreClient: &mockedStoreAPI{RespLabelValues: &storepb.LabelValuesResponse{Values: []string{"4"}}},
+			labels:      []storepb.Label{{Name: "ext", Value: "1"}},
+			minTime:     200,
+			maxTime:     300,
+		},
+	}
+	q := NewProxyStore(nil,
+		func() []Client { return cls },
+		component.Query,
+		nil,
+		0*time.Second,
+		nil, 0, false, 0,
+		"", "",
+	)
+
+	req := &storepb.LabelValuesRequest{
+		Label:    "a",
+		MinTime:  10,
+		MaxTime:  50,
+		Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "ext", Value: "1"}},
+	}
+	resp, err := q.LabelValues(context.Background(), req)
+	testutil.Ok(t, err)
+	testutil.Assert(t, proto.Equal(req, m1.LastLabelValuesReq), "request was not proxied properly to underlying storeAPI: %s vs %s", req, m1.LastLabelValuesReq)
+	testutil.Equals(t, []string{"1", "2"}, resp.Values)
 }
 
 func TestProxyStore_LabelNames(t *testing.T) {
@@ -775,6 +1208,8 @@ func TestProxyStore_LabelNames(t *testing.T) {
 				component.Query,
 				nil,
 				0*time.Second,
+				nil, 0, false, 0,
+				"", "",
 			)
 
 			ctx := context.Background()
@@ -910,6 +1345,7 @@ type storeSeriesServer struct {
 
 	SeriesSet []storepb.Series
 	Warnings  []string
+	Hints     []storepb.SeriesHints
 }
 
 func newStoreSeriesServer(ctx context.Context) *storeSeriesServer {
@@ -922,6 +1358,11 @@ func (s *storeSeriesServer) Send(r *storepb.SeriesResponse) error {
 		return nil
 	}
 
+	if h := r.GetHints(); h != nil {
+		s.Hints = append(s.Hints, *h)
+		return nil
+	}
+
 	if r.GetSeries() == nil {
 		return errors.New("no seriesSet")
 	}
@@ -940,6 +1381,8 @@ type mockedStoreAPI struct {
 	RespLabelNames  *storepb.LabelNamesResponse
 	RespError       error
 	RespDuration    time.Duration
+	// RespSeriesError is returned by the Series stream after all RespSeries.
+	RespSeriesError error
 
 	LastSeriesReq      *storepb.SeriesRequest
 	LastLabelValuesReq *storepb.LabelValuesRequest
@@ -953,7 +1396,7 @@ func (s *mockedStoreAPI) Info(ctx context.Context, req *storepb.InfoRequest, _ .
 func (s *mockedStoreAPI) Series(ctx context.Context, req *storepb.SeriesRequest, _ ...grpc.CallOption) (storepb.Store_SeriesClient, error) {
 	s.LastSeriesReq = req
 
-	return &StoreSeriesClient{ctx: ctx, respSet: s.RespSeries, respDur: s.RespDuration}, s.RespError
+	return &StoreSeriesClient{ctx: ctx, respSet: s.RespSeries, respDur: s.RespDuration, err: s.RespSeriesError}, s.RespError
 }
 
 func (s *mockedStoreAPI) LabelNames(ctx context.Context, req *storepb.LabelNamesRequest, _ ...grpc.CallOption) (*storepb.LabelNamesResponse, error) {
@@ -976,12 +1419,16 @@ type StoreSeriesClient struct {
 	i       int
 	respSet []*storepb.SeriesResponse
 	respDur time.Duration
+	err     error
 }
 
 func (c *StoreSeriesClient) Recv() (*storepb.SeriesResponse, error) {
 	time.Sleep(c.respDur)
 
 	if c.i >= len(c.respSet) {
+		if c.err != nil {
+			return nil, c.err
+		}
 		return nil, io.EOF
 	}
 	s := c.respSet[c.i]
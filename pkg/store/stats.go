@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+type queryStatsKey struct{}
+
+// QueryStats collects statistics of the Series requests the ProxyStore sends to each store while evaluating a query.
+// It is safe for concurrent use, a nil QueryStats collects nothing.
+type QueryStats struct {
+	mtx    sync.Mutex
+	stores map[string]*StoreStats
+}
+
+// StoreStats are the statistics of the Series requests sent to a single store.
+type StoreStats struct {
+	// Store is the address of the store.
+	Store    string
+	Requests int
+	Series   int
+	Chunks   int
+	// Duration is the sum of the durations of all requests, MaxDuration the duration of the slowest one.
+	Duration    time.Duration
+	MaxDuration time.Duration
+	Warnings    []string
+}
+
+// NewQueryStats returns new empty query statistics.
+func NewQueryStats() *QueryStats {
+	return &QueryStats{stores: map[string]*StoreStats{}}
+}
+
+// ContextWithQueryStats returns a context making the ProxyStore collect the statistics of Series requests into s.
+func ContextWithQueryStats(ctx context.Context, s *QueryStats) context.Context {
+	return context.WithValue(ctx, queryStatsKey{}, s)
+}
+
+// QueryStatsFromContext returns the query statistics of the context or nil if there are none.
+func QueryStatsFromContext(ctx context.Context) *QueryStats {
+	s, _ := ctx.Value(queryStatsKey{}).(*QueryStats)
+	return s
+}
+
+// observe records a finished Series request against the given store.
+func (s *QueryStats) observe(store string, d time.Duration, series, chunks int, warnings ...string) {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	st, ok := s.stores[store]
+	if !ok {
+		st = &StoreStats{Store: store}
+		s.stores[store] = st
+	}
+	st.Requests++
+	st.Series += series
+	st.Chunks += chunks
+	st.Duration += d
+	if d > st.MaxDuration {
+		st.MaxDuration = d
+	}
+	st.Warnings = append(st.Warnings, warnings...)
+}
+
+// Stores returns the statistics of all stores sorted by address.
+func (s *QueryStats) Stores() []StoreStats {
+	if s == nil {
+		return nil
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	res := make([]StoreStats, 0, len(s.stores))
+	for _, st := range s.stores {
+		c := *st
+		c.Warnings = append([]string(nil), st.Warnings...)
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Store < res[j].Store
+	})
+	return res
+}
+
+// MarshalJSON marshals the statistics with durations in seconds.
+func (s StoreStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Store       string   `json:"store"`
+		Requests    int      `json:"requests"`
+		Series      int      `json:"series"`
+		Chunks      int      `json:"chunks"`
+		Duration    float64  `json:"durationSeconds"`
+		MaxDuration float64  `json:"maxDurationSeconds"`
+		Warnings    []string `json:"warnings,omitempty"`
+	}{
+		Store:       s.Store,
+		Requests:    s.Requests,
+		Series:      s.Series,
+		Chunks:      s.Chunks,
+		Duration:    s.Duration.Seconds(),
+		MaxDuration: s.MaxDuration.Seconds(),
+		Warnings:    s.Warnings,
+	})
+}
@@ -1,8 +1,10 @@
 package storepb
 
 import (
+	"sort"
 	"strings"
 
+	"github.com/cespare/xxhash"
 	"github.com/prometheus/prometheus/pkg/labels"
 )
 
@@ -30,6 +32,14 @@ func NewSeriesResponse(series *Series) *SeriesResponse {
 	}
 }
 
+func NewHintsSeriesResponse(hints *SeriesHints) *SeriesResponse {
+	return &SeriesResponse{
+		Result: &SeriesResponse_Hints{
+			Hints: hints,
+		},
+	}
+}
+
 // CompareLabels compares two sets of labels.
 func CompareLabels(a, b []Label) int {
 	l := len(a)
@@ -48,6 +58,27 @@ func CompareLabels(a, b []Label) int {
 	return len(a) - len(b)
 }
 
+// LabelsToMap returns the labels as map from label names to values, e.g. for the JSON responses of Prometheus APIs.
+func LabelsToMap(lset []Label) map[string]string {
+	m := make(map[string]string, len(lset))
+	for _, l := range lset {
+		m[l.Name] = l.Value
+	}
+	return m
+}
+
+// LabelsFromMap returns the sorted labels of the given map from label names to values.
+func LabelsFromMap(m map[string]string) []Label {
+	lset := make([]Label, 0, len(m))
+	for name, value := range m {
+		lset = append(lset, Label{Name: name, Value: value})
+	}
+	sort.Slice(lset, func(i, j int) bool {
+		return lset[i].Name < lset[j].Name
+	})
+	return lset
+}
+
 type emptySeriesSet struct{}
 
 func (emptySeriesSet) Next() bool                 { return false }
@@ -177,3 +208,26 @@ func LabelsToString(lset []Label) string {
 	}
 	return "[" + strings.Join(s, ",") + "]"
 }
+
+// shardLabelSep separates the label values hashed to select the shard of a series.
+var shardLabelSep = []byte{0xff}
+
+// Matches returns whether the series with the given labels belongs to the shard. A nil ShardInfo or one with at most
+// one shard matches all series.
+func (m *ShardInfo) Matches(lset []Label) bool {
+	if m == nil || m.TotalShards <= 1 {
+		return true
+	}
+
+	h := xxhash.New()
+	for _, name := range m.Labels {
+		for _, l := range lset {
+			if l.Name == name {
+				_, _ = h.Write([]byte(l.Value))
+				break
+			}
+		}
+		_, _ = h.Write(shardLabelSep)
+	}
+	return h.Sum64()%uint64(m.TotalShards) == uint64(m.ShardIndex)
+}
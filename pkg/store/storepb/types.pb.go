@@ -25,20 +25,28 @@ type Chunk_Encoding int32
 
 const (
 	Chunk_XOR Chunk_Encoding = 0
+	// Native histogram chunks of newer Prometheus versions. They are passed through as is, as
+	// this version cannot decode them.
+	Chunk_HISTOGRAM       Chunk_Encoding = 1
+	Chunk_FLOAT_HISTOGRAM Chunk_Encoding = 2
 )
 
 var Chunk_Encoding_name = map[int32]string{
 	0: "XOR",
+	1: "HISTOGRAM",
+	2: "FLOAT_HISTOGRAM",
 }
 var Chunk_Encoding_value = map[string]int32{
-	"XOR": 0,
+	"XOR":             0,
+	"HISTOGRAM":       1,
+	"FLOAT_HISTOGRAM": 2,
 }
 
 func (x Chunk_Encoding) String() string {
 	return proto.EnumName(Chunk_Encoding_name, int32(x))
 }
 func (Chunk_Encoding) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_types_60e135d4a4f03620, []int{1, 0}
+	return fileDescriptor_types_fd73e423f1489382, []int{1, 0}
 }
 
 type LabelMatcher_Type int32
@@ -67,7 +75,7 @@ func (x LabelMatcher_Type) String() string {
 	return proto.EnumName(LabelMatcher_Type_name, int32(x))
 }
 func (LabelMatcher_Type) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_types_60e135d4a4f03620, []int{4, 0}
+	return fileDescriptor_types_fd73e423f1489382, []int{4, 0}
 }
 
 type Label struct {
@@ -82,7 +90,7 @@ func (m *Label) Reset()         { *m = Label{} }
 func (m *Label) String() string { return proto.CompactTextString(m) }
 func (*Label) ProtoMessage()    {}
 func (*Label) Descriptor() ([]byte, []int) {
-	return fileDescriptor_types_60e135d4a4f03620, []int{0}
+	return fileDescriptor_types_fd73e423f1489382, []int{0}
 }
 func (m *Label) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -123,7 +131,7 @@ func (m *Chunk) Reset()         { *m = Chunk{} }
 func (m *Chunk) String() string { return proto.CompactTextString(m) }
 func (*Chunk) ProtoMessage()    {}
 func (*Chunk) Descriptor() ([]byte, []int) {
-	return fileDescriptor_types_60e135d4a4f03620, []int{1}
+	return fileDescriptor_types_fd73e423f1489382, []int{1}
 }
 func (m *Chunk) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -164,7 +172,7 @@ func (m *Series) Reset()         { *m = Series{} }
 func (m *Series) String() string { return proto.CompactTextString(m) }
 func (*Series) ProtoMessage()    {}
 func (*Series) Descriptor() ([]byte, []int) {
-	return fileDescriptor_types_60e135d4a4f03620, []int{2}
+	return fileDescriptor_types_fd73e423f1489382, []int{2}
 }
 func (m *Series) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -211,7 +219,7 @@ func (m *AggrChunk) Reset()         { *m = AggrChunk{} }
 func (m *AggrChunk) String() string { return proto.CompactTextString(m) }
 func (*AggrChunk) ProtoMessage()    {}
 func (*AggrChunk) Descriptor() ([]byte, []int) {
-	return fileDescriptor_types_60e135d4a4f03620, []int{3}
+	return fileDescriptor_types_fd73e423f1489382, []int{3}
 }
 func (m *AggrChunk) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -254,7 +262,7 @@ func (m *LabelMatcher) Reset()         { *m = LabelMatcher{} }
 func (m *LabelMatcher) String() string { return proto.CompactTextString(m) }
 func (*LabelMatcher) ProtoMessage()    {}
 func (*LabelMatcher) Descriptor() ([]byte, []int) {
-	return fileDescriptor_types_60e135d4a4f03620, []int{4}
+	return fileDescriptor_types_fd73e423f1489382, []int{4}
 }
 func (m *LabelMatcher) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -1524,35 +1532,37 @@ var (
 	ErrIntOverflowTypes   = fmt.Errorf("proto: integer overflow")
 )
 
-func init() { proto.RegisterFile("types.proto", fileDescriptor_types_60e135d4a4f03620) }
+func init() { proto.RegisterFile("types.proto", fileDescriptor_types_fd73e423f1489382) }
 
-var fileDescriptor_types_60e135d4a4f03620 = []byte{
-	// 432 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x92, 0xdd, 0x6e, 0xd3, 0x30,
-	0x14, 0xc7, 0xeb, 0x7c, 0x76, 0x67, 0x03, 0x05, 0x33, 0x21, 0x97, 0x8b, 0xae, 0x0a, 0x17, 0x54,
-	0x20, 0x32, 0x31, 0x9e, 0x80, 0xa1, 0xdc, 0xf1, 0xa1, 0x99, 0x5d, 0x20, 0x84, 0x84, 0xdc, 0xce,
-	0xa4, 0x11, 0x8d, 0x5d, 0xc5, 0x0e, 0x74, 0x8f, 0x81, 0x78, 0xa9, 0x5e, 0xf2, 0x04, 0x08, 0xfa,
-	0x24, 0xc8, 0x27, 0x0d, 0x5b, 0xa5, 0xdc, 0x1d, 0x9f, 0xff, 0xef, 0x7c, 0xc8, 0xe7, 0x0f, 0x87,
-	0xf6, 0x7a, 0x25, 0x4d, 0xb6, 0xaa, 0xb5, 0xd5, 0x34, 0xb2, 0x0b, 0xa1, 0xb4, 0x79, 0x78, 0x5c,
-	0xe8, 0x42, 0x63, 0xea, 0xd4, 0x45, 0xad, 0x9a, 0x3e, 0x87, 0xf0, 0xb5, 0x98, 0xc9, 0x25, 0xa5,
-	0x10, 0x28, 0x51, 0x49, 0x46, 0x26, 0x64, 0x7a, 0xc0, 0x31, 0xa6, 0xc7, 0x10, 0x7e, 0x13, 0xcb,
-	0x46, 0x32, 0x0f, 0x93, 0xed, 0x23, 0xfd, 0x04, 0xe1, 0xab, 0x45, 0xa3, 0xbe, 0xd2, 0x27, 0x10,
-	0xb8, 0x41, 0x58, 0x72, 0xf7, 0xec, 0x41, 0xd6, 0x0e, 0xca, 0x50, 0xcc, 0x72, 0x35, 0xd7, 0x57,
-	0xa5, 0x2a, 0x38, 0x32, 0xae, 0xfd, 0x95, 0xb0, 0x02, 0x3b, 0x1d, 0x71, 0x8c, 0xd3, 0xfb, 0x30,
-	0xec, 0x28, 0x1a, 0x83, 0xff, 0xe1, 0x1d, 0x4f, 0x06, 0xe9, 0x17, 0x88, 0xde, 0xcb, 0xba, 0x94,
-	0x86, 0x3e, 0x85, 0x68, 0xe9, 0x56, 0x33, 0x8c, 0x4c, 0xfc, 0xe9, 0xe1, 0xd9, 0x9d, 0x6e, 0x00,
-	0x2e, 0x7c, 0x1e, 0x6c, 0x7e, 0x9f, 0x0c, 0xf8, 0x0e, 0xa1, 0xa7, 0x10, 0xcd, 0xdd, 0x5c, 0xc3,
-	0x3c, 0x84, 0xef, 0x75, 0xf0, 0xcb, 0xa2, 0xa8, 0x71, 0xa3, 0xae, 0xa0, 0xc5, 0xd2, 0x9f, 0x1e,
-	0x1c, 0xfc, 0xd7, 0xe8, 0x08, 0x86, 0x55, 0xa9, 0x3e, 0xdb, 0x72, 0xf7, 0x03, 0x3e, 0x8f, 0xab,
-	0x52, 0x5d, 0x96, 0x95, 0x44, 0x49, 0xac, 0x5b, 0xc9, 0xdb, 0x49, 0x62, 0x8d, 0xd2, 0x09, 0xf8,
-	0xb5, 0xf8, 0xce, 0xfc, 0x09, 0xb9, 0xbd, 0x1e, 0x76, 0xe4, 0x4e, 0xa1, 0x8f, 0x20, 0x9c, 0xeb,
-	0x46, 0x59, 0x16, 0xf4, 0x21, 0xad, 0xe6, 0xba, 0x98, 0xa6, 0x62, 0x61, 0x6f, 0x17, 0xd3, 0x54,
-	0x0e, 0xa8, 0x4a, 0xc5, 0xa2, 0x5e, 0xa0, 0x2a, 0x15, 0x02, 0x62, 0xcd, 0xe2, 0x7e, 0x40, 0xac,
-	0xe9, 0x63, 0x88, 0x71, 0x96, 0xac, 0xd9, 0xb0, 0x0f, 0xea, 0xd4, 0xf4, 0x07, 0x81, 0x23, 0xfc,
-	0xde, 0x37, 0xc2, 0xce, 0x17, 0xb2, 0xa6, 0xcf, 0xf6, 0x6e, 0x3c, 0xda, 0x3b, 0xc1, 0x8e, 0xc9,
-	0x2e, 0xaf, 0x57, 0xf2, 0xe6, 0xcc, 0xe8, 0x22, 0xaf, 0xcf, 0x45, 0xfe, 0x6d, 0x17, 0x4d, 0x21,
-	0x70, 0x75, 0x34, 0x02, 0x2f, 0xbf, 0x48, 0x06, 0xce, 0x00, 0x6f, 0xf3, 0x8b, 0x84, 0xb8, 0x04,
-	0xcf, 0x13, 0x0f, 0x13, 0x3c, 0x4f, 0xfc, 0xf3, 0xd1, 0xe6, 0xef, 0x78, 0xb0, 0xd9, 0x8e, 0xc9,
-	0xaf, 0xed, 0x98, 0xfc, 0xd9, 0x8e, 0xc9, 0xc7, 0xd8, 0x58, 0x5d, 0xcb, 0xd5, 0x6c, 0x16, 0xa1,
-	0x89, 0x5f, 0xfc, 0x0b, 0x00, 0x00, 0xff, 0xff, 0x46, 0x5a, 0xe2, 0x68, 0xf1, 0x02, 0x00, 0x00,
+var fileDescriptor_types_fd73e423f1489382 = []byte{
+	// 459 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x92, 0xcb, 0x6e, 0xd3, 0x40,
+	0x14, 0x86, 0x33, 0xbe, 0x26, 0xa7, 0x2d, 0x98, 0xa1, 0x42, 0x13, 0x16, 0x69, 0x64, 0x16, 0x44,
+	0x20, 0x5c, 0x51, 0x16, 0xac, 0x53, 0x64, 0x2e, 0x52, 0x4b, 0xd4, 0x69, 0x16, 0x88, 0x4d, 0x35,
+	0x49, 0x07, 0xc7, 0x22, 0x1e, 0x47, 0xbe, 0x40, 0xba, 0xe3, 0x15, 0x10, 0x2f, 0x95, 0x25, 0x4f,
+	0x80, 0x20, 0x4f, 0x82, 0xe6, 0xd8, 0xa6, 0xad, 0xf0, 0xee, 0xf8, 0xfc, 0xdf, 0xb9, 0xf8, 0xcc,
+	0x0f, 0x3b, 0xc5, 0xd5, 0x4a, 0xe6, 0xc1, 0x2a, 0x4b, 0x8b, 0x94, 0x3a, 0xc5, 0x42, 0xa8, 0x34,
+	0x7f, 0xb8, 0x1f, 0xa5, 0x51, 0x8a, 0xa9, 0x43, 0x1d, 0x55, 0xaa, 0xff, 0x1c, 0xec, 0x13, 0x31,
+	0x93, 0x4b, 0x4a, 0xc1, 0x52, 0x22, 0x91, 0x8c, 0x0c, 0xc9, 0xa8, 0xc7, 0x31, 0xa6, 0xfb, 0x60,
+	0x7f, 0x11, 0xcb, 0x52, 0x32, 0x03, 0x93, 0xd5, 0x87, 0xff, 0x8d, 0x80, 0xfd, 0x6a, 0x51, 0xaa,
+	0xcf, 0xf4, 0x09, 0x58, 0x7a, 0x12, 0xd6, 0xdc, 0x39, 0x7a, 0x10, 0x54, 0x93, 0x02, 0x14, 0x83,
+	0x50, 0xcd, 0xd3, 0xcb, 0x58, 0x45, 0x1c, 0x19, 0xdd, 0xff, 0x52, 0x14, 0x02, 0x5b, 0xed, 0x72,
+	0x8c, 0xfd, 0x97, 0xd0, 0x6d, 0x28, 0xea, 0x82, 0xf9, 0x61, 0xc2, 0xbd, 0x0e, 0xdd, 0x83, 0xde,
+	0xdb, 0x77, 0xe7, 0xd3, 0xc9, 0x1b, 0x3e, 0x3e, 0xf5, 0x08, 0xbd, 0x0f, 0x77, 0x5f, 0x9f, 0x4c,
+	0xc6, 0xd3, 0x8b, 0xeb, 0xa4, 0xe1, 0x7f, 0x02, 0xe7, 0x5c, 0x66, 0xb1, 0xcc, 0xe9, 0x53, 0x70,
+	0x96, 0x7a, 0xff, 0x9c, 0x91, 0xa1, 0x39, 0xda, 0x39, 0xda, 0x6b, 0x96, 0xc0, 0xbf, 0x3a, 0xb6,
+	0x36, 0xbf, 0x0e, 0x3a, 0xbc, 0x46, 0xe8, 0x21, 0x38, 0x73, 0xbd, 0x5b, 0xce, 0x0c, 0x84, 0xef,
+	0x35, 0xf0, 0x38, 0x8a, 0x32, 0xdc, 0xba, 0x29, 0xa8, 0x30, 0xff, 0x87, 0x01, 0xbd, 0x7f, 0x1a,
+	0xed, 0x43, 0x37, 0x89, 0xd5, 0x45, 0x11, 0xd7, 0x67, 0x32, 0xb9, 0x9b, 0xc4, 0x6a, 0x1a, 0x27,
+	0x12, 0x25, 0xb1, 0xae, 0x24, 0xa3, 0x96, 0xc4, 0x1a, 0xa5, 0x03, 0x30, 0x33, 0xf1, 0x95, 0x99,
+	0x43, 0x72, 0x73, 0x3d, 0xec, 0xc8, 0xb5, 0x42, 0x1f, 0x81, 0x3d, 0x4f, 0x4b, 0x55, 0x30, 0xab,
+	0x0d, 0xa9, 0x34, 0xdd, 0x25, 0x2f, 0x13, 0x66, 0xb7, 0x76, 0xc9, 0xcb, 0x44, 0x03, 0x49, 0xac,
+	0x98, 0xd3, 0x0a, 0x24, 0xb1, 0x42, 0x40, 0xac, 0x99, 0xdb, 0x0e, 0x88, 0x35, 0x7d, 0x0c, 0x2e,
+	0xce, 0x92, 0x19, 0xeb, 0xb6, 0x41, 0x8d, 0xea, 0x7f, 0x27, 0xb0, 0x8b, 0xe7, 0x3d, 0x15, 0xc5,
+	0x7c, 0x21, 0x33, 0xfa, 0xec, 0x96, 0x0f, 0xfa, 0xb7, 0x9e, 0xa0, 0x66, 0x82, 0xe9, 0xd5, 0x4a,
+	0x5e, 0x5b, 0x41, 0x89, 0xfa, 0x50, 0xff, 0x59, 0xcd, 0xbc, 0x69, 0xb5, 0x11, 0x58, 0xba, 0x8e,
+	0x3a, 0x60, 0x84, 0x67, 0x5e, 0x47, 0x9b, 0xe4, 0x7d, 0x78, 0xe6, 0x11, 0x9d, 0xe0, 0xa1, 0x67,
+	0x60, 0x82, 0x87, 0x9e, 0x79, 0xdc, 0xdf, 0xfc, 0x19, 0x74, 0x36, 0xdb, 0x01, 0xf9, 0xb9, 0x1d,
+	0x90, 0xdf, 0xdb, 0x01, 0xf9, 0xe8, 0xe6, 0x45, 0x9a, 0xc9, 0xd5, 0x6c, 0xe6, 0xa0, 0xd3, 0x5f,
+	0xfc, 0x1d, 0x00, 0x15, 0x2a, 0x7e, 0xed, 0x16, 0x03, 0x00, 0x00,
 }
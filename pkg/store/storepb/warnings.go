@@ -0,0 +1,78 @@
+package storepb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WarningCode is the machine readable reason of a warning, e.g. for dashboards to mark panels that may lack data.
+type WarningCode string
+
+const (
+	// WarningStoreError means a request to a store failed, its data is missing.
+	WarningStoreError WarningCode = "store_error"
+	// WarningStoreTimeout means a store did not respond in time, its data is missing.
+	WarningStoreTimeout WarningCode = "store_timeout"
+	// WarningStore is a warning reported by the store itself.
+	WarningStore WarningCode = "store_warning"
+	// WarningNoStoreMatched means no store matched the request.
+	WarningNoStoreMatched WarningCode = "no_store_matched"
+)
+
+const (
+	warningCodePrefix  = "code="
+	warningStorePrefix = "store="
+)
+
+// Warning is a warning of a partial response. It is passed on as string in the form
+// `code=<code> store=<address>: <message>`, the store being omitted for warnings not about a single store, so that
+// warnings keep their code and store through all layers of queriers up to the warnings of the query API.
+type Warning struct {
+	Code WarningCode
+	// Store is the address of the store the warning is about, empty if it is not about a single store.
+	Store string
+	Msg   string
+}
+
+// NewWarning returns the warning with the given code about the store with the given address caused by err.
+func NewWarning(code WarningCode, store string, err error) Warning {
+	return Warning{Code: code, Store: store, Msg: err.Error()}
+}
+
+func (w Warning) Error() string {
+	if w.Store == "" {
+		return fmt.Sprintf("%s%s: %s", warningCodePrefix, w.Code, w.Msg)
+	}
+	return fmt.Sprintf("%s%s %s%s: %s", warningCodePrefix, w.Code, warningStorePrefix, w.Store, w.Msg)
+}
+
+// ParseWarning parses a warning formatted by Warning.Error. It returns false if the given string is no such warning,
+// e.g. one of a store not supporting warning codes.
+func ParseWarning(s string) (Warning, bool) {
+	if !strings.HasPrefix(s, warningCodePrefix) {
+		return Warning{}, false
+	}
+	i := strings.Index(s, ": ")
+	if i < 0 {
+		return Warning{}, false
+	}
+	fields := strings.Fields(s[:i])
+	w := Warning{Code: WarningCode(strings.TrimPrefix(fields[0], warningCodePrefix)), Msg: s[i+2:]}
+	switch {
+	case len(fields) == 1:
+	case len(fields) == 2 && strings.HasPrefix(fields[1], warningStorePrefix):
+		w.Store = strings.TrimPrefix(fields[1], warningStorePrefix)
+	default:
+		return Warning{}, false
+	}
+	return w, w.Code != ""
+}
+
+// StoreWarning returns the given warning reported by the store with the given address. Warnings already carrying a
+// code, e.g. of queriers behind a querier, are kept as they are.
+func StoreWarning(store, warning string) Warning {
+	if w, ok := ParseWarning(warning); ok {
+		return w
+	}
+	return Warning{Code: WarningStore, Store: store, Msg: warning}
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultTenantHeader is the gRPC metadata key carrying the tenant of a request.
+const DefaultTenantHeader = "thanos-tenant"
+
+// TenantStore wraps a StoreServer and requires Series, LabelNames and LabelValues requests to be limited to a single
+// tenant with an equality matcher on the tenant label. Requests without such a matcher are rejected or, if injection is
+// enabled, get the matcher for the tenant given in the request's gRPC metadata. A tenant given in the metadata must
+// match the tenant matcher of the request.
+type TenantStore struct {
+	storepb.StoreServer
+
+	labelName string
+	header    string
+	inject    bool
+}
+
+// NewTenantStore creates a new TenantStore enforcing matchers on the labelName label.
+func NewTenantStore(s storepb.StoreServer, labelName, header string, inject bool) *TenantStore {
+	return &TenantStore{
+		StoreServer: s,
+		labelName:   labelName,
+		header:      header,
+		inject:      inject,
+	}
+}
+
+// enforce returns the matchers limited to the tenant of the request.
+func (s *TenantStore) enforce(ctx context.Context, ms []storepb.LabelMatcher) ([]storepb.LabelMatcher, error) {
+	var tenant string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vs := md.Get(s.header); len(vs) > 0 {
+			tenant = vs[0]
+		}
+	}
+
+	found := false
+	for _, m := range ms {
+		if m.Name != s.labelName || m.Type != storepb.LabelMatcher_EQ || m.Value == "" {
+			continue
+		}
+		if tenant != "" && m.Value != tenant {
+			return nil, status.Errorf(codes.PermissionDenied, "matcher %s=%q does not match tenant %q of the request", m.Name, m.Value, tenant)
+		}
+		found = true
+	}
+	if found {
+		return ms, nil
+	}
+
+	if !s.inject || tenant == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "request must be limited to a single tenant with a %s equality matcher", s.labelName)
+	}
+	res := make([]storepb.LabelMatcher, 0, len(ms)+1)
+	res = append(res, ms...)
+	return append(res, storepb.LabelMatcher{Type: storepb.LabelMatcher_EQ, Name: s.labelName, Value: tenant}), nil
+}
+
+// Series implements the storepb.StoreServer interface.
+func (s *TenantStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	ms, err := s.enforce(srv.Context(), r.Matchers)
+	if err != nil {
+		return err
+	}
+	req := *r
+	req.Matchers = ms
+	return s.StoreServer.Series(&req, srv)
+}
+
+// LabelNames implements the storepb.StoreServer interface.
+func (s *TenantStore) LabelNames(ctx context.Context, r *storepb.LabelNamesRequest) (*storepb.LabelNamesResponse, error) {
+	ms, err := s.enforce(ctx, r.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	req := *r
+	req.Matchers = ms
+	return s.StoreServer.LabelNames(ctx, &req)
+}
+
+// LabelValues implements the storepb.StoreServer interface.
+func (s *TenantStore) LabelValues(ctx context.Context, r *storepb.LabelValuesRequest) (*storepb.LabelValuesResponse, error) {
+	ms, err := s.enforce(ctx, r.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	req := *r
+	req.Matchers = ms
+	return s.StoreServer.LabelValues(ctx, &req)
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// matchersRecordingStore records the matchers of the last call.
+type matchersRecordingStore struct {
+	storepb.StoreServer
+
+	matchers []storepb.LabelMatcher
+}
+
+func (s *matchersRecordingStore) Series(r *storepb.SeriesRequest, _ storepb.Store_SeriesServer) error {
+	s.matchers = r.Matchers
+	return nil
+}
+
+func (s *matchersRecordingStore) LabelNames(_ context.Context, r *storepb.LabelNamesRequest) (*storepb.LabelNamesResponse, error) {
+	s.matchers = r.Matchers
+	return &storepb.LabelNamesResponse{}, nil
+}
+
+func (s *matchersRecordingStore) LabelValues(_ context.Context, r *storepb.LabelValuesRequest) (*storepb.LabelValuesResponse, error) {
+	s.matchers = r.Matchers
+	return &storepb.LabelValuesResponse{}, nil
+}
+
+func TestTenantStore(t *testing.T) {
+	tenantA := storepb.LabelMatcher{Type: storepb.LabelMatcher_EQ, Name: "tenant", Value: "a"}
+	other := storepb.LabelMatcher{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "x"}
+
+	for _, tcase := range []struct {
+		name     string
+		inject   bool
+		tenant   string
+		matchers []storepb.LabelMatcher
+
+		expected []storepb.LabelMatcher
+		code     codes.Code
+	}{
+		{name: "tenant matcher", matchers: []storepb.LabelMatcher{other, tenantA}, expected: []storepb.LabelMatcher{other, tenantA}},
+		{name: "tenant matcher of the request tenant", tenant: "a", matchers: []storepb.LabelMatcher{tenantA}, expected: []storepb.LabelMatcher{tenantA}},
+		{name: "tenant matcher of another tenant", inject: true, tenant: "b", matchers: []storepb.LabelMatcher{tenantA}, code: codes.PermissionDenied},
+		{name: "no tenant matcher", tenant: "a", matchers: []storepb.LabelMatcher{other}, code: codes.InvalidArgument},
+		{
+			name:     "regexp tenant matcher",
+			matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_RE, Name: "tenant", Value: "a|b"}},
+			code:     codes.InvalidArgument,
+		},
+		{name: "injected tenant matcher", inject: true, tenant: "a", matchers: []storepb.LabelMatcher{other}, expected: []storepb.LabelMatcher{other, tenantA}},
+		{name: "no tenant to inject", inject: true, matchers: []storepb.LabelMatcher{other}, code: codes.InvalidArgument},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			rec := &matchersRecordingStore{}
+			s := NewTenantStore(rec, "tenant", DefaultTenantHeader, tcase.inject)
+
+			ctx := context.Background()
+			if tcase.tenant != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(DefaultTenantHeader, tcase.tenant))
+			}
+
+			check := func(err error) {
+				if tcase.code != codes.OK {
+					testutil.NotOk(t, err)
+					testutil.Equals(t, tcase.code, status.Code(err))
+					testutil.Assert(t, rec.matchers == nil, "request passed without tenant enforcement")
+					return
+				}
+				testutil.Ok(t, err)
+				testutil.Equals(t, tcase.expected, rec.matchers)
+				rec.matchers = nil
+			}
+
+			check(s.Series(&storepb.SeriesRequest{Matchers: tcase.matchers}, newStoreSeriesServer(ctx)))
+			_, err := s.LabelNames(ctx, &storepb.LabelNamesRequest{Matchers: tcase.matchers})
+			check(err)
+			_, err = s.LabelValues(ctx, &storepb.LabelValuesRequest{Label: "job", Matchers: tcase.matchers})
+			check(err)
+		})
+	}
+}
@@ -20,7 +20,8 @@ import (
 
 // TSDBStore implements the store API against a local TSDB instance.
 // It attaches the provided external labels to all results. It only responds with raw data
-// and does not support downsampling.
+// and does not support downsampling. Raw data is the finest resolution, so it is a valid answer for any
+// requested max resolution window.
 type TSDBStore struct {
 	logger    log.Logger
 	db        *tsdb.DB
@@ -41,17 +42,16 @@ func NewTSDBStore(logger log.Logger, reg prometheus.Registerer, db *tsdb.DB, com
 	}
 }
 
-// Info returns store information about the Prometheus instance.
+// Info returns store information about the TSDB instance. The advertised time range starts at the oldest
+// sample of the blocks and the head, so data not yet compacted or shipped is included. It is open ended
+// as new samples are appended all the time.
 func (s *TSDBStore) Info(ctx context.Context, r *storepb.InfoRequest) (*storepb.InfoResponse, error) {
 	res := &storepb.InfoResponse{
 		Labels:    make([]storepb.Label, 0, len(s.labels)),
 		StoreType: s.component.ToProto(),
-		MinTime:   0,
+		MinTime:   s.minTime(),
 		MaxTime:   math.MaxInt64,
 	}
-	if blocks := s.db.Blocks(); len(blocks) > 0 {
-		res.MinTime = blocks[0].Meta().MinTime
-	}
 	for _, l := range s.labels {
 		res.Labels = append(res.Labels, storepb.Label{
 			Name:  l.Name,
@@ -61,6 +61,19 @@ func (s *TSDBStore) Info(ctx context.Context, r *storepb.InfoRequest) (*storepb.
 	return res, nil
 }
 
+// minTime returns the timestamp of the oldest sample in the TSDB. An empty TSDB advertises the whole time range
+// as the first samples can be appended at any time and queriers only refresh the advertised range periodically.
+func (s *TSDBStore) minTime() int64 {
+	mint := s.db.Head().MinTime()
+	if blocks := s.db.Blocks(); len(blocks) > 0 && blocks[0].Meta().MinTime < mint {
+		mint = blocks[0].Meta().MinTime
+	}
+	if mint == math.MaxInt64 {
+		return 0
+	}
+	return mint
+}
+
 // Series returns all series for a requested time range and label matcher. The returned data may
 // exceed the requested time bounds.
 func (s *TSDBStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
@@ -99,12 +112,24 @@ func (s *TSDBStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSer
 		// NOTE: XOR encoding supports a max size of 2^16 - 1 samples, so we need
 		// to chunk all samples into groups of no more than 2^16 - 1
 		// See: https://github.com/improbable-eng/thanos/pull/1038
+		respSeries.Labels = s.translateAndExtendLabels(series.Labels(), s.labels)
+		if !r.ShardInfo.Matches(respSeries.Labels) {
+			continue
+		}
+
+		if r.SkipChunks {
+			respSeries.Chunks = respSeries.Chunks[:0]
+			if err := srv.Send(storepb.NewSeriesResponse(&respSeries)); err != nil {
+				return status.Error(codes.Aborted, err.Error())
+			}
+			continue
+		}
+
 		c, err := s.encodeChunks(series.Iterator(), math.MaxUint16)
 		if err != nil {
 			return status.Errorf(codes.Internal, "encode chunk: %s", err)
 		}
 
-		respSeries.Labels = s.translateAndExtendLabels(series.Labels(), s.labels)
 		respSeries.Chunks = append(respSeries.Chunks[:0], c...)
 
 		if err := srv.Send(storepb.NewSeriesResponse(&respSeries)); err != nil {
@@ -182,36 +207,108 @@ func (s *TSDBStore) translateAndExtendLabels(m, extend labels.Labels) []storepb.
 	return lset
 }
 
-// LabelNames returns all known label names.
-func (s *TSDBStore) LabelNames(ctx context.Context, _ *storepb.LabelNamesRequest) (
+// LabelNames returns all known label names. If matchers are given, only the label names of the matching series
+// within the requested time range are returned.
+func (s *TSDBStore) LabelNames(ctx context.Context, r *storepb.LabelNamesRequest) (
 	*storepb.LabelNamesResponse, error,
 ) {
-	q, err := s.db.Querier(math.MinInt64, math.MaxInt64)
+	q, err := s.db.Querier(labelRequestTimeRange(r.MinTime, r.MaxTime))
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 	defer runutil.CloseWithLogOnErr(s.logger, q, "close tsdb querier label names")
 
-	res, err := q.LabelNames()
+	if len(r.Matchers) == 0 {
+		res, err := q.LabelNames()
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return &storepb.LabelNamesResponse{Names: res}, nil
+	}
+
+	res, err := s.matchingLabelValues(q, r.Matchers, func(l labels.Label) string { return l.Name })
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
 	return &storepb.LabelNamesResponse{Names: res}, nil
 }
 
-// LabelValues returns all known label values for a given label name.
+// LabelValues returns all known label values for a given label name. If matchers are given, only the label values
+// of the matching series within the requested time range are returned.
 func (s *TSDBStore) LabelValues(ctx context.Context, r *storepb.LabelValuesRequest) (
 	*storepb.LabelValuesResponse, error,
 ) {
-	q, err := s.db.Querier(math.MinInt64, math.MaxInt64)
+	q, err := s.db.Querier(labelRequestTimeRange(r.MinTime, r.MaxTime))
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 	defer runutil.CloseWithLogOnErr(s.logger, q, "close tsdb querier label values")
 
-	res, err := q.LabelValues(r.Label)
+	if len(r.Matchers) == 0 {
+		res, err := q.LabelValues(r.Label)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return &storepb.LabelValuesResponse{Values: res}, nil
+	}
+
+	res, err := s.matchingLabelValues(q, r.Matchers, func(l labels.Label) string {
+		if l.Name != r.Label {
+			return ""
+		}
+		return l.Value
+	})
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
 	return &storepb.LabelValuesResponse{Values: res}, nil
 }
+
+// matchingLabelValues returns the sorted unique non-empty values the given function returns for the labels of all
+// series matching the given matchers.
+func (s *TSDBStore) matchingLabelValues(q tsdb.Querier, ms []storepb.LabelMatcher, value func(labels.Label) string) ([]string, error) {
+	match, newMatchers, err := labelsMatches(s.labels, ms)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !match {
+		return []string{}, nil
+	}
+	matchers, err := translateMatchers(newMatchers)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	set, err := q.Select(matchers...)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	values := map[string]struct{}{}
+	for set.Next() {
+		for _, l := range set.At().Labels() {
+			if v := value(l); v != "" {
+				values[v] = struct{}{}
+			}
+		}
+	}
+	if err := set.Err(); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	res := make([]string, 0, len(values))
+	for v := range values {
+		res = append(res, v)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// labelRequestTimeRange returns the time range of label requests, where both mint and maxt of 0 stand for the whole
+// time range.
+func labelRequestTimeRange(mint, maxt int64) (int64, int64) {
+	if mint == 0 && maxt == 0 {
+		return math.MinInt64, math.MaxInt64
+	}
+	return mint, maxt
+}
@@ -32,6 +32,58 @@ func TestTSDBStore_Info(t *testing.T) {
 	testutil.Equals(t, storepb.StoreType_RULE, resp.StoreType)
 	testutil.Equals(t, int64(0), resp.MinTime)
 	testutil.Equals(t, int64(math.MaxInt64), resp.MaxTime)
+
+	// Samples of the head, which are not compacted into blocks yet, are advertised.
+	a := db.Appender()
+	_, err = a.Add(labels.FromStrings("__name__", "up"), 1000, 1)
+	testutil.Ok(t, err)
+	testutil.Ok(t, a.Commit())
+
+	resp, err = tsdbStore.Info(ctx, &storepb.InfoRequest{})
+	testutil.Ok(t, err)
+	testutil.Equals(t, int64(1000), resp.MinTime)
+	testutil.Equals(t, int64(math.MaxInt64), resp.MaxTime)
+}
+
+func TestTSDBStore_Series(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	db, err := testutil.NewTSDB()
+	defer func() { testutil.Ok(t, db.Close()) }()
+	testutil.Ok(t, err)
+
+	a := db.Appender()
+	_, err = a.Add(labels.FromStrings("__name__", "job:up:sum", "job", "a"), 1000, 1)
+	testutil.Ok(t, err)
+	testutil.Ok(t, a.Commit())
+
+	tsdbStore := NewTSDBStore(nil, nil, db, component.Rule, labels.FromStrings("region", "eu-west"))
+	expLset := []storepb.Label{{Name: "__name__", Value: "job:up:sum"}, {Name: "job", Value: "a"}, {Name: "region", Value: "eu-west"}}
+
+	// Raw data is returned for any max resolution window.
+	srv := newStoreSeriesServer(context.Background())
+	testutil.Ok(t, tsdbStore.Series(&storepb.SeriesRequest{
+		MinTime:             0,
+		MaxTime:             2000,
+		Matchers:            []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "a"}},
+		MaxResolutionWindow: 3600 * 1000,
+		Aggregates:          []storepb.Aggr{storepb.Aggr_COUNT, storepb.Aggr_SUM},
+	}, srv))
+	testutil.Equals(t, 1, len(srv.SeriesSet))
+	testutil.Equals(t, expLset, srv.SeriesSet[0].Labels)
+	testutil.Equals(t, 1, len(srv.SeriesSet[0].Chunks))
+	testutil.Assert(t, srv.SeriesSet[0].Chunks[0].Raw != nil, "expected raw chunk")
+
+	srv = newStoreSeriesServer(context.Background())
+	testutil.Ok(t, tsdbStore.Series(&storepb.SeriesRequest{
+		MinTime:    0,
+		MaxTime:    2000,
+		Matchers:   []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "a"}},
+		SkipChunks: true,
+	}, srv))
+	testutil.Equals(t, 1, len(srv.SeriesSet))
+	testutil.Equals(t, expLset, srv.SeriesSet[0].Labels)
+	testutil.Equals(t, 0, len(srv.SeriesSet[0].Chunks))
 }
 
 // Regression test for https://github.com/improbable-eng/thanos/issues/1038.
@@ -48,3 +100,63 @@ func TestTSDBStore_Series_SplitSamplesIntoChunksWithMaxSizeOfUint16_e2e(t *testi
 		return tsdbStore
 	})
 }
+
+func TestTSDBStore_LabelNamesAndValues_Matchers(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 10*time.Second)()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := testutil.NewTSDB()
+	defer func() { testutil.Ok(t, db.Close()) }()
+	testutil.Ok(t, err)
+
+	a := db.Appender()
+	_, err = a.Add(labels.FromStrings("__name__", "up", "job", "a", "instance", "1"), 100, 1)
+	testutil.Ok(t, err)
+	_, err = a.Add(labels.FromStrings("__name__", "up", "job", "b"), 100, 1)
+	testutil.Ok(t, err)
+	_, err = a.Add(labels.FromStrings("__name__", "down", "job", "c", "zone", "z"), 100, 1)
+	testutil.Ok(t, err)
+	testutil.Ok(t, a.Commit())
+
+	tsdbStore := NewTSDBStore(nil, nil, db, component.Rule, labels.FromStrings("region", "eu-west"))
+
+	names, err := tsdbStore.LabelNames(ctx, &storepb.LabelNamesRequest{})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"__name__", "instance", "job", "zone"}, names.Names)
+
+	names, err = tsdbStore.LabelNames(ctx, &storepb.LabelNamesRequest{
+		Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "__name__", Value: "up"}},
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"__name__", "instance", "job"}, names.Names)
+
+	values, err := tsdbStore.LabelValues(ctx, &storepb.LabelValuesRequest{
+		Label:    "job",
+		Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "__name__", Value: "up"}},
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"a", "b"}, values.Values)
+
+	// Matchers of external labels select all or no series of the store.
+	values, err = tsdbStore.LabelValues(ctx, &storepb.LabelValuesRequest{
+		Label: "job",
+		Matchers: []storepb.LabelMatcher{
+			{Type: storepb.LabelMatcher_EQ, Name: "__name__", Value: "up"},
+			{Type: storepb.LabelMatcher_EQ, Name: "region", Value: "us-east"},
+		},
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{}, values.Values)
+
+	// No series outside of the requested time range.
+	values, err = tsdbStore.LabelValues(ctx, &storepb.LabelValuesRequest{
+		Label:    "job",
+		MinTime:  200,
+		MaxTime:  300,
+		Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "__name__", Value: "up"}},
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{}, values.Values)
+}
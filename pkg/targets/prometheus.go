@@ -0,0 +1,73 @@
+package targets
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/promclient"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/targets/targetspb"
+	"github.com/prometheus/tsdb/labels"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Prometheus implements targetspb.TargetsServer on top of the targets API of Prometheus.
+type Prometheus struct {
+	logger         log.Logger
+	base           *url.URL
+	externalLabels func() labels.Labels
+}
+
+// NewPrometheus creates a new targets server querying the Prometheus at the given URL. The external labels are
+// attached to the labels of all targets, so that the Prometheus scraping them can be told apart.
+func NewPrometheus(logger log.Logger, base *url.URL, externalLabels func() labels.Labels) *Prometheus {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return &Prometheus{
+		logger:         logger,
+		base:           base,
+		externalLabels: externalLabels,
+	}
+}
+
+// Targets returns the targets of Prometheus in the requested state.
+func (p *Prometheus) Targets(r *targetspb.TargetsRequest, s targetspb.Targets_TargetsServer) error {
+	var state string
+	if r.State != targetspb.TargetsRequest_ANY {
+		state = strings.ToLower(r.State.String())
+	}
+
+	td, err := promclient.Targets(s.Context(), p.logger, p.base, state)
+	if err != nil {
+		return status.Error(codes.Unknown, err.Error())
+	}
+
+	// Older Prometheus versions ignore the state parameter.
+	switch r.State {
+	case targetspb.TargetsRequest_ACTIVE:
+		td.DroppedTargets = nil
+	case targetspb.TargetsRequest_DROPPED:
+		td.ActiveTargets = nil
+	}
+
+	ext := p.externalLabels()
+	for _, t := range td.ActiveTargets {
+		t.Labels = extendLabels(t.Labels, ext)
+	}
+	for _, t := range td.DroppedTargets {
+		t.DiscoveredLabels = extendLabels(t.DiscoveredLabels, ext)
+	}
+	return s.Send(targetspb.NewTargetsResponse(td))
+}
+
+// extendLabels returns the given labels with the external labels attached, overwriting existing ones on collision.
+func extendLabels(lset []storepb.Label, ext labels.Labels) []storepb.Label {
+	m := storepb.LabelsToMap(lset)
+	for _, l := range ext {
+		m[l.Name] = l.Value
+	}
+	return storepb.LabelsFromMap(m)
+}
@@ -0,0 +1,54 @@
+package targets
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/improbable-eng/thanos/pkg/federation"
+	"github.com/improbable-eng/thanos/pkg/targets/targetspb"
+)
+
+// Client is a targets API client of a single endpoint.
+type Client struct {
+	targetspb.TargetsClient
+
+	// Addr is used for errors and warnings.
+	Addr string
+}
+
+// Proxy implements targetspb.TargetsServer by fanning requests out to the targets APIs of all given endpoints.
+type Proxy struct {
+	logger  log.Logger
+	clients func() []Client
+}
+
+// NewProxy returns a new targets proxy over the clients returned by the given function.
+func NewProxy(logger log.Logger, clients func() []Client) *Proxy {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return &Proxy{
+		logger:  logger,
+		clients: clients,
+	}
+}
+
+// Targets returns the targets of all endpoints. Endpoints not implementing the targets API are skipped.
+func (p *Proxy) Targets(r *targetspb.TargetsRequest, s targetspb.Targets_TargetsServer) error {
+	clients := p.clients()
+	streams := make([]federation.Stream, 0, len(clients))
+	for _, c := range clients {
+		c := c
+		streams = append(streams, federation.Stream{Addr: c.Addr, Open: func(ctx context.Context) (func() (interface{}, error), error) {
+			stream, err := c.Targets(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+			return func() (interface{}, error) { return stream.Recv() }, nil
+		}})
+	}
+	return federation.FanOut(s.Context(), p.logger, "targets", r.PartialResponseStrategy, streams,
+		func(resp interface{}) error { return s.Send(resp.(*targetspb.TargetsResponse)) },
+		func(err error) interface{} { return targetspb.NewWarningTargetsResponse(err) },
+	)
+}
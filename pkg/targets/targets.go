@@ -0,0 +1,118 @@
+// Package targets implements the federation of the targets API of Prometheus.
+package targets
+
+import (
+	"context"
+	"sort"
+
+	"github.com/improbable-eng/thanos/pkg/federation"
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/targets/targetspb"
+	"github.com/pkg/errors"
+)
+
+// GRPCClient queries a targets server, e.g. the Proxy, within the same process and deduplicates the targets of
+// replicas.
+type GRPCClient struct {
+	proxy         targetspb.TargetsServer
+	replicaLabels federation.ReplicaLabels
+}
+
+// NewGRPCClient returns a new GRPCClient treating targets that only differ in the given replica labels as the same.
+func NewGRPCClient(ts targetspb.TargetsServer, replicaLabels []string) *GRPCClient {
+	return &GRPCClient{proxy: ts, replicaLabels: federation.NewReplicaLabels(replicaLabels)}
+}
+
+type targetsServer struct {
+	// This field just exist to pseudo-implement the unused methods of the interface.
+	targetspb.Targets_TargetsServer
+	ctx context.Context
+
+	targets  *targetspb.TargetDiscovery
+	warnings []error
+}
+
+func (s *targetsServer) Send(r *targetspb.TargetsResponse) error {
+	if w := r.GetWarning(); w != "" {
+		s.warnings = append(s.warnings, errors.New(w))
+		return nil
+	}
+	t := r.GetTargets()
+	if t == nil {
+		return errors.New("no targets or warning")
+	}
+	s.targets.ActiveTargets = append(s.targets.ActiveTargets, t.ActiveTargets...)
+	s.targets.DroppedTargets = append(s.targets.DroppedTargets, t.DroppedTargets...)
+	return nil
+}
+
+func (s *targetsServer) Context() context.Context {
+	return s.ctx
+}
+
+// Targets returns the targets of all endpoints. If dedup is true, the replica labels are removed from the target
+// labels and the same target scraped by replicas is returned once, with the state of its most recent scrape.
+func (c *GRPCClient) Targets(ctx context.Context, r *targetspb.TargetsRequest, dedup bool) (*targetspb.TargetDiscovery, []error, error) {
+	srv := &targetsServer{ctx: ctx, targets: &targetspb.TargetDiscovery{}}
+	if err := c.proxy.Targets(r, srv); err != nil {
+		return nil, nil, errors.Wrap(err, "proxy Targets()")
+	}
+
+	replicaLabels := c.replicaLabels
+	if !dedup {
+		replicaLabels = nil
+	}
+	return &targetspb.TargetDiscovery{
+		ActiveTargets:  dedupActiveTargets(srv.targets.ActiveTargets, replicaLabels),
+		DroppedTargets: dedupDroppedTargets(srv.targets.DroppedTargets, replicaLabels),
+	}, srv.warnings, nil
+}
+
+// dedupActiveTargets removes the replica labels from the target labels and keeps the most recently scraped of
+// equal targets. The result is sorted by scrape pool and labels.
+func dedupActiveTargets(targets []*targetspb.ActiveTarget, replicaLabels federation.ReplicaLabels) []*targetspb.ActiveTarget {
+	for _, t := range targets {
+		t.Labels = replicaLabels.Remove(t.Labels)
+	}
+	sort.SliceStable(targets, func(i, j int) bool {
+		if targets[i].ScrapePool != targets[j].ScrapePool {
+			return targets[i].ScrapePool < targets[j].ScrapePool
+		}
+		if c := storepb.CompareLabels(targets[i].Labels, targets[j].Labels); c != 0 {
+			return c < 0
+		}
+		return targets[i].LastScrape > targets[j].LastScrape
+	})
+
+	res := make([]*targetspb.ActiveTarget, 0, len(targets))
+	for _, t := range targets {
+		if len(res) > 0 {
+			last := res[len(res)-1]
+			if last.ScrapePool == t.ScrapePool && storepb.CompareLabels(last.Labels, t.Labels) == 0 {
+				continue
+			}
+		}
+		res = append(res, t)
+	}
+	return res
+}
+
+// dedupDroppedTargets removes the replica labels from the discovered labels and keeps equal targets once. The
+// result is sorted by discovered labels.
+func dedupDroppedTargets(targets []*targetspb.DroppedTarget, replicaLabels federation.ReplicaLabels) []*targetspb.DroppedTarget {
+	for _, t := range targets {
+		t.DiscoveredLabels = replicaLabels.Remove(t.DiscoveredLabels)
+	}
+	sort.SliceStable(targets, func(i, j int) bool {
+		return storepb.CompareLabels(targets[i].DiscoveredLabels, targets[j].DiscoveredLabels) < 0
+	})
+
+	res := make([]*targetspb.DroppedTarget, 0, len(targets))
+	for _, t := range targets {
+		if len(res) > 0 && storepb.CompareLabels(res[len(res)-1].DiscoveredLabels, t.DiscoveredLabels) == 0 {
+			continue
+		}
+		res = append(res, t)
+	}
+	return res
+}
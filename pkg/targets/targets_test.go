@@ -0,0 +1,105 @@
+package targets
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/improbable-eng/thanos/pkg/targets/targetspb"
+	"github.com/improbable-eng/thanos/pkg/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func lbls(kv ...string) []storepb.Label {
+	var lset []storepb.Label
+	for i := 0; i < len(kv); i += 2 {
+		lset = append(lset, storepb.Label{Name: kv[i], Value: kv[i+1]})
+	}
+	return lset
+}
+
+type testTargetsClient struct {
+	resps []*targetspb.TargetsResponse
+	err   error
+}
+
+func (c *testTargetsClient) Targets(ctx context.Context, r *targetspb.TargetsRequest, _ ...grpc.CallOption) (targetspb.Targets_TargetsClient, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &testTargetsStream{resps: c.resps}, nil
+}
+
+type testTargetsStream struct {
+	grpc.ClientStream
+	resps []*targetspb.TargetsResponse
+}
+
+func (s *testTargetsStream) Recv() (*targetspb.TargetsResponse, error) {
+	if len(s.resps) == 0 {
+		return nil, io.EOF
+	}
+	r := s.resps[0]
+	s.resps = s.resps[1:]
+	return r, nil
+}
+
+func TestGRPCClient_Targets(t *testing.T) {
+	replica := func(replica string, lastScrape int64, health string) []*targetspb.TargetsResponse {
+		return []*targetspb.TargetsResponse{
+			targetspb.NewTargetsResponse(&targetspb.TargetDiscovery{
+				ActiveTargets: []*targetspb.ActiveTarget{{
+					Labels:     lbls("instance", "a:9090", "job", "node", "prometheus", "eu", "replica", replica),
+					ScrapePool: "node",
+					LastScrape: lastScrape,
+					Health:     health,
+				}},
+				DroppedTargets: []*targetspb.DroppedTarget{{
+					DiscoveredLabels: lbls("__address__", "b:9090", "prometheus", "eu", "replica", replica),
+				}},
+			}),
+		}
+	}
+	proxy := NewProxy(nil, func() []Client {
+		return []Client{
+			{TargetsClient: &testTargetsClient{resps: replica("1", 1000, "down")}, Addr: "1"},
+			{TargetsClient: &testTargetsClient{resps: replica("2", 2000, "up")}, Addr: "2"},
+			// Stores without targets API are skipped silently.
+			{TargetsClient: &testTargetsClient{err: status.Error(codes.Unimplemented, "unknown service")}, Addr: "3"},
+		}
+	})
+	c := NewGRPCClient(proxy, []string{"replica"})
+
+	res, warnings, err := c.Targets(context.Background(), &targetspb.TargetsRequest{}, true)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(warnings))
+	testutil.Equals(t, 1, len(res.ActiveTargets))
+	testutil.Equals(t, lbls("instance", "a:9090", "job", "node", "prometheus", "eu"), res.ActiveTargets[0].Labels)
+	// The most recent scrape wins.
+	testutil.Equals(t, "up", res.ActiveTargets[0].Health)
+	testutil.Equals(t, []*targetspb.DroppedTarget{{DiscoveredLabels: lbls("__address__", "b:9090", "prometheus", "eu")}}, res.DroppedTargets)
+
+	res, _, err = c.Targets(context.Background(), &targetspb.TargetsRequest{}, false)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(res.ActiveTargets))
+	testutil.Equals(t, 2, len(res.DroppedTargets))
+}
+
+func TestTargetDiscovery_JSON(t *testing.T) {
+	b := []byte(`{"activeTargets":[{"discoveredLabels":{"__address__":"a:9090"},"labels":{"instance":"a:9090","job":"node"},"scrapePool":"node","scrapeUrl":"http://a:9090/metrics","globalUrl":"http://a:9090/metrics","lastError":"","lastScrape":"2020-09-14T15:22:25.479Z","lastScrapeDuration":0.05,"health":"up"}],"droppedTargets":[{"discoveredLabels":{"__address__":"b:9090"}}]}`)
+
+	var td targetspb.TargetDiscovery
+	testutil.Ok(t, json.Unmarshal(b, &td))
+	testutil.Equals(t, 1, len(td.ActiveTargets))
+	testutil.Equals(t, lbls("instance", "a:9090", "job", "node"), td.ActiveTargets[0].Labels)
+	testutil.Equals(t, int64(1600096945479), td.ActiveTargets[0].LastScrape)
+	testutil.Equals(t, lbls("__address__", "b:9090"), td.DroppedTargets[0].DiscoveredLabels)
+
+	out, err := json.Marshal(&td)
+	testutil.Ok(t, err)
+	testutil.Equals(t, string(b), string(out))
+}
@@ -0,0 +1,120 @@
+package targetspb
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/improbable-eng/thanos/pkg/store/storepb"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+)
+
+func NewWarningTargetsResponse(err error) *TargetsResponse {
+	return &TargetsResponse{
+		Result: &TargetsResponse_Warning{
+			Warning: err.Error(),
+		},
+	}
+}
+
+func NewTargetsResponse(targets *TargetDiscovery) *TargetsResponse {
+	return &TargetsResponse{
+		Result: &TargetsResponse_Targets{
+			Targets: targets,
+		},
+	}
+}
+
+// targetDiscoveryJSON is the JSON format of the Prometheus targets API.
+type targetDiscoveryJSON struct {
+	ActiveTargets  []*ActiveTarget  `json:"activeTargets"`
+	DroppedTargets []*DroppedTarget `json:"droppedTargets"`
+}
+
+// MarshalJSON marshals the targets the way the Prometheus targets API does.
+func (m *TargetDiscovery) MarshalJSON() ([]byte, error) {
+	d := targetDiscoveryJSON{ActiveTargets: m.ActiveTargets, DroppedTargets: m.DroppedTargets}
+	if d.ActiveTargets == nil {
+		d.ActiveTargets = []*ActiveTarget{}
+	}
+	if d.DroppedTargets == nil {
+		d.DroppedTargets = []*DroppedTarget{}
+	}
+	return json.Marshal(d)
+}
+
+// UnmarshalJSON unmarshals targets of the Prometheus targets API.
+func (m *TargetDiscovery) UnmarshalJSON(b []byte) error {
+	var d targetDiscoveryJSON
+	if err := json.Unmarshal(b, &d); err != nil {
+		return err
+	}
+	m.ActiveTargets = d.ActiveTargets
+	m.DroppedTargets = d.DroppedTargets
+	return nil
+}
+
+type activeTargetJSON struct {
+	DiscoveredLabels   map[string]string `json:"discoveredLabels"`
+	Labels             map[string]string `json:"labels"`
+	ScrapePool         string            `json:"scrapePool"`
+	ScrapeURL          string            `json:"scrapeUrl"`
+	GlobalURL          string            `json:"globalUrl"`
+	LastError          string            `json:"lastError"`
+	LastScrape         time.Time         `json:"lastScrape"`
+	LastScrapeDuration float64           `json:"lastScrapeDuration"`
+	Health             string            `json:"health"`
+}
+
+// MarshalJSON marshals the target the way the Prometheus targets API does.
+func (m *ActiveTarget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(activeTargetJSON{
+		DiscoveredLabels:   storepb.LabelsToMap(m.DiscoveredLabels),
+		Labels:             storepb.LabelsToMap(m.Labels),
+		ScrapePool:         m.ScrapePool,
+		ScrapeURL:          m.ScrapeUrl,
+		GlobalURL:          m.GlobalUrl,
+		LastError:          m.LastError,
+		LastScrape:         timestamp.Time(m.LastScrape).UTC(),
+		LastScrapeDuration: m.LastScrapeDuration,
+		Health:             m.Health,
+	})
+}
+
+// UnmarshalJSON unmarshals a target of the Prometheus targets API.
+func (m *ActiveTarget) UnmarshalJSON(b []byte) error {
+	var t activeTargetJSON
+	if err := json.Unmarshal(b, &t); err != nil {
+		return err
+	}
+	*m = ActiveTarget{
+		DiscoveredLabels:   storepb.LabelsFromMap(t.DiscoveredLabels),
+		Labels:             storepb.LabelsFromMap(t.Labels),
+		ScrapePool:         t.ScrapePool,
+		ScrapeUrl:          t.ScrapeURL,
+		GlobalUrl:          t.GlobalURL,
+		LastError:          t.LastError,
+		LastScrape:         timestamp.FromTime(t.LastScrape),
+		LastScrapeDuration: t.LastScrapeDuration,
+		Health:             t.Health,
+	}
+	return nil
+}
+
+type droppedTargetJSON struct {
+	DiscoveredLabels map[string]string `json:"discoveredLabels"`
+}
+
+// MarshalJSON marshals the dropped target the way the Prometheus targets API does.
+func (m *DroppedTarget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(droppedTargetJSON{DiscoveredLabels: storepb.LabelsToMap(m.DiscoveredLabels)})
+}
+
+// UnmarshalJSON unmarshals a dropped target of the Prometheus targets API.
+func (m *DroppedTarget) UnmarshalJSON(b []byte) error {
+	var t droppedTargetJSON
+	if err := json.Unmarshal(b, &t); err != nil {
+		return err
+	}
+	m.DiscoveredLabels = storepb.LabelsFromMap(t.DiscoveredLabels)
+	return nil
+}
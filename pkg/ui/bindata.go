@@ -163,7 +163,7 @@ func pkgUiTemplates_baseHtml() (*asset, error) {
 	return a, nil
 }
 
-var _pkgUiTemplatesAlertsHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xac\x56\xcd\x8e\xdb\x36\x10\xbe\xef\x53\x0c\x94\x1c\x5a\xa0\xb2\xb0\xd8\xe4\x50\x9b\x56\xb1\xc8\xa5\x87\x24\x28\xb2\xe9\x5e\x17\x14\x39\xb6\x18\x73\x49\x81\xa4\xbd\x76\x59\xbd\x7b\x41\x52\xf2\xca\xb2\xd4\xa4\x40\x61\x40\x20\x87\xc3\x6f\xbe\xf9\xe1\x8c\xbd\xe7\xb8\x11\x0a\x21\xab\x91\xf2\xac\x6d\x6f\x00\x88\x14\x6a\x07\xee\xd4\xe0\x3a\x73\x78\x74\x05\xb3\x36\x03\x83\x72\x9d\x59\x77\x92\x68\x6b\x44\x97\x41\x6d\x70\xb3\xce\xbc\x87\x86\xba\xfa\x0f\x83\x1b\x71\x84\xb6\x2d\xac\xa3\x4e\xb0\x70\xa7\xa0\x12\x8d\xb3\x0b\x66\xed\x6f\x87\xb5\xf7\x50\xed\x85\xe4\x8f\x68\xac\xd0\x0a\xda\x36\x2b\x83\x31\xcb\x8c\x68\x1c\x58\xc3\xe6\xc1\xbe\x9d\xb1\xbe\xcd\x41\x91\x22\x01\x95\x37\xde\xa3\xe2\x6d\x7b\x73\xf3\xea\x1b\xd3\xca\xa1\x72\xc1\x3d\xc2\xc5\x01\x98\xa4\xd6\xae\xa3\x98\x0a\x85\x26\xdf\xc8\xbd\xe0\x89\x4f\x7d\x5b\xde\x47\x5b\xa4\xa8\x6f\xa3\x64\x70\xc3\xd6\xfa\x25\xa7\x4a\xe9\xc0\x4b\x2b\x1b\xaf\x00\x00\x11\xbd\xc6\x56\x9e\x9a\x5a\x30\xad\xe0\xbc\xca\xf7\x8a\xd5\xc8\x76\xc8\x03\x4d\xd1\x5d\x01\x20\xd5\xde\x39\xad\xba\x48\xa7\x4d\x36\x6b\x09\x9c\x70\x12\xd3\x01\x5c\x50\x78\x18\x49\x48\x91\xb0\x22\xf9\x82\x8b\x43\x5c\x38\x5a\x49\xec\xd1\xd3\x26\x7e\xf3\x4a\x1b\x8e\x06\x79\xb7\x65\x5a\x4a\xda\x58\xe4\x9d\x6f\xc4\x55\x9a\x9f\xd2\xda\xfb\xb7\x31\x0f\x0f\x8e\x3a\xfc\xaa\xbf\xe8\x97\x0f\x01\x0f\x96\x6b\x58\xdc\x4f\x1c\xc4\x72\x0a\xd7\x0c\x55\x5b\xec\x74\x84\xda\x7e\xd9\x4b\xec\x0f\x13\x2a\x73\xe2\x80\x29\xee\x09\x6d\x20\x38\x2b\x12\x67\x7a\x07\x22\x0d\x88\xdf\xdc\x7b\xa1\x38\x1e\x61\x9a\xdb\x22\x0a\xda\x36\x29\x3f\x85\x32\x47\x93\x9d\x93\x00\xc4\xf1\xf2\x35\x7d\x31\x5f\xac\xc6\x83\xd1\x2a\xe7\xfa\x45\xa5\x94\x01\xa9\x4a\xef\x17\x9f\xe9\x33\xb6\x2d\x29\xaa\x12\x7e\xf2\x5e\xa2\x82\x0b\xe6\xc1\x48\xdc\xfe\x4c\x0a\xc7\x7b\x13\xa4\x70\xa6\x9c\xf1\xe0\x89\xa3\xa3\x42\xda\x11\x9f\xf3\x26\x55\xdf\x70\x0f\x40\x1a\x83\x10\x1f\xe2\x3a\xe3\xc2\x36\x92\x9e\x96\x95\xd4\x6c\xb7\x82\x86\x72\x2e\xd4\x76\xf9\xeb\xe2\x7d\x73\x5c\xc1\x46\x2b\x97\x5b\xf1\x17\x2e\x6f\xef\xc2\x9e\x69\xa9\xcd\xf2\xcd\xdd\xdd\xdd\x0a\x5e\xb4\xe1\x79\x65\x90\xee\x96\xf1\x9b\x53\x29\x57\x50\x51\xb6\xdb\x1a\xbd\x57\x3c\xef\x94\x37\xef\xc3\x6f\x05\xa9\x4a\x96\xb7\xcd\x11\xac\x96\x82\xc3\x1b\xc6\x58\x2f\xce\x0d\xe5\x62\x6f\x97\xef\x9a\xe3\x2a\x83\x92\x30\xcd\x31\xc4\xeb\xf7\xaf\x9f\x3e\x3e\x28\xd1\x34\xe8\x06\x0f\x3b\x44\x30\x6a\x90\xa2\x31\x78\xe1\x6c\x31\xf2\xd6\x7b\xb1\x19\xc7\x78\xa8\xff\xa3\x45\x5d\xeb\x03\x9a\x6e\x6d\x9f\xbb\x52\x40\x89\xcf\xa8\x9c\x7d\x8a\xf2\x6c\x14\xe5\xd7\x4c\x8d\x4e\xc2\x59\x5d\x7e\xa4\x15\x4a\x4b\x0a\x57\x4f\x9d\xc6\x9a\x9b\x3b\x4c\xb5\x0d\x0f\x42\xb1\x59\x9d\x47\x2a\xf7\x13\x87\xc3\x5a\xea\x23\x94\xde\xd6\x7c\x90\xa2\x2f\xd7\x36\xf8\x58\x34\xc0\x92\xc1\xb9\x5f\xe0\xed\x21\xb0\x88\xef\x31\xb9\xbb\xf8\x44\x9b\x11\x76\x07\x67\x1b\xaa\xfa\x78\x55\x94\x6f\x11\xe2\x37\x6f\x8c\x78\xa6\xe6\x94\x95\xde\x27\xd4\xb6\x0d\x6d\x3e\x21\xb7\x6d\x46\x8a\x70\x73\x8a\x4a\x6a\xe2\x23\x33\xc5\x35\xed\xf8\x7e\x87\xe6\x2f\x5b\x43\xf7\xfe\xe1\x6f\x18\x76\x87\xd4\x1a\xda\x16\xc2\x80\xc1\x27\xa1\xb8\x60\xd4\x69\x03\x61\xde\xe5\xfb\xa6\x41\xc3\xa8\xc5\x40\xbb\xef\x1f\x1d\xd3\x39\x0a\xde\xf7\x3d\xcb\x2d\xfe\xfc\xfa\x21\xe8\xcf\x2a\x3e\x26\xe7\xaf\x35\xa6\xd2\x0b\x62\x03\x8b\xfb\xd7\xee\x3e\x91\x83\x50\xab\xa3\x86\xa0\xb4\xc2\xec\xb2\xd5\x4c\x8c\xad\x01\x42\x1d\xba\x43\xf0\x70\x9d\xbd\xcb\xca\xfb\xe1\x34\xf9\x7e\x11\xfe\x1f\x04\xf8\x05\x81\xab\x82\x20\x5c\xfe\xa7\x82\xfd\xf7\x88\xf5\x98\x6e\x50\x97\xa4\xe0\xee\xda\x44\xd0\x0a\x49\xeb\x2b\x96\x14\x7c\xf2\xe5\x4c\x95\x6b\x6c\x68\x57\xb4\x7f\x2c\xed\xd7\x78\xd7\x32\x52\xc4\xce\x75\xd9\x30\x2f\x95\xa6\x07\x91\xf7\x28\x2d\x0e\xe7\xea\xec\xfc\x01\xf8\xac\xd3\xd3\x11\x6a\x0b\x26\x0c\x6e\x48\x7f\xab\xf8\xf7\x8d\x9c\xa9\x90\xe2\xfc\x2f\xe2\x4c\xba\x6b\xf6\xbd\xda\x3f\x01\x00\x00\xff\xff\x90\x89\x38\x20\x8a\x0a\x00\x00")
+var _pkgUiTemplatesAlertsHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xac\x56\xdf\x6f\xdb\x36\x10\x7e\xf7\x5f\x71\x50\xf3\xb0\x01\x95\x85\x20\xed\xc3\x6c\x5a\x43\xd0\x97\x3d\xb4\x45\x91\x64\x7d\x0d\x28\xf2\x6c\xb1\x61\x48\x82\xa4\x1d\x7b\x1c\xff\xf7\x81\x94\xe4\xc8\xbf\xba\x0c\x98\x0d\x08\x22\xef\xf8\xdd\x77\xbc\x8f\x47\x85\xc0\x71\x29\x14\x42\xd1\x22\xe5\x45\x8c\x13\x00\x22\x85\x7a\x02\xbf\x33\xb8\x28\x3c\x6e\x7d\xc5\x9c\x2b\xc0\xa2\x5c\x14\xce\xef\x24\xba\x16\xd1\x17\xd0\x5a\x5c\x2e\x8a\x10\xc0\x50\xdf\x7e\xb3\xb8\x14\x5b\x88\xb1\x72\x9e\x7a\xc1\xd2\x9a\x8a\x4a\xb4\xde\x4d\x99\x73\xbf\x6f\x16\x21\x40\xb3\x16\x92\x7f\x47\xeb\x84\x56\x10\x63\x51\xa7\x60\x8e\x59\x61\x3c\x38\xcb\x2e\x83\xfd\xd8\x63\xfd\xb8\x04\x45\xaa\x0e\xa8\x9e\x84\x80\x8a\xc7\x38\x99\xbc\xe6\xc6\xb4\xf2\xa8\x7c\x4a\x8f\x70\xb1\x01\x26\xa9\x73\x8b\x3c\x4d\x85\x42\x5b\x2e\xe5\x5a\xf0\x8e\x4f\x7b\x5d\xdf\x66\xde\xa4\x6a\xaf\xf3\xcc\x68\x85\x6b\xf5\x4b\x49\x95\xd2\x89\x97\x56\x2e\x2f\x01\x00\x22\x06\x8f\x95\xdc\x99\x56\x30\xad\x60\xff\x56\xae\x15\x6b\x91\x3d\x21\x4f\x34\x45\xbf\x04\x80\x34\x6b\xef\xb5\xea\x77\xba\x1b\x14\x17\x23\x81\x17\x5e\x62\x67\x80\x03\x0a\xf7\x47\x33\xa4\xea\xb0\x52\x20\x52\x71\xb1\xc9\x2f\x9e\x36\x12\x07\xf4\x6e\x90\x9f\x65\xa3\x2d\x47\x8b\xbc\x1f\x32\x2d\x25\x35\x2e\x91\xcd\x44\x89\x6f\x34\xdf\x25\x08\x80\x10\xae\x72\x1d\xee\x3d\xf5\xf8\xa0\xef\xf4\xcb\xa7\x84\x07\xb3\x05\x4c\x6f\xcf\x18\xb2\x9c\xd2\x32\x4b\xd5\x0a\x7b\x1f\xa1\x56\x77\x6b\x89\x83\x31\x99\xaf\x28\xf3\x62\x83\xd9\xdc\xa3\x8d\x26\xf6\x8e\xc4\xdb\x21\x81\x4c\x03\xf2\xb3\x0c\x41\x28\x8e\x5b\x38\xcf\x6d\x9a\x27\x62\xec\x9c\x1f\x93\xcc\xd1\x0e\x75\xcb\xa0\xbc\x7e\x2d\x5f\xaa\x5c\xc9\x5a\xdc\x58\xad\x4a\xae\x5f\x54\x57\x32\x20\x4d\x1d\xc2\xf4\x2b\x7d\xc6\x18\x49\xd5\xd4\xf0\x4b\x08\x12\x15\x1c\x30\x4f\x41\xf2\xf0\x3d\x18\x6a\xbd\xa0\x12\x2c\x3a\xa3\x95\xc3\x19\x84\xe0\xbc\xa5\x1e\x57\x3b\x98\x7e\xeb\xac\x77\xbd\xf1\xbe\x37\xc4\xf8\x2b\xa9\x3c\x1f\xb8\x91\xca\xdb\xfa\x42\xea\x8f\x1c\x3d\x15\xd2\x1d\x25\xb2\x1f\x74\xb2\x1d\x8f\x01\x88\xb1\x08\xf9\x04\x2f\x0a\x2e\x9c\x91\x74\x37\x6b\xa4\x66\x4f\x73\x30\x94\x73\xa1\x56\xb3\xdf\xa6\x1f\xcd\x76\x0e\x4b\xad\x7c\xe9\xc4\x5f\x38\xbb\xbe\x49\x63\xa6\xa5\xb6\xb3\x77\x37\x37\x37\x73\x78\xd1\x96\x97\x8d\x45\xfa\x34\xcb\xcf\x92\x4a\x39\x87\x86\xb2\xa7\x95\xd5\x6b\xc5\xcb\xde\x79\xf9\x31\xfd\xe7\xd0\xc9\x6b\x76\x6d\xb6\xe0\xb4\x14\x1c\xde\x31\xc6\x86\xe9\xd2\x52\x2e\xd6\x6e\xf6\xc1\x6c\xe7\x05\xd4\x84\x69\x8e\x69\xa3\xff\x78\xf8\xf2\xf9\x5e\x09\x63\xd0\x8f\x3a\x42\xda\xfa\xec\x41\x2a\x63\xf1\x20\xd9\x41\xe6\xc3\x2f\x04\xb1\x3c\x2e\xce\xd8\xff\xad\xa7\xa1\xd5\x1b\xb4\xfd\xbb\x7b\xee\x35\x84\x12\x9f\x51\x79\xf7\x98\xe7\x47\x25\x38\xaa\xd4\x91\x25\xd9\xda\xfa\x33\x6d\x50\x3a\x52\xf9\xf6\x9c\x35\x8b\xf5\x92\xf1\x36\x8b\x0b\xee\x85\x62\x17\x7d\xbe\x53\xb9\x3e\x63\x1c\x6b\x69\xd8\xa1\xee\x50\x5e\xde\xa4\x04\x78\xb4\xe8\x44\x65\x47\x58\x32\x25\xf7\x1e\xae\x36\x89\x45\x3e\xc8\x5d\xba\xd3\x2f\xd4\x1c\x61\xf7\x70\xce\x50\x35\xec\x57\x43\xf9\x0a\x21\x3f\x4b\x63\xc5\x33\xb5\xbb\xa2\x0e\xa1\x43\x8d\x31\xdd\x0f\x1d\x72\x8c\x05\xa9\xd2\xca\x73\x54\xba\xee\x7f\x14\x66\x74\xac\x86\x7f\xca\xe4\x20\xfc\x61\x4f\xe9\x1b\x07\xfc\x0d\xe3\xb6\xd2\xf5\x94\x18\x21\x5d\x73\xf8\x28\x14\x17\x8c\x7a\x6d\x21\x5d\x94\xe5\xda\x18\xb4\x8c\x3a\x4c\xb4\x87\xc6\xd3\x33\xbd\x44\x21\x84\xa1\xd9\xf9\xe9\x9f\x0f\x9f\x62\xfc\x89\x63\x2e\xee\x39\x8f\x73\xe5\x05\xb1\x84\xe9\xed\xeb\xb5\x70\xa6\x06\x49\xab\x47\x0d\x41\x69\x85\xfb\x4b\x28\x67\xfe\x78\x70\xd9\x1c\xac\x4f\xc4\x5a\x60\x5a\xa6\x0c\x17\xc5\x87\xa2\x1e\xc5\x7b\x8b\x08\xff\x0f\x02\xfc\x80\xc0\x91\x39\xf5\x41\xf9\x9f\x04\xfb\xf3\x1d\x1b\x30\xfd\x48\x97\xa4\xe2\xfe\x34\x44\xf2\xe2\xf5\xab\x62\x49\xc5\xf9\x5b\xe5\x9a\xb6\xea\x94\xf6\xdb\xca\x7e\x8a\x77\x3a\x47\xaa\xdc\xb9\xea\xc9\x65\xa7\x71\xb0\xd7\x30\x21\xa0\x74\xb8\x77\x3b\xe8\x0f\x27\x9d\xe1\xab\xee\x8e\x8e\x50\x2b\xb0\xe9\xc6\x87\xee\x7b\x8c\xff\x7b\x90\x3d\x15\x52\xed\x3f\x3f\xf6\xa4\xfb\x6f\x9a\x10\x50\xf1\x18\x27\xff\x0c\x00\xd5\xdf\x58\x78\xc3\x0a\x00\x00")
 
 func pkgUiTemplatesAlertsHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -178,7 +178,7 @@ func pkgUiTemplatesAlertsHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "pkg/ui/templates/alerts.html", size: 2698, mode: os.FileMode(420), modTime: time.Unix(1556158847, 0)}
+	info := bindataFileInfo{name: "pkg/ui/templates/alerts.html", size: 2755, mode: os.FileMode(420), modTime: time.Unix(1556158847, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -263,7 +263,7 @@ func pkgUiTemplatesRule_menuHtml() (*asset, error) {
 	return a, nil
 }
 
-var _pkgUiTemplatesRulesHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x55\x5d\x6f\xd3\x30\x14\x7d\xef\xaf\xb8\x32\x3c\xc0\x43\x12\x18\x4f\x20\xa7\x68\x82\x69\x43\x1a\x13\xda\x26\x1e\x86\x60\x72\xe3\xdb\xc5\xc2\xb5\x23\xdb\x29\xdb\x8c\xff\x3b\xb2\xd3\x6e\xe9\x47\x56\x8a\x44\x1f\xa2\x5e\x27\xf7\x9e\x73\xbf\x8e\xbd\xe7\x38\x15\x0a\x81\xd4\xc8\x38\x09\x61\x44\xa5\x50\x3f\xc1\xdd\x35\x58\x12\x87\xb7\xae\xa8\xac\x25\x60\x50\x96\xc4\xba\x3b\x89\xb6\x46\x74\x04\x6a\x83\xd3\x92\x78\x0f\x0d\x73\xf5\x17\x83\x53\x71\x0b\x21\x14\xd6\x31\x27\xaa\xe8\x53\x98\x56\xa2\xcd\x2b\x6b\xdf\xcf\x4b\xef\x61\xd2\x0a\xc9\xbf\xa2\xb1\x42\x2b\x08\x81\x8c\x47\xde\xa3\xe2\x21\x8c\x46\x8f\x24\x2a\xad\x1c\x2a\x17\x79\x00\x50\x2e\xe6\x50\x49\x66\x6d\x99\x5e\x30\xa1\xd0\x64\x53\xd9\x0a\x4e\xc6\x23\x00\x00\x5a\x1f\x8c\xcf\x23\x0c\x2d\xea\x83\xc5\x91\x63\x13\x89\x4b\xb7\xce\x48\xcf\x6c\xa2\x0d\x47\x83\x4b\x5f\x00\xef\x0d\x53\x37\x08\x79\x0c\x71\x6c\x74\xdb\xd8\x84\xdb\xfd\xa8\x8b\x15\x19\x3f\xd8\xf1\xc4\xf4\xcd\x78\xc0\xa1\xd2\xd2\x36\x4c\x95\xe4\x0d\x19\x47\x3a\x94\x2d\x4a\xf3\xcc\x7b\x83\xe7\xd8\x48\x56\xe1\xa1\x94\x40\x5e\x7c\xfb\xc1\xb2\xfb\xc3\xec\xea\x55\xf6\xf6\xfb\x4b\x02\xe4\xf9\x6b\x02\xf9\x19\x9b\x61\x08\x04\x14\x9b\x61\xac\xe7\x1e\x3e\x63\xef\x17\x7f\x53\xfa\xb4\x70\x7c\x83\x5f\xe2\xe4\xbd\x98\x42\x7e\x8c\xee\x68\xce\x64\xcb\x9c\xd0\xea\x52\xcc\xd0\x3a\x36\x6b\xf2\x4f\xf6\x0a\x8d\x0e\xe1\x0c\xe7\x68\xbc\x47\x69\x31\x04\xef\xad\x50\x15\x0e\x39\x85\x00\xec\x46\x2f\xfa\xb7\x13\xbc\x6e\x67\x4c\x89\x7b\xfc\xd8\x9a\x14\x66\x2d\xea\xf2\x38\xbf\xc0\x4a\x2b\x6e\x07\x22\xd2\xa2\x5f\x7e\x5a\xac\xb5\x87\xba\x89\xe6\x77\x3b\xdb\x95\x46\xb8\x24\x53\xad\x5c\xf6\x0b\xc5\x4d\xed\xde\x4d\xb4\xe4\x24\x8d\xd1\xd6\x24\x86\x5d\x2e\x1c\x73\xfb\xfa\x1c\x19\xa3\xcd\x9e\x3e\xa7\xcc\x3a\x78\xac\xd7\xbe\x88\x0f\x8e\x10\xfb\xf7\x74\x59\xd7\x96\xa2\xbf\x0f\x43\xf3\xdf\x2d\x5a\x5c\xf6\xeb\x0a\xa5\x4c\x53\x79\x72\xf9\xf9\xf4\x42\x89\xa6\x41\xd7\xd3\x87\xd8\xd8\x6d\xd4\x17\x21\xa2\x74\x20\x59\x7d\x0d\x40\xe3\x76\x2d\x3f\x61\x12\x8d\x83\xf4\xcc\xbc\x87\xfc\x04\x99\x74\x35\xfc\x86\x08\xdf\x19\x97\xfa\x43\xfc\x16\x42\x80\x14\xf0\x5a\x28\x2e\x2a\xe6\xb4\x81\xa8\x66\x59\xdb\x34\x68\x2a\x66\x37\x91\x62\xee\x8b\x88\x2b\x69\x77\x55\x8a\x34\xd6\xa8\x3f\x95\x0c\xc6\x3e\xdb\x0d\x8c\x6e\x13\x63\x43\xd3\x20\x6c\xe2\x0c\x65\xcb\x63\x53\xcc\x7a\x4a\xa9\xd8\xbd\x68\xdb\x68\x46\xd0\x4e\x67\x77\x92\xdf\xce\xf6\x7f\xe9\xc6\x5f\xf0\xf9\x27\xed\xd8\x35\xdf\x7d\xf0\x25\xef\x9e\x8c\x98\x55\x0d\x59\x63\x75\xa6\xd3\xac\x59\xe8\x6e\x2c\x3e\x1a\x4a\xa1\x8f\xbb\x8a\x49\x8b\x9e\x54\xd1\x22\x5d\x4e\xd1\xa0\x05\x17\xf3\xc7\x5b\xf1\x4f\x00\x00\x00\xff\xff\x9f\x06\x54\xde\x9a\x07\x00\x00")
+var _pkgUiTemplatesRulesHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x55\x4d\x6f\xe3\x36\x10\xbd\xeb\x57\x0c\xd8\x1e\xda\x83\xa4\x36\x3d\x35\xa0\x54\x04\x6d\x90\x14\x48\x83\x20\x0e\x7a\xc8\x62\x37\xa0\xc5\xb1\x45\x2c\x4d\x0a\x24\xe5\x8d\xc3\xe5\x7f\x5f\x90\xb6\x13\xd9\x8e\xe3\x78\x81\x85\x0c\xc1\xfc\x78\xf3\xe6\xcd\x97\xbc\xe7\x38\x11\x0a\x81\xb4\xc8\x38\x09\x21\xa3\x52\xa8\xcf\xe0\x16\x1d\x56\xc4\xe1\xa3\x2b\x1b\x6b\x09\x18\x94\x15\xb1\x6e\x21\xd1\xb6\x88\x8e\x40\x6b\x70\x52\x11\xef\xa1\x63\xae\xbd\x31\x38\x11\x8f\x10\x42\x69\x1d\x73\xa2\x89\x98\xd2\xf4\x12\x6d\xd1\x58\xfb\xd7\xbc\xf2\x1e\xc6\xbd\x90\xfc\x7f\x34\x56\x68\x05\x21\x90\x3a\xf3\x1e\x15\x0f\x21\xcb\x5e\x9c\x68\xb4\x72\xa8\x5c\xf4\x03\x80\x72\x31\x87\x46\x32\x6b\xab\x74\xc0\x84\x42\x93\x4f\x64\x2f\x38\xa9\x33\x00\x00\xda\x9e\xd4\xb7\x91\x86\x96\xed\xc9\x6a\xcb\xb1\xb1\xc4\x35\x6c\xb9\x48\xef\x7c\xac\x0d\x47\x83\x6b\x2c\x80\xf7\x86\xa9\x29\x42\x11\x4d\x5c\x18\xdd\x77\x36\xf1\xc6\xb3\x68\x28\x46\x64\x7d\x37\x3e\xd4\x99\xe1\x32\x6e\x70\x68\xb4\xb4\x1d\x53\x15\xf9\x83\xd4\xd1\x1d\xca\x56\xa1\xf9\xc9\x7b\x83\xb7\xd8\x49\xd6\xe0\x99\x94\x40\x7e\xf9\xf0\x89\xe5\x4f\x67\xf9\xfd\x6f\xf9\x9f\x1f\x7f\x25\x40\x7e\xfe\x9d\x40\x71\xcd\x66\x18\x02\x01\xc5\x66\x58\x91\xa3\x30\xb5\xf7\xab\xbf\xb4\x64\x35\x50\x3b\x63\x52\xd6\x1d\x33\x4e\x30\x09\x06\x6d\xa7\x95\xc5\x53\xf0\xde\x3a\xc3\x1c\x4e\x17\x50\xdc\x2c\x4f\x6f\x57\x87\xa3\xd5\x41\x08\xb4\x5c\xc2\x53\x24\x69\xe9\x36\x94\x27\xa9\x49\x9e\xf7\x62\x02\xc5\x05\xba\xf3\x39\x93\x3d\x73\x42\xab\x3b\x31\x43\xeb\xd8\xac\x2b\xfe\xb5\xf7\x68\x74\x08\xd7\x38\x47\xe3\x3d\x4a\x8b\x21\x78\x6f\x85\x6a\x70\x1f\x28\x04\x60\x53\xbd\x2a\x85\x83\xe4\x6d\x3f\x63\x4a\x3c\xe1\x3f\xbd\x49\x66\xb6\xac\xae\xb7\x8b\x11\x36\x5a\x71\xbb\xc7\x22\x2d\x87\x99\xa4\xe5\x56\xa6\xa9\x1b\x6b\xbe\x38\x98\xf9\xd4\x0d\x15\x99\x68\xe5\xf2\x2f\x28\xa6\xad\x3b\x1d\x6b\xc9\x49\xaa\xc8\x57\x45\xec\x87\x8c\x1c\x73\xc7\x62\xce\x8d\xd1\xe6\x48\xcc\x15\xb3\x0e\x5e\xe2\x75\x2c\xe3\x33\x10\x62\xd2\xdf\x0e\xeb\x56\x7f\x0d\x5b\x6b\x5f\x2b\x2d\x7b\x36\xce\x8d\x87\x06\xa5\x4c\x05\x7e\x79\xf7\xdf\xd5\x48\x89\xae\x43\x37\x18\x35\x21\x6c\x93\x6f\x98\x88\x53\x08\x9f\xfb\x7c\xfd\xd0\xd8\xa8\xeb\x2b\x4c\xa2\x71\x90\xde\xb9\xf7\x50\x5c\x22\x93\xae\x85\xaf\x10\xe9\x97\x8b\x3b\xfd\x77\xbc\x0b\x21\x40\x32\xf8\x20\x14\x17\x0d\x73\xda\x40\x1c\x8c\x79\xdf\x75\x68\x1a\x66\x77\x99\xa2\xf6\x95\xc5\x0d\xd9\xf1\x47\xcb\xe8\xc6\x26\xe2\x4d\x31\x18\xf3\x6c\x77\x38\x96\x9d\x18\x13\x9a\x0a\x21\x84\xf7\xaa\xe5\x71\xe8\x99\x6d\x49\x29\xd8\x03\x6b\xaf\xb9\x19\x65\xa5\x3e\x3d\xec\xfc\x2e\xf0\x47\xce\x8d\x77\xf8\xf3\x5d\xb3\xe3\x50\x7d\x0f\xc9\xd7\x7e\x67\x7b\x6a\x7c\x27\x28\xd7\x3a\xd5\x9a\x85\xe5\xc7\x8f\x67\xfb\x24\x0c\x79\x37\x39\x69\x39\x18\x55\xb4\x4c\xdf\xb9\x78\x93\x96\x5c\xcc\xeb\xcc\x7b\x54\x3c\x84\xec\xdb\x00\x34\xc1\x77\x77\xe5\x07\x00\x00")
 
 func pkgUiTemplatesRulesHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -278,7 +278,7 @@ func pkgUiTemplatesRulesHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "pkg/ui/templates/rules.html", size: 1946, mode: os.FileMode(420), modTime: time.Unix(1556158847, 0)}
+	info := bindataFileInfo{name: "pkg/ui/templates/rules.html", size: 2021, mode: os.FileMode(420), modTime: time.Unix(1556158847, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
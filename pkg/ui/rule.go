@@ -8,6 +8,7 @@ import (
 	"path"
 	"regexp"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/go-kit/kit/log"
@@ -64,6 +65,9 @@ func ruleTmplFuncs(queryURL string) template.FuncMap {
 			}
 		},
 		"queryURL": func() string { return queryURL },
+		"strategy": func(s storepb.PartialResponseStrategy) string {
+			return strings.ToLower(s.String())
+		},
 		"reReplaceAll": func(pattern, repl, text string) string {
 			re := regexp.MustCompile(pattern)
 			return re.ReplaceAllString(text, repl)
@@ -127,14 +131,12 @@ func (ru *Rule) alerts(w http.ResponseWriter, r *http.Request) {
 
 	prefix := GetWebPrefix(ru.logger, ru.flagsMap, r)
 
-	// TODO(bwplotka): Update HTML to include partial response.
 	ru.executeTemplate(w, "alerts.html", prefix, alertStatus)
 }
 
 func (ru *Rule) rules(w http.ResponseWriter, r *http.Request) {
 	prefix := GetWebPrefix(ru.logger, ru.flagsMap, r)
 
-	// TODO(bwplotka): Update HTML to include partial response.
 	ru.executeTemplate(w, "rules.html", prefix, ru.ruleManagers)
 }
 
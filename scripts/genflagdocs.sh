@@ -33,17 +33,22 @@ fi
 
 CHECK=${1:-}
 
-commands=("compact" "query" "rule" "sidecar" "store" "bucket")
+commands=("compact" "query" "query-frontend" "rule" "sidecar" "store" "bucket")
 
 for x in "${commands[@]}"; do
     ./thanos "${x}" --help &> "docs/components/flags/${x}.txt"
 done
 
-bucketCommands=("verify" "ls" "inspect")
+bucketCommands=("verify" "ls" "inspect" "rewrite" "retention" "backfill")
 for x in "${bucketCommands[@]}"; do
     ./thanos bucket "${x}" --help &> "docs/components/flags/bucket_${x}.txt"
 done
 
+checkCommands=("rules")
+for x in "${checkCommands[@]}"; do
+    ./thanos check "${x}" --help &> "docs/components/flags/check_${x}.txt"
+done
+
 # remove white noise
 sed -i 's/[ \t]*$//' docs/components/flags/*.txt
 
@@ -25,14 +25,18 @@ PROM_PATH="$(pwd)/pkg/store/prompb"
 GOGOPROTO_ROOT="$(GO111MODULE=on go list -f '{{ .Dir }}' -m github.com/gogo/protobuf)"
 GOGOPROTO_PATH="${GOGOPROTO_ROOT}:${GOGOPROTO_ROOT}/protobuf"
 
-DIRS="pkg/store/storepb pkg/store/prompb"
+STORE_PATH="$(pwd)/pkg/store/storepb"
+STORE_MAPPING="Mtypes.proto=github.com/improbable-eng/thanos/pkg/store/storepb,Mrpc.proto=github.com/improbable-eng/thanos/pkg/store/storepb"
+
+DIRS="pkg/store/storepb pkg/store/prompb pkg/exemplars/exemplarspb pkg/metadata/metadatapb pkg/targets/targetspb pkg/rules/rulespb"
 
 echo "generating code"
 for dir in ${DIRS}; do
 	pushd ${dir}
-		${PROTOC_BIN} --gogofast_out=plugins=grpc:. -I=. \
+		${PROTOC_BIN} --gogofast_out=plugins=grpc,${STORE_MAPPING}:. -I=. \
             -I="${GOGOPROTO_PATH}" \
             -I="${PROM_PATH}" \
+            -I="${STORE_PATH}" \
             *.proto
 
 		sed -i.bak -E 's/import _ \"gogoproto\"//g' *.pb.go